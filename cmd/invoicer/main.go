@@ -1,17 +1,29 @@
 package main
 
 import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
 	"github.com/alecthomas/kong"
 	"github.com/zon/invoicer/internal/cli"
 )
 
 func main() {
+	// Ctrl-C or SIGTERM cancels the running command, which stops any
+	// subprocess and removes partially written output.
+	sigCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	var cmd cli.CLI
 	ctx := kong.Parse(&cmd,
 		kong.Name("invoicer"),
 		kong.Description("Generate invoices for an hourly contractor."),
 		kong.UsageOnError(),
+		kong.BindTo(sigCtx, (*context.Context)(nil)),
+		cli.Mappers(),
 	)
-	err := ctx.Run()
+	err := ctx.Run(&cmd.Globals)
 	ctx.FatalIfErrorf(err)
 }
@@ -0,0 +1,250 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/zon/invoicer/internal/config"
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+// BundleCmd is the 'bundle' subcommand.
+// It merges a year's invoice PDFs into one PDF, e.g. for an accountant.
+type BundleCmd struct {
+	// Year is the year whose invoices are bundled.
+	Year int `arg:"" help:"Year whose invoices to bundle."`
+
+	// Customer limits the bundle to one customer's invoices.
+	Customer string `short:"c" predictor:"customer" help:"Only bundle this customer's invoices."`
+
+	// Output is the bundle's path.
+	Output string `short:"o" type:"path" help:"PDF file to write. Defaults to invoices-<customer>-<year>.pdf, or invoices-<year>.pdf without --customer, in --dir."`
+
+	// Dir is where the invoices are.
+	Dir string `type:"path" help:"Directory the invoices are in. Defaults to the current directory."`
+
+	// Cover starts the bundle with a page listing the invoices.
+	Cover bool `help:"Start the bundle with a cover page listing the months and their totals."`
+}
+
+// Run executes the 'bundle' subcommand.
+func (b *BundleCmd) Run(g *Globals, ctx context.Context) error {
+	configPath, err := g.configPath()
+	if err != nil {
+		return err
+	}
+	global, err := g.loadConfig(configPath)
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+	local, err := g.localConfig()
+	if err != nil {
+		return err
+	}
+	// The PDF settings and the vendor come from the config, as they do
+	// for generation.
+	opts, err := (&GenerateCmd{Customer: b.Customer}).resolveOptions(global, local)
+	if err != nil {
+		return err
+	}
+	return runBundle(ctx, b, opts, config.Merge(global, local).VendorNames(), os.Stdout)
+}
+
+// runBundle merges the invoices b selects into one PDF, converting any that
+// only exist as HTML first, and reports progress on w. vendors are the
+// config's vendors, whose invoices name them. Months without an invoice
+// are reported as warnings.
+func runBundle(ctx context.Context, b *BundleCmd, opts *ResolvedOptions, vendors []string, w io.Writer) error {
+	dir := b.Dir
+	if dir == "" {
+		dir = invoice.CurrentDir()
+	}
+	files, err := invoice.FindInvoices(dir, b.Year, b.Customer, vendors...)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		if b.Customer != "" {
+			return fmt.Errorf("no invoices for %s in %d found in %s", b.Customer, b.Year, dir)
+		}
+		return fmt.Errorf("no invoices for %d found in %s", b.Year, dir)
+	}
+	missing := missingMonths(files, b.Year, invoice.Now())
+	for _, month := range missing {
+		fmt.Fprintf(config.Warnings, "Warning: no invoice for %s %d\n", month, b.Year)
+	}
+
+	var inputs []string
+	for _, f := range files {
+		if f.PDFPath == "" {
+			inv, _, err := bundleInvoice(opts, vendors, f)
+			if err != nil {
+				return err
+			}
+			f.PDFPath = invoice.PDFFilePath(inv, dir)
+			fmt.Fprintf(w, "Converting %s to PDF...\n", filepath.Base(f.HTMLPath))
+			if _, err := convertPDF(ctx, opts, inv, f.HTMLPath, f.PDFPath); err != nil {
+				return fmt.Errorf("converting %s to PDF: %w", filepath.Base(f.HTMLPath), err)
+			}
+		}
+		inputs = append(inputs, f.PDFPath)
+	}
+
+	if b.Cover {
+		tmp, err := os.MkdirTemp("", "invoicer-bundle-")
+		if err != nil {
+			return fmt.Errorf("creating temp dir: %w", err)
+		}
+		defer os.RemoveAll(tmp)
+		coverPath, err := writeBundleCover(ctx, b, opts, vendors, files, missing, tmp)
+		if err != nil {
+			return err
+		}
+		inputs = append([]string{coverPath}, inputs...)
+	}
+
+	output := b.Output
+	if output == "" {
+		name := fmt.Sprintf("invoices-%d.pdf", b.Year)
+		if b.Customer != "" {
+			name = fmt.Sprintf("invoices-%s-%d.pdf", files[0].CustomerSlug, b.Year)
+		}
+		output = filepath.Join(dir, name)
+	}
+	tool, err := invoice.MergePDFs(ctx, inputs, output)
+	if err != nil {
+		return fmt.Errorf("merging the invoices: %w", err)
+	}
+	fmt.Fprintf(w, "Bundle of %d invoices written to: %s (%s)\n", len(files), output, tool)
+	return nil
+}
+
+// missingMonths returns the months of year that have no invoice in files,
+// up to the last month that could have been invoiced by now.
+func missingMonths(files []invoice.InvoiceFile, year int, now time.Time) []time.Month {
+	last := time.December
+	switch {
+	case year > now.Year():
+		return nil
+	case year == now.Year():
+		last = now.Month() - 1
+	}
+	found := map[time.Month]bool{}
+	for _, f := range files {
+		found[f.Month] = true
+	}
+	var missing []time.Month
+	for month := time.January; month <= last; month++ {
+		if !found[month] {
+			missing = append(missing, month)
+		}
+	}
+	return missing
+}
+
+// bundleInvoice returns the invoice f holds and whether it was recorded:
+// the one in its JSON export, as it was issued, or, without one, an
+// invoice named from f, opts and vendors, with the customer's name if it
+// is the configured one and the vendor's if f is one of vendors'. Only a
+// recorded invoice has figures; one worked out from today's config could
+// differ from the invoice that was sent.
+func bundleInvoice(opts *ResolvedOptions, vendors []string, f invoice.InvoiceFile) (*invoice.Invoice, bool, error) {
+	if f.JSONPath != "" {
+		inv, err := readSpecFile(f.JSONPath, false)
+		if err != nil {
+			return nil, false, err
+		}
+		return inv, true, nil
+	}
+	customer := f.CustomerSlug
+	if opts.Customer != "" && f.IsFor(opts.Customer) {
+		customer = opts.Customer
+	}
+	inv := &invoice.Invoice{
+		Month:    f.Month,
+		Year:     f.Year,
+		Vendor:   opts.Vendor,
+		Customer: customer,
+	}
+	if f.VendorSlug != "" {
+		inv.PerVendor = true
+		inv.Vendor = f.VendorSlug
+		for _, v := range vendors {
+			if f.IsFrom(v) {
+				inv.Vendor = v
+			}
+		}
+	}
+	return inv, false, nil
+}
+
+// writeBundleCover converts a cover page listing files, and the missing
+// months, to a PDF in dir and returns its path.
+func writeBundleCover(ctx context.Context, b *BundleCmd, opts *ResolvedOptions, vendors []string, files []invoice.InvoiceFile, missing []time.Month, dir string) (string, error) {
+	title, months, err := bundleCover(b, opts, vendors, files, missing)
+	if err != nil {
+		return "", err
+	}
+	var html strings.Builder
+	if err := invoice.WriteBundleCover(&html, title, months); err != nil {
+		return "", fmt.Errorf("writing the cover page: %w", err)
+	}
+	htmlPath := filepath.Join(dir, "cover.html")
+	if err := os.WriteFile(htmlPath, []byte(html.String()), 0o644); err != nil {
+		return "", fmt.Errorf("writing the cover page: %w", err)
+	}
+	pdfPath := filepath.Join(dir, "cover.pdf")
+	if _, err := convertHTML(ctx, opts, htmlPath, pdfPath); err != nil {
+		return "", fmt.Errorf("converting the cover page to PDF: %w", err)
+	}
+	return pdfPath, nil
+}
+
+// bundleCover returns the title of the cover page of files and its lines:
+// one for each invoice, with its total if it was recorded in a JSON
+// export, and one for each missing month.
+func bundleCover(b *BundleCmd, opts *ResolvedOptions, vendors []string, files []invoice.InvoiceFile, missing []time.Month) (string, []invoice.BundleMonth, error) {
+	var months []invoice.BundleMonth
+	customer := ""
+	for i, f := range files {
+		inv, recorded, err := bundleInvoice(opts, vendors, f)
+		if err != nil {
+			return "", nil, err
+		}
+		m := invoice.BundleMonth{Month: f.Month, Customer: inv.Customer, Number: invoice.InvoiceNumber(inv)}
+		if recorded {
+			total := inv.Total()
+			m.Total = &total
+		}
+		months = append(months, m)
+		if i == 0 && b.Customer != "" {
+			customer = inv.Customer
+		}
+	}
+	for _, month := range missing {
+		months = append(months, invoice.BundleMonth{Month: month, Customer: customer})
+	}
+	slices.SortStableFunc(months, func(a, b invoice.BundleMonth) int {
+		if a.Month != b.Month {
+			return int(a.Month - b.Month)
+		}
+		return strings.Compare(a.Customer, b.Customer)
+	})
+
+	title := fmt.Sprintf("Invoices for %d", b.Year)
+	switch {
+	case opts.Vendor != "" && customer != "":
+		title = fmt.Sprintf("Invoices from %s to %s for %d", opts.Vendor, customer, b.Year)
+	case customer != "":
+		title = fmt.Sprintf("Invoices to %s for %d", customer, b.Year)
+	case opts.Vendor != "":
+		title = fmt.Sprintf("Invoices from %s for %d", opts.Vendor, b.Year)
+	}
+	return title, months, nil
+}
@@ -0,0 +1,211 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zon/invoicer/internal/config"
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+// writeBundleTools puts a fake wkhtmltopdf, and a fake pdfunite that records
+// its arguments to args.txt, first on PATH and returns their directory.
+func writeBundleTools(t *testing.T) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("uses shell scripts as a fake wkhtmltopdf and pdfunite")
+	}
+	bin := t.TempDir()
+	for name, script := range map[string]string{
+		"wkhtmltopdf": "#!/bin/sh\nprintf '%%PDF-1.4\\n%%%%EOF\\n' > \"$2\"\n",
+		"pdfunite":    "#!/bin/sh\nprintf '%s\\n' \"$@\" > \"${0%/*}/args.txt\"\nfor out; do :; done\nprintf '%%PDF-1.4\\n%%%%EOF\\n' > \"$out\"\n",
+	} {
+		if err := os.WriteFile(filepath.Join(bin, name), []byte(script), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return bin
+}
+
+func TestRunBundle(t *testing.T) {
+	bin := writeBundleTools(t)
+	var warnings bytes.Buffer
+	old := config.Warnings
+	config.Warnings = &warnings
+	t.Cleanup(func() { config.Warnings = old })
+	oldNow := invoice.Now
+	invoice.Now = func() time.Time { return time.Date(2025, time.May, 10, 0, 0, 0, 0, time.UTC) }
+	t.Cleanup(func() { invoice.Now = oldNow })
+
+	dir := t.TempDir()
+	for _, name := range []string{
+		"invoice-acme-corp-2025-03.pdf",
+		"invoice-acme-corp-2025-01.pdf",
+		"invoice-acme-corp-2025-02.html",
+		"invoice-side-client-2025-01.pdf",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("%PDF-1.4\n%%EOF\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	opts := &ResolvedOptions{Vendor: "Jane", Customer: "Acme Corp", Rate: 100, Hours: 40, PDFTimeout: time.Minute, PDFTitle: invoice.DefaultPDFTitle}
+	var out bytes.Buffer
+
+	err := runBundle(context.Background(), &BundleCmd{Year: 2025, Customer: "Acme Corp", Dir: dir, Cover: true}, opts, nil, &out)
+	if err != nil {
+		t.Fatalf("runBundle: %v", err)
+	}
+
+	args, err := os.ReadFile(filepath.Join(bin, "args.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	output := filepath.Join(dir, "invoices-acme-corp-2025.pdf")
+	lines := strings.Split(strings.TrimSpace(string(args)), "\n")
+	if len(lines) != 5 || filepath.Base(lines[0]) != "cover.pdf" || !strings.HasPrefix(lines[4], output+".tmp-") {
+		t.Fatalf("pdfunite arguments = %q", lines)
+	}
+	var names []string
+	for _, line := range lines {
+		names = append(names, filepath.Base(line))
+	}
+	want := []string{"invoice-acme-corp-2025-01.pdf", "invoice-acme-corp-2025-02.pdf", "invoice-acme-corp-2025-03.pdf"}
+	if strings.Join(names[1:4], " ") != strings.Join(want, " ") {
+		t.Errorf("invoices = %q, want them in order: %q", names[1:4], want)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "invoice-acme-corp-2025-02.pdf")); err != nil {
+		t.Errorf("the HTML-only invoice should be converted: %v", err)
+	}
+	if err := invoice.VerifyPDF(output); err != nil {
+		t.Errorf("the bundle should be written to the default path: %v", err)
+	}
+	if !strings.Contains(out.String(), "Bundle of 3 invoices written to: "+output+" (pdfunite)") {
+		t.Errorf("output = %q", out.String())
+	}
+	if got := warnings.String(); !strings.Contains(got, "Warning: no invoice for April 2025\n") || strings.Contains(got, "May") {
+		t.Errorf("warnings = %q, want April reported missing", got)
+	}
+}
+
+func TestRunBundle_NoInvoices(t *testing.T) {
+	dir := t.TempDir()
+	err := runBundle(context.Background(), &BundleCmd{Year: 2025, Customer: "Acme Corp", Dir: dir}, &ResolvedOptions{}, nil, &bytes.Buffer{})
+	if err == nil || !strings.Contains(err.Error(), "no invoices for Acme Corp in 2025") {
+		t.Fatalf("expected no invoices to be found, got %v", err)
+	}
+}
+
+func TestMissingMonths(t *testing.T) {
+	files := []invoice.InvoiceFile{{Month: time.January}, {Month: time.March}, {Month: time.March}}
+	now := time.Date(2025, time.May, 10, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		year int
+		want []time.Month
+	}{
+		{2024, []time.Month{time.February, time.April, time.May, time.June, time.July, time.August, time.September, time.October, time.November, time.December}},
+		{2025, []time.Month{time.February, time.April}},
+		{2026, nil},
+	}
+	for _, tt := range tests {
+		if got := missingMonths(files, tt.year, now); !slices.Equal(got, tt.want) {
+			t.Errorf("missingMonths(%d) = %v, want %v", tt.year, got, tt.want)
+		}
+	}
+}
+
+func TestBundleCover(t *testing.T) {
+	dir := t.TempDir()
+	// January was recorded with an expense the config knows nothing of,
+	// at a rate it no longer has; February was not recorded.
+	jan := &invoice.Invoice{
+		Month: time.January, Year: 2025, Vendor: "Jane", Customer: "Acme Corp", Rate: 90,
+		Weeks:       invoice.WeeksForMonth(2025, time.January, 40),
+		Adjustments: []invoice.Adjustment{{Description: "Travel", Amount: 250}},
+	}
+	data, err := json.Marshal(jan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(invoice.JSONFilePath(jan, dir), data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	files := []invoice.InvoiceFile{
+		{CustomerSlug: "acme-corp", Year: 2025, Month: time.January, PDFPath: invoice.PDFFilePath(jan, dir), JSONPath: invoice.JSONFilePath(jan, dir)},
+		{CustomerSlug: "acme-corp", Year: 2025, Month: time.February, PDFPath: filepath.Join(dir, "invoice-acme-corp-2025-02.pdf")},
+	}
+	opts := &ResolvedOptions{Vendor: "Jane", Customer: "Acme Corp", Rate: 100, Hours: 40}
+
+	title, months, err := bundleCover(&BundleCmd{Year: 2025, Customer: "Acme Corp"}, opts, nil, files, []time.Month{time.March})
+	if err != nil {
+		t.Fatalf("bundleCover: %v", err)
+	}
+	if title != "Invoices from Jane to Acme Corp for 2025" {
+		t.Errorf("title = %q", title)
+	}
+	if len(months) != 3 {
+		t.Fatalf("got %d months, want 3: %+v", len(months), months)
+	}
+	if m := months[0]; m.Number != "INV-202501-acme-corp" || m.Total == nil || *m.Total != jan.Total() || *m.Total != 16810 {
+		t.Errorf("January = %+v, want the recorded total 16810", m)
+	}
+	if m := months[1]; m.Number != "INV-202502-acme-corp" || m.Customer != "Acme Corp" || m.Total != nil {
+		t.Errorf("February = %+v, want no total without a JSON export", m)
+	}
+	if m := months[2]; m.Month != time.March || m.Number != "" {
+		t.Errorf("March = %+v, want it missing", m)
+	}
+}
+
+func TestRunBundle_PerVendor(t *testing.T) {
+	bin := writeBundleTools(t)
+	oldNow := invoice.Now
+	invoice.Now = func() time.Time { return time.Date(2025, time.February, 10, 0, 0, 0, 0, time.UTC) }
+	t.Cleanup(func() { invoice.Now = oldNow })
+
+	dir := t.TempDir()
+	for _, name := range []string{
+		"invoice-acme-corp-jane-doe-2025-01.pdf",
+		"invoice-acme-corp-john-roe-2025-01.pdf",
+		"invoice-side-client-jane-doe-2025-01.pdf",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("%PDF-1.4\n%%EOF\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	opts := &ResolvedOptions{Vendor: "Agency", Customer: "Acme Corp", PDFTimeout: time.Minute, PDFTitle: invoice.DefaultPDFTitle}
+	vendors := []string{"Jane Doe", "John Roe"}
+	b := &BundleCmd{Year: 2025, Customer: "acme-corp", Dir: dir}
+	var out bytes.Buffer
+	if err := runBundle(context.Background(), b, opts, vendors, &out); err != nil {
+		t.Fatalf("runBundle: %v", err)
+	}
+	args, err := os.ReadFile(filepath.Join(bin, "args.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(args)), "\n")
+	if len(lines) != 3 || filepath.Base(lines[0]) != "invoice-acme-corp-jane-doe-2025-01.pdf" || filepath.Base(lines[1]) != "invoice-acme-corp-john-roe-2025-01.pdf" {
+		t.Errorf("pdfunite arguments = %q, want both vendors' invoices to Acme Corp", lines)
+	}
+
+	files, err := invoice.FindInvoices(dir, 2025, b.Customer, vendors...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, months, err := bundleCover(b, opts, vendors, files, nil)
+	if err != nil {
+		t.Fatalf("bundleCover: %v", err)
+	}
+	if len(months) != 2 || months[0].Customer != "Acme Corp" || months[0].Number != "INV-202501-acme-corp-jane-doe" || months[1].Number != "INV-202501-acme-corp-john-roe" {
+		t.Errorf("cover = %+v, want each vendor's invoice to Acme Corp", months)
+	}
+}
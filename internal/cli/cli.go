@@ -2,106 +2,929 @@
 package cli
 
 import (
+	"cmp"
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"maps"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/zon/invoicer/internal/config"
-	"github.com/zon/invoicer/internal/invoice"
+	"github.com/zon/invoicer/pkg/invoice"
 )
 
 // CLI is the root command for invoicer.
 type CLI struct {
+	Globals
+
 	// Generate is the default subcommand for generating an invoice.
 	Generate GenerateCmd `cmd:"" default:"withargs" help:"Generate an invoice for a given month."`
 
+	// Init is the 'init' subcommand for setting up the config.
+	Init InitCmd `cmd:"" help:"Set up the config file by answering a few questions."`
+
 	// Set is the 'set' subcommand group for managing configuration.
 	Set SetCmd `cmd:"" name:"set" help:"Subcommands for managing invoicer configuration."`
+
+	// Unset is the 'unset' subcommand group for removing configuration.
+	Unset UnsetCmd `cmd:"" name:"unset" help:"Subcommands for removing invoicer configuration."`
+
+	// Show is the 'show' subcommand group for inspecting configuration.
+	Show ShowCmd `cmd:"" name:"show" help:"Subcommands for inspecting invoicer configuration."`
+
+	// Totals prints the amount an invoice would be for.
+	Totals TotalsCmd `cmd:"" help:"Print the total of a month's invoice, without generating it."`
+
+	// Weeks prints the weeks an invoice would bill.
+	Weeks WeeksCmd `cmd:"" help:"Print the weeks of a month's invoice, with their workdays, hours and subtotals, without generating it."`
+
+	// Diff compares a month's invoice with another's.
+	Diff DiffCmd `cmd:"" help:"Compare a month's invoice with another month's: the totals, the weeks and the other items."`
+
+	// Models lists the models opencode can use.
+	Models ModelsCmd `cmd:"" help:"List the models opencode can use for invoice generation."`
+
+	// Serve runs an HTTP API for invoice generation.
+	Serve ServeCmd `cmd:"" help:"Serve an HTTP API for invoice generation."`
+
+	// Mcp runs a Model Context Protocol server so agents can drive invoicer.
+	Mcp McpCmd `cmd:"" name:"mcp" help:"Run a Model Context Protocol server on stdin/stdout."`
+
+	// Thumbnail renders an HTML invoice to a PNG preview.
+	Thumbnail ThumbnailCmd `cmd:"" help:"Render a PNG thumbnail of an HTML invoice."`
+
+	// Bundle merges a year's invoices into one PDF.
+	Bundle BundleCmd `cmd:"" help:"Merge a year's invoices into one PDF."`
+
+	// Export writes invoices for accounting applications.
+	Export ExportCmd `cmd:"" help:"Subcommands for exporting invoices to accounting applications."`
+
+	// Import converts data from other tools for invoicer.
+	Import ImportCmd `cmd:"" help:"Subcommands for importing data from other tools."`
+
+	// Edit opens the overrides file of an invoice in the editor.
+	Edit EditCmd `cmd:"" help:"Edit the overrides file of a month's invoice in $VISUAL or $EDITOR, then regenerate the invoice."`
+
+	// Send emails an invoice to the customer.
+	Send SendCmd `cmd:"" help:"Email a month's PDF invoice to the customer, generating it first if needed."`
+
+	// Due lists, and generates, the invoices the schedule says are due.
+	Due DueCmd `cmd:"" help:"List the invoices due by each customer's schedule, and generate them with --generate."`
+
+	// Completion prints shell completion scripts.
+	Completion CompletionCmd `cmd:"" help:"Print a shell completion script."`
+
+	// Complete is invoked by the completion scripts to produce candidates.
+	Complete CompleteCmd `cmd:"" name:"__complete" hidden:"" help:"Print completion candidates."`
+}
+
+// Globals holds flags shared by every subcommand.
+type Globals struct {
+	// Config is an alternate config file path.
+	Config string `name:"config" env:"INVOICER_CONFIG" type:"path" help:"Path to the config file. Defaults to ~/.invoicer/config.yaml if present, else invoicer/config.yaml in the user config directory."`
+
+	// Profile is the named profile whose config is used instead of the
+	// default config file.
+	Profile string `name:"profile" env:"INVOICER_PROFILE" predictor:"profile" help:"Use the config of the named profile, e.g. for a second business, instead of the default config file."`
+
+	// NoLocal disables the project-local .invoicer.yaml lookup.
+	NoLocal bool `name:"no-local" env:"INVOICER_NO_LOCAL" help:"Ignore .invoicer.yaml files in the current directory and its parents."`
+
+	// StrictConfig makes unknown config keys an error.
+	StrictConfig bool `name:"strict-config" env:"INVOICER_STRICT_CONFIG" help:"Treat unknown keys in config files as errors instead of warnings."`
+
+	// Verbose shows each step of the generation and the output of the
+	// tools invoicer runs as they run.
+	Verbose bool `env:"INVOICER_VERBOSE" help:"Show each step of the generation, and the output of the generation backend and the PDF tool, as they run."`
+}
+
+// AfterApply applies the global flags that change how invoicer runs.
+func (g *Globals) AfterApply() error {
+	if g.Profile != "" {
+		if g.Config != "" {
+			return errors.New("--config and --profile cannot be used together: a profile has its own config file")
+		}
+		if _, err := config.ProfilePath(g.Profile); err != nil {
+			return err
+		}
+	}
+	if g.Verbose {
+		invoice.ToolOutput = os.Stderr
+		progressOutput = os.Stderr
+	}
+	return nil
+}
+
+// configPath returns the config file path to read from: the --config
+// path, the --profile config or the default. An explicitly given path that
+// does not exist is an error, as is an unknown profile.
+func (g *Globals) configPath() (string, error) {
+	if g != nil && g.Profile != "" {
+		return config.LookupProfile(g.Profile)
+	}
+	if g == nil || g.Config == "" {
+		path, err := config.DefaultPath()
+		if err != nil {
+			return "", fmt.Errorf("determining config path: %w", err)
+		}
+		return path, nil
+	}
+	if _, err := os.Stat(g.Config); err != nil {
+		return "", fmt.Errorf("config file %q: %w", g.Config, err)
+	}
+	return g.Config, nil
+}
+
+// writePath returns the config file path for set config and init to
+// write to: the --config path, the --profile config, which is created if
+// the profile is new, or "" for the default.
+func (g *Globals) writePath() (string, error) {
+	if g.Profile != "" {
+		return config.ProfilePath(g.Profile)
+	}
+	return g.Config, nil
+}
+
+// loadConfig reads the config file at path, treating unknown keys as errors
+// if --strict-config is set.
+func (g *Globals) loadConfig(path string) (*config.Config, error) {
+	if g != nil && g.StrictConfig {
+		return config.LoadStrict(path)
+	}
+	return config.Load(path)
+}
+
+// localConfig loads the project-local config found from the current directory.
+// It returns nil if there is none or the lookup is disabled.
+func (g *Globals) localConfig() (*config.Config, error) {
+	if g != nil && g.NoLocal {
+		return nil, nil
+	}
+	dir, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("determining current directory: %w", err)
+	}
+	home, _ := os.UserHomeDir()
+	path := config.FindLocal(dir, home)
+	if path == "" {
+		return nil, nil
+	}
+	cfg, err := g.loadConfig(path)
+	if err != nil {
+		return nil, fmt.Errorf("loading local config: %w", err)
+	}
+	return cfg, nil
 }
 
 // GenerateCmd is the default subcommand for generating an invoice.
 type GenerateCmd struct {
 	// Month is the month to invoice for (text or numeric). Defaults to previous month.
-	Month string `arg:"" optional:"" help:"Month to invoice for (e.g. 'january', 'jan', or '1'). Defaults to previous month."`
+	Month string `arg:"" optional:"" predictor:"month" help:"Month to invoice for (e.g. 'january', 'jan', or '1'). Defaults to previous month."`
 
 	// Year is the year of the month to invoice for. Defaults to the year closest to the given month.
 	Year int `arg:"" optional:"" help:"Year of the month to invoice for. Defaults to the year closest to the given month."`
 
 	// Vendor is the name of the contractor sending the invoice.
-	Vendor string `short:"v" help:"Name of the contractor sending the invoice. Required without config."`
+	Vendor string `short:"v" env:"INVOICER_VENDOR" help:"Name of the contractor sending the invoice. Required without config."`
+
+	// AllVendors generates the invoice of each vendor in the config's
+	// vendors: list in turn.
+	AllVendors bool `name:"all-vendors" help:"Generate an invoice for each vendor in vendors: in the config in turn, each with its own rate, hours and bank account."`
 
 	// Customer is the name of the client receiving the invoice.
-	Customer string `short:"c" help:"Name of the client receiving the invoice. Required without config."`
+	Customer string `short:"c" env:"INVOICER_CUSTOMER" predictor:"customer" help:"Name of the client receiving the invoice. Required without config."`
 
-	// Rate is the hourly rate for the contractor.
-	Rate float64 `short:"r" help:"Hourly rate in dollars. Required without config."`
+	// Rate is the hourly rate for the contractor. Nil if not given.
+	Rate *float64 `short:"r" type:"number" env:"INVOICER_RATE" help:"Hourly rate in dollars. Required without config."`
 
-	// Hours is the number of hours per week worked.
-	Hours float64 `short:"H" help:"Hours per week worked. Required without config."`
+	// Hours is the number of hours per week worked. Nil if not given.
+	Hours *float64 `short:"H" type:"number" env:"INVOICER_HOURS" help:"Hours per week worked. Required without config."`
 
-	// PDF controls whether the HTML invoice is converted to a PDF.
-	PDF bool `short:"p" help:"Convert the HTML invoice to a PDF file. Defaults to false."`
+	// Timesheet is a timesheet of the hours worked each day, or "-" for
+	// standard input.
+	Timesheet string `help:"Read the hours worked each day from the CSV or YAML timesheet at PATH, or from standard input with --timesheet -, instead of --hours."`
 
-	// Model is the opencode-formatted model stub to use for generation.
-	Model string `short:"m" default:"anthropic/claude-haiku-4-5" help:"opencode-formatted model stub to use for invoice generation. Defaults to anthropic/claude-haiku-4-5."`
-}
+	// MonthHours is the hours worked in the month, split across its weeks.
+	// Nil if not given.
+	MonthHours *float64 `name:"month-hours" type:"number" env:"INVOICER_MONTH_HOURS" help:"Hours worked in the month, split across its weeks by their workdays in quarter hours, instead of --hours."`
 
-// resolveOptions merges config file values with CLI-provided values.
-// CLI values take precedence over config file values.
-// configPath may be empty to use the default path.
-func (c *GenerateCmd) resolveOptions(configPath string) (*ResolvedOptions, error) {
-	if configPath == "" {
-		var err error
-		configPath, err = config.DefaultPath()
-		if err != nil {
-			return nil, fmt.Errorf("determining config path: %w", err)
-		}
-	}
+	// DaysOff are dates not worked, taken off the hours of their weeks.
+	DaysOff []string `name:"days-off" help:"Dates not worked, e.g. 2025-01-15,2025-01-16, each taking a fifth of --hours off its week. Repeat or separate with commas."`
 
-	cfg, err := config.Load(configPath)
-	if err != nil {
-		return nil, fmt.Errorf("loading config: %w", err)
+	// HolidayCountry is the region whose public holidays are not worked.
+	HolidayCountry string `name:"holiday-country" env:"INVOICER_HOLIDAY_COUNTRY" predictor:"holiday_country" help:"Take the public holidays of this country, or region of one, e.g. US or DE-BY, off the workdays of their weeks. Defaults to holidays: country: in the config."`
+
+	// IBAN is the vendor's bank account, shown with a payment QR code.
+	IBAN string `name:"iban" env:"INVOICER_IBAN" help:"IBAN of the account the invoice is paid into. Shows the payment details, and an EPC payment QR code on an invoice billed in euros with --bill-currency EUR."`
+
+	// BIC is the Business Identifier Code of the vendor's bank.
+	BIC string `name:"bic" env:"INVOICER_BIC" help:"BIC of the bank the invoice is paid into. Optional with --iban."`
+
+	// RoutingNumber and AccountNumber are the vendor's US bank account.
+	RoutingNumber string `name:"routing-number" env:"INVOICER_ROUTING_NUMBER" help:"ABA routing number of the US bank the invoice is paid into, shown with --account-number in the payment details."`
+	AccountNumber string `name:"account-number" env:"INVOICER_ACCOUNT_NUMBER" help:"Number of the US bank account the invoice is paid into."`
+
+	// VendorCountry is the vendor's country code, for e-invoices.
+	VendorCountry string `name:"vendor-country" env:"INVOICER_VENDOR_COUNTRY" help:"ISO 3166-1 alpha-2 code of the vendor's country, e.g. DE. Required by --export-ubl."`
+
+	// CustomerCountry is the customer's country code, for e-invoices.
+	CustomerCountry string `name:"customer-country" env:"INVOICER_CUSTOMER_COUNTRY" help:"ISO 3166-1 alpha-2 code of the customer's country, e.g. FR. Required by --export-ubl."`
+
+	// VendorVATID is the vendor's VAT identifier, for e-invoices.
+	VendorVATID string `name:"vendor-vat-id" env:"INVOICER_VENDOR_VAT_ID" help:"Vendor's VAT ID with its country prefix, for --export-ubl."`
+
+	// CustomerVATID is the customer's VAT identifier, for e-invoices.
+	CustomerVATID string `name:"customer-vat-id" env:"INVOICER_CUSTOMER_VAT_ID" help:"Customer's VAT ID with its country prefix, for --export-ubl."`
+
+	// PDF controls whether the HTML invoice is converted to a PDF. Nil if not given,
+	// so --no-pdf can override pdf: true in the config.
+	PDF *bool `short:"p" negatable:"" env:"INVOICER_PDF" help:"Convert the HTML invoice to a PDF file (--no-pdf to skip). Defaults to false."`
+
+	// Model is the opencode-formatted model stub to use for generation. Nil if not given.
+	// It has no kong default so that a config value is not masked; defaultModel
+	// is applied last in resolveOptions.
+	Model *string `short:"m" env:"INVOICER_MODEL" help:"opencode-formatted model stub to use for invoice generation, or a comma-separated list to fall back through. Defaults to anthropic/claude-haiku-4-5."`
+
+	// Backend is the name of the generation backend. Nil if not given.
+	Backend *string `env:"INVOICER_BACKEND" predictor:"backend" help:"Generation backend: opencode, claude, or ollama. Defaults to opencode."`
+
+	// OllamaHost is the URL of the ollama server. Nil if not given.
+	OllamaHost *string `env:"INVOICER_OLLAMA_HOST" help:"URL of the ollama server for --backend ollama. Defaults to http://localhost:11434."`
+
+	// OllamaModel is the ollama model name. Nil if not given.
+	OllamaModel *string `env:"INVOICER_OLLAMA_MODEL" help:"ollama model for --backend ollama. Defaults to llama3.2."`
+
+	// Agent is the opencode agent that writes the invoice. Nil if not given.
+	Agent *string `env:"INVOICER_AGENT" help:"opencode agent to write the invoice with, e.g. one with your own instructions."`
+
+	// Session is the opencode session to continue. Nil if not given.
+	Session *string `env:"INVOICER_SESSION" help:"opencode session to continue, e.g. the one a previous invoice was written in."`
+
+	// RestrictTools switches off the opencode tools in DeniedTools. Nil if not given.
+	RestrictTools *bool `name:"restrict-tools" negatable:"" env:"INVOICER_RESTRICT_TOOLS" help:"Switch off the opencode tools in --denied-tools and fail if they are used anyway (--no-restrict-tools to allow every tool). Defaults to true."`
+
+	// DeniedTools lists the opencode tools to switch off. Nil if not given.
+	DeniedTools *string `name:"denied-tools" env:"INVOICER_DENIED_TOOLS" help:"Comma-separated opencode tools to switch off with --restrict-tools. Defaults to bash,webfetch,websearch."`
+
+	// Timeout bounds the generation step. Nil if not given.
+	Timeout *time.Duration `env:"INVOICER_TIMEOUT" help:"Maximum time to wait for the invoice to be generated, e.g. 120s. Defaults to 5m."`
+
+	// PDFOnly converts to PDF and removes the HTML invoice afterwards.
+	// Nil if not given.
+	PDFOnly *bool `name:"pdf-only" negatable:"" env:"INVOICER_PDF_ONLY" help:"Convert the invoice to PDF (implies --pdf) and remove the HTML once the PDF is written. The HTML is kept if the conversion fails."`
+
+	// PDFEngine is how the PDF is produced. Nil if not given.
+	PDFEngine *string `name:"pdf-engine" env:"INVOICER_PDF_ENGINE" predictor:"pdf_engine" help:"PDF engine: exec (run a conversion tool), chromedp (drive headless Chrome), or auto (chromedp, falling back to exec). Defaults to exec."`
+
+	// PDFTool is the PDF conversion tool to use. Nil if not given.
+	PDFTool *string `name:"pdf-tool" env:"INVOICER_PDF_TOOL" predictor:"pdf_tool" help:"PDF conversion tool: auto, wkhtmltopdf, chromium, chrome, edge, weasyprint, or pandoc. A tool other than auto must be installed. Defaults to auto."`
+
+	// PDFToolPath is the PDF tool's executable. Nil if not given.
+	PDFToolPath *string `name:"pdf-tool-path" env:"INVOICER_PDF_TOOL_PATH" type:"path" help:"Path to the PDF tool's executable, for one that is not on PATH."`
+
+	// PDFArgs are extra arguments for the PDF tool. Empty if not given.
+	PDFArgs []string `name:"pdf-arg" sep:"none" help:"Extra argument for the PDF tool, passed before the input and output, e.g. --pdf-arg=--enable-local-file-access. Repeat for more; replaces pdf_tool_args: in the config."`
+
+	// PDFTimeout bounds the PDF conversion step. Nil if not given.
+	PDFTimeout *time.Duration `name:"pdf-timeout" env:"INVOICER_PDF_TIMEOUT" help:"Maximum time to wait for the PDF conversion, e.g. 30s. Defaults to 60s."`
+
+	// PDFTitle is the pattern for the PDF's title. Nil if not given.
+	PDFTitle *string `name:"pdf-title" env:"INVOICER_PDF_TITLE" help:"Title of the PDF, with {number}, {vendor}, {customer}, {month} and {year} filled in. Defaults to \"Invoice {number} — {customer} — {month} {year}\"."`
+
+	// PDFPassword encrypts the PDF with a password.
+	PDFPassword pdfPasswordFlag `name:"pdf-password" env:"INVOICER_PDF_PASSWORD" help:"Encrypt the PDF so it needs a password to open and cannot be changed, with --pdf-password=PASSWORD or, without a value, a password asked for. Needs qpdf or pdftk."`
+
+	// Thumbnail renders a PNG preview of the invoice. Nil if not given.
+	Thumbnail *bool `negatable:"" env:"INVOICER_THUMBNAIL" help:"Render a PNG thumbnail of the invoice beside it with headless Chromium, Chrome or Edge (--no-thumbnail to skip). Defaults to false."`
+
+	// ThumbnailWidth is the width of the thumbnail in pixels. Nil if not given.
+	ThumbnailWidth *int `name:"thumbnail-width" env:"INVOICER_THUMBNAIL_WIDTH" help:"Width of the thumbnail in pixels. Defaults to 300."`
+
+	// Retries is how many times a failed generation is retried. Nil if not given.
+	Retries *int `env:"INVOICER_RETRIES" help:"Retry a failed generation up to this many times, with backoff. Defaults to 0."`
+
+	// MaxCost caps the cost of the generation in dollars. Nil if not given.
+	MaxCost *float64 `name:"max-cost" env:"INVOICER_MAX_COST" help:"Stop the generation once it has cost more than this many dollars, e.g. 0.50. Retries and fallback models count too."`
+
+	// SkipModelCheck passes the model to the backend without checking it.
+	SkipModelCheck bool `env:"INVOICER_SKIP_MODEL_CHECK" help:"Pass --model to the backend without checking that it looks like provider/model."`
+
+	// SkipVerify keeps whatever the backend wrote without checking it is HTML.
+	SkipVerify bool `env:"INVOICER_SKIP_VERIFY" help:"Keep whatever the backend wrote without checking that it is a complete HTML document."`
+
+	// NoFormat keeps the HTML as the backend wrote it, and Minify strips
+	// its insignificant whitespace instead of laying it out.
+	NoFormat bool `name:"no-format" env:"INVOICER_NO_FORMAT" help:"Keep the HTML as the backend wrote it, instead of laying it out with one block element per line."`
+	Minify   bool `env:"INVOICER_MINIFY" help:"Strip the whitespace and comments the HTML does not need, instead of laying it out with one block element per line."`
+
+	// StrictExit fails the generation when the backend exits with an
+	// error, even if it wrote a good invoice first.
+	StrictExit bool `name:"strict-exit" env:"INVOICER_STRICT_EXIT" help:"Fail when the backend exits with an error, even if it wrote a good invoice first."`
+
+	// FormatOut is the invoice's format: html, written by the backend, or
+	// text, laid out by invoicer itself.
+	FormatOut string `name:"format-out" enum:"html,text" default:"html" env:"INVOICER_FORMAT_OUT" help:"Invoice format: html, written by the backend, or text, a fixed-width plain-text invoice written without a backend (html or text)."`
+
+	// PromptOnly writes the generation prompt to a file instead of generating.
+	PromptOnly optionalPathFlag `name:"prompt-only" help:"Write the generation prompt to <invoice>.prompt.txt, or to PATH with --prompt-only=PATH, and exit without generating."`
+
+	// ExportJSON writes the invoice's data as JSON instead of generating.
+	ExportJSON optionalPathFlag `name:"export-json" help:"Write the invoice's data as JSON to <invoice>.json, to PATH with --export-json=PATH, or to standard output with --export-json=-, and exit without generating."`
+
+	// ExportUBL writes the invoice as a UBL e-invoice instead of generating.
+	ExportUBL optionalPathFlag `name:"export-ubl" help:"Write the invoice as a UBL 2.1 (EN 16931) e-invoice to <invoice>.xml, to PATH with --export-ubl=PATH, or to standard output with --export-ubl=-, and exit without generating."`
+
+	// SendStripe creates a Stripe invoice alongside the generated one.
+	SendStripe bool `name:"send-stripe" env:"INVOICER_SEND_STRIPE" help:"Create a draft Stripe invoice for the customer alongside the generated invoice."`
+
+	// StripeOnly creates the Stripe invoice instead of generating.
+	StripeOnly bool `name:"stripe-only" help:"Create the Stripe invoice (implies --send-stripe) and exit without generating."`
+
+	// SendPayPal creates a PayPal invoice alongside the generated one.
+	SendPayPal bool `name:"send-paypal" env:"INVOICER_SEND_PAYPAL" help:"Create a draft PayPal invoice for the customer alongside the generated invoice."`
+
+	// PayPalOnly creates the PayPal invoice instead of generating.
+	PayPalOnly bool `name:"paypal-only" help:"Create the PayPal invoice (implies --send-paypal) and exit without generating."`
+
+	// Finalize finalizes the Stripe invoice, and sends the PayPal invoice,
+	// instead of leaving drafts.
+	Finalize bool `help:"Finalize the Stripe invoice so it can be sent, and send the PayPal invoice to the customer, instead of leaving drafts."`
+
+	// StripeAPIKey is the secret key Stripe invoices are created with.
+	StripeAPIKey string `name:"stripe-api-key" env:"INVOICER_STRIPE_API_KEY" help:"Stripe secret key for --send-stripe. Defaults to stripe_api_key: in the config."`
+
+	// StripeCustomerID is the Stripe customer to bill.
+	StripeCustomerID string `name:"stripe-customer-id" env:"INVOICER_STRIPE_CUSTOMER_ID" help:"Stripe customer to bill with --send-stripe, e.g. cus_123."`
+
+	// StripeCustomerEmail finds or creates the Stripe customer to bill.
+	StripeCustomerEmail string `name:"stripe-customer-email" env:"INVOICER_STRIPE_CUSTOMER_EMAIL" help:"Email of the Stripe customer to bill with --send-stripe, used without --stripe-customer-id; the customer is created if there is none."`
+
+	// PayPalClientID and PayPalClientSecret are the PayPal REST app's
+	// credentials.
+	PayPalClientID     string `name:"paypal-client-id" env:"INVOICER_PAYPAL_CLIENT_ID" help:"Client ID of the PayPal REST app for --send-paypal. Defaults to paypal_client_id: in the config."`
+	PayPalClientSecret string `name:"paypal-client-secret" env:"INVOICER_PAYPAL_CLIENT_SECRET" help:"Secret of the PayPal REST app for --send-paypal. Defaults to paypal_client_secret: in the config."`
+
+	// PayPalCustomerEmail is where the PayPal invoice is sent.
+	PayPalCustomerEmail string `name:"paypal-customer-email" env:"INVOICER_PAYPAL_CUSTOMER_EMAIL" help:"Email the PayPal invoice is sent to with --send-paypal."`
+
+	// Send emails the invoice to the customer once it is generated.
+	Send bool `env:"INVOICER_SEND" help:"Email the PDF invoice to the customer (implies --pdf). See 'invoicer send'."`
+
+	// AttachHTML attaches the HTML invoice to the email too.
+	AttachHTML bool `name:"attach-html" help:"Attach the HTML invoice to the email as well as the PDF."`
+
+	// SMTPPassword is the password of the SMTP user.
+	SMTPPassword string `name:"smtp-password" env:"INVOICER_SMTP_PASSWORD" help:"Password of the SMTP user for --send. Defaults to smtp_password: in the config."`
+
+	// GmailToken and SendGridAPIKey are the credentials of the email APIs.
+	GmailToken     string `name:"gmail-token" env:"INVOICER_GMAIL_TOKEN" help:"Gmail OAuth access token for --send with the gmail email provider. Defaults to gmail_token: in the config."`
+	SendGridAPIKey string `name:"sendgrid-api-key" env:"INVOICER_SENDGRID_API_KEY" help:"SendGrid API key for --send with the sendgrid email provider. Defaults to sendgrid_api_key: in the config."`
+
+	// NotifyWebhook is where a message is posted once the invoice is
+	// generated.
+	NotifyWebhook string `name:"notify-webhook" env:"INVOICER_NOTIFY_WEBHOOK" help:"Slack incoming webhook or Discord webhook URL to post a message to once the invoice is generated. Defaults to notify_webhook: in the config."`
+
+	// NoNotify skips the notification.
+	NoNotify bool `name:"no-notify" help:"Do not post the notification of notify_webhook: in the config."`
+
+	// Notes are shown on the invoice.
+	Notes string `help:"Notes to show on the invoice, overriding notes: in the overrides file."`
+
+	// PONumber is the customer's purchase order number.
+	PONumber string `name:"po-number" help:"Customer's purchase order number to show on the invoice, overriding po_number: in the overrides file."`
+
+	// Items are ad-hoc line items billed after the weeks.
+	Items []string `name:"item" sep:"none" help:"Bill an ad-hoc line item after the weeks, written as description:amount[:qty[:unit]], e.g. --item \"Domain renewal:18.99\". With a quantity, the amount is the price of each unit. Repeat for more."`
+
+	// BillCurrency is the currency the invoice is billed in, if not
+	// dollars.
+	BillCurrency string `name:"bill-currency" env:"INVOICER_BILL_CURRENCY" help:"Bill the invoice in this ISO 4217 currency, e.g. EUR, converting every amount from dollars at one exchange rate. Defaults to bill_currency: in the config."`
+
+	// Language is the language the invoice is labelled in.
+	Language string `env:"INVOICER_LANGUAGE" help:"Label the invoice in this language, e.g. de or fr, with the built-in labels and any in translations.yaml. Defaults to language: in the config, or English."`
+
+	// FXRate is the exchange rate to bill at, instead of fetching one. Nil
+	// if not given.
+	FXRate *float64 `name:"fx-rate" help:"Units of the --bill-currency one dollar buys, e.g. 0.93, instead of fetching the rate of the last day of the month from fx_provider: in the config."`
+
+	// IssueDate is the date the invoice is issued, instead of today.
+	IssueDate string `name:"issue-date" aliases:"backdate" help:"Date the invoice is issued, e.g. 2025-01-31, instead of today."`
+
+	// Force issues the invoice on an --issue-date that looks wrong without
+	// asking.
+	Force bool `help:"Use an --issue-date before the invoiced month or over a year ago without asking."`
+
+	// Overrides is the overrides file to read instead of invoice.yaml in
+	// the current directory.
+	Overrides string `type:"path" help:"Read one-off overrides for this invoice from PATH instead of invoice.yaml in the current directory."`
+
+	// NoOverrides skips the overrides file.
+	NoOverrides bool `name:"no-overrides" help:"Ignore invoice.yaml in the current directory."`
+
+	// FromFile is a complete invoice spec to generate instead of the
+	// invoice the options describe.
+	FromFile string `name:"from-file" type:"path" help:"Generate the invoice specified in full by the YAML or JSON file at PATH, in the schema of --export-json, instead of from the config and options."`
+
+	// StyleFrom is an earlier HTML invoice for the new one to look like,
+	// or "last" for the latest one to the customer.
+	StyleFrom string `name:"style-from" help:"Style the invoice like an earlier one: last, the latest HTML invoice to the customer in the current directory, or the HTML invoice at PATH."`
+
+	// Hook is a command to run after the invoice is generated.
+	Hook string `env:"INVOICER_HOOK" help:"Command to run after the invoice is generated. Receives INVOICE_* environment variables."`
+}
+
+// resolveOptions merges config values with CLI-provided values.
+// CLI values take precedence over the local config, which takes precedence
+// over the global config. local may be nil.
+func (c *GenerateCmd) resolveOptions(global, local *config.Config) (*ResolvedOptions, error) {
+	if err := c.checkFlags(); err != nil {
+		return nil, err
 	}
+	cfg := config.Merge(global, local)
 
 	opts := &ResolvedOptions{
 		Month: c.Month,
 		Year:  c.Year,
-		PDF:   c.PDF,
 	}
 
-	// Merge string fields: CLI takes precedence, fall back to config.
+	// Merge fields: CLI takes precedence, fall back to config.
 	opts.Vendor = c.Vendor
-	if opts.Vendor == "" {
-		opts.Vendor = cfg.Vendor
+	if opts.Vendor == "" && cfg.Vendor != nil {
+		opts.Vendor = *cfg.Vendor
 	}
 
 	opts.Customer = c.Customer
-	if opts.Customer == "" {
-		opts.Customer = cfg.Customer
+	if opts.Customer == "" && cfg.Customer != nil {
+		opts.Customer = *cfg.Customer
+	}
+
+	opts.IBAN = c.IBAN
+	if opts.IBAN == "" && cfg.IBAN != nil {
+		opts.IBAN = *cfg.IBAN
+	}
+
+	opts.BIC = c.BIC
+	if opts.BIC == "" && cfg.BIC != nil {
+		opts.BIC = *cfg.BIC
+	}
+
+	for _, f := range []struct {
+		opt  *string
+		flag string
+		cfg  *string
+	}{
+		{&opts.RoutingNumber, c.RoutingNumber, cfg.RoutingNumber},
+		{&opts.AccountNumber, c.AccountNumber, cfg.AccountNumber},
+		{&opts.VendorCountry, c.VendorCountry, cfg.VendorCountry},
+		{&opts.CustomerCountry, c.CustomerCountry, cfg.CustomerCountry},
+		{&opts.VendorVATID, c.VendorVATID, cfg.VendorVATID},
+		{&opts.CustomerVATID, c.CustomerVATID, cfg.CustomerVATID},
+		{&opts.QuickBooksIncomeAccount, "", cfg.QuickBooksIncomeAccount},
+		{&opts.QuickBooksItem, "", cfg.QuickBooksItem},
+		{&opts.XeroAccountCode, "", cfg.XeroAccountCode},
+		{&opts.XeroTaxType, "", cfg.XeroTaxType},
+		{&opts.XeroRegion, "", cfg.XeroRegion},
+		{&opts.StripeAPIKey, c.StripeAPIKey, cfg.StripeAPIKey},
+		{&opts.StripeCustomerID, c.StripeCustomerID, cfg.StripeCustomerID},
+		{&opts.StripeCustomerEmail, c.StripeCustomerEmail, cfg.StripeCustomerEmail},
+		{&opts.PayPalClientID, c.PayPalClientID, cfg.PayPalClientID},
+		{&opts.PayPalClientSecret, c.PayPalClientSecret, cfg.PayPalClientSecret},
+		{&opts.PayPalCustomerEmail, c.PayPalCustomerEmail, cfg.PayPalCustomerEmail},
+		{&opts.PayPalPaymentTerm, "", cfg.PayPalPaymentTerm},
+		{&opts.FreshBooksToken, "", cfg.FreshBooksToken},
+		{&opts.FreshBooksAccountID, "", cfg.FreshBooksAccountID},
+		{&opts.FreshBooksClientID, "", cfg.FreshBooksClientID},
+		{&opts.EmailProvider, "", cfg.EmailProvider},
+		{&opts.SMTPHost, "", cfg.SMTPHost},
+		{&opts.SMTPSecurity, "", cfg.SMTPSecurity},
+		{&opts.SMTPUsername, "", cfg.SMTPUsername},
+		{&opts.SMTPPassword, c.SMTPPassword, cfg.SMTPPassword},
+		{&opts.GmailToken, c.GmailToken, cfg.GmailToken},
+		{&opts.SendGridAPIKey, c.SendGridAPIKey, cfg.SendGridAPIKey},
+		{&opts.EmailFrom, "", cfg.EmailFrom},
+		{&opts.EmailTo, "", cfg.EmailTo},
+		{&opts.EmailSubject, "", cfg.EmailSubject},
+		{&opts.EmailBody, "", cfg.EmailBody},
+		{&opts.NotifyWebhook, c.NotifyWebhook, cfg.NotifyWebhook},
+		{&opts.NotifyMessage, "", cfg.NotifyMessage},
+	} {
+		*f.opt = f.flag
+		if *f.opt == "" && f.cfg != nil {
+			*f.opt = *f.cfg
+		}
 	}
 
-	// Merge numeric fields: CLI takes precedence (non-zero), fall back to config.
-	opts.Rate = c.Rate
-	if opts.Rate == 0 {
-		opts.Rate = cfg.Rate
+	// Numeric flags are nil when not given, so an explicit zero is honored.
+	if c.Rate != nil {
+		opts.Rate, opts.RateFlag = *c.Rate, true
+	} else if cfg.Rate != nil {
+		opts.Rate = *cfg.Rate
 	}
 
-	opts.Hours = c.Hours
-	if opts.Hours == 0 {
-		opts.Hours = cfg.Hours
+	if c.Hours != nil {
+		opts.Hours = *c.Hours
+	} else if cfg.Hours != nil {
+		opts.Hours = *cfg.Hours
+	}
+	if c.MonthHours != nil {
+		if m := *c.MonthHours; m <= 0 || m/invoice.HoursIncrement != math.Round(m/invoice.HoursIncrement) {
+			return nil, fmt.Errorf("--month-hours must be a positive number of quarter hours, e.g. 120 or 87.75, got %v", m)
+		}
+		opts.MonthHours = *c.MonthHours
 	}
 
-	// Merge PDF: CLI flag (-p) sets to true; if false (not set), use config value.
-	if !c.PDF && cfg.PDF != nil {
+	// A vendor of an agency's vendors: list invoices with its own rate,
+	// hours and bank account, unless flags give them.
+	if v := cfg.FindVendor(opts.Vendor); v != nil {
+		opts.Vendor, opts.PerVendor = v.Name, true
+		if c.Rate == nil && v.Rate != nil {
+			opts.Rate = *v.Rate
+		}
+		if c.Hours == nil && v.Hours != nil {
+			opts.Hours = *v.Hours
+		}
+		if c.IBAN == "" && v.IBAN != nil {
+			opts.IBAN = *v.IBAN
+		}
+		if c.BIC == "" && v.BIC != nil {
+			opts.BIC = *v.BIC
+		}
+	}
+
+	// Merge PDF: --pdf or --no-pdf, then config, then false.
+	if c.PDF != nil {
+		opts.PDF = *c.PDF
+	} else if cfg.PDF != nil {
 		opts.PDF = *cfg.PDF
 	}
 
-	// Merge model: CLI default is "anthropic/claude-haiku-4-5"; config may override
-	// but CLI explicit value takes precedence. Since we can't distinguish CLI default
-	// from user-specified, we prefer the CLI value (which may be the default).
-	opts.Model = c.Model
-	if opts.Model == "" && cfg.Model != "" {
-		opts.Model = cfg.Model
+	// --pdf-only implies --pdf. --no-pdf still overrides pdf_only: true in
+	// the config.
+	switch {
+	case c.PDFOnly != nil:
+		opts.PDFOnly = *c.PDFOnly
+	case cfg.PDFOnly != nil:
+		opts.PDFOnly = *cfg.PDFOnly && (c.PDF == nil || *c.PDF)
+	}
+	if opts.PDFOnly {
+		opts.PDF = true
+	}
+
+	// Merge model: explicit flag, then config, then the built-in default.
+	switch {
+	case c.Model != nil:
+		opts.Model = *c.Model
+	case cfg.Model != nil:
+		opts.Model = *cfg.Model
+	default:
+		opts.Model = defaultModel
+	}
+
+	switch {
+	case c.Backend != nil:
+		opts.Backend = *c.Backend
+	case cfg.Backend != nil:
+		opts.Backend = *cfg.Backend
+	default:
+		opts.Backend = invoice.DefaultBackend
+	}
+	if err := validateBackend(opts.Backend); err != nil {
+		return nil, err
+	}
+	if opts.Backend != "ollama" && !c.SkipModelCheck {
+		if err := checkModels(opts.Model); err != nil {
+			return nil, err
+		}
+	}
+
+	switch {
+	case c.OllamaHost != nil:
+		opts.OllamaHost = *c.OllamaHost
+	case cfg.OllamaHost != nil:
+		opts.OllamaHost = *cfg.OllamaHost
+	default:
+		opts.OllamaHost = invoice.DefaultOllamaHost
+	}
+
+	switch {
+	case c.OllamaModel != nil:
+		opts.OllamaModel = *c.OllamaModel
+	case cfg.OllamaModel != nil:
+		opts.OllamaModel = *cfg.OllamaModel
+	default:
+		opts.OllamaModel = invoice.DefaultOllamaModel
+	}
+
+	switch {
+	case c.Agent != nil:
+		opts.Agent = *c.Agent
+	case cfg.Agent != nil:
+		opts.Agent = *cfg.Agent
+	}
+
+	switch {
+	case c.Session != nil:
+		opts.Session = *c.Session
+	case cfg.Session != nil:
+		opts.Session = *cfg.Session
+	}
+
+	// Tools are restricted unless switched off; the deny list then comes
+	// from the flag, the config, or the built-in default.
+	restrict := true
+	if c.RestrictTools != nil {
+		restrict = *c.RestrictTools
+	} else if cfg.RestrictTools != nil {
+		restrict = *cfg.RestrictTools
+	}
+	switch {
+	case !restrict:
+	case c.DeniedTools != nil:
+		opts.DeniedTools = config.SplitTools(*c.DeniedTools)
+	case cfg.DeniedTools != nil:
+		opts.DeniedTools = config.SplitTools(*cfg.DeniedTools)
+	default:
+		opts.DeniedTools = invoice.DefaultDeniedTools
+	}
+
+	switch {
+	case c.Timeout != nil:
+		opts.Timeout = *c.Timeout
+	case cfg.Timeout != nil:
+		opts.Timeout = time.Duration(*cfg.Timeout)
+	default:
+		opts.Timeout = defaultTimeout
+	}
+	if opts.Timeout <= 0 {
+		return nil, fmt.Errorf("timeout must be positive, got %s", opts.Timeout)
+	}
+
+	switch {
+	case c.PDFEngine != nil:
+		opts.PDFEngine = *c.PDFEngine
+	case cfg.PDFEngine != nil:
+		opts.PDFEngine = *cfg.PDFEngine
+	default:
+		opts.PDFEngine = invoice.ExecPDFEngine
+	}
+	if err := invoice.CheckPDFEngine(opts.PDFEngine); err != nil {
+		return nil, err
+	}
+	switch {
+	case c.PDFTool != nil:
+		opts.PDFTool = *c.PDFTool
+	case cfg.PDFTool != nil:
+		opts.PDFTool = *cfg.PDFTool
+	default:
+		opts.PDFTool = invoice.AutoPDFTool
+	}
+	if err := invoice.CheckPDFTool(opts.PDFTool); err != nil {
+		return nil, err
+	}
+	switch {
+	case c.PDFToolPath != nil:
+		opts.PDFToolPath = *c.PDFToolPath
+	case cfg.PDFToolPath != nil:
+		opts.PDFToolPath = *cfg.PDFToolPath
+	}
+	opts.PDFArgs = c.PDFArgs
+	if len(opts.PDFArgs) == 0 {
+		opts.PDFArgs = cfg.PDFToolArgs
+	}
+
+	switch {
+	case c.PDFTimeout != nil:
+		opts.PDFTimeout = *c.PDFTimeout
+	case cfg.PDFTimeout != nil:
+		opts.PDFTimeout = time.Duration(*cfg.PDFTimeout)
+	default:
+		opts.PDFTimeout = defaultPDFTimeout
+	}
+	if opts.PDFTimeout <= 0 {
+		return nil, fmt.Errorf("pdf timeout must be positive, got %s", opts.PDFTimeout)
+	}
+
+	switch {
+	case c.PDFTitle != nil:
+		opts.PDFTitle = *c.PDFTitle
+	case cfg.PDFTitle != nil:
+		opts.PDFTitle = *cfg.PDFTitle
+	default:
+		opts.PDFTitle = invoice.DefaultPDFTitle
+	}
+	if err := invoice.CheckPDFTitle(opts.PDFTitle); err != nil {
+		return nil, err
+	}
+	if c.PDFPassword.Set && !opts.PDF {
+		return nil, errors.New("--pdf-password needs --pdf")
+	}
+	opts.PDFPassword = c.PDFPassword.Password
+
+	if c.Thumbnail != nil {
+		opts.Thumbnail = *c.Thumbnail
+	} else if cfg.Thumbnail != nil {
+		opts.Thumbnail = *cfg.Thumbnail
+	}
+	switch {
+	case c.ThumbnailWidth != nil:
+		opts.ThumbnailWidth = *c.ThumbnailWidth
+	case cfg.ThumbnailWidth != nil:
+		opts.ThumbnailWidth = *cfg.ThumbnailWidth
+	default:
+		opts.ThumbnailWidth = invoice.DefaultThumbnailWidth
+	}
+	if opts.ThumbnailWidth <= 0 {
+		return nil, fmt.Errorf("thumbnail width must be positive, got %d", opts.ThumbnailWidth)
+	}
+
+	// Check the Stripe and PayPal settings before spending anything on
+	// generation.
+	opts.SendStripe = c.SendStripe || c.StripeOnly
+	opts.SendPayPal = c.SendPayPal || c.PayPalOnly
+	opts.Finalize = c.Finalize
+	if cfg.StripeDaysUntilDue != nil {
+		opts.StripeDaysUntilDue = *cfg.StripeDaysUntilDue
+	}
+	if cfg.PayPalSandbox != nil {
+		opts.PayPalSandbox = *cfg.PayPalSandbox
+	}
+	switch {
+	case opts.SendStripe && opts.StripeAPIKey == "":
+		return nil, errors.New("a Stripe API key is required for --send-stripe (use --stripe-api-key or set stripe_api_key in config)")
+	case opts.SendStripe && opts.StripeCustomerID == "" && opts.StripeCustomerEmail == "":
+		return nil, errors.New("a Stripe customer is required for --send-stripe (use --stripe-customer-id or --stripe-customer-email, or set stripe_customer_id or stripe_customer_email in config)")
+	case opts.SendPayPal && (opts.PayPalClientID == "" || opts.PayPalClientSecret == ""):
+		return nil, errors.New("PayPal client credentials are required for --send-paypal (use --paypal-client-id and --paypal-client-secret, or set paypal_client_id and paypal_client_secret in config)")
+	case opts.SendPayPal && opts.PayPalCustomerEmail == "":
+		return nil, errors.New("the customer's email is required for --send-paypal (use --paypal-customer-email or set paypal_customer_email in config)")
+	}
+	if opts.SendPayPal && opts.PayPalPaymentTerm != "" {
+		if err := invoice.ValidatePayPalPaymentTerm(opts.PayPalPaymentTerm); err != nil {
+			return nil, err
+		}
+	}
+
+	// Emailing the invoice needs its PDF, and the email settings.
+	opts.Send, opts.AttachHTML = c.Send, c.AttachHTML
+	if cfg.SMTPPort != nil {
+		opts.SMTPPort = *cfg.SMTPPort
+	}
+	if c.Send {
+		if err := opts.checkEmail(); err != nil {
+			return nil, err
+		}
+		opts.PDF = true
+	}
+	if c.AttachHTML && opts.PDFOnly {
+		return nil, errors.New("--attach-html cannot be combined with pdf_only: true in the config, which removes the HTML invoice")
+	}
+
+	// A text invoice has no HTML to convert, so the PDF and thumbnail
+	// settings in the config are passed over; asking for them is an error
+	// caught by checkFlags.
+	opts.FormatOut = c.FormatOut
+	if opts.FormatOut == textFormat {
+		opts.PDF, opts.PDFOnly, opts.Thumbnail = false, false, false
+	}
+
+	switch {
+	case c.Retries != nil:
+		opts.Retries = *c.Retries
+	case cfg.Retries != nil:
+		opts.Retries = *cfg.Retries
+	}
+	if opts.Retries < 0 || opts.Retries > config.MaxRetries {
+		return nil, fmt.Errorf("retries must be between 0 and %d, got %d", config.MaxRetries, opts.Retries)
+	}
+
+	switch {
+	case c.MaxCost != nil:
+		opts.MaxCost = *c.MaxCost
+	case cfg.MaxCost != nil:
+		opts.MaxCost = *cfg.MaxCost
+	}
+	if opts.MaxCost < 0 || (c.MaxCost != nil && opts.MaxCost == 0) {
+		return nil, fmt.Errorf("max cost must be positive, got %v", opts.MaxCost)
+	}
+
+	if c.NoNotify {
+		opts.NotifyWebhook = ""
+	}
+
+	opts.Hook = c.Hook
+	if opts.Hook == "" && cfg.PostGenerateHook != nil {
+		opts.Hook = *cfg.PostGenerateHook
+	}
+	if cfg.HookStrict != nil {
+		opts.HookStrict = *cfg.HookStrict
+	}
+
+	opts.Notes, opts.PONumber = c.Notes, c.PONumber
+	if c.IssueDate != "" {
+		issued, err := time.Parse(time.DateOnly, c.IssueDate)
+		if err != nil {
+			return nil, fmt.Errorf("issue date %q is not a date like 2025-01-31", c.IssueDate)
+		}
+		opts.IssueDate = issued
+	}
+	for _, s := range c.DaysOff {
+		day, err := time.Parse(time.DateOnly, strings.TrimSpace(s))
+		if err != nil {
+			return nil, fmt.Errorf("day off %q is not a date like 2025-01-31", s)
+		}
+		opts.DaysOff = append(opts.DaysOff, day)
+	}
+	opts.HolidayCountry = c.HolidayCountry
+	if opts.HolidayCountry == "" {
+		opts.HolidayCountry = cfg.HolidayCountry()
+	}
+	if opts.HolidayCountry != "" {
+		if err := invoice.ValidateHolidayRegion(opts.HolidayCountry); err != nil {
+			return nil, err
+		}
+		opts.HolidayCountry = invoice.NormalizeHolidayRegion(opts.HolidayCountry)
+	}
+	for _, s := range c.Items {
+		item, err := invoice.ParseItem(s)
+		if err != nil {
+			return nil, err
+		}
+		opts.Items = append(opts.Items, item)
+	}
+
+	// Exports show dates as the invoice does unless set apart.
+	opts.DateFormat = invoice.DateLong
+	if cfg.DateFormat != nil {
+		opts.DateFormat = *cfg.DateFormat
+	}
+	opts.ExportDateFormat = opts.DateFormat
+	if cfg.ExportDateFormat != nil {
+		opts.ExportDateFormat = *cfg.ExportDateFormat
+	}
+	for _, format := range []string{opts.DateFormat, opts.ExportDateFormat} {
+		if err := invoice.ValidateDateFormat(format); err != nil {
+			return nil, err
+		}
+	}
+	opts.RatePrecision, opts.HoursPrecision = cfg.RatePrecision, cfg.HoursPrecision
+	opts.ShowWorkdays = cfg.ShowWorkdays != nil && *cfg.ShowWorkdays
+	translations, err := loadTranslations()
+	if err != nil {
+		return nil, err
+	}
+	opts.Translations = translations
+	opts.Language = c.Language
+	if opts.Language == "" && cfg.Language != nil {
+		opts.Language = *cfg.Language
+	}
+	if opts.Language != "" {
+		if err := invoice.ValidateLanguage(opts.Language); err != nil {
+			return nil, err
+		}
+		opts.Language = invoice.NormalizeLanguage(opts.Language)
+		checkLanguage(opts.Language, opts.Translations)
+	}
+
+	opts.BillCurrency = strings.ToUpper(c.BillCurrency)
+	if opts.BillCurrency == "" && cfg.BillCurrency != nil {
+		opts.BillCurrency = strings.ToUpper(*cfg.BillCurrency)
+	}
+	if opts.BillCurrency == invoice.Currency {
+		opts.BillCurrency = ""
+	}
+	if opts.BillCurrency != "" {
+		if err := invoice.ValidateCurrency(opts.BillCurrency); err != nil {
+			return nil, err
+		}
+	}
+	if c.FXRate != nil {
+		if *c.FXRate <= 0 || math.IsInf(*c.FXRate, 0) || math.IsNaN(*c.FXRate) {
+			return nil, fmt.Errorf("exchange rate must be positive, got %v", *c.FXRate)
+		}
+		opts.FXRate = *c.FXRate
+	}
+	opts.FXProvider = invoice.DefaultFXProvider
+	if cfg.FXProvider != nil {
+		opts.FXProvider = *cfg.FXProvider
+	}
+	if err := invoice.ValidateFXProvider(opts.FXProvider); err != nil {
+		return nil, err
+	}
+	if cfg.FXAPIKey != nil {
+		opts.FXAPIKey = *cfg.FXAPIKey
 	}
 
+	opts.SkipVerify = c.SkipVerify
+	opts.NoFormat = c.NoFormat
+	opts.Minify = c.Minify
+	opts.StrictExit = c.StrictExit
+
 	return opts, nil
 }
 
@@ -115,66 +938,824 @@ type ResolvedOptions struct {
 	Hours    float64
 	PDF      bool
 	Model    string
+	Backend  string
+
+	// RateFlag is set when --rate gives the rate. Only such a per-invoice
+	// override may bill at a rate of zero, e.g. for pro bono work; a rate
+	// kept in the config must be positive.
+	RateFlag bool
+
+	// PerVendor is set when Vendor is one of the config's vendors:, whose
+	// invoices name the vendor in their numbers and file names.
+	PerVendor bool
+
+	// Timesheet sets the hours of the weeks instead of Hours. Nil if no
+	// timesheet is given.
+	Timesheet []invoice.TimesheetEntry
+
+	// MonthHours, if positive, is split across the weeks instead of
+	// Hours.
+	MonthHours float64
+
+	// DaysOff are the dates not worked, which are not workdays of their
+	// weeks.
+	DaysOff []time.Time
+
+	// HolidayCountry is the region whose public holidays are not
+	// workdays of their weeks, or "" for none.
+	HolidayCountry string
+
+	// DateFormat is the format the invoice shows dates in, and
+	// ExportDateFormat the format exports label weeks in.
+	DateFormat       string
+	ExportDateFormat string
+
+	// Language is the language the invoice is labelled in, "" for
+	// English, and Translations the labels of translations.yaml.
+	Language     string
+	Translations invoice.Translations
+
+	// ShowWorkdays adds each week's workdays to its line item.
+	ShowWorkdays bool
+
+	// RatePrecision and HoursPrecision are the decimals the invoice shows
+	// rates and hours with; nil for the defaults.
+	RatePrecision  *int
+	HoursPrecision *int
+
+	IBAN          string
+	BIC           string
+	RoutingNumber string
+	AccountNumber string
+
+	VendorCountry   string
+	CustomerCountry string
+	VendorVATID     string
+	CustomerVATID   string
+
+	QuickBooksIncomeAccount string
+	QuickBooksItem          string
+
+	XeroAccountCode string
+	XeroTaxType     string
+	XeroRegion      string
+
+	// Finalize finalizes the Stripe invoice and sends the PayPal one.
+	Finalize bool
+
+	SendStripe          bool
+	StripeAPIKey        string
+	StripeCustomerID    string
+	StripeCustomerEmail string
+	StripeDaysUntilDue  int
+
+	SendPayPal          bool
+	PayPalClientID      string
+	PayPalClientSecret  string
+	PayPalSandbox       bool
+	PayPalCustomerEmail string
+	PayPalPaymentTerm   string
+
+	FreshBooksToken     string
+	FreshBooksAccountID string
+	FreshBooksClientID  string
+
+	// Send emails the PDF invoice, and AttachHTML the HTML one with it.
+	Send           bool
+	AttachHTML     bool
+	EmailProvider  string
+	SMTPHost       string
+	SMTPPort       int
+	SMTPSecurity   string
+	SMTPUsername   string
+	SMTPPassword   string
+	GmailToken     string
+	SendGridAPIKey string
+	EmailFrom      string
+	EmailTo        string
+	EmailSubject   string
+	EmailBody      string
+
+	// NotifyWebhook is posted NotifyMessage once the invoice is generated.
+	NotifyWebhook string
+	NotifyMessage string
+
+	OllamaHost  string
+	OllamaModel string
+
+	Agent       string
+	Session     string
+	DeniedTools []string
+
+	Timeout time.Duration
+	Retries int
+	MaxCost float64
+
+	PDFOnly     bool
+	PDFEngine   string
+	PDFTool     string
+	PDFToolPath string
+	PDFArgs     []string
+	PDFTimeout  time.Duration
+	PDFTitle    string
+	PDFPassword secret
+
+	Thumbnail      bool
+	ThumbnailWidth int
+
+	FormatOut string
+
+	// Notes and PONumber are shown on the invoice.
+	Notes    string
+	PONumber string
+
+	// Items are billed after the weeks and the adjustments of the
+	// overrides file.
+	Items []invoice.Adjustment
+
+	// IssueDate is the date the invoice is issued; zero for today.
+	IssueDate time.Time
+
+	// BillCurrency is the currency the invoice is billed in; "" for
+	// dollars. FXRate is its exchange rate, or 0 to fetch it from
+	// FXProvider with FXAPIKey.
+	BillCurrency string
+	FXRate       float64
+	FXProvider   string
+	FXAPIKey     string
+
+	// Overrides changes the weeks of the invoice and adds adjustments, as
+	// read from OverridesPath. Nil if there is no overrides file.
+	Overrides     *config.Overrides
+	OverridesPath string
+
+	SkipVerify bool
+	NoFormat   bool
+	Minify     bool
+	StrictExit bool
+
+	Hook       string
+	HookStrict bool
 }
 
 // Run executes the generate subcommand (invoice generation).
-func (c *GenerateCmd) Run() error {
-	opts, err := c.resolveOptions("")
+// Generation and PDF conversion stop when ctx is cancelled.
+func (c *GenerateCmd) Run(g *Globals, ctx context.Context) error {
+	configPath, err := g.configPath()
+	if err != nil {
+		return err
+	}
+	global, err := g.loadConfig(configPath)
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+	local, err := g.localConfig()
+	if err != nil {
+		return err
+	}
+	if c.AllVendors {
+		return c.runAllVendors(g, ctx, config.Merge(global, local))
+	}
+	run, err := c.prepare(g, global, local)
 	if err != nil {
 		return err
 	}
+	opts, inv := run.opts, run.inv
+	warnPayment(inv.Payment)
+	if c.IssueDate != "" {
+		// Standard input may hold the timesheet rather than an answer.
+		terminal := c.Timesheet != stdinPath && stdinIsTerminal()
+		if err := checkIssueDate(inv, c.Force, terminal, os.Stdin, os.Stderr); err != nil {
+			return err
+		}
+	}
+	if run.overPath != "" {
+		printOverrides(os.Stderr, run.overPath, run.changes)
+	}
+
+	// Determine output paths.
+	dir := invoice.CurrentDir()
+	htmlPath := invoice.InvoiceFilePath(inv, dir)
+
+	if opts.BillCurrency != "" && inv.Exchange == nil {
+		if inv.Exchange, err = exchangeRate(ctx, opts, inv); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Exchange rate: %s\n", inv.Exchange)
+	}
+
+	if c.StyleFrom != "" && opts.FormatOut != textFormat {
+		if inv.Style, err = styleReference(c.StyleFrom, inv, dir); err != nil {
+			return err
+		}
+	}
 
-	// Validate required options.
+	if c.PromptOnly.Set {
+		path := c.PromptOnly.Path
+		if path == "" {
+			path = invoice.PromptFilePath(inv, dir)
+		}
+		return writePromptFile(inv, htmlPath, path, os.Stdout)
+	}
+	// The exports label the weeks in their own date format.
+	if c.ExportJSON.Set || c.ExportUBL.Set {
+		inv.DateFormat = opts.ExportDateFormat
+	}
+	if c.ExportJSON.Set {
+		path := c.ExportJSON.Path
+		if path == "" {
+			path = invoice.JSONFilePath(inv, dir)
+		}
+		return writeInvoiceJSON(inv, path, os.Stdout)
+	}
+	if c.ExportUBL.Set {
+		path := c.ExportUBL.Path
+		if path == "" {
+			path = invoice.UBLFilePath(inv, dir)
+		}
+		return writeInvoiceUBL(opts, inv, path, os.Stdout)
+	}
+	if c.StripeOnly || c.PayPalOnly {
+		return sendPaymentInvoices(ctx, opts, inv, os.Stdout)
+	}
+
+	if opts.FormatOut == textFormat {
+		if err := writeTextInvoice(inv, invoice.TextFilePath(inv, dir), os.Stdout); err != nil {
+			return err
+		}
+		if err := sendPaymentInvoices(ctx, opts, inv, os.Stdout); err != nil {
+			return err
+		}
+		if err := runPostGenerateHook(opts, inv, "", "", invoice.TextFilePath(inv, dir), os.Stderr); err != nil {
+			return err
+		}
+		notifyGenerated(ctx, opts, inv, invoice.TextFilePath(inv, dir), os.Stderr)
+		return nil
+	}
+
+	// Find the encryption tool, and ask for the password, before spending
+	// anything on generation.
+	if c.PDFPassword.Set {
+		if err := invoice.CheckPDFEncrypter(); err != nil {
+			return err
+		}
+		if opts.PDFPassword == "" {
+			if opts.PDFPassword, err = promptPassword(os.Stdin, os.Stderr); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Generate HTML invoice via the selected backend.
+	fmt.Printf("Generating invoice for %s %d...\n", inv.Month.String(), inv.Year)
+	start := time.Now()
+	res, err := generateHTML(ctx, opts, inv, htmlPath)
+	if err != nil {
+		return fmt.Errorf("generating HTML invoice: %w", err)
+	}
+	if !opts.PDFOnly {
+		fmt.Printf("HTML invoice written to: %s\n", htmlPath)
+	}
+	fmt.Println(generationSummary(res, time.Since(start)))
+
+	// Convert to PDF if requested. The thumbnail comes after it, from the
+	// HTML, which writePDF keeps for it.
+	var pdfPath, thumbnailPath, thumbnailTool string
+	if opts.PDF {
+		pdfPath = invoice.PDFFilePath(inv, dir)
+		fmt.Printf("Converting to PDF...\n")
+		res, err := writePDF(ctx, opts, inv, htmlPath, pdfPath)
+		if err != nil {
+			return fmt.Errorf("converting to PDF: %w", err)
+		}
+		fmt.Printf("PDF invoice written to: %s (%s)\n", pdfPath, res)
+		if res.HTMLRemoved {
+			htmlPath = ""
+		}
+		thumbnailPath, thumbnailTool = res.Thumbnail, res.ThumbnailTool
+	} else if opts.Thumbnail {
+		thumbnailPath, thumbnailTool = writeThumbnail(ctx, opts, inv, htmlPath)
+	}
+	if thumbnailPath != "" {
+		fmt.Printf("Thumbnail written to: %s (%s)\n", thumbnailPath, thumbnailTool)
+	}
+
+	if err := sendPaymentInvoices(ctx, opts, inv, os.Stdout); err != nil {
+		return err
+	}
+
+	if opts.Send {
+		if err := sendInvoiceEmail(ctx, opts, inv, pdfPath, htmlPath, os.Stdout); err != nil {
+			return err
+		}
+	}
+
+	if err := runPostGenerateHook(opts, inv, htmlPath, pdfPath, "", os.Stderr); err != nil {
+		return err
+	}
+	notifyGenerated(ctx, opts, inv, cmp.Or(pdfPath, htmlPath), os.Stderr)
+	return nil
+}
+
+// preparedRun is the invoice a run is for, before anything is made from
+// it.
+type preparedRun struct {
+	opts *ResolvedOptions
+	inv  *invoice.Invoice
+	// overPath is the overrides file applied, and changes what it changed,
+	// for printOverrides; overPath is "" if there was none.
+	overPath string
+	changes  []string
+}
+
+// prepare resolves c's options and builds the invoice they describe, from
+// the config, the overrides file, the timesheet and the options, or from
+// the spec file of --from-file. Every command that works out an invoice as
+// a run would goes through it, so they agree on the amounts.
+func (c *GenerateCmd) prepare(g *Globals, global, local *config.Config) (*preparedRun, error) {
+	over, overPath, err := c.loadOverrides()
+	if err != nil {
+		return nil, err
+	}
+	opts, err := c.resolveOptions(global, local)
+	if err != nil {
+		return nil, err
+	}
+	c.warnFlags(opts)
+	if c.Timesheet != "" {
+		if opts.Timesheet, err = readTimesheet(c.Timesheet, os.Stdin); err != nil {
+			return nil, err
+		}
+	}
+	changes := c.applyOverrides(opts, over, overPath)
+
+	var inv *invoice.Invoice
+	if c.FromFile != "" {
+		if inv, err = readSpecFile(c.FromFile, g.StrictConfig); err == nil {
+			opts.display(inv)
+		}
+	} else {
+		inv, err = opts.buildInvoice()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &preparedRun{opts: opts, inv: inv, overPath: overPath, changes: changes}, nil
+}
+
+// checkModels rejects a model list that the backend could not use, and warns
+// about models from providers invoicer does not know.
+func checkModels(list string) error {
+	models, err := config.ParseModels(list)
+	if err != nil {
+		return fmt.Errorf("%w (use --skip-model-check to pass it through anyway)", err)
+	}
+	for _, model := range models {
+		if !config.KnownProvider(model) {
+			fmt.Fprintf(config.Warnings, "Warning: model %q is from an unknown provider (known: %s); using it anyway\n", model, strings.Join(config.KnownProviders, ", "))
+		}
+	}
+	return nil
+}
+
+// generationSummary describes a finished generation in one line, e.g.
+// "model: anthropic/claude-haiku-4-5, 12.3k tokens, $0.04, 38s".
+func generationSummary(res *invoice.Result, elapsed time.Duration) string {
+	var parts []string
+	if res.Model != "" {
+		parts = append(parts, "model: "+res.Model)
+	}
+	if usage := res.Usage.String(); usage != "" {
+		parts = append(parts, usage)
+	}
+	if res.SessionID != "" {
+		parts = append(parts, "session: "+res.SessionID)
+	}
+	if elapsed < 10*time.Second {
+		elapsed = elapsed.Round(100 * time.Millisecond)
+	} else {
+		elapsed = elapsed.Round(time.Second)
+	}
+	parts = append(parts, elapsed.String())
+	return strings.Join(parts, ", ")
+}
+
+// buildInvoice checks the required options and computes the invoice they
+// describe, which must pass invoice.Invoice.Validate before anything is
+// generated from it.
+func (opts *ResolvedOptions) buildInvoice() (*invoice.Invoice, error) {
 	if opts.Vendor == "" {
-		return fmt.Errorf("vendor is required (use --vendor or set in config)")
+		return nil, fmt.Errorf("vendor is required (use --vendor or set in config)")
 	}
 	if opts.Customer == "" {
-		return fmt.Errorf("customer is required (use --customer or set in config)")
+		return nil, fmt.Errorf("customer is required (use --customer or set in config)")
 	}
-	if opts.Rate == 0 {
-		return fmt.Errorf("rate is required (use --rate or set in config)")
+	if opts.Rate <= 0 && !opts.zeroRate() {
+		return nil, fmt.Errorf("rate is required and must be positive (use --rate or set in config)")
 	}
-	if opts.Hours == 0 {
-		return fmt.Errorf("hours is required (use --hours or set in config)")
+	if opts.Hours <= 0 && opts.Timesheet == nil && opts.MonthHours <= 0 {
+		return nil, fmt.Errorf("hours is required and must be positive (use --hours, --month-hours or --timesheet, or set in config)")
 	}
 
-	// Resolve month and year.
 	month, year, err := invoice.ResolveMonthYear(opts.Month, opts.Year, invoice.Now())
 	if err != nil {
-		return fmt.Errorf("resolving month/year: %w", err)
+		return nil, fmt.Errorf("resolving month/year: %w", err)
+	}
+
+	daysOff := opts.DaysOff
+	if opts.HolidayCountry != "" {
+		holidays, err := invoice.Holidays(opts.HolidayCountry, year)
+		if err != nil {
+			return nil, err
+		}
+		daysOff = slices.Clip(daysOff)
+		for _, h := range holidays {
+			daysOff = append(daysOff, h.Date)
+		}
 	}
 
-	// Build invoice.
-	weeks := invoice.WeeksForMonth(year, month, opts.Hours)
 	inv := &invoice.Invoice{
-		Month:    month,
-		Year:     year,
-		Vendor:   opts.Vendor,
-		Customer: opts.Customer,
-		Rate:     opts.Rate,
-		Weeks:    weeks,
+		Month:     month,
+		Year:      year,
+		Vendor:    opts.Vendor,
+		Customer:  opts.Customer,
+		PerVendor: opts.PerVendor,
+		Rate:      opts.Rate,
+		Weeks:     invoice.WeeksForMonthExcept(year, month, opts.Hours, daysOff),
+		Issued:    opts.IssueDate,
+		Notes:     opts.Notes,
+		PONumber:  opts.PONumber,
+	}
+	if inv.Issued.IsZero() {
+		inv.Issued = invoice.Now()
+	}
+	for _, day := range opts.DaysOff {
+		if !slices.ContainsFunc(inv.Weeks, func(w invoice.Week) bool { return !day.Before(w.Start) && !day.After(w.End) }) {
+			fmt.Fprintf(config.Warnings, "Warning: day off %s is in no week of %s %d; ignoring it\n", day.Format(time.DateOnly), month, year)
+		}
+	}
+	opts.display(inv)
+	if opts.Timesheet != nil {
+		if err := invoice.ApplyTimesheet(inv.Weeks, opts.Timesheet); err != nil {
+			return nil, err
+		}
 	}
+	overridden, err := opts.overrideWeeks(inv)
+	if err != nil {
+		return nil, err
+	}
+	if opts.MonthHours > 0 {
+		if err := distributeMonthHours(inv.Weeks, overridden, opts.MonthHours); err != nil {
+			return nil, err
+		}
+	}
+	inv.Adjustments = append(inv.Adjustments, opts.Items...)
+	// A BIC without an IBAN still sets Payment, so generation warns that
+	// the payment QR code is left out.
+	if opts.IBAN != "" || opts.BIC != "" || opts.RoutingNumber != "" || opts.AccountNumber != "" {
+		inv.Payment = &invoice.PaymentDetails{
+			IBAN:          opts.IBAN,
+			BIC:           opts.BIC,
+			RoutingNumber: opts.RoutingNumber,
+			AccountNumber: opts.AccountNumber,
+		}
+	}
+	if err := opts.validate(inv); err != nil {
+		return nil, err
+	}
+	return inv, nil
+}
 
-	// Determine output paths.
-	dir := invoice.CurrentDir()
-	htmlPath := invoice.InvoiceFilePath(inv, dir)
+// zeroRate reports whether the invoice is billed at a rate of zero given
+// with --rate 0.
+func (opts *ResolvedOptions) zeroRate() bool {
+	return opts.RateFlag && opts.Rate == 0
+}
 
-	// Generate HTML invoice via opencode.
-	fmt.Printf("Generating invoice for %s %d...\n", month.String(), year)
-	if err := invoice.GenerateHTML(inv, opts.Model, htmlPath); err != nil {
-		return fmt.Errorf("generating HTML invoice: %w", err)
+// validate is inv.Validate, less the rate it reports for a zero rate
+// given with --rate 0, which Validate rejects as it would a zero rate
+// from anywhere else.
+func (opts *ResolvedOptions) validate(inv *invoice.Invoice) error {
+	var problems invoice.ValidationErrors
+	if err := inv.Validate(); !errors.As(err, &problems) || !opts.zeroRate() {
+		return err
 	}
-	fmt.Printf("HTML invoice written to: %s\n", htmlPath)
+	problems = slices.DeleteFunc(problems, func(p *invoice.ValidationError) bool {
+		return p.Code == invoice.ValidationInvalidRate
+	})
+	if len(problems) == 0 {
+		return nil
+	}
+	return problems
+}
 
-	// Convert to PDF if requested.
-	if opts.PDF {
-		pdfPath := invoice.PDFFilePath(inv, dir)
-		fmt.Printf("Converting to PDF...\n")
-		if err := invoice.ConvertToPDF(htmlPath, pdfPath); err != nil {
-			return fmt.Errorf("converting to PDF: %w", err)
+// warnPayment warns about each of p's bank details that does not look
+// right. A typo would send the customer's payment astray, but details in
+// a foreign format may be meant, so generation goes on. p may be nil.
+func warnPayment(p *invoice.PaymentDetails) {
+	if p == nil {
+		return
+	}
+	err := p.Validate()
+	if err == nil {
+		return
+	}
+	for _, line := range strings.Split(err.Error(), "\n") {
+		fmt.Fprintf(config.Warnings, "Warning: payment details: %s; check them before sending the invoice\n", line)
+	}
+}
+
+// display sets how inv shows its dates and figures, as opts say.
+func (opts *ResolvedOptions) display(inv *invoice.Invoice) {
+	inv.DateFormat = opts.DateFormat
+	inv.RatePrecision, inv.HoursPrecision = opts.RatePrecision, opts.HoursPrecision
+	inv.Language, inv.Translations = opts.Language, opts.Translations
+	inv.ShowWorkdays = opts.ShowWorkdays
+}
+
+// loadTranslations reads the labels of the translations file, with the
+// language codes normalized.
+func loadTranslations() (invoice.Translations, error) {
+	path, err := config.TranslationsPath()
+	if err != nil {
+		return nil, err
+	}
+	labels, err := config.LoadTranslations(path, invoice.LabelKeys())
+	if err != nil {
+		return nil, err
+	}
+	var t invoice.Translations
+	for lang, l := range labels {
+		if t == nil {
+			t = make(invoice.Translations)
+		}
+		lang = invoice.NormalizeLanguage(lang)
+		if t[lang] == nil {
+			t[lang] = make(map[string]string)
+		}
+		maps.Copy(t[lang], l)
+	}
+	return t, nil
+}
+
+// checkLanguage warns if neither invoicer nor the translations file has
+// labels in lang, or in the language it is a dialect of, since the
+// invoice would be labelled in English.
+func checkLanguage(lang string, t invoice.Translations) {
+	base, _, _ := strings.Cut(lang, "-")
+	for _, l := range []string{lang, base} {
+		if slices.Contains(invoice.Languages(), l) || len(t[l]) > 0 {
+			return
+		}
+	}
+	fmt.Fprintf(config.Warnings, "Warning: there are no labels in %q (built in: %s); labelling the invoice in English (add labels to translations.yaml)\n", lang, strings.Join(invoice.Languages(), ", "))
+}
+
+// generateHTML writes the HTML invoice using the backend in opts, giving up
+// after opts.Timeout with a timeoutError. opts.Model may list fallback
+// models, separated by commas.
+func generateHTML(ctx context.Context, opts *ResolvedOptions, inv *invoice.Invoice, htmlPath string) (*invoice.Result, error) {
+	genCtx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+	models := config.SplitModels(opts.Model)
+	if opts.Backend == "ollama" {
+		// The ollama backend uses ollama_model, so there is nothing to fall back through.
+		models = models[:1]
+	}
+	res, err := invoice.Generate(genCtx, opts.Backend, inv, htmlPath, invoice.GenerateOptions{
+		Model:          models[0],
+		FallbackModels: models[1:],
+		OllamaHost:     opts.OllamaHost,
+		OllamaModel:    opts.OllamaModel,
+		Agent:          opts.Agent,
+		Session:        opts.Session,
+		DeniedTools:    opts.DeniedTools,
+		Retries:        opts.Retries,
+		MaxCost:        opts.MaxCost,
+		SkipVerify:     opts.SkipVerify,
+		NoFormat:       opts.NoFormat,
+		Minify:         opts.Minify,
+		StrictExit:     opts.StrictExit,
+		OnRetry: func(attempt int, err error, delay time.Duration) {
+			fmt.Fprintf(os.Stderr, "Attempt %d of %d failed: %v\nRetrying in %s...\n", attempt, opts.Retries+1, err, delay.Round(100*time.Millisecond))
+		},
+		OnFallback: func(model string, err error) {
+			fmt.Fprintf(os.Stderr, "Generation failed: %v\nFalling back to %s...\n", err, model)
+		},
+		OnWarning: func(err error) {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		},
+		Progress: reportProgress(),
+	})
+	var budget *invoice.BudgetError
+	switch {
+	case errors.As(err, &budget):
+		return nil, &budgetError{err: budget}
+	case err != nil && ctx.Err() == nil && errors.Is(genCtx.Err(), context.DeadlineExceeded):
+		return nil, &timeoutError{timeout: opts.Timeout, err: err}
+	}
+	return res, err
+}
+
+// convertPDF converts htmlPath to pdfPath with convertHTML, then sets the
+// PDF's title and other metadata for inv. A PDF whose metadata cannot be set is kept with
+// a warning. It returns the tool used.
+func convertPDF(ctx context.Context, opts *ResolvedOptions, inv *invoice.Invoice, htmlPath, pdfPath string) (string, error) {
+	tool, err := convertHTML(ctx, opts, htmlPath, pdfPath)
+	if err != nil {
+		return tool, err
+	}
+	if err := invoice.SetPDFInfo(pdfPath, invoice.NewPDFInfo(inv, opts.PDFTitle, time.Now())); err != nil {
+		fmt.Fprintf(config.Warnings, "Warning: could not set the PDF metadata: %v\n", err)
+	}
+	return tool, nil
+}
+
+// convertHTML converts htmlPath to pdfPath with the PDF options in opts,
+// giving up after opts.PDFTimeout with a timeoutError that names the tool.
+// It returns the tool used.
+func convertHTML(ctx context.Context, opts *ResolvedOptions, htmlPath, pdfPath string) (string, error) {
+	pdfCtx, cancel := context.WithTimeout(ctx, opts.PDFTimeout)
+	defer cancel()
+	tool, err := invoice.ConvertToPDF(pdfCtx, htmlPath, pdfPath, opts.pdfOptions())
+	if err != nil && ctx.Err() == nil && errors.Is(pdfCtx.Err(), context.DeadlineExceeded) {
+		return tool, &timeoutError{timeout: opts.PDFTimeout, tool: tool, key: "pdf_timeout", err: err}
+	}
+	return tool, err
+}
+
+// renderThumbnail renders htmlPath to a PNG at pngPath, opts.ThumbnailWidth
+// pixels wide, with the browser and engine the PDF options choose. Like
+// convertHTML it gives up after opts.PDFTimeout. It returns the tool used.
+func renderThumbnail(ctx context.Context, opts *ResolvedOptions, htmlPath, pngPath string) (string, error) {
+	thumbCtx, cancel := context.WithTimeout(ctx, opts.PDFTimeout)
+	defer cancel()
+	tool, err := invoice.RenderThumbnail(thumbCtx, htmlPath, pngPath, opts.ThumbnailWidth, opts.pdfOptions())
+	if err != nil && ctx.Err() == nil && errors.Is(thumbCtx.Err(), context.DeadlineExceeded) {
+		return tool, &timeoutError{timeout: opts.PDFTimeout, tool: tool, key: "pdf_timeout", err: err}
+	}
+	return tool, err
+}
+
+// writeThumbnail renders the thumbnail of inv from htmlPath, beside it, and
+// returns its path and the tool used. A thumbnail is only a preview of an
+// invoice that is already written, so one that cannot be rendered is a
+// warning, and the path is "".
+func writeThumbnail(ctx context.Context, opts *ResolvedOptions, inv *invoice.Invoice, htmlPath string) (string, string) {
+	path := invoice.ThumbnailFilePath(inv, filepath.Dir(htmlPath))
+	tool, err := renderThumbnail(ctx, opts, htmlPath, path)
+	if err != nil {
+		fmt.Fprintf(config.Warnings, "Warning: leaving out the thumbnail: %v\n", err)
+		return "", tool
+	}
+	return path, tool
+}
+
+// pdfOptions returns the options for running the PDF tool.
+func (opts *ResolvedOptions) pdfOptions() invoice.PDFOptions {
+	return invoice.PDFOptions{
+		Tool:     opts.PDFTool,
+		ToolPath: opts.PDFToolPath,
+		Args:     opts.PDFArgs,
+		Engine:   opts.PDFEngine,
+		OnFallback: func(err error) {
+			fmt.Fprintf(os.Stderr, "PDF engine chromedp failed: %v\nFalling back to a conversion tool...\n", err)
+		},
+		Progress: reportProgress(),
+	}
+}
+
+// pdfResult describes a PDF written by writePDF.
+type pdfResult struct {
+	// Tool converted the HTML to PDF.
+	Tool string
+	// Encrypter encrypted the PDF; "" if it is not encrypted.
+	Encrypter string
+	// HTMLRemoved reports whether the HTML invoice was removed.
+	HTMLRemoved bool
+	// Thumbnail is the path of the thumbnail, and ThumbnailTool the tool
+	// that rendered it; Thumbnail is "" if there is none.
+	Thumbnail, ThumbnailTool string
+}
+
+// String names the programs that made the PDF, e.g.
+// "wkhtmltopdf, encrypted with qpdf".
+func (r *pdfResult) String() string {
+	if r.Encrypter != "" {
+		return r.Tool + ", encrypted with " + r.Encrypter
+	}
+	return r.Tool
+}
+
+// writePDF converts htmlPath to pdfPath with convertPDF, then encrypts it
+// with opts.PDFPassword if that is set. A PDF that cannot be encrypted is
+// removed rather than left readable. With opts.Thumbnail the thumbnail is
+// then rendered with writeThumbnail, and with opts.PDFOnly the HTML is
+// removed after that. If anything but the thumbnail fails the HTML is
+// kept, and the error says where, so the generated invoice is not lost.
+func writePDF(ctx context.Context, opts *ResolvedOptions, inv *invoice.Invoice, htmlPath, pdfPath string) (*pdfResult, error) {
+	res := &pdfResult{}
+	var err error
+	res.Tool, err = convertPDF(ctx, opts, inv, htmlPath, pdfPath)
+	if err == nil && opts.PDFPassword != "" {
+		if res.Encrypter, err = invoice.EncryptPDF(ctx, pdfPath, string(opts.PDFPassword)); err != nil {
+			os.Remove(pdfPath)
 		}
-		fmt.Printf("PDF invoice written to: %s\n", pdfPath)
 	}
+	if err != nil {
+		if opts.PDFOnly {
+			err = fmt.Errorf("%w (the HTML invoice is kept at %s)", err, htmlPath)
+		}
+		return res, err
+	}
+	if opts.Thumbnail {
+		res.Thumbnail, res.ThumbnailTool = writeThumbnail(ctx, opts, inv, htmlPath)
+	}
+	if !opts.PDFOnly {
+		return res, nil
+	}
+	if err := os.Remove(htmlPath); err != nil {
+		fmt.Fprintf(config.Warnings, "Warning: could not remove the HTML invoice: %v\n", err)
+		return res, nil
+	}
+	res.HTMLRemoved = true
+	return res, nil
+}
 
+// exitBudget is the exit status when generation is stopped for going over
+// the cost limit.
+const exitBudget = 3
+
+// budgetError reports that generation was stopped by the cost limit.
+// It makes invoicer exit with exitBudget.
+type budgetError struct {
+	err *invoice.BudgetError
+}
+
+func (e *budgetError) Error() string {
+	return e.err.Error() + " (raise --max-cost or max_cost: in the config)"
+}
+
+func (e *budgetError) Unwrap() error { return e.err }
+
+// ExitCode implements kong.ExitCoder.
+func (e *budgetError) ExitCode() int { return exitBudget }
+
+// exitTimeout is the exit status when generation times out, matching timeout(1).
+const exitTimeout = 124
+
+// timeoutError reports that generation or PDF conversion did not finish
+// within its timeout. It makes invoicer exit with exitTimeout.
+type timeoutError struct {
+	timeout time.Duration
+	// tool names the PDF tool that timed out; "" for generation.
+	tool string
+	// key is the config key that sets the timeout; "" means timeout.
+	key string
+	err error
+}
+
+func (e *timeoutError) Error() string {
+	key := e.key
+	if key == "" {
+		key = "timeout"
+	}
+	msg := fmt.Sprintf("timed out after %s (raise --%s or %s: in the config)", e.timeout, strings.ReplaceAll(key, "_", "-"), key)
+	if e.tool != "" {
+		return e.tool + " " + msg
+	}
+	return msg
+}
+
+func (e *timeoutError) Unwrap() error { return e.err }
+
+// ExitCode implements kong.ExitCoder.
+func (e *timeoutError) ExitCode() int { return exitTimeout }
+
+// validateBackend returns an error if name is not a registered backend.
+func validateBackend(name string) error {
+	_, err := invoice.Lookup(name)
+	return err
+}
+
+// runPostGenerateHook runs the configured hook, if any, for the HTML and
+// PDF invoices or, if textPath is set, for the plain-text one.
+// A failing hook is reported as a warning on w unless HookStrict is set.
+func runPostGenerateHook(opts *ResolvedOptions, inv *invoice.Invoice, htmlPath, pdfPath, textPath string, w io.Writer) error {
+	if opts.Hook == "" {
+		return nil
+	}
+	var err error
+	if textPath != "" {
+		err = invoice.RunTextHook(opts.Hook, inv, textPath)
+	} else {
+		err = invoice.RunHook(opts.Hook, inv, htmlPath, pdfPath)
+	}
+	if err != nil {
+		if opts.HookStrict {
+			return fmt.Errorf("running post-generation hook: %w", err)
+		}
+		fmt.Fprintf(w, "Warning: post-generation hook failed: %v\n", err)
+	}
 	return nil
 }
@@ -1,16 +1,48 @@
 package cli
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
 	"os"
 	"path/filepath"
+	"runtime"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/alecthomas/kong"
+	"github.com/zon/invoicer/internal/config"
+	"github.com/zon/invoicer/pkg/invoice"
 )
 
 func boolPtr(b bool) *bool { return &b }
 
+func strPtr(s string) *string { return &s }
+
+func floatPtr(f float64) *float64 { return &f }
+
+func intPtr(i int) *int { return &i }
+
+// str and num format optional values for test failure messages.
+func str(p *string) string {
+	if p == nil {
+		return "<nil>"
+	}
+	return *p
+}
+
+func num(p *float64) string {
+	if p == nil {
+		return "<nil>"
+	}
+	return fmt.Sprint(*p)
+}
+
 // TestCLIParsesWithoutPanic verifies that the CLI struct can be parsed by kong
 // without duplicate flag errors. This guards against regressions where sibling
 // subcommands happen to share flag names.
@@ -59,17 +91,27 @@ func writeTestConfig(t *testing.T, content string) string {
 	return path
 }
 
+// loadTestConfig loads the config file at path, failing the test on error.
+func loadTestConfig(t *testing.T, path string) *config.Config {
+	t.Helper()
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("loading test config: %v", err)
+	}
+	return cfg
+}
+
 func TestResolveOptions_NoConfig(t *testing.T) {
 	c := &GenerateCmd{
 		Vendor:   "My Vendor",
 		Customer: "My Customer",
-		Rate:     100,
-		Hours:    40,
-		PDF:      true,
-		Model:    "anthropic/claude-haiku-4-5",
+		Rate:     floatPtr(100),
+		Hours:    floatPtr(40),
+		PDF:      boolPtr(true),
+		Model:    strPtr("anthropic/claude-haiku-4-5"),
 	}
 	path := filepath.Join(t.TempDir(), "nonexistent.yaml")
-	opts, err := c.resolveOptions(path)
+	opts, err := c.resolveOptions(loadTestConfig(t, path), nil)
 	if err != nil {
 		t.Fatalf("resolveOptions: %v", err)
 	}
@@ -104,12 +146,12 @@ model: anthropic/claude-haiku-4-5
 	c := &GenerateCmd{
 		Vendor:   "CLI Vendor",
 		Customer: "CLI Customer",
-		Rate:     150,
-		Hours:    35,
-		PDF:      true,
-		Model:    "anthropic/claude-sonnet-4-6",
+		Rate:     floatPtr(150),
+		Hours:    floatPtr(35),
+		PDF:      boolPtr(true),
+		Model:    strPtr("anthropic/claude-sonnet-4-6"),
 	}
-	opts, err := c.resolveOptions(path)
+	opts, err := c.resolveOptions(loadTestConfig(t, path), nil)
 	if err != nil {
 		t.Fatalf("resolveOptions: %v", err)
 	}
@@ -143,7 +185,7 @@ model: anthropic/claude-haiku-4-5
 `)
 	// CLI provides no values (zero values).
 	c := &GenerateCmd{}
-	opts, err := c.resolveOptions(path)
+	opts, err := c.resolveOptions(loadTestConfig(t, path), nil)
 	if err != nil {
 		t.Fatalf("resolveOptions: %v", err)
 	}
@@ -162,14 +204,447 @@ model: anthropic/claude-haiku-4-5
 	if !opts.PDF {
 		t.Error("PDF: expected config fallback true; got false")
 	}
-	// Model: c.Model is "" (zero) so config is used, but actually since kong sets
-	// the default "anthropic/claude-haiku-4-5" at parse time, in unit tests c.Model
-	// is "" — so here we expect the config value.
 	if opts.Model != "anthropic/claude-haiku-4-5" {
 		t.Errorf("Model: expected config fallback; got %q", opts.Model)
 	}
 }
 
+func TestResolveOptions_ModelPrecedence(t *testing.T) {
+	const sonnet = "anthropic/claude-sonnet-4-6"
+	tests := []struct {
+		name   string
+		args   []string
+		config string
+		want   string
+	}{
+		{"absent flag uses config", nil, "model: " + sonnet + "\n", sonnet},
+		{"absent flag without config uses default", nil, "", defaultModel},
+		{"explicit flag equal to default beats config", []string{"--model", defaultModel}, "model: " + sonnet + "\n", defaultModel},
+		{"explicit flag beats config", []string{"-m", "openai/gpt-5"}, "model: " + sonnet + "\n", "openai/gpt-5"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeTestConfig(t, tt.config)
+			cmd := parseCLI(t, tt.args...)
+			opts, err := cmd.Generate.resolveOptions(loadTestConfig(t, path), nil)
+			if err != nil {
+				t.Fatalf("resolveOptions: %v", err)
+			}
+			if opts.Model != tt.want {
+				t.Errorf("Model = %q, want %q", opts.Model, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveOptions_PDFTriState(t *testing.T) {
+	tests := []struct {
+		name   string
+		args   []string
+		config string
+		want   bool
+	}{
+		{"absent flag without config", nil, "", false},
+		{"absent flag uses config true", nil, "pdf: true\n", true},
+		{"--pdf overrides config false", []string{"--pdf"}, "pdf: false\n", true},
+		{"-p overrides config false", []string{"-p"}, "pdf: false\n", true},
+		{"--no-pdf overrides config true", []string{"--no-pdf"}, "pdf: true\n", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeTestConfig(t, tt.config)
+			cmd := parseCLI(t, tt.args...)
+			opts, err := cmd.Generate.resolveOptions(loadTestConfig(t, path), nil)
+			if err != nil {
+				t.Fatalf("resolveOptions: %v", err)
+			}
+			if opts.PDF != tt.want {
+				t.Errorf("PDF = %v, want %v", opts.PDF, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveOptions_PDFOnly(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		config   string
+		wantOnly bool
+		wantPDF  bool
+		wantErr  string
+	}{
+		{"default", nil, "", false, false, ""},
+		{"flag implies --pdf", []string{"--pdf-only"}, "pdf: false\n", true, true, ""},
+		{"config implies pdf", nil, "pdf_only: true\n", true, true, ""},
+		{"--no-pdf-only overrides config", []string{"--no-pdf-only"}, "pdf_only: true\npdf: true\n", false, true, ""},
+		{"--no-pdf overrides config", []string{"--no-pdf"}, "pdf_only: true\n", false, false, ""},
+		{"--no-pdf conflicts", []string{"--pdf-only", "--no-pdf"}, "", false, false, "--pdf-only cannot be combined with --no-pdf"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeTestConfig(t, tt.config)
+			cmd := parseCLI(t, tt.args...)
+			opts, err := cmd.Generate.resolveOptions(loadTestConfig(t, path), nil)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("expected error %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveOptions: %v", err)
+			}
+			if opts.PDFOnly != tt.wantOnly || opts.PDF != tt.wantPDF {
+				t.Errorf("PDFOnly = %v, PDF = %v, want %v, %v", opts.PDFOnly, opts.PDF, tt.wantOnly, tt.wantPDF)
+			}
+		})
+	}
+}
+
+func TestWritePDF_PDFOnly(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as a fake wkhtmltopdf")
+	}
+	tests := []struct {
+		name       string
+		script     string
+		pdfOnly    bool
+		wantErr    string
+		wantHTML   bool
+		wantPDF    bool
+		wantRemove bool
+	}{
+		{"pdf only", "#!/bin/sh\nprintf '%%PDF-1.4\\n%%%%EOF\\n' > \"$2\"\n", true, "", false, true, true},
+		{"pdf and html", "#!/bin/sh\nprintf '%%PDF-1.4\\n%%%%EOF\\n' > \"$2\"\n", false, "", true, true, false},
+		{"conversion fails", "#!/bin/sh\necho 'cannot load page' >&2\nexit 1\n", true, "the HTML invoice is kept at ", true, false, false},
+		{"not a PDF", "#!/bin/sh\necho 'oops' > \"$2\"\n", true, "the HTML invoice is kept at ", true, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bin := t.TempDir()
+			if err := os.WriteFile(filepath.Join(bin, "wkhtmltopdf"), []byte(tt.script), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+			var warnings strings.Builder
+			old := config.Warnings
+			config.Warnings = &warnings
+			t.Cleanup(func() { config.Warnings = old })
+			dir := t.TempDir()
+			htmlPath := filepath.Join(dir, "invoice.html")
+			pdfPath := filepath.Join(dir, "invoice.pdf")
+			if err := os.WriteFile(htmlPath, []byte("<html></html>"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			opts := &ResolvedOptions{PDF: true, PDFOnly: tt.pdfOnly, PDFTimeout: time.Minute, PDFTitle: invoice.DefaultPDFTitle}
+			res, err := writePDF(context.Background(), opts, testTimeoutInvoice(), htmlPath, pdfPath)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr+htmlPath) {
+					t.Errorf("expected error %q, got %v", tt.wantErr+htmlPath, err)
+				}
+			} else if err != nil {
+				t.Fatalf("writePDF: %v", err)
+			}
+			if res.HTMLRemoved != tt.wantRemove {
+				t.Errorf("HTMLRemoved = %v, want %v", res.HTMLRemoved, tt.wantRemove)
+			}
+			if _, err := os.Stat(htmlPath); (err == nil) != tt.wantHTML {
+				t.Errorf("HTML exists = %v, want %v", err == nil, tt.wantHTML)
+			}
+			if _, err := os.Stat(pdfPath); (err == nil) != tt.wantPDF {
+				t.Errorf("PDF exists = %v, want %v", err == nil, tt.wantPDF)
+			}
+		})
+	}
+}
+
+func TestResolveOptions_Backend(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		config  string
+		want    string
+		wantErr string
+	}{
+		{"default", nil, "", "opencode", ""},
+		{"config", nil, "backend: claude\n", "claude", ""},
+		{"flag beats config", []string{"--backend", "opencode"}, "backend: claude\n", "opencode", ""},
+		{"unknown", []string{"--backend", "gpt"}, "", "", `unknown backend "gpt" (use one of: claude, ollama, opencode`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeTestConfig(t, tt.config)
+			cmd := parseCLI(t, tt.args...)
+			opts, err := cmd.Generate.resolveOptions(loadTestConfig(t, path), nil)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("expected error %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveOptions: %v", err)
+			}
+			if opts.Backend != tt.want {
+				t.Errorf("Backend = %q, want %q", opts.Backend, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveOptions_Ollama(t *testing.T) {
+	path := writeTestConfig(t, "backend: ollama\nollama_model: qwen2.5\n")
+	cmd := parseCLI(t, "--ollama-host", "http://gpu-box:11434")
+	opts, err := cmd.Generate.resolveOptions(loadTestConfig(t, path), nil)
+	if err != nil {
+		t.Fatalf("resolveOptions: %v", err)
+	}
+	if opts.Backend != "ollama" || opts.OllamaHost != "http://gpu-box:11434" || opts.OllamaModel != "qwen2.5" {
+		t.Errorf("unexpected ollama options: backend=%q host=%q model=%q", opts.Backend, opts.OllamaHost, opts.OllamaModel)
+	}
+
+	opts, err = (&GenerateCmd{}).resolveOptions(&config.Config{}, nil)
+	if err != nil {
+		t.Fatalf("resolveOptions: %v", err)
+	}
+	if opts.OllamaHost != invoice.DefaultOllamaHost || opts.OllamaModel != invoice.DefaultOllamaModel {
+		t.Errorf("expected ollama defaults, got host=%q model=%q", opts.OllamaHost, opts.OllamaModel)
+	}
+}
+
+func TestResolveOptions_Timeout(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		config  string
+		want    time.Duration
+		wantErr string
+	}{
+		{"default", nil, "", defaultTimeout, ""},
+		{"config", nil, "timeout: 90s\n", 90 * time.Second, ""},
+		{"flag beats config", []string{"--timeout", "10s"}, "timeout: 90s\n", 10 * time.Second, ""},
+		{"zero", []string{"--timeout", "0s"}, "", 0, "timeout must be positive"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeTestConfig(t, tt.config)
+			cmd := parseCLI(t, tt.args...)
+			opts, err := cmd.Generate.resolveOptions(loadTestConfig(t, path), nil)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("expected error %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveOptions: %v", err)
+			}
+			if opts.Timeout != tt.want {
+				t.Errorf("Timeout = %s, want %s", opts.Timeout, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveOptions_PDFTimeout(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		config  string
+		want    time.Duration
+		wantErr string
+	}{
+		{"default", nil, "", defaultPDFTimeout, ""},
+		{"config", nil, "pdf_timeout: 2m\n", 2 * time.Minute, ""},
+		{"flag beats config", []string{"--pdf-timeout", "10s"}, "pdf_timeout: 2m\n", 10 * time.Second, ""},
+		{"zero", []string{"--pdf-timeout", "0s"}, "", 0, "pdf timeout must be positive"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeTestConfig(t, tt.config)
+			cmd := parseCLI(t, tt.args...)
+			opts, err := cmd.Generate.resolveOptions(loadTestConfig(t, path), nil)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("expected error %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveOptions: %v", err)
+			}
+			if opts.PDFTimeout != tt.want {
+				t.Errorf("PDFTimeout = %s, want %s", opts.PDFTimeout, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveOptions_PDFEngine(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		config  string
+		want    string
+		wantErr string
+	}{
+		{"default", nil, "", invoice.ExecPDFEngine, ""},
+		{"config", nil, "pdf_engine: auto\n", invoice.AutoPDFEngine, ""},
+		{"flag beats config", []string{"--pdf-engine", "chromedp"}, "pdf_engine: exec\n", invoice.ChromedpPDFEngine, ""},
+		{"unknown", nil, "pdf_engine: puppeteer\n", "", `unknown PDF engine "puppeteer"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeTestConfig(t, tt.config)
+			cmd := parseCLI(t, tt.args...)
+			opts, err := cmd.Generate.resolveOptions(loadTestConfig(t, path), nil)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("expected error %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveOptions: %v", err)
+			}
+			if opts.PDFEngine != tt.want {
+				t.Errorf("PDFEngine = %q, want %q", opts.PDFEngine, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveOptions_PDFTool(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		config   string
+		want     string
+		wantPath string
+		wantErr  string
+	}{
+		{"default", nil, "", invoice.AutoPDFTool, "", ""},
+		{"config", nil, "pdf_tool: chrome\npdf_tool_path: /opt/chrome/chrome\n", "chrome", "/opt/chrome/chrome", ""},
+		{"flag beats config", []string{"--pdf-tool", "wkhtmltopdf", "--pdf-tool-path", "/usr/local/bin/wkhtmltopdf"}, "pdf_tool: chrome\npdf_tool_path: /opt/chrome/chrome\n", "wkhtmltopdf", "/usr/local/bin/wkhtmltopdf", ""},
+		{"unknown", []string{"--pdf-tool", "prince"}, "", "", "", `unknown PDF tool "prince"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeTestConfig(t, tt.config)
+			cmd := parseCLI(t, tt.args...)
+			opts, err := cmd.Generate.resolveOptions(loadTestConfig(t, path), nil)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("expected error %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveOptions: %v", err)
+			}
+			if opts.PDFTool != tt.want || opts.PDFToolPath != tt.wantPath {
+				t.Errorf("PDFTool = %q, PDFToolPath = %q, want %q, %q", opts.PDFTool, opts.PDFToolPath, tt.want, tt.wantPath)
+			}
+		})
+	}
+}
+
+func TestResolveOptions_PDFArgs(t *testing.T) {
+	tests := []struct {
+		name   string
+		args   []string
+		config string
+		want   []string
+	}{
+		{"default", nil, "", nil},
+		{"config", nil, "pdf_tool_args: [--enable-local-file-access, --quiet]\n", []string{"--enable-local-file-access", "--quiet"}},
+		{"flags replace config", []string{"--pdf-arg=--virtual-time-budget=10000", "--pdf-arg=--a,b"}, "pdf_tool_args: [--quiet]\n", []string{"--virtual-time-budget=10000", "--a,b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeTestConfig(t, tt.config)
+			cmd := parseCLI(t, tt.args...)
+			opts, err := cmd.Generate.resolveOptions(loadTestConfig(t, path), nil)
+			if err != nil {
+				t.Fatalf("resolveOptions: %v", err)
+			}
+			if !slices.Equal(opts.PDFArgs, tt.want) {
+				t.Errorf("PDFArgs = %q, want %q", opts.PDFArgs, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveOptions_PDFTitle(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		config  string
+		want    string
+		wantErr string
+	}{
+		{"default", nil, "", invoice.DefaultPDFTitle, ""},
+		{"config", nil, "pdf_title: \"{number} for {customer}\"\n", "{number} for {customer}", ""},
+		{"flag beats config", []string{"--pdf-title", "Invoice {number}"}, "pdf_title: \"{number} for {customer}\"\n", "Invoice {number}", ""},
+		{"unknown placeholder", []string{"--pdf-title", "Invoice {client}"}, "", "", "unknown placeholder {client}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeTestConfig(t, tt.config)
+			cmd := parseCLI(t, tt.args...)
+			opts, err := cmd.Generate.resolveOptions(loadTestConfig(t, path), nil)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("expected error %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveOptions: %v", err)
+			}
+			if opts.PDFTitle != tt.want {
+				t.Errorf("PDFTitle = %q, want %q", opts.PDFTitle, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveOptions_Retries(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		config  string
+		want    int
+		wantErr string
+	}{
+		{"default", nil, "", 0, ""},
+		{"config", nil, "retries: 3\n", 3, ""},
+		{"flag beats config", []string{"--retries", "1"}, "retries: 3\n", 1, ""},
+		{"negative", []string{"--retries=-1"}, "", 0, "retries must be between 0 and"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeTestConfig(t, tt.config)
+			cmd := parseCLI(t, tt.args...)
+			opts, err := cmd.Generate.resolveOptions(loadTestConfig(t, path), nil)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("expected error %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveOptions: %v", err)
+			}
+			if opts.Retries != tt.want {
+				t.Errorf("Retries = %d, want %d", opts.Retries, tt.want)
+			}
+		})
+	}
+}
+
 func TestResolveOptions_PartialCLIOverride(t *testing.T) {
 	path := writeTestConfig(t, `vendor: Config Vendor
 customer: Config Customer
@@ -179,9 +654,9 @@ hours: 20
 	// CLI only provides rate and vendor.
 	c := &GenerateCmd{
 		Vendor: "CLI Vendor",
-		Rate:   200,
+		Rate:   floatPtr(200),
 	}
-	opts, err := c.resolveOptions(path)
+	opts, err := c.resolveOptions(loadTestConfig(t, path), nil)
 	if err != nil {
 		t.Fatalf("resolveOptions: %v", err)
 	}
@@ -200,3 +675,473 @@ hours: 20
 		t.Errorf("Hours: expected config fallback; got %v", opts.Hours)
 	}
 }
+
+func parseCLI(t *testing.T, args ...string) *CLI {
+	t.Helper()
+	var cmd CLI
+	p, err := kong.New(&cmd, kong.Name("invoicer"), kong.Exit(func(int) {}), Mappers())
+	if err != nil {
+		t.Fatalf("kong.New failed: %v", err)
+	}
+	if _, err := p.Parse(args); err != nil {
+		t.Fatalf("Parse(%v) failed: %v", args, err)
+	}
+	return &cmd
+}
+
+func TestGlobalConfigFlag(t *testing.T) {
+	path := writeTestConfig(t, "vendor: Side Co\n")
+	cmd := parseCLI(t, "--config", path, "january")
+	if cmd.Config != path {
+		t.Errorf("Config: got %q, want %q", cmd.Config, path)
+	}
+	got, err := cmd.Globals.configPath()
+	if err != nil {
+		t.Fatalf("configPath: %v", err)
+	}
+	if got != path {
+		t.Errorf("configPath: got %q, want %q", got, path)
+	}
+}
+
+func TestGlobalConfigEnv(t *testing.T) {
+	path := writeTestConfig(t, "vendor: Side Co\n")
+	t.Setenv("INVOICER_CONFIG", path)
+	cmd := parseCLI(t, "set", "config", "--vendor", "x")
+	if cmd.Config != path {
+		t.Errorf("Config: got %q, want %q from INVOICER_CONFIG", cmd.Config, path)
+	}
+}
+
+func TestGlobalConfigFlagOverridesEnv(t *testing.T) {
+	envPath := writeTestConfig(t, "vendor: Env Co\n")
+	flagPath := writeTestConfig(t, "vendor: Flag Co\n")
+	t.Setenv("INVOICER_CONFIG", envPath)
+	cmd := parseCLI(t, "--config", flagPath)
+	if cmd.Config != flagPath {
+		t.Errorf("Config: got %q, want flag value %q", cmd.Config, flagPath)
+	}
+}
+
+func TestGlobalConfigExplicitMissingFile(t *testing.T) {
+	g := &Globals{Config: filepath.Join(t.TempDir(), "missing.yaml")}
+	if _, err := g.configPath(); err == nil {
+		t.Error("expected error for explicit missing config file, got nil")
+	}
+}
+
+func TestGlobalStrictConfig(t *testing.T) {
+	var warnings bytes.Buffer
+	old := config.Warnings
+	config.Warnings = &warnings
+	t.Cleanup(func() { config.Warnings = old })
+
+	path := writeTestConfig(t, "vendor: Jane\nmodle: x/y\n")
+
+	if _, err := (&Globals{}).loadConfig(path); err != nil {
+		t.Fatalf("loadConfig without --strict-config: %v", err)
+	}
+	if !strings.Contains(warnings.String(), `did you mean "model"`) {
+		t.Errorf("expected a warning for the unknown key, got %q", warnings.String())
+	}
+
+	cmd := parseCLI(t, "--strict-config", "--config", path)
+	_, err := cmd.Globals.loadConfig(path)
+	if err == nil || !strings.Contains(err.Error(), path+":2:") {
+		t.Errorf("expected --strict-config error naming %s:2, got %v", path, err)
+	}
+}
+
+func TestGenerateRun_ExplicitMissingConfig(t *testing.T) {
+	g := &Globals{Config: filepath.Join(t.TempDir(), "missing.yaml")}
+	c := &GenerateCmd{Vendor: "V", Customer: "C", Rate: floatPtr(1), Hours: floatPtr(1)}
+	err := c.Run(g, context.Background())
+	if err == nil || !strings.Contains(err.Error(), "missing.yaml") {
+		t.Errorf("expected error naming the missing config file, got %v", err)
+	}
+}
+
+func TestResolveOptions_ExplicitZeroRateOverridesConfig(t *testing.T) {
+	path := writeTestConfig(t, "rate: 50\n")
+	c := &GenerateCmd{Rate: floatPtr(0)}
+	opts, err := c.resolveOptions(loadTestConfig(t, path), nil)
+	if err != nil {
+		t.Fatalf("resolveOptions: %v", err)
+	}
+	if opts.Rate != 0 {
+		t.Errorf("Rate: explicit --rate 0 should override config; got %v", opts.Rate)
+	}
+}
+
+func TestBuildInvoice_Rate(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		config  string
+		wantErr string
+	}{
+		{"zero flag", []string{"--rate", "0"}, "rate: 150\n", ""},
+		{"negative flag", []string{"--rate=-50"}, "", "rate is required and must be positive"},
+		{"zero in the config", nil, "rate: 0\n", "rate is required and must be positive"},
+		{"unset", nil, "", "rate is required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeTestConfig(t, "vendor: Jane\ncustomer: Acme\nhours: 40\n"+tt.config)
+			cmd := parseCLI(t, append(tt.args, "january", "2025")...)
+			opts, err := cmd.Generate.resolveOptions(loadTestConfig(t, path), nil)
+			if err != nil {
+				t.Fatalf("resolveOptions: %v", err)
+			}
+			inv, err := opts.buildInvoice()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("buildInvoice: expected %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("buildInvoice: %v", err)
+			}
+			if inv.Rate != 0 || inv.Total() != 0 {
+				t.Errorf("Rate, Total = %v, %v; want a zero rate and total", inv.Rate, inv.Total())
+			}
+		})
+	}
+}
+
+func TestGenerateHTML_ModelFallback(t *testing.T) {
+	orig, origSleep := invoice.OpencodeExec, invoice.Sleep
+	t.Cleanup(func() { invoice.OpencodeExec, invoice.Sleep = orig, origSleep })
+	invoice.Sleep = func(ctx context.Context, d time.Duration) error { return nil }
+	invoice.OpencodeExec = func(ctx context.Context, r invoice.OpencodeRun, stdout io.Writer) error {
+		if r.Model == "anthropic/claude-haiku-4-5" {
+			return fmt.Errorf("model overloaded")
+		}
+		// Write to the staging file named in the prompt, as opencode would.
+		_, path, _ := strings.Cut(r.Prompt, "to the file: ")
+		path, _, _ = strings.Cut(path, "\n")
+		return os.WriteFile(path, []byte("<html>ok</html>"), 0o644)
+	}
+
+	opts := &ResolvedOptions{
+		Backend: "opencode",
+		Model:   "anthropic/claude-haiku-4-5, anthropic/claude-sonnet-4-6",
+		Timeout: time.Minute,
+	}
+	res, err := generateHTML(context.Background(), opts, testTimeoutInvoice(), filepath.Join(t.TempDir(), "invoice.html"))
+	if err != nil {
+		t.Fatalf("generateHTML: %v", err)
+	}
+	if res.Model != "anthropic/claude-sonnet-4-6" {
+		t.Errorf("Model = %q, want the fallback model", res.Model)
+	}
+}
+
+func TestVerboseEchoesToolOutput(t *testing.T) {
+	t.Cleanup(func() { invoice.ToolOutput, progressOutput = nil, nil })
+	parseCLI(t, "january")
+	if invoice.ToolOutput != nil || progressOutput != nil {
+		t.Errorf("tool output should stay quiet without --verbose")
+	}
+	parseCLI(t, "--verbose", "january")
+	if invoice.ToolOutput != os.Stderr {
+		t.Errorf("--verbose should echo tool output to stderr, got %v", invoice.ToolOutput)
+	}
+	if progressOutput != os.Stderr {
+		t.Errorf("--verbose should report progress on stderr, got %v", progressOutput)
+	}
+}
+
+func TestResolveOptions_ModelCheck(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		wantErr  string
+		wantWarn string
+	}{
+		{"valid", []string{"--model", "anthropic/claude-haiku-4-5"}, "", ""},
+		{"valid list", []string{"--model", "anthropic/claude-haiku-4-5, openai/gpt-5"}, "", ""},
+		{"missing slash", []string{"--model", "claude-haiku"}, `got "claude-haiku"`, ""},
+		{"whitespace", []string{"--model", "anthropic/claude haiku"}, `got "anthropic/claude haiku"`, ""},
+		{"empty list entry", []string{"--model", "anthropic/claude-haiku-4-5,,openai/gpt-5"}, "has an empty entry", ""},
+		{"unknown provider", []string{"--model", "acme/llm-1"}, "", `model "acme/llm-1" is from an unknown provider`},
+		{"skipped", []string{"--model", "claude-haiku", "--skip-model-check"}, "", ""},
+		{"ollama ignores model", []string{"--model", "claude-haiku", "--backend", "ollama"}, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var warnings bytes.Buffer
+			old := config.Warnings
+			config.Warnings = &warnings
+			t.Cleanup(func() { config.Warnings = old })
+
+			cmd := parseCLI(t, tt.args...)
+			_, err := cmd.Generate.resolveOptions(&config.Config{}, nil)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) || !strings.Contains(err.Error(), "--skip-model-check") {
+					t.Errorf("expected error %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveOptions: %v", err)
+			}
+			if got := warnings.String(); (tt.wantWarn == "" && got != "") || !strings.Contains(got, tt.wantWarn) {
+				t.Errorf("warnings = %q, want %q", got, tt.wantWarn)
+			}
+		})
+	}
+}
+
+func TestGenerationSummary(t *testing.T) {
+	tests := []struct {
+		res     invoice.Result
+		elapsed time.Duration
+		want    string
+	}{
+		{invoice.Result{Model: "anthropic/claude-haiku-4-5", Usage: invoice.Usage{InputTokens: 10000, OutputTokens: 2300, Cost: 0.04}}, 38*time.Second + 400*time.Millisecond, "model: anthropic/claude-haiku-4-5, 12.3k tokens, $0.04, 38s"},
+		{invoice.Result{Model: "llama3.2"}, 2345 * time.Millisecond, "model: llama3.2, 2.3s"},
+		{invoice.Result{Model: "anthropic/claude-haiku-4-5", SessionID: "ses_04"}, 12 * time.Second, "model: anthropic/claude-haiku-4-5, session: ses_04, 12s"},
+	}
+	for _, tt := range tests {
+		if got := generationSummary(&tt.res, tt.elapsed); got != tt.want {
+			t.Errorf("generationSummary = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestResolveOptions_AgentAndSession(t *testing.T) {
+	path := writeTestConfig(t, "agent: invoice-writer\nsession: ses_01\n")
+	cmd := parseCLI(t, "--session", "ses_02")
+	opts, err := cmd.Generate.resolveOptions(loadTestConfig(t, path), nil)
+	if err != nil {
+		t.Fatalf("resolveOptions: %v", err)
+	}
+	if opts.Agent != "invoice-writer" || opts.Session != "ses_02" {
+		t.Errorf("Agent, Session = %q, %q; want the config agent and the flag session", opts.Agent, opts.Session)
+	}
+}
+
+func TestResolveOptions_PaymentDetails(t *testing.T) {
+	path := writeTestConfig(t, "vendor: Jane\ncustomer: Acme\nrate: 100\nhours: 40\niban: DE89370400440532013000\nbic: COBADEFFXXX\n")
+	cmd := parseCLI(t, "--iban", "GB82 WEST 1234 5698 7654 32", "january", "2025")
+	opts, err := cmd.Generate.resolveOptions(loadTestConfig(t, path), nil)
+	if err != nil {
+		t.Fatalf("resolveOptions: %v", err)
+	}
+	inv, err := opts.buildInvoice()
+	if err != nil {
+		t.Fatalf("buildInvoice: %v", err)
+	}
+	want := invoice.PaymentDetails{IBAN: "GB82 WEST 1234 5698 7654 32", BIC: "COBADEFFXXX"}
+	if inv.Payment == nil || *inv.Payment != want {
+		t.Errorf("Payment = %+v, want the flag IBAN and the config BIC", inv.Payment)
+	}
+
+	// Without an IBAN or BIC there are no payment details.
+	cmd = parseCLI(t, "-v", "Jane", "-c", "Acme", "-r", "100", "-H", "40")
+	opts, err = cmd.Generate.resolveOptions(loadTestConfig(t, writeTestConfig(t, "")), nil)
+	if err != nil {
+		t.Fatalf("resolveOptions: %v", err)
+	}
+	if inv, err = opts.buildInvoice(); err != nil {
+		t.Fatalf("buildInvoice: %v", err)
+	}
+	if inv.Payment != nil {
+		t.Errorf("Payment = %+v, want nil", inv.Payment)
+	}
+}
+
+func TestResolveOptions_USAccount(t *testing.T) {
+	path := writeTestConfig(t, "vendor: Jane\ncustomer: Acme\nrate: 100\nhours: 40\nrouting_number: \"021000021\"\n")
+	cmd := parseCLI(t, "--account-number", "000123456789", "january", "2025")
+	opts, err := cmd.Generate.resolveOptions(loadTestConfig(t, path), nil)
+	if err != nil {
+		t.Fatalf("resolveOptions: %v", err)
+	}
+	inv, err := opts.buildInvoice()
+	if err != nil {
+		t.Fatalf("buildInvoice: %v", err)
+	}
+	want := invoice.PaymentDetails{RoutingNumber: "021000021", AccountNumber: "000123456789"}
+	if inv.Payment == nil || *inv.Payment != want {
+		t.Errorf("Payment = %+v, want the config routing number and the flag account number", inv.Payment)
+	}
+}
+
+func TestWarnPayment(t *testing.T) {
+	var warnings bytes.Buffer
+	old := config.Warnings
+	config.Warnings = &warnings
+	t.Cleanup(func() { config.Warnings = old })
+
+	warnPayment(nil)
+	warnPayment(&invoice.PaymentDetails{IBAN: "DE89370400440532013000", RoutingNumber: "021000021", AccountNumber: "1234"})
+	if warnings.Len() != 0 {
+		t.Errorf("valid details should not warn: %s", warnings.String())
+	}
+
+	warnPayment(&invoice.PaymentDetails{IBAN: "DE89370400440532013001", RoutingNumber: "021000022", AccountNumber: "1234"})
+	got := warnings.String()
+	if n := strings.Count(got, "Warning: payment details: "); n != 2 {
+		t.Errorf("got %d warnings, want one for the IBAN and one for the routing number:\n%s", n, got)
+	}
+	for _, want := range []string{"IBAN \"DE89370400440532013001\" is not valid", "routing number \"021000022\" is not valid", "check them before sending the invoice"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("warnings should contain %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestResolveOptions_Items(t *testing.T) {
+	cmd := parseCLI(t, "-v", "Jane", "-c", "Acme", "-r", "100", "-H", "40", "january", "2025",
+		"--item", "Domain renewal:18.99", "--item", "Conference: day two:800:2:day")
+	opts, err := cmd.Generate.resolveOptions(loadTestConfig(t, writeTestConfig(t, "")), nil)
+	if err != nil {
+		t.Fatalf("resolveOptions: %v", err)
+	}
+	inv, err := opts.buildInvoice()
+	if err != nil {
+		t.Fatalf("buildInvoice: %v", err)
+	}
+	want := []invoice.Adjustment{
+		{Description: "Domain renewal", Amount: 18.99},
+		{Description: "Conference: day two", Amount: 800, Quantity: 2, Unit: "day"},
+	}
+	if !slices.Equal(inv.Adjustments, want) {
+		t.Errorf("Adjustments = %+v, want %+v", inv.Adjustments, want)
+	}
+	lines := inv.Breakdown().Lines
+	if last := lines[len(lines)-1]; last.Description != "Conference: day two" || last.Amount.Dollars() != 1600 {
+		t.Errorf("last line = %+v, want the conference days after the weeks", last)
+	}
+	weeks := (&invoice.Invoice{Rate: inv.Rate, Weeks: inv.Weeks}).Total()
+	if got := inv.Total(); math.Abs(got-(weeks+18.99+1600)) > 0.001 {
+		t.Errorf("Total() = %v, want the weeks (%v) and the items", got, weeks)
+	}
+
+	cmd = parseCLI(t, "--item", "Domain renewal:lots")
+	if _, err := cmd.Generate.resolveOptions(loadTestConfig(t, writeTestConfig(t, "")), nil); err == nil || !strings.Contains(err.Error(), `amount "lots"`) {
+		t.Errorf("expected a malformed amount error, got %v", err)
+	}
+}
+
+func TestResolveOptions_DeniedTools(t *testing.T) {
+	tests := []struct {
+		name   string
+		args   []string
+		config string
+		want   []string
+	}{
+		{"default", nil, "", invoice.DefaultDeniedTools},
+		{"config list", nil, "denied_tools: bash, task\n", []string{"bash", "task"}},
+		{"flag beats config", []string{"--denied-tools", "webfetch"}, "denied_tools: bash\n", []string{"webfetch"}},
+		{"config opt-out", nil, "restrict_tools: false\n", nil},
+		{"flag opt-out", []string{"--no-restrict-tools"}, "denied_tools: bash\n", nil},
+		{"flag opt-in beats config", []string{"--restrict-tools"}, "restrict_tools: false\n", invoice.DefaultDeniedTools},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeTestConfig(t, tt.config)
+			cmd := parseCLI(t, tt.args...)
+			opts, err := cmd.Generate.resolveOptions(loadTestConfig(t, path), nil)
+			if err != nil {
+				t.Fatalf("resolveOptions: %v", err)
+			}
+			if !slices.Equal(opts.DeniedTools, tt.want) {
+				t.Errorf("DeniedTools = %q, want %q", opts.DeniedTools, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveOptions_MaxCost(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		config  string
+		want    float64
+		wantErr string
+	}{
+		{"default", nil, "", 0, ""},
+		{"config", nil, "max_cost: 0.5\n", 0.5, ""},
+		{"flag beats config", []string{"--max-cost", "2"}, "max_cost: 0.5\n", 2, ""},
+		{"zero", []string{"--max-cost", "0"}, "", 0, "max cost must be positive"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeTestConfig(t, tt.config)
+			cmd := parseCLI(t, tt.args...)
+			opts, err := cmd.Generate.resolveOptions(loadTestConfig(t, path), nil)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("expected error %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveOptions: %v", err)
+			}
+			if opts.MaxCost != tt.want {
+				t.Errorf("MaxCost = %v, want %v", opts.MaxCost, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateHTML_OverBudget(t *testing.T) {
+	orig := invoice.OpencodeExec
+	t.Cleanup(func() { invoice.OpencodeExec = orig })
+	invoice.OpencodeExec = func(ctx context.Context, r invoice.OpencodeRun, stdout io.Writer) error {
+		io.WriteString(stdout, `{"type":"step_finish","part":{"cost":0.75}}`+"\n")
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	opts := &ResolvedOptions{Backend: "opencode", Model: defaultModel, Timeout: time.Minute, MaxCost: 0.5}
+	_, err := generateHTML(context.Background(), opts, testTimeoutInvoice(), filepath.Join(t.TempDir(), "invoice.html"))
+	if err == nil || err.Error() != "generation cost $0.75, over the $0.50 limit (raise --max-cost or max_cost: in the config)" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var coder kong.ExitCoder
+	if !errors.As(fmt.Errorf("generating HTML invoice: %w", err), &coder) || coder.ExitCode() != exitBudget {
+		t.Errorf("expected exit code %d through wrapping, got %v", exitBudget, coder)
+	}
+}
+
+func TestFormattedNumberFlags(t *testing.T) {
+	t.Setenv("INVOICER_HOURS", "37,5")
+	cmd := parseCLI(t, "--rate", "$1,250.00", "january")
+	if r := cmd.Generate.Rate; r == nil || *r != 1250 {
+		t.Errorf("--rate $1,250.00: got %v, want 1250", r)
+	}
+	if h := cmd.Generate.Hours; h == nil || *h != 37.5 {
+		t.Errorf("INVOICER_HOURS=37,5: got %v, want 37.5", h)
+	}
+
+	cmd = parseCLI(t, "totals", "--rate", "1.250,00 €", "--hours", "40")
+	if r := cmd.Totals.Rate; r == nil || *r != 1250 {
+		t.Errorf("totals --rate '1.250,00 €': got %v, want 1250", r)
+	}
+
+	var c CLI
+	p, err := kong.New(&c, kong.Name("invoicer"), kong.Exit(func(int) {}), Mappers())
+	if err != nil {
+		t.Fatalf("kong.New failed: %v", err)
+	}
+	_, err = p.Parse([]string{"--rate", "1,250", "january"})
+	if err == nil || !strings.Contains(err.Error(), `--rate: "1,250" could be 1250 or 1.25`) {
+		t.Errorf("an ambiguous rate should fail, got %v", err)
+	}
+	_, err = p.Parse([]string{"--rate", "Inf", "january"})
+	if err == nil || !strings.Contains(err.Error(), `--rate: "Inf" is not a number`) {
+		t.Errorf("an infinite rate should fail, got %v", err)
+	}
+
+	t.Setenv(config.DecimalSeparatorEnv, ",")
+	cmd = parseCLI(t, "--rate", "1,250", "january")
+	if r := cmd.Generate.Rate; r == nil || *r != 1.25 {
+		t.Errorf("--rate 1,250 with %s=,: got %v, want 1.25", config.DecimalSeparatorEnv, r)
+	}
+}
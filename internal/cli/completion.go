@@ -0,0 +1,298 @@
+package cli
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alecthomas/kong"
+	"github.com/zon/invoicer/internal/config"
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+// CompletionCmd is the 'completion' subcommand.
+// It prints a completion script for the given shell.
+type CompletionCmd struct {
+	// Shell is the shell to generate the completion script for.
+	Shell string `arg:"" enum:"bash,zsh,fish" help:"Shell to generate the completion script for (bash, zsh, or fish)."`
+}
+
+// Run executes the 'completion' subcommand.
+func (c *CompletionCmd) Run() error {
+	script, err := completionScript(c.Shell)
+	if err != nil {
+		return err
+	}
+	fmt.Print(script)
+	return nil
+}
+
+// CompleteCmd is the hidden '__complete' subcommand invoked by completion scripts.
+// It prints one candidate per line for the last word of the given command line.
+type CompleteCmd struct {
+	// Words is the command line after the program name, ending with the word being completed.
+	Words []string `arg:"" optional:"" passthrough:"" help:"Command line words, ending with the word being completed."`
+}
+
+// Run executes the '__complete' subcommand.
+func (c *CompleteCmd) Run(k *kong.Kong, g *Globals) error {
+	for _, candidate := range complete(k.Model.Node, c.Words, g.Config) {
+		fmt.Println(candidate)
+	}
+	return nil
+}
+
+// predictors maps the names used in `predictor:""` struct tags to the
+// functions that return candidates for a partial word.
+// configPath is the config file in effect for the completed command line.
+var predictors = map[string]func(configPath, partial string) []string{
+	"month":       func(_, partial string) []string { return PredictMonths(partial) },
+	"customer":    predictCustomers,
+	"backend":     func(_, partial string) []string { return predictPrefix(invoice.Backends(), partial) },
+	"pdf_engine":  func(_, partial string) []string { return predictPrefix(invoice.PDFEngines(), partial) },
+	"xero_region": func(_, partial string) []string { return predictPrefix(invoice.XeroRegions(), partial) },
+	"holiday_country": func(_, partial string) []string {
+		return predictPrefix(invoice.HolidayRegions(), strings.ToUpper(partial))
+	},
+	"email_provider": func(_, partial string) []string {
+		return predictPrefix(invoice.EmailProviders(), partial)
+	},
+	"smtp_security": func(_, partial string) []string {
+		return predictPrefix(invoice.SMTPSecurities(), partial)
+	},
+	"profile": func(_, partial string) []string {
+		names, _ := config.Profiles()
+		return predictPrefix(names, partial)
+	},
+	"pdf_tool": func(_, partial string) []string {
+		return predictPrefix(append([]string{invoice.AutoPDFTool}, invoice.PDFTools()...), partial)
+	},
+}
+
+// predictPrefix returns the candidates starting with partial.
+func predictPrefix(candidates []string, partial string) []string {
+	var out []string
+	for _, c := range candidates {
+		if strings.HasPrefix(c, partial) {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// PredictMonths returns the month names, abbreviations, or numbers starting with partial.
+func PredictMonths(partial string) []string {
+	var out []string
+	lower := strings.ToLower(partial)
+	numeric := lower != "" && lower[0] >= '0' && lower[0] <= '9'
+	for m := time.January; m <= time.December; m++ {
+		candidates := []string{strings.ToLower(m.String())}
+		if numeric {
+			candidates = []string{fmt.Sprint(int(m))}
+		} else if len(candidates[0]) > 3 {
+			candidates = append([]string{candidates[0][:3]}, candidates...)
+		}
+		for _, candidate := range candidates {
+			if strings.HasPrefix(candidate, lower) {
+				out = append(out, candidate)
+			}
+		}
+	}
+	return out
+}
+
+// PredictCustomers returns the customer names in cfg starting with partial.
+func PredictCustomers(cfg *config.Config, partial string) []string {
+	var out []string
+	if cfg.Customer != nil && *cfg.Customer != "" && strings.HasPrefix(strings.ToLower(*cfg.Customer), strings.ToLower(partial)) {
+		out = append(out, *cfg.Customer)
+	}
+	return out
+}
+
+// predictCustomers loads the config at configPath (or the default path if empty)
+// and predicts customer names from it.
+// Errors are ignored since completion must never fail noisily.
+func predictCustomers(configPath, partial string) []string {
+	path, err := (&Globals{Config: configPath}).configPath()
+	if err != nil {
+		return nil
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		return nil
+	}
+	return PredictCustomers(cfg, partial)
+}
+
+// complete returns the completion candidates for the last word in words,
+// walking the kong model from node to find the active command.
+// configPath is updated if the words include a --config flag.
+func complete(node *kong.Node, words []string, configPath string) []string {
+	if len(words) == 0 {
+		words = []string{""}
+	}
+	partial := words[len(words)-1]
+
+	positional := 0
+	var pending *kong.Flag
+	for _, w := range words[:len(words)-1] {
+		if pending != nil {
+			if pending.Name == "config" {
+				configPath = w
+			}
+			pending = nil
+			continue
+		}
+		if strings.HasPrefix(w, "-") {
+			f := findFlag(node, w)
+			if name, value, ok := strings.Cut(w, "="); ok && name == "--config" {
+				configPath = value
+			} else if f != nil && !f.IsBool() && !ok {
+				pending = f
+			}
+			continue
+		}
+		if positional == 0 {
+			if child := findChild(node, w); child != nil {
+				node = child
+				continue
+			}
+		}
+		if node.DefaultCmd != nil {
+			node = node.DefaultCmd
+		}
+		positional++
+	}
+
+	if pending != nil {
+		return predict(pending.Tag, configPath, partial)
+	}
+
+	var out []string
+	if strings.HasPrefix(partial, "-") {
+		for _, f := range visibleFlags(node) {
+			for _, name := range []string{"--" + f.Name, negatedName(f)} {
+				if name != "" && strings.HasPrefix(name, partial) {
+					out = append(out, name)
+				}
+			}
+		}
+		sort.Strings(out)
+		return out
+	}
+
+	if positional == 0 {
+		for _, child := range node.Children {
+			if !child.Hidden && strings.HasPrefix(child.Name, partial) {
+				out = append(out, child.Name)
+			}
+		}
+	}
+	argNode := node
+	if argNode.DefaultCmd != nil {
+		argNode = argNode.DefaultCmd
+	}
+	if positional < len(argNode.Positional) {
+		out = append(out, predict(argNode.Positional[positional].Tag, configPath, partial)...)
+	}
+	return out
+}
+
+// predict runs the predictor named by the tag's `predictor` key, if any.
+func predict(tag *kong.Tag, configPath, partial string) []string {
+	if fn, ok := predictors[tag.Get("predictor")]; ok {
+		return fn(configPath, partial)
+	}
+	return nil
+}
+
+// visibleFlags returns the non-hidden flags available at node, including inherited
+// flags and those of its default command.
+func visibleFlags(node *kong.Node) []*kong.Flag {
+	var out []*kong.Flag
+	groups := node.AllFlags(true)
+	if node.DefaultCmd != nil {
+		groups = append(groups, node.DefaultCmd.Flags)
+	}
+	for _, group := range groups {
+		out = append(out, group...)
+	}
+	return out
+}
+
+// negatedName returns the --no-<name> form of a negatable flag, or "" if it
+// is not negatable.
+func negatedName(f *kong.Flag) string {
+	switch f.Tag.Negatable {
+	case "":
+		return ""
+	case "_":
+		// kong's placeholder for the default negation.
+		return "--no-" + f.Name
+	default:
+		return "--" + f.Tag.Negatable
+	}
+}
+
+// findFlag returns the flag matching the word (e.g. "--customer", "-c", "--rate=5") at node.
+func findFlag(node *kong.Node, word string) *kong.Flag {
+	name, _, _ := strings.Cut(word, "=")
+	for _, f := range visibleFlags(node) {
+		if name == "--"+f.Name || name == negatedName(f) || (f.Short != 0 && name == "-"+string(f.Short)) {
+			return f
+		}
+	}
+	return nil
+}
+
+// findChild returns the subcommand of node with the given name or alias.
+func findChild(node *kong.Node, name string) *kong.Node {
+	for _, child := range node.Children {
+		if child.Name == name {
+			return child
+		}
+		for _, alias := range child.Aliases {
+			if alias == name {
+				return child
+			}
+		}
+	}
+	return nil
+}
+
+// completionScript returns the completion script for the given shell.
+func completionScript(shell string) (string, error) {
+	switch shell {
+	case "bash":
+		return bashCompletion, nil
+	case "zsh":
+		return zshCompletion, nil
+	case "fish":
+		return fishCompletion, nil
+	}
+	return "", fmt.Errorf("unsupported shell %q (use bash, zsh, or fish)", shell)
+}
+
+const bashCompletion = `# bash completion for invoicer
+_invoicer_complete() {
+    local IFS=$'\n'
+    COMPREPLY=($(invoicer __complete "${COMP_WORDS[@]:1:$COMP_CWORD}" 2>/dev/null))
+}
+complete -o default -F _invoicer_complete invoicer
+`
+
+const zshCompletion = `#compdef invoicer
+# zsh completion for invoicer
+_invoicer() {
+    local -a candidates
+    candidates=("${(@f)$(invoicer __complete "${(@)words[2,$CURRENT]}" 2>/dev/null)}")
+    compadd -- $candidates
+}
+compdef _invoicer invoicer
+`
+
+const fishCompletion = `# fish completion for invoicer
+complete -c invoicer -f -a '(invoicer __complete (commandline -opc)[2..-1] (commandline -ct) 2>/dev/null)'
+`
@@ -0,0 +1,118 @@
+package cli
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/kong"
+	"github.com/zon/invoicer/internal/config"
+)
+
+func TestPredictMonths(t *testing.T) {
+	tests := []struct {
+		partial string
+		want    []string
+	}{
+		{"ja", []string{"jan", "january"}},
+		{"MAR", []string{"mar", "march"}},
+		{"may", []string{"may"}},
+		{"1", []string{"1", "10", "11", "12"}},
+		{"x", nil},
+	}
+	for _, tt := range tests {
+		got := PredictMonths(tt.partial)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("PredictMonths(%q) = %v, want %v", tt.partial, got, tt.want)
+		}
+	}
+}
+
+func TestPredictCustomers(t *testing.T) {
+	cfg := &config.Config{Customer: strPtr("Acme Corp")}
+	if got := PredictCustomers(cfg, "ac"); !reflect.DeepEqual(got, []string{"Acme Corp"}) {
+		t.Errorf("PredictCustomers(ac) = %v, want [Acme Corp]", got)
+	}
+	if got := PredictCustomers(cfg, "z"); got != nil {
+		t.Errorf("PredictCustomers(z) = %v, want nil", got)
+	}
+	if got := PredictCustomers(&config.Config{}, ""); got != nil {
+		t.Errorf("PredictCustomers with empty config = %v, want nil", got)
+	}
+}
+
+func completionModel(t *testing.T) *kong.Node {
+	t.Helper()
+	var cmd CLI
+	k, err := kong.New(&cmd, kong.Name("invoicer"), kong.Exit(func(int) {}))
+	if err != nil {
+		t.Fatalf("kong.New failed: %v", err)
+	}
+	return k.Model.Node
+}
+
+func TestComplete_MonthPositional(t *testing.T) {
+	got := complete(completionModel(t), []string{"feb"}, "")
+	if !reflect.DeepEqual(got, []string{"feb", "february"}) {
+		t.Errorf("complete(feb) = %v", got)
+	}
+}
+
+func TestComplete_Subcommands(t *testing.T) {
+	// Subcommands are offered alongside months for the default generate command.
+	got := complete(completionModel(t), []string{"se"}, "")
+	if !reflect.DeepEqual(got, []string{"set", "serve", "send", "sep", "september"}) {
+		t.Errorf("complete(se) = %v, want [set serve send sep september]", got)
+	}
+	for _, c := range complete(completionModel(t), []string{"__"}, "") {
+		t.Errorf("hidden command offered: %q", c)
+	}
+}
+
+func TestComplete_Flags(t *testing.T) {
+	got := complete(completionModel(t), []string{"--cu"}, "")
+	if want := []string{"--customer", "--customer-country", "--customer-vat-id"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("complete(--cu) = %v, want %v", got, want)
+	}
+	got = complete(completionModel(t), []string{"set", "config", "--ven"}, "")
+	if want := []string{"--vendor", "--vendor-country", "--vendor-vat-id"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("complete(set config --ven) = %v, want %v", got, want)
+	}
+	got = complete(completionModel(t), []string{"--no-p"}, "")
+	if !reflect.DeepEqual(got, []string{"--no-pdf", "--no-pdf-only"}) {
+		t.Errorf("complete(--no-p) = %v, want [--no-pdf --no-pdf-only]", got)
+	}
+}
+
+func TestComplete_FlagValueUsesPredictor(t *testing.T) {
+	got := complete(completionModel(t), []string{"january", "--rate", "100", "-c", "q"}, "")
+	// No config customer starts with "q", but the month predictor must not be used.
+	for _, c := range got {
+		if !strings.HasPrefix(c, "q") {
+			t.Errorf("unexpected candidate %q for -c value", c)
+		}
+	}
+}
+
+func TestCompletionScript(t *testing.T) {
+	for _, shell := range []string{"bash", "zsh", "fish"} {
+		script, err := completionScript(shell)
+		if err != nil {
+			t.Fatalf("completionScript(%s): %v", shell, err)
+		}
+		if !strings.Contains(script, "invoicer __complete") {
+			t.Errorf("%s script does not call __complete", shell)
+		}
+	}
+	if _, err := completionScript("powershell"); err == nil {
+		t.Error("expected error for unsupported shell")
+	}
+}
+
+func TestComplete_CustomerFromConfigFlag(t *testing.T) {
+	path := writeTestConfig(t, "customer: Side Client\n")
+	got := complete(completionModel(t), []string{"--config", path, "--customer", "si"}, "")
+	if !reflect.DeepEqual(got, []string{"Side Client"}) {
+		t.Errorf("complete(--customer si) = %v, want [Side Client]", got)
+	}
+}
@@ -0,0 +1,205 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/zon/invoicer/internal/config"
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+// cliFlag is a generate flag and whether it was given.
+type cliFlag struct {
+	name string
+	set  bool
+}
+
+// given returns the names of the flags that were given, in order.
+func given(flags ...cliFlag) []string {
+	var names []string
+	for _, f := range flags {
+		if f.set {
+			names = append(names, f.name)
+		}
+	}
+	return names
+}
+
+// checkFlags reports every combination of the given flags that makes no
+// sense together, with why, as one error. Only flags from the command
+// line and the environment are checked: settings from the config are
+// passed over where they do not apply, so the config can hold defaults
+// for every kind of run. The checks are made here rather than with kong's
+// xor groups so that all the conflicts are reported at once.
+func (c *GenerateCmd) checkFlags() error {
+	var errs []error
+	conflict := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	// Each of these writes one thing and exits without generating.
+	only := given(
+		cliFlag{"--prompt-only", c.PromptOnly.Set},
+		cliFlag{"--export-json", c.ExportJSON.Set},
+		cliFlag{"--export-ubl", c.ExportUBL.Set},
+		cliFlag{"--stripe-only", c.StripeOnly},
+		cliFlag{"--paypal-only", c.PayPalOnly},
+	)
+	for _, name := range only[min(1, len(only)):] {
+		conflict("%s cannot be combined with %s: each writes one thing and exits, so run them one at a time", name, only[0])
+	}
+
+	converting := given(
+		cliFlag{"--pdf", c.PDF != nil && *c.PDF},
+		cliFlag{"--pdf-only", c.PDFOnly != nil && *c.PDFOnly},
+		cliFlag{"--pdf-password", c.PDFPassword.Set},
+		cliFlag{"--thumbnail", c.Thumbnail != nil && *c.Thumbnail},
+		cliFlag{"--send", c.Send},
+	)
+	if len(only) > 0 {
+		for _, name := range converting {
+			conflict("%s cannot be combined with %s: no invoice is generated to convert or send", only[0], name)
+		}
+	}
+
+	if c.PDFOnly != nil && *c.PDFOnly && c.PDF != nil && !*c.PDF {
+		conflict("--pdf-only cannot be combined with --no-pdf: --pdf-only implies --pdf")
+	}
+
+	// A text invoice is written without a backend and has no HTML to
+	// convert.
+	if c.FormatOut == textFormat {
+		for _, name := range converting {
+			conflict("--format-out text cannot be combined with %s: a text invoice has no HTML to convert", name)
+		}
+		for _, name := range given(
+			cliFlag{"--prompt-only", c.PromptOnly.Set},
+			cliFlag{"--model", c.Model != nil},
+			cliFlag{"--backend", c.Backend != nil},
+			cliFlag{"--agent", c.Agent != nil},
+			cliFlag{"--session", c.Session != nil},
+			cliFlag{"--ollama-host", c.OllamaHost != nil},
+			cliFlag{"--ollama-model", c.OllamaModel != nil},
+		) {
+			conflict("--format-out text cannot be combined with %s: a text invoice is written without a backend", name)
+		}
+		for _, name := range given(
+			cliFlag{"--no-format", c.NoFormat},
+			cliFlag{"--minify", c.Minify},
+		) {
+			conflict("--format-out text cannot be combined with %s: a text invoice has no HTML to format", name)
+		}
+	}
+	if c.NoFormat && c.Minify {
+		conflict("--minify cannot be combined with --no-format: minifying is a way of formatting")
+	}
+
+	switch {
+	case c.AttachHTML && !c.Send:
+		conflict("--attach-html needs --send")
+	case c.AttachHTML && c.PDFOnly != nil && *c.PDFOnly:
+		conflict("--attach-html cannot be combined with --pdf-only, which removes the HTML invoice")
+	}
+	// Standard input holds the timesheet, so nothing can be asked for.
+	if c.Timesheet == stdinPath && c.PDFPassword.Set && c.PDFPassword.Password == "" {
+		conflict("--timesheet - cannot be combined with --pdf-password without a value: standard input holds the timesheet, so the password cannot be asked for (use --pdf-password=PASSWORD)")
+	}
+	// A spec is complete, so nothing else can describe the invoice.
+	if c.FromFile != "" {
+		for _, name := range given(
+			cliFlag{"a month", c.Month != ""},
+			cliFlag{"a year", c.Year != 0},
+			cliFlag{"--vendor", c.Vendor != ""},
+			cliFlag{"--customer", c.Customer != ""},
+			cliFlag{"--rate", c.Rate != nil},
+			cliFlag{"--hours", c.Hours != nil},
+			cliFlag{"--timesheet", c.Timesheet != ""},
+			cliFlag{"--month-hours", c.MonthHours != nil},
+			cliFlag{"--days-off", len(c.DaysOff) > 0},
+			cliFlag{"--holiday-country", c.HolidayCountry != ""},
+			cliFlag{"--iban", c.IBAN != ""},
+			cliFlag{"--bic", c.BIC != ""},
+			cliFlag{"--routing-number", c.RoutingNumber != ""},
+			cliFlag{"--account-number", c.AccountNumber != ""},
+			cliFlag{"--notes", c.Notes != ""},
+			cliFlag{"--po-number", c.PONumber != ""},
+			cliFlag{"--item", len(c.Items) > 0},
+			cliFlag{"--issue-date", c.IssueDate != ""},
+			cliFlag{"--overrides", c.Overrides != ""},
+		) {
+			conflict("--from-file cannot be combined with %s: the spec file describes the whole invoice", name)
+		}
+	}
+	// Each vendor has an invoice of its own.
+	if c.AllVendors {
+		for _, name := range given(
+			cliFlag{"--vendor", c.Vendor != ""},
+			cliFlag{"--from-file", c.FromFile != ""},
+		) {
+			conflict("--all-vendors cannot be combined with %s: each vendor in vendors: in the config gets an invoice of its own", name)
+		}
+		for _, name := range given(
+			cliFlag{"--prompt-only=PATH", c.PromptOnly.Path != ""},
+			cliFlag{"--export-json=PATH", c.ExportJSON.Path != "" && c.ExportJSON.Path != stdoutPath},
+			cliFlag{"--export-ubl=PATH", c.ExportUBL.Path != "" && c.ExportUBL.Path != stdoutPath},
+		) {
+			conflict("--all-vendors cannot be combined with %s: every vendor's file would be written to PATH (leave it out to name each file after its vendor)", name)
+		}
+	}
+	// The month's hours set every week's hours.
+	if c.MonthHours != nil {
+		for _, name := range given(
+			cliFlag{"--hours", c.Hours != nil},
+			cliFlag{"--timesheet", c.Timesheet != ""},
+		) {
+			conflict("--month-hours cannot be combined with %s: the month's hours are split across its weeks by their workdays", name)
+		}
+	}
+	if c.Overrides != "" && c.NoOverrides {
+		conflict("--overrides cannot be combined with --no-overrides")
+	}
+	if c.Finalize && !c.SendStripe && !c.StripeOnly && !c.SendPayPal && !c.PayPalOnly {
+		conflict("--finalize needs --send-stripe or --send-paypal")
+	}
+
+	return errors.Join(errs...)
+}
+
+// warnFlags warns about flags that are allowed but have no effect with
+// the resolved options.
+func (c *GenerateCmd) warnFlags(opts *ResolvedOptions) {
+	var ignored []string
+	if c.Timesheet != "" && c.Hours != nil {
+		ignored = append(ignored, "--hours has no effect with --timesheet")
+	}
+	if c.Timesheet != "" && len(c.DaysOff) > 0 {
+		ignored = append(ignored, "--days-off has no effect on the hours of --timesheet, which gives each day's hours")
+	}
+	if c.Timesheet != "" && c.HolidayCountry != "" {
+		ignored = append(ignored, "--holiday-country has no effect on the hours of --timesheet, which gives each day's hours")
+	}
+	if c.ThumbnailWidth != nil && !opts.Thumbnail {
+		ignored = append(ignored, "--thumbnail-width has no effect without --thumbnail")
+	}
+	if c.Force && c.IssueDate == "" {
+		ignored = append(ignored, "--force has no effect without --issue-date")
+	}
+	if c.FXRate != nil && opts.BillCurrency == "" {
+		ignored = append(ignored, "--fx-rate has no effect without --bill-currency")
+	}
+	if opts.BillCurrency != "" && (c.ExportUBL.Set || opts.SendStripe || opts.SendPayPal) {
+		ignored = append(ignored, "--bill-currency has no effect on e-invoices and Stripe and PayPal invoices, which are billed in "+invoice.Currency)
+	}
+	if c.StyleFrom != "" && opts.FormatOut == textFormat {
+		ignored = append(ignored, "--style-from has no effect with --format-out text")
+	}
+	if (c.NoFormat || c.Minify) && c.SkipVerify {
+		ignored = append(ignored, "--no-format and --minify have no effect with --skip-verify, which keeps the HTML as the backend wrote it")
+	}
+	if (c.OllamaHost != nil || c.OllamaModel != nil) && opts.Backend != "ollama" && opts.FormatOut != textFormat {
+		ignored = append(ignored, fmt.Sprintf("--ollama-host and --ollama-model have no effect with the %s backend", opts.Backend))
+	}
+	for _, msg := range ignored {
+		fmt.Fprintf(config.Warnings, "Warning: %s\n", msg)
+	}
+}
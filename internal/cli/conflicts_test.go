@@ -0,0 +1,117 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/zon/invoicer/internal/config"
+)
+
+func TestCheckFlags(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{"none", nil, ""},
+		{"pdf and thumbnail", []string{"--pdf", "--thumbnail"}, ""},
+		{"config-only flags", []string{"--format-out=text", "--no-pdf"}, ""},
+		{"two exports", []string{"--export-json", "--export-ubl"}, "--export-ubl cannot be combined with --export-json: each writes one thing and exits"},
+		{"prompt and paypal", []string{"--prompt-only", "--paypal-only"}, "--paypal-only cannot be combined with --prompt-only"},
+		{"export with pdf", []string{"--export-json=-", "--pdf"}, "--export-json cannot be combined with --pdf: no invoice is generated"},
+		{"prompt with send", []string{"--prompt-only", "--send"}, "--prompt-only cannot be combined with --send"},
+		{"stripe only with thumbnail", []string{"--stripe-only", "--thumbnail"}, "--stripe-only cannot be combined with --thumbnail"},
+		{"pdf-only with no-pdf", []string{"--pdf-only", "--no-pdf"}, "--pdf-only cannot be combined with --no-pdf: --pdf-only implies --pdf"},
+		{"text with pdf", []string{"--format-out=text", "--pdf"}, "--format-out text cannot be combined with --pdf: a text invoice has no HTML to convert"},
+		{"text with pdf password", []string{"--format-out=text", "--pdf-password=secret"}, "--format-out text cannot be combined with --pdf-password"},
+		{"text with model", []string{"--format-out=text", "--model", "anthropic/claude-haiku-4-5"}, "--format-out text cannot be combined with --model: a text invoice is written without a backend"},
+		{"text with backend", []string{"--format-out=text", "--backend", "claude"}, "--format-out text cannot be combined with --backend"},
+		{"text with agent", []string{"--format-out=text", "--agent", "invoice"}, "--format-out text cannot be combined with --agent"},
+		{"text with session", []string{"--format-out=text", "--session", "ses_1"}, "--format-out text cannot be combined with --session"},
+		{"text with ollama", []string{"--format-out=text", "--ollama-model", "llama3.2"}, "--format-out text cannot be combined with --ollama-model"},
+		{"text with prompt", []string{"--format-out=text", "--prompt-only"}, "--format-out text cannot be combined with --prompt-only"},
+		{"text with minify", []string{"--format-out=text", "--minify"}, "--format-out text cannot be combined with --minify: a text invoice has no HTML to format"},
+		{"minify with no-format", []string{"--minify", "--no-format"}, "--minify cannot be combined with --no-format"},
+		{"attach without send", []string{"--attach-html"}, "--attach-html needs --send"},
+		{"attach with pdf-only", []string{"--send", "--attach-html", "--pdf-only"}, "--attach-html cannot be combined with --pdf-only"},
+		{"finalize alone", []string{"--finalize"}, "--finalize needs --send-stripe or --send-paypal"},
+		{"from-file alone", []string{"--from-file=spec.yaml", "--pdf"}, ""},
+		{"from-file with period", []string{"--from-file=spec.yaml", "january", "2025"}, "--from-file cannot be combined with a month: the spec file describes the whole invoice"},
+		{"from-file with rate", []string{"--from-file=spec.yaml", "--rate=100"}, "--from-file cannot be combined with --rate"},
+		{"from-file with holiday country", []string{"--from-file=spec.yaml", "--holiday-country=US"}, "--from-file cannot be combined with --holiday-country"},
+		{"stdin timesheet with pdf password", []string{"--timesheet=-", "--pdf-password=secret"}, ""},
+		{"all vendors", []string{"--all-vendors", "--export-json=-"}, ""},
+		{"all vendors with vendor", []string{"--all-vendors", "--vendor", "Jane Doe"}, "--all-vendors cannot be combined with --vendor"},
+		{"all vendors with export path", []string{"--all-vendors", "--export-json=out.json"}, "--all-vendors cannot be combined with --export-json=PATH"},
+		{"stdin timesheet with asked pdf password", []string{"--timesheet=-", "--pdf-password"}, "--timesheet - cannot be combined with --pdf-password without a value"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := parseCLI(t, tt.args...).Generate.checkFlags()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("checkFlags: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestCheckFlags_ReportsEveryConflict(t *testing.T) {
+	err := parseCLI(t, "--format-out=text", "--model", "anthropic/claude-haiku-4-5", "--pdf", "--finalize").Generate.checkFlags()
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	lines := strings.Split(err.Error(), "\n")
+	want := []string{
+		"--format-out text cannot be combined with --pdf",
+		"--format-out text cannot be combined with --model",
+		"--finalize needs",
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("got %d conflicts, want %d:\n%v", len(lines), len(want), err)
+	}
+	for i, w := range want {
+		if !strings.HasPrefix(lines[i], w) {
+			t.Errorf("conflict %d = %q, want %q", i, lines[i], w)
+		}
+	}
+}
+
+func TestWarnFlags(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"thumbnail width without thumbnail", []string{"--thumbnail-width", "400"}, "Warning: --thumbnail-width has no effect without --thumbnail\n"},
+		{"thumbnail width with thumbnail", []string{"--thumbnail", "--thumbnail-width", "400"}, ""},
+		{"holiday country with timesheet", []string{"--timesheet", "hours.csv", "--holiday-country", "US"}, "Warning: --holiday-country has no effect on the hours of --timesheet, which gives each day's hours\n"},
+		{"ollama model with opencode", []string{"--ollama-model", "llama3.2"}, "Warning: --ollama-host and --ollama-model have no effect with the opencode backend\n"},
+		{"ollama model with ollama", []string{"--backend", "ollama", "--ollama-model", "llama3.2"}, ""},
+		{"minify with skip verify", []string{"--minify", "--skip-verify"}, "Warning: --no-format and --minify have no effect with --skip-verify, which keeps the HTML as the backend wrote it\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var warnings bytes.Buffer
+			old := config.Warnings
+			config.Warnings = &warnings
+			t.Cleanup(func() { config.Warnings = old })
+
+			cmd := parseCLI(t, tt.args...)
+			opts, err := cmd.Generate.resolveOptions(loadTestConfig(t, writeTestConfig(t, "")), nil)
+			if err != nil {
+				t.Fatalf("resolveOptions: %v", err)
+			}
+			cmd.Generate.warnFlags(opts)
+			if warnings.String() != tt.want {
+				t.Errorf("warnings = %q, want %q", warnings.String(), tt.want)
+			}
+		})
+	}
+}
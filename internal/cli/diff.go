@@ -0,0 +1,480 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/zon/invoicer/internal/config"
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+// DiffCmd is the 'diff' subcommand.
+// It compares a month's invoice with another's, e.g. this month's with
+// last month's.
+type DiffCmd struct {
+	// Periods are the month to review and the month to compare it with,
+	// each a month optionally followed by its year, e.g. "feb 2025 jan
+	// 2025".
+	Periods []string `arg:"" optional:"" predictor:"month" help:"Month to review and month to compare it with, each optionally followed by its year, e.g. 'feb 2025 jan 2025'. Default to the previous month and the month before it."`
+
+	// Vendor and Customer name the invoices, as for generation.
+	Vendor   string `short:"v" env:"INVOICER_VENDOR" help:"Name of the contractor sending the invoices. Defaults to vendor: in the config."`
+	Customer string `short:"c" env:"INVOICER_CUSTOMER" predictor:"customer" help:"Name of the client the invoices are for. Defaults to customer: in the config."`
+
+	// Overrides and NoOverrides choose the overrides file for the month
+	// reviewed, as for generation.
+	Overrides   string `type:"path" help:"Read one-off overrides for the month reviewed from PATH instead of invoice.yaml in the current directory."`
+	NoOverrides bool   `name:"no-overrides" help:"Ignore invoice.yaml in the current directory."`
+
+	// Dir is where the recorded invoices are.
+	Dir string `type:"path" help:"Directory the invoices' JSON exports are in. Defaults to the current directory."`
+
+	// Recompute works both invoices out from the config, even if they
+	// were recorded.
+	Recompute bool `help:"Work both invoices out from the config, ignoring their JSON exports."`
+
+	// Format is how the comparison is printed.
+	Format string `enum:"table,json" default:"table" help:"Output format: table or json."`
+}
+
+// Run executes the 'diff' subcommand.
+func (c *DiffCmd) Run(g *Globals) error {
+	to, from, err := diffPeriods(c.Periods, invoice.Now())
+	if err != nil {
+		return err
+	}
+	configPath, err := g.configPath()
+	if err != nil {
+		return err
+	}
+	global, err := g.loadConfig(configPath)
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+	local, err := g.localConfig()
+	if err != nil {
+		return err
+	}
+	fromSide, err := c.load(g, global, local, from, false)
+	if err != nil {
+		return fmt.Errorf("%s: %w", from, err)
+	}
+	toSide, err := c.load(g, global, local, to, true)
+	if err != nil {
+		return fmt.Errorf("%s: %w", to, err)
+	}
+	return writeDiff(c.Format, compareInvoices(fromSide, toSide), os.Stdout)
+}
+
+// diffPeriod is a month of a year.
+type diffPeriod struct {
+	month time.Month
+	year  int
+}
+
+// String returns the period as "Jan 2025".
+func (p diffPeriod) String() string {
+	return fmt.Sprintf("%s %d", p.month.String()[:3], p.year)
+}
+
+// diffPeriods reads the month to review and the month to compare it with
+// from args, each a month optionally followed by its year. A number over
+// 12 is a year. The month to review defaults to the month before now's,
+// and the month to compare it with to the month before that.
+func diffPeriods(args []string, now time.Time) (to, from diffPeriod, err error) {
+	type given struct {
+		month string
+		year  int
+	}
+	var periods []given
+	for _, arg := range args {
+		if n, err := strconv.Atoi(arg); err == nil && n > 12 {
+			if len(periods) == 0 || periods[len(periods)-1].year != 0 {
+				return to, from, fmt.Errorf("year %d does not follow a month", n)
+			}
+			periods[len(periods)-1].year = n
+			continue
+		}
+		periods = append(periods, given{month: arg})
+	}
+	if len(periods) > 2 {
+		return to, from, errors.New("give at most two months: the month to review and the month to compare it with")
+	}
+	periods = append(periods, given{}, given{})
+	if to.month, to.year, err = invoice.ResolveMonthYear(periods[0].month, periods[0].year, now); err != nil {
+		return to, from, err
+	}
+	if periods[1].month == "" {
+		prev := time.Date(to.year, to.month-1, 1, 0, 0, 0, 0, time.UTC)
+		return to, diffPeriod{prev.Month(), prev.Year()}, nil
+	}
+	if from.month, from.year, err = invoice.ResolveMonthYear(periods[1].month, periods[1].year, now); err != nil {
+		return to, from, err
+	}
+	return to, from, nil
+}
+
+// diffSide is one of the invoices compared: the one recorded in its JSON
+// export at path, or, if none was, the one a run would generate.
+type diffSide struct {
+	period diffPeriod
+	inv    *invoice.Invoice
+	path   string
+}
+
+// source says where the side's invoice came from.
+func (s *diffSide) source() string {
+	if s.path != "" {
+		return "recorded"
+	}
+	return "computed"
+}
+
+// load returns the invoice of period: its JSON export in the directory,
+// unless --recompute is given, or else the one a run would generate, with
+// the overrides file only if overrides is set.
+func (c *DiffCmd) load(g *Globals, global, local *config.Config, period diffPeriod, overrides bool) (*diffSide, error) {
+	gen := &GenerateCmd{
+		Month:       period.month.String(),
+		Year:        period.year,
+		Vendor:      c.Vendor,
+		Customer:    c.Customer,
+		NoOverrides: c.NoOverrides || !overrides,
+	}
+	if overrides {
+		gen.Overrides = c.Overrides
+	}
+	run, err := gen.prepare(g, global, local)
+	if err != nil {
+		return nil, err
+	}
+	side := &diffSide{period: period, inv: run.inv}
+	if !c.Recompute {
+		dir := c.Dir
+		if dir == "" {
+			dir = invoice.CurrentDir()
+		}
+		path := invoice.JSONFilePath(run.inv, dir)
+		if _, err := os.Stat(path); err == nil {
+			inv, err := readSpecFile(path, g.StrictConfig)
+			if err != nil {
+				return nil, err
+			}
+			run.opts.display(inv)
+			side.inv, side.path = inv, path
+		}
+	}
+	// The weeks are compared by their dates alone.
+	side.inv.ShowWorkdays = false
+	return side, nil
+}
+
+// invoiceDiff is how the invoice reviewed, To, differs from the one it is
+// compared with, From, in the JSON --format json prints. Amounts are in
+// dollars, and each change is To's figure less From's.
+type invoiceDiff struct {
+	From   diffSummary `json:"from"`
+	To     diffSummary `json:"to"`
+	Change diffChange  `json:"change"`
+	// Weeks are the weeks of either invoice, those in the same place in
+	// their months side by side.
+	Weeks []weekDiff `json:"weeks"`
+	// Items are the lines other than weeks of either invoice, those with
+	// the same description side by side.
+	Items []itemDiff `json:"items"`
+}
+
+// diffSummary sums up one of the invoices compared.
+type diffSummary struct {
+	Month  string  `json:"month"`
+	Year   int     `json:"year"`
+	Source string  `json:"source"`
+	Path   string  `json:"path,omitempty"`
+	Rate   float64 `json:"rate"`
+	Hours  float64 `json:"hours"`
+	Total  float64 `json:"total"`
+}
+
+// diffChange is how the rate, the hours and the total changed.
+type diffChange struct {
+	Rate  float64 `json:"rate"`
+	Hours float64 `json:"hours"`
+	Total float64 `json:"total"`
+}
+
+// weekDiff is a week of either invoice. Week is its number in its month,
+// counting from 1, or 0 for a week that is not one of WeeksForMonth's,
+// which is never side by side with another. From or To is nil if only
+// the other invoice has the week.
+type weekDiff struct {
+	Week     int       `json:"week"`
+	From     *diffWeek `json:"from"`
+	To       *diffWeek `json:"to"`
+	Hours    float64   `json:"hours"`
+	Subtotal float64   `json:"subtotal"`
+}
+
+// diffWeek is a week of one of the invoices compared.
+type diffWeek struct {
+	Label    string  `json:"label"`
+	Start    string  `json:"start"`
+	End      string  `json:"end"`
+	Hours    float64 `json:"hours"`
+	Subtotal float64 `json:"subtotal"`
+}
+
+// itemDiff is a line other than a week of either invoice, with its amount
+// in each; nil in one that does not have it.
+type itemDiff struct {
+	Description string   `json:"description"`
+	From        *float64 `json:"from"`
+	To          *float64 `json:"to"`
+	Change      float64  `json:"change"`
+}
+
+// compareInvoices returns how to's invoice differs from from's.
+func compareInvoices(from, to *diffSide) invoiceDiff {
+	fb, tb := from.inv.Breakdown(), to.inv.Breakdown()
+	d := invoiceDiff{
+		From:  summarize(from, fb),
+		To:    summarize(to, tb),
+		Weeks: compareWeeks(from, fb, to, tb),
+		Items: compareItems(fb.Lines[len(from.inv.Weeks):], tb.Lines[len(to.inv.Weeks):]),
+	}
+	d.Change = diffChange{
+		Rate:  (invoice.ToCents(d.To.Rate) - invoice.ToCents(d.From.Rate)).Dollars(),
+		Hours: roundHours(d.To.Hours - d.From.Hours),
+		Total: (tb.AmountDue - fb.AmountDue).Dollars(),
+	}
+	return d
+}
+
+// summarize sums up side, whose breakdown is b. Its hours are those of
+// its lines billed by the hour.
+func summarize(side *diffSide, b invoice.InvoiceBreakdown) diffSummary {
+	s := diffSummary{
+		Month:  side.period.month.String(),
+		Year:   side.period.year,
+		Source: side.source(),
+		Path:   side.path,
+		Rate:   side.inv.Rate,
+		Total:  b.AmountDue.Dollars(),
+	}
+	for _, l := range b.Lines {
+		if l.Unit == invoice.UnitHour {
+			s.Hours += l.Quantity
+		}
+	}
+	s.Hours = roundHours(s.Hours)
+	return s
+}
+
+// compareWeeks puts the weeks of the two invoices side by side by their
+// number in their months, in order, followed by any that have none.
+func compareWeeks(from *diffSide, fb invoice.InvoiceBreakdown, to *diffSide, tb invoice.InvoiceBreakdown) []weekDiff {
+	var weeks, loose []weekDiff
+	add := func(side *diffSide, b invoice.InvoiceBreakdown, isTo bool) {
+		month := invoice.WeeksForMonth(side.period.year, side.period.month, 0)
+		for i, w := range side.inv.Weeks {
+			dw := &diffWeek{
+				Label:    b.Lines[i].Description,
+				Start:    w.Start.Format(time.DateOnly),
+				End:      w.End.Format(time.DateOnly),
+				Hours:    w.Hours,
+				Subtotal: b.Lines[i].Amount.Dollars(),
+			}
+			n := slices.IndexFunc(month, func(m invoice.Week) bool {
+				return !w.Start.Before(m.Start) && !w.Start.After(m.End)
+			}) + 1
+			var wd *weekDiff
+			if j := slices.IndexFunc(weeks, func(wd weekDiff) bool { return wd.Week == n }); n > 0 && j >= 0 {
+				wd = &weeks[j]
+			} else if n > 0 {
+				weeks = append(weeks, weekDiff{Week: n})
+				wd = &weeks[len(weeks)-1]
+			} else {
+				loose = append(loose, weekDiff{})
+				wd = &loose[len(loose)-1]
+			}
+			if isTo {
+				wd.To = dw
+			} else {
+				wd.From = dw
+			}
+		}
+	}
+	add(from, fb, false)
+	add(to, tb, true)
+	slices.SortStableFunc(weeks, func(a, b weekDiff) int { return a.Week - b.Week })
+	weeks = append(weeks, loose...)
+	for i := range weeks {
+		wd := &weeks[i]
+		var fromHours, toHours float64
+		var fromSub, toSub invoice.Cents
+		if wd.From != nil {
+			fromHours, fromSub = wd.From.Hours, invoice.ToCents(wd.From.Subtotal)
+		}
+		if wd.To != nil {
+			toHours, toSub = wd.To.Hours, invoice.ToCents(wd.To.Subtotal)
+		}
+		wd.Hours = roundHours(toHours - fromHours)
+		wd.Subtotal = (toSub - fromSub).Dollars()
+	}
+	return weeks
+}
+
+// compareItems puts the lines other than weeks of the two invoices side
+// by side by their description, in the order from has them, followed by
+// those only to has. Lines with the same description are summed.
+func compareItems(from, to []invoice.BreakdownLine) []itemDiff {
+	var items []itemDiff
+	amounts := make(map[string][2]invoice.Cents)
+	has := make(map[string][2]bool)
+	for side, lines := range [][]invoice.BreakdownLine{from, to} {
+		for _, l := range lines {
+			if !has[l.Description][0] && !has[l.Description][1] {
+				items = append(items, itemDiff{Description: l.Description})
+			}
+			a, h := amounts[l.Description], has[l.Description]
+			a[side] += l.Amount
+			h[side] = true
+			amounts[l.Description], has[l.Description] = a, h
+		}
+	}
+	for i := range items {
+		it := &items[i]
+		a, h := amounts[it.Description], has[it.Description]
+		fromAmount, toAmount := a[0].Dollars(), a[1].Dollars()
+		if h[0] {
+			it.From = &fromAmount
+		}
+		if h[1] {
+			it.To = &toAmount
+		}
+		it.Change = (a[1] - a[0]).Dollars()
+	}
+	return items
+}
+
+// roundHours rounds h to hundredths of an hour, so that sums and
+// differences of hours do not show the error of floating point.
+func roundHours(h float64) float64 {
+	return math.Round(h*100) / 100
+}
+
+// writeDiff writes d to w in format: tables of the totals, the weeks and
+// the other items, or JSON.
+func writeDiff(format string, d invoiceDiff, w io.Writer) error {
+	if format == "json" {
+		data, err := json.MarshalIndent(d, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(append(data, '\n'))
+		return err
+	}
+
+	from := strings.ToUpper(fmt.Sprintf("%.3s %d", d.From.Month, d.From.Year))
+	to := strings.ToUpper(fmt.Sprintf("%.3s %d", d.To.Month, d.To.Year))
+	var out bytes.Buffer
+	tw := tabwriter.NewWriter(&out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "\t%s\t%s\tCHANGE\n", from, to)
+	fmt.Fprintf(tw, "Source\t%s\t%s\n", d.From.Source, d.To.Source)
+	fmt.Fprintf(tw, "Rate\t%s\t%s\t%s\n", dollars(d.From.Rate), dollars(d.To.Rate), signedDollars(d.Change.Rate))
+	fmt.Fprintf(tw, "Hours\t%s\t%s\t%s\n", hours(d.From.Hours), hours(d.To.Hours), signedHours(d.Change.Hours))
+	fmt.Fprintf(tw, "Total\t%s\t%s\t%s\n", dollars(d.From.Total), dollars(d.To.Total), signedDollars(d.Change.Total))
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	out.WriteString("\n")
+	tw = tabwriter.NewWriter(&out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "WEEK\t%s\tHOURS\t%s\tHOURS\tCHANGE\tSUBTOTAL CHANGE\n", from, to)
+	for _, wd := range d.Weeks {
+		n := "-"
+		if wd.Week > 0 {
+			n = strconv.Itoa(wd.Week)
+		}
+		fromLabel, fromHours, toLabel, toHours := "-", "-", "-", "-"
+		if wd.From != nil {
+			fromLabel, fromHours = wd.From.Label, hours(wd.From.Hours)
+		}
+		if wd.To != nil {
+			toLabel, toHours = wd.To.Label, hours(wd.To.Hours)
+		}
+		change, subtotal := signedHours(wd.Hours), signedDollars(wd.Subtotal)
+		switch {
+		case wd.From == nil:
+			change = "new"
+		case wd.To == nil:
+			change = "removed"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", n, fromLabel, fromHours, toLabel, toHours, change, subtotal)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if len(d.Items) > 0 {
+		out.WriteString("\n")
+		tw = tabwriter.NewWriter(&out, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(tw, "ITEM\t%s\t%s\tCHANGE\n", from, to)
+		for _, it := range d.Items {
+			fromAmount, toAmount, change := "-", "-", signedDollars(it.Change)
+			if it.From != nil {
+				fromAmount = dollars(*it.From)
+			}
+			if it.To != nil {
+				toAmount = dollars(*it.To)
+			}
+			switch {
+			case it.From == nil:
+				change = "new"
+			case it.To == nil:
+				change = "removed"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", it.Description, fromAmount, toAmount, change)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+	_, err := out.WriteTo(w)
+	return err
+}
+
+// dollars returns the amount d to the cent, e.g. "3600.00".
+func dollars(d float64) string {
+	return invoice.ToCents(d).String()
+}
+
+// signedDollars is like dollars, with a plus sign if d is positive.
+func signedDollars(d float64) string {
+	if d > 0 {
+		return "+" + dollars(d)
+	}
+	return dollars(d)
+}
+
+// hours returns h with the decimals it has, e.g. "37.5".
+func hours(h float64) string {
+	return strconv.FormatFloat(h, 'f', -1, 64)
+}
+
+// signedHours is like hours, with a plus sign if h is positive.
+func signedHours(h float64) string {
+	if h > 0 {
+		return "+" + hours(h)
+	}
+	return hours(h)
+}
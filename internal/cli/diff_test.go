@@ -0,0 +1,134 @@
+package cli
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// diffFixture sets up a config and the JSON exports of the invoices for
+// January and February 2025 to Acme Corp, in a new current directory, and
+// returns the config's path. February's rate is higher, its third week
+// is shortened by a vacation, and each month has an item of its own.
+func diffFixture(t *testing.T) string {
+	t.Helper()
+	path := writeTestConfig(t, "vendor: Jane Doe\ncustomer: Acme Corp\nrate: 165\nhours: 40\n")
+	dir := t.TempDir()
+	t.Chdir(dir)
+	for name, content := range map[string]string{
+		"invoice-acme-corp-2025-01.json": `{
+  "month": "January", "year": 2025, "vendor": "Jane Doe", "customer": "Acme Corp", "rate": 150,
+  "weeks": [
+    {"start": "2025-01-01", "end": "2025-01-05", "hours": 24},
+    {"start": "2025-01-06", "end": "2025-01-12", "hours": 40},
+    {"start": "2025-01-13", "end": "2025-01-19", "hours": 40},
+    {"start": "2025-01-20", "end": "2025-01-26", "hours": 40},
+    {"start": "2025-01-27", "end": "2025-01-31", "hours": 40}
+  ],
+  "adjustments": [{"description": "Travel", "amount": 120.50}]
+}`,
+		"invoice-acme-corp-2025-02.json": `{
+  "month": "February", "year": 2025, "vendor": "Jane Doe", "customer": "Acme Corp", "rate": 165,
+  "weeks": [
+    {"start": "2025-02-03", "end": "2025-02-09", "hours": 40},
+    {"start": "2025-02-10", "end": "2025-02-16", "hours": 40},
+    {"start": "2025-02-17", "end": "2025-02-23", "hours": 16},
+    {"start": "2025-02-24", "end": "2025-02-28", "hours": 40}
+  ],
+  "adjustments": [{"description": "Domain renewal", "amount": 18.99}]
+}`,
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return path
+}
+
+func TestDiff(t *testing.T) {
+	path := diffFixture(t)
+	out := captureStdout(t, func() {
+		if err := runCLI(t, "diff", "--config", path, "--no-local", "feb", "2025", "jan", "2025"); err != nil {
+			t.Errorf("diff: %v", err)
+		}
+	})
+	want := `        JAN 2025  FEB 2025  CHANGE
+Source  recorded  recorded
+Rate    150.00    165.00    +15.00
+Hours   184       136       -48
+Total   27720.50  22458.99  -5261.51
+
+WEEK  JAN 2025   HOURS  FEB 2025   HOURS  CHANGE   SUBTOTAL CHANGE
+1     Jan 1-5    24     Feb 3-9    40     +16      +3000.00
+2     Jan 6-12   40     Feb 10-16  40     0        +600.00
+3     Jan 13-19  40     Feb 17-23  16     -24      -3360.00
+4     Jan 20-26  40     Feb 24-28  40     0        +600.00
+5     Jan 27-31  40     -          -      removed  -6000.00
+
+ITEM            JAN 2025  FEB 2025  CHANGE
+Travel          120.50    -         removed
+Domain renewal  -         18.99     new
+`
+	if out != want {
+		t.Errorf("got:\n%s\nwant:\n%s", out, want)
+	}
+}
+
+func TestDiff_JSONWithComputedSide(t *testing.T) {
+	path := diffFixture(t)
+	if err := os.Remove("invoice-acme-corp-2025-02.json"); err != nil {
+		t.Fatal(err)
+	}
+	out := captureStdout(t, func() {
+		if err := runCLI(t, "diff", "--config", path, "--no-local", "--format", "json", "feb", "2025", "jan", "2025"); err != nil {
+			t.Errorf("diff: %v", err)
+		}
+	})
+	var d invoiceDiff
+	if err := json.Unmarshal([]byte(out), &d); err != nil {
+		t.Fatalf("reading the JSON: %v\n%s", err, out)
+	}
+	// February is worked out from the config: four full weeks at 165.
+	if d.From.Source != "recorded" || d.To.Source != "computed" {
+		t.Errorf("sources = %s and %s, want recorded and computed", d.From.Source, d.To.Source)
+	}
+	if want := (diffChange{Rate: 15, Hours: -24, Total: -1320.50}); d.Change != want {
+		t.Errorf("change = %+v, want %+v", d.Change, want)
+	}
+	if len(d.Weeks) != 5 || d.Weeks[0].Hours != 16 || d.Weeks[4].To != nil {
+		t.Errorf("weeks = %+v, want January's five beside February's four", d.Weeks)
+	}
+	if len(d.Items) != 1 || d.Items[0].Description != "Travel" || d.Items[0].To != nil {
+		t.Errorf("items = %+v, want Travel removed", d.Items)
+	}
+}
+
+func TestDiffPeriods(t *testing.T) {
+	now := time.Date(2025, time.March, 10, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		args     []string
+		to, from diffPeriod
+		err      bool
+	}{
+		{nil, diffPeriod{time.February, 2025}, diffPeriod{time.January, 2025}, false},
+		{[]string{"jan", "2025"}, diffPeriod{time.January, 2025}, diffPeriod{time.December, 2024}, false},
+		{[]string{"feb", "jan"}, diffPeriod{time.February, 2025}, diffPeriod{time.January, 2025}, false},
+		{[]string{"3", "2024", "3"}, diffPeriod{time.March, 2024}, diffPeriod{time.March, 2025}, false},
+		{[]string{"2025", "jan"}, diffPeriod{}, diffPeriod{}, true},
+		{[]string{"jan", "feb", "mar"}, diffPeriod{}, diffPeriod{}, true},
+	}
+	for _, tt := range tests {
+		to, from, err := diffPeriods(tt.args, now)
+		if tt.err {
+			if err == nil {
+				t.Errorf("%v: expected an error", tt.args)
+			}
+			continue
+		}
+		if err != nil || to != tt.to || from != tt.from {
+			t.Errorf("%v = %v, %v, %v; want %v, %v", tt.args, to, from, err, tt.to, tt.from)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/zon/invoicer/internal/config"
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+// DueCmd is the 'due' subcommand.
+type DueCmd struct {
+	// Dir holds a directory per customer.
+	Dir string `type:"path" help:"Directory holding one directory per customer, each with a .invoicer.yaml. Defaults to the current directory."`
+
+	// Generate generates the invoices that are due.
+	Generate bool `help:"Generate the invoices that are due, each in its customer's directory."`
+}
+
+// dueInvoice is an invoice the schedule says should have been generated.
+type dueInvoice struct {
+	// Dir is the customer's directory.
+	Dir      string
+	Customer string
+	Month    time.Month
+	Year     int
+	// Since is the day the invoice became due.
+	Since time.Time
+}
+
+// Run executes the 'due' subcommand.
+func (c *DueCmd) Run(g *Globals, ctx context.Context) error {
+	configPath, err := g.configPath()
+	if err != nil {
+		return err
+	}
+	global, err := g.loadConfig(configPath)
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+	dir := c.Dir
+	if dir == "" {
+		dir = invoice.CurrentDir()
+	}
+	due, err := findDue(g, global, dir, invoice.Now())
+	if err != nil {
+		return err
+	}
+	printDue(os.Stdout, due)
+	if !c.Generate {
+		return nil
+	}
+	return generateAllDue(ctx, g, due, os.Stdout)
+}
+
+// findDue returns the invoices due at now for the customers in root: root
+// itself and the directories in it that have a config.LocalFile with, or
+// under, a schedule. An invoice is due once its schedule day has come and
+// there is no HTML or PDF invoice for the period in the customer's
+// directory yet.
+func findDue(g *Globals, global *config.Config, root string, now time.Time) ([]dueInvoice, error) {
+	dirs := []string{root}
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, fmt.Errorf("listing customers: %w", err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			dirs = append(dirs, filepath.Join(root, entry.Name()))
+		}
+	}
+
+	var due []dueInvoice
+	for _, dir := range dirs {
+		path := filepath.Join(dir, config.LocalFile)
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		local, err := g.loadConfig(path)
+		if err != nil {
+			return nil, fmt.Errorf("loading local config: %w", err)
+		}
+		cfg := config.Merge(global, local)
+		if cfg.Schedule == nil {
+			continue
+		}
+		if cfg.Customer == nil || *cfg.Customer == "" {
+			fmt.Fprintf(config.Warnings, "Warning: %s has a schedule but no customer; skipping it\n", path)
+			continue
+		}
+		sched, err := scheduleOf(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		month, year, since := sched.Period(now)
+		files, err := invoice.FindInvoices(dir, year, *cfg.Customer, cfg.VendorNames()...)
+		if err != nil {
+			return nil, err
+		}
+		if slices.ContainsFunc(files, func(f invoice.InvoiceFile) bool { return f.Month == month }) {
+			continue
+		}
+		due = append(due, dueInvoice{Dir: dir, Customer: *cfg.Customer, Month: month, Year: year, Since: since})
+	}
+	return due, nil
+}
+
+// scheduleOf returns the schedule cfg sets, on day 1 in the local time
+// zone unless it says otherwise.
+func scheduleOf(cfg *config.Config) (invoice.Schedule, error) {
+	sched := invoice.Schedule{Day: 1, Location: time.Local}
+	if err := invoice.ValidateSchedule(*cfg.Schedule); err != nil {
+		return sched, err
+	}
+	// The model is not used here, and may have been saved with
+	// --skip-model-check.
+	unchecked := *cfg
+	unchecked.Model = nil
+	if err := unchecked.Validate(); err != nil {
+		return sched, err
+	}
+	if cfg.ScheduleDay != nil {
+		sched.Day = *cfg.ScheduleDay
+	}
+	if cfg.Timezone != nil {
+		loc, err := time.LoadLocation(*cfg.Timezone)
+		if err != nil {
+			return sched, err
+		}
+		sched.Location = loc
+	}
+	return sched, nil
+}
+
+// printDue lists the due invoices on w.
+func printDue(w io.Writer, due []dueInvoice) {
+	if len(due) == 0 {
+		fmt.Fprintln(w, "No invoices are due.")
+		return
+	}
+	fmt.Fprintln(w, "Invoices due:")
+	for _, d := range due {
+		fmt.Fprintf(w, "  %s: %s %d, due since %s (%s)\n", d.Customer, d.Month, d.Year, d.Since.Format(time.DateOnly), d.Dir)
+	}
+}
+
+// generateDue generates the due invoice d in its customer's directory, as
+// `invoicer <month> <year>` run there would. It can be overridden in
+// tests.
+var generateDue = func(ctx context.Context, g *Globals, d dueInvoice) error {
+	wd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("determining current directory: %w", err)
+	}
+	if err := os.Chdir(d.Dir); err != nil {
+		return err
+	}
+	defer os.Chdir(wd)
+	return (&GenerateCmd{Month: strings.ToLower(d.Month.String()), Year: d.Year}).Run(g, ctx)
+}
+
+// generateAllDue generates each of the due invoices, reporting progress on
+// w. A failure does not stop the others; all of them are returned.
+func generateAllDue(ctx context.Context, g *Globals, due []dueInvoice, w io.Writer) error {
+	var errs []error
+	for _, d := range due {
+		if ctx.Err() != nil {
+			errs = append(errs, ctx.Err())
+			break
+		}
+		fmt.Fprintf(w, "\n%s, %s %d:\n", d.Customer, d.Month, d.Year)
+		if err := generateDue(ctx, g, d); err != nil {
+			errs = append(errs, fmt.Errorf("%s, %s %d: %w", d.Customer, d.Month, d.Year, err))
+		}
+	}
+	return errors.Join(errs...)
+}
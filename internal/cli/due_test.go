@@ -0,0 +1,146 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zon/invoicer/internal/config"
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+// writeCustomer creates the directory of a customer under root with the
+// given local config and invoice files.
+func writeCustomer(t *testing.T, root, name, local string, files ...string) string {
+	t.Helper()
+	dir := filepath.Join(root, name)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, config.LocalFile), []byte(local), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		if err := os.WriteFile(filepath.Join(dir, file), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestFindDue(t *testing.T) {
+	root := t.TempDir()
+	acme := writeCustomer(t, root, "acme", "customer: Acme Corp\nschedule: monthly\nschedule_day: 3\n")
+	writeCustomer(t, root, "globex", "customer: Globex\nschedule: monthly\nschedule_day: 3\n", "invoice-globex-2025-01.pdf")
+	writeCustomer(t, root, "initech", "customer: Initech\nschedule: monthly\nschedule_day: 10\n")
+	writeCustomer(t, root, "hooli", "customer: Hooli\n")
+	// 23:30 UTC on February 2 is already February 3 in Tokyo.
+	tokyo := writeCustomer(t, root, "tokyo", "customer: Tokyo Co\nschedule: monthly\nschedule_day: 3\ntimezone: Asia/Tokyo\n")
+	global := &config.Config{Vendor: strPtr("Jane")}
+
+	tests := []struct {
+		name string
+		now  time.Time
+		want []string
+	}{
+		{"before any day", time.Date(2025, time.February, 2, 12, 0, 0, 0, time.UTC), []string{"Acme Corp December 2024", "Globex December 2024", "Initech December 2024", "Tokyo Co December 2024"}},
+		{"day 3 in Tokyo only", time.Date(2025, time.February, 2, 23, 30, 0, 0, time.UTC), []string{"Acme Corp December 2024", "Globex December 2024", "Initech December 2024", "Tokyo Co January 2025"}},
+		{"day 3", time.Date(2025, time.February, 3, 0, 0, 0, 0, time.UTC), []string{"Acme Corp January 2025", "Initech December 2024", "Tokyo Co January 2025"}},
+		{"day 10", time.Date(2025, time.February, 10, 0, 0, 0, 0, time.UTC), []string{"Acme Corp January 2025", "Initech January 2025", "Tokyo Co January 2025"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			due, err := findDue(&Globals{}, global, root, tt.now)
+			if err != nil {
+				t.Fatalf("findDue: %v", err)
+			}
+			var got []string
+			for _, d := range due {
+				got = append(got, d.Customer+" "+d.Month.String()+" "+strconv.Itoa(d.Year))
+			}
+			if strings.Join(got, ", ") != strings.Join(tt.want, ", ") {
+				t.Errorf("due = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	due, err := findDue(&Globals{}, global, root, time.Date(2025, time.February, 3, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if due[0].Dir != acme || due[len(due)-1].Dir != tokyo {
+		t.Errorf("due should name the customers' directories, got %+v", due)
+	}
+}
+
+func TestFindDue_GlobalSchedule(t *testing.T) {
+	root := t.TempDir()
+	writeCustomer(t, root, "acme", "customer: Acme Corp\n")
+	writeCustomer(t, root, "globex", "customer: Globex\nschedule_day: 31\n")
+	// A model saved with set --skip-model-check does not stop due.
+	global := &config.Config{Schedule: strPtr(invoice.ScheduleMonthly), ScheduleDay: intPtr(15), Timezone: strPtr("UTC"), Model: strPtr("in-house")}
+
+	// February has no day 31, so Globex is due on the 28th.
+	due, err := findDue(&Globals{}, global, root, time.Date(2025, time.February, 28, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("findDue: %v", err)
+	}
+	if len(due) != 2 || due[0].Month != time.January || due[1].Month != time.January || due[1].Since.Day() != 28 {
+		t.Errorf("expected both customers due for January, got %+v", due)
+	}
+}
+
+func TestFindDue_InvalidSchedule(t *testing.T) {
+	root := t.TempDir()
+	writeCustomer(t, root, "acme", "customer: Acme Corp\nschedule: weekly\n")
+	_, err := findDue(&Globals{}, &config.Config{}, root, time.Now())
+	if err == nil || !strings.Contains(err.Error(), `unknown schedule "weekly"`) {
+		t.Errorf("expected an unknown schedule error, got %v", err)
+	}
+}
+
+func TestGenerateAllDue(t *testing.T) {
+	var generated []string
+	orig := generateDue
+	t.Cleanup(func() { generateDue = orig })
+	generateDue = func(ctx context.Context, g *Globals, d dueInvoice) error {
+		generated = append(generated, d.Customer)
+		if d.Customer == "Globex" {
+			return errors.New("backend failed")
+		}
+		return nil
+	}
+
+	due := []dueInvoice{
+		{Customer: "Acme Corp", Month: time.January, Year: 2025},
+		{Customer: "Globex", Month: time.January, Year: 2025},
+		{Customer: "Initech", Month: time.January, Year: 2025},
+	}
+	var out bytes.Buffer
+	err := generateAllDue(context.Background(), &Globals{}, due, &out)
+	if strings.Join(generated, ", ") != "Acme Corp, Globex, Initech" {
+		t.Errorf("a failure should not stop the others, generated %v", generated)
+	}
+	if err == nil || !strings.Contains(err.Error(), "Globex, January 2025: backend failed") {
+		t.Errorf("expected Globex's error, got %v", err)
+	}
+}
+
+func TestPrintDue(t *testing.T) {
+	var out bytes.Buffer
+	printDue(&out, nil)
+	if out.String() != "No invoices are due.\n" {
+		t.Errorf("got %q", out.String())
+	}
+	out.Reset()
+	printDue(&out, []dueInvoice{{Dir: "acme", Customer: "Acme Corp", Month: time.January, Year: 2025, Since: time.Date(2025, time.February, 3, 0, 0, 0, 0, time.UTC)}})
+	if want := "Invoices due:\n  Acme Corp: January 2025, due since 2025-02-03 (acme)\n"; out.String() != want {
+		t.Errorf("got %q, want %q", out.String(), want)
+	}
+}
@@ -0,0 +1,184 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/zon/invoicer/internal/config"
+	"github.com/zon/invoicer/internal/fsutil"
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+// EditCmd is the 'edit' subcommand.
+type EditCmd struct {
+	// Month is the month of the invoice. Defaults to previous month.
+	Month string `arg:"" optional:"" predictor:"month" help:"Month of the invoice (e.g. 'january', 'jan', or '1'). Defaults to previous month."`
+
+	// Year is the year of the month. Defaults to the year closest to the given month.
+	Year int `arg:"" optional:"" help:"Year of the month. Defaults to the year closest to the given month."`
+
+	// Customer is the client the invoice is for.
+	Customer string `short:"c" env:"INVOICER_CUSTOMER" predictor:"customer" help:"Name of the client the invoice is for. Defaults to customer: in the config."`
+
+	// Overrides is the overrides file to edit instead of invoice.yaml in
+	// the current directory.
+	Overrides string `type:"path" help:"Edit the overrides file at PATH instead of invoice.yaml in the current directory."`
+
+	// Regenerate regenerates the invoice once the file is saved. Nil if
+	// not given, to ask.
+	Regenerate *bool `negatable:"" help:"Regenerate the invoice once the file is saved without asking (--no-regenerate to only edit). Asks by default."`
+}
+
+// Run executes the 'edit' subcommand.
+func (c *EditCmd) Run(g *Globals, ctx context.Context) error {
+	regenerate := c.Regenerate
+	if regenerate == nil && !stdinIsTerminal() {
+		no := false
+		regenerate = &no
+	}
+	return runEdit(ctx, g, c, regenerate, os.Stdin, os.Stdout)
+}
+
+// runEditor opens path in the user's editor and waits for it to exit. It
+// can be overridden in tests.
+var runEditor = func(ctx context.Context, path string) error {
+	args := strings.Fields(editor())
+	cmd := exec.CommandContext(ctx, args[0], append(args[1:], path)...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("running editor %s: %w", args[0], err)
+	}
+	return nil
+}
+
+// regenerate generates the invoice c describes. It can be overridden in
+// tests.
+var regenerate = func(ctx context.Context, g *Globals, c *GenerateCmd) error {
+	return c.Run(g, ctx)
+}
+
+// defaultEditor is run when neither $VISUAL nor $EDITOR is set.
+const defaultEditor = "vi"
+
+// editor returns the command line of the user's editor: $VISUAL, then
+// $EDITOR, then defaultEditor.
+func editor() string {
+	for _, name := range []string{"VISUAL", "EDITOR"} {
+		if e := strings.TrimSpace(os.Getenv(name)); e != "" {
+			return e
+		}
+	}
+	return defaultEditor
+}
+
+// runEdit opens the overrides file of the invoice c describes in the
+// editor, creating it from a template listing the invoice's weeks if
+// there is none, until it is valid. An invalid file is reported on out
+// and opened again with the edits kept, unless the editor left it as it
+// was, which gives up. Then the invoice is regenerated if regen is set,
+// or if the answer read from in is yes when regen is nil.
+func runEdit(ctx context.Context, g *Globals, c *EditCmd, regen *bool, in io.Reader, out io.Writer) error {
+	gen := &GenerateCmd{Month: c.Month, Year: c.Year, Customer: c.Customer}
+	opts, err := resolveCommandOptions(g, gen)
+	if err != nil {
+		return err
+	}
+	inv, err := opts.buildInvoice()
+	if err != nil {
+		return err
+	}
+
+	path := c.Overrides
+	if path == "" {
+		dir, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("determining current directory: %w", err)
+		}
+		path = filepath.Join(dir, config.OverridesFile)
+	}
+	if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+		if err := fsutil.WriteFileAtomic(path, []byte(overridesTemplate(inv)), 0o644); err != nil {
+			return fmt.Errorf("writing overrides file: %w", err)
+		}
+		fmt.Fprintf(out, "Created %s\n", path)
+	}
+
+	for {
+		before, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("reading overrides file: %w", err)
+		}
+		if err := runEditor(ctx, path); err != nil {
+			return err
+		}
+		err = checkOverrides(opts, gen, path)
+		if err == nil {
+			break
+		}
+		after, readErr := os.ReadFile(path)
+		if readErr == nil && bytes.Equal(before, after) {
+			return fmt.Errorf("%s is unchanged and still not valid: %w", path, err)
+		}
+		fmt.Fprintf(out, "%v\nOpening %s again...\n", err, path)
+	}
+	fmt.Fprintf(out, "Saved %s\n", path)
+
+	if regen == nil {
+		fmt.Fprintf(out, "Regenerate the invoice for %s %d now? [Y/n] ", inv.Month, inv.Year)
+		s := bufio.NewScanner(in)
+		answer := ""
+		if s.Scan() {
+			answer = strings.ToLower(strings.TrimSpace(s.Text()))
+		}
+		yes := answer == "" || answer == "y" || answer == "yes"
+		regen = &yes
+	}
+	if !*regen {
+		fmt.Fprintf(out, "Run 'invoicer %s %d' to regenerate the invoice.\n", strings.ToLower(inv.Month.String()), inv.Year)
+		return nil
+	}
+	gen.Month, gen.Year = strings.ToLower(inv.Month.String()), inv.Year
+	gen.Overrides = path
+	return regenerate(ctx, g, gen)
+}
+
+// checkOverrides checks the overrides file at path against the invoice
+// opts and gen describe.
+func checkOverrides(opts *ResolvedOptions, gen *GenerateCmd, path string) error {
+	over, err := config.LoadOverrides(path)
+	if err != nil {
+		return err
+	}
+	o := *opts
+	gen.applyOverrides(&o, over, path)
+	_, err = o.buildInvoice()
+	return err
+}
+
+// overridesTemplate returns a new overrides file for inv: every key
+// commented out, with the invoice's weeks and their hours.
+func overridesTemplate(inv *invoice.Invoice) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# Overrides for the invoice of %s for %s %d.\n", inv.Customer, inv.Month, inv.Year)
+	sb.WriteString("# Uncomment and change what differs; remove the file once the invoice is sent.\n")
+	sb.WriteString("#\n# Hours of single weeks, by any date in the week:\n# weeks:\n")
+	for _, w := range inv.Weeks {
+		fmt.Fprintf(&sb, "#   %s: %g  # %s\n", w.Start.Format(time.DateOnly), w.Hours, invoice.FormatWeekLabel(w))
+	}
+	sb.WriteString("#\n# Weeks to leave out, by any date in the week:\n# exclude:\n")
+	fmt.Fprintf(&sb, "#   - %s\n", inv.Weeks[len(inv.Weeks)-1].Start.Format(time.DateOnly))
+	sb.WriteString("#\n# notes: Thanks for your business.\n# po_number: PO-1234\n")
+	sb.WriteString("#\n# Flat amounts; negative for a credit:\n# adjustments:\n")
+	sb.WriteString("#   - description: Travel expenses\n#     amount: 120.50\n")
+	return sb.String()
+}
@@ -0,0 +1,180 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zon/invoicer/internal/config"
+)
+
+// fakeEditor sets $EDITOR to a shell script with the given body, run with
+// the file to edit as $1.
+func fakeEditor(t *testing.T, body string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as a fake editor")
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "editor")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+body+"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", path)
+}
+
+// captureRegenerate replaces regenerate with one that records the
+// generate command and the overrides it would read.
+func captureRegenerate(t *testing.T) (*GenerateCmd, *config.Overrides) {
+	t.Helper()
+	var gen GenerateCmd
+	var over config.Overrides
+	orig := regenerate
+	t.Cleanup(func() { regenerate = orig })
+	regenerate = func(ctx context.Context, g *Globals, c *GenerateCmd) error {
+		gen = *c
+		o, _, err := c.loadOverrides()
+		if err != nil {
+			return err
+		}
+		over = *o
+		return nil
+	}
+	return &gen, &over
+}
+
+// editGlobals returns globals with a config holding the options a January
+// 2025 invoice needs, and changes into an empty directory.
+func editGlobals(t *testing.T) *Globals {
+	t.Helper()
+	path := writeTestConfig(t, "vendor: Jane\ncustomer: Acme\nrate: 100\nhours: 40\n")
+	t.Chdir(t.TempDir())
+	return &Globals{Config: path, NoLocal: true}
+}
+
+func TestRunEdit_RegeneratesWithNewHours(t *testing.T) {
+	g := editGlobals(t)
+	fakeEditor(t, `grep -q '2025-01-06: 40' "$1" && printf 'weeks:\n  2025-01-06: 12\n' > "$1"`)
+	gen, over := captureRegenerate(t)
+
+	var out bytes.Buffer
+	err := runEdit(context.Background(), g, &EditCmd{Month: "january", Year: 2025}, nil, strings.NewReader("y\n"), &out)
+	if err != nil {
+		t.Fatalf("runEdit: %v\n%s", err, out.String())
+	}
+	if gen.Month != "january" || gen.Year != 2025 || gen.Overrides != filepath.Join(mustGetwd(t), config.OverridesFile) {
+		t.Errorf("regenerated %+v, want january 2025 with the edited file", gen)
+	}
+	want := time.Date(2025, time.January, 6, 0, 0, 0, 0, time.UTC)
+	if len(over.Weeks) != 1 || !over.Weeks[0].Date.Equal(want) || over.Weeks[0].Hours != 12 {
+		t.Errorf("regenerate should read the new hours, got %+v", over.Weeks)
+	}
+	if !strings.Contains(out.String(), "Created ") || !strings.Contains(out.String(), "Regenerate the invoice for January 2025 now?") {
+		t.Errorf("unexpected output:\n%s", out.String())
+	}
+}
+
+func TestRunEdit_InvalidLoopsBackToEditor(t *testing.T) {
+	g := editGlobals(t)
+	fakeEditor(t, `if [ -e "$0.ran" ]; then
+  printf 'weeks:\n  2025-01-13: 30\n' > "$1"
+else
+  touch "$0.ran"
+  printf 'weeks:\n  2025-02-03: 30\n' > "$1"
+fi`)
+	gen, over := captureRegenerate(t)
+
+	var out bytes.Buffer
+	yes := true
+	if err := runEdit(context.Background(), g, &EditCmd{Month: "january", Year: 2025}, &yes, nil, &out); err != nil {
+		t.Fatalf("runEdit: %v\n%s", err, out.String())
+	}
+	if !strings.Contains(out.String(), "2025-02-03 is in no week of January 2025") || !strings.Contains(out.String(), "Opening ") {
+		t.Errorf("expected the error and the editor opened again, got:\n%s", out.String())
+	}
+	if gen.Month == "" || len(over.Weeks) != 1 || over.Weeks[0].Hours != 30 {
+		t.Errorf("expected regeneration with the fixed file, got %+v, %+v", gen, over.Weeks)
+	}
+}
+
+func TestRunEdit_UnchangedInvalidGivesUp(t *testing.T) {
+	g := editGlobals(t)
+	if err := os.WriteFile(config.OverridesFile, []byte("colour: blue\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	fakeEditor(t, "true")
+	captureRegenerate(t)
+
+	var out bytes.Buffer
+	err := runEdit(context.Background(), g, &EditCmd{Month: "january", Year: 2025}, nil, nil, &out)
+	if err == nil || !strings.Contains(err.Error(), "unchanged and still not valid") {
+		t.Fatalf("expected runEdit to give up, got %v", err)
+	}
+	data, _ := os.ReadFile(config.OverridesFile)
+	if string(data) != "colour: blue\n" {
+		t.Errorf("the file should be kept as it was, got %q", data)
+	}
+}
+
+func TestRunEdit_NoRegenerate(t *testing.T) {
+	g := editGlobals(t)
+	fakeEditor(t, `printf 'notes: Thanks!\n' > "$1"`)
+	gen, _ := captureRegenerate(t)
+
+	var out bytes.Buffer
+	if err := runEdit(context.Background(), g, &EditCmd{Month: "january", Year: 2025}, nil, strings.NewReader("n\n"), &out); err != nil {
+		t.Fatalf("runEdit: %v", err)
+	}
+	if gen.Month != "" {
+		t.Errorf("answering no should not regenerate, got %+v", gen)
+	}
+	if !strings.Contains(out.String(), "Run 'invoicer january 2025' to regenerate") {
+		t.Errorf("expected a hint to regenerate, got:\n%s", out.String())
+	}
+}
+
+func TestOverridesTemplate(t *testing.T) {
+	data := overridesTemplate(testTimeoutInvoice())
+	if !strings.Contains(data, "#   2025-01-06: 40  # Jan 6-12\n") {
+		t.Errorf("template should list the weeks, got:\n%s", data)
+	}
+	path := filepath.Join(t.TempDir(), config.OverridesFile)
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	o, err := config.LoadOverrides(path)
+	if err != nil || o.Weeks != nil || o.Notes != nil {
+		t.Errorf("an untouched template should change nothing, got %+v, %v", o, err)
+	}
+}
+
+func TestEditor(t *testing.T) {
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", "")
+	if got := editor(); got != defaultEditor {
+		t.Errorf("editor() = %q, want %q", got, defaultEditor)
+	}
+	t.Setenv("EDITOR", "nano")
+	if got := editor(); got != "nano" {
+		t.Errorf("editor() = %q, want $EDITOR", got)
+	}
+	t.Setenv("VISUAL", "code --wait")
+	if got := editor(); got != "code --wait" {
+		t.Errorf("editor() = %q, want $VISUAL", got)
+	}
+}
+
+func mustGetwd(t *testing.T) string {
+	t.Helper()
+	dir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
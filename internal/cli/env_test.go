@@ -0,0 +1,102 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/kong"
+)
+
+func TestEnv_OverridesConfig(t *testing.T) {
+	path := writeTestConfig(t, `vendor: Config Vendor
+customer: Config Customer
+rate: 50
+hours: 20
+model: anthropic/claude-haiku-4-5
+`)
+	t.Setenv("INVOICER_VENDOR", "Env Vendor")
+	t.Setenv("INVOICER_RATE", "75.5")
+	t.Setenv("INVOICER_MODEL", "anthropic/claude-sonnet-4-6")
+
+	cmd := parseCLI(t)
+	opts, err := cmd.Generate.resolveOptions(loadTestConfig(t, path), nil)
+	if err != nil {
+		t.Fatalf("resolveOptions: %v", err)
+	}
+	if opts.Vendor != "Env Vendor" {
+		t.Errorf("Vendor: env should override config; got %q", opts.Vendor)
+	}
+	if opts.Rate != 75.5 {
+		t.Errorf("Rate: env should override config; got %v", opts.Rate)
+	}
+	if opts.Model != "anthropic/claude-sonnet-4-6" {
+		t.Errorf("Model: env should override default; got %q", opts.Model)
+	}
+	// Unset variables fall back to config.
+	if opts.Customer != "Config Customer" {
+		t.Errorf("Customer: expected config fallback; got %q", opts.Customer)
+	}
+	if opts.Hours != 20 {
+		t.Errorf("Hours: expected config fallback; got %v", opts.Hours)
+	}
+}
+
+func TestEnv_FlagOverridesEnv(t *testing.T) {
+	t.Setenv("INVOICER_VENDOR", "Env Vendor")
+	t.Setenv("INVOICER_HOURS", "30")
+	t.Setenv("INVOICER_PDF", "true")
+
+	cmd := parseCLI(t, "--vendor", "Flag Vendor", "--hours", "35")
+	if cmd.Generate.Vendor != "Flag Vendor" {
+		t.Errorf("Vendor: flag should override env; got %q", cmd.Generate.Vendor)
+	}
+	if cmd.Generate.Hours == nil || *cmd.Generate.Hours != 35 {
+		t.Errorf("Hours: flag should override env; got %v", cmd.Generate.Hours)
+	}
+	if cmd.Generate.PDF == nil || !*cmd.Generate.PDF {
+		t.Error("PDF: expected true from INVOICER_PDF")
+	}
+}
+
+func TestEnv_StrictExit(t *testing.T) {
+	// --strict-exit is not --strict-config, nor the strict config key.
+	t.Setenv("INVOICER_STRICT_EXIT", "true")
+	cmd := parseCLI(t)
+	if !cmd.Generate.StrictExit || cmd.StrictConfig {
+		t.Errorf("INVOICER_STRICT_EXIT: StrictExit %v, StrictConfig %v; want true, false", cmd.Generate.StrictExit, cmd.StrictConfig)
+	}
+	opts, err := cmd.Generate.resolveOptions(loadTestConfig(t, writeTestConfig(t, "vendor: V\ncustomer: C\nrate: 1\n")), nil)
+	if err != nil {
+		t.Fatalf("resolveOptions: %v", err)
+	}
+	if !opts.StrictExit {
+		t.Error("StrictExit: got false, want true")
+	}
+}
+
+func TestEnv_ParseErrorsNameVariable(t *testing.T) {
+	tests := []struct {
+		env, value string
+	}{
+		{"INVOICER_RATE", "abc"},
+		{"INVOICER_HOURS", "forty"},
+		{"INVOICER_PDF", "maybe"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.env, func(t *testing.T) {
+			t.Setenv(tt.env, tt.value)
+			var cmd CLI
+			p, err := kong.New(&cmd, kong.Name("invoicer"), kong.Exit(func(int) {}))
+			if err != nil {
+				t.Fatalf("kong.New failed: %v", err)
+			}
+			_, err = p.Parse([]string{"january"})
+			if err == nil {
+				t.Fatalf("expected parse error for %s=%q", tt.env, tt.value)
+			}
+			if !strings.Contains(err.Error(), tt.env) {
+				t.Errorf("error %q does not name %s", err, tt.env)
+			}
+		})
+	}
+}
@@ -0,0 +1,320 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/zon/invoicer/internal/fsutil"
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+// stdoutPath is the path that stands for standard output.
+const stdoutPath = "-"
+
+// buildExport is buildInvoice for an export, with the week labels in
+// the export date format.
+func (opts *ResolvedOptions) buildExport() (*invoice.Invoice, error) {
+	inv, err := opts.buildInvoice()
+	if err != nil {
+		return nil, err
+	}
+	inv.DateFormat = opts.ExportDateFormat
+	return inv, nil
+}
+
+// writeInvoiceJSON writes inv's data as indented JSON to path, or to w if
+// path is "-", and otherwise reports where it went on w.
+func writeInvoiceJSON(inv *invoice.Invoice, path string, w io.Writer) error {
+	data, err := json.MarshalIndent(inv, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding invoice: %w", err)
+	}
+	data = append(data, '\n')
+	if path == stdoutPath {
+		_, err := w.Write(data)
+		return err
+	}
+	if err := fsutil.WriteFileAtomic(path, data, 0o644); err != nil {
+		return fmt.Errorf("writing invoice JSON: %w", err)
+	}
+	fmt.Fprintf(w, "Invoice data written to: %s\n", path)
+	return nil
+}
+
+// writeInvoiceUBL writes inv as a UBL e-invoice to path, or to w if path
+// is "-", and otherwise reports where it went on w. The parties' countries
+// and VAT IDs come from opts.
+func writeInvoiceUBL(opts *ResolvedOptions, inv *invoice.Invoice, path string, w io.Writer) error {
+	if opts.VendorCountry == "" {
+		return errors.New("vendor country is required for --export-ubl (use --vendor-country or set vendor_country in config)")
+	}
+	if opts.CustomerCountry == "" {
+		return errors.New("customer country is required for --export-ubl (use --customer-country or set customer_country in config)")
+	}
+	seller := invoice.UBLParty{Country: opts.VendorCountry, VATID: opts.VendorVATID}
+	buyer := invoice.UBLParty{Country: opts.CustomerCountry, VATID: opts.CustomerVATID}
+	var buf bytes.Buffer
+	if err := invoice.WriteUBL(&buf, inv, seller, buyer); err != nil {
+		return fmt.Errorf("writing e-invoice: %w", err)
+	}
+	if path == stdoutPath {
+		_, err := w.Write(buf.Bytes())
+		return err
+	}
+	if err := fsutil.WriteFileAtomic(path, buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("writing e-invoice: %w", err)
+	}
+	fmt.Fprintf(w, "E-invoice written to: %s\n", path)
+	return nil
+}
+
+// ExportCmd is the 'export' subcommand group.
+// Its subcommands write a month's invoice in the import format of an
+// accounting application, without generating it.
+type ExportCmd struct {
+	// QuickBooks writes the invoice for QuickBooks.
+	QuickBooks ExportQuickBooksCmd `cmd:"" name:"quickbooks" help:"Export an invoice as a QuickBooks IIF file or QuickBooks Online CSV import."`
+
+	// Xero writes the invoice for Xero.
+	Xero ExportXeroCmd `cmd:"" name:"xero" help:"Export an invoice as a Xero sales invoice CSV import."`
+
+	// FreshBooks creates the invoice in FreshBooks.
+	FreshBooks ExportFreshBooksCmd `cmd:"" name:"freshbooks" help:"Create an invoice as a draft in FreshBooks."`
+}
+
+// ExportQuickBooksCmd is the 'export quickbooks' subcommand.
+type ExportQuickBooksCmd struct {
+	// Month is the month of the invoice. Defaults to previous month.
+	Month string `arg:"" optional:"" predictor:"month" help:"Month of the invoice (e.g. 'january', 'jan', or '1'). Defaults to previous month."`
+
+	// Year is the year of the month. Defaults to the year closest to the given month.
+	Year int `arg:"" optional:"" help:"Year of the month. Defaults to the year closest to the given month."`
+
+	// Customer is the client the invoice is for.
+	Customer string `short:"c" env:"INVOICER_CUSTOMER" predictor:"customer" help:"Name of the client the invoice is for. Defaults to customer: in the config."`
+
+	// Format is the import format.
+	Format string `enum:"iif,csv" default:"iif" help:"Import format: iif, for QuickBooks Desktop, or csv, for QuickBooks Online (iif or csv)."`
+
+	// Output is the export's path.
+	Output string `short:"o" type:"path" help:"File to write, or - for standard output. Defaults to <invoice>.iif or <invoice>.csv in the current directory."`
+
+	// IncomeAccount is the income account the lines are booked to.
+	IncomeAccount string `name:"income-account" help:"Income account the lines are booked to. Defaults to quickbooks_income_account: in the config, or Services."`
+
+	// Item is the product/service the lines are for.
+	Item string `help:"Product/service the lines are for. Defaults to quickbooks_item: in the config, or Services."`
+}
+
+// Run executes the 'export quickbooks' subcommand.
+func (c *ExportQuickBooksCmd) Run(g *Globals) error {
+	opts, err := resolveExportOptions(g, c.Month, c.Year, c.Customer)
+	if err != nil {
+		return err
+	}
+	return runExportQuickBooks(c, opts, invoice.CurrentDir(), os.Stdout)
+}
+
+// resolveExportOptions resolves the options of an export of the invoice for
+// month and year. The vendor, rate and hours come from the config, as they
+// do for generation.
+func resolveExportOptions(g *Globals, month string, year int, customer string) (*ResolvedOptions, error) {
+	return resolveCommandOptions(g, &GenerateCmd{Month: month, Year: year, Customer: customer})
+}
+
+// resolveCommandOptions resolves the options of a subcommand that works on
+// the invoice c describes, from c and the global and local config.
+func resolveCommandOptions(g *Globals, c *GenerateCmd) (*ResolvedOptions, error) {
+	configPath, err := g.configPath()
+	if err != nil {
+		return nil, err
+	}
+	global, err := g.loadConfig(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("loading config: %w", err)
+	}
+	local, err := g.localConfig()
+	if err != nil {
+		return nil, err
+	}
+	return c.resolveOptions(global, local)
+}
+
+// writeExport writes an accounting export for app to path, or to w if path
+// is "-", and otherwise reports where it went on w.
+func writeExport(app string, data []byte, path string, w io.Writer) error {
+	if path == stdoutPath {
+		_, err := w.Write(data)
+		return err
+	}
+	if err := fsutil.WriteFileAtomic(path, data, 0o644); err != nil {
+		return fmt.Errorf("writing %s export: %w", app, err)
+	}
+	fmt.Fprintf(w, "%s export written to: %s\n", app, path)
+	return nil
+}
+
+// runExportQuickBooks writes the invoice opts describe in c's format to
+// c.Output, or to <invoice>.iif or <invoice>.csv in dir, and reports where
+// it went on w. With c.Output "-" the export itself goes to w.
+func runExportQuickBooks(c *ExportQuickBooksCmd, opts *ResolvedOptions, dir string, w io.Writer) error {
+	inv, err := opts.buildExport()
+	if err != nil {
+		return err
+	}
+	qb := invoice.QuickBooksOptions{IncomeAccount: opts.QuickBooksIncomeAccount, Item: opts.QuickBooksItem}
+	if c.IncomeAccount != "" {
+		qb.IncomeAccount = c.IncomeAccount
+	}
+	if c.Item != "" {
+		qb.Item = c.Item
+	}
+	write := invoice.WriteQuickBooksIIF
+	if c.Format == "csv" {
+		write = invoice.WriteQuickBooksCSV
+	}
+	var buf bytes.Buffer
+	if err := write(&buf, inv, qb); err != nil {
+		return fmt.Errorf("writing QuickBooks export: %w", err)
+	}
+	path := c.Output
+	if path == "" {
+		path = filepath.Join(dir, invoice.OutputFilename(inv)+"."+c.Format)
+	}
+	return writeExport("QuickBooks", buf.Bytes(), path, w)
+}
+
+// ExportXeroCmd is the 'export xero' subcommand.
+type ExportXeroCmd struct {
+	// Month is the month of the invoice. Defaults to previous month.
+	Month string `arg:"" optional:"" predictor:"month" help:"Month of the invoice (e.g. 'january', 'jan', or '1'). Defaults to previous month."`
+
+	// Year is the year of the month. Defaults to the year closest to the given month.
+	Year int `arg:"" optional:"" help:"Year of the month. Defaults to the year closest to the given month."`
+
+	// Customer is the client the invoice is for.
+	Customer string `short:"c" env:"INVOICER_CUSTOMER" predictor:"customer" help:"Name of the client the invoice is for. Defaults to customer: in the config."`
+
+	// Output is the export's path.
+	Output string `short:"o" type:"path" help:"File to write, or - for standard output. Defaults to <invoice>-xero.csv in the current directory."`
+
+	// AccountCode is the revenue account the lines are coded to.
+	AccountCode string `name:"account-code" help:"Revenue account code the lines are coded to. Defaults to xero_account_code: in the config, or 200."`
+
+	// TaxType is the tax rate of the lines.
+	TaxType string `name:"tax-type" help:"Tax rate of the lines. Defaults to xero_tax_type: in the config, or the region's rate for sales without tax."`
+
+	// Region is the region of the Xero organisation.
+	Region string `predictor:"xero_region" help:"Region of the Xero organisation, which decides the date format: US, UK, AU, NZ, or global. Defaults to xero_region: in the config, or US."`
+}
+
+// Run executes the 'export xero' subcommand.
+func (c *ExportXeroCmd) Run(g *Globals) error {
+	opts, err := resolveExportOptions(g, c.Month, c.Year, c.Customer)
+	if err != nil {
+		return err
+	}
+	return runExportXero(c, opts, invoice.CurrentDir(), os.Stdout)
+}
+
+// runExportXero writes the invoice opts describe as a Xero import to
+// c.Output, or to <invoice>-xero.csv in dir, and reports where it went on
+// w. With c.Output "-" the export itself goes to w.
+func runExportXero(c *ExportXeroCmd, opts *ResolvedOptions, dir string, w io.Writer) error {
+	inv, err := opts.buildExport()
+	if err != nil {
+		return err
+	}
+	xo := invoice.XeroOptions{AccountCode: opts.XeroAccountCode, TaxType: opts.XeroTaxType, Region: opts.XeroRegion}
+	for _, f := range []struct {
+		opt  *string
+		flag string
+	}{{&xo.AccountCode, c.AccountCode}, {&xo.TaxType, c.TaxType}, {&xo.Region, c.Region}} {
+		if f.flag != "" {
+			*f.opt = f.flag
+		}
+	}
+	var buf bytes.Buffer
+	if err := invoice.WriteXeroCSV(&buf, inv, xo); err != nil {
+		return fmt.Errorf("writing Xero export: %w", err)
+	}
+	path := c.Output
+	if path == "" {
+		path = filepath.Join(dir, invoice.OutputFilename(inv)+"-xero.csv")
+	}
+	return writeExport("Xero", buf.Bytes(), path, w)
+}
+
+// ExportFreshBooksCmd is the 'export freshbooks' subcommand.
+type ExportFreshBooksCmd struct {
+	// Month is the month of the invoice. Defaults to previous month.
+	Month string `arg:"" optional:"" predictor:"month" help:"Month of the invoice (e.g. 'january', 'jan', or '1'). Defaults to previous month."`
+
+	// Year is the year of the month. Defaults to the year closest to the given month.
+	Year int `arg:"" optional:"" help:"Year of the month. Defaults to the year closest to the given month."`
+
+	// Customer is the client the invoice is for.
+	Customer string `short:"c" env:"INVOICER_CUSTOMER" predictor:"customer" help:"Name of the client the invoice is for. Defaults to customer: in the config."`
+
+	// Token is the OAuth access token.
+	Token string `env:"INVOICER_FRESHBOOKS_TOKEN" help:"FreshBooks OAuth access token. Defaults to freshbooks_token: in the config."`
+
+	// AccountID is the FreshBooks account.
+	AccountID string `name:"account-id" env:"INVOICER_FRESHBOOKS_ACCOUNT_ID" help:"ID of the FreshBooks account. Defaults to freshbooks_account_id: in the config."`
+
+	// ClientID is the FreshBooks client the customer is.
+	ClientID string `name:"client-id" help:"ID of the FreshBooks client to bill. Defaults to freshbooks_client_id: in the config."`
+}
+
+// Run executes the 'export freshbooks' subcommand.
+func (c *ExportFreshBooksCmd) Run(g *Globals) error {
+	opts, err := resolveExportOptions(g, c.Month, c.Year, c.Customer)
+	if err != nil {
+		return err
+	}
+	return runExportFreshBooks(context.Background(), c, opts, os.Stdout)
+}
+
+// newFreshBooksClient returns the client FreshBooks invoices are created
+// with. It can be overridden in tests.
+var newFreshBooksClient = func(token, accountID string) *invoice.FreshBooksClient {
+	return &invoice.FreshBooksClient{Token: token, AccountID: accountID}
+}
+
+// runExportFreshBooks creates the invoice opts describe in FreshBooks, for
+// the client the customer is mapped to, and reports its ID on w.
+func runExportFreshBooks(ctx context.Context, c *ExportFreshBooksCmd, opts *ResolvedOptions, w io.Writer) error {
+	token, accountID, clientID := opts.FreshBooksToken, opts.FreshBooksAccountID, opts.FreshBooksClientID
+	for _, f := range []struct {
+		opt  *string
+		flag string
+	}{{&token, c.Token}, {&accountID, c.AccountID}, {&clientID, c.ClientID}} {
+		if f.flag != "" {
+			*f.opt = f.flag
+		}
+	}
+	switch {
+	case token == "":
+		return errors.New("a FreshBooks token is required (use --token or set freshbooks_token in config)")
+	case accountID == "":
+		return errors.New("a FreshBooks account ID is required (use --account-id or set freshbooks_account_id in config)")
+	case clientID == "":
+		return fmt.Errorf("no FreshBooks client is mapped to customer %q: set freshbooks_client_id: in the .invoicer.yaml of the customer's directory, or use --client-id", opts.Customer)
+	}
+	inv, err := opts.buildExport()
+	if err != nil {
+		return err
+	}
+	created, err := invoice.SendFreshBooks(ctx, newFreshBooksClient(token, accountID), inv, invoice.FreshBooksOptions{ClientID: clientID})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "FreshBooks invoice created: %d (%s, client %s)\n", created.ID, created.Number, clientID)
+	return nil
+}
@@ -0,0 +1,337 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+func TestWriteInvoiceJSON(t *testing.T) {
+	inv := testTimeoutInvoice()
+	path := filepath.Join(t.TempDir(), "invoice-acme-corp-2025-01.json")
+	var out bytes.Buffer
+	if err := writeInvoiceJSON(inv, path, &out); err != nil {
+		t.Fatalf("writeInvoiceJSON: %v", err)
+	}
+	if got, want := out.String(), "Invoice data written to: "+path+"\n"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got invoice.Invoice
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("the file is not an invoice: %v\n%s", err, data)
+	}
+	if got.Total() != inv.Total() || got.Customer != inv.Customer {
+		t.Errorf("read back %+v, want %+v", got, *inv)
+	}
+}
+
+func TestWriteInvoiceJSON_Stdout(t *testing.T) {
+	var out bytes.Buffer
+	if err := writeInvoiceJSON(testTimeoutInvoice(), "-", &out); err != nil {
+		t.Fatalf("writeInvoiceJSON: %v", err)
+	}
+	if !strings.HasPrefix(out.String(), "{\n  \"number\": ") || !strings.HasSuffix(out.String(), "}\n") {
+		t.Errorf("expected indented JSON alone on stdout, got:\n%s", out.String())
+	}
+}
+
+func TestResolveOptions_ExportJSONWithPromptOnly(t *testing.T) {
+	cmd := parseCLI(t, "--export-json", "--prompt-only")
+	_, err := cmd.Generate.resolveOptions(loadTestConfig(t, writeTestConfig(t, "")), nil)
+	if err == nil || !strings.Contains(err.Error(), "--export-json cannot be combined with --prompt-only") {
+		t.Errorf("expected a conflict, got %v", err)
+	}
+}
+
+func TestWriteInvoiceUBL(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "invoice-acme-corp-2025-01.xml")
+	opts := &ResolvedOptions{VendorCountry: "DE", CustomerCountry: "FR", VendorVATID: "DE123456789", CustomerVATID: "FR12345678901"}
+	var out bytes.Buffer
+	if err := writeInvoiceUBL(opts, testTimeoutInvoice(), path, &out); err != nil {
+		t.Fatalf("writeInvoiceUBL: %v", err)
+	}
+	if got, want := out.String(), "E-invoice written to: "+path+"\n"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "<cbc:CompanyID>FR12345678901</cbc:CompanyID>") {
+		t.Errorf("the customer's VAT ID is missing:\n%s", data)
+	}
+}
+
+func TestWriteInvoiceUBL_NeedsCountries(t *testing.T) {
+	tests := []struct {
+		opts *ResolvedOptions
+		want string
+	}{
+		{&ResolvedOptions{CustomerCountry: "FR"}, "vendor_country"},
+		{&ResolvedOptions{VendorCountry: "DE"}, "customer_country"},
+		{&ResolvedOptions{VendorCountry: "DE", CustomerCountry: "FR", VendorVATID: "DE123456789"}, "buyer's VAT ID"},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "invoice.xml")
+		err := writeInvoiceUBL(tt.opts, testTimeoutInvoice(), path, io.Discard)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("expected error about %s, got %v", tt.want, err)
+		}
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Error("no e-invoice should be written")
+		}
+	}
+}
+
+func TestResolveOptions_ExportConflicts(t *testing.T) {
+	cmd := parseCLI(t, "--export-json", "--export-ubl")
+	_, err := cmd.Generate.resolveOptions(loadTestConfig(t, writeTestConfig(t, "")), nil)
+	if err == nil || !strings.Contains(err.Error(), "--export-ubl cannot be combined with --export-json") {
+		t.Errorf("expected a conflict, got %v", err)
+	}
+}
+
+func TestResolveOptions_UBLParties(t *testing.T) {
+	path := writeTestConfig(t, "vendor_country: DE\nvendor_vat_id: DE123456789\ncustomer_country: FR\n")
+	cmd := parseCLI(t, "--customer-country", "BE", "--customer-vat-id", "BE0123456789")
+	opts, err := cmd.Generate.resolveOptions(loadTestConfig(t, path), nil)
+	if err != nil {
+		t.Fatalf("resolveOptions: %v", err)
+	}
+	if opts.VendorCountry != "DE" || opts.VendorVATID != "DE123456789" || opts.CustomerCountry != "BE" || opts.CustomerVATID != "BE0123456789" {
+		t.Errorf("parties = %q %q %q %q", opts.VendorCountry, opts.VendorVATID, opts.CustomerCountry, opts.CustomerVATID)
+	}
+}
+
+func TestRunExportQuickBooks(t *testing.T) {
+	config := "vendor: Jane\ncustomer: Acme Corp\nrate: 100\nhours: 40\nquickbooks_income_account: Consulting Income\n"
+	tests := []struct {
+		name     string
+		args     []string
+		file     string
+		contains []string
+	}{
+		{"iif", []string{"export", "quickbooks", "january", "2025"}, "invoice-acme-corp-2025-01.iif",
+			[]string{"\tConsulting Income\tAcme Corp\t-2400.00\t", "\tServices\r\n"}},
+		{"csv with item", []string{"export", "quickbooks", "january", "2025", "--format=csv", "--item", "Development"}, "invoice-acme-corp-2025-01.csv",
+			[]string{"InvoiceNo,Customer,", "INV-202501-acme-corp,Acme Corp,"}},
+		{"flags override config", []string{"export", "quickbooks", "jan", "2025", "--income-account=Sales", "-c", "Globex"}, "invoice-globex-2025-01.iif",
+			[]string{"\tSales\tGlobex\t"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := parseCLI(t, tt.args...)
+			c := &cmd.Export.QuickBooks
+			opts, err := (&GenerateCmd{Month: c.Month, Year: c.Year, Customer: c.Customer}).resolveOptions(loadTestConfig(t, writeTestConfig(t, config)), nil)
+			if err != nil {
+				t.Fatalf("resolveOptions: %v", err)
+			}
+			dir := t.TempDir()
+			var out bytes.Buffer
+			if err := runExportQuickBooks(c, opts, dir, &out); err != nil {
+				t.Fatalf("runExportQuickBooks: %v", err)
+			}
+			path := filepath.Join(dir, tt.file)
+			if got, want := out.String(), "QuickBooks export written to: "+path+"\n"; got != want {
+				t.Errorf("output = %q, want %q", got, want)
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range tt.contains {
+				if !strings.Contains(string(data), want) {
+					t.Errorf("expected %q in the export:\n%s", want, data)
+				}
+			}
+		})
+	}
+}
+
+func TestRunExportQuickBooks_Stdout(t *testing.T) {
+	cmd := parseCLI(t, "export", "quickbooks", "january", "2025", "-o", "-")
+	opts := &ResolvedOptions{Month: "january", Year: 2025, Vendor: "Jane", Customer: "Acme Corp", Rate: 100, Hours: 40}
+	var out bytes.Buffer
+	if err := runExportQuickBooks(&cmd.Export.QuickBooks, opts, t.TempDir(), &out); err != nil {
+		t.Fatalf("runExportQuickBooks: %v", err)
+	}
+	if !strings.HasPrefix(out.String(), "!TRNS\t") || !strings.HasSuffix(out.String(), "ENDTRNS\r\n") {
+		t.Errorf("expected the IIF file alone on stdout, got:\n%s", out.String())
+	}
+}
+
+func TestRunExportQuickBooks_NeedsRate(t *testing.T) {
+	cmd := parseCLI(t, "export", "quickbooks")
+	opts := &ResolvedOptions{Vendor: "Jane", Customer: "Acme Corp", Hours: 40}
+	err := runExportQuickBooks(&cmd.Export.QuickBooks, opts, t.TempDir(), io.Discard)
+	if err == nil || !strings.Contains(err.Error(), "rate is required") {
+		t.Errorf("expected a missing rate error, got %v", err)
+	}
+}
+
+func TestRunExportXero(t *testing.T) {
+	config := "vendor: Jane\ncustomer: Acme Corp\nrate: 100\nhours: 40\nxero_region: UK\nxero_account_code: \"4000\"\n"
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"config", []string{"export", "xero", "january", "2025"}, "Acme Corp,INV-202501-acme-corp,15/02/2025,15/02/2025,Services Jan 1-5,24,100.00,4000,No VAT\n"},
+		{"flags override config", []string{"export", "xero", "january", "2025", "--region=US", "--account-code=200", "--tax-type", "Exempt"}, "Acme Corp,INV-202501-acme-corp,02/15/2025,02/15/2025,Services Jan 1-5,24,100.00,200,Exempt\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldNow := invoice.Now
+			invoice.Now = func() time.Time { return time.Date(2025, time.February, 15, 9, 0, 0, 0, time.UTC) }
+			t.Cleanup(func() { invoice.Now = oldNow })
+
+			cmd := parseCLI(t, tt.args...)
+			c := &cmd.Export.Xero
+			opts, err := (&GenerateCmd{Month: c.Month, Year: c.Year, Customer: c.Customer}).resolveOptions(loadTestConfig(t, writeTestConfig(t, config)), nil)
+			if err != nil {
+				t.Fatalf("resolveOptions: %v", err)
+			}
+			dir := t.TempDir()
+			var out bytes.Buffer
+			if err := runExportXero(c, opts, dir, &out); err != nil {
+				t.Fatalf("runExportXero: %v", err)
+			}
+			path := filepath.Join(dir, "invoice-acme-corp-2025-01-xero.csv")
+			if got, want := out.String(), "Xero export written to: "+path+"\n"; got != want {
+				t.Errorf("output = %q, want %q", got, want)
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(data), tt.want) {
+				t.Errorf("expected %q in the export:\n%s", tt.want, data)
+			}
+		})
+	}
+}
+
+func TestRunExportXero_BadRegion(t *testing.T) {
+	cmd := parseCLI(t, "export", "xero", "--region", "EU", "-o", "-")
+	opts := &ResolvedOptions{Month: "january", Year: 2025, Vendor: "Jane", Customer: "Acme Corp", Rate: 100, Hours: 40}
+	err := runExportXero(&cmd.Export.Xero, opts, t.TempDir(), io.Discard)
+	if err == nil || !strings.Contains(err.Error(), `Xero region "EU"`) {
+		t.Errorf("expected a region error, got %v", err)
+	}
+}
+
+func TestRunExportFreshBooks(t *testing.T) {
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		if len(paths) == 1 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		fmt.Fprint(w, `{"response":{"result":{"invoice":{"id":730105,"invoice_number":"INV-202501-acme-corp","v3_status":"draft"}}}}`)
+	}))
+	t.Cleanup(srv.Close)
+	old := newFreshBooksClient
+	newFreshBooksClient = func(token, accountID string) *invoice.FreshBooksClient {
+		return &invoice.FreshBooksClient{Token: token, AccountID: accountID, BaseURL: srv.URL}
+	}
+	t.Cleanup(func() { newFreshBooksClient = old })
+	oldSleep := invoice.Sleep
+	invoice.Sleep = func(context.Context, time.Duration) error { return nil }
+	t.Cleanup(func() { invoice.Sleep = oldSleep })
+
+	cmd := parseCLI(t, "export", "freshbooks", "january", "2025", "--client-id=81224")
+	opts := &ResolvedOptions{Month: "january", Year: 2025, Vendor: "Jane", Customer: "Acme Corp", Rate: 100, Hours: 40, FreshBooksToken: "fb-token", FreshBooksAccountID: "zDmNq"}
+	var out bytes.Buffer
+	if err := runExportFreshBooks(context.Background(), &cmd.Export.FreshBooks, opts, &out); err != nil {
+		t.Fatalf("runExportFreshBooks: %v", err)
+	}
+	if want := "FreshBooks invoice created: 730105 (INV-202501-acme-corp, client 81224)\n"; out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+	want := "/accounting/account/zDmNq/invoices/invoices"
+	if len(paths) != 2 || paths[1] != want {
+		t.Errorf("requests = %v, want a rate limited one and a retry to %s", paths, want)
+	}
+}
+
+func TestRunExportFreshBooks_MissingSettings(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    ResolvedOptions
+		wantErr string
+	}{
+		{"no token", ResolvedOptions{FreshBooksAccountID: "zDmNq", FreshBooksClientID: "81224"}, "set freshbooks_token in config"},
+		{"no account", ResolvedOptions{FreshBooksToken: "fb-token", FreshBooksClientID: "81224"}, "set freshbooks_account_id in config"},
+		{"no client mapping", ResolvedOptions{FreshBooksToken: "fb-token", FreshBooksAccountID: "zDmNq"}, `no FreshBooks client is mapped to customer "Acme Corp": set freshbooks_client_id:`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := tt.opts
+			opts.Month, opts.Year, opts.Vendor, opts.Customer, opts.Rate, opts.Hours = "january", 2025, "Jane", "Acme Corp", 100, 40
+			cmd := parseCLI(t, "export", "freshbooks")
+			err := runExportFreshBooks(context.Background(), &cmd.Export.FreshBooks, &opts, io.Discard)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestResolveOptions_DateFormats(t *testing.T) {
+	tests := []struct {
+		name                  string
+		config                string
+		wantLabel, wantExport string
+	}{
+		{"default", "", "Jan 6-12", "Jan 6-12"},
+		{"exports follow the invoice", "date_format: eu\n", "06.01. - 12.01.", "06.01. - 12.01."},
+		{"ISO exports", "date_format: eu\nexport_date_format: iso\n", "06.01. - 12.01.", "2025-01-06/2025-01-12"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := loadTestConfig(t, writeTestConfig(t, "vendor: Jane\ncustomer: Acme Corp\nrate: 100\nhours: 40\n"+tt.config))
+			opts, err := (&GenerateCmd{Month: "january", Year: 2025}).resolveOptions(cfg, nil)
+			if err != nil {
+				t.Fatalf("resolveOptions: %v", err)
+			}
+			inv, err := opts.buildInvoice()
+			if err != nil {
+				t.Fatalf("buildInvoice: %v", err)
+			}
+			if got := inv.LineItems()[1].Description; got != tt.wantLabel {
+				t.Errorf("invoice label = %q, want %q", got, tt.wantLabel)
+			}
+			exp, err := opts.buildExport()
+			if err != nil {
+				t.Fatalf("buildExport: %v", err)
+			}
+			if got := exp.LineItems()[1].Description; got != tt.wantExport {
+				t.Errorf("export label = %q, want %q", got, tt.wantExport)
+			}
+		})
+	}
+}
+
+func TestResolveOptions_BadDateFormat(t *testing.T) {
+	cfg := loadTestConfig(t, writeTestConfig(t, "export_date_format: german\n"))
+	_, err := (&GenerateCmd{}).resolveOptions(cfg, nil)
+	if err == nil || !strings.Contains(err.Error(), `unknown date format "german"`) {
+		t.Errorf("expected an unknown date format error, got %v", err)
+	}
+}
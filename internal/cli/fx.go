@@ -0,0 +1,31 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+// newFXClient returns the client exchange rates are fetched with. It can
+// be overridden in tests.
+var newFXClient = func(opts *ResolvedOptions) *invoice.FXClient {
+	return &invoice.FXClient{Provider: opts.FXProvider, APIKey: opts.FXAPIKey}
+}
+
+// exchangeRate returns the rate inv is billed at in opts.BillCurrency:
+// opts.FXRate if it is given, or else the rate of the last day of the
+// invoiced month from opts.FXProvider. A rate that cannot be fetched is an
+// error, rather than a guess.
+func exchangeRate(ctx context.Context, opts *ResolvedOptions, inv *invoice.Invoice) (*invoice.ExchangeRate, error) {
+	end := time.Date(inv.Year, inv.Month+1, 0, 0, 0, 0, 0, time.UTC)
+	if opts.FXRate > 0 {
+		return &invoice.ExchangeRate{Currency: opts.BillCurrency, Rate: opts.FXRate, Date: end, Source: invoice.FXManual}, nil
+	}
+	x, err := newFXClient(opts).Rate(ctx, opts.BillCurrency, end)
+	if err != nil {
+		return nil, fmt.Errorf("%w (use --fx-rate to give the rate yourself)", err)
+	}
+	return x, nil
+}
@@ -0,0 +1,87 @@
+package cli
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zon/invoicer/internal/config"
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+func TestResolveOptions_BillCurrency(t *testing.T) {
+	cmd := parseCLI(t, "--bill-currency=eur", "--fx-rate=0.93")
+	opts, err := cmd.Generate.resolveOptions(&config.Config{}, nil)
+	if err != nil {
+		t.Fatalf("resolveOptions: %v", err)
+	}
+	if opts.BillCurrency != "EUR" || opts.FXRate != 0.93 || opts.FXProvider != invoice.DefaultFXProvider {
+		t.Errorf("BillCurrency, FXRate, FXProvider = %q, %v, %q", opts.BillCurrency, opts.FXRate, opts.FXProvider)
+	}
+
+	// The config's currency is upper-cased like the flag's.
+	cfg := &config.Config{BillCurrency: strPtr("gbp")}
+	if opts, err = parseCLI(t).Generate.resolveOptions(cfg, nil); err != nil || opts.BillCurrency != "GBP" {
+		t.Errorf("bill_currency: gbp: BillCurrency = %q, %v; want GBP", opts.BillCurrency, err)
+	}
+
+	// Billing in dollars needs no rate.
+	cmd = parseCLI(t, "--bill-currency=USD")
+	if opts, err = cmd.Generate.resolveOptions(&config.Config{}, nil); err != nil || opts.BillCurrency != "" {
+		t.Errorf("BillCurrency = %q, %v; want none for USD", opts.BillCurrency, err)
+	}
+
+	for _, tt := range []struct{ args []string }{
+		{[]string{"--bill-currency=euro"}},
+		{[]string{"--bill-currency=EUR", "--fx-rate=0"}},
+	} {
+		if _, err := parseCLI(t, tt.args...).Generate.resolveOptions(&config.Config{}, nil); err == nil {
+			t.Errorf("%v: expected an error", tt.args)
+		}
+	}
+}
+
+func TestExchangeRate(t *testing.T) {
+	inv := &invoice.Invoice{Month: time.February, Year: 2025}
+	end := time.Date(2025, time.February, 28, 0, 0, 0, 0, time.UTC)
+
+	// A rate given by hand is used as it is, dated the end of the month.
+	x, err := exchangeRate(context.Background(), &ResolvedOptions{BillCurrency: "EUR", FXRate: 0.93}, inv)
+	if err != nil {
+		t.Fatalf("exchangeRate: %v", err)
+	}
+	want := invoice.ExchangeRate{Currency: "EUR", Rate: 0.93, Date: end, Source: invoice.FXManual}
+	if *x != want {
+		t.Errorf("exchangeRate = %+v, want %+v", x, want)
+	}
+
+	// Otherwise the rate of the last day of the month is fetched.
+	var asked string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		asked = r.URL.Query().Get("date")
+		w.Write([]byte(`{"success":true,"date":"2025-02-28","quotes":{"USDGBP":0.79}}`))
+	}))
+	t.Cleanup(srv.Close)
+	old := newFXClient
+	t.Cleanup(func() { newFXClient = old })
+	newFXClient = func(opts *ResolvedOptions) *invoice.FXClient {
+		return &invoice.FXClient{Provider: opts.FXProvider, APIKey: opts.FXAPIKey, BaseURL: srv.URL}
+	}
+	opts := &ResolvedOptions{BillCurrency: "GBP", FXProvider: invoice.FXProviderExchangeRateHost, FXAPIKey: "key"}
+	if x, err = exchangeRate(context.Background(), opts, inv); err != nil {
+		t.Fatalf("exchangeRate: %v", err)
+	}
+	if asked != "2025-02-28" || x.Rate != 0.79 || x.Source != invoice.FXProviderExchangeRateHost {
+		t.Errorf("fetched %+v for %s, want 0.79 for 2025-02-28", x, asked)
+	}
+
+	// A rate that cannot be fetched needs --fx-rate.
+	srv.Close()
+	_, err = exchangeRate(context.Background(), opts, inv)
+	if err == nil || !strings.Contains(err.Error(), "use --fx-rate to give the rate yourself") {
+		t.Errorf("expected a fetch error pointing at --fx-rate, got %v", err)
+	}
+}
@@ -0,0 +1,72 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+func failingHook(t *testing.T) {
+	t.Helper()
+	origExec := invoice.HookExec
+	t.Cleanup(func() { invoice.HookExec = origExec })
+	invoice.HookExec = func(command string, env []string) error {
+		return errors.New("exit status 3")
+	}
+}
+
+func hookInvoice() *invoice.Invoice {
+	return &invoice.Invoice{Month: time.January, Year: 2025, Customer: "Acme Corp", Rate: 100}
+}
+
+func TestRunPostGenerateHook_WarnsByDefault(t *testing.T) {
+	failingHook(t)
+	var stderr bytes.Buffer
+	opts := &ResolvedOptions{Hook: "./publish.sh"}
+	if err := runPostGenerateHook(opts, hookInvoice(), "/tmp/a.html", "", "", &stderr); err != nil {
+		t.Fatalf("expected warning only, got error: %v", err)
+	}
+	if !strings.Contains(stderr.String(), "Warning") {
+		t.Errorf("expected warning on stderr, got %q", stderr.String())
+	}
+}
+
+func TestRunPostGenerateHook_StrictFails(t *testing.T) {
+	failingHook(t)
+	var stderr bytes.Buffer
+	opts := &ResolvedOptions{Hook: "./publish.sh", HookStrict: true}
+	if err := runPostGenerateHook(opts, hookInvoice(), "/tmp/a.html", "", "", &stderr); err == nil {
+		t.Fatal("expected error in strict mode, got nil")
+	}
+	if stderr.Len() != 0 {
+		t.Errorf("expected no warning in strict mode, got %q", stderr.String())
+	}
+}
+
+func TestRunPostGenerateHook_NoHook(t *testing.T) {
+	failingHook(t)
+	var stderr bytes.Buffer
+	if err := runPostGenerateHook(&ResolvedOptions{}, hookInvoice(), "/tmp/a.html", "", "", &stderr); err != nil {
+		t.Fatalf("expected no error without a hook, got: %v", err)
+	}
+}
+
+func TestResolveOptions_HookFromConfig(t *testing.T) {
+	path := writeTestConfig(t, `post_generate_hook: ./publish.sh
+hook_strict: true
+`)
+	opts, err := (&GenerateCmd{}).resolveOptions(loadTestConfig(t, path), nil)
+	if err != nil {
+		t.Fatalf("resolveOptions: %v", err)
+	}
+	if opts.Hook != "./publish.sh" {
+		t.Errorf("Hook: got %q, want %q", opts.Hook, "./publish.sh")
+	}
+	if !opts.HookStrict {
+		t.Error("HookStrict: got false, want true")
+	}
+}
@@ -0,0 +1,81 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/zon/invoicer/internal/fsutil"
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+// ImportCmd is the 'import' subcommand group.
+type ImportCmd struct {
+	// Hours converts a time tracker's export into a timesheet.
+	Hours ImportHoursCmd `cmd:"" name:"hours" help:"Convert a time tracker's export (Toggl, Clockify, Harvest or date,hours CSV) into a timesheet of the hours worked each day."`
+}
+
+// ImportHoursCmd is the 'import hours' subcommand.
+type ImportHoursCmd struct {
+	// File is the export to read, or "-" for standard input.
+	File string `arg:"" help:"Export to read, or - for standard input."`
+
+	// Output is the timesheet's path.
+	Output string `short:"o" type:"path" help:"Timesheet to write, or - for standard output (the default)."`
+}
+
+// Run executes the 'import hours' subcommand.
+func (c *ImportHoursCmd) Run() error {
+	return runImportHours(c, os.Stdin, os.Stdout, os.Stderr)
+}
+
+// runImportHours reads the export of c.File from stdin if it is "-", and
+// writes its hours as a timesheet to c.Output or stdout. What was read is
+// reported on status.
+func runImportHours(c *ImportHoursCmd, stdin io.Reader, stdout, status io.Writer) error {
+	entries, format, err := readTimesheetFormat(c.File, stdin)
+	if err != nil {
+		return err
+	}
+	data := timesheetCSV(entries)
+	var total float64
+	for _, e := range entries {
+		total += e.Hours
+	}
+	fmt.Fprintf(status, "Read %d %s entries: %s hours\n", len(entries), format, invoice.FormatHours(total))
+	if c.Output == "" || c.Output == stdoutPath {
+		_, err := stdout.Write(data)
+		return err
+	}
+	if err := fsutil.WriteFileAtomic(c.Output, data, 0o644); err != nil {
+		return fmt.Errorf("writing the timesheet: %w", err)
+	}
+	fmt.Fprintf(status, "Timesheet written to: %s\n", c.Output)
+	return nil
+}
+
+// timesheetCSV returns entries as a CSV timesheet with the hours of each
+// day on one line, in order of date. Hours are rounded to four decimals,
+// so adding up a tracker's durations does not leave float noise.
+func timesheetCSV(entries []invoice.TimesheetEntry) []byte {
+	days := map[time.Time]float64{}
+	for _, e := range entries {
+		days[e.Date] += e.Hours
+	}
+	dates := make([]time.Time, 0, len(days))
+	for d := range days {
+		dates = append(dates, d)
+	}
+	slices.SortFunc(dates, time.Time.Compare)
+	var buf bytes.Buffer
+	buf.WriteString("date,hours\n")
+	for _, d := range dates {
+		fmt.Fprintf(&buf, "%s,%s\n", d.Format(time.DateOnly), strconv.FormatFloat(math.Round(days[d]*10000)/10000, 'f', -1, 64))
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,52 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const togglExport = "\uFEFFUser,Email,Client,Project,Task,Description,Billable,Start date,Start time,End date,End time,Duration,Tags,Amount (USD)\n" +
+	"Jane Doe,jane@example.com,Acme Corp,Website,,Build,Yes,2025-01-07,09:00:00,2025-01-07,16:30:00,07:30:00,,1125.00\n" +
+	"Jane Doe,jane@example.com,Acme Corp,Website,,Planning,Yes,2025-01-06,09:00:00,2025-01-06,17:00:00,08:00:00,,1200.00\n" +
+	"Jane Doe,jane@example.com,Acme Corp,Website,,Review,Yes,2025-01-07,17:00:00,2025-01-07,17:20:00,00:20:00,,50.00\n"
+
+func TestImportHours(t *testing.T) {
+	var stdout, status bytes.Buffer
+	if err := runImportHours(&ImportHoursCmd{File: "-"}, strings.NewReader(togglExport), &stdout, &status); err != nil {
+		t.Fatalf("import hours: %v", err)
+	}
+	want := "date,hours\n2025-01-06,8\n2025-01-07,7.8333\n"
+	if stdout.String() != want {
+		t.Errorf("timesheet = %q, want %q", stdout.String(), want)
+	}
+	if !strings.Contains(status.String(), "Read 3 Toggl entries") {
+		t.Errorf("status = %q, want the format and the number of entries", status.String())
+	}
+
+	// The timesheet written reads back as the same hours.
+	path := filepath.Join(t.TempDir(), "hours.csv")
+	if err := runImportHours(&ImportHoursCmd{File: "-", Output: path}, strings.NewReader(togglExport), &stdout, &status); err != nil {
+		t.Fatalf("import hours -o: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != want {
+		t.Errorf("file = %q, want %q", data, want)
+	}
+	entries, err := readTimesheet(path, nil)
+	if err != nil || len(entries) != 2 {
+		t.Errorf("reading the timesheet back = %v, %v", entries, err)
+	}
+}
+
+func TestImportHours_UnknownFormat(t *testing.T) {
+	err := runImportHours(&ImportHoursCmd{File: "-"}, strings.NewReader("Day,Worked\nMonday,8\n"), &bytes.Buffer{}, &bytes.Buffer{})
+	if err == nil || !strings.Contains(err.Error(), "unknown timesheet header") || !strings.Contains(err.Error(), "Harvest") {
+		t.Errorf("expected an unknown header error listing the formats, got %v", err)
+	}
+}
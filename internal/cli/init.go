@@ -0,0 +1,167 @@
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/zon/invoicer/internal/config"
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+// InitCmd is the 'init' subcommand.
+type InitCmd struct {
+	// From is an old invoice to take the answers from.
+	From string `type:"existingfile" help:"Take the answers from an old invoice: an HTML page, or an invoice's JSON or YAML."`
+}
+
+// Run executes the 'init' subcommand.
+func (c *InitCmd) Run(g *Globals) error {
+	path, err := g.writePath()
+	if err != nil {
+		return err
+	}
+	return RunInit(path, c.From, os.Stdin, os.Stdout)
+}
+
+// initKey is a config key 'init' asks for.
+type initKey struct {
+	key string
+	// required keys must have a value for a new config.
+	required bool
+}
+
+// initKeys are the keys 'init' asks for, in order: what every invoice
+// needs, then how it is generated.
+var initKeys = []initKey{
+	{"vendor", true},
+	{"customer", true},
+	{"rate", true},
+	{"hours", true},
+	{"backend", false},
+	{"model", false},
+	{"pdf", false},
+}
+
+// errInitCancelled is returned by RunInit when the input ends before a new
+// config has every required key.
+var errInitCancelled = errors.New("setup cancelled; nothing was written")
+
+// RunInit sets up the config file at path, or the default path if path is
+// empty, by asking for the keys in initKeys on out and reading the answers
+// from in. Each answer is checked as 'set config' checks a flag and asked
+// again if it is not valid. An existing config is only updated, if the
+// user agrees, with its values as the answers a blank line keeps, and its
+// other keys left as they are. Once saved, RunInit offers a plain-text
+// preview of last month's invoice, made without a backend.
+// If from is not empty, the vendor, customer, rate and bank account found
+// in the old invoice at from are offered as the answers first.
+// This function is exported for testability.
+func RunInit(path, from string, in io.Reader, out io.Writer) error {
+	if path == "" {
+		var err error
+		path, err = config.DefaultPath()
+		if err != nil {
+			return fmt.Errorf("determining config path: %w", err)
+		}
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+	_, statErr := os.Stat(path)
+	exists := statErr == nil
+
+	s := bufio.NewScanner(in)
+	if exists {
+		fmt.Fprintf(out, "%s already exists. Update it? [Y/n] ", path)
+		if !askYes(s) {
+			fmt.Fprintf(out, "Nothing changed. Use 'invoicer set config' to change single keys.\n")
+			return nil
+		}
+		fmt.Fprintf(out, "Press Enter to keep a value, or enter %s to clear it.\n\n", clearValue)
+	} else {
+		fmt.Fprintf(out, "Setting up invoicer. Your answers are saved to %s.\n\n", path)
+	}
+
+	edited := *cfg
+	if from != "" {
+		if err := importOldInvoice(&edited, from, s, out); err != nil {
+			return err
+		}
+	}
+	var changes []configChange
+ask:
+	for _, k := range initKeys {
+		key := k.key
+		if key == "model" && edited.Backend != nil && *edited.Backend == "ollama" {
+			key = "ollama_model"
+		}
+		for {
+			ch, ok, err := promptKey(&edited, cfg, key, configDefaults[key], s, out)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				if !exists {
+					return errInitCancelled
+				}
+				break ask
+			}
+			if k.required && reflect.ValueOf(edited).Field(configField(key)).IsNil() {
+				fmt.Fprintf(out, "  %s is required\n", key)
+				continue
+			}
+			if ch != nil {
+				changes = append(changes, *ch)
+			}
+			break
+		}
+	}
+	changes = append(changes, importedChanges(&edited, cfg)...)
+
+	if len(changes) == 0 {
+		fmt.Fprintf(out, "\nNo changes; %s was not written.\n", path)
+	} else if err := saveChanges(path, &edited, changes, out); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "\nPreview last month's invoice as plain text? [Y/n] ")
+	if !askYes(s) {
+		fmt.Fprintln(out, "Run 'invoicer' to generate last month's invoice.")
+		return nil
+	}
+	return previewInvoice(&edited, out)
+}
+
+// askYes reads a yes or no answer from s; a blank answer, or none, is yes.
+func askYes(s *bufio.Scanner) bool {
+	answer := ""
+	if s.Scan() {
+		answer = strings.ToLower(strings.TrimSpace(s.Text()))
+	}
+	return answer == "" || answer == "y" || answer == "yes"
+}
+
+// previewInvoice writes last month's invoice for cfg to out as a
+// plain-text invoice, which needs no backend.
+func previewInvoice(cfg *config.Config, out io.Writer) error {
+	opts, err := (&GenerateCmd{}).resolveOptions(cfg, nil)
+	if err != nil {
+		return err
+	}
+	inv, err := opts.buildInvoice()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(out)
+	if err := invoice.WriteText(out, inv); err != nil {
+		return fmt.Errorf("writing the preview: %w", err)
+	}
+	fmt.Fprintf(out, "\nRun 'invoicer' to generate it with the %s backend.\n", opts.Backend)
+	return nil
+}
@@ -0,0 +1,205 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+func TestRunInit_Fresh(t *testing.T) {
+	oldNow := invoice.Now
+	invoice.Now = func() time.Time { return time.Date(2025, time.February, 3, 9, 0, 0, 0, time.UTC) }
+	t.Cleanup(func() { invoice.Now = oldNow })
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	in := strings.Join([]string{
+		"Jane Contractor",
+		"Acme Corp",
+		"abc", // not a number: asked again
+		"150",
+		"", // required: asked again
+		"40",
+		"nope", // unknown backend: asked again
+		"",     // backend: keep the default
+		"",     // model: keep the default
+		"yes",  // pdf
+		"",     // preview
+	}, "\n") + "\n"
+	var out bytes.Buffer
+	if err := RunInit(path, "", strings.NewReader(in), &out); err != nil {
+		t.Fatalf("RunInit: %v\n%s", err, out.String())
+	}
+
+	cfg := loadTestConfig(t, path)
+	if *cfg.Vendor != "Jane Contractor" || *cfg.Customer != "Acme Corp" || *cfg.Rate != 150 || *cfg.Hours != 40 || !*cfg.PDF {
+		t.Errorf("config = %+v", cfg)
+	}
+	if cfg.Backend != nil || cfg.Model != nil {
+		t.Errorf("defaults kept with a blank answer should not be written, got backend %v model %v", cfg.Backend, cfg.Model)
+	}
+	for _, want := range []string{
+		"  rate is not valid:",
+		"  hours is required\n",
+		"  backend is not valid:",
+		"backend [opencode]: ",
+		"Saved " + path + ":\n",
+		"Acme Corp",
+		"January 2025",
+		"Run 'invoicer' to generate it with the opencode backend.",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output is missing %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestRunInit_FreshCancelled(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	err := RunInit(path, "", strings.NewReader("Jane Contractor\n"), &bytes.Buffer{})
+	if !errors.Is(err, errInitCancelled) {
+		t.Errorf("got %v, want errInitCancelled", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("no config should be written, stat err = %v", err)
+	}
+}
+
+func TestRunInit_Existing(t *testing.T) {
+	path := writeTestConfig(t, "vendor: Jane Contractor\ncustomer: Acme Corp\nrate: 100\nhours: 40\nmax_cost: 0.5\n")
+
+	t.Run("declined", func(t *testing.T) {
+		var out bytes.Buffer
+		if err := RunInit(path, "", strings.NewReader("n\n"), &out); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(out.String(), "Nothing changed.") {
+			t.Errorf("output = %q", out.String())
+		}
+		if cfg := loadTestConfig(t, path); *cfg.Rate != 100 {
+			t.Errorf("rate = %v, want it unchanged", *cfg.Rate)
+		}
+	})
+
+	t.Run("updated", func(t *testing.T) {
+		// Update, keep vendor and customer, change the rate, keep the
+		// rest, and skip the preview.
+		in := "\n\n\n175\n\n\n\n\nn\n"
+		var out bytes.Buffer
+		if err := RunInit(path, "", strings.NewReader(in), &out); err != nil {
+			t.Fatalf("RunInit: %v\n%s", err, out.String())
+		}
+		cfg := loadTestConfig(t, path)
+		if *cfg.Vendor != "Jane Contractor" || *cfg.Customer != "Acme Corp" || *cfg.Rate != 175 || *cfg.Hours != 40 {
+			t.Errorf("config = %+v", cfg)
+		}
+		if cfg.MaxCost == nil || *cfg.MaxCost != 0.5 {
+			t.Errorf("max_cost = %v, want the key init does not ask for kept", cfg.MaxCost)
+		}
+		for _, want := range []string{"vendor [Jane Contractor]: ", "rate [100]: ", "  rate: 100 -> 175\n"} {
+			if !strings.Contains(out.String(), want) {
+				t.Errorf("output is missing %q:\n%s", want, out.String())
+			}
+		}
+	})
+}
+
+func TestRunInit_FromHTML(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	// Use the values found, keep them as the answers, give the hours, keep
+	// the defaults, and skip the preview.
+	in := "\n\n\n\n40\n\n\n\nn\n"
+	var out bytes.Buffer
+	if err := RunInit(path, filepath.Join("testdata", "old-invoice.html"), strings.NewReader(in), &out); err != nil {
+		t.Fatalf("RunInit: %v\n%s", err, out.String())
+	}
+
+	cfg := loadTestConfig(t, path)
+	if *cfg.Vendor != "Jane Contractor" || *cfg.Customer != "Acme Corp" || *cfg.Rate != 150 || *cfg.Hours != 40 {
+		t.Errorf("config = %+v", cfg)
+	}
+	if cfg.IBAN == nil || *cfg.IBAN != "DE89 3704 0044 0532 0130 00" || cfg.BIC == nil || *cfg.BIC != "COBADEFFXXX" {
+		t.Errorf("iban = %v, bic = %v", cfg.IBAN, cfg.BIC)
+	}
+	if cfg.AccountNumber != nil || cfg.RoutingNumber != nil {
+		t.Errorf("account_number = %v, routing_number = %v, want the invalid and missing ones left out", cfg.AccountNumber, cfg.RoutingNumber)
+	}
+	for _, want := range []string{
+		"  vendor: Jane Contractor\n",
+		"  customer: Acme Corp\n",
+		"  rate: 150\n",
+		"  number: 2024-117 (not carried over:",
+		"Not found: routing_number\n",
+		"Not valid, so left out:\n  account_number: account number \"12\" is not valid",
+		"vendor [Jane Contractor]: ",
+		"  vendor: set to Jane Contractor\n",
+		"  iban: set to DE89 3704 0044 0532 0130 00\n",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output is missing %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestRunInit_FromJSONLD(t *testing.T) {
+	page := `<html><head><script type="application/ld+json">
+{"@context": "https://schema.org", "@type": "Invoice", "identifier": "INV-0042",
+ "provider": {"@type": "Person", "name": "Jane Contractor"},
+ "customer": {"@type": "Organization", "name": "Acme Corp"},
+ "referencesOrder": {"@type": "Order", "orderedItem": {"@type": "OrderItem",
+   "priceSpecification": {"@type": "UnitPriceSpecification", "price": 165, "unitCode": "HUR"}}}}
+</script></head><body><p>From: Someone Else</p><p>40 hours @ $150/hr</p></body></html>`
+	old, err := readInvoiceHTML([]byte(page))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"vendor": "Jane Contractor", "customer": "Acme Corp", "rate": "165"}
+	if !reflect.DeepEqual(old.values, want) || old.number != "INV-0042" {
+		t.Errorf("got %v and number %q, want %v and INV-0042", old.values, old.number, want)
+	}
+}
+
+func TestRunInit_FromSidecar(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	sidecar := filepath.Join(dir, "invoice-2024-12.yaml")
+	data := "number: INV-202412-acme-corp\nvendor: Jane Contractor\ncustomer: Acme Corp\nrate: \"$175\"\n" +
+		"payment:\n  routing_number: \"021000021\"\n  account_number: \"123456789\"\n"
+	if err := os.WriteFile(sidecar, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("declined", func(t *testing.T) {
+		var out bytes.Buffer
+		if err := RunInit(path, sidecar, strings.NewReader("n\n"), &out); !errors.Is(err, errInitCancelled) {
+			t.Fatalf("got %v, want errInitCancelled\n%s", err, out.String())
+		}
+		if !strings.Contains(out.String(), "vendor: ") || strings.Contains(out.String(), "vendor [Jane Contractor]") {
+			t.Errorf("declined values should not be offered as answers:\n%s", out.String())
+		}
+	})
+
+	t.Run("accepted", func(t *testing.T) {
+		in := "y\n\n\n\n32\n\n\n\nn\n"
+		var out bytes.Buffer
+		if err := RunInit(path, sidecar, strings.NewReader(in), &out); err != nil {
+			t.Fatalf("RunInit: %v\n%s", err, out.String())
+		}
+		cfg := loadTestConfig(t, path)
+		if *cfg.Vendor != "Jane Contractor" || *cfg.Customer != "Acme Corp" || *cfg.Rate != 175 || *cfg.Hours != 32 {
+			t.Errorf("config = %+v", cfg)
+		}
+		if cfg.RoutingNumber == nil || *cfg.RoutingNumber != "021000021" || cfg.AccountNumber == nil || *cfg.AccountNumber != "123456789" {
+			t.Errorf("routing_number = %v, account_number = %v", cfg.RoutingNumber, cfg.AccountNumber)
+		}
+		if !strings.Contains(out.String(), "Not found: iban, bic\n") {
+			t.Errorf("output = %s", out.String())
+		}
+	})
+}
@@ -0,0 +1,398 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/zon/invoicer/internal/config"
+	"github.com/zon/invoicer/pkg/invoice"
+	"golang.org/x/net/html"
+	"gopkg.in/yaml.v3"
+)
+
+// importKeys are the config keys 'init --from' looks for in an old
+// invoice, in the order they are shown.
+var importKeys = []string{"vendor", "customer", "rate", "iban", "bic", "routing_number", "account_number"}
+
+// oldInvoice is what 'init --from' found in an old invoice: the values of
+// importKeys, and the invoice's number.
+type oldInvoice struct {
+	values map[string]string
+	number string
+}
+
+// readOldInvoice reads the invoice at path: the JSON or YAML of an
+// invoice, as 'export json' writes it, if path ends in .json, .yaml or
+// .yml, and HTML otherwise.
+func readOldInvoice(path string) (*oldInvoice, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading the old invoice: %w", err)
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json", ".yaml", ".yml":
+		return readInvoiceData(data)
+	}
+	return readInvoiceHTML(data)
+}
+
+// oldInvoiceData is the part of an invoice's JSON or YAML that 'init
+// --from' uses. The rate is a string, so "$150" is read too.
+type oldInvoiceData struct {
+	Number   string `yaml:"number"`
+	Vendor   string `yaml:"vendor"`
+	Customer string `yaml:"customer"`
+	Rate     string `yaml:"rate"`
+	Payment  struct {
+		IBAN          string `yaml:"iban"`
+		BIC           string `yaml:"bic"`
+		RoutingNumber string `yaml:"routing_number"`
+		AccountNumber string `yaml:"account_number"`
+	} `yaml:"payment"`
+}
+
+// readInvoiceData reads the JSON or YAML of an invoice. JSON is read as
+// YAML, which it is.
+func readInvoiceData(data []byte) (*oldInvoice, error) {
+	var d oldInvoiceData
+	if err := yaml.Unmarshal(data, &d); err != nil {
+		return nil, fmt.Errorf("parsing the old invoice: %w", err)
+	}
+	old := &oldInvoice{values: map[string]string{}, number: d.Number}
+	for key, v := range map[string]string{
+		"vendor":         d.Vendor,
+		"customer":       d.Customer,
+		"rate":           d.Rate,
+		"iban":           d.Payment.IBAN,
+		"bic":            d.Payment.BIC,
+		"routing_number": d.Payment.RoutingNumber,
+		"account_number": d.Payment.AccountNumber,
+	} {
+		if v = strings.TrimSpace(v); v != "" {
+			old.values[key] = v
+		}
+	}
+	return old, nil
+}
+
+// readInvoiceHTML reads an invoice made as an HTML page. JSON-LD in the
+// page, a schema.org Invoice, is read first; what it does not give is
+// looked for in the page's text: a value after its label, such as "Bill
+// To" or "IBAN:", in any language invoicer has labels for, a rate written
+// as "@ $150/hr", and an invoice number such as "Invoice #: 42".
+func readInvoiceHTML(data []byte) (*oldInvoice, error) {
+	doc, err := html.Parse(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("parsing the old invoice: %w", err)
+	}
+	var lines, scripts []string
+	htmlText(doc, &lines, &scripts)
+
+	old := &oldInvoice{values: map[string]string{}}
+	for _, s := range scripts {
+		readJSONLD(s, old)
+	}
+	for key, labels := range importLabels() {
+		if old.values[key] != "" {
+			continue
+		}
+		if v := labelledValue(lines, labels); v != "" {
+			old.values[key] = v
+		}
+	}
+	text := strings.Join(lines, "\n")
+	if old.values["rate"] == "" {
+		if m := ratePattern.FindStringSubmatch(text); m != nil {
+			old.values["rate"] = m[1]
+		}
+	}
+	if old.number == "" {
+		if m := numberPattern.FindStringSubmatch(text); m != nil {
+			old.number = m[1]
+		} else {
+			old.number = invoiceNumberPattern.FindString(text)
+		}
+	}
+	return old, nil
+}
+
+// htmlText appends the text of n and its children to lines, one line per
+// text node with its whitespace collapsed, and the content of its JSON-LD
+// scripts to scripts. Other scripts and styles are left out.
+func htmlText(n *html.Node, lines, scripts *[]string) {
+	if n.Type == html.ElementNode && (n.Data == "script" || n.Data == "style") {
+		if n.Data == "script" && n.FirstChild != nil && slices.ContainsFunc(n.Attr, func(a html.Attribute) bool {
+			return a.Key == "type" && strings.EqualFold(a.Val, "application/ld+json")
+		}) {
+			*scripts = append(*scripts, n.FirstChild.Data)
+		}
+		return
+	}
+	if n.Type == html.TextNode {
+		if s := strings.Join(strings.Fields(n.Data), " "); s != "" {
+			*lines = append(*lines, s)
+		}
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		htmlText(c, lines, scripts)
+	}
+}
+
+// importLabels returns the labels, in lower case, that the value of each
+// of importKeys follows in an invoice's text: invoicer's own labels in
+// every language it has, and the usual English ones.
+func importLabels() map[string][]string {
+	labels := map[string][]string{
+		"vendor":         {"from", "vendor", "contractor"},
+		"customer":       {"bill to", "billed to", "client", "customer"},
+		"iban":           {"iban"},
+		"bic":            {"bic", "swift", "bic/swift", "swift/bic", "swift code"},
+		"routing_number": {"aba", "aba routing number"},
+		"account_number": {"account no", "account no."},
+	}
+	for key, label := range map[string]string{
+		"vendor":         "from",
+		"customer":       "bill_to",
+		"routing_number": "routing_number",
+		"account_number": "account_number",
+	} {
+		for _, lang := range invoice.Languages() {
+			if l := strings.ToLower(invoice.Label(nil, lang, label)); !slices.Contains(labels[key], l) {
+				labels[key] = append(labels[key], l)
+			}
+		}
+	}
+	return labels
+}
+
+// labelledValue returns the value after the first of lines that is one
+// of labels, with or without a colon, or that starts with one of them and
+// a colon, as "IBAN: DE89…" does. A label on a line of its own is
+// followed by its value on the next line.
+func labelledValue(lines, labels []string) string {
+	for i, line := range lines {
+		lower := strings.ToLower(line)
+		for _, label := range labels {
+			if strings.TrimSpace(strings.TrimSuffix(lower, ":")) == label {
+				if i+1 < len(lines) {
+					return lines[i+1]
+				}
+				return ""
+			}
+			if strings.HasPrefix(lower, label+":") {
+				if _, v, _ := strings.Cut(line, ":"); strings.TrimSpace(v) != "" {
+					return strings.TrimSpace(v)
+				}
+			}
+		}
+	}
+	return ""
+}
+
+var (
+	// ratePattern matches an hourly rate as a line item shows it, e.g.
+	// "@ $150/hr" or "$150.00 per hour", with the amount as its group.
+	ratePattern = regexp.MustCompile(`(?i)[$€£]?\s*(\d[\d.,]*\d|\d)\s*(?:/\s*|per\s+)(?:hr|hour)\b`)
+
+	// numberPattern matches a labelled invoice number, e.g. "Invoice #:
+	// 42" or "Invoice No. 2024-007", with the number as its group.
+	numberPattern = regexp.MustCompile(`(?i)invoice[ \t]*(?:#|no\b\.?|number\b)[ \t]*:?\s*([A-Za-z0-9][\w-]*)`)
+
+	// invoiceNumberPattern matches an invoice number as invoicer makes
+	// them, e.g. "INV-202501-acme-corp".
+	invoiceNumberPattern = regexp.MustCompile(`\bINV-[\w-]+`)
+)
+
+// readJSONLD reads the vendor, customer, invoice number and hourly rate
+// of the schema.org Invoice in the JSON-LD script s into old. A script
+// that is not JSON is ignored.
+func readJSONLD(s string, old *oldInvoice) {
+	var doc any
+	if err := json.Unmarshal([]byte(s), &doc); err != nil {
+		return
+	}
+	walkJSON(doc, func(m map[string]any) {
+		if m["unitCode"] == "HUR" && old.values["rate"] == "" {
+			if p := jsonString(m["price"]); p != "" {
+				old.values["rate"] = p
+			}
+		}
+		if !slices.Contains(jsonStrings(m["@type"]), "Invoice") {
+			return
+		}
+		for key, fields := range map[string][]string{
+			"vendor":   {"provider", "seller", "broker"},
+			"customer": {"customer"},
+		} {
+			for _, f := range fields {
+				if name := jsonName(m[f]); name != "" && old.values[key] == "" {
+					old.values[key] = name
+				}
+			}
+		}
+		for _, f := range []string{"identifier", "confirmationNumber"} {
+			if n := jsonString(m[f]); n != "" && old.number == "" {
+				old.number = n
+			}
+		}
+	})
+}
+
+// walkJSON calls fn with each object in v, outermost first.
+func walkJSON(v any, fn func(map[string]any)) {
+	switch v := v.(type) {
+	case map[string]any:
+		fn(v)
+		for _, k := range slices.Sorted(maps.Keys(v)) {
+			walkJSON(v[k], fn)
+		}
+	case []any:
+		for _, e := range v {
+			walkJSON(e, fn)
+		}
+	}
+}
+
+// jsonName returns the name of a JSON-LD party: a string, or an object
+// with a name.
+func jsonName(v any) string {
+	if m, ok := v.(map[string]any); ok {
+		return jsonString(m["name"])
+	}
+	return jsonString(v)
+}
+
+// jsonString returns v as a string if it is a string or a number.
+func jsonString(v any) string {
+	switch v := v.(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case float64:
+		return fmt.Sprint(v)
+	}
+	return ""
+}
+
+// jsonStrings returns v as a list of strings: a string, or the strings in
+// a list, as JSON-LD writes an @type.
+func jsonStrings(v any) []string {
+	if s, ok := v.(string); ok {
+		return []string{s}
+	}
+	var out []string
+	if l, ok := v.([]any); ok {
+		for _, e := range l {
+			if s, ok := e.(string); ok {
+				out = append(out, s)
+			}
+		}
+	}
+	return out
+}
+
+// importOldInvoice reads the invoice at path, shows what it found on out
+// and, if the user agrees, sets it in edited as the answers a blank line
+// keeps. Each value is checked as 'set config' checks a flag; one that is
+// not valid is left for the user to answer.
+func importOldInvoice(edited *config.Config, path string, s *bufio.Scanner, out io.Writer) error {
+	old, err := readOldInvoice(path)
+	if err != nil {
+		return err
+	}
+
+	var found config.Config
+	v := reflect.ValueOf(&found).Elem()
+	var shown, invalid, missing []string
+	for _, key := range importKeys {
+		value, ok := old.values[key]
+		if !ok {
+			missing = append(missing, key)
+			continue
+		}
+		field := v.Field(configField(key))
+		var single config.Config
+		sv := reflect.ValueOf(&single).Elem().Field(configField(key))
+		err := setFromString(sv, value)
+		if err == nil {
+			err = validateConfig(&single)
+		}
+		if err != nil {
+			invalid = append(invalid, fmt.Sprintf("  %s: %v", key, err))
+			continue
+		}
+		field.Set(sv)
+		shown = append(shown, fmt.Sprintf("  %s: %s", key, effectiveField{Key: key, Value: field}.String()))
+	}
+
+	if len(shown) == 0 {
+		fmt.Fprintf(out, "Found nothing to use in %s.\n", path)
+		if len(invalid) > 0 {
+			fmt.Fprintf(out, "%s\n", strings.Join(invalid, "\n"))
+		}
+		if old.number != "" {
+			fmt.Fprintf(out, "Its number, %s, is not carried over: %s.\n", old.number, numberingNote)
+		}
+		fmt.Fprintln(out)
+		return nil
+	}
+	fmt.Fprintf(out, "Found in %s:\n%s\n", path, strings.Join(shown, "\n"))
+	if old.number != "" {
+		fmt.Fprintf(out, "  number: %s (not carried over: %s)\n", old.number, numberingNote)
+	}
+	if len(missing) > 0 {
+		fmt.Fprintf(out, "Not found: %s\n", strings.Join(missing, ", "))
+	}
+	if len(invalid) > 0 {
+		fmt.Fprintf(out, "Not valid, so left out:\n%s\n", strings.Join(invalid, "\n"))
+	}
+	fmt.Fprintf(out, "Use these values? [Y/n] ")
+	if !askYes(s) {
+		fmt.Fprintln(out)
+		return nil
+	}
+	fmt.Fprintln(out)
+	ev := reflect.ValueOf(edited).Elem()
+	for i := range v.NumField() {
+		if !v.Field(i).IsZero() {
+			ev.Field(i).Set(v.Field(i))
+		}
+	}
+	return nil
+}
+
+// numberingNote says why an old invoice's number is not carried over.
+const numberingNote = "invoicer numbers each invoice by its month and customer, e.g. INV-202501-acme-corp"
+
+// importedChanges returns the changes of the keys 'init --from' sets
+// that 'init' does not ask for, from orig to edited.
+func importedChanges(edited, orig *config.Config) []configChange {
+	var changes []configChange
+	ev, ov := reflect.ValueOf(edited).Elem(), reflect.ValueOf(orig).Elem()
+	for _, key := range importKeys {
+		if slices.ContainsFunc(initKeys, func(k initKey) bool { return k.key == key }) {
+			continue
+		}
+		i := configField(key)
+		if reflect.DeepEqual(ev.Field(i).Interface(), ov.Field(i).Interface()) {
+			continue
+		}
+		secret := hasTag(ev.Type().Field(i).Tag, "secret")
+		changes = append(changes, configChange{
+			key:   key,
+			old:   effectiveField{Key: key, Value: ov.Field(i), Secret: secret}.display(),
+			new:   effectiveField{Key: key, Value: ev.Field(i), Secret: secret}.display(),
+			index: i,
+		})
+	}
+	return changes
+}
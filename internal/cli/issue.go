@@ -0,0 +1,48 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+// maxBackdate is how long ago an --issue-date may be without asking.
+const maxBackdate = 365 * 24 * time.Hour
+
+// checkIssueDate makes sure an --issue-date that looks like a mistake is
+// meant: one before the month inv bills, or more than maxBackdate ago.
+// Unless force is set, it asks on a terminal with in and out, and is an
+// error elsewhere.
+func checkIssueDate(inv *invoice.Invoice, force, terminal bool, in io.Reader, out io.Writer) error {
+	issued := inv.IssueDate()
+	start := time.Date(inv.Year, inv.Month, 1, 0, 0, 0, 0, time.UTC)
+	var problem string
+	switch {
+	case issued.Before(start):
+		problem = fmt.Sprintf("the issue date %s is before %s %d, the month invoiced", issued.Format(time.DateOnly), inv.Month, inv.Year)
+	case invoice.Now().Sub(issued) > maxBackdate:
+		problem = fmt.Sprintf("the issue date %s is more than a year ago", issued.Format(time.DateOnly))
+	default:
+		return nil
+	}
+	if force {
+		return nil
+	}
+	if !terminal {
+		return fmt.Errorf("%s (use --force to issue it anyway)", problem)
+	}
+	fmt.Fprintf(out, "Warning: %s.\nIssue the invoice on %s anyway? [y/N] ", problem, issued.Format(time.DateOnly))
+	s := bufio.NewScanner(in)
+	answer := ""
+	if s.Scan() {
+		answer = strings.ToLower(strings.TrimSpace(s.Text()))
+	}
+	if answer != "y" && answer != "yes" {
+		return fmt.Errorf("%s; no invoice generated", problem)
+	}
+	return nil
+}
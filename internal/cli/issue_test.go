@@ -0,0 +1,118 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zon/invoicer/internal/config"
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+func TestIssueDate_UsedEverywhere(t *testing.T) {
+	oldNow := invoice.Now
+	invoice.Now = func() time.Time { return time.Date(2025, time.February, 3, 9, 0, 0, 0, time.UTC) }
+	t.Cleanup(func() { invoice.Now = oldNow })
+
+	// --backdate is another name for --issue-date.
+	cmd := parseCLI(t, "--vendor=Jane", "--customer=Acme", "--rate=100", "--hours=40", "--backdate=2025-01-31", "january", "2025")
+	opts, err := cmd.Generate.resolveOptions(&config.Config{}, nil)
+	if err != nil {
+		t.Fatalf("resolveOptions: %v", err)
+	}
+	inv, err := opts.buildInvoice()
+	if err != nil {
+		t.Fatalf("buildInvoice: %v", err)
+	}
+	want := time.Date(2025, time.January, 31, 0, 0, 0, 0, time.UTC)
+	if !inv.IssueDate().Equal(want) {
+		t.Fatalf("IssueDate() = %v, want %v", inv.IssueDate(), want)
+	}
+
+	data, err := json.Marshal(inv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"issue_date":"2025-01-31"`) {
+		t.Errorf("JSON export = %s, want the issue date 2025-01-31", data)
+	}
+	var text bytes.Buffer
+	if err := invoice.WriteText(&text, inv); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(text.String(), "Date: January 31, 2025") {
+		t.Errorf("text invoice does not show the issue date:\n%s", text.String())
+	}
+	if prompt := invoice.BuildPrompt(inv, "invoice.html"); !strings.Contains(prompt, "- Invoice Date: January 31, 2025") {
+		t.Errorf("prompt does not have the issue date:\n%s", prompt)
+	}
+	if f := invoice.InvoiceEmailFields(inv); f.Issued != "January 31, 2025" {
+		t.Errorf("email Issued = %q, want January 31, 2025", f.Issued)
+	}
+
+	// Without the flag, the invoice is issued today.
+	cmd = parseCLI(t, "--vendor=Jane", "--customer=Acme", "--rate=100", "--hours=40", "january", "2025")
+	if opts, err = cmd.Generate.resolveOptions(&config.Config{}, nil); err != nil {
+		t.Fatalf("resolveOptions: %v", err)
+	}
+	if inv, err = opts.buildInvoice(); err != nil {
+		t.Fatalf("buildInvoice: %v", err)
+	}
+	if !inv.IssueDate().Equal(invoice.Now()) {
+		t.Errorf("IssueDate() = %v, want today", inv.IssueDate())
+	}
+}
+
+func TestIssueDate_Invalid(t *testing.T) {
+	cmd := parseCLI(t, "--issue-date=31/01/2025")
+	_, err := cmd.Generate.resolveOptions(&config.Config{}, nil)
+	if err == nil || !strings.Contains(err.Error(), `issue date "31/01/2025" is not a date like 2025-01-31`) {
+		t.Errorf("expected an invalid date error, got %v", err)
+	}
+}
+
+func TestCheckIssueDate(t *testing.T) {
+	oldNow := invoice.Now
+	invoice.Now = func() time.Time { return time.Date(2025, time.February, 3, 9, 0, 0, 0, time.UTC) }
+	t.Cleanup(func() { invoice.Now = oldNow })
+
+	day := func(year int, month time.Month, d int) time.Time {
+		return time.Date(year, month, d, 0, 0, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		name     string
+		month    time.Month
+		year     int
+		issued   time.Time
+		force    bool
+		terminal bool
+		answer   string
+		wantErr  string
+	}{
+		{"end of the month", time.January, 2025, day(2025, time.January, 31), false, false, "", ""},
+		{"before the month", time.January, 2025, day(2024, time.December, 20), false, false, "", "before January 2025, the month invoiced (use --force"},
+		{"before the month forced", time.January, 2025, day(2024, time.December, 20), true, false, "", ""},
+		{"before the month confirmed", time.January, 2025, day(2024, time.December, 20), false, true, "y\n", ""},
+		{"before the month declined", time.January, 2025, day(2024, time.December, 20), false, true, "\n", "no invoice generated"},
+		{"over a year ago", time.January, 2023, day(2023, time.January, 31), false, false, "", "2023-01-31 is more than a year ago"},
+		{"over a year ago forced", time.January, 2023, day(2023, time.January, 31), true, false, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inv := &invoice.Invoice{Month: tt.month, Year: tt.year, Issued: tt.issued}
+			var out bytes.Buffer
+			err := checkIssueDate(inv, tt.force, tt.terminal, strings.NewReader(tt.answer), &out)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("checkIssueDate: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zon/invoicer/internal/config"
+)
+
+// chdirProject creates a project directory under a temporary $HOME containing
+// a local config with the given content, and changes into it.
+func chdirProject(t *testing.T, content string) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	dir := filepath.Join(home, "clients", "acme")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, config.LocalFile)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(dir)
+	return path
+}
+
+func TestResolveOptions_LocalConfigPrecedence(t *testing.T) {
+	global := writeTestConfig(t, `vendor: Global Vendor
+customer: Global Customer
+rate: 50
+hours: 20
+`)
+	local := &config.Config{Customer: strPtr("Local Customer"), Rate: floatPtr(120)}
+	c := &GenerateCmd{Rate: floatPtr(150)}
+
+	opts, err := c.resolveOptions(loadTestConfig(t, global), local)
+	if err != nil {
+		t.Fatalf("resolveOptions: %v", err)
+	}
+	if opts.Vendor != "Global Vendor" {
+		t.Errorf("Vendor: expected global fallback, got %q", opts.Vendor)
+	}
+	if opts.Customer != "Local Customer" {
+		t.Errorf("Customer: local should override global, got %q", opts.Customer)
+	}
+	if opts.Rate != 150 {
+		t.Errorf("Rate: CLI should override local, got %v", opts.Rate)
+	}
+	if opts.Hours != 20 {
+		t.Errorf("Hours: expected global fallback, got %v", opts.Hours)
+	}
+}
+
+func TestLocalConfig_FoundFromWorkingDirectory(t *testing.T) {
+	chdirProject(t, "customer: Acme\n")
+
+	cfg, err := (&Globals{}).localConfig()
+	if err != nil {
+		t.Fatalf("localConfig: %v", err)
+	}
+	if cfg == nil || str(cfg.Customer) != "Acme" {
+		t.Errorf("expected local customer Acme, got %+v", cfg)
+	}
+}
+
+func TestLocalConfig_NoLocal(t *testing.T) {
+	chdirProject(t, "customer: Acme\n")
+
+	cfg, err := (&Globals{NoLocal: true}).localConfig()
+	if err != nil {
+		t.Fatalf("localConfig: %v", err)
+	}
+	if cfg != nil {
+		t.Errorf("--no-local should skip the lookup, got %+v", cfg)
+	}
+}
+
+func TestLocalConfig_MalformedNamesFile(t *testing.T) {
+	path := chdirProject(t, "rate: [not a number\n")
+
+	_, err := (&Globals{}).localConfig()
+	if err == nil {
+		t.Fatal("expected error for malformed local config, got nil")
+	}
+	if !strings.Contains(err.Error(), path) {
+		t.Errorf("expected error naming %s, got %v", path, err)
+	}
+}
+
+func TestRunShowConfig_LocalProvenance(t *testing.T) {
+	cfg := &config.Config{Vendor: strPtr("Global Vendor"), Customer: strPtr("Global Customer")}
+	local := &config.Config{Customer: strPtr("Local Customer")}
+
+	var out bytes.Buffer
+	if err := RunShowConfig(&ShowConfigCmd{Format: "table"}, cfg, local, envMap(nil), &out); err != nil {
+		t.Fatalf("RunShowConfig: %v", err)
+	}
+	for _, line := range strings.Split(out.String(), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "customer":
+			if !strings.Contains(line, "Local Customer") || fields[len(fields)-1] != string(SourceLocal) {
+				t.Errorf("customer: expected local value and source, got %q", line)
+			}
+		case "vendor":
+			if fields[len(fields)-1] != string(SourceConfig) {
+				t.Errorf("vendor: expected config source, got %q", line)
+			}
+		}
+	}
+}
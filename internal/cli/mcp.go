@@ -0,0 +1,163 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"runtime/debug"
+	"time"
+
+	"github.com/zon/invoicer/internal/config"
+	"github.com/zon/invoicer/internal/mcp"
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+// McpCmd is the 'mcp' subcommand, a Model Context Protocol server on stdio.
+type McpCmd struct {
+	// Dir is where generated invoices are written and listed from.
+	Dir string `type:"path" help:"Directory to write invoices to. Defaults to the current directory."`
+
+	// Timeout bounds the generation of a single invoice.
+	Timeout time.Duration `default:"5m" help:"Maximum time to spend on one generate_invoice call."`
+}
+
+// Run executes the 'mcp' subcommand until stdin closes or ctx is cancelled.
+func (m *McpCmd) Run(g *Globals, ctx context.Context) error {
+	configPath, err := g.configPath()
+	if err != nil {
+		return err
+	}
+	global, err := g.loadConfig(configPath)
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+	local, err := g.localConfig()
+	if err != nil {
+		return err
+	}
+
+	srv := newServer(config.Merge(global, local), &ServeCmd{Dir: m.Dir, Timeout: m.Timeout, MaxConcurrent: 1}, os.Stderr)
+
+	// Stdout carries the protocol, so anything else that writes to it, such
+	// as the hook, is sent to stderr instead.
+	out := os.Stdout
+	os.Stdout = os.Stderr
+	defer func() { os.Stdout = out }()
+
+	return newMCPServer(srv).Serve(ctx, os.Stdin, out)
+}
+
+// newMCPServer exposes the invoice pipeline of srv as MCP tools.
+func newMCPServer(srv *server) *mcp.Server {
+	closed := false
+	s := mcp.NewServer("invoicer", buildVersion())
+	s.AddTool(mcp.Tool{
+		Name:        "preview_invoice",
+		Description: "Compute an invoice's weeks and total without generating any files. Options not given come from the invoicer config.",
+		InputSchema: invoiceSchema(false),
+		Handler:     srv.previewTool,
+	})
+	s.AddTool(mcp.Tool{
+		Name:        "generate_invoice",
+		Description: "Generate an HTML (and optionally PDF) invoice and return the file paths. Options not given come from the invoicer config.",
+		InputSchema: invoiceSchema(true),
+		Handler:     srv.generateTool,
+	})
+	s.AddTool(mcp.Tool{
+		Name:        "list_invoices",
+		Description: "List the generated invoice files.",
+		InputSchema: &mcp.Schema{Type: "object", AdditionalProperties: &closed},
+		Handler:     srv.listTool,
+	})
+	return s
+}
+
+// invoiceSchema describes the invoiceRequest fields accepted by a tool.
+// The generation options are only offered when generate is set.
+func invoiceSchema(generate bool) *mcp.Schema {
+	one, closed := 1, false
+	minYear, maxYear := 1900.0, 9999.0
+	zero, maxHours := 0.0, 168.0
+	props := map[string]*mcp.Schema{
+		"month":    {Type: "string", Description: "Month to invoice for, e.g. 'january', 'jan' or '1'. Defaults to the previous month.", MinLength: &one},
+		"year":     {Type: "integer", Description: "Year of the month. Defaults to the year closest to the month.", Minimum: &minYear, Maximum: &maxYear},
+		"vendor":   {Type: "string", Description: "Name of the contractor sending the invoice.", MinLength: &one},
+		"customer": {Type: "string", Description: "Name of the client receiving the invoice.", MinLength: &one},
+		"rate":     {Type: "number", Description: "Hourly rate in dollars.", Minimum: &zero},
+		"hours":    {Type: "number", Description: "Hours per week worked.", Minimum: &zero, Maximum: &maxHours},
+	}
+	if generate {
+		props["pdf"] = &mcp.Schema{Type: "boolean", Description: "Also convert the invoice to PDF."}
+		props["model"] = &mcp.Schema{Type: "string", Description: "opencode-formatted model stub, e.g. anthropic/claude-haiku-4-5."}
+		props["backend"] = &mcp.Schema{Type: "string", Description: "Generation backend.", Enum: invoice.Backends()}
+		props["ollama_host"] = &mcp.Schema{Type: "string", Description: "URL of the ollama server."}
+		props["ollama_model"] = &mcp.Schema{Type: "string", Description: "ollama model name."}
+	}
+	return &mcp.Schema{Type: "object", Properties: props, AdditionalProperties: &closed}
+}
+
+// resolveRequest decodes tool arguments and builds the invoice they describe.
+func (s *server) resolveRequest(args json.RawMessage) (*ResolvedOptions, *invoice.Invoice, error) {
+	var req invoiceRequest
+	if err := json.Unmarshal(args, &req); err != nil {
+		return nil, nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+	opts, err := req.generateCmd().resolveOptions(s.cfg, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	inv, err := opts.buildInvoice()
+	if err != nil {
+		return nil, nil, err
+	}
+	return opts, inv, nil
+}
+
+func (s *server) previewTool(ctx context.Context, args json.RawMessage, progress mcp.ProgressFunc) (any, error) {
+	_, inv, err := s.resolveRequest(args)
+	if err != nil {
+		return nil, err
+	}
+	return newInvoiceResponse(inv), nil
+}
+
+func (s *server) generateTool(ctx context.Context, args json.RawMessage, progress mcp.ProgressFunc) (any, error) {
+	opts, inv, err := s.resolveRequest(args)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	select {
+	case s.slots <- struct{}{}:
+		defer func() { <-s.slots }()
+	case <-ctx.Done():
+		return nil, fmt.Errorf("waiting for another invoice to finish: %w", ctx.Err())
+	}
+
+	progress(0, 1, fmt.Sprintf("Generating invoice for %s %d", inv.Month, inv.Year))
+	resp, err := s.generate(ctx, opts, inv, false)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, fmt.Errorf("generation timed out after %s: %w", s.timeout, err)
+		}
+		return nil, err
+	}
+	progress(1, 1, "Invoice written to "+resp.HTMLPath)
+	return resp, nil
+}
+
+func (s *server) listTool(ctx context.Context, args json.RawMessage, progress mcp.ProgressFunc) (any, error) {
+	return listInvoiceFiles(s.dir)
+}
+
+// buildVersion returns the module version invoicer was built from.
+func buildVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		return info.Main.Version
+	}
+	return "(devel)"
+}
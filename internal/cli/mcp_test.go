@@ -0,0 +1,115 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zon/invoicer/internal/config"
+)
+
+// callMCP sends one tools/call request to an invoicer MCP server and returns
+// the decoded response.
+func callMCP(t *testing.T, srv *server, tool, args string) map[string]any {
+	t.Helper()
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	go func() {
+		_ = newMCPServer(srv).Serve(context.Background(), inR, outW)
+		outW.Close()
+	}()
+	defer inW.Close()
+	go io.WriteString(inW, `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"`+tool+`","arguments":`+args+`}}`+"\n")
+	line, err := bufio.NewReader(outR).ReadBytes('\n')
+	if err != nil {
+		t.Fatalf("reading response: %v", err)
+	}
+	go io.Copy(io.Discard, outR)
+	var resp map[string]any
+	if err := json.Unmarshal(line, &resp); err != nil {
+		t.Fatalf("decoding %q: %v", line, err)
+	}
+	return resp
+}
+
+// toolText returns the text of a successful tool result.
+func toolText(t *testing.T, resp map[string]any) string {
+	t.Helper()
+	result, ok := resp["result"].(map[string]any)
+	if !ok {
+		t.Fatalf("expected a result, got %v", resp)
+	}
+	text := result["content"].([]any)[0].(map[string]any)["text"].(string)
+	if result["isError"] != false {
+		t.Fatalf("tool failed: %s", text)
+	}
+	return text
+}
+
+func newTestMCP(t *testing.T) *server {
+	cfg := &config.Config{Vendor: strPtr("Jane"), Rate: floatPtr(100), Hours: floatPtr(40), Backend: strPtr("serve-test")}
+	return newServer(cfg, &ServeCmd{Dir: t.TempDir(), Timeout: time.Minute, MaxConcurrent: 1}, io.Discard)
+}
+
+func TestMCPPreviewInvoice(t *testing.T) {
+	srv := newTestMCP(t)
+	var got invoiceResponse
+	text := toolText(t, callMCP(t, srv, "preview_invoice", `{"month":"january","year":2025,"customer":"Acme Corp"}`))
+	if err := json.Unmarshal([]byte(text), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Invoice.Total() != 18400 || len(got.Invoice.Weeks) != 5 || got.HTMLPath != "" {
+		t.Errorf("unexpected preview: %+v", got)
+	}
+}
+
+func TestMCPGenerateAndListInvoices(t *testing.T) {
+	srv := newTestMCP(t)
+	var got invoiceResponse
+	text := toolText(t, callMCP(t, srv, "generate_invoice", `{"month":"january","year":2025,"customer":"Acme Corp"}`))
+	if err := json.Unmarshal([]byte(text), &got); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(got.HTMLPath, "invoice-acme-corp-2025-01.html") {
+		t.Errorf("html_path = %q", got.HTMLPath)
+	}
+
+	var files []invoiceFile
+	if err := json.Unmarshal([]byte(toolText(t, callMCP(t, srv, "list_invoices", `{}`))), &files); err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Path != got.HTMLPath {
+		t.Errorf("list_invoices = %+v", files)
+	}
+}
+
+func TestMCPInvalidArguments(t *testing.T) {
+	srv := newTestMCP(t)
+	tests := []struct {
+		tool string
+		args string
+		want string
+	}{
+		{"preview_invoice", `{"year":"2025"}`, "year must be an integer"},
+		{"preview_invoice", `{"customer":""}`, "customer must not be empty"},
+		{"preview_invoice", `{"backend":"claude"}`, "backend is not a known property"},
+		{"generate_invoice", `{"backend":"gpt"}`, "backend must be one of"},
+	}
+	for _, tt := range tests {
+		resp := callMCP(t, srv, tt.tool, tt.args)
+		rpcErr, ok := resp["error"].(map[string]any)
+		if !ok || !strings.Contains(rpcErr["message"].(string), tt.want) {
+			t.Errorf("%s %s: expected error containing %q, got %v", tt.tool, tt.args, tt.want, resp)
+		}
+	}
+
+	// Problems only the pipeline can find are reported as tool errors.
+	resp := callMCP(t, srv, "preview_invoice", `{"month":"smarch"}`)
+	if result := resp["result"].(map[string]any); result["isError"] != true {
+		t.Errorf("expected a tool error for an unknown month, got %v", result)
+	}
+}
@@ -0,0 +1,87 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+
+	"github.com/zon/invoicer/internal/config"
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+// ModelsCmd is the 'models' subcommand.
+// It lists the models opencode can use for invoice generation.
+type ModelsCmd struct {
+	// Provider limits the list to one provider's models.
+	Provider string `help:"Only list models from this provider, e.g. anthropic."`
+}
+
+// Run executes the 'models' subcommand.
+func (m *ModelsCmd) Run(g *Globals, ctx context.Context) error {
+	path, err := g.configPath()
+	if err != nil {
+		return err
+	}
+	cfg, err := g.loadConfig(path)
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+	local, err := g.localConfig()
+	if err != nil {
+		return err
+	}
+	configured := defaultModel
+	if merged := config.Merge(cfg, local); merged.Model != nil {
+		configured = *merged.Model
+	}
+	return RunModels(ctx, m, configured, os.Stdout)
+}
+
+// RunModels writes the models opencode lists to w, marking the first of the
+// configured models with an asterisk. If opencode cannot list them, it writes
+// the configured model and a hint instead.
+// This function is exported for testability.
+func RunModels(ctx context.Context, m *ModelsCmd, configured string, w io.Writer) error {
+	configuredModels := config.SplitModels(configured)
+	models, err := invoice.OpencodeModels(ctx)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		fmt.Fprintf(w, "Configured model: %s\n\n", configured)
+		if errors.Is(err, exec.ErrNotFound) {
+			fmt.Fprintln(w, "opencode is not installed, so the available models cannot be listed. Install it from https://opencode.ai to see them.")
+		} else {
+			fmt.Fprintf(w, "Could not list models from opencode: %v\nRun `opencode models` to see what went wrong.\n", err)
+		}
+		return nil
+	}
+
+	prefix := strings.TrimSuffix(m.Provider, "/") + "/"
+	if m.Provider != "" {
+		models = slices.DeleteFunc(models, func(model string) bool { return !strings.HasPrefix(model, prefix) })
+		if len(models) == 0 {
+			fmt.Fprintf(w, "opencode lists no models from provider %q.\n", m.Provider)
+			return nil
+		}
+	}
+
+	for _, model := range models {
+		mark := " "
+		if model == configuredModels[0] {
+			mark = "*"
+		}
+		fmt.Fprintf(w, "%s %s\n", mark, model)
+	}
+	for _, model := range configuredModels {
+		if !slices.Contains(models, model) && (m.Provider == "" || strings.HasPrefix(model, prefix)) {
+			fmt.Fprintf(w, "\nThe configured model %s is not in opencode's list.\n", model)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+// fakeModels makes `opencode models` print out, or fail with err.
+func fakeModels(t *testing.T, out string, err error) {
+	t.Helper()
+	orig := invoice.OpencodeModelsExec
+	t.Cleanup(func() { invoice.OpencodeModelsExec = orig })
+	invoice.OpencodeModelsExec = func(ctx context.Context) ([]byte, error) {
+		return []byte(out), err
+	}
+}
+
+const (
+	textModels = "anthropic/claude-haiku-4-5\nanthropic/claude-sonnet-4-6\nopenai/gpt-5\n"
+	jsonModels = `[{"id":"claude-haiku-4-5","providerID":"anthropic"},{"id":"claude-sonnet-4-6","providerID":"anthropic"},{"id":"gpt-5","providerID":"openai"}]`
+)
+
+func TestRunModels_MarksConfigured(t *testing.T) {
+	for name, out := range map[string]string{"text": textModels, "json": jsonModels} {
+		t.Run(name, func(t *testing.T) {
+			fakeModels(t, out, nil)
+			var buf bytes.Buffer
+			if err := RunModels(context.Background(), &ModelsCmd{}, "anthropic/claude-sonnet-4-6", &buf); err != nil {
+				t.Fatal(err)
+			}
+			want := "  anthropic/claude-haiku-4-5\n* anthropic/claude-sonnet-4-6\n  openai/gpt-5\n"
+			if buf.String() != want {
+				t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+			}
+		})
+	}
+}
+
+func TestRunModels_Provider(t *testing.T) {
+	fakeModels(t, textModels, nil)
+	var buf bytes.Buffer
+	if err := RunModels(context.Background(), &ModelsCmd{Provider: "openai"}, defaultModel, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "  openai/gpt-5\n" {
+		t.Errorf("got %q", buf.String())
+	}
+
+	buf.Reset()
+	if err := RunModels(context.Background(), &ModelsCmd{Provider: "acme"}, defaultModel, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `no models from provider "acme"`) {
+		t.Errorf("got %q", buf.String())
+	}
+}
+
+func TestRunModels_ConfiguredNotListed(t *testing.T) {
+	fakeModels(t, textModels, nil)
+	var buf bytes.Buffer
+	if err := RunModels(context.Background(), &ModelsCmd{}, "anthropic/claude-haiku-4,openai/gpt-5", &buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "The configured model anthropic/claude-haiku-4 is not in opencode's list.") {
+		t.Errorf("got:\n%s", buf.String())
+	}
+}
+
+func TestRunModels_Degrades(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"missing", &exec.Error{Name: "opencode", Err: exec.ErrNotFound}, "opencode is not installed"},
+		{"failed", errors.New("exit status 1: no providers configured"), "Could not list models from opencode: exit status 1: no providers configured"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeModels(t, "", tt.err)
+			var buf bytes.Buffer
+			if err := RunModels(context.Background(), &ModelsCmd{}, defaultModel, &buf); err != nil {
+				t.Fatalf("RunModels should not fail, got %v", err)
+			}
+			if !strings.Contains(buf.String(), "Configured model: "+defaultModel) || !strings.Contains(buf.String(), tt.want) {
+				t.Errorf("got:\n%s", buf.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+// postNotification posts a notification payload to a webhook. It can be
+// overridden in tests.
+var postNotification = func(ctx context.Context, webhook string, payload []byte) error {
+	return invoice.PostNotification(ctx, nil, webhook, payload)
+}
+
+// notifyGenerated posts opts.NotifyMessage about inv and the generated file
+// at path to opts.NotifyWebhook, if it is set. The invoice is already
+// written, so a notification that cannot be sent is only a warning on w.
+func notifyGenerated(ctx context.Context, opts *ResolvedOptions, inv *invoice.Invoice, path string, w io.Writer) {
+	if opts.NotifyWebhook == "" {
+		return
+	}
+	if err := notify(ctx, opts, inv, path); err != nil {
+		fmt.Fprintf(w, "Warning: could not send the notification: %v\n", err)
+	}
+}
+
+// notify builds the notification and posts it.
+func notify(ctx context.Context, opts *ResolvedOptions, inv *invoice.Invoice, path string) error {
+	provider, err := invoice.NotifyProvider(opts.NotifyWebhook)
+	if err != nil {
+		return err
+	}
+	text, err := invoice.RenderNotification(provider, opts.NotifyMessage, inv, path)
+	if err != nil {
+		return err
+	}
+	payload, err := invoice.NotificationPayload(provider, text)
+	if err != nil {
+		return err
+	}
+	return postNotification(ctx, opts.NotifyWebhook, payload)
+}
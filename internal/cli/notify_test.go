@@ -0,0 +1,89 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+const slackWebhook = "https://hooks.slack.com/services/T000/B000/XXXX"
+
+// fakeNotification replaces postNotification with one that records what
+// it is asked to post and returns err.
+func fakeNotification(t *testing.T, err error) (webhook *string, payload *[]byte) {
+	t.Helper()
+	orig := postNotification
+	t.Cleanup(func() { postNotification = orig })
+	webhook, payload = new(string), new([]byte)
+	postNotification = func(_ context.Context, url string, data []byte) error {
+		*webhook, *payload = url, data
+		return err
+	}
+	return webhook, payload
+}
+
+func TestNotifyGenerated(t *testing.T) {
+	webhook, payload := fakeNotification(t, nil)
+	var stderr bytes.Buffer
+	opts := &ResolvedOptions{NotifyWebhook: slackWebhook, NotifyMessage: "{{.Customer}}: {{.Path}}"}
+	notifyGenerated(context.Background(), opts, hookInvoice(), "/tmp/a.pdf", &stderr)
+	if stderr.Len() != 0 {
+		t.Errorf("unexpected warning: %q", stderr.String())
+	}
+	if *webhook != slackWebhook {
+		t.Errorf("posted to %q, want %q", *webhook, slackWebhook)
+	}
+	var got map[string]string
+	if err := json.Unmarshal(*payload, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got["text"] != "Acme Corp: /tmp/a.pdf" {
+		t.Errorf("text = %q", got["text"])
+	}
+}
+
+func TestNotifyGenerated_WarnsOnFailure(t *testing.T) {
+	fakeNotification(t, errors.New("posting the notification: 500 Internal Server Error"))
+	for name, opts := range map[string]*ResolvedOptions{
+		"post":     {NotifyWebhook: slackWebhook},
+		"webhook":  {NotifyWebhook: "https://example.com/hook"},
+		"template": {NotifyWebhook: slackWebhook, NotifyMessage: "{{.Amount}}"},
+	} {
+		var stderr bytes.Buffer
+		notifyGenerated(context.Background(), opts, hookInvoice(), "/tmp/a.pdf", &stderr)
+		if !strings.HasPrefix(stderr.String(), "Warning: could not send the notification") {
+			t.Errorf("%s: got %q, want a warning", name, stderr.String())
+		}
+	}
+}
+
+func TestNotifyGenerated_NoWebhook(t *testing.T) {
+	webhook, _ := fakeNotification(t, nil)
+	var stderr bytes.Buffer
+	notifyGenerated(context.Background(), &ResolvedOptions{}, hookInvoice(), "/tmp/a.pdf", &stderr)
+	if *webhook != "" || stderr.Len() != 0 {
+		t.Errorf("expected nothing posted, got %q and %q", *webhook, stderr.String())
+	}
+}
+
+func TestResolveOptions_Notify(t *testing.T) {
+	path := writeTestConfig(t, "notify_webhook: "+slackWebhook+"\nnotify_message: done\n")
+	cfg := loadTestConfig(t, path)
+	opts, err := (&GenerateCmd{}).resolveOptions(cfg, nil)
+	if err != nil {
+		t.Fatalf("resolveOptions: %v", err)
+	}
+	if opts.NotifyWebhook != slackWebhook || opts.NotifyMessage != "done" {
+		t.Errorf("got %q, %q", opts.NotifyWebhook, opts.NotifyMessage)
+	}
+	opts, err = (&GenerateCmd{NoNotify: true}).resolveOptions(cfg, nil)
+	if err != nil {
+		t.Fatalf("resolveOptions: %v", err)
+	}
+	if opts.NotifyWebhook != "" {
+		t.Errorf("--no-notify: NotifyWebhook = %q, want none", opts.NotifyWebhook)
+	}
+}
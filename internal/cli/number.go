@@ -0,0 +1,37 @@
+package cli
+
+import (
+	"reflect"
+
+	"github.com/alecthomas/kong"
+	"github.com/zon/invoicer/internal/config"
+)
+
+// Mappers returns the kong option that registers the mappers of the CLI's
+// own flag types, which kong.Parse needs to read them.
+func Mappers() kong.Option {
+	return kong.NamedMapper("number", numberMapper{})
+}
+
+// numberMapper reads the value of a float flag tagged type:"number", such
+// as --rate, with config.ParseNumber, so "$150" and "1.250,00" are read as
+// they are written in a contract.
+type numberMapper struct{}
+
+// Decode implements kong.Mapper.
+func (numberMapper) Decode(ctx *kong.DecodeContext, target reflect.Value) error {
+	var s string
+	if err := ctx.Scan.PopValueInto("number", &s); err != nil {
+		return err
+	}
+	f, err := config.ParseNumber(s)
+	if err != nil {
+		return err
+	}
+	if target.Kind() == reflect.Pointer {
+		target.Set(reflect.ValueOf(&f))
+		return nil
+	}
+	target.SetFloat(f)
+	return nil
+}
@@ -0,0 +1,177 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/zon/invoicer/internal/config"
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+// loadOverrides reads the overrides file given with --overrides, or else
+// the config.OverridesFile in the current directory. It returns nil if
+// there is none or --no-overrides is given, and with --from-file, whose
+// spec is complete.
+func (c *GenerateCmd) loadOverrides() (*config.Overrides, string, error) {
+	if c.NoOverrides || c.FromFile != "" {
+		return nil, "", nil
+	}
+	path := c.Overrides
+	if path == "" {
+		dir, err := os.Getwd()
+		if err != nil {
+			return nil, "", fmt.Errorf("determining current directory: %w", err)
+		}
+		path = filepath.Join(dir, config.OverridesFile)
+		if info, err := os.Stat(path); err != nil || info.IsDir() {
+			return nil, "", nil
+		}
+	}
+	over, err := config.LoadOverrides(path)
+	if err != nil {
+		return nil, "", err
+	}
+	return over, path, nil
+}
+
+// applyOverrides layers over, read from path, onto opts: above the config
+// but below the flags, so --notes and --po-number still win. The weeks
+// and adjustments are applied by buildInvoice. It returns a line for each
+// change, for the summary printed by printOverrides.
+func (c *GenerateCmd) applyOverrides(opts *ResolvedOptions, over *config.Overrides, path string) []string {
+	if over == nil {
+		return nil
+	}
+	opts.Overrides, opts.OverridesPath = over, path
+
+	var changes []string
+	for _, w := range over.Weeks {
+		changes = append(changes, fmt.Sprintf("week of %s: %s hours", w.Date.Format(time.DateOnly), invoice.FormatHours(w.Hours)))
+	}
+	for _, d := range over.Exclude {
+		changes = append(changes, fmt.Sprintf("week of %s: left out", d.Date.Format(time.DateOnly)))
+	}
+	switch {
+	case over.Notes == nil:
+	case c.Notes != "":
+		changes = append(changes, "notes: passed over for --notes")
+	default:
+		opts.Notes = *over.Notes
+		changes = append(changes, "notes: set")
+	}
+	switch {
+	case over.PONumber == nil:
+	case c.PONumber != "":
+		changes = append(changes, "PO number: passed over for --po-number")
+	default:
+		opts.PONumber = *over.PONumber
+		changes = append(changes, "PO number: "+opts.PONumber)
+	}
+	for _, a := range over.Adjustments {
+		changes = append(changes, fmt.Sprintf("adjustment: %s, %s", a.Description, invoice.FormatMoney(a.Amount)))
+	}
+	return changes
+}
+
+// printOverrides writes the changes made by the overrides file at path
+// to w.
+func printOverrides(w io.Writer, path string, changes []string) {
+	if len(changes) == 0 {
+		fmt.Fprintf(w, "Overrides from %s: none\n", path)
+		return
+	}
+	fmt.Fprintf(w, "Overrides from %s:\n", path)
+	for _, change := range changes {
+		fmt.Fprintf(w, "  - %s\n", change)
+	}
+}
+
+// overrideWeeks applies the week hours, excluded weeks and adjustments of
+// opts.Overrides to inv, and reports which of the weeks left have their
+// hours set by it. A date in no week of the invoice is an error naming its
+// line in the file.
+func (opts *ResolvedOptions) overrideWeeks(inv *invoice.Invoice) ([]bool, error) {
+	overridden := make([]bool, len(inv.Weeks))
+	over := opts.Overrides
+	if over == nil {
+		return overridden, nil
+	}
+	find := func(date time.Time, line int) (int, error) {
+		i := slices.IndexFunc(inv.Weeks, func(w invoice.Week) bool {
+			return !date.Before(w.Start) && !date.After(w.End)
+		})
+		if i < 0 {
+			return 0, &config.OverridesError{Path: opts.OverridesPath, Line: line, Msg: fmt.Sprintf("%s is in no week of %s %d", date.Format(time.DateOnly), inv.Month, inv.Year)}
+		}
+		return i, nil
+	}
+	for _, w := range over.Weeks {
+		i, err := find(w.Date, w.Line)
+		if err != nil {
+			return nil, err
+		}
+		inv.Weeks[i].Hours = w.Hours
+		overridden[i] = true
+	}
+	excluded := make([]bool, len(inv.Weeks))
+	for _, d := range over.Exclude {
+		i, err := find(d.Date, d.Line)
+		if err != nil {
+			return nil, err
+		}
+		excluded[i] = true
+	}
+	weeks := inv.Weeks[:0]
+	kept := overridden[:0]
+	for i, w := range inv.Weeks {
+		if !excluded[i] {
+			weeks = append(weeks, w)
+			kept = append(kept, overridden[i])
+		}
+	}
+	inv.Weeks = weeks
+	for _, a := range over.Adjustments {
+		inv.Adjustments = append(inv.Adjustments, invoice.Adjustment{Description: a.Description, Amount: a.Amount})
+	}
+	return kept, nil
+}
+
+// distributeMonthHours splits monthHours across the weeks whose hours no
+// override set, as invoice.DistributeHours does: what the overridden
+// weeks do not take is shared among the rest. If the overridden weeks
+// take more than monthHours, or set every week, the invoice bills other
+// than monthHours, which is warned about.
+func distributeMonthHours(weeks []invoice.Week, overridden []bool, monthHours float64) error {
+	rest := monthHours
+	var free []invoice.Week
+	for i, w := range weeks {
+		if overridden[i] {
+			rest -= w.Hours
+		} else {
+			free = append(free, w)
+		}
+	}
+	switch {
+	case rest < 0:
+		fmt.Fprintf(config.Warnings, "Warning: the week overrides add up to %s hours, more than the %s of --month-hours; the other weeks get none\n",
+			invoice.FormatHours(monthHours-rest), invoice.FormatHours(monthHours))
+		rest = 0
+	case rest > 0 && len(free) == 0:
+		fmt.Fprintf(config.Warnings, "Warning: the week overrides set every week, adding up to %s hours rather than the %s of --month-hours\n",
+			invoice.FormatHours(monthHours-rest), invoice.FormatHours(monthHours))
+		rest = 0
+	}
+	if err := invoice.DistributeHours(free, rest); err != nil {
+		return err
+	}
+	for i := range weeks {
+		if !overridden[i] {
+			weeks[i].Hours, free = free[0].Hours, free[1:]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zon/invoicer/internal/config"
+)
+
+// chdirOverrides changes into a new directory containing an overrides
+// file with the given content.
+func chdirOverrides(t *testing.T, content string) string {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, config.OverridesFile)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(dir)
+	return path
+}
+
+// resolveWithOverrides resolves the options of args with the overrides
+// file in the current directory and builds the invoice.
+func resolveWithOverrides(t *testing.T, args ...string) (*ResolvedOptions, []string, error) {
+	t.Helper()
+	cmd := parseCLI(t, append([]string{"--vendor=Jane", "--customer=Acme", "--rate=100", "--hours=40", "january", "2025"}, args...)...)
+	c := &cmd.Generate
+	over, path, err := c.loadOverrides()
+	if err != nil {
+		return nil, nil, err
+	}
+	opts, err := c.resolveOptions(&config.Config{}, nil)
+	if err != nil {
+		t.Fatalf("resolveOptions: %v", err)
+	}
+	return opts, c.applyOverrides(opts, over, path), nil
+}
+
+func TestOverrides_FlagBeatsFile(t *testing.T) {
+	chdirOverrides(t, "po_number: PO-FILE\nnotes: From the file\n")
+
+	opts, changes, err := resolveWithOverrides(t, "--po-number=PO-FLAG")
+	if err != nil {
+		t.Fatalf("loadOverrides: %v", err)
+	}
+	if opts.PONumber != "PO-FLAG" {
+		t.Errorf("PONumber = %q, want the flag's PO-FLAG", opts.PONumber)
+	}
+	if opts.Notes != "From the file" {
+		t.Errorf("Notes = %q, want the file's", opts.Notes)
+	}
+	want := []string{"notes: set", "PO number: passed over for --po-number"}
+	if strings.Join(changes, "\n") != strings.Join(want, "\n") {
+		t.Errorf("changes = %q, want %q", changes, want)
+	}
+}
+
+func TestOverrides_FileBeatsConfig(t *testing.T) {
+	chdirOverrides(t, "weeks:\n  2025-01-08: 10\nexclude: [2025-01-31]\nadjustments:\n  - description: Travel\n    amount: 80\n")
+
+	opts, _, err := resolveWithOverrides(t)
+	if err != nil {
+		t.Fatalf("loadOverrides: %v", err)
+	}
+	inv, err := opts.buildInvoice()
+	if err != nil {
+		t.Fatalf("buildInvoice: %v", err)
+	}
+	// January 2025 has five weeks; the last, Jan 27-31, is left out.
+	if len(inv.Weeks) != 4 {
+		t.Fatalf("expected 4 weeks, got %+v", inv.Weeks)
+	}
+	if inv.Weeks[1].Hours != 10 {
+		t.Errorf("week of Jan 6: hours = %v, want 10", inv.Weeks[1].Hours)
+	}
+	if len(inv.Adjustments) != 1 || inv.Adjustments[0].Amount != 80 {
+		t.Errorf("Adjustments = %+v", inv.Adjustments)
+	}
+}
+
+func TestOverrides_OnlyNotes(t *testing.T) {
+	chdirOverrides(t, "notes: Thanks!\n")
+
+	opts, changes, err := resolveWithOverrides(t)
+	if err != nil {
+		t.Fatalf("loadOverrides: %v", err)
+	}
+	inv, err := opts.buildInvoice()
+	if err != nil {
+		t.Fatalf("buildInvoice: %v", err)
+	}
+	if inv.Notes != "Thanks!" || inv.PONumber != "" || len(inv.Adjustments) != 0 || len(inv.Weeks) != 5 {
+		t.Errorf("only the notes should change, got %+v", inv)
+	}
+	var out bytes.Buffer
+	printOverrides(&out, "invoice.yaml", changes)
+	if got, want := out.String(), "Overrides from invoice.yaml:\n  - notes: set\n"; got != want {
+		t.Errorf("summary = %q, want %q", got, want)
+	}
+}
+
+func TestOverrides_DateOutsideMonth(t *testing.T) {
+	path := chdirOverrides(t, "exclude:\n  - 2025-02-03\n")
+
+	opts, _, err := resolveWithOverrides(t)
+	if err != nil {
+		t.Fatalf("loadOverrides: %v", err)
+	}
+	_, err = opts.buildInvoice()
+	if err == nil || !strings.Contains(err.Error(), path+":2: 2025-02-03 is in no week of January 2025") {
+		t.Errorf("expected an error naming %s:2, got %v", path, err)
+	}
+}
+
+func TestOverrides_SchemaError(t *testing.T) {
+	path := chdirOverrides(t, "notes: ok\npo_nummer: PO-1\n")
+
+	_, _, err := resolveWithOverrides(t)
+	if err == nil || !strings.Contains(err.Error(), path+`:2: unknown overrides key "po_nummer"`) {
+		t.Errorf("expected an error naming %s:2, got %v", path, err)
+	}
+}
+
+func TestOverrides_NoOverrides(t *testing.T) {
+	chdirOverrides(t, "po_number: PO-FILE\n")
+
+	opts, changes, err := resolveWithOverrides(t, "--no-overrides")
+	if err != nil {
+		t.Fatalf("loadOverrides: %v", err)
+	}
+	if opts.PONumber != "" || changes != nil {
+		t.Errorf("--no-overrides should skip the file, got PONumber %q, changes %q", opts.PONumber, changes)
+	}
+}
+
+func TestOverrides_Path(t *testing.T) {
+	t.Chdir(t.TempDir())
+	path := filepath.Join(t.TempDir(), "one-off.yaml")
+	if err := os.WriteFile(path, []byte("po_number: PO-7\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	opts, _, err := resolveWithOverrides(t, "--overrides", path)
+	if err != nil {
+		t.Fatalf("loadOverrides: %v", err)
+	}
+	if opts.PONumber != "PO-7" {
+		t.Errorf("PONumber = %q, want PO-7 from --overrides", opts.PONumber)
+	}
+}
+
+func TestGenerateRun_Overrides(t *testing.T) {
+	chdirOverrides(t, "po_number: PO-42\n")
+	out := filepath.Join(t.TempDir(), "invoice.json")
+	g := &Globals{Config: writeTestConfig(t, ""), NoLocal: true}
+	cmd := parseCLI(t, "--vendor=Jane", "--customer=Acme", "--rate=100", "--hours=40", "--export-json="+out, "january", "2025")
+	if err := cmd.Generate.Run(g, context.Background()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got struct {
+		PONumber string `json:"po_number"`
+	}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.PONumber != "PO-42" {
+		t.Errorf("po_number = %q, want PO-42", got.PONumber)
+	}
+}
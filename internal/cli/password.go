@@ -0,0 +1,76 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/alecthomas/kong"
+	"golang.org/x/term"
+)
+
+// pdfPasswordFlag is the value of --pdf-password. Like a bool flag it needs
+// no value, in which case the password is asked for, but
+// --pdf-password=PASSWORD gives it.
+type pdfPasswordFlag struct {
+	Set      bool
+	Password secret
+}
+
+// Decode implements kong.MapperValue.
+func (f *pdfPasswordFlag) Decode(ctx *kong.DecodeContext) error {
+	f.Set = true
+	if ctx.Scan.Peek().Type == kong.FlagValueToken {
+		f.Password = secret(ctx.Scan.Pop().String())
+	}
+	return nil
+}
+
+// IsBool implements kong.BoolMapperValue so the flag takes no separate argument.
+func (f *pdfPasswordFlag) IsBool() bool { return true }
+
+// secret is a string that is never shown: it formats as [redacted], so
+// printing the options that hold it cannot leak it.
+type secret string
+
+func (s secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return "[redacted]"
+}
+
+func (s secret) GoString() string { return fmt.Sprintf("%q", s.String()) }
+
+// promptPassword asks for the PDF password on the terminal in, without
+// echoing it, and asks again to confirm it.
+func promptPassword(in *os.File, out io.Writer) (secret, error) {
+	if !term.IsTerminal(int(in.Fd())) {
+		return "", errors.New("--pdf-password needs a value when not run in a terminal (use --pdf-password=PASSWORD or set INVOICER_PDF_PASSWORD)")
+	}
+	read := func(prompt string) (string, error) {
+		fmt.Fprint(out, prompt)
+		b, err := term.ReadPassword(int(in.Fd()))
+		fmt.Fprintln(out)
+		if err != nil {
+			return "", fmt.Errorf("reading the PDF password: %w", err)
+		}
+		return string(b), nil
+	}
+	password, err := read("PDF password: ")
+	if err != nil {
+		return "", err
+	}
+	if password == "" {
+		return "", errors.New("the PDF password is empty")
+	}
+	again, err := read("Repeat the PDF password: ")
+	if err != nil {
+		return "", err
+	}
+	if again != password {
+		return "", errors.New("the PDF passwords do not match")
+	}
+	return secret(password), nil
+}
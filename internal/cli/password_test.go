@@ -0,0 +1,141 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zon/invoicer/internal/config"
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+const testPassword = "correct horse battery"
+
+func TestResolveOptions_PDFPassword(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		env     string
+		want    secret
+		wantSet bool
+		wantErr string
+	}{
+		{"unset", []string{"--pdf"}, "", "", false, ""},
+		{"flag value", []string{"--pdf", "--pdf-password=" + testPassword}, "", testPassword, true, ""},
+		{"env", []string{"--pdf"}, testPassword, testPassword, true, ""},
+		{"no value asks", []string{"--pdf", "--pdf-password"}, "", "", true, ""},
+		{"needs --pdf", []string{"--pdf-password=" + testPassword}, "", "", false, "--pdf-password needs --pdf"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.env != "" {
+				t.Setenv("INVOICER_PDF_PASSWORD", tt.env)
+			}
+			path := writeTestConfig(t, "")
+			cmd := parseCLI(t, tt.args...)
+			opts, err := cmd.Generate.resolveOptions(loadTestConfig(t, path), nil)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("expected error %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveOptions: %v", err)
+			}
+			if opts.PDFPassword != tt.want || cmd.Generate.PDFPassword.Set != tt.wantSet {
+				t.Errorf("PDFPassword = %q (set %v), want %q (set %v)", string(opts.PDFPassword), cmd.Generate.PDFPassword.Set, string(tt.want), tt.wantSet)
+			}
+			for _, verb := range []string{"%v", "%+v", "%#v", "%s", "%q"} {
+				if s := fmt.Sprintf(verb, opts); strings.Contains(s, testPassword) {
+					t.Errorf("%s of the options shows the password: %s", verb, s)
+				}
+			}
+		})
+	}
+}
+
+func TestGenerateRun_PDFPasswordNeedsEncrypter(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	t.Chdir(t.TempDir())
+	path := writeTestConfig(t, "vendor: Jane\ncustomer: Acme Corp\nrate: 100\nhours: 40\n")
+	cmd := parseCLI(t, "--config", path, "--no-local", "january", "2025", "--pdf", "--pdf-password="+testPassword)
+
+	err := cmd.Generate.Run(&cmd.Globals, context.Background())
+	var missing *invoice.NotInstalledError
+	if !errors.As(err, &missing) || missing.Tool != "qpdf" {
+		t.Fatalf("expected qpdf to be reported missing, got %v", err)
+	}
+	if files, _ := filepath.Glob("*"); len(files) > 0 {
+		t.Errorf("nothing should be generated without an encryption tool, found %v", files)
+	}
+}
+
+func TestWritePDF_Encrypts(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses shell scripts as a fake wkhtmltopdf and qpdf")
+	}
+	tests := []struct {
+		name    string
+		qpdf    string
+		want    string
+		wantErr string
+	}{
+		{"encrypted", "#!/bin/sh\nwhile IFS= read -r line; do out=$line; done < \"${1#@}\"\nprintf '%%PDF-1.4\\n%%%%EOF\\n' > \"$out\"\n", "wkhtmltopdf, encrypted with qpdf", ""},
+		{"qpdf fails", "#!/bin/sh\nwhile IFS= read -r line; do echo \"qpdf: bad option $line\" >&2; done < \"${1#@}\"\nexit 2\n", "", "encrypting the PDF with qpdf"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bin := t.TempDir()
+			for name, script := range map[string]string{
+				"wkhtmltopdf": "#!/bin/sh\nprintf '%%PDF-1.4\\n%%%%EOF\\n' > \"$2\"\n",
+				"qpdf":        tt.qpdf,
+			} {
+				if err := os.WriteFile(filepath.Join(bin, name), []byte(script), 0o755); err != nil {
+					t.Fatal(err)
+				}
+			}
+			t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+			old := config.Warnings
+			config.Warnings = io.Discard
+			t.Cleanup(func() { config.Warnings = old })
+			dir := t.TempDir()
+			htmlPath := filepath.Join(dir, "invoice.html")
+			pdfPath := filepath.Join(dir, "invoice.pdf")
+			if err := os.WriteFile(htmlPath, []byte("<html></html>"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			opts := &ResolvedOptions{PDF: true, PDFOnly: true, PDFPassword: testPassword, PDFTimeout: time.Minute, PDFTitle: invoice.DefaultPDFTitle}
+			res, err := writePDF(context.Background(), opts, testTimeoutInvoice(), htmlPath, pdfPath)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) || !strings.Contains(err.Error(), "the HTML invoice is kept at") {
+					t.Fatalf("expected error %q, got %v", tt.wantErr, err)
+				}
+				if strings.Contains(err.Error(), testPassword) {
+					t.Errorf("the error shows the password: %v", err)
+				}
+				if _, err := os.Stat(pdfPath); !os.IsNotExist(err) {
+					t.Errorf("an unencrypted PDF should not be left behind, stat err = %v", err)
+				}
+				if _, err := os.Stat(htmlPath); err != nil {
+					t.Errorf("the HTML should be kept: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("writePDF: %v", err)
+			}
+			if got := res.String(); got != tt.want {
+				t.Errorf("result = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+// newPayPalClient returns the client PayPal invoices are created with.
+// It can be overridden in tests.
+var newPayPalClient = func(opts *ResolvedOptions) *invoice.PayPalClient {
+	c := &invoice.PayPalClient{ClientID: opts.PayPalClientID, Secret: opts.PayPalClientSecret}
+	if opts.PayPalSandbox {
+		c.BaseURL = invoice.PayPalSandboxURL
+	}
+	return c
+}
+
+// sendPayPalInvoice creates the PayPal invoice for inv that opts describe,
+// sends it if opts.Finalize is set, and reports it on w. An invoice that
+// was created but not sent is still reported, so it can be found.
+func sendPayPalInvoice(ctx context.Context, opts *ResolvedOptions, inv *invoice.Invoice, w io.Writer) error {
+	created, err := invoice.SendPayPal(ctx, newPayPalClient(opts), inv, invoice.PayPalOptions{
+		CustomerEmail: opts.PayPalCustomerEmail,
+		PaymentTerm:   opts.PayPalPaymentTerm,
+		Send:          opts.Finalize,
+	})
+	if created != nil {
+		status := "draft"
+		if created.Sent {
+			status = "sent to " + opts.PayPalCustomerEmail
+		}
+		fmt.Fprintf(w, "PayPal invoice created: %s (%s)\n", created.ID, status)
+		if created.PayerURL != "" {
+			fmt.Fprintf(w, "Payment page: %s\n", created.PayerURL)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("creating the PayPal invoice: %w", err)
+	}
+	return nil
+}
+
+// sendPaymentInvoices creates the Stripe and PayPal invoices for inv that
+// opts ask for, and reports them on w.
+func sendPaymentInvoices(ctx context.Context, opts *ResolvedOptions, inv *invoice.Invoice, w io.Writer) error {
+	if opts.SendStripe {
+		if err := sendStripeInvoice(ctx, opts, inv, w); err != nil {
+			return err
+		}
+	}
+	if opts.SendPayPal {
+		if err := sendPayPalInvoice(ctx, opts, inv, w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
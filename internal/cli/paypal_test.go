@@ -0,0 +1,120 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+func TestResolveOptions_PayPal(t *testing.T) {
+	creds := "paypal_client_id: id\npaypal_client_secret: secret\n"
+	tests := []struct {
+		name    string
+		args    []string
+		config  string
+		wantErr string
+	}{
+		{"off", nil, "", ""},
+		{"config", []string{"--send-paypal"}, creds + "paypal_customer_email: ap@acme.example\npaypal_sandbox: true\npaypal_payment_term: NET_15\n", ""},
+		{"flags", []string{"--paypal-only", "--finalize", "--paypal-client-id=id", "--paypal-client-secret=secret", "--paypal-customer-email=ap@acme.example"}, "", ""},
+		{"no secret", []string{"--send-paypal", "--paypal-client-id=id"}, "paypal_customer_email: ap@acme.example\n", "PayPal client credentials are required"},
+		{"no email", []string{"--send-paypal"}, creds, "customer's email is required for --send-paypal"},
+		{"bad term", []string{"--send-paypal"}, creds + "paypal_customer_email: ap@acme.example\npaypal_payment_term: NET_7\n", `payment term "NET_7"`},
+		{"with stripe only", []string{"--paypal-only", "--stripe-only"}, "", "cannot be combined with"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := parseCLI(t, tt.args...)
+			opts, err := cmd.Generate.resolveOptions(loadTestConfig(t, writeTestConfig(t, tt.config)), nil)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("expected error %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveOptions: %v", err)
+			}
+			if opts.SendPayPal != (len(tt.args) > 0) {
+				t.Errorf("SendPayPal = %v", opts.SendPayPal)
+			}
+			if tt.name == "config" && (!opts.PayPalSandbox || opts.PayPalPaymentTerm != "NET_15" || opts.PayPalClientSecret != "secret") {
+				t.Errorf("options from config = %+v", opts)
+			}
+			if tt.name == "flags" && (!opts.Finalize || opts.PayPalCustomerEmail != "ap@acme.example") {
+				t.Errorf("options from flags = %+v", opts)
+			}
+		})
+	}
+}
+
+// fakePayPal points newPayPalClient at a fake PayPal API for the test,
+// which fails sending with status send if it is not 0.
+func fakePayPal(t *testing.T, send int) {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/oauth2/token":
+			fmt.Fprint(w, `{"access_token":"token","token_type":"Bearer"}`)
+		case "/v2/invoicing/invoices":
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprint(w, `{"id":"INV2-TEST","status":"DRAFT"}`)
+		case "/v2/invoicing/invoices/INV2-TEST/send":
+			if send != 0 {
+				w.WriteHeader(send)
+				fmt.Fprint(w, `{"name":"UNPROCESSABLE_ENTITY","message":"The requested action could not be performed."}`)
+				return
+			}
+			fmt.Fprint(w, `{"href":"https://www.paypal.com/invoice/p/#TEST"}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	old := newPayPalClient
+	newPayPalClient = func(opts *ResolvedOptions) *invoice.PayPalClient {
+		return &invoice.PayPalClient{ClientID: opts.PayPalClientID, Secret: opts.PayPalClientSecret, BaseURL: srv.URL}
+	}
+	t.Cleanup(func() { newPayPalClient = old })
+}
+
+func TestSendPayPalInvoice(t *testing.T) {
+	fakePayPal(t, 0)
+	opts := &ResolvedOptions{PayPalClientID: "id", PayPalClientSecret: "secret", PayPalCustomerEmail: "ap@acme.example", Finalize: true}
+	var out bytes.Buffer
+	if err := sendPayPalInvoice(context.Background(), opts, testTimeoutInvoice(), &out); err != nil {
+		t.Fatalf("sendPayPalInvoice: %v", err)
+	}
+	want := "PayPal invoice created: INV2-TEST (sent to ap@acme.example)\nPayment page: https://www.paypal.com/invoice/p/#TEST\n"
+	if out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+}
+
+func TestSendPayPalInvoice_SendFailureReportsDraft(t *testing.T) {
+	fakePayPal(t, http.StatusUnprocessableEntity)
+	opts := &ResolvedOptions{PayPalClientID: "id", PayPalClientSecret: "secret", PayPalCustomerEmail: "ap@acme.example", Finalize: true}
+	var out bytes.Buffer
+	err := sendPayPalInvoice(context.Background(), opts, testTimeoutInvoice(), &out)
+	if err == nil || !strings.Contains(err.Error(), "creating the PayPal invoice: PayPal sending failed") {
+		t.Errorf("expected a sending error, got %v", err)
+	}
+	if want := "PayPal invoice created: INV2-TEST (draft)\n"; out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+}
+
+func TestNewPayPalClient_Sandbox(t *testing.T) {
+	if c := newPayPalClient(&ResolvedOptions{PayPalSandbox: true}); c.BaseURL != invoice.PayPalSandboxURL {
+		t.Errorf("sandbox BaseURL = %q", c.BaseURL)
+	}
+	if c := newPayPalClient(&ResolvedOptions{}); c.BaseURL != "" {
+		t.Errorf("live BaseURL = %q, want the default", c.BaseURL)
+	}
+}
@@ -0,0 +1,161 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/kong"
+)
+
+// profileHome makes a temporary home whose default config, under
+// ~/.invoicer, is for Main Co, with a sideco profile for Side Co, and
+// returns the config directory.
+func profileHome(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	root := filepath.Join(home, ".invoicer")
+	for path, content := range map[string]string{
+		filepath.Join(root, "config.yaml"):                       "vendor: Main Co\n",
+		filepath.Join(root, "profiles", "sideco", "config.yaml"): "vendor: Side Co\n",
+	} {
+		if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+// runCLI parses and runs args as invoicer would.
+func runCLI(t *testing.T, args ...string) error {
+	t.Helper()
+	var cmd CLI
+	p, err := kong.New(&cmd, kong.Name("invoicer"), kong.Exit(func(int) {}), Mappers())
+	if err != nil {
+		t.Fatalf("kong.New failed: %v", err)
+	}
+	kctx, err := p.Parse(args)
+	if err != nil {
+		return err
+	}
+	return kctx.Run(&cmd.Globals)
+}
+
+func TestProfile_Selection(t *testing.T) {
+	root := profileHome(t)
+	sideco := filepath.Join(root, "profiles", "sideco", "config.yaml")
+	tests := []struct {
+		name string
+		env  string
+		args []string
+		want string
+	}{
+		{"default", "", nil, filepath.Join(root, "config.yaml")},
+		{"flag", "", []string{"--profile", "sideco"}, sideco},
+		{"env", "sideco", nil, sideco},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("INVOICER_PROFILE", tt.env)
+			cmd := parseCLI(t, append(tt.args, "january")...)
+			path, err := cmd.Globals.configPath()
+			if err != nil {
+				t.Fatalf("configPath: %v", err)
+			}
+			if path != tt.want {
+				t.Errorf("configPath = %q, want %q", path, tt.want)
+			}
+		})
+	}
+}
+
+func TestProfile_SetConfigIsolated(t *testing.T) {
+	root := profileHome(t)
+	t.Setenv("INVOICER_PROFILE", "")
+
+	if err := runCLI(t, "--profile", "newco", "set", "config", "--vendor", "New Co", "--rate", "90"); err != nil {
+		t.Fatalf("set config: %v", err)
+	}
+	if err := runCLI(t, "--profile", "sideco", "set", "config", "--rate", "120"); err != nil {
+		t.Fatalf("set config: %v", err)
+	}
+
+	for path, want := range map[string]string{
+		filepath.Join(root, "config.yaml"):                       "vendor: Main Co\n",
+		filepath.Join(root, "profiles", "sideco", "config.yaml"): "vendor: Side Co\nrate: 120\n",
+		filepath.Join(root, "profiles", "newco", "config.yaml"):  "vendor: New Co\nrate: 90\n",
+	} {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != want {
+			t.Errorf("%s:\n%s\nwant:\n%s", path, data, want)
+		}
+	}
+}
+
+func TestProfile_ShowConfig(t *testing.T) {
+	profileHome(t)
+	t.Setenv("INVOICER_PROFILE", "sideco")
+	t.Chdir(t.TempDir())
+
+	out := captureStdout(t, func() {
+		if err := runCLI(t, "--no-local", "show", "config", "--format", "yaml"); err != nil {
+			t.Errorf("show config: %v", err)
+		}
+	})
+	if !strings.Contains(out, "vendor: Side Co") {
+		t.Errorf("show config should show the profile's config:\n%s", out)
+	}
+}
+
+func TestProfile_Unknown(t *testing.T) {
+	profileHome(t)
+	t.Setenv("INVOICER_PROFILE", "")
+	cmd := parseCLI(t, "--profile", "other", "january")
+	_, err := cmd.Globals.configPath()
+	if err == nil || err.Error() != `unknown profile "other" (available: sideco)` {
+		t.Errorf("got %v, want an error listing the profiles", err)
+	}
+}
+
+func TestProfile_Invalid(t *testing.T) {
+	profileHome(t)
+	t.Setenv("INVOICER_PROFILE", "")
+	for _, args := range [][]string{
+		{"--profile", "../escape", "january"},
+		{"--profile", "sideco", "--config", "/tmp/config.yaml", "january"},
+	} {
+		if err := runCLI(t, args...); err == nil {
+			t.Errorf("%v: expected an error", args)
+		}
+	}
+}
+
+// captureStdout returns what f writes to os.Stdout.
+func captureStdout(t *testing.T, f func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = old }()
+	done := make(chan string)
+	go func() {
+		var buf bytes.Buffer
+		buf.ReadFrom(r)
+		done <- buf.String()
+	}()
+	f()
+	w.Close()
+	return <-done
+}
@@ -0,0 +1,52 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+// progressOutput, if set, receives a line for each step of the generation
+// and the PDF conversion. --verbose sets it to stderr.
+var progressOutput io.Writer
+
+// reportProgress returns the Progress callback that writes to
+// progressOutput, or nil if it is not set.
+func reportProgress() func(invoice.ProgressEvent) {
+	if progressOutput == nil {
+		return nil
+	}
+	w := progressOutput
+	return func(e invoice.ProgressEvent) {
+		fmt.Fprintln(w, describeProgress(e))
+	}
+}
+
+// describeProgress returns a line describing e, e.g.
+// "opencode: write invoice-acme-corp-2025-01.html".
+func describeProgress(e invoice.ProgressEvent) string {
+	switch e.Kind {
+	case invoice.ProgressPromptBuilt:
+		return fmt.Sprintf("%s: attempt %d with %s: prompt built", e.Backend, e.Attempt, e.Model)
+	case invoice.ProgressStarted:
+		return fmt.Sprintf("%s: started", e.Backend)
+	case invoice.ProgressToolUse:
+		if e.Path == "" {
+			return fmt.Sprintf("%s: %s", e.Backend, e.Tool)
+		}
+		return fmt.Sprintf("%s: %s %s", e.Backend, e.Tool, e.Path)
+	case invoice.ProgressVerified:
+		return fmt.Sprintf("%s: verified %s", e.Backend, e.Path)
+	case invoice.ProgressFailed:
+		return fmt.Sprintf("%s: attempt %d failed: %v", e.Backend, e.Attempt, e.Err)
+	case invoice.ProgressPDFStarted:
+		return fmt.Sprintf("pdf: converting to %s", e.Path)
+	case invoice.ProgressPDFFinished:
+		if e.Err != nil {
+			return fmt.Sprintf("pdf: %s failed: %v", e.Tool, e.Err)
+		}
+		return fmt.Sprintf("pdf: %s wrote %s", e.Tool, e.Path)
+	}
+	return string(e.Kind)
+}
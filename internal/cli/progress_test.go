@@ -0,0 +1,64 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+func TestGenerateHTML_VerboseProgress(t *testing.T) {
+	orig, origSleep := invoice.OpencodeExec, invoice.Sleep
+	t.Cleanup(func() { invoice.OpencodeExec, invoice.Sleep = orig, origSleep })
+	invoice.Sleep = func(ctx context.Context, d time.Duration) error { return nil }
+	invoice.OpencodeExec = func(ctx context.Context, r invoice.OpencodeRun, stdout io.Writer) error {
+		if r.Model == "anthropic/claude-haiku-4-5" {
+			return fmt.Errorf("model overloaded")
+		}
+		_, path, _ := strings.Cut(r.Prompt, "to the file: ")
+		path, _, _ = strings.Cut(path, "\n")
+		if err := os.WriteFile(path, []byte("<html>ok</html>"), 0o644); err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, `{"type":"tool_use","part":{"tool":"write","state":{"status":"completed","input":{"filePath":%q}}}}`+"\n", path)
+		return nil
+	}
+	var out bytes.Buffer
+	progressOutput = &out
+	t.Cleanup(func() { progressOutput = nil })
+
+	opts := &ResolvedOptions{
+		Backend: "opencode",
+		Model:   "anthropic/claude-haiku-4-5, anthropic/claude-sonnet-4-6",
+		Timeout: time.Minute,
+	}
+	htmlPath := filepath.Join(t.TempDir(), "invoice.html")
+	if _, err := generateHTML(context.Background(), opts, testTimeoutInvoice(), htmlPath); err != nil {
+		t.Fatalf("generateHTML: %v", err)
+	}
+	want := strings.Join([]string{
+		"opencode: attempt 1 with anthropic/claude-haiku-4-5: prompt built",
+		"opencode: started",
+		"opencode: attempt 1 failed: running opencode: model overloaded",
+		"opencode: attempt 2 with anthropic/claude-sonnet-4-6: prompt built",
+		"opencode: started",
+		"opencode: write " + htmlPath,
+		"opencode: verified " + htmlPath,
+	}, "\n") + "\n"
+	if out.String() != want {
+		t.Errorf("progress:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+func TestGenerateHTML_QuietWithoutVerbose(t *testing.T) {
+	if reportProgress() != nil {
+		t.Error("progress should not be reported without --verbose")
+	}
+}
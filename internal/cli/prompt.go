@@ -0,0 +1,42 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/alecthomas/kong"
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+// optionalPathFlag is the value of a flag such as --prompt-only. Like a bool
+// flag it needs no value, but --prompt-only=PATH names the file to write.
+type optionalPathFlag struct {
+	Set  bool
+	Path string
+}
+
+// Decode implements kong.MapperValue.
+func (f *optionalPathFlag) Decode(ctx *kong.DecodeContext) error {
+	f.Set = true
+	if ctx.Scan.Peek().Type == kong.FlagValueToken {
+		f.Path = ctx.Scan.Pop().String()
+	}
+	return nil
+}
+
+// IsBool implements kong.BoolMapperValue so the flag takes no separate argument.
+func (f *optionalPathFlag) IsBool() bool { return true }
+
+// writePromptFile writes the prompt that would generate the invoice at
+// htmlPath to path, headed by comments naming the file the invoice belongs
+// in, and reports where it went on w.
+func writePromptFile(inv *invoice.Invoice, htmlPath, path string, w io.Writer) error {
+	content := fmt.Sprintf("# invoicer prompt for invoice %s\n# Write the HTML invoice to: %s\n\n%s",
+		invoice.InvoiceNumber(inv), htmlPath, invoice.BuildPrompt(inv, htmlPath))
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		return fmt.Errorf("writing prompt: %w", err)
+	}
+	fmt.Fprintf(w, "Prompt written to: %s\n", path)
+	return nil
+}
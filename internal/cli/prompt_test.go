@@ -0,0 +1,80 @@
+package cli
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+func TestPromptOnlyFlag(t *testing.T) {
+	tests := []struct {
+		args     []string
+		wantSet  bool
+		wantPath string
+	}{
+		{nil, false, ""},
+		{[]string{"--prompt-only"}, true, ""},
+		{[]string{"--prompt-only=out/prompt.txt"}, true, "out/prompt.txt"},
+		{[]string{"--prompt-only", "january"}, true, ""},
+	}
+	for _, tt := range tests {
+		cmd := parseCLI(t, tt.args...)
+		if got := cmd.Generate.PromptOnly; got.Set != tt.wantSet || got.Path != tt.wantPath {
+			t.Errorf("%v: PromptOnly = %+v, want set %v path %q", tt.args, got, tt.wantSet, tt.wantPath)
+		}
+	}
+}
+
+// runPromptOnly runs generate with args in a temporary directory, failing the test if a backend is started.
+func runPromptOnly(t *testing.T, args ...string) string {
+	t.Helper()
+	orig := invoice.OpencodeExec
+	t.Cleanup(func() { invoice.OpencodeExec = orig })
+	invoice.OpencodeExec = func(ctx context.Context, r invoice.OpencodeRun, stdout io.Writer) error {
+		t.Error("opencode should not run with --r.Prompt-only")
+		return nil
+	}
+
+	dir := t.TempDir()
+	t.Chdir(dir)
+	path := writeTestConfig(t, "vendor: Jane\ncustomer: Acme Corp\nrate: 100\nhours: 40\n")
+	cmd := parseCLI(t, append([]string{"--config", path}, args...)...)
+	if err := cmd.Generate.Run(&cmd.Globals, context.Background()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	return dir
+}
+
+func TestGenerateRun_PromptOnlyDefaultPath(t *testing.T) {
+	dir := runPromptOnly(t, "--prompt-only", "january", "2025")
+
+	data, err := os.ReadFile(filepath.Join(dir, "invoice-acme-corp-2025-01.prompt.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	htmlPath := filepath.Join(dir, "invoice-acme-corp-2025-01.html")
+	if !strings.HasPrefix(string(data), "# invoicer prompt for invoice ") || !strings.Contains(string(data), "# Write the HTML invoice to: "+htmlPath+"\n") {
+		t.Errorf("missing header naming %s:\n%s", htmlPath, data)
+	}
+	if !strings.Contains(string(data), "to the file: "+htmlPath+"\n") {
+		t.Errorf("prompt does not name the invoice path:\n%s", data)
+	}
+	if _, err := os.Stat(htmlPath); !os.IsNotExist(err) {
+		t.Errorf("no invoice should be generated, stat err = %v", err)
+	}
+}
+
+func TestGenerateRun_PromptOnlyExplicitPath(t *testing.T) {
+	dir := runPromptOnly(t, "--prompt-only=chat.txt", "january", "2025")
+	if _, err := os.Stat(filepath.Join(dir, "chat.txt")); err != nil {
+		t.Errorf("prompt not written to the given path: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "invoice-acme-corp-2025-01.prompt.txt")); !os.IsNotExist(err) {
+		t.Errorf("the default prompt file should not be written, stat err = %v", err)
+	}
+}
@@ -0,0 +1,195 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+// SendCmd is the 'send' subcommand.
+type SendCmd struct {
+	// Month is the month of the invoice. Defaults to previous month.
+	Month string `arg:"" optional:"" predictor:"month" help:"Month of the invoice (e.g. 'january', 'jan', or '1'). Defaults to previous month."`
+
+	// Year is the year of the month. Defaults to the year closest to the given month.
+	Year int `arg:"" optional:"" help:"Year of the month. Defaults to the year closest to the given month."`
+
+	// Customer is the client the invoice is for.
+	Customer string `short:"c" env:"INVOICER_CUSTOMER" predictor:"customer" help:"Name of the client the invoice is for. Defaults to customer: in the config."`
+
+	// To overrides the recipients.
+	To string `help:"Comma-separated recipients, overriding email_to: in the config."`
+
+	// AttachHTML attaches the HTML invoice too.
+	AttachHTML bool `name:"attach-html" help:"Attach the HTML invoice as well as the PDF, if it was kept."`
+
+	// SMTPPassword is the password of the SMTP user.
+	SMTPPassword string `name:"smtp-password" env:"INVOICER_SMTP_PASSWORD" help:"Password of the SMTP user. Defaults to smtp_password: in the config."`
+
+	// GmailToken and SendGridAPIKey are the credentials of the email APIs.
+	GmailToken     string `name:"gmail-token" env:"INVOICER_GMAIL_TOKEN" help:"Gmail OAuth access token, for the gmail email provider. Defaults to gmail_token: in the config."`
+	SendGridAPIKey string `name:"sendgrid-api-key" env:"INVOICER_SENDGRID_API_KEY" help:"SendGrid API key, for the sendgrid email provider. Defaults to sendgrid_api_key: in the config."`
+}
+
+// Run executes the 'send' subcommand.
+func (c *SendCmd) Run(g *Globals, ctx context.Context) error {
+	opts, err := resolveCommandOptions(g, &GenerateCmd{
+		Month:          c.Month,
+		Year:           c.Year,
+		Customer:       c.Customer,
+		SMTPPassword:   c.SMTPPassword,
+		GmailToken:     c.GmailToken,
+		SendGridAPIKey: c.SendGridAPIKey,
+	})
+	if err != nil {
+		return err
+	}
+	if c.To != "" {
+		opts.EmailTo = c.To
+	}
+	opts.AttachHTML = c.AttachHTML
+	if err := opts.checkEmail(); err != nil {
+		return err
+	}
+	inv, err := opts.buildInvoice()
+	if err != nil {
+		return err
+	}
+	dir := invoice.CurrentDir()
+	htmlPath, pdfPath := invoice.InvoiceFilePath(inv, dir), invoice.PDFFilePath(inv, dir)
+	if err := ensurePDF(ctx, opts, inv, htmlPath, pdfPath, os.Stdout); err != nil {
+		return err
+	}
+	return sendInvoiceEmail(ctx, opts, inv, pdfPath, htmlPath, os.Stdout)
+}
+
+// ensurePDF generates the PDF invoice at pdfPath if there is none yet,
+// from the HTML invoice at htmlPath, which is generated first if it is
+// missing too.
+func ensurePDF(ctx context.Context, opts *ResolvedOptions, inv *invoice.Invoice, htmlPath, pdfPath string, w io.Writer) error {
+	if _, err := os.Stat(pdfPath); !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if _, err := os.Stat(htmlPath); errors.Is(err, fs.ErrNotExist) {
+		fmt.Fprintf(w, "Generating invoice for %s %d...\n", inv.Month, inv.Year)
+		start := time.Now()
+		res, err := generateHTML(ctx, opts, inv, htmlPath)
+		if err != nil {
+			return fmt.Errorf("generating HTML invoice: %w", err)
+		}
+		fmt.Fprintf(w, "HTML invoice written to: %s\n", htmlPath)
+		fmt.Fprintln(w, generationSummary(res, time.Since(start)))
+	}
+	fmt.Fprintf(w, "Converting to PDF...\n")
+	res, err := writePDF(ctx, opts, inv, htmlPath, pdfPath)
+	if err != nil {
+		return fmt.Errorf("converting to PDF: %w", err)
+	}
+	fmt.Fprintf(w, "PDF invoice written to: %s (%s)\n", pdfPath, res)
+	return nil
+}
+
+// checkEmail checks the settings an invoice email needs, before anything
+// is spent on generating the invoice.
+func (opts *ResolvedOptions) checkEmail() error {
+	if opts.EmailProvider == "" {
+		opts.EmailProvider = invoice.DefaultEmailProvider
+	}
+	if err := invoice.ValidateEmailProvider(opts.EmailProvider); err != nil {
+		return err
+	}
+	switch {
+	case opts.EmailProvider == invoice.EmailProviderSMTP && opts.SMTPHost == "":
+		return errors.New("an SMTP server is required to email the invoice (set smtp_host in config)")
+	case opts.EmailProvider == invoice.EmailProviderGmail && opts.GmailToken == "":
+		return errors.New("a Gmail OAuth token is required to email the invoice with Gmail (use --gmail-token or set gmail_token in config)")
+	case opts.EmailProvider == invoice.EmailProviderSendGrid && opts.SendGridAPIKey == "":
+		return errors.New("a SendGrid API key is required to email the invoice with SendGrid (use --sendgrid-api-key or set sendgrid_api_key in config)")
+	case opts.EmailFrom == "":
+		return errors.New("a sender is required to email the invoice (set email_from in config)")
+	case opts.EmailTo == "":
+		return fmt.Errorf("no email recipients are set for customer %q: set email_to: in the .invoicer.yaml of the customer's directory, or use --to with 'invoicer send'", opts.Customer)
+	}
+	if opts.SMTPSecurity != "" {
+		if err := invoice.ValidateSMTPSecurity(opts.SMTPSecurity); err != nil {
+			return err
+		}
+	}
+	if _, err := invoice.ParseAddresses(opts.EmailTo); err != nil {
+		return err
+	}
+	_, _, err := invoice.RenderEmail(&invoice.Invoice{Month: time.January, Year: 2025}, opts.EmailSubject, opts.EmailBody)
+	return err
+}
+
+// sendEmail sends an email over SMTP, and newGmailClient and
+// newSendGridClient return the clients of the email APIs. They can be
+// overridden in tests.
+var (
+	sendEmail      = invoice.SendEmail
+	newGmailClient = func(token string) *invoice.GmailClient {
+		return &invoice.GmailClient{Token: token}
+	}
+	newSendGridClient = func(key string) *invoice.SendGridClient {
+		return &invoice.SendGridClient{Key: key}
+	}
+)
+
+// sendInvoiceEmail emails the PDF invoice at pdfPath to the customer's
+// recipients through opts.EmailProvider, with the HTML invoice at htmlPath
+// too if opts.AttachHTML is set and it was kept, and reports who it went to
+// on w, with the message ID the provider gave it.
+func sendInvoiceEmail(ctx context.Context, opts *ResolvedOptions, inv *invoice.Invoice, pdfPath, htmlPath string, w io.Writer) error {
+	to, err := invoice.ParseAddresses(opts.EmailTo)
+	if err != nil {
+		return err
+	}
+	subject, body, err := invoice.RenderEmail(inv, opts.EmailSubject, opts.EmailBody)
+	if err != nil {
+		return err
+	}
+	e := &invoice.Email{From: opts.EmailFrom, To: to, Subject: subject, Body: body}
+	attachments := []struct{ path, contentType string }{{pdfPath, "application/pdf"}}
+	if opts.AttachHTML && htmlPath != "" {
+		attachments = append(attachments, struct{ path, contentType string }{htmlPath, "text/html"})
+	}
+	for _, a := range attachments {
+		data, err := os.ReadFile(a.path)
+		if err != nil {
+			return fmt.Errorf("attaching the invoice: %w", err)
+		}
+		e.Attachments = append(e.Attachments, invoice.Attachment{Name: filepath.Base(a.path), ContentType: a.contentType, Data: data})
+	}
+	var id string
+	switch opts.EmailProvider {
+	case invoice.EmailProviderGmail:
+		id, err = newGmailClient(opts.GmailToken).Send(ctx, e)
+	case invoice.EmailProviderSendGrid:
+		id, err = newSendGridClient(opts.SendGridAPIKey).Send(ctx, e)
+	default:
+		err = sendEmail(ctx, invoice.SMTPOptions{
+			Host:     opts.SMTPHost,
+			Port:     opts.SMTPPort,
+			Security: opts.SMTPSecurity,
+			Username: opts.SMTPUsername,
+			Password: opts.SMTPPassword,
+		}, e)
+	}
+	if err != nil {
+		return fmt.Errorf("emailing the invoice: %w", err)
+	}
+	if id != "" {
+		fmt.Fprintf(w, "Invoice emailed to: %s (message ID %s)\n", strings.Join(to, ", "), id)
+	} else {
+		fmt.Fprintf(w, "Invoice emailed to: %s\n", strings.Join(to, ", "))
+	}
+	return nil
+}
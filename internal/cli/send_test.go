@@ -0,0 +1,203 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+const emailConfig = "smtp_host: smtp.example.com\nemail_from: Jane <jane@smith.example>\nemail_to: ap@acme.example, Bob <bob@acme.example>\n"
+
+func TestResolveOptions_Send(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		config  string
+		wantErr string
+	}{
+		{"off", nil, "", ""},
+		{"send", []string{"--send", "--attach-html"}, emailConfig + "smtp_port: 465\nsmtp_security: tls\n", ""},
+		{"no host", []string{"--send"}, "email_from: jane@smith.example\nemail_to: ap@acme.example\n", "set smtp_host in config"},
+		{"no recipients", []string{"--send"}, "smtp_host: smtp.example.com\nemail_from: jane@smith.example\ncustomer: Acme Corp\n", `no email recipients are set for customer "Acme Corp": set email_to:`},
+		{"bad security", []string{"--send"}, emailConfig + "smtp_security: ssl\n", `SMTP security "ssl"`},
+		{"attach without send", []string{"--attach-html"}, emailConfig, "--attach-html needs --send"},
+		{"attach with pdf-only", []string{"--send", "--attach-html", "--pdf-only"}, emailConfig, "--attach-html cannot be combined with --pdf-only"},
+		{"text", []string{"--send", "--format-out", "text"}, emailConfig, "--format-out text cannot be combined with --send"},
+		{"sendgrid", []string{"--send", "--sendgrid-api-key=SG.key"}, "email_provider: sendgrid\nemail_from: jane@smith.example\nemail_to: ap@acme.example\n", ""},
+		{"gmail without token", []string{"--send"}, emailConfig + "email_provider: gmail\n", "set gmail_token in config"},
+		{"unknown provider", []string{"--send"}, emailConfig + "email_provider: mailgun\n", `email provider "mailgun"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := parseCLI(t, tt.args...)
+			opts, err := cmd.Generate.resolveOptions(loadTestConfig(t, writeTestConfig(t, tt.config)), nil)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("expected error %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveOptions: %v", err)
+			}
+			if tt.name == "send" && (!opts.Send || !opts.PDF || !opts.AttachHTML || opts.SMTPPort != 465 || opts.SMTPSecurity != "tls") {
+				t.Errorf("options = %+v, want --send with a PDF", opts)
+			}
+			if tt.name == "sendgrid" && (opts.EmailProvider != "sendgrid" || opts.SendGridAPIKey != "SG.key") {
+				t.Errorf("options = %+v, want SendGrid", opts)
+			}
+			if tt.name == "off" && (opts.Send || opts.PDF) {
+				t.Errorf("options = %+v, want no email", opts)
+			}
+		})
+	}
+}
+
+// captureEmail replaces sendEmail for the test, failing with err, and
+// returns where the emails sent are recorded.
+func captureEmail(t *testing.T, err error) *[]*invoice.Email {
+	t.Helper()
+	var sent []*invoice.Email
+	old := sendEmail
+	sendEmail = func(_ context.Context, opts invoice.SMTPOptions, e *invoice.Email) error {
+		if opts.Host != "smtp.example.com" || opts.Password != "secret" {
+			t.Errorf("SMTP options = %+v", opts)
+		}
+		sent = append(sent, e)
+		return err
+	}
+	t.Cleanup(func() { sendEmail = old })
+	return &sent
+}
+
+func TestSendInvoiceEmail(t *testing.T) {
+	sent := captureEmail(t, nil)
+	dir := t.TempDir()
+	inv := testTimeoutInvoice()
+	pdfPath, htmlPath := invoice.PDFFilePath(inv, dir), invoice.InvoiceFilePath(inv, dir)
+	pdf := []byte("%PDF-1.7\n\x00\xff binary")
+	if err := os.WriteFile(pdfPath, pdf, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(htmlPath, []byte("<html></html>"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	opts := &ResolvedOptions{
+		SMTPHost: "smtp.example.com", SMTPPassword: "secret", AttachHTML: true,
+		EmailFrom: "jane@smith.example", EmailTo: "ap@acme.example, Bob <bob@acme.example>",
+		EmailSubject: "{{.Number}} for {{.Customer}}",
+	}
+	var out bytes.Buffer
+	if err := sendInvoiceEmail(context.Background(), opts, inv, pdfPath, htmlPath, &out); err != nil {
+		t.Fatalf("sendInvoiceEmail: %v", err)
+	}
+	if want := "Invoice emailed to: ap@acme.example, bob@acme.example\n"; out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+	if len(*sent) != 1 {
+		t.Fatalf("sent %d emails", len(*sent))
+	}
+	e := (*sent)[0]
+	if e.Subject != "INV-202501-acme-corp for Acme Corp" || !strings.Contains(e.Body, "invoice INV-202501-acme-corp for January 2025") {
+		t.Errorf("subject %q, body %q", e.Subject, e.Body)
+	}
+	if len(e.Attachments) != 2 {
+		t.Fatalf("got %d attachments, want the PDF and the HTML", len(e.Attachments))
+	}
+	if a := e.Attachments[0]; a.Name != "invoice-acme-corp-2025-01.pdf" || a.ContentType != "application/pdf" || !bytes.Equal(a.Data, pdf) {
+		t.Errorf("PDF attachment = %s %s %q", a.Name, a.ContentType, a.Data)
+	}
+	if a := e.Attachments[1]; a.Name != "invoice-acme-corp-2025-01.html" || a.ContentType != "text/html" {
+		t.Errorf("HTML attachment = %s %s", a.Name, a.ContentType)
+	}
+}
+
+func TestSendInvoiceEmail_Failure(t *testing.T) {
+	captureEmail(t, &invoice.EmailError{Step: invoice.EmailStepAuth, Err: errors.New("535 bad credentials")})
+	dir := t.TempDir()
+	pdfPath := filepath.Join(dir, "invoice.pdf")
+	if err := os.WriteFile(pdfPath, []byte("%PDF"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	opts := &ResolvedOptions{SMTPHost: "smtp.example.com", SMTPPassword: "secret", EmailFrom: "jane@smith.example", EmailTo: "ap@acme.example"}
+	var out bytes.Buffer
+	err := sendInvoiceEmail(context.Background(), opts, testTimeoutInvoice(), pdfPath, "", &out)
+	if err == nil || !strings.Contains(err.Error(), "emailing the invoice: SMTP authentication failed") {
+		t.Errorf("expected an authentication error, got %v", err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("nothing should be reported sent, got %q", out.String())
+	}
+}
+
+func TestEnsurePDF_Existing(t *testing.T) {
+	dir := t.TempDir()
+	pdfPath := filepath.Join(dir, "invoice.pdf")
+	if err := os.WriteFile(pdfPath, []byte("%PDF"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	// No backend is configured, so anything but reusing the PDF fails.
+	if err := ensurePDF(context.Background(), &ResolvedOptions{}, testTimeoutInvoice(), filepath.Join(dir, "invoice.html"), pdfPath, &out); err != nil {
+		t.Fatalf("ensurePDF: %v", err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("the existing PDF should be used as is, got %q", out.String())
+	}
+}
+
+func TestSendInvoiceEmail_Providers(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/gmail/v1/users/me/messages/send":
+			fmt.Fprint(w, `{"id":"18d4c2f7a9b3e601"}`)
+		case "/v3/mail/send":
+			w.Header().Set("X-Message-Id", "W86EgYT6SQKk0lRflfLRsA")
+			w.WriteHeader(http.StatusAccepted)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	oldGmail, oldSendGrid := newGmailClient, newSendGridClient
+	newGmailClient = func(token string) *invoice.GmailClient {
+		return &invoice.GmailClient{Token: token, BaseURL: srv.URL}
+	}
+	newSendGridClient = func(key string) *invoice.SendGridClient {
+		return &invoice.SendGridClient{Key: key, BaseURL: srv.URL}
+	}
+	t.Cleanup(func() { newGmailClient, newSendGridClient = oldGmail, oldSendGrid })
+	sent := captureEmail(t, nil)
+
+	pdfPath := filepath.Join(t.TempDir(), "invoice.pdf")
+	if err := os.WriteFile(pdfPath, []byte("%PDF"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for provider, want := range map[string]string{
+		invoice.EmailProviderGmail:    "Invoice emailed to: ap@acme.example (message ID 18d4c2f7a9b3e601)\n",
+		invoice.EmailProviderSendGrid: "Invoice emailed to: ap@acme.example (message ID W86EgYT6SQKk0lRflfLRsA)\n",
+	} {
+		t.Run(provider, func(t *testing.T) {
+			opts := &ResolvedOptions{EmailProvider: provider, GmailToken: "token", SendGridAPIKey: "SG.key", EmailFrom: "jane@smith.example", EmailTo: "ap@acme.example"}
+			var out bytes.Buffer
+			if err := sendInvoiceEmail(context.Background(), opts, testTimeoutInvoice(), pdfPath, "", &out); err != nil {
+				t.Fatalf("sendInvoiceEmail: %v", err)
+			}
+			if out.String() != want {
+				t.Errorf("output = %q, want %q", out.String(), want)
+			}
+		})
+	}
+	if len(*sent) != 0 {
+		t.Error("the API providers should not use SMTP")
+	}
+}
@@ -0,0 +1,418 @@
+package cli
+
+import (
+	"cmp"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/zon/invoicer/internal/config"
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+// maxRequestBody is the largest POST /invoices body accepted.
+const maxRequestBody = 1 << 20
+
+// shutdownTimeout bounds how long in-flight requests get to finish after
+// the server is told to stop.
+const shutdownTimeout = 5 * time.Second
+
+// ServeCmd is the 'serve' subcommand, an HTTP API for invoice generation.
+type ServeCmd struct {
+	// Listen is the address to listen on.
+	Listen string `default:":8080" help:"Address to listen on."`
+
+	// Dir is where generated invoices are written and listed from.
+	Dir string `type:"path" help:"Directory to write invoices to. Defaults to the current directory."`
+
+	// Timeout bounds the generation of a single invoice.
+	Timeout time.Duration `default:"5m" help:"Maximum time to spend on one invoice request."`
+
+	// MaxConcurrent limits how many invoices are generated at once.
+	MaxConcurrent int `name:"max-concurrent" default:"2" help:"Maximum number of invoices generated at once."`
+
+	// Token is the bearer token required on /invoices. Nil if not given.
+	Token *string `env:"INVOICER_SERVE_TOKEN" help:"Bearer token required on /invoices requests. Defaults to serve_token from the config."`
+}
+
+// Run executes the 'serve' subcommand. It shuts down gracefully when ctx
+// is cancelled.
+func (s *ServeCmd) Run(g *Globals, ctx context.Context) error {
+	configPath, err := g.configPath()
+	if err != nil {
+		return err
+	}
+	global, err := g.loadConfig(configPath)
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+	local, err := g.localConfig()
+	if err != nil {
+		return err
+	}
+	if s.MaxConcurrent < 1 {
+		return fmt.Errorf("--max-concurrent must be at least 1, got %d", s.MaxConcurrent)
+	}
+
+	srv := newServer(config.Merge(global, local), s, os.Stderr)
+	if srv.token == "" {
+		fmt.Fprintf(os.Stderr, "Warning: no serve_token is set; /invoices accepts unauthenticated requests\n")
+	}
+
+	httpServer := &http.Server{
+		Addr:              s.Listen,
+		Handler:           srv.handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	httpServer.BaseContext = func(net.Listener) context.Context { return ctx }
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		_ = httpServer.Shutdown(shutdownCtx)
+	}()
+
+	fmt.Printf("Listening on %s\n", s.Listen)
+	if err := httpServer.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// server handles the HTTP API. Requests are resolved against cfg exactly as
+// command-line flags are.
+type server struct {
+	cfg     *config.Config
+	dir     string
+	token   string
+	timeout time.Duration
+	slots   chan struct{}
+	hookOut io.Writer
+}
+
+// newServer builds a server from the serve flags and the merged config.
+func newServer(cfg *config.Config, s *ServeCmd, hookOut io.Writer) *server {
+	if cfg == nil {
+		cfg = &config.Config{}
+	}
+	dir := s.Dir
+	if dir == "" {
+		dir = invoice.CurrentDir()
+	}
+	var token string
+	switch {
+	case s.Token != nil:
+		token = *s.Token
+	case cfg.ServeToken != nil:
+		token = *cfg.ServeToken
+	}
+	return &server{
+		cfg:     cfg,
+		dir:     dir,
+		token:   token,
+		timeout: s.Timeout,
+		slots:   make(chan struct{}, s.MaxConcurrent),
+		hookOut: hookOut,
+	}
+}
+
+// handler returns the HTTP routes.
+func (s *server) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", s.handleHealth)
+	mux.HandleFunc("POST /invoices", s.authorize(s.handleCreate))
+	mux.HandleFunc("GET /invoices", s.authorize(s.handleList))
+	return mux
+}
+
+// authorize rejects requests without the configured bearer token.
+func (s *server) authorize(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.token != "" {
+			got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(s.token)) != 1 {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				writeError(w, http.StatusUnauthorized, errors.New("missing or invalid bearer token"))
+				return
+			}
+		}
+		next(w, r)
+	}
+}
+
+func (s *server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// invoiceRequest is the body of POST /invoices. Fields that are not given
+// fall back to the config, then to the built-in defaults.
+type invoiceRequest struct {
+	Month       string   `json:"month"`
+	Year        int      `json:"year"`
+	Vendor      string   `json:"vendor"`
+	Customer    string   `json:"customer"`
+	Rate        *float64 `json:"rate"`
+	Hours       *float64 `json:"hours"`
+	PDF         *bool    `json:"pdf"`
+	Model       *string  `json:"model"`
+	Backend     *string  `json:"backend"`
+	OllamaHost  *string  `json:"ollama_host"`
+	OllamaModel *string  `json:"ollama_model"`
+
+	// Inline returns the HTML in the response instead of writing it to the
+	// server's directory.
+	Inline bool `json:"inline"`
+}
+
+// generateCmd converts the request to the equivalent command-line flags.
+// The hook is never taken from a request; only the config can set it.
+func (req *invoiceRequest) generateCmd() *GenerateCmd {
+	return &GenerateCmd{
+		Month:       req.Month,
+		Year:        req.Year,
+		Vendor:      req.Vendor,
+		Customer:    req.Customer,
+		Rate:        req.Rate,
+		Hours:       req.Hours,
+		PDF:         req.PDF,
+		Model:       req.Model,
+		Backend:     req.Backend,
+		OllamaHost:  req.OllamaHost,
+		OllamaModel: req.OllamaModel,
+	}
+}
+
+// invoiceResponse describes a generated invoice: the fields of the
+// invoice's own JSON (see invoice.Invoice.MarshalJSON), followed by those
+// of the generation.
+type invoiceResponse struct {
+	Invoice       *invoice.Invoice `json:"-"`
+	Model         string           `json:"model,omitempty"`
+	Usage         *invoice.Usage   `json:"usage,omitempty"`
+	Session       string           `json:"session,omitempty"`
+	HTMLPath      string           `json:"html_path,omitempty"`
+	PDFPath       string           `json:"pdf_path,omitempty"`
+	PDFTool       string           `json:"pdf_tool,omitempty"`
+	ThumbnailPath string           `json:"thumbnail_path,omitempty"`
+	HTML          string           `json:"html,omitempty"`
+}
+
+// generationResponse holds the fields of an invoiceResponse other than
+// the invoice's, without its JSON methods.
+type generationResponse invoiceResponse
+
+// MarshalJSON implements json.Marshaler, merging the invoice's fields and
+// the generation's into one object.
+func (r *invoiceResponse) MarshalJSON() ([]byte, error) {
+	inv, err := json.Marshal(r.Invoice)
+	if err != nil {
+		return nil, err
+	}
+	gen, err := json.Marshal((*generationResponse)(r))
+	if err != nil {
+		return nil, err
+	}
+	if string(gen) == "{}" {
+		return inv, nil
+	}
+	return append(append(inv[:len(inv)-1:len(inv)-1], ','), gen[1:]...), nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler for the object MarshalJSON writes.
+func (r *invoiceResponse) UnmarshalJSON(data []byte) error {
+	r.Invoice = new(invoice.Invoice)
+	if err := json.Unmarshal(data, r.Invoice); err != nil {
+		return err
+	}
+	return json.Unmarshal(data, (*generationResponse)(r))
+}
+
+func (s *server) handleCreate(w http.ResponseWriter, r *http.Request) {
+	var req invoiceRequest
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBody))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return
+	}
+
+	opts, err := req.generateCmd().resolveOptions(s.cfg, nil)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	inv, err := opts.buildInvoice()
+	if err != nil {
+		var problems invoice.ValidationErrors
+		if errors.As(err, &problems) {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": err.Error(), "problems": problems})
+			return
+		}
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if req.Inline && opts.PDF {
+		writeError(w, http.StatusBadRequest, errors.New("pdf cannot be combined with inline"))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), s.timeout)
+	defer cancel()
+
+	select {
+	case s.slots <- struct{}{}:
+		defer func() { <-s.slots }()
+	case <-ctx.Done():
+		writeError(w, http.StatusServiceUnavailable, errors.New("too many invoices are being generated; try again later"))
+		return
+	}
+
+	resp, err := s.generate(ctx, opts, inv, req.Inline)
+	if err != nil {
+		status := http.StatusInternalServerError
+		var timeout *timeoutError
+		var budget *budgetError
+		if errors.As(err, &timeout) {
+			status = http.StatusGatewayTimeout
+		} else if errors.As(err, &budget) {
+			status = http.StatusPaymentRequired
+		} else if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			status = http.StatusGatewayTimeout
+			err = fmt.Errorf("generation timed out after %s: %w", s.timeout, err)
+		}
+		writeError(w, status, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, resp)
+}
+
+// newInvoiceResponse describes inv without any generated files.
+func newInvoiceResponse(inv *invoice.Invoice) *invoiceResponse {
+	return &invoiceResponse{Invoice: inv}
+}
+
+// generate runs the same generation pipeline as the generate subcommand.
+func (s *server) generate(ctx context.Context, opts *ResolvedOptions, inv *invoice.Invoice, inline bool) (*invoiceResponse, error) {
+	resp := newInvoiceResponse(inv)
+
+	dir := s.dir
+	if inline {
+		tmp, err := os.MkdirTemp("", "invoicer-serve-")
+		if err != nil {
+			return nil, fmt.Errorf("creating temp dir: %w", err)
+		}
+		defer os.RemoveAll(tmp)
+		dir = tmp
+	}
+
+	htmlPath := invoice.InvoiceFilePath(inv, dir)
+	res, err := generateHTML(ctx, opts, inv, htmlPath)
+	if err != nil {
+		return nil, fmt.Errorf("generating HTML invoice: %w", err)
+	}
+	resp.Model = res.Model
+	resp.Session = res.SessionID
+	if !res.Usage.IsZero() {
+		resp.Usage = &res.Usage
+	}
+
+	if inline {
+		html, err := os.ReadFile(htmlPath)
+		if err != nil {
+			return nil, fmt.Errorf("reading HTML invoice: %w", err)
+		}
+		resp.HTML = string(html)
+		return resp, nil
+	}
+	resp.HTMLPath = htmlPath
+
+	if opts.PDF {
+		resp.PDFPath = invoice.PDFFilePath(inv, dir)
+		res, err := writePDF(ctx, opts, inv, htmlPath, resp.PDFPath)
+		if err != nil {
+			return nil, fmt.Errorf("converting to PDF: %w", err)
+		}
+		resp.PDFTool = res.Tool
+		if res.HTMLRemoved {
+			resp.HTMLPath = ""
+		}
+		resp.ThumbnailPath = res.Thumbnail
+	} else if opts.Thumbnail {
+		resp.ThumbnailPath, _ = writeThumbnail(ctx, opts, inv, htmlPath)
+	}
+
+	if err := runPostGenerateHook(opts, inv, resp.HTMLPath, resp.PDFPath, "", s.hookOut); err != nil {
+		return nil, err
+	}
+	notifyGenerated(ctx, opts, inv, cmp.Or(resp.PDFPath, resp.HTMLPath), s.hookOut)
+	return resp, nil
+}
+
+// invoiceFile is one entry of the GET /invoices listing.
+type invoiceFile struct {
+	Name     string    `json:"name"`
+	Path     string    `json:"path"`
+	Size     int64     `json:"size"`
+	Modified time.Time `json:"modified"`
+}
+
+// handleList lists the invoice files in the server's directory.
+func (s *server) handleList(w http.ResponseWriter, r *http.Request) {
+	files, err := listInvoiceFiles(s.dir)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, files)
+}
+
+// listInvoiceFiles returns the invoice HTML, PDF and thumbnail files in dir,
+// sorted by name.
+func listInvoiceFiles(dir string) ([]invoiceFile, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("listing invoices: %w", err)
+	}
+	files := []invoiceFile{}
+	for _, entry := range entries {
+		name := entry.Name()
+		ext := filepath.Ext(name)
+		if entry.IsDir() || !strings.HasPrefix(name, "invoice-") || (ext != ".html" && ext != ".pdf" && ext != ".png") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, invoiceFile{
+			Name:     name,
+			Path:     filepath.Join(dir, name),
+			Size:     info.Size(),
+			Modified: info.ModTime().UTC(),
+		})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+	return files, nil
+}
+
+// writeJSON writes v as the JSON response body.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes err as a JSON error response.
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
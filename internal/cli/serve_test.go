@@ -0,0 +1,274 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zon/invoicer/internal/config"
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+// serveGate holds "serve-test-gate" generations until it is closed.
+var serveGate chan struct{}
+
+// serveTestGenerator writes a fixed HTML file. If block is set it instead
+// waits until the context is done; if gated, it first waits for serveGate.
+type serveTestGenerator struct {
+	block bool
+	gated bool
+}
+
+func (g serveTestGenerator) Generate(ctx context.Context, inv *invoice.Invoice, outputPath string, opts invoice.GenerateOptions) (*invoice.Result, error) {
+	if g.gated {
+		<-serveGate
+	}
+	if g.block {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	if err := os.WriteFile(outputPath, []byte("<html>"+inv.Customer+"</html>"), 0o644); err != nil {
+		return nil, err
+	}
+	return &invoice.Result{Path: outputPath}, nil
+}
+
+func init() {
+	invoice.Register("serve-test", serveTestGenerator{})
+	invoice.Register("serve-test-block", serveTestGenerator{block: true})
+	invoice.Register("serve-test-gate", serveTestGenerator{gated: true})
+}
+
+// newTestServer starts an httptest server backed by a server writing to a temp dir.
+func newTestServer(t *testing.T, cfg *config.Config, s *ServeCmd) (*httptest.Server, string) {
+	t.Helper()
+	if s.Dir == "" {
+		s.Dir = t.TempDir()
+	}
+	if s.Timeout == 0 {
+		s.Timeout = time.Minute
+	}
+	if s.MaxConcurrent == 0 {
+		s.MaxConcurrent = 1
+	}
+	ts := httptest.NewServer(newServer(cfg, s, io.Discard).handler())
+	t.Cleanup(ts.Close)
+	return ts, s.Dir
+}
+
+func postInvoice(t *testing.T, url, token, body string) (*http.Response, map[string]any) {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, url+"/invoices", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("POST /invoices: %v", err)
+	}
+	defer resp.Body.Close()
+	var out map[string]any
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	return resp, out
+}
+
+func TestServeHealthz(t *testing.T) {
+	ts, _ := newTestServer(t, &config.Config{ServeToken: strPtr("secret")}, &ServeCmd{})
+	resp, err := http.Get(ts.URL + "/healthz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected 200 without a token, got %d", resp.StatusCode)
+	}
+}
+
+func TestServeCreateInvoice(t *testing.T) {
+	cfg := &config.Config{
+		Vendor:  strPtr("Jane Smith"),
+		Rate:    floatPtr(100),
+		Hours:   floatPtr(40),
+		Backend: strPtr("serve-test"),
+	}
+	ts, dir := newTestServer(t, cfg, &ServeCmd{})
+
+	resp, out := postInvoice(t, ts.URL, "", `{"month": "january", "year": 2025, "customer": "Acme Corp"}`)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %v", resp.StatusCode, out)
+	}
+	if out["number"] != "INV-202501-acme-corp" {
+		t.Errorf("number = %v", out["number"])
+	}
+	if out["total"] != 18400.0 {
+		t.Errorf("total = %v, want 18400", out["total"])
+	}
+	// The invoice is described as in its own JSON.
+	if out["month"] != "January" || out["month_number"] != 1.0 || out["currency"] != "USD" {
+		t.Errorf("month, month_number, currency = %v, %v, %v", out["month"], out["month_number"], out["currency"])
+	}
+	wantPath := dir + "/invoice-acme-corp-2025-01.html"
+	if out["html_path"] != wantPath {
+		t.Errorf("html_path = %v, want %s", out["html_path"], wantPath)
+	}
+	if _, err := os.Stat(wantPath); err != nil {
+		t.Errorf("HTML file not written: %v", err)
+	}
+}
+
+func TestServeCreateInvoiceInline(t *testing.T) {
+	ts, dir := newTestServer(t, nil, &ServeCmd{})
+
+	body := `{"month": "1", "year": 2025, "vendor": "Jane", "customer": "Acme Corp", "rate": 100, "hours": 40, "backend": "serve-test", "inline": true}`
+	resp, out := postInvoice(t, ts.URL, "", body)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %v", resp.StatusCode, out)
+	}
+	if out["html"] != "<html>Acme Corp</html>\n" {
+		t.Errorf("html = %v", out["html"])
+	}
+	if _, ok := out["html_path"]; ok {
+		t.Errorf("inline response should not include html_path: %v", out)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 0 {
+		t.Errorf("inline generation should not write to the server dir, found %d entries", len(entries))
+	}
+}
+
+func TestServeValidationErrors(t *testing.T) {
+	ts, _ := newTestServer(t, nil, &ServeCmd{})
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"malformed", `{"month":`, "invalid request body"},
+		{"unknown field", `{"colour": "red"}`, "unknown field"},
+		{"missing vendor", `{"customer": "Acme", "rate": 100, "hours": 40}`, "vendor is required"},
+		{"bad month", `{"month": "smarch", "vendor": "J", "customer": "A", "rate": 1, "hours": 1}`, "unrecognized month"},
+		{"bad backend", `{"vendor": "J", "customer": "A", "rate": 1, "hours": 1, "backend": "nope"}`, "unknown backend"},
+		{"inline pdf", `{"vendor": "J", "customer": "A", "rate": 1, "hours": 1, "pdf": true, "inline": true}`, "inline"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, out := postInvoice(t, ts.URL, "", tt.body)
+			if resp.StatusCode != http.StatusBadRequest {
+				t.Errorf("expected 400, got %d", resp.StatusCode)
+			}
+			if msg, _ := out["error"].(string); !strings.Contains(msg, tt.want) {
+				t.Errorf("error %q does not contain %q", msg, tt.want)
+			}
+		})
+	}
+}
+
+func TestServeInvalidInvoice(t *testing.T) {
+	ts, _ := newTestServer(t, nil, &ServeCmd{})
+	resp, out := postInvoice(t, ts.URL, "", `{"month": "march", "year": -1, "vendor": "J", "customer": "A", "rate": 1, "hours": 1}`)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", resp.StatusCode)
+	}
+	problems, _ := out["problems"].([]any)
+	if len(problems) != 1 {
+		t.Fatalf("problems = %v, want one", out["problems"])
+	}
+	if p, _ := problems[0].(map[string]any); p["code"] != invoice.ValidationInvalidPeriod || p["field"] != "year" {
+		t.Errorf("problem = %v", p)
+	}
+}
+
+func TestServeTimeout(t *testing.T) {
+	ts, _ := newTestServer(t, nil, &ServeCmd{Timeout: 50 * time.Millisecond})
+
+	body := `{"vendor": "J", "customer": "A", "rate": 1, "hours": 1, "backend": "serve-test-block"}`
+	resp, out := postInvoice(t, ts.URL, "", body)
+	if resp.StatusCode != http.StatusGatewayTimeout {
+		t.Fatalf("expected 504, got %d: %v", resp.StatusCode, out)
+	}
+	if msg, _ := out["error"].(string); !strings.Contains(msg, "timed out") {
+		t.Errorf("error %q does not mention the timeout", msg)
+	}
+}
+
+func TestServeConcurrencyLimit(t *testing.T) {
+	cmd := &ServeCmd{Timeout: 50 * time.Millisecond, MaxConcurrent: 1}
+	ts, _ := newTestServer(t, nil, cmd)
+
+	// Occupy the only slot until the second request has given up waiting.
+	serveGate = make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		body := `{"vendor": "J", "customer": "A", "rate": 1, "hours": 1, "backend": "serve-test-gate"}`
+		if resp, err := http.Post(ts.URL+"/invoices", "application/json", strings.NewReader(body)); err == nil {
+			resp.Body.Close()
+		}
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	resp, out := postInvoice(t, ts.URL, "", `{"vendor": "J", "customer": "B", "rate": 1, "hours": 1, "backend": "serve-test"}`)
+	close(serveGate)
+	<-done
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 while the only slot is busy, got %d: %v", resp.StatusCode, out)
+	}
+}
+
+func TestServeAuth(t *testing.T) {
+	cfg := &config.Config{ServeToken: strPtr("secret")}
+	ts, _ := newTestServer(t, cfg, &ServeCmd{})
+
+	body := `{"vendor": "J", "customer": "A", "rate": 1, "hours": 1, "backend": "serve-test"}`
+	for _, token := range []string{"", "wrong"} {
+		resp, _ := postInvoice(t, ts.URL, token, body)
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("token %q: expected 401, got %d", token, resp.StatusCode)
+		}
+	}
+	resp, out := postInvoice(t, ts.URL, "secret", body)
+	if resp.StatusCode != http.StatusCreated {
+		t.Errorf("expected 201 with the token, got %d: %v", resp.StatusCode, out)
+	}
+}
+
+func TestServeTokenFlagOverridesConfig(t *testing.T) {
+	cfg := &config.Config{ServeToken: strPtr("from-config")}
+	srv := newServer(cfg, &ServeCmd{Token: strPtr("from-flag"), MaxConcurrent: 1}, io.Discard)
+	if srv.token != "from-flag" {
+		t.Errorf("token = %q, want from-flag", srv.token)
+	}
+}
+
+func TestServeListInvoices(t *testing.T) {
+	ts, dir := newTestServer(t, nil, &ServeCmd{})
+	for _, name := range []string{"invoice-b-2025-02.html", "invoice-a-2025-01.pdf", "invoice-a-2025-01.png", "notes.txt"} {
+		if err := os.WriteFile(dir+"/"+name, []byte("x"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	resp, err := http.Get(ts.URL + "/invoices")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var files []invoiceFile
+	if err := json.NewDecoder(resp.Body).Decode(&files); err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 3 || files[0].Name != "invoice-a-2025-01.pdf" || files[1].Name != "invoice-a-2025-01.png" || files[2].Name != "invoice-b-2025-02.html" {
+		t.Errorf("unexpected listing: %+v", files)
+	}
+}
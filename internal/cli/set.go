@@ -2,48 +2,296 @@ package cli
 
 import (
 	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/alecthomas/kong"
+	"golang.org/x/term"
 
 	"github.com/zon/invoicer/internal/config"
+	"github.com/zon/invoicer/pkg/invoice"
 )
 
 // SetCmd groups subcommands under "set".
 type SetCmd struct {
-	Config SetConfigCmd `cmd:"" name:"config" help:"Write configuration options to ~/.invoicer/config.yaml."`
+	Config SetConfigCmd `cmd:"" name:"config" help:"Write configuration options to the config file."`
 }
 
 // SetConfigCmd is the 'set config' subcommand.
-// It accepts the same options as the main command and writes them to ~/.invoicer/config.yaml.
+// It accepts the same options as the main command and writes them to the config file.
 // Only explicitly provided options are written; others are left unchanged.
 type SetConfigCmd struct {
 	// Vendor is the name of the contractor sending the invoice.
-	Vendor string `help:"Name of the contractor sending the invoice."`
+	Vendor *string `help:"Name of the contractor sending the invoice."`
 
 	// Customer is the name of the client receiving the invoice.
-	Customer string `help:"Name of the client receiving the invoice."`
+	Customer *string `help:"Name of the client receiving the invoice."`
 
 	// Rate is the hourly rate for the contractor.
-	Rate float64 `help:"Hourly rate in dollars."`
+	Rate *float64 `type:"number" help:"Hourly rate in dollars."`
 
 	// Hours is the number of hours per week worked.
-	Hours float64 `help:"Hours per week worked."`
+	Hours *float64 `type:"number" help:"Hours per week worked."`
 
 	// PDF controls whether the HTML invoice is converted to a PDF.
-	PDF *bool `help:"Convert the HTML invoice to a PDF file."`
+	PDF *bool `negatable:"" help:"Convert the HTML invoice to a PDF file (--no-pdf to save false)."`
 
 	// Model is the opencode-formatted model stub to use for generation.
-	Model string `help:"opencode-formatted model stub to use for invoice generation."`
+	Model *string `help:"opencode-formatted model stub to use for invoice generation, or a comma-separated list to fall back through."`
+
+	// SkipModelCheck saves Model without checking it. It is not saved.
+	SkipModelCheck bool `name:"skip-model-check" help:"Save --model without checking that it looks like provider/model."`
+
+	// Backend is the name of the generation backend.
+	Backend *string `predictor:"backend" help:"Generation backend: opencode, claude, or ollama."`
+
+	// IBAN is the vendor's bank account.
+	IBAN *string `name:"iban" help:"IBAN of the account invoices are paid into, for the payment details and an EPC payment QR code."`
+
+	// BIC is the Business Identifier Code of the vendor's bank.
+	BIC *string `name:"bic" help:"BIC of the bank invoices are paid into."`
+
+	// RoutingNumber and AccountNumber are the vendor's US bank account.
+	RoutingNumber *string `name:"routing-number" help:"ABA routing number of the US bank invoices are paid into, e.g. 021000021."`
+	AccountNumber *string `name:"account-number" help:"Number of the US bank account invoices are paid into."`
+
+	// VendorCountry is the vendor's country code, for e-invoices.
+	VendorCountry *string `name:"vendor-country" help:"ISO 3166-1 alpha-2 code of the vendor's country, e.g. DE, for e-invoices."`
+
+	// CustomerCountry is the customer's country code, for e-invoices.
+	CustomerCountry *string `name:"customer-country" help:"ISO 3166-1 alpha-2 code of the customer's country, e.g. FR, for e-invoices."`
+
+	// VendorVATID is the vendor's VAT identifier, for e-invoices.
+	VendorVATID *string `name:"vendor-vat-id" help:"Vendor's VAT ID with its country prefix, e.g. DE123456789, for e-invoices."`
+
+	// CustomerVATID is the customer's VAT identifier, for e-invoices.
+	CustomerVATID *string `name:"customer-vat-id" help:"Customer's VAT ID with its country prefix, e.g. FR12345678901, for e-invoices."`
+
+	// QuickBooksIncomeAccount is the income account QuickBooks exports book to.
+	QuickBooksIncomeAccount *string `name:"quickbooks-income-account" help:"QuickBooks income account that exported invoices are booked to."`
+
+	// QuickBooksItem is the product/service QuickBooks exports bill for.
+	QuickBooksItem *string `name:"quickbooks-item" help:"QuickBooks product/service that exported invoice lines are for."`
+
+	// XeroAccountCode is the account Xero exports code lines to.
+	XeroAccountCode *string `name:"xero-account-code" help:"Xero revenue account code that exported invoice lines are coded to."`
+
+	// XeroTaxType is the tax rate of Xero export lines.
+	XeroTaxType *string `name:"xero-tax-type" help:"Xero tax rate of exported invoice lines, e.g. \"Tax Exempt\"."`
+
+	// XeroRegion is the region of the Xero organisation.
+	XeroRegion *string `name:"xero-region" predictor:"xero_region" help:"Region of the Xero organisation, which decides the date format: US, UK, AU, NZ, or global."`
+
+	// StripeAPIKey is the secret key Stripe invoices are created with.
+	StripeAPIKey *string `name:"stripe-api-key" help:"Stripe secret key that --send-stripe creates invoices with."`
+
+	// StripeCustomerID is the Stripe customer to bill.
+	StripeCustomerID *string `name:"stripe-customer-id" help:"Stripe customer to bill with --send-stripe, e.g. cus_123."`
+
+	// StripeCustomerEmail finds or creates the Stripe customer to bill.
+	StripeCustomerEmail *string `name:"stripe-customer-email" help:"Email of the Stripe customer to bill with --send-stripe, used without a customer ID; the customer is created if there is none."`
+
+	// StripeDaysUntilDue is how long the customer has to pay.
+	StripeDaysUntilDue *int `name:"stripe-days-until-due" help:"Days the customer has to pay a Stripe invoice."`
+
+	// PayPalClientID is the client ID of the PayPal REST app.
+	PayPalClientID *string `name:"paypal-client-id" help:"Client ID of the PayPal REST app that --send-paypal creates invoices with."`
+
+	// PayPalClientSecret is the secret of the PayPal REST app.
+	PayPalClientSecret *string `name:"paypal-client-secret" help:"Secret of the PayPal REST app."`
+
+	// PayPalSandbox uses the PayPal sandbox.
+	PayPalSandbox *bool `name:"paypal-sandbox" negatable:"" help:"Create PayPal invoices in the sandbox, for sandbox credentials (--no-paypal-sandbox to save false)."`
+
+	// PayPalCustomerEmail is where PayPal invoices are sent.
+	PayPalCustomerEmail *string `name:"paypal-customer-email" help:"Email PayPal invoices are sent to."`
+
+	// PayPalPaymentTerm is the payment term of PayPal invoices.
+	PayPalPaymentTerm *string `name:"paypal-payment-term" help:"Payment term of PayPal invoices without a due date: DUE_ON_RECEIPT, NET_10, NET_15, NET_30, NET_45, NET_60, or NET_90."`
+
+	// FreshBooksToken is the FreshBooks OAuth token.
+	FreshBooksToken *string `name:"freshbooks-token" help:"OAuth access token that 'export freshbooks' creates invoices with."`
+
+	// FreshBooksAccountID is the FreshBooks account.
+	FreshBooksAccountID *string `name:"freshbooks-account-id" help:"ID of the FreshBooks account invoices are created in."`
+
+	// FreshBooksClientID is the FreshBooks client the customer is.
+	FreshBooksClientID *string `name:"freshbooks-client-id" help:"ID of the FreshBooks client the customer is. Set it in each customer's project-local config."`
+
+	// EmailProvider is how invoice emails are sent.
+	EmailProvider *string `name:"email-provider" predictor:"email_provider" help:"How 'send' and --send email invoices: smtp, or gmail or sendgrid for their HTTP APIs, where SMTP is blocked."`
+
+	// SMTPHost is the SMTP server invoices are emailed through.
+	SMTPHost *string `name:"smtp-host" help:"SMTP server that 'send' and --send email invoices through."`
+
+	// SMTPPort is the SMTP server's port.
+	SMTPPort *int `name:"smtp-port" help:"Port of the SMTP server. Defaults to 587."`
+
+	// SMTPSecurity is the SMTP connection's security.
+	SMTPSecurity *string `name:"smtp-security" predictor:"smtp_security" help:"Security of the SMTP connection: starttls, tls (implicit TLS, usually on port 465), or none."`
+
+	// SMTPUsername is the SMTP user.
+	SMTPUsername *string `name:"smtp-username" help:"User to sign in to the SMTP server as."`
+
+	// SMTPPassword is the SMTP user's password.
+	SMTPPassword *string `name:"smtp-password" help:"Password of the SMTP user. Prefer INVOICER_SMTP_PASSWORD to saving it."`
+
+	// GmailToken is the Gmail OAuth access token.
+	GmailToken *string `name:"gmail-token" help:"OAuth access token with the gmail.send scope, for the gmail email provider."`
+
+	// SendGridAPIKey is the SendGrid API key.
+	SendGridAPIKey *string `name:"sendgrid-api-key" help:"API key with the Mail Send permission, for the sendgrid email provider."`
+
+	// EmailFrom is the sender of invoice emails.
+	EmailFrom *string `name:"email-from" help:"Sender of invoice emails, e.g. 'Jane Smith <jane@example.com>'."`
+
+	// EmailTo is the recipients of invoice emails.
+	EmailTo *string `name:"email-to" help:"Comma-separated recipients of invoice emails. Set it in each customer's project-local config."`
+
+	// EmailSubject is the subject template of invoice emails.
+	EmailSubject *string `name:"email-subject" help:"Subject template of invoice emails, e.g. 'Invoice {{.Number}} — {{.Month}} {{.Year}}'."`
+
+	// EmailBody is the body template of invoice emails.
+	EmailBody *string `name:"email-body" help:"Body template of invoice emails, with the same fields as the subject."`
+
+	// NotifyWebhook is the webhook notifications are posted to.
+	NotifyWebhook *string `name:"notify-webhook" help:"Slack incoming webhook or Discord webhook URL to post a message to when an invoice is generated."`
+
+	// NotifyMessage is the template of notifications.
+	NotifyMessage *string `name:"notify-message" help:"Template of the notification, with the email template fields and {{.Path}}, the generated file."`
+
+	// DateFormat and ExportDateFormat are the styles dates are shown in.
+	DateFormat       *string `name:"date-format" help:"Style dates are shown in on invoices and in emails: long, iso, us or eu."`
+	ExportDateFormat *string `name:"export-date-format" help:"Style exports label weeks in: long, iso, us or eu. Defaults to the date format."`
+
+	// Language is the language invoices are labelled in.
+	Language *string `help:"Language invoices are labelled in, e.g. de or fr. Defaults to English."`
+
+	// ShowWorkdays adds each week's workdays to its line item.
+	ShowWorkdays *bool `name:"show-workdays" negatable:"" help:"Show each week's workdays on its line item, e.g. 'Jan 1-5 (3 workdays)' (--no-show-workdays to save false)."`
+
+	// HolidayCountry is the region whose public holidays are not worked.
+	HolidayCountry *string `name:"holiday-country" predictor:"holiday_country" help:"Country, or region of one, whose public holidays are not worked, e.g. US or DE-BY."`
+
+	// RatePrecision and HoursPrecision are the decimals rates and hours
+	// are shown with.
+	RatePrecision  *int `name:"rate-precision" help:"Decimals rates are shown with, 0 to 4. Defaults to 2."`
+	HoursPrecision *int `name:"hours-precision" help:"Decimals hours are shown with, 0 to 4. Defaults to 1."`
+
+	// DecimalSeparator is how rates and hours written as text are read.
+	DecimalSeparator *string `name:"decimal-separator" help:"Decimal separator of rates and hours written as text in the config file, . or , e.g. , to read 1.250 as 1250."`
+
+	// BillCurrency is the currency invoices are billed in, and FXProvider
+	// and FXAPIKey where its exchange rate is fetched from.
+	BillCurrency *string `name:"bill-currency" help:"ISO 4217 currency invoices are billed in, e.g. EUR, converted from dollars at the exchange rate of the last day of the month."`
+	FXProvider   *string `name:"fx-provider" help:"Where exchange rates are fetched from: ecb or exchangerate.host."`
+	FXAPIKey     *string `name:"fx-api-key" help:"Access key of the exchangerate.host API."`
+
+	// Schedule, ScheduleDay and Timezone say when the customer is invoiced.
+	Schedule    *string `help:"How often the customer is invoiced, for 'invoicer due': monthly."`
+	ScheduleDay *int    `name:"schedule-day" help:"Day of the month the previous month is invoiced on, 1 to 31; the last day in shorter months."`
+	Timezone    *string `help:"IANA time zone days are counted in for the schedule, e.g. Europe/Berlin."`
+
+	// OllamaHost is the URL of the ollama server.
+	OllamaHost *string `help:"URL of the ollama server."`
+
+	// OllamaModel is the ollama model name.
+	OllamaModel *string `help:"ollama model for the ollama backend."`
+
+	// Agent is the opencode agent that writes the invoice.
+	Agent *string `help:"opencode agent to write the invoice with."`
+
+	// Session is the opencode session to continue.
+	Session *string `help:"opencode session to continue."`
+
+	// RestrictTools switches off the opencode tools in DeniedTools.
+	RestrictTools *bool `name:"restrict-tools" negatable:"" help:"Switch off the opencode tools in --denied-tools (--no-restrict-tools to save false)."`
+
+	// DeniedTools lists the opencode tools to switch off.
+	DeniedTools *string `name:"denied-tools" help:"Comma-separated opencode tools to switch off, e.g. bash,webfetch."`
+
+	// Timeout bounds the generation step.
+	Timeout *time.Duration `help:"Maximum time to wait for the invoice to be generated, e.g. 120s."`
+
+	// PDFOnly converts to PDF and removes the HTML invoice afterwards.
+	PDFOnly *bool `name:"pdf-only" negatable:"" help:"Convert the invoice to PDF and remove the HTML afterwards (--no-pdf-only to save false)."`
+
+	// PDFEngine is how the PDF is produced.
+	PDFEngine *string `name:"pdf-engine" predictor:"pdf_engine" help:"PDF engine: exec (run a conversion tool), chromedp (drive headless Chrome), or auto (chromedp, falling back to exec)."`
+
+	// PDFTool is the PDF conversion tool to use.
+	PDFTool *string `name:"pdf-tool" predictor:"pdf_tool" help:"PDF conversion tool: auto, wkhtmltopdf, chromium, chrome, edge, weasyprint, or pandoc."`
+
+	// PDFToolPath is the PDF tool's executable.
+	PDFToolPath *string `name:"pdf-tool-path" type:"path" help:"Path to the PDF tool's executable, for one that is not on PATH."`
+
+	// PDFArgs are extra arguments for the PDF tool.
+	PDFArgs []string `name:"pdf-arg" sep:"none" help:"Extra argument for the PDF tool, e.g. --pdf-arg=--enable-local-file-access. Repeat for more; the list replaces the saved one."`
+
+	// PDFTimeout bounds the PDF conversion step.
+	PDFTimeout *time.Duration `name:"pdf-timeout" help:"Maximum time to wait for the PDF conversion, e.g. 60s."`
+
+	// PDFTitle is the pattern for the PDF's title.
+	PDFTitle *string `name:"pdf-title" help:"Title of the PDF, with {number}, {vendor}, {customer}, {month} and {year} filled in."`
+
+	// Thumbnail renders a PNG preview of each invoice.
+	Thumbnail *bool `negatable:"" help:"Render a PNG thumbnail of each invoice beside it (--no-thumbnail to save false)."`
+
+	// ThumbnailWidth is the width of the thumbnail in pixels.
+	ThumbnailWidth *int `name:"thumbnail-width" help:"Width of the thumbnail in pixels."`
+
+	// Retries is how many times a failed generation is retried.
+	Retries *int `help:"Retry a failed generation up to this many times, with backoff."`
+
+	// MaxCost is the most a generation may cost in dollars.
+	MaxCost *float64 `name:"max-cost" help:"Stop a generation once it has cost more than this many dollars."`
+
+	// Hook is a command to run after an invoice is generated.
+	Hook *string `help:"Command to run after an invoice is generated."`
+
+	// HookStrict controls whether a failing hook fails the run.
+	HookStrict *bool `name:"hook-strict" negatable:"" help:"Fail the run when the post-generation hook exits non-zero."`
+
+	// ServeToken is the bearer token required by 'invoicer serve'.
+	ServeToken *string `name:"serve-token" help:"Bearer token required by 'invoicer serve'."`
+
+	// Strict makes unknown keys in the config file an error.
+	Strict *bool `negatable:"" help:"Treat unknown keys in the config file as errors instead of warnings."`
+}
+
+// stdinIsTerminal reports whether standard input is a terminal. It can be
+// overridden in tests.
+var stdinIsTerminal = func() bool {
+	return term.IsTerminal(int(os.Stdin.Fd()))
 }
 
-// Run executes the 'set config' subcommand, writing specified options to ~/.invoicer/config.yaml.
+// Run executes the 'set config' subcommand, writing specified options to the config file.
 // Only options that are explicitly provided are updated; others remain unchanged.
-func (s *SetConfigCmd) Run() error {
-	return RunSetConfig(s, "")
+// An explicit --config path is created if it does not exist. Without any
+// options it asks for each value in turn on a terminal, and prints its
+// usage otherwise.
+func (s *SetConfigCmd) Run(g *Globals, kctx *kong.Context) error {
+	path, err := g.writePath()
+	if err != nil {
+		return err
+	}
+	if reflect.ValueOf(*s).IsZero() {
+		if !stdinIsTerminal() {
+			return kctx.PrintUsage(false)
+		}
+		return RunSetConfigInteractive(path, os.Stdin, os.Stdout)
+	}
+	return RunSetConfig(s, path, os.Stdout)
 }
 
-// RunSetConfig writes the given SetConfigCmd options to the config file at path.
-// If path is empty, the default path (~/.invoicer/config.yaml) is used.
+// RunSetConfig validates the given SetConfigCmd options and writes them to the
+// config file at path, then prints the resulting effective config to w.
+// If path is empty, the default path (see config.DefaultPath) is used.
 // This function is exported for testability.
-func RunSetConfig(s *SetConfigCmd, path string) error {
+func RunSetConfig(s *SetConfigCmd, path string, w io.Writer) error {
 	if path == "" {
 		var err error
 		path, err = config.DefaultPath()
@@ -59,10 +307,280 @@ func RunSetConfig(s *SetConfigCmd, path string) error {
 		Hours:    s.Hours,
 		PDF:      s.PDF,
 		Model:    s.Model,
+		Backend:  s.Backend,
+
+		IBAN:          s.IBAN,
+		BIC:           s.BIC,
+		RoutingNumber: s.RoutingNumber,
+		AccountNumber: s.AccountNumber,
+
+		VendorCountry:   s.VendorCountry,
+		CustomerCountry: s.CustomerCountry,
+		VendorVATID:     s.VendorVATID,
+		CustomerVATID:   s.CustomerVATID,
+
+		QuickBooksIncomeAccount: s.QuickBooksIncomeAccount,
+		QuickBooksItem:          s.QuickBooksItem,
+
+		XeroAccountCode: s.XeroAccountCode,
+		XeroTaxType:     s.XeroTaxType,
+		XeroRegion:      s.XeroRegion,
+
+		StripeAPIKey:        s.StripeAPIKey,
+		StripeCustomerID:    s.StripeCustomerID,
+		StripeCustomerEmail: s.StripeCustomerEmail,
+		StripeDaysUntilDue:  s.StripeDaysUntilDue,
+
+		PayPalClientID:      s.PayPalClientID,
+		PayPalClientSecret:  s.PayPalClientSecret,
+		PayPalSandbox:       s.PayPalSandbox,
+		PayPalCustomerEmail: s.PayPalCustomerEmail,
+		PayPalPaymentTerm:   s.PayPalPaymentTerm,
+
+		FreshBooksToken:     s.FreshBooksToken,
+		FreshBooksAccountID: s.FreshBooksAccountID,
+		FreshBooksClientID:  s.FreshBooksClientID,
+
+		EmailProvider:  s.EmailProvider,
+		SMTPHost:       s.SMTPHost,
+		SMTPPort:       s.SMTPPort,
+		SMTPSecurity:   s.SMTPSecurity,
+		SMTPUsername:   s.SMTPUsername,
+		SMTPPassword:   s.SMTPPassword,
+		GmailToken:     s.GmailToken,
+		SendGridAPIKey: s.SendGridAPIKey,
+		EmailFrom:      s.EmailFrom,
+		EmailTo:        s.EmailTo,
+		EmailSubject:   s.EmailSubject,
+		EmailBody:      s.EmailBody,
+
+		NotifyWebhook: s.NotifyWebhook,
+		NotifyMessage: s.NotifyMessage,
+
+		DateFormat:       s.DateFormat,
+		ExportDateFormat: s.ExportDateFormat,
+		Language:         s.Language,
+		ShowWorkdays:     s.ShowWorkdays,
+		RatePrecision:    s.RatePrecision,
+		HoursPrecision:   s.HoursPrecision,
+		DecimalSeparator: s.DecimalSeparator,
+
+		BillCurrency: s.BillCurrency,
+		FXProvider:   s.FXProvider,
+		FXAPIKey:     s.FXAPIKey,
+
+		Schedule:    s.Schedule,
+		ScheduleDay: s.ScheduleDay,
+		Timezone:    s.Timezone,
+
+		OllamaHost:  s.OllamaHost,
+		OllamaModel: s.OllamaModel,
+
+		Agent:   s.Agent,
+		Session: s.Session,
+
+		RestrictTools: s.RestrictTools,
+		DeniedTools:   s.DeniedTools,
+
+		Timeout: (*config.Duration)(s.Timeout),
+		Retries: s.Retries,
+		MaxCost: s.MaxCost,
+
+		PDFOnly:     s.PDFOnly,
+		PDFEngine:   s.PDFEngine,
+		PDFTool:     s.PDFTool,
+		PDFToolPath: s.PDFToolPath,
+		PDFToolArgs: s.PDFArgs,
+		PDFTimeout:  (*config.Duration)(s.PDFTimeout),
+		PDFTitle:    s.PDFTitle,
+
+		Thumbnail:      s.Thumbnail,
+		ThumbnailWidth: s.ThumbnailWidth,
+
+		PostGenerateHook: s.Hook,
+		HookStrict:       s.HookStrict,
+
+		ServeToken: s.ServeToken,
+		Strict:     s.Strict,
+	}
+
+	if s.HolidayCountry != nil {
+		updates.Holidays = &config.Holidays{Country: s.HolidayCountry}
+	}
+
+	// Currency codes are saved upper-cased, as --bill-currency reads them.
+	if updates.BillCurrency != nil {
+		currency := strings.ToUpper(*updates.BillCurrency)
+		updates.BillCurrency = &currency
+	}
+
+	// The model is checked as generate checks it, with the same way out.
+	checked := updates
+	if s.SkipModelCheck {
+		unchecked := *updates
+		unchecked.Model = nil
+		checked = &unchecked
+	} else if updates.Model != nil {
+		if err := checkModels(*updates.Model); err != nil {
+			return fmt.Errorf("invalid config: %w", err)
+		}
+	}
+	if err := validateConfig(checked); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
 	}
 
 	if err := config.Save(path, updates); err != nil {
 		return fmt.Errorf("saving config: %w", err)
 	}
+
+	cfg, err := config.Load(path)
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+	fmt.Fprintf(w, "Saved %s\n\n", path)
+	return RunShowConfig(&ShowConfigCmd{Format: "table"}, cfg, nil, os.LookupEnv, w)
+}
+
+// validateConfig checks the values of the fields set in c, beyond what
+// config.Validate checks: backends, bank and tax IDs, countries, the
+// export, email and notification settings, and the email and
+// notification templates, which are filled in for a sample invoice.
+func validateConfig(c *config.Config) error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+	if c.Backend != nil {
+		if err := validateBackend(*c.Backend); err != nil {
+			return err
+		}
+	}
+
+	if c.IBAN != nil && *c.IBAN != "" {
+		if err := invoice.ValidateIBAN(*c.IBAN); err != nil {
+			return err
+		}
+	}
+	if c.BIC != nil && *c.BIC != "" {
+		if err := invoice.ValidateBIC(*c.BIC); err != nil {
+			return err
+		}
+	}
+	if c.RoutingNumber != nil && *c.RoutingNumber != "" {
+		if err := invoice.ValidateRoutingNumber(*c.RoutingNumber); err != nil {
+			return err
+		}
+	}
+	if c.AccountNumber != nil && *c.AccountNumber != "" {
+		if err := invoice.ValidateAccountNumber(*c.AccountNumber); err != nil {
+			return err
+		}
+	}
+	for _, country := range []*string{c.VendorCountry, c.CustomerCountry} {
+		if country != nil && *country != "" {
+			if err := invoice.ValidateCountryCode(*country); err != nil {
+				return err
+			}
+		}
+	}
+	for _, id := range []*string{c.VendorVATID, c.CustomerVATID} {
+		if id != nil && *id != "" {
+			if err := invoice.ValidateVATID(*id); err != nil {
+				return err
+			}
+		}
+	}
+
+	if c.XeroRegion != nil && *c.XeroRegion != "" {
+		if err := invoice.ValidateXeroRegion(*c.XeroRegion); err != nil {
+			return err
+		}
+	}
+
+	if c.PayPalPaymentTerm != nil && *c.PayPalPaymentTerm != "" {
+		if err := invoice.ValidatePayPalPaymentTerm(*c.PayPalPaymentTerm); err != nil {
+			return err
+		}
+	}
+
+	if c.BillCurrency != nil && *c.BillCurrency != "" {
+		if err := invoice.ValidateCurrency(*c.BillCurrency); err != nil {
+			return err
+		}
+	}
+	if c.FXProvider != nil && *c.FXProvider != "" {
+		if err := invoice.ValidateFXProvider(*c.FXProvider); err != nil {
+			return err
+		}
+	}
+
+	if c.EmailProvider != nil && *c.EmailProvider != "" {
+		if err := invoice.ValidateEmailProvider(*c.EmailProvider); err != nil {
+			return err
+		}
+	}
+	if c.SMTPSecurity != nil && *c.SMTPSecurity != "" {
+		if err := invoice.ValidateSMTPSecurity(*c.SMTPSecurity); err != nil {
+			return err
+		}
+	}
+	for _, addrs := range []*string{c.EmailFrom, c.EmailTo} {
+		if addrs != nil && *addrs != "" {
+			if _, err := invoice.ParseAddresses(*addrs); err != nil {
+				return err
+			}
+		}
+	}
+	if c.EmailSubject != nil || c.EmailBody != nil {
+		// Fill the templates in for a sample invoice to catch mistakes now.
+		var subject, body string
+		if c.EmailSubject != nil {
+			subject = *c.EmailSubject
+		}
+		if c.EmailBody != nil {
+			body = *c.EmailBody
+		}
+		inv := &invoice.Invoice{Month: time.January, Year: 2025}
+		if _, _, err := invoice.RenderEmail(inv, subject, body); err != nil {
+			return err
+		}
+	}
+
+	for _, format := range []*string{c.DateFormat, c.ExportDateFormat} {
+		if format != nil && *format != "" {
+			if err := invoice.ValidateDateFormat(*format); err != nil {
+				return err
+			}
+		}
+	}
+
+	if c.Language != nil && *c.Language != "" {
+		if err := invoice.ValidateLanguage(*c.Language); err != nil {
+			return err
+		}
+	}
+
+	if country := c.HolidayCountry(); country != "" {
+		if err := invoice.ValidateHolidayRegion(country); err != nil {
+			return err
+		}
+	}
+
+	if c.Schedule != nil && *c.Schedule != "" {
+		if err := invoice.ValidateSchedule(*c.Schedule); err != nil {
+			return err
+		}
+	}
+
+	if c.NotifyWebhook != nil && *c.NotifyWebhook != "" {
+		if _, err := invoice.NotifyProvider(*c.NotifyWebhook); err != nil {
+			return err
+		}
+	}
+	if c.NotifyMessage != nil {
+		inv := &invoice.Invoice{Month: time.January, Year: 2025}
+		if _, err := invoice.RenderNotification(invoice.NotifySlack, *c.NotifyMessage, inv, ""); err != nil {
+			return err
+		}
+	}
 	return nil
 }
@@ -1,8 +1,11 @@
 package cli
 
 import (
+	"bytes"
+	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/zon/invoicer/internal/config"
@@ -13,15 +16,15 @@ func TestRunSetConfig_CreatesConfig(t *testing.T) {
 	path := filepath.Join(dir, "config.yaml")
 
 	cmd := &SetConfigCmd{
-		Vendor:   "Test Vendor",
-		Customer: "Test Client",
-		Rate:     120,
-		Hours:    40,
+		Vendor:   strPtr("Test Vendor"),
+		Customer: strPtr("Test Client"),
+		Rate:     floatPtr(120),
+		Hours:    floatPtr(40),
 		PDF:      boolPtr(true),
-		Model:    "anthropic/claude-haiku-4-5",
+		Model:    strPtr("anthropic/claude-haiku-4-5"),
 	}
 
-	if err := RunSetConfig(cmd, path); err != nil {
+	if err := RunSetConfig(cmd, path, io.Discard); err != nil {
 		t.Fatalf("RunSetConfig: %v", err)
 	}
 
@@ -29,23 +32,23 @@ func TestRunSetConfig_CreatesConfig(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Load: %v", err)
 	}
-	if cfg.Vendor != "Test Vendor" {
-		t.Errorf("Vendor: got %q, want %q", cfg.Vendor, "Test Vendor")
+	if cfg.Vendor == nil || *cfg.Vendor != "Test Vendor" {
+		t.Errorf("Vendor: got %q, want %q", str(cfg.Vendor), "Test Vendor")
 	}
-	if cfg.Customer != "Test Client" {
-		t.Errorf("Customer: got %q, want %q", cfg.Customer, "Test Client")
+	if cfg.Customer == nil || *cfg.Customer != "Test Client" {
+		t.Errorf("Customer: got %q, want %q", str(cfg.Customer), "Test Client")
 	}
-	if cfg.Rate != 120 {
-		t.Errorf("Rate: got %v, want 120", cfg.Rate)
+	if cfg.Rate == nil || *cfg.Rate != 120 {
+		t.Errorf("Rate: got %v, want 120", num(cfg.Rate))
 	}
-	if cfg.Hours != 40 {
-		t.Errorf("Hours: got %v, want 40", cfg.Hours)
+	if cfg.Hours == nil || *cfg.Hours != 40 {
+		t.Errorf("Hours: got %v, want 40", num(cfg.Hours))
 	}
 	if cfg.PDF == nil || !*cfg.PDF {
 		t.Errorf("PDF: got %v, want true", cfg.PDF)
 	}
-	if cfg.Model != "anthropic/claude-haiku-4-5" {
-		t.Errorf("Model: got %q, want %q", cfg.Model, "anthropic/claude-haiku-4-5")
+	if cfg.Model == nil || *cfg.Model != "anthropic/claude-haiku-4-5" {
+		t.Errorf("Model: got %q, want %q", str(cfg.Model), "anthropic/claude-haiku-4-5")
 	}
 }
 
@@ -65,9 +68,9 @@ hours: 30
 
 	// Only update vendor.
 	cmd := &SetConfigCmd{
-		Vendor: "Updated Vendor",
+		Vendor: strPtr("Updated Vendor"),
 	}
-	if err := RunSetConfig(cmd, path); err != nil {
+	if err := RunSetConfig(cmd, path, io.Discard); err != nil {
 		t.Fatalf("RunSetConfig: %v", err)
 	}
 
@@ -75,18 +78,18 @@ hours: 30
 	if err != nil {
 		t.Fatalf("Load: %v", err)
 	}
-	if cfg.Vendor != "Updated Vendor" {
-		t.Errorf("Vendor: got %q, want Updated Vendor", cfg.Vendor)
+	if cfg.Vendor == nil || *cfg.Vendor != "Updated Vendor" {
+		t.Errorf("Vendor: got %q, want Updated Vendor", str(cfg.Vendor))
 	}
 	// Unchanged fields should persist.
-	if cfg.Customer != "Initial Customer" {
-		t.Errorf("Customer: expected unchanged; got %q", cfg.Customer)
+	if cfg.Customer == nil || *cfg.Customer != "Initial Customer" {
+		t.Errorf("Customer: expected unchanged; got %q", str(cfg.Customer))
 	}
-	if cfg.Rate != 80 {
-		t.Errorf("Rate: expected unchanged; got %v", cfg.Rate)
+	if cfg.Rate == nil || *cfg.Rate != 80 {
+		t.Errorf("Rate: expected unchanged; got %v", num(cfg.Rate))
 	}
-	if cfg.Hours != 30 {
-		t.Errorf("Hours: expected unchanged; got %v", cfg.Hours)
+	if cfg.Hours == nil || *cfg.Hours != 30 {
+		t.Errorf("Hours: expected unchanged; got %v", num(cfg.Hours))
 	}
 }
 
@@ -95,9 +98,9 @@ func TestRunSetConfig_CreatesDirectory(t *testing.T) {
 	path := filepath.Join(dir, "subdir", "config.yaml")
 
 	cmd := &SetConfigCmd{
-		Vendor: "Test Vendor",
+		Vendor: strPtr("Test Vendor"),
 	}
-	if err := RunSetConfig(cmd, path); err != nil {
+	if err := RunSetConfig(cmd, path, io.Discard); err != nil {
 		t.Fatalf("RunSetConfig: %v", err)
 	}
 
@@ -105,3 +108,167 @@ func TestRunSetConfig_CreatesDirectory(t *testing.T) {
 		t.Errorf("expected config file to exist: %v", err)
 	}
 }
+
+func TestSetConfigRun_UsesGlobalConfigPath(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "side.yaml")
+	cmd := &SetConfigCmd{Vendor: strPtr("Side Co")}
+	if err := cmd.Run(&Globals{Config: path}, nil); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Vendor == nil || *cfg.Vendor != "Side Co" {
+		t.Errorf("Vendor: got %q, want %q", str(cfg.Vendor), "Side Co")
+	}
+}
+
+func TestSetConfig_NegatedBoolsSaveFalse(t *testing.T) {
+	cmd := parseCLI(t, "set", "config", "--no-pdf", "--no-hook-strict")
+	s := &cmd.Set.Config
+	if s.PDF == nil || *s.PDF {
+		t.Errorf("--no-pdf: got %v, want explicit false", s.PDF)
+	}
+	if s.HookStrict == nil || *s.HookStrict {
+		t.Errorf("--no-hook-strict: got %v, want explicit false", s.HookStrict)
+	}
+	if s.Strict != nil {
+		t.Errorf("Strict: expected nil when not given, got %v", *s.Strict)
+	}
+}
+
+func TestRunSetConfig_RejectsInvalidValues(t *testing.T) {
+	tests := []struct {
+		name string
+		cmd  *SetConfigCmd
+		want string
+	}{
+		{"negative rate", &SetConfigCmd{Rate: floatPtr(-50)}, "rate"},
+		{"zero rate", &SetConfigCmd{Rate: floatPtr(0)}, "rate"},
+		{"zero hours", &SetConfigCmd{Hours: floatPtr(0)}, "hours"},
+		{"too many hours", &SetConfigCmd{Hours: floatPtr(169)}, "hours"},
+		{"model without provider", &SetConfigCmd{Model: strPtr("banana")}, "model"},
+		{"model with whitespace", &SetConfigCmd{Model: strPtr("anthropic/claude haiku")}, "model"},
+		{"unknown backend", &SetConfigCmd{Backend: strPtr("gpt")}, "backend"},
+		{"ollama host without scheme", &SetConfigCmd{OllamaHost: strPtr("localhost:11434")}, "ollama_host"},
+		{"IBAN with bad check digits", &SetConfigCmd{IBAN: strPtr("DE89370400440532013001")}, "IBAN"},
+		{"short BIC", &SetConfigCmd{BIC: strPtr("COBA")}, "BIC"},
+		{"IBAN of the wrong length", &SetConfigCmd{IBAN: strPtr("DE543704004405320130001")}, "IBANs from DE have 22 characters"},
+		{"routing number with bad check digit", &SetConfigCmd{RoutingNumber: strPtr("021000022")}, "routing number"},
+		{"account number with letters", &SetConfigCmd{AccountNumber: strPtr("ACCT-1234")}, "account number"},
+		{"lowercase country", &SetConfigCmd{VendorCountry: strPtr("de")}, "country"},
+		{"unknown country", &SetConfigCmd{CustomerCountry: strPtr("UK")}, "country"},
+		{"VAT ID without prefix", &SetConfigCmd{VendorVATID: strPtr("123456789")}, "VAT ID"},
+		{"unknown Xero region", &SetConfigCmd{XeroRegion: strPtr("EU")}, "Xero region"},
+		{"unknown SMTP security", &SetConfigCmd{SMTPSecurity: strPtr("ssl")}, "SMTP security"},
+		{"SMTP port out of range", &SetConfigCmd{SMTPPort: intPtr(70000)}, "smtp_port"},
+		{"bad recipient", &SetConfigCmd{EmailTo: strPtr("ap@acme.example, accounts")}, "email addresses"},
+		{"broken subject template", &SetConfigCmd{EmailSubject: strPtr("Invoice {{.Number")}, "email subject template"},
+		{"unknown currency", &SetConfigCmd{BillCurrency: strPtr("euro")}, "currency"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			err := RunSetConfig(tt.cmd, path, io.Discard)
+			if err == nil {
+				t.Fatal("expected validation error, got nil")
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error %q does not mention %s", err, tt.want)
+			}
+			if _, err := os.Stat(path); !os.IsNotExist(err) {
+				t.Error("config file should not be written when validation fails")
+			}
+		})
+	}
+}
+
+func TestRunSetConfig_UpperCasesBillCurrency(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := RunSetConfig(&SetConfigCmd{BillCurrency: strPtr("eur")}, path, io.Discard); err != nil {
+		t.Fatalf("RunSetConfig: %v", err)
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.BillCurrency == nil || *cfg.BillCurrency != "EUR" {
+		t.Errorf("bill_currency = %v, want EUR", cfg.BillCurrency)
+	}
+}
+
+func TestRunSetConfig_HolidayCountry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := RunSetConfig(&SetConfigCmd{HolidayCountry: strPtr("DE-BY")}, path, io.Discard); err != nil {
+		t.Fatalf("RunSetConfig: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "holidays:\n    country: DE-BY\n") {
+		t.Errorf("expected the country saved in a holidays: block, got:\n%s", data)
+	}
+
+	err = RunSetConfig(&SetConfigCmd{HolidayCountry: strPtr("XX")}, path, io.Discard)
+	if err == nil {
+		t.Error("expected an unknown holiday country to be rejected")
+	}
+}
+
+func TestRunSetConfig_SkipModelCheck(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	err := RunSetConfig(&SetConfigCmd{Model: strPtr("banana")}, path, io.Discard)
+	if err == nil || !strings.Contains(err.Error(), "--skip-model-check") {
+		t.Fatalf("expected a model error suggesting --skip-model-check, got %v", err)
+	}
+
+	var warnings strings.Builder
+	old := config.Warnings
+	config.Warnings = &warnings
+	t.Cleanup(func() { config.Warnings = old })
+	if err := RunSetConfig(&SetConfigCmd{Model: strPtr("acme/llm-1")}, path, io.Discard); err != nil {
+		t.Fatalf("RunSetConfig: %v", err)
+	}
+	if !strings.Contains(warnings.String(), `model "acme/llm-1" is from an unknown provider`) {
+		t.Errorf("warnings = %q, want the unknown provider", warnings.String())
+	}
+
+	// The check is skipped, but the rest of the config is still checked.
+	if err := RunSetConfig(&SetConfigCmd{Model: strPtr("banana"), SkipModelCheck: true}, path, io.Discard); err != nil {
+		t.Fatalf("RunSetConfig with SkipModelCheck: %v", err)
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Model == nil || *cfg.Model != "banana" {
+		t.Errorf("model = %v, want banana saved as given", cfg.Model)
+	}
+	if err := RunSetConfig(&SetConfigCmd{Model: strPtr("banana"), Rate: floatPtr(-1), SkipModelCheck: true}, path, io.Discard); err == nil || !strings.Contains(err.Error(), "rate") {
+		t.Errorf("expected the rate to be checked with SkipModelCheck, got %v", err)
+	}
+}
+
+func TestRunSetConfig_EchoesEffectiveConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	var out bytes.Buffer
+	if err := RunSetConfig(&SetConfigCmd{Rate: floatPtr(150)}, path, &out); err != nil {
+		t.Fatalf("RunSetConfig: %v", err)
+	}
+	got := out.String()
+	if !strings.Contains(got, "Saved "+path) {
+		t.Errorf("expected saved path in output, got:\n%s", got)
+	}
+	for _, line := range strings.Split(got, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 3 && fields[0] == "rate" {
+			if fields[1] != "150" || fields[2] != "config" {
+				t.Errorf("rate line = %q, want value 150 from config", line)
+			}
+			return
+		}
+	}
+	t.Errorf("rate missing from echoed config:\n%s", got)
+}
@@ -0,0 +1,190 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/zon/invoicer/internal/config"
+)
+
+// clearValue, entered at a 'set config' prompt, removes the key from the
+// config file.
+const clearValue = "-"
+
+// configChange is a config key changed at the prompts, with its value
+// before and after as they are displayed; "" means unset.
+type configChange struct {
+	key      string
+	old, new string
+	index    int
+}
+
+// RunSetConfigInteractive asks for the value of each config key in turn on
+// out, reading the answers from in, and writes the changes to the config
+// file at path once all keys are answered. Each prompt shows the current
+// value: a blank answer keeps it, clearValue removes the key, and anything
+// else is checked as 'set config' checks a flag and asked again if it is
+// not valid. At the end of in, the remaining keys are kept. If path is
+// empty, the default path (see config.DefaultPath) is used.
+// This function is exported for testability.
+func RunSetConfigInteractive(path string, in io.Reader, out io.Writer) error {
+	if path == "" {
+		var err error
+		path, err = config.DefaultPath()
+		if err != nil {
+			return fmt.Errorf("determining config path: %w", err)
+		}
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+
+	fmt.Fprintf(out, "Editing %s. Press Enter to keep a value, enter %s to clear it, or press Ctrl-D to keep the rest.\n\n", path, clearValue)
+	edited, changes, err := promptConfig(cfg, bufio.NewScanner(in), out)
+	if err != nil {
+		return err
+	}
+	if len(changes) == 0 {
+		fmt.Fprintf(out, "\nNo changes; %s was not written.\n", path)
+		return nil
+	}
+
+	return saveChanges(path, edited, changes, out)
+}
+
+// saveChanges writes the changed keys of edited to the config file at
+// path, leaving the others as they are, and lists the changes on out.
+func saveChanges(path string, edited *config.Config, changes []configChange, out io.Writer) error {
+	err := config.Edit(path, func(c *config.Config) {
+		v, ev := reflect.ValueOf(c).Elem(), reflect.ValueOf(edited).Elem()
+		for _, ch := range changes {
+			v.Field(ch.index).Set(ev.Field(ch.index))
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("saving config: %w", err)
+	}
+
+	fmt.Fprintf(out, "\nSaved %s:\n", path)
+	for _, ch := range changes {
+		switch {
+		case ch.new == "":
+			fmt.Fprintf(out, "  %s: cleared (was %s)\n", ch.key, ch.old)
+		case ch.old == "":
+			fmt.Fprintf(out, "  %s: set to %s\n", ch.key, ch.new)
+		default:
+			fmt.Fprintf(out, "  %s: %s -> %s\n", ch.key, ch.old, ch.new)
+		}
+	}
+	return nil
+}
+
+// promptConfig asks for each key of cfg in turn and returns a copy of cfg
+// with the answers and the keys that changed.
+func promptConfig(cfg *config.Config, in *bufio.Scanner, out io.Writer) (*config.Config, []configChange, error) {
+	edited := *cfg
+	var changes []configChange
+	for _, key := range config.Keys() {
+		if !promptable(key) {
+			continue
+		}
+		ch, ok, err := promptKey(&edited, cfg, key, "", in, out)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !ok {
+			return &edited, changes, nil
+		}
+		if ch != nil {
+			changes = append(changes, *ch)
+		}
+	}
+	return &edited, changes, nil
+}
+
+// promptKey asks for the value of key in edited until the answer is valid
+// and sets it, showing its value in edited, or def if it has none, as the
+// answer a blank line keeps. It returns the change from orig, or nil if
+// the value is as in orig, and false at the end of in.
+func promptKey(edited, orig *config.Config, key, def string, in *bufio.Scanner, out io.Writer) (*configChange, bool, error) {
+	v := reflect.ValueOf(edited).Elem()
+	i := configField(key)
+	secret := hasTag(v.Type().Field(i).Tag, "secret")
+	field := effectiveField{Key: key, Value: v.Field(i), Secret: secret}
+	old := effectiveField{Key: key, Value: reflect.ValueOf(orig).Elem().Field(i), Secret: secret}.display()
+	shown := field.display()
+	if shown == "" {
+		shown = def
+	}
+	for {
+		if shown == "" {
+			fmt.Fprintf(out, "%s: ", key)
+		} else {
+			fmt.Fprintf(out, "%s [%s]: ", key, shown)
+		}
+		if !in.Scan() {
+			if err := in.Err(); err != nil {
+				return nil, false, fmt.Errorf("reading the answer: %w", err)
+			}
+			fmt.Fprintln(out)
+			return nil, false, nil
+		}
+		answer := strings.TrimSpace(in.Text())
+		if answer == "" {
+			break
+		}
+		if answer == clearValue {
+			field.Value.SetZero()
+			break
+		}
+		// Check the answer on its own, as 'set config' checks a flag.
+		var single config.Config
+		sv := reflect.ValueOf(&single).Elem().Field(i)
+		err := setFromString(sv, answer)
+		if err == nil {
+			err = validateConfig(&single)
+		}
+		if err != nil {
+			fmt.Fprintf(out, "  %s is not valid: %v\n", key, err)
+			continue
+		}
+		field.Value.Set(sv)
+		break
+	}
+	if reflect.DeepEqual(field.Value.Interface(), reflect.ValueOf(orig).Elem().Field(i).Interface()) {
+		return nil, true, nil
+	}
+	return &configChange{key: key, old: old, new: field.display(), index: i}, true, nil
+}
+
+// configField returns the index of the config.Config field with the YAML
+// key key, which must be one of config.Keys.
+func configField(key string) int {
+	t := reflect.TypeFor[config.Config]()
+	for i := range t.NumField() {
+		if k, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ","); k == key {
+			return i
+		}
+	}
+	panic("cli: unknown config key " + key)
+}
+
+// promptable reports whether key's value can be answered in one line:
+// lists of entries, such as vendors, are only edited in the file.
+func promptable(key string) bool {
+	t := reflect.TypeFor[config.Config]().Field(configField(key)).Type
+	return t.Kind() != reflect.Slice || t.Elem().Kind() == reflect.String
+}
+
+// display formats the field's value for a prompt, with a secret redacted.
+func (f effectiveField) display() string {
+	s := f.String()
+	if f.Secret && s != "" {
+		return redacted
+	}
+	return s
+}
@@ -0,0 +1,190 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/kong"
+	"github.com/zon/invoicer/internal/config"
+)
+
+// promptAnswers returns the input that answers the 'set config' prompts
+// with answers by key, blank for the keys before the last one answered.
+// An answer may hold several lines, for a key that is asked again.
+func promptAnswers(answers map[string]string) string {
+	keys := config.Keys()
+	last := 0
+	for i, key := range keys {
+		if _, ok := answers[key]; ok {
+			last = i
+		}
+	}
+	var sb strings.Builder
+	for _, key := range keys[:last+1] {
+		if !promptable(key) {
+			continue
+		}
+		sb.WriteString(answers[key] + "\n")
+	}
+	return sb.String()
+}
+
+func TestRunSetConfigInteractive(t *testing.T) {
+	path := writeTestConfig(t, `vendor: Old Co
+customer: Acme Corp
+rate: 100
+hours: 40
+pdf: true
+timeout: 2m
+pdf_tool_args: [--quiet]
+retries: 2
+`)
+	in := promptAnswers(map[string]string{
+		"vendor":        "New Co",
+		"customer":      "",
+		"rate":          "-",
+		"pdf":           "no",
+		"model":         "-",
+		"backend":       "claude",
+		"timeout":       "90s",
+		"pdf_tool_args": "--quiet --zoom=2",
+		"retries":       "-",
+		"max_cost":      "0.5",
+	})
+	var out bytes.Buffer
+	if err := RunSetConfigInteractive(path, strings.NewReader(in), &out); err != nil {
+		t.Fatalf("RunSetConfigInteractive: %v", err)
+	}
+
+	cfg := loadTestConfig(t, path)
+	switch {
+	case str(cfg.Vendor) != "New Co":
+		t.Errorf("vendor = %q, want changed", str(cfg.Vendor))
+	case str(cfg.Customer) != "Acme Corp":
+		t.Errorf("customer = %q, want kept", str(cfg.Customer))
+	case cfg.Rate != nil:
+		t.Errorf("rate = %v, want cleared", *cfg.Rate)
+	case cfg.Hours == nil || *cfg.Hours != 40:
+		t.Errorf("hours = %v, want kept", cfg.Hours)
+	case cfg.PDF == nil || *cfg.PDF:
+		t.Errorf("pdf = %v, want false", cfg.PDF)
+	case cfg.Model != nil:
+		t.Errorf("model = %q, want unset", *cfg.Model)
+	case str(cfg.Backend) != "claude":
+		t.Errorf("backend = %q, want set", str(cfg.Backend))
+	case cfg.Timeout == nil || time.Duration(*cfg.Timeout) != 90*time.Second:
+		t.Errorf("timeout = %v, want 90s", cfg.Timeout)
+	case !slices.Equal(cfg.PDFToolArgs, []string{"--quiet", "--zoom=2"}):
+		t.Errorf("pdf_tool_args = %q", cfg.PDFToolArgs)
+	case cfg.Retries != nil:
+		t.Errorf("retries = %d, want cleared", *cfg.Retries)
+	case cfg.MaxCost == nil || *cfg.MaxCost != 0.5:
+		t.Errorf("max_cost = %v, want 0.5", cfg.MaxCost)
+	}
+
+	got := out.String()
+	for _, want := range []string{
+		"vendor [Old Co]: ",
+		"model: ",
+		"  vendor: Old Co -> New Co\n",
+		"  rate: cleared (was 100)\n",
+		"  pdf: true -> false\n",
+		"  backend: set to claude\n",
+		"  timeout: 2m0s -> 1m30s\n",
+		"  pdf_tool_args: --quiet -> --quiet --zoom=2\n",
+		"  retries: cleared (was 2)\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output does not contain %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "\n  customer:") {
+		t.Errorf("customer was not changed but is in the summary:\n%s", got)
+	}
+}
+
+func TestRunSetConfigInteractive_AsksAgain(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	in := promptAnswers(map[string]string{
+		"rate":           "abc\n-5\n175",
+		"model":          "not a model\nanthropic/claude-haiku-4-5",
+		"iban":           "DE00 1234\nDE89370400440532013000",
+		"stripe_api_key": "sk_test_1",
+	})
+	var out bytes.Buffer
+	if err := RunSetConfigInteractive(path, strings.NewReader(in), &out); err != nil {
+		t.Fatalf("RunSetConfigInteractive: %v", err)
+	}
+	cfg := loadTestConfig(t, path)
+	if cfg.Rate == nil || *cfg.Rate != 175 || str(cfg.Model) != "anthropic/claude-haiku-4-5" || str(cfg.IBAN) != "DE89370400440532013000" {
+		t.Errorf("got rate %v, model %q, iban %q", cfg.Rate, str(cfg.Model), str(cfg.IBAN))
+	}
+	got := out.String()
+	if n := strings.Count(got, "rate is not valid"); n != 2 {
+		t.Errorf("rate asked again %d times, want 2:\n%s", n, got)
+	}
+	for _, key := range []string{"model", "iban"} {
+		if !strings.Contains(got, key+" is not valid") {
+			t.Errorf("%s was not asked again:\n%s", key, got)
+		}
+	}
+	if strings.Contains(got, "sk_test_1") {
+		t.Errorf("the secret is shown:\n%s", got)
+	}
+}
+
+func TestRunSetConfigInteractive_RedactsSecrets(t *testing.T) {
+	path := writeTestConfig(t, "stripe_api_key: sk_test_1\n")
+	var out bytes.Buffer
+	if err := RunSetConfigInteractive(path, strings.NewReader(promptAnswers(map[string]string{"stripe_api_key": ""})), &out); err != nil {
+		t.Fatalf("RunSetConfigInteractive: %v", err)
+	}
+	if !strings.Contains(out.String(), "stripe_api_key ["+redacted+"]: ") || strings.Contains(out.String(), "sk_test_1") {
+		t.Errorf("the secret is not redacted:\n%s", out.String())
+	}
+}
+
+func TestRunSetConfigInteractive_NoChanges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	var out bytes.Buffer
+	if err := RunSetConfigInteractive(path, strings.NewReader("\n\n-\n"), &out); err != nil {
+		t.Fatalf("RunSetConfigInteractive: %v", err)
+	}
+	if !strings.Contains(out.String(), "No changes") {
+		t.Errorf("output = %q", out.String())
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("the config was written: %v", err)
+	}
+}
+
+func TestSetConfigRun_NoFlagsPrintsUsage(t *testing.T) {
+	var cmd CLI
+	var out bytes.Buffer
+	p, err := kong.New(&cmd, kong.Name("invoicer"), kong.Writers(&out, &out), kong.Exit(func(int) {}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, err := p.Parse([]string{"set", "config"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	orig := stdinIsTerminal
+	t.Cleanup(func() { stdinIsTerminal = orig })
+	stdinIsTerminal = func() bool { return false }
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := ctx.Run(&Globals{Config: path}); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if !strings.Contains(out.String(), "Usage: invoicer set config") {
+		t.Errorf("expected the usage, got:\n%s", out.String())
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("the config was written: %v", err)
+	}
+}
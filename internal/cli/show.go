@@ -0,0 +1,383 @@
+package cli
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/zon/invoicer/internal/config"
+	"github.com/zon/invoicer/pkg/invoice"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultModel is the model used when none is configured.
+const defaultModel = "anthropic/claude-haiku-4-5"
+
+// defaultTimeout bounds generation when no timeout is configured.
+const defaultTimeout = 5 * time.Minute
+
+// defaultPDFTimeout bounds PDF conversion when no pdf_timeout is configured.
+const defaultPDFTimeout = time.Minute
+
+// redacted replaces secret values in displayed config.
+const redacted = "********"
+
+// Source identifies where an effective config value came from.
+type Source string
+
+// Sources of effective config values, from lowest to highest precedence.
+const (
+	SourceUnset   Source = "unset"
+	SourceDefault Source = "default"
+	SourceConfig  Source = "config"
+	SourceLocal   Source = "local"
+	SourceEnv     Source = "env"
+)
+
+// configEnv maps config keys to the environment variables that override them.
+// The names match the env tags on GenerateCmd, ServeCmd and the export
+// commands.
+var configEnv = map[string]string{
+	"vendor":                "INVOICER_VENDOR",
+	"customer":              "INVOICER_CUSTOMER",
+	"rate":                  "INVOICER_RATE",
+	"hours":                 "INVOICER_HOURS",
+	"pdf":                   "INVOICER_PDF",
+	"model":                 "INVOICER_MODEL",
+	"backend":               "INVOICER_BACKEND",
+	"iban":                  "INVOICER_IBAN",
+	"bic":                   "INVOICER_BIC",
+	"routing_number":        "INVOICER_ROUTING_NUMBER",
+	"account_number":        "INVOICER_ACCOUNT_NUMBER",
+	"vendor_country":        "INVOICER_VENDOR_COUNTRY",
+	"customer_country":      "INVOICER_CUSTOMER_COUNTRY",
+	"vendor_vat_id":         "INVOICER_VENDOR_VAT_ID",
+	"customer_vat_id":       "INVOICER_CUSTOMER_VAT_ID",
+	"stripe_api_key":        "INVOICER_STRIPE_API_KEY",
+	"stripe_customer_id":    "INVOICER_STRIPE_CUSTOMER_ID",
+	"stripe_customer_email": "INVOICER_STRIPE_CUSTOMER_EMAIL",
+	"paypal_client_id":      "INVOICER_PAYPAL_CLIENT_ID",
+	"paypal_client_secret":  "INVOICER_PAYPAL_CLIENT_SECRET",
+	"paypal_customer_email": "INVOICER_PAYPAL_CUSTOMER_EMAIL",
+	"freshbooks_token":      "INVOICER_FRESHBOOKS_TOKEN",
+	"freshbooks_account_id": "INVOICER_FRESHBOOKS_ACCOUNT_ID",
+	"smtp_password":         "INVOICER_SMTP_PASSWORD",
+	"gmail_token":           "INVOICER_GMAIL_TOKEN",
+	"sendgrid_api_key":      "INVOICER_SENDGRID_API_KEY",
+	"notify_webhook":        "INVOICER_NOTIFY_WEBHOOK",
+	"bill_currency":         "INVOICER_BILL_CURRENCY",
+	"language":              "INVOICER_LANGUAGE",
+	"holidays":              "INVOICER_HOLIDAY_COUNTRY",
+	"ollama_host":           "INVOICER_OLLAMA_HOST",
+	"ollama_model":          "INVOICER_OLLAMA_MODEL",
+	"agent":                 "INVOICER_AGENT",
+	"session":               "INVOICER_SESSION",
+	"restrict_tools":        "INVOICER_RESTRICT_TOOLS",
+	"denied_tools":          "INVOICER_DENIED_TOOLS",
+	"timeout":               "INVOICER_TIMEOUT",
+	"pdf_only":              "INVOICER_PDF_ONLY",
+	"pdf_engine":            "INVOICER_PDF_ENGINE",
+	"pdf_tool":              "INVOICER_PDF_TOOL",
+	"pdf_tool_path":         "INVOICER_PDF_TOOL_PATH",
+	"pdf_timeout":           "INVOICER_PDF_TIMEOUT",
+	"pdf_title":             "INVOICER_PDF_TITLE",
+	"thumbnail":             "INVOICER_THUMBNAIL",
+	"thumbnail_width":       "INVOICER_THUMBNAIL_WIDTH",
+	"retries":               "INVOICER_RETRIES",
+	"max_cost":              "INVOICER_MAX_COST",
+	"post_generate_hook":    "INVOICER_HOOK",
+	"serve_token":           "INVOICER_SERVE_TOKEN",
+}
+
+// configDefaults holds the built-in default for config keys that have one.
+var configDefaults = map[string]string{
+	"pdf":     "false",
+	"model":   defaultModel,
+	"backend": invoice.DefaultBackend,
+
+	"quickbooks_income_account": invoice.DefaultQuickBooksIncomeAccount,
+	"quickbooks_item":           invoice.DefaultQuickBooksItem,
+	"xero_account_code":         invoice.DefaultXeroAccountCode,
+	"xero_region":               invoice.XeroRegionUS,
+	"stripe_days_until_due":     strconv.Itoa(invoice.DefaultStripeDaysUntilDue),
+	"paypal_sandbox":            "false",
+	"paypal_payment_term":       invoice.DefaultPayPalPaymentTerm,
+	"email_provider":            invoice.DefaultEmailProvider,
+	"smtp_port":                 strconv.Itoa(invoice.DefaultSMTPPort),
+	"smtp_security":             invoice.SMTPSecurityStartTLS,
+	"email_subject":             invoice.DefaultEmailSubject,
+	"notify_message":            invoice.DefaultNotifyMessage,
+	"date_format":               invoice.DateLong,
+	"language":                  invoice.LanguageEnglish,
+	"show_workdays":             "false",
+	"rate_precision":            strconv.Itoa(invoice.DefaultRatePrecision),
+	"hours_precision":           strconv.Itoa(invoice.DefaultHoursPrecision),
+	"fx_provider":               invoice.DefaultFXProvider,
+	"schedule_day":              "1",
+	"timezone":                  "Local",
+
+	"ollama_host":  invoice.DefaultOllamaHost,
+	"ollama_model": invoice.DefaultOllamaModel,
+
+	"restrict_tools": "true",
+	"denied_tools":   strings.Join(invoice.DefaultDeniedTools, ","),
+
+	"timeout":     defaultTimeout.String(),
+	"pdf_only":    "false",
+	"pdf_engine":  invoice.ExecPDFEngine,
+	"pdf_tool":    invoice.AutoPDFTool,
+	"pdf_timeout": defaultPDFTimeout.String(),
+	"pdf_title":   invoice.DefaultPDFTitle,
+
+	"thumbnail":       "false",
+	"thumbnail_width": strconv.Itoa(invoice.DefaultThumbnailWidth),
+
+	"retries": "0",
+}
+
+// effectiveField is one config key's effective value and where it came from.
+type effectiveField struct {
+	Key    string
+	Value  reflect.Value
+	Source Source
+	Secret bool
+}
+
+// String formats the field's value for display, or "" if it is unset.
+func (f effectiveField) String() string {
+	v := f.Value
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	} else if v.IsZero() {
+		return ""
+	}
+	switch v.Kind() {
+	case reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64)
+	case reflect.Slice:
+		if ss, ok := v.Interface().([]string); ok {
+			return strings.Join(ss, " ")
+		}
+		names := make([]string, v.Len())
+		for i := range names {
+			names[i] = fmt.Sprint(v.Index(i).Interface())
+		}
+		return strings.Join(names, ", ")
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
+
+// effectiveConfig applies the local config, environment overrides, and built-in
+// defaults to cfg, returning the resulting config and the provenance of each key.
+// local may be nil.
+func effectiveConfig(cfg, local *config.Config, lookupEnv func(string) (string, bool)) (*config.Config, []effectiveField, error) {
+	eff := *cfg
+	var lv reflect.Value
+	if local != nil {
+		lv = reflect.ValueOf(local).Elem()
+	}
+	fields, err := applyEffective(reflect.ValueOf(&eff).Elem(), lv, lookupEnv)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &eff, fields, nil
+}
+
+// applyEffective applies local overrides, environment overrides, and defaults to
+// the yaml-tagged fields of the struct v in place and returns their provenance.
+// local is a struct of the same type, or the zero Value if there is none.
+func applyEffective(v, local reflect.Value, lookupEnv func(string) (string, bool)) ([]effectiveField, error) {
+	t := v.Type()
+
+	var fields []effectiveField
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		key, _, _ := strings.Cut(sf.Tag.Get("yaml"), ",")
+		if key == "" || key == "-" {
+			continue
+		}
+		f := effectiveField{
+			Key:    key,
+			Value:  v.Field(i),
+			Source: SourceUnset,
+			Secret: hasTag(sf.Tag, "secret"),
+		}
+		if !f.Value.IsZero() {
+			f.Source = SourceConfig
+		}
+		if local.IsValid() {
+			if lf := local.Field(i); !lf.IsZero() {
+				f.Value.Set(lf)
+				f.Source = SourceLocal
+			}
+		}
+		if env, ok := configEnv[key]; ok {
+			if raw, ok := lookupEnv(env); ok {
+				if err := setFromString(f.Value, raw); err != nil {
+					return nil, fmt.Errorf("invalid %s=%q: %w", env, raw, err)
+				}
+				f.Source = SourceEnv
+			}
+		}
+		if def, ok := configDefaults[key]; ok && f.Source == SourceUnset {
+			if err := setFromString(f.Value, def); err != nil {
+				return nil, fmt.Errorf("default for %s: %w", key, err)
+			}
+			f.Source = SourceDefault
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// redactSecrets replaces the values of set secret fields with a placeholder.
+func redactSecrets(fields []effectiveField) error {
+	for _, f := range fields {
+		if f.Secret && f.Source != SourceUnset {
+			if err := setFromString(f.Value, redacted); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// hasTag reports whether the struct tag contains the given key.
+func hasTag(tag reflect.StructTag, key string) bool {
+	_, ok := tag.Lookup(key)
+	return ok
+}
+
+// setFromString parses s into the field v according to its kind.
+func setFromString(v reflect.Value, s string) error {
+	if v.Kind() == reflect.Pointer {
+		p := reflect.New(v.Type().Elem())
+		if err := setFromString(p.Elem(), s); err != nil {
+			return err
+		}
+		v.Set(p)
+		return nil
+	}
+	if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(s))
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Float64:
+		f, err := config.ParseNumber(s)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Int:
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return fmt.Errorf("expected a whole number")
+		}
+		v.SetInt(int64(n))
+	case reflect.Bool:
+		b, err := parseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("a list of %s is only set in the config file", v.Type().Elem())
+		}
+		v.Set(reflect.ValueOf(strings.Fields(s)))
+	default:
+		return fmt.Errorf("unsupported config field type %s", v.Type())
+	}
+	return nil
+}
+
+// parseBool accepts the same boolean spellings as kong's env handling.
+func parseBool(s string) (bool, error) {
+	switch strings.ToLower(s) {
+	case "true", "1", "yes":
+		return true, nil
+	case "false", "0", "no":
+		return false, nil
+	}
+	return false, fmt.Errorf("expected true, 1, yes, false, 0, or no")
+}
+
+// ShowCmd groups subcommands under "show".
+type ShowCmd struct {
+	Config ShowConfigCmd `cmd:"" name:"config" help:"Print the effective configuration and where each value comes from."`
+}
+
+// ShowConfigCmd is the 'show config' subcommand.
+type ShowConfigCmd struct {
+	// Format selects the output format.
+	Format string `enum:"table,yaml,json" default:"table" help:"Output format: table (with sources), yaml, or json."`
+
+	// ShowSecrets disables redaction of secret values.
+	ShowSecrets bool `help:"Print secret values instead of redacting them."`
+}
+
+// Run executes the 'show config' subcommand.
+func (s *ShowConfigCmd) Run(g *Globals) error {
+	path, err := g.configPath()
+	if err != nil {
+		return err
+	}
+	cfg, err := g.loadConfig(path)
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+	local, err := g.localConfig()
+	if err != nil {
+		return err
+	}
+	return RunShowConfig(s, cfg, local, os.LookupEnv, os.Stdout)
+}
+
+// RunShowConfig writes the effective config derived from cfg, the local config
+// (which may be nil), and the environment to w.
+// This function is exported for testability.
+func RunShowConfig(s *ShowConfigCmd, cfg, local *config.Config, lookupEnv func(string) (string, bool), w io.Writer) error {
+	eff, fields, err := effectiveConfig(cfg, local, lookupEnv)
+	if err != nil {
+		return err
+	}
+	if !s.ShowSecrets {
+		if err := redactSecrets(fields); err != nil {
+			return err
+		}
+	}
+
+	switch s.Format {
+	case "yaml":
+		data, err := yaml.Marshal(eff)
+		if err != nil {
+			return fmt.Errorf("marshaling config: %w", err)
+		}
+		_, err = w.Write(data)
+		return err
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(eff)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "KEY\tVALUE\tSOURCE")
+	for _, f := range fields {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", f.Key, f.String(), f.Source)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,180 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/kong"
+	"github.com/zon/invoicer/internal/config"
+)
+
+// envMap returns a lookup function over the given variables.
+func envMap(vars map[string]string) func(string) (string, bool) {
+	return func(k string) (string, bool) {
+		v, ok := vars[k]
+		return v, ok
+	}
+}
+
+func sources(fields []effectiveField) map[string]Source {
+	out := make(map[string]Source, len(fields))
+	for _, f := range fields {
+		out[f.Key] = f.Source
+	}
+	return out
+}
+
+func TestEffectiveConfig_Provenance(t *testing.T) {
+	cfg := &config.Config{Vendor: strPtr("Config Vendor"), Rate: floatPtr(50)}
+	env := envMap(map[string]string{"INVOICER_RATE": "75", "INVOICER_CUSTOMER": "Env Customer"})
+
+	eff, fields, err := effectiveConfig(cfg, nil, env)
+	if err != nil {
+		t.Fatalf("effectiveConfig: %v", err)
+	}
+	want := map[string]Source{
+		"vendor":   SourceConfig,
+		"customer": SourceEnv,
+		"rate":     SourceEnv,
+		"hours":    SourceUnset,
+		"pdf":      SourceDefault,
+		"model":    SourceDefault,
+	}
+	got := sources(fields)
+	for key, src := range want {
+		if got[key] != src {
+			t.Errorf("%s: source %q, want %q", key, got[key], src)
+		}
+	}
+	if *eff.Rate != 75 || *eff.Customer != "Env Customer" || *eff.Model != defaultModel {
+		t.Errorf("unexpected effective config: %+v", eff)
+	}
+	if *cfg.Rate != 50 {
+		t.Error("effectiveConfig must not modify the loaded config")
+	}
+}
+
+func TestEffectiveConfig_InvalidEnv(t *testing.T) {
+	_, _, err := effectiveConfig(&config.Config{}, nil, envMap(map[string]string{"INVOICER_HOURS": "forty"}))
+	if err == nil || !strings.Contains(err.Error(), "INVOICER_HOURS") {
+		t.Errorf("expected error naming INVOICER_HOURS, got %v", err)
+	}
+}
+
+func TestEffectiveConfig_HolidayCountryEnv(t *testing.T) {
+	us := "US"
+	cfg := &config.Config{Holidays: &config.Holidays{Country: &us}}
+	eff, fields, err := effectiveConfig(cfg, nil, envMap(map[string]string{"INVOICER_HOLIDAY_COUNTRY": "DE-BY"}))
+	if err != nil {
+		t.Fatalf("effectiveConfig: %v", err)
+	}
+	if got := eff.HolidayCountry(); got != "DE-BY" {
+		t.Errorf("HolidayCountry() = %q, want DE-BY", got)
+	}
+	for _, f := range fields {
+		if f.Key == "holidays" && (f.String() != "DE-BY" || f.Source != SourceEnv) {
+			t.Errorf("holidays = %q from %s, want DE-BY from env", f.String(), f.Source)
+		}
+	}
+	if *cfg.Holidays.Country != "US" {
+		t.Errorf("the loaded config's country changed to %q", *cfg.Holidays.Country)
+	}
+}
+
+func TestRedactSecrets(t *testing.T) {
+	var s struct {
+		User  string `yaml:"user"`
+		Token string `yaml:"token" secret:""`
+		Unset string `yaml:"unset" secret:""`
+	}
+	s.User = "jane"
+	s.Token = "abc123"
+	fields, err := applyEffective(reflect.ValueOf(&s).Elem(), reflect.Value{}, envMap(nil))
+	if err != nil {
+		t.Fatalf("applyEffective: %v", err)
+	}
+	if err := redactSecrets(fields); err != nil {
+		t.Fatalf("redactSecrets: %v", err)
+	}
+	if s.Token != redacted {
+		t.Errorf("Token: got %q, want redacted", s.Token)
+	}
+	if s.User != "jane" {
+		t.Errorf("User: non-secret value changed to %q", s.User)
+	}
+	if s.Unset != "" {
+		t.Errorf("Unset: unset secret should stay empty, got %q", s.Unset)
+	}
+}
+
+func TestRunShowConfig_Table(t *testing.T) {
+	var out bytes.Buffer
+	cfg := &config.Config{Vendor: strPtr("Jane Smith")}
+	if err := RunShowConfig(&ShowConfigCmd{Format: "table"}, cfg, nil, envMap(nil), &out); err != nil {
+		t.Fatalf("RunShowConfig: %v", err)
+	}
+	for _, line := range strings.Split(out.String(), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) > 0 && fields[0] == "vendor" {
+			if fields[len(fields)-1] != "config" {
+				t.Errorf("vendor line %q should end with source config", line)
+			}
+			return
+		}
+	}
+	t.Errorf("vendor missing from output:\n%s", out.String())
+}
+
+func TestRunShowConfig_JSON(t *testing.T) {
+	var out bytes.Buffer
+	cfg := &config.Config{Vendor: strPtr("Jane Smith"), Rate: floatPtr(150)}
+	if err := RunShowConfig(&ShowConfigCmd{Format: "json"}, cfg, nil, envMap(nil), &out); err != nil {
+		t.Fatalf("RunShowConfig: %v", err)
+	}
+	var got map[string]any
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatalf("invalid JSON %q: %v", out.String(), err)
+	}
+	if got["vendor"] != "Jane Smith" || got["rate"] != 150.0 || got["model"] != defaultModel {
+		t.Errorf("unexpected JSON output: %v", got)
+	}
+}
+
+// TestConfigEnvMatchesFlags guards against the show config env table drifting
+// from the env tags on the generate flags.
+func TestConfigEnvMatchesFlags(t *testing.T) {
+	var cmd CLI
+	k, err := kong.New(&cmd, kong.Name("invoicer"), kong.Exit(func(int) {}))
+	if err != nil {
+		t.Fatalf("kong.New failed: %v", err)
+	}
+	flagEnvs := map[string]bool{}
+	var flags []*kong.Flag
+	var walk func(*kong.Node)
+	walk = func(n *kong.Node) {
+		for _, group := range n.AllFlags(true) {
+			flags = append(flags, group...)
+		}
+		for _, child := range n.Children {
+			walk(child)
+		}
+	}
+	walk(k.Model.Node)
+	for _, f := range flags {
+		for _, env := range f.Envs {
+			flagEnvs[env] = true
+		}
+		if slices.Contains(f.Envs, "INVOICER_MODEL") && !strings.Contains(f.Help, defaultModel) {
+			t.Errorf("--model help %q does not mention defaultModel %q", f.Help, defaultModel)
+		}
+	}
+	for key, env := range configEnv {
+		if !flagEnvs[env] {
+			t.Errorf("config key %s maps to %s, which no flag reads", key, env)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/zon/invoicer/internal/config"
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+// readSpecFile reads the invoice spec at path for --from-file. Its
+// unknown keys are warned about, or with strict are an error, as they are
+// in config files with --strict-config.
+func readSpecFile(path string, strict bool) (*invoice.Invoice, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading the invoice spec: %w", err)
+	}
+	inv, unknown, err := invoice.ReadSpec(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for _, key := range unknown {
+		if strict {
+			return nil, fmt.Errorf("%s: unknown key %q", path, key)
+		}
+		fmt.Fprintf(config.Warnings, "Warning: %s: unknown key %q; ignoring it\n", path, key)
+	}
+	return inv, nil
+}
@@ -0,0 +1,64 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zon/invoicer/internal/config"
+)
+
+const testSpec = `month: January
+year: 2025
+vendor: Jane Doe
+customer: Acme Corp
+rate: 100
+hours: 40
+discount_code: WINTER
+`
+
+func writeTestSpec(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "spec.yaml")
+	if err := os.WriteFile(path, []byte(testSpec), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadSpecFile_WarnsUnknownKeys(t *testing.T) {
+	var warnings bytes.Buffer
+	old := config.Warnings
+	config.Warnings = &warnings
+	t.Cleanup(func() { config.Warnings = old })
+
+	inv, err := readSpecFile(writeTestSpec(t), false)
+	if err != nil {
+		t.Fatalf("readSpecFile: %v", err)
+	}
+	if len(inv.Weeks) != 5 || inv.Weeks[1].Hours != 40 {
+		t.Errorf("weeks = %v, want January's five weeks of 40 hours", inv.Weeks)
+	}
+	if !strings.Contains(warnings.String(), `unknown key "discount_code"; ignoring it`) {
+		t.Errorf("warnings = %q, want the unknown key", warnings.String())
+	}
+}
+
+func TestReadSpecFile_Strict(t *testing.T) {
+	_, err := readSpecFile(writeTestSpec(t), true)
+	if err == nil || !strings.Contains(err.Error(), `unknown key "discount_code"`) {
+		t.Errorf("expected an unknown key error, got %v", err)
+	}
+}
+
+func TestReadSpecFile_SkipsOverrides(t *testing.T) {
+	// The spec may itself be named invoice.yaml, like the overrides file.
+	chdirOverrides(t, testSpec)
+	cmd := parseCLI(t, "--from-file", config.OverridesFile)
+	over, _, err := cmd.Generate.loadOverrides()
+	if err != nil || over != nil {
+		t.Errorf("loadOverrides = %v, %v; want no overrides", over, err)
+	}
+}
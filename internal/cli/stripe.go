@@ -0,0 +1,34 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+// newStripeClient returns the client Stripe invoices are created with.
+// It can be overridden in tests.
+var newStripeClient = func(key string) *invoice.StripeClient {
+	return &invoice.StripeClient{Key: key}
+}
+
+// sendStripeInvoice creates the Stripe invoice for inv that opts describe
+// and reports it on w.
+func sendStripeInvoice(ctx context.Context, opts *ResolvedOptions, inv *invoice.Invoice, w io.Writer) error {
+	created, err := invoice.SendStripe(ctx, newStripeClient(opts.StripeAPIKey), inv, invoice.StripeOptions{
+		CustomerID:    opts.StripeCustomerID,
+		CustomerEmail: opts.StripeCustomerEmail,
+		DaysUntilDue:  opts.StripeDaysUntilDue,
+		Finalize:      opts.Finalize,
+	})
+	if err != nil {
+		return fmt.Errorf("creating the Stripe invoice: %w", err)
+	}
+	fmt.Fprintf(w, "Stripe invoice created: %s (%s, customer %s)\n", created.ID, created.Status, created.Customer)
+	if created.HostedURL != "" {
+		fmt.Fprintf(w, "Payment page: %s\n", created.HostedURL)
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+func TestResolveOptions_Stripe(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		config  string
+		wantErr string
+	}{
+		{"off", nil, "", ""},
+		{"config", []string{"--send-stripe"}, "stripe_api_key: sk_test_1\nstripe_customer_id: cus_1\nstripe_days_until_due: 14\n", ""},
+		{"flags", []string{"--stripe-only", "--finalize", "--stripe-api-key=sk_test_1", "--stripe-customer-email=ap@acme.example"}, "", ""},
+		{"no key", []string{"--send-stripe"}, "stripe_customer_id: cus_1\n", "Stripe API key is required"},
+		{"no customer", []string{"--send-stripe", "--stripe-api-key=sk_test_1"}, "", "Stripe customer is required"},
+		{"finalize alone", []string{"--finalize"}, "", "--finalize needs --send-stripe or --send-paypal"},
+		{"with export", []string{"--stripe-only", "--export-json"}, "stripe_api_key: sk_test_1\nstripe_customer_id: cus_1\n", "--stripe-only cannot be combined with --export-json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := parseCLI(t, tt.args...)
+			opts, err := cmd.Generate.resolveOptions(loadTestConfig(t, writeTestConfig(t, tt.config)), nil)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("expected error %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveOptions: %v", err)
+			}
+			if opts.SendStripe != (len(tt.args) > 0) {
+				t.Errorf("SendStripe = %v", opts.SendStripe)
+			}
+			if tt.name == "config" && (opts.StripeAPIKey != "sk_test_1" || opts.StripeCustomerID != "cus_1" || opts.StripeDaysUntilDue != 14) {
+				t.Errorf("options from config = %+v", opts)
+			}
+			if tt.name == "flags" && (!opts.Finalize || opts.StripeCustomerEmail != "ap@acme.example") {
+				t.Errorf("options from flags = %+v", opts)
+			}
+		})
+	}
+}
+
+func TestSendStripeInvoice(t *testing.T) {
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.Method+" "+r.URL.Path)
+		switch {
+		case r.URL.Path == "/v1/customers/cus_1":
+			fmt.Fprint(w, `{"id":"cus_1"}`)
+		case r.URL.Path == "/v1/invoices":
+			fmt.Fprint(w, `{"id":"in_1","customer":"cus_1","status":"draft"}`)
+		case r.URL.Path == "/v1/invoiceitems":
+			fmt.Fprint(w, `{"id":"ii_1"}`)
+		case r.URL.Path == "/v1/invoices/in_1/finalize":
+			fmt.Fprint(w, `{"id":"in_1","customer":"cus_1","status":"open","hosted_invoice_url":"https://invoice.stripe.com/i/in_1"}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	old := newStripeClient
+	newStripeClient = func(key string) *invoice.StripeClient {
+		return &invoice.StripeClient{Key: key, BaseURL: srv.URL}
+	}
+	t.Cleanup(func() { newStripeClient = old })
+
+	opts := &ResolvedOptions{StripeAPIKey: "sk_test_1", StripeCustomerID: "cus_1", Finalize: true}
+	var out bytes.Buffer
+	if err := sendStripeInvoice(context.Background(), opts, testTimeoutInvoice(), &out); err != nil {
+		t.Fatalf("sendStripeInvoice: %v", err)
+	}
+	want := "Stripe invoice created: in_1 (open, customer cus_1)\nPayment page: https://invoice.stripe.com/i/in_1\n"
+	if out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+	if n := len(paths); n != 1+1+5+1 {
+		t.Errorf("made %d requests, want a lookup, an invoice, five items and a finalization: %v", n, paths)
+	}
+}
+
+func TestSendStripeInvoice_ReportsStep(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, `{"error":{"message":"Invalid API Key provided"}}`)
+	}))
+	t.Cleanup(srv.Close)
+	old := newStripeClient
+	newStripeClient = func(key string) *invoice.StripeClient {
+		return &invoice.StripeClient{Key: key, BaseURL: srv.URL}
+	}
+	t.Cleanup(func() { newStripeClient = old })
+
+	opts := &ResolvedOptions{StripeAPIKey: "sk_test_bad", StripeCustomerID: "cus_1"}
+	err := sendStripeInvoice(context.Background(), opts, testTimeoutInvoice(), &bytes.Buffer{})
+	if err == nil || !strings.Contains(err.Error(), "creating the Stripe invoice: Stripe authentication failed") {
+		t.Errorf("expected an authentication error, got %v", err)
+	}
+}
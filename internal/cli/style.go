@@ -0,0 +1,44 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/zon/invoicer/internal/config"
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+// styleFromLast is the --style-from value for the latest earlier invoice
+// to the same customer.
+const styleFromLast = "last"
+
+// styleReference returns the style reference --style-from names for inv:
+// the HTML invoice at from, or with styleFromLast the latest one in dir
+// for an earlier month. No earlier invoice, or one without a stylesheet,
+// is warned about and gives nil, for a new design.
+func styleReference(from string, inv *invoice.Invoice, dir string) (*invoice.StyleReference, error) {
+	path := from
+	if from == styleFromLast {
+		var err error
+		if path, err = invoice.LastInvoice(dir, inv); err != nil {
+			return nil, err
+		}
+		if path == "" {
+			fmt.Fprintf(config.Warnings, "Warning: no earlier HTML invoice to %s in %s; generating a new design\n", inv.Customer, dir)
+			return nil, nil
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading the style reference: %w", err)
+	}
+	ref := invoice.ExtractStyle(data)
+	if ref == nil {
+		fmt.Fprintf(config.Warnings, "Warning: %s has no stylesheet; generating a new design\n", path)
+		return nil, nil
+	}
+	if ref.Truncated {
+		fmt.Fprintf(config.Warnings, "Warning: the stylesheet of %s is over %d KB; only its start is used\n", path, invoice.MaxStyleSize>>10)
+	}
+	return ref, nil
+}
@@ -0,0 +1,99 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zon/invoicer/internal/config"
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+// captureWarnings collects the warnings written during the test.
+func captureWarnings(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var warnings bytes.Buffer
+	old := config.Warnings
+	config.Warnings = &warnings
+	t.Cleanup(func() { config.Warnings = old })
+	return &warnings
+}
+
+func TestGenerateRun_StyleFromLast(t *testing.T) {
+	warnings := captureWarnings(t)
+	dir := t.TempDir()
+	t.Chdir(dir)
+	prev := "<html><head><style>.total { color: #c0ffee; }</style></head><body><table class=\"items\"></table></body></html>"
+	if err := os.WriteFile(filepath.Join(dir, "invoice-acme-corp-2024-12.html"), []byte(prev), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	path := writeTestConfig(t, "vendor: Jane\ncustomer: Acme Corp\nrate: 100\nhours: 40\n")
+	cmd := parseCLI(t, "--config", path, "--style-from", "last", "--prompt-only", "january", "2025")
+	if err := cmd.Generate.Run(&cmd.Globals, context.Background()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "invoice-acme-corp-2025-01.prompt.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"Style Reference (from the previous invoice):", ".total { color: #c0ffee; }", "- Layout: table.items\n"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("prompt is missing %q:\n%s", want, data)
+		}
+	}
+	if warnings.Len() != 0 {
+		t.Errorf("unexpected warnings: %s", warnings)
+	}
+}
+
+func TestStyleReference_NoEarlierInvoice(t *testing.T) {
+	warnings := captureWarnings(t)
+	inv := &invoice.Invoice{Customer: "Acme Corp", Month: 1, Year: 2025}
+	ref, err := styleReference(styleFromLast, inv, t.TempDir())
+	if err != nil {
+		t.Fatalf("styleReference: %v", err)
+	}
+	if ref != nil {
+		t.Errorf("styleReference = %+v, want nil", ref)
+	}
+	if !strings.Contains(warnings.String(), "Warning: no earlier HTML invoice to Acme Corp") {
+		t.Errorf("expected a warning, got %q", warnings)
+	}
+}
+
+func TestStyleReference_Path(t *testing.T) {
+	captureWarnings(t)
+	inv := &invoice.Invoice{Customer: "Acme Corp", Month: 1, Year: 2025}
+	if _, err := styleReference(filepath.Join(t.TempDir(), "missing.html"), inv, "."); err == nil || !strings.Contains(err.Error(), "reading the style reference") {
+		t.Errorf("expected a read error, got %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "design.html")
+	if err := os.WriteFile(path, []byte("<style>h1 { color: red; }</style>"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ref, err := styleReference(path, inv, ".")
+	if err != nil || ref == nil || ref.CSS != "h1 { color: red; }" {
+		t.Errorf("styleReference = %+v, %v", ref, err)
+	}
+}
+
+func TestStyleReference_Truncated(t *testing.T) {
+	warnings := captureWarnings(t)
+	css := strings.Repeat(".a { color: red; }\n", 2*invoice.MaxStyleSize/19)
+	path := filepath.Join(t.TempDir(), "big.html")
+	if err := os.WriteFile(path, []byte("<style>"+css+"</style>"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ref, err := styleReference(path, &invoice.Invoice{}, ".")
+	if err != nil || !ref.Truncated {
+		t.Fatalf("styleReference = %+v, %v; want a truncated reference", ref, err)
+	}
+	if !strings.Contains(warnings.String(), "only its start is used") {
+		t.Errorf("expected a truncation warning, got %q", warnings)
+	}
+}
@@ -0,0 +1,27 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/zon/invoicer/internal/fsutil"
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+// textFormat is the --format-out value for a plain-text invoice.
+const textFormat = "text"
+
+// writeTextInvoice writes inv as a plain-text invoice to path and reports
+// where it went on w. No backend is involved, so it costs nothing.
+func writeTextInvoice(inv *invoice.Invoice, path string, w io.Writer) error {
+	var buf bytes.Buffer
+	if err := invoice.WriteText(&buf, inv); err != nil {
+		return fmt.Errorf("writing text invoice: %w", err)
+	}
+	if err := fsutil.WriteFileAtomic(path, buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("writing text invoice: %w", err)
+	}
+	fmt.Fprintf(w, "Text invoice written to: %s\n", path)
+	return nil
+}
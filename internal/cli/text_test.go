@@ -0,0 +1,98 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+func TestResolveOptions_FormatOut(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		config  string
+		want    string
+		wantErr string
+	}{
+		{"default", nil, "", "html", ""},
+		{"text", []string{"--format-out", "text"}, "", "text", ""},
+		{"text passes over the config's PDF", []string{"--format-out=text"}, "pdf: true\npdf_only: true\nthumbnail: true\n", "text", ""},
+		{"text with --pdf", []string{"--format-out=text", "--pdf"}, "", "", "--format-out text cannot be combined with --pdf"},
+		{"text with --thumbnail", []string{"--format-out=text", "--thumbnail"}, "", "", "cannot be combined with --thumbnail"},
+		{"text with --prompt-only", []string{"--format-out=text", "--prompt-only"}, "", "", "cannot be combined with --prompt-only"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeTestConfig(t, tt.config)
+			cmd := parseCLI(t, tt.args...)
+			opts, err := cmd.Generate.resolveOptions(loadTestConfig(t, path), nil)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("expected error %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveOptions: %v", err)
+			}
+			if opts.FormatOut != tt.want {
+				t.Errorf("FormatOut = %q, want %q", opts.FormatOut, tt.want)
+			}
+			if opts.FormatOut == textFormat && (opts.PDF || opts.PDFOnly || opts.Thumbnail) {
+				t.Errorf("a text invoice should not be converted: PDF %v, PDFOnly %v, Thumbnail %v", opts.PDF, opts.PDFOnly, opts.Thumbnail)
+			}
+		})
+	}
+}
+
+func TestWriteTextInvoice(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "invoice-acme-corp-2025-01.txt")
+	var out bytes.Buffer
+	if err := writeTextInvoice(testTimeoutInvoice(), path, &out); err != nil {
+		t.Fatalf("writeTextInvoice: %v", err)
+	}
+	if got, want := out.String(), "Text invoice written to: "+path+"\n"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), "INVOICE ") || !strings.Contains(string(data), "Total") {
+		t.Errorf("unexpected text invoice:\n%s", data)
+	}
+}
+
+func TestGenerateRun_TextRunsHook(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	var command string
+	var env []string
+	origExec := invoice.HookExec
+	t.Cleanup(func() { invoice.HookExec = origExec })
+	invoice.HookExec = func(c string, e []string) error {
+		command, env = c, e
+		return nil
+	}
+
+	g := &Globals{Config: writeTestConfig(t, "post_generate_hook: ./publish.sh\n"), NoLocal: true}
+	cmd := parseCLI(t, "--vendor=Jane", "--customer=Acme", "--rate=100", "--hours=40", "--format-out=text", "january", "2025")
+	if err := cmd.Generate.Run(g, context.Background()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if command != "./publish.sh" {
+		t.Fatalf("hook command = %q, want the configured hook to run", command)
+	}
+	text := filepath.Join(dir, "invoice-acme-2025-01.txt")
+	for _, kv := range []string{"INVOICE_TEXT=" + text, "INVOICE_HTML=", "INVOICE_PDF="} {
+		if !slices.Contains(env, kv) {
+			t.Errorf("hook environment has no %q: %v", kv, env)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ThumbnailCmd is the 'thumbnail' subcommand.
+// It renders an existing HTML invoice to a PNG preview.
+type ThumbnailCmd struct {
+	// HTML is the invoice to render.
+	HTML string `arg:"" type:"existingfile" help:"HTML invoice to render."`
+
+	// Output is the thumbnail's path.
+	Output string `short:"o" type:"path" help:"PNG file to write. Defaults to the invoice's path with a .png extension."`
+
+	// Width is the width of the thumbnail in pixels. Nil if not given.
+	Width *int `env:"INVOICER_THUMBNAIL_WIDTH" help:"Width of the thumbnail in pixels. Defaults to thumbnail_width: in the config, or 300."`
+}
+
+// Run executes the 'thumbnail' subcommand.
+func (c *ThumbnailCmd) Run(g *Globals, ctx context.Context) error {
+	configPath, err := g.configPath()
+	if err != nil {
+		return err
+	}
+	global, err := g.loadConfig(configPath)
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+	local, err := g.localConfig()
+	if err != nil {
+		return err
+	}
+	// The width and the PDF settings that choose the browser come from the
+	// config, as they do for generation.
+	opts, err := (&GenerateCmd{ThumbnailWidth: c.Width}).resolveOptions(global, local)
+	if err != nil {
+		return err
+	}
+	return runThumbnail(ctx, c, opts, os.Stdout)
+}
+
+// runThumbnail renders the invoice c names and reports where it went on w.
+func runThumbnail(ctx context.Context, c *ThumbnailCmd, opts *ResolvedOptions, w io.Writer) error {
+	output := c.Output
+	if output == "" {
+		output = strings.TrimSuffix(c.HTML, filepath.Ext(c.HTML)) + ".png"
+	}
+	tool, err := renderThumbnail(ctx, opts, c.HTML, output)
+	if err != nil {
+		return fmt.Errorf("rendering the thumbnail: %w", err)
+	}
+	fmt.Fprintf(w, "Thumbnail written to: %s (%s)\n", output, tool)
+	return nil
+}
@@ -0,0 +1,148 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zon/invoicer/internal/config"
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+func TestResolveOptions_Thumbnail(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		config    string
+		want      bool
+		wantWidth int
+		wantErr   string
+	}{
+		{"default", nil, "", false, 300, ""},
+		{"flag", []string{"--thumbnail", "--thumbnail-width", "120"}, "", true, 120, ""},
+		{"config", nil, "thumbnail: true\nthumbnail_width: 200\n", true, 200, ""},
+		{"flag overrides config", []string{"--no-thumbnail", "--thumbnail-width=150"}, "thumbnail: true\nthumbnail_width: 200\n", false, 150, ""},
+		{"zero width", []string{"--thumbnail-width=0"}, "", false, 0, "thumbnail width must be positive"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeTestConfig(t, tt.config)
+			cmd := parseCLI(t, tt.args...)
+			opts, err := cmd.Generate.resolveOptions(loadTestConfig(t, path), nil)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("expected error %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveOptions: %v", err)
+			}
+			if opts.Thumbnail != tt.want || opts.ThumbnailWidth != tt.wantWidth {
+				t.Errorf("Thumbnail = %v, width %d, want %v, width %d", opts.Thumbnail, opts.ThumbnailWidth, tt.want, tt.wantWidth)
+			}
+		})
+	}
+}
+
+func TestRunThumbnail(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as a fake chromium")
+	}
+	bin := t.TempDir()
+	script := "#!/bin/sh\nprintf '%s\\n' \"$@\" > \"${0%/*}/args.txt\"\nfor arg; do case $arg in --screenshot=*) printf '\\211PNG\\r\\n\\032\\nIHDR' > \"${arg#--screenshot=}\";; esac; done\n"
+	if err := os.WriteFile(filepath.Join(bin, "chromium"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	dir := t.TempDir()
+	htmlPath := filepath.Join(dir, "invoice-acme-2025-01.html")
+	if err := os.WriteFile(htmlPath, []byte("<html></html>"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := &ResolvedOptions{PDFTool: "chromium", PDFTimeout: time.Minute, ThumbnailWidth: 204}
+	var out bytes.Buffer
+	if err := runThumbnail(context.Background(), &ThumbnailCmd{HTML: htmlPath}, opts, &out); err != nil {
+		t.Fatalf("runThumbnail: %v", err)
+	}
+	pngPath := filepath.Join(dir, "invoice-acme-2025-01.png")
+	if got, want := out.String(), "Thumbnail written to: "+pngPath+" (chromium)\n"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+	args, err := os.ReadFile(filepath.Join(bin, "args.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(args), "--force-device-scale-factor=0.25\n") {
+		t.Errorf("the width should set the scale, got arguments:\n%s", args)
+	}
+}
+
+func TestWritePDF_Thumbnail(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses shell scripts as a fake wkhtmltopdf and chromium")
+	}
+	tests := []struct {
+		name     string
+		chromium string
+		wantPNG  bool
+		wantWarn string
+	}{
+		{"rendered", "#!/bin/sh\nfor arg; do case $arg in --screenshot=*) printf '\\211PNG\\r\\n\\032\\nIHDR' > \"${arg#--screenshot=}\";; esac; done\n", true, ""},
+		{"browser fails", "#!/bin/sh\necho 'GPU process crashed' >&2\nexit 1\n", false, "Warning: leaving out the thumbnail: "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bin := t.TempDir()
+			for name, script := range map[string]string{
+				"wkhtmltopdf": "#!/bin/sh\nprintf '%%PDF-1.4\\n%%%%EOF\\n' > \"$2\"\n",
+				"chromium":    tt.chromium,
+			} {
+				if err := os.WriteFile(filepath.Join(bin, name), []byte(script), 0o755); err != nil {
+					t.Fatal(err)
+				}
+			}
+			t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+			var warnings strings.Builder
+			old := config.Warnings
+			config.Warnings = &warnings
+			t.Cleanup(func() { config.Warnings = old })
+			dir := t.TempDir()
+			inv := testTimeoutInvoice()
+			htmlPath := invoice.InvoiceFilePath(inv, dir)
+			pdfPath := invoice.PDFFilePath(inv, dir)
+			if err := os.WriteFile(htmlPath, []byte("<html></html>"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			// The thumbnail is rendered after the PDF, from the HTML that
+			// --pdf-only then removes, and a failed one does not fail the
+			// run.
+			opts := &ResolvedOptions{PDF: true, PDFOnly: true, PDFTool: "wkhtmltopdf", PDFTimeout: time.Minute, PDFTitle: invoice.DefaultPDFTitle, Thumbnail: true, ThumbnailWidth: 300}
+			res, err := writePDF(context.Background(), opts, inv, htmlPath, pdfPath)
+			if err != nil {
+				t.Fatalf("writePDF: %v", err)
+			}
+			if !res.HTMLRemoved {
+				t.Error("the HTML should be removed after the thumbnail")
+			}
+			if _, err := os.Stat(pdfPath); err != nil {
+				t.Errorf("the PDF should be written: %v", err)
+			}
+			pngPath := invoice.ThumbnailFilePath(inv, dir)
+			_, err = os.Stat(pngPath)
+			if (err == nil) != tt.wantPNG || (res.Thumbnail == pngPath) != tt.wantPNG {
+				t.Errorf("thumbnail exists = %v, Thumbnail = %q, want %v", err == nil, res.Thumbnail, tt.wantPNG)
+			}
+			if strings.Contains(warnings.String(), "thumbnail") != (tt.wantWarn != "") || !strings.Contains(warnings.String(), tt.wantWarn) {
+				t.Errorf("warnings = %q, want %q", warnings.String(), tt.wantWarn)
+			}
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/kong"
+	"github.com/zon/invoicer/internal/config"
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+// sleepyOpencode is a fake opencode that writes part of the invoice named in
+// its prompt and then hangs.
+const sleepyOpencode = `#!/bin/sh
+for arg; do prompt=$arg; done
+out=$(printf '%s\n' "$prompt" | sed -n 's/^.*to the file: //p')
+printf '<html><body>partial' > "$out"
+exec sleep 30
+`
+
+func testTimeoutInvoice() *invoice.Invoice {
+	return &invoice.Invoice{
+		Month:    time.January,
+		Year:     2025,
+		Vendor:   "Jane",
+		Customer: "Acme Corp",
+		Rate:     100,
+		Weeks:    invoice.WeeksForMonth(2025, time.January, 40),
+	}
+}
+
+func TestGenerateHTML_Timeout(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as a fake opencode")
+	}
+	bin := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bin, "opencode"), []byte(sleepyOpencode), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	htmlPath := filepath.Join(t.TempDir(), "invoice.html")
+
+	opts := &ResolvedOptions{Backend: "opencode", Model: defaultModel, Timeout: 200 * time.Millisecond}
+	start := time.Now()
+	_, err := generateHTML(context.Background(), opts, testTimeoutInvoice(), htmlPath)
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("generateHTML took %s despite a 200ms timeout", elapsed)
+	}
+
+	var timeout *timeoutError
+	if !errors.As(err, &timeout) {
+		t.Fatalf("expected a timeoutError, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "timed out after 200ms") {
+		t.Errorf("error %q does not name the timeout", err)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("error %q should wrap context.DeadlineExceeded", err)
+	}
+	var coder kong.ExitCoder
+	if !errors.As(fmt.Errorf("generating HTML invoice: %w", err), &coder) || coder.ExitCode() != exitTimeout {
+		t.Errorf("expected exit code %d through wrapping, got %v", exitTimeout, coder)
+	}
+	if _, err := os.Stat(htmlPath); !os.IsNotExist(err) {
+		t.Errorf("no HTML should be saved after a timeout, stat err = %v", err)
+	}
+	if staged, _ := filepath.Glob(htmlPath + ".tmp-*"); len(staged) > 0 {
+		t.Errorf("staging files left behind: %v", staged)
+	}
+}
+
+func TestGenerateHTML_FastRunUnaffectedByTimeout(t *testing.T) {
+	htmlPath := filepath.Join(t.TempDir(), "invoice.html")
+	opts := &ResolvedOptions{Backend: "serve-test", Timeout: time.Second}
+	if _, err := generateHTML(context.Background(), opts, testTimeoutInvoice(), htmlPath); err != nil {
+		t.Fatalf("generateHTML: %v", err)
+	}
+	if _, err := os.Stat(htmlPath); err != nil {
+		t.Errorf("HTML not written: %v", err)
+	}
+}
+
+func TestGenerateHTML_CancelIsNotATimeout(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	opts := &ResolvedOptions{Backend: "serve-test-block", Timeout: time.Minute}
+	_, err := generateHTML(ctx, opts, testTimeoutInvoice(), filepath.Join(t.TempDir(), "invoice.html"))
+	var timeout *timeoutError
+	if errors.As(err, &timeout) || !errors.Is(err, context.Canceled) {
+		t.Errorf("expected a plain cancellation error, got %v", err)
+	}
+}
+
+func TestConvertPDF_Timeout(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as a fake wkhtmltopdf")
+	}
+	bin := t.TempDir()
+	script := "#!/bin/sh\nprintf '%%PDF-1.4\\n' > \"$2\"\nexec sleep 30\n"
+	if err := os.WriteFile(filepath.Join(bin, "wkhtmltopdf"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	dir := t.TempDir()
+	htmlPath := filepath.Join(dir, "invoice.html")
+	pdfPath := filepath.Join(dir, "invoice.pdf")
+	if err := os.WriteFile(htmlPath, []byte("<html></html>"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := &ResolvedOptions{PDFTimeout: 200 * time.Millisecond}
+	_, err := convertPDF(context.Background(), opts, testTimeoutInvoice(), htmlPath, pdfPath)
+	var timeout *timeoutError
+	if !errors.As(err, &timeout) {
+		t.Fatalf("expected a timeoutError, got %v", err)
+	}
+	want := "wkhtmltopdf timed out after 200ms (raise --pdf-timeout or pdf_timeout: in the config)"
+	if err.Error() != want {
+		t.Errorf("error = %q, want %q", err, want)
+	}
+	if _, err := os.Stat(pdfPath); !os.IsNotExist(err) {
+		t.Errorf("partial PDF should be removed, stat err = %v", err)
+	}
+}
+
+func TestConvertPDF_WarnsWhenMetadataFails(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as a fake wkhtmltopdf")
+	}
+	bin := t.TempDir()
+	// A PDF with no cross-reference table passes VerifyPDF but cannot be updated.
+	script := "#!/bin/sh\nprintf '%%PDF-1.4\\n%%%%EOF\\n' > \"$2\"\n"
+	if err := os.WriteFile(filepath.Join(bin, "wkhtmltopdf"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	var warnings strings.Builder
+	old := config.Warnings
+	config.Warnings = &warnings
+	t.Cleanup(func() { config.Warnings = old })
+	dir := t.TempDir()
+	htmlPath := filepath.Join(dir, "invoice.html")
+	pdfPath := filepath.Join(dir, "invoice.pdf")
+	if err := os.WriteFile(htmlPath, []byte("<html></html>"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := &ResolvedOptions{PDFTimeout: time.Minute, PDFTitle: invoice.DefaultPDFTitle}
+	if _, err := convertPDF(context.Background(), opts, testTimeoutInvoice(), htmlPath, pdfPath); err != nil {
+		t.Fatalf("convertPDF: %v", err)
+	}
+	if !strings.Contains(warnings.String(), "Warning: could not set the PDF metadata: the PDF has no startxref") {
+		t.Errorf("warnings = %q", warnings.String())
+	}
+	if _, err := os.Stat(pdfPath); err != nil {
+		t.Errorf("the PDF should be kept: %v", err)
+	}
+}
@@ -0,0 +1,39 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+// stdinPath is the --timesheet path that stands for standard input.
+const stdinPath = "-"
+
+// readTimesheet reads the timesheet at path, or from stdin if path is
+// stdinPath.
+func readTimesheet(path string, stdin io.Reader) ([]invoice.TimesheetEntry, error) {
+	entries, _, err := readTimesheetFormat(path, stdin)
+	return entries, err
+}
+
+// readTimesheetFormat is readTimesheet that also returns the name of the
+// timesheet's format, as invoice.ReadTimesheetFormat does.
+func readTimesheetFormat(path string, stdin io.Reader) ([]invoice.TimesheetEntry, string, error) {
+	r, name := stdin, "standard input"
+	if path != stdinPath {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, "", fmt.Errorf("reading the timesheet: %w", err)
+		}
+		defer f.Close()
+		r, name = f, path
+	}
+	entries, format, err := invoice.ReadTimesheetFormat(r)
+	if errors.Is(err, invoice.ErrEmptyTimesheet) {
+		return nil, "", fmt.Errorf("%w in %s; no invoice is generated for a month without hours", err, name)
+	}
+	return entries, format, err
+}
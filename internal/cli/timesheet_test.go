@@ -0,0 +1,66 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zon/invoicer/internal/config"
+)
+
+func TestReadTimesheet_Stdin(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"csv", "2025-01-06,8\n2025-01-07,8\n2025-01-14,6\n"},
+		{"yaml", "entries:\n  - date: 2025-01-06\n    hours: 8\n  - date: 2025-01-07\n    hours: 8\n  - date: 2025-01-14\n    hours: 6\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := readTimesheet("-", bytes.NewBufferString(tt.input))
+			if err != nil {
+				t.Fatalf("readTimesheet: %v", err)
+			}
+			cmd := parseCLI(t, "--vendor=Jane", "--customer=Acme", "--rate=100", "--timesheet=-", "january", "2025")
+			opts, err := cmd.Generate.resolveOptions(&config.Config{}, nil)
+			if err != nil {
+				t.Fatalf("resolveOptions: %v", err)
+			}
+			opts.Timesheet = entries
+			inv, err := opts.buildInvoice()
+			if err != nil {
+				t.Fatalf("buildInvoice: %v", err)
+			}
+			want := []float64{0, 16, 6, 0, 0}
+			for i, w := range inv.Weeks {
+				if w.Hours != want[i] {
+					t.Errorf("week %d hours = %v, want %v", i, w.Hours, want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestReadTimesheet_EmptyStdin(t *testing.T) {
+	_, err := readTimesheet("-", &bytes.Buffer{})
+	if err == nil || !strings.Contains(err.Error(), "the timesheet has no entries in standard input") {
+		t.Errorf("expected an empty-timesheet error, got %v", err)
+	}
+}
+
+func TestReadTimesheet_File(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hours.csv")
+	if err := os.WriteFile(path, []byte("date,hours\n2025-01-06,8\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := readTimesheet(path, strings.NewReader("2025-01-07,8\n"))
+	if err != nil {
+		t.Fatalf("readTimesheet: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Date.Day() != 6 {
+		t.Errorf("entries = %v, want the file's one entry", entries)
+	}
+}
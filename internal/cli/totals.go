@@ -0,0 +1,124 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+// TotalsCmd is the 'totals' subcommand.
+type TotalsCmd struct {
+	// Month is the month of the invoice. Defaults to previous month.
+	Month string `arg:"" optional:"" predictor:"month" help:"Month of the invoice (e.g. 'january', 'jan', or '1'). Defaults to previous month."`
+
+	// Year is the year of the month. Defaults to the year closest to the given month.
+	Year int `arg:"" optional:"" help:"Year of the month. Defaults to the year closest to the given month."`
+
+	// Vendor, Customer, Rate, Hours, Timesheet, MonthHours, DaysOff,
+	// HolidayCountry and Items describe the invoice as they do for
+	// generation.
+	Vendor         string   `short:"v" env:"INVOICER_VENDOR" help:"Name of the contractor sending the invoice. Defaults to vendor: in the config."`
+	Customer       string   `short:"c" env:"INVOICER_CUSTOMER" predictor:"customer" help:"Name of the client the invoice is for. Defaults to customer: in the config."`
+	Rate           *float64 `short:"r" type:"number" env:"INVOICER_RATE" help:"Hourly rate in dollars. Defaults to rate: in the config."`
+	Hours          *float64 `short:"H" type:"number" env:"INVOICER_HOURS" help:"Hours per week worked. Defaults to hours: in the config."`
+	Timesheet      string   `help:"Read the hours worked each day from the CSV or YAML timesheet at PATH, or from standard input with --timesheet -, instead of --hours."`
+	MonthHours     *float64 `name:"month-hours" type:"number" env:"INVOICER_MONTH_HOURS" help:"Hours worked in the month, split across its weeks by their workdays in quarter hours, instead of --hours."`
+	DaysOff        []string `name:"days-off" help:"Dates not worked, e.g. 2025-01-15,2025-01-16, each taking a fifth of --hours off its week. Repeat or separate with commas."`
+	HolidayCountry string   `name:"holiday-country" env:"INVOICER_HOLIDAY_COUNTRY" predictor:"holiday_country" help:"Take the public holidays of this country, or region of one, e.g. US or DE-BY, off the workdays of their weeks. Defaults to holidays: country: in the config."`
+	Items          []string `name:"item" sep:"none" help:"Bill an ad-hoc line item after the weeks, written as description:amount[:qty[:unit]]. Repeat for more."`
+
+	// Overrides and NoOverrides choose the overrides file, as for
+	// generation.
+	Overrides   string `type:"path" help:"Read one-off overrides for this invoice from PATH instead of invoice.yaml in the current directory."`
+	NoOverrides bool   `name:"no-overrides" help:"Ignore invoice.yaml in the current directory."`
+
+	// Breakdown prints the subtotal, discount, tax and withholding too.
+	Breakdown bool `help:"Print the subtotal, discount, tax and withholding before the total, one per line."`
+
+	// Format is how the amounts are printed.
+	Format string `enum:"text,json" default:"text" help:"Output format: text, bare amounts, or json (text or json)."`
+}
+
+// Run executes the 'totals' subcommand.
+func (c *TotalsCmd) Run(g *Globals) error {
+	configPath, err := g.configPath()
+	if err != nil {
+		return err
+	}
+	global, err := g.loadConfig(configPath)
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+	local, err := g.localConfig()
+	if err != nil {
+		return err
+	}
+	gen := &GenerateCmd{
+		Month:          c.Month,
+		Year:           c.Year,
+		Vendor:         c.Vendor,
+		Customer:       c.Customer,
+		Rate:           c.Rate,
+		Hours:          c.Hours,
+		Timesheet:      c.Timesheet,
+		MonthHours:     c.MonthHours,
+		DaysOff:        c.DaysOff,
+		HolidayCountry: c.HolidayCountry,
+		Items:          c.Items,
+		Overrides:      c.Overrides,
+		NoOverrides:    c.NoOverrides,
+	}
+	run, err := gen.prepare(g, global, local)
+	if err != nil {
+		return err
+	}
+	return writeTotals(c, run.inv.Breakdown(), os.Stdout)
+}
+
+// totalsJSON is the JSON form of an invoice's totals, in dollars. Only
+// the total is set without --breakdown.
+type totalsJSON struct {
+	Subtotal    *float64 `json:"subtotal,omitempty"`
+	Discount    *float64 `json:"discount,omitempty"`
+	Tax         *float64 `json:"tax,omitempty"`
+	Withholding *float64 `json:"withholding,omitempty"`
+	Total       float64  `json:"total"`
+}
+
+// writeTotals writes the amount due of b to w, as a bare amount such as
+// "10800.00", or with c.Breakdown a line of name and amount for each step
+// from the subtotal to the total, or as JSON.
+func writeTotals(c *TotalsCmd, b invoice.InvoiceBreakdown, w io.Writer) error {
+	if c.Format == "json" {
+		out := totalsJSON{Total: b.AmountDue.Dollars()}
+		if c.Breakdown {
+			amounts := []float64{b.Subtotal.Dollars(), b.Discount.Dollars(), b.Tax.Dollars(), b.Withholding.Dollars()}
+			out.Subtotal, out.Discount, out.Tax, out.Withholding = &amounts[0], &amounts[1], &amounts[2], &amounts[3]
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(out)
+	}
+	if !c.Breakdown {
+		_, err := fmt.Fprintln(w, b.AmountDue)
+		return err
+	}
+	for _, line := range []struct {
+		name   string
+		amount invoice.Cents
+	}{
+		{"subtotal", b.Subtotal},
+		{"discount", b.Discount},
+		{"tax", b.Tax},
+		{"withholding", b.Withholding},
+		{"total", b.AmountDue},
+	} {
+		if _, err := fmt.Fprintf(w, "%s\t%s\n", line.name, line.amount); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+// totalsFixture sets up a config, an overrides file that leaves out a week
+// and adds an expense, and a timesheet, in a new current directory, and
+// returns the config's path.
+func totalsFixture(t *testing.T) string {
+	t.Helper()
+	path := writeTestConfig(t, "vendor: Jane Doe\ncustomer: Acme Corp\nrate: 150\nhours: 40\n")
+	dir := t.TempDir()
+	t.Chdir(dir)
+	for name, content := range map[string]string{
+		"invoice.yaml":  "exclude:\n  - 2025-01-27\nadjustments:\n  - description: Travel\n    amount: 120.50\n",
+		"timesheet.csv": "date,hours\n2025-01-06,8\n2025-01-07,7.5\n2025-01-14,6\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return path
+}
+
+// exportedTotal returns the total of the JSON export of a full run with
+// args, which exits without generating.
+func exportedTotal(t *testing.T, args ...string) float64 {
+	t.Helper()
+	out := captureStdout(t, func() {
+		cmd := parseCLI(t, append(args, "--export-json=-")...)
+		if err := cmd.Generate.Run(&cmd.Globals, context.Background()); err != nil {
+			t.Errorf("--export-json: %v", err)
+		}
+	})
+	var data struct {
+		Total float64 `json:"total"`
+	}
+	if err := json.Unmarshal([]byte(out), &data); err != nil {
+		t.Fatalf("reading the export: %v\n%s", err, out)
+	}
+	return data.Total
+}
+
+func TestTotals_MatchesRun(t *testing.T) {
+	path := totalsFixture(t)
+	for _, args := range [][]string{
+		{"january", "2025"},
+		{"january", "2025", "--timesheet", "timesheet.csv", "--item", "Domain renewal:18.99"},
+		{"january", "2025", "--no-overrides", "--rate", "120"},
+	} {
+		base := append([]string{"--config", path, "--no-local"}, args...)
+		want := exportedTotal(t, base...)
+		out := captureStdout(t, func() {
+			if err := runCLI(t, append([]string{"totals"}, base...)...); err != nil {
+				t.Errorf("totals: %v", err)
+			}
+		})
+		if got := invoice.ToCents(want).String() + "\n"; out != got {
+			t.Errorf("%v: totals printed %q, want %q as the run would invoice", args, out, got)
+		}
+	}
+}
+
+func TestTotals_Breakdown(t *testing.T) {
+	path := totalsFixture(t)
+	out := captureStdout(t, func() {
+		if err := runCLI(t, "totals", "--config", path, "--no-local", "--breakdown", "january", "2025"); err != nil {
+			t.Errorf("totals: %v", err)
+		}
+	})
+	// 144 of January's hours, without the week of the 27th, and the
+	// expense.
+	want := "subtotal\t21720.50\ndiscount\t0.00\ntax\t0.00\nwithholding\t0.00\ntotal\t21720.50\n"
+	if out != want {
+		t.Errorf("got:\n%s\nwant:\n%s", out, want)
+	}
+}
+
+func TestTotals_JSON(t *testing.T) {
+	path := totalsFixture(t)
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{nil, `{ "total": 21720.5 }`},
+		{[]string{"--breakdown"}, `{ "subtotal": 21720.5, "discount": 0, "tax": 0, "withholding": 0, "total": 21720.5 }`},
+	}
+	for _, tt := range tests {
+		out := captureStdout(t, func() {
+			args := append([]string{"totals", "--config", path, "--no-local", "--format", "json", "january", "2025"}, tt.args...)
+			if err := runCLI(t, args...); err != nil {
+				t.Errorf("totals: %v", err)
+			}
+		})
+		if got := strings.Join(strings.Fields(out), " "); got != tt.want {
+			t.Errorf("%v: got %s, want %s", tt.args, got, tt.want)
+		}
+	}
+}
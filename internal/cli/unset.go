@@ -0,0 +1,60 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/zon/invoicer/internal/config"
+)
+
+// UnsetCmd groups subcommands under "unset".
+type UnsetCmd struct {
+	Config UnsetConfigCmd `cmd:"" name:"config" help:"Remove options from the config file."`
+}
+
+// UnsetConfigCmd is the 'unset config' subcommand.
+// It removes the named keys from the config file.
+type UnsetConfigCmd struct {
+	// Keys are the config keys to remove.
+	Keys []string `arg:"" name:"key" help:"Config keys to remove (e.g. 'model', 'pdf')."`
+}
+
+// Run executes the 'unset config' subcommand.
+func (u *UnsetConfigCmd) Run(g *Globals) error {
+	path, err := g.configPath()
+	if err != nil {
+		return err
+	}
+	return RunUnsetConfig(u, path, os.Stdout)
+}
+
+// RunUnsetConfig removes the given keys from the config file at path,
+// reporting removed keys and keys that were not set to w.
+// This function is exported for testability.
+func RunUnsetConfig(u *UnsetConfigCmd, path string, w io.Writer) error {
+	removed, err := config.Unset(path, u.Keys)
+	if err != nil {
+		return fmt.Errorf("unsetting config: %w", err)
+	}
+
+	wasRemoved := make(map[string]bool, len(removed))
+	for _, key := range removed {
+		wasRemoved[key] = true
+	}
+	var notSet []string
+	for _, key := range u.Keys {
+		if !wasRemoved[key] {
+			notSet = append(notSet, key)
+		}
+	}
+
+	if len(removed) > 0 {
+		fmt.Fprintf(w, "Removed from %s: %s\n", path, strings.Join(removed, ", "))
+	}
+	if len(notSet) > 0 {
+		fmt.Fprintf(w, "Not set, nothing to remove: %s\n", strings.Join(notSet, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zon/invoicer/internal/config"
+)
+
+func TestRunUnsetConfig_RemovalPersists(t *testing.T) {
+	path := writeTestConfig(t, `vendor: Jane
+customer: Acme
+pdf: true
+model: anthropic/claude-sonnet-4-6
+`)
+	var out bytes.Buffer
+	if err := RunUnsetConfig(&UnsetConfigCmd{Keys: []string{"model", "pdf"}}, path, &out); err != nil {
+		t.Fatalf("RunUnsetConfig: %v", err)
+	}
+
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Model != nil {
+		t.Errorf("Model: expected removed, got %q", str(cfg.Model))
+	}
+	if cfg.PDF != nil {
+		t.Errorf("PDF: expected removed, got %v", *cfg.PDF)
+	}
+	if str(cfg.Vendor) != "Jane" || str(cfg.Customer) != "Acme" {
+		t.Errorf("other keys should be unchanged, got %+v", cfg)
+	}
+	if !strings.Contains(out.String(), "model, pdf") {
+		t.Errorf("expected removed keys reported, got %q", out.String())
+	}
+}
+
+func TestRunUnsetConfig_UnknownKeySuggests(t *testing.T) {
+	path := writeTestConfig(t, "model: anthropic/claude-sonnet-4-6\n")
+	var out bytes.Buffer
+	err := RunUnsetConfig(&UnsetConfigCmd{Keys: []string{"pdf", "modle"}}, path, &out)
+	if err == nil {
+		t.Fatal("expected error for unknown key, got nil")
+	}
+	if !strings.Contains(err.Error(), `did you mean "model"`) {
+		t.Errorf("expected suggestion in error, got %v", err)
+	}
+	cfg, _ := config.Load(path)
+	if cfg.Model == nil {
+		t.Error("config should be untouched when a key is unknown")
+	}
+}
+
+func TestRunUnsetConfig_AbsentKeysLeaveFileUntouched(t *testing.T) {
+	path := writeTestConfig(t, "vendor: Jane\n")
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := RunUnsetConfig(&UnsetConfigCmd{Keys: []string{"model", "pdf"}}, path, &out); err != nil {
+		t.Fatalf("RunUnsetConfig: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.ModTime().Equal(old) {
+		t.Error("config file was rewritten although no key was set")
+	}
+	if !strings.Contains(out.String(), "Not set") {
+		t.Errorf("expected notice for keys that were not set, got %q", out.String())
+	}
+}
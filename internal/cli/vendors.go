@@ -0,0 +1,38 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/zon/invoicer/internal/config"
+)
+
+// runAllVendors generates the invoice of each vendor in cfg's vendors: in
+// turn, as if run with --vendor and its name. Each invoice names its
+// vendor in its number and file names, so they do not overwrite each
+// other. It stops at the first vendor whose invoice fails.
+func (c *GenerateCmd) runAllVendors(g *Globals, ctx context.Context, cfg *config.Config) error {
+	if err := c.checkFlags(); err != nil {
+		return err
+	}
+	if len(cfg.Vendors) == 0 {
+		return errors.New("--all-vendors needs vendors: in the config, each with a name")
+	}
+	for i, v := range cfg.Vendors {
+		if v.Name == "" {
+			return fmt.Errorf("vendors[%d] has no name", i)
+		}
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Printf("Vendor %d of %d: %s\n", i+1, len(cfg.Vendors), v.Name)
+		one := *c
+		one.AllVendors = false
+		one.Vendor = v.Name
+		if err := one.Run(g, ctx); err != nil {
+			return fmt.Errorf("invoice of %s: %w", v.Name, err)
+		}
+	}
+	return nil
+}
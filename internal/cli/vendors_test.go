@@ -0,0 +1,94 @@
+package cli
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const vendorsConfig = `customer: Acme Corp
+rate: 100
+hours: 40
+iban: DE89370400440532013000
+vendors:
+  - name: Jane Doe
+    rate: 150
+  - name: John Roe
+    rate: 120
+    hours: 20
+    iban: FR1420041010050500013M02606
+`
+
+func TestResolveOptions_Vendor(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		vendor    string
+		perVendor bool
+		rate      float64
+		hours     float64
+		iban      string
+	}{
+		{"listed vendor", []string{"--vendor", "jane doe"}, "Jane Doe", true, 150, 40, "DE89370400440532013000"},
+		{"vendor's own hours and iban", []string{"--vendor", "John Roe"}, "John Roe", true, 120, 20, "FR1420041010050500013M02606"},
+		{"flags win", []string{"--vendor", "John Roe", "--rate", "90", "--hours", "10"}, "John Roe", true, 90, 10, "FR1420041010050500013M02606"},
+		{"unlisted vendor", []string{"--vendor", "Agency Ltd"}, "Agency Ltd", false, 100, 40, "DE89370400440532013000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := loadTestConfig(t, writeTestConfig(t, vendorsConfig))
+			opts, err := parseCLI(t, tt.args...).Generate.resolveOptions(cfg, nil)
+			if err != nil {
+				t.Fatalf("resolveOptions: %v", err)
+			}
+			if opts.Vendor != tt.vendor || opts.PerVendor != tt.perVendor || opts.Rate != tt.rate || opts.Hours != tt.hours || opts.IBAN != tt.iban {
+				t.Errorf("got vendor %q, per vendor %v, rate %v, hours %v, iban %q; want %q, %v, %v, %v, %q",
+					opts.Vendor, opts.PerVendor, opts.Rate, opts.Hours, opts.IBAN, tt.vendor, tt.perVendor, tt.rate, tt.hours, tt.iban)
+			}
+		})
+	}
+}
+
+func TestGenerate_AllVendors(t *testing.T) {
+	path := writeTestConfig(t, vendorsConfig)
+	dir := t.TempDir()
+	t.Chdir(dir)
+
+	out := captureStdout(t, func() {
+		cmd := parseCLI(t, "--config", path, "--no-local", "--all-vendors", "--format-out=text", "--no-overrides", "january", "2025")
+		if err := cmd.Generate.Run(&cmd.Globals, context.Background()); err != nil {
+			t.Errorf("--all-vendors: %v", err)
+		}
+	})
+	for _, want := range []string{"Vendor 1 of 2: Jane Doe", "Vendor 2 of 2: John Roe"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output should show %q:\n%s", want, out)
+		}
+	}
+
+	for _, tt := range []struct {
+		file, number, rate string
+	}{
+		{"invoice-acme-corp-jane-doe-2025-01.txt", "INV-202501-acme-corp-jane-doe", "$150.00"},
+		{"invoice-acme-corp-john-roe-2025-01.txt", "INV-202501-acme-corp-john-roe", "$120.00"},
+	} {
+		data, err := os.ReadFile(filepath.Join(dir, tt.file))
+		if err != nil {
+			t.Fatalf("each vendor should have its own invoice: %v", err)
+		}
+		if !strings.Contains(string(data), tt.number) || !strings.Contains(string(data), tt.rate) {
+			t.Errorf("%s should be %s at %s:\n%s", tt.file, tt.number, tt.rate, data)
+		}
+	}
+}
+
+func TestGenerate_AllVendorsWithoutVendors(t *testing.T) {
+	path := writeTestConfig(t, "vendor: Jane Doe\ncustomer: Acme Corp\n")
+	cmd := parseCLI(t, "--config", path, "--no-local", "--all-vendors", "january", "2025")
+	err := cmd.Generate.Run(&cmd.Globals, context.Background())
+	if err == nil || !strings.Contains(err.Error(), "--all-vendors needs vendors: in the config") {
+		t.Errorf("got %v, want an error asking for vendors", err)
+	}
+}
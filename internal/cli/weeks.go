@@ -0,0 +1,131 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+// WeeksCmd is the 'weeks' subcommand.
+type WeeksCmd struct {
+	// Month is the month of the invoice. Defaults to previous month.
+	Month string `arg:"" optional:"" predictor:"month" help:"Month of the invoice (e.g. 'january', 'jan', or '1'). Defaults to previous month."`
+
+	// Year is the year of the month. Defaults to the year closest to the given month.
+	Year int `arg:"" optional:"" help:"Year of the month. Defaults to the year closest to the given month."`
+
+	// Vendor, Customer, Rate, Hours, Timesheet, MonthHours, DaysOff and
+	// HolidayCountry describe the invoice as they do for generation.
+	Vendor         string   `short:"v" env:"INVOICER_VENDOR" help:"Name of the contractor sending the invoice. Defaults to vendor: in the config."`
+	Customer       string   `short:"c" env:"INVOICER_CUSTOMER" predictor:"customer" help:"Name of the client the invoice is for. Defaults to customer: in the config."`
+	Rate           *float64 `short:"r" type:"number" env:"INVOICER_RATE" help:"Hourly rate in dollars. Defaults to rate: in the config."`
+	Hours          *float64 `short:"H" type:"number" env:"INVOICER_HOURS" help:"Hours per week worked. Defaults to hours: in the config."`
+	Timesheet      string   `help:"Read the hours worked each day from the CSV or YAML timesheet at PATH, or from standard input with --timesheet -, instead of --hours."`
+	MonthHours     *float64 `name:"month-hours" type:"number" env:"INVOICER_MONTH_HOURS" help:"Hours worked in the month, split across its weeks by their workdays in quarter hours, instead of --hours."`
+	DaysOff        []string `name:"days-off" help:"Dates not worked, e.g. 2025-01-15,2025-01-16, each taking a fifth of --hours off its week. Repeat or separate with commas."`
+	HolidayCountry string   `name:"holiday-country" env:"INVOICER_HOLIDAY_COUNTRY" predictor:"holiday_country" help:"Take the public holidays of this country, or region of one, e.g. US or DE-BY, off the workdays of their weeks. Defaults to holidays: country: in the config."`
+
+	// Overrides and NoOverrides choose the overrides file, as for
+	// generation.
+	Overrides   string `type:"path" help:"Read one-off overrides for this invoice from PATH instead of invoice.yaml in the current directory."`
+	NoOverrides bool   `name:"no-overrides" help:"Ignore invoice.yaml in the current directory."`
+
+	// Language is the language the weeks are labelled in, as for
+	// generation.
+	Language string `env:"INVOICER_LANGUAGE" help:"Label the weeks in this language, e.g. de or fr. Defaults to language: in the config, or English."`
+
+	// Format is how the weeks are printed.
+	Format string `enum:"table,csv,json" default:"table" help:"Output format: table, csv or json."`
+}
+
+// Run executes the 'weeks' subcommand.
+func (c *WeeksCmd) Run(g *Globals) error {
+	configPath, err := g.configPath()
+	if err != nil {
+		return err
+	}
+	global, err := g.loadConfig(configPath)
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+	local, err := g.localConfig()
+	if err != nil {
+		return err
+	}
+	gen := &GenerateCmd{
+		Month:          c.Month,
+		Year:           c.Year,
+		Vendor:         c.Vendor,
+		Customer:       c.Customer,
+		Rate:           c.Rate,
+		Hours:          c.Hours,
+		Timesheet:      c.Timesheet,
+		MonthHours:     c.MonthHours,
+		DaysOff:        c.DaysOff,
+		HolidayCountry: c.HolidayCountry,
+		Overrides:      c.Overrides,
+		NoOverrides:    c.NoOverrides,
+		Language:       c.Language,
+	}
+	run, err := gen.prepare(g, global, local)
+	if err != nil {
+		return err
+	}
+	// The workdays have a column of their own.
+	run.inv.ShowWorkdays = false
+	return writeWeeks(c.Format, run.inv, os.Stdout)
+}
+
+// writeWeeks writes the weeks of inv to w in format: a table, CSV with a
+// header row, or JSON. Each week has its start, end, workdays, hours and
+// subtotal.
+func writeWeeks(format string, inv *invoice.Invoice, w io.Writer) error {
+	if format == "json" {
+		data, err := inv.MarshalWeeks()
+		if err != nil {
+			return err
+		}
+		var out bytes.Buffer
+		if err := json.Indent(&out, data, "", "  "); err != nil {
+			return err
+		}
+		out.WriteByte('\n')
+		_, err = out.WriteTo(w)
+		return err
+	}
+
+	lines := inv.Breakdown().Lines
+	rows := [][]string{{"start", "end", "workdays", "hours", "subtotal"}}
+	for i, week := range inv.Weeks {
+		rows = append(rows, []string{
+			week.Start.Format(time.DateOnly),
+			week.End.Format(time.DateOnly),
+			strconv.Itoa(week.Workdays),
+			strconv.FormatFloat(week.Hours, 'f', -1, 64),
+			lines[i].Amount.String(),
+		})
+	}
+	if format == "csv" {
+		cw := csv.NewWriter(w)
+		if err := cw.WriteAll(rows); err != nil {
+			return fmt.Errorf("writing CSV: %w", err)
+		}
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "WEEK\tSTART\tEND\tWORKDAYS\tHOURS\tSUBTOTAL")
+	for i, row := range rows[1:] {
+		fmt.Fprintf(tw, "%s\t%s\n", lines[i].Description, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,311 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zon/invoicer/internal/config"
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+// weeksFixture sets up a config and an overrides file for January 2025
+// that takes Martin Luther King Jr. Day off the week of the 20th and
+// leaves out the week of the 27th, in a new current directory, and
+// returns the config's path.
+func weeksFixture(t *testing.T) string {
+	t.Helper()
+	path := writeTestConfig(t, "vendor: Jane Doe\ncustomer: Acme Corp\nrate: 150\nhours: 40\n")
+	dir := t.TempDir()
+	t.Chdir(dir)
+	overrides := "weeks:\n  2025-01-20: 32\nexclude:\n  - 2025-01-27\n"
+	if err := os.WriteFile(filepath.Join(dir, "invoice.yaml"), []byte(overrides), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestWeeks(t *testing.T) {
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"table", `WEEK       START       END         WORKDAYS  HOURS  SUBTOTAL
+Jan 1-5    2025-01-01  2025-01-05  3         24     3600.00
+Jan 6-12   2025-01-06  2025-01-12  5         40     6000.00
+Jan 13-19  2025-01-13  2025-01-19  5         40     6000.00
+Jan 20-26  2025-01-20  2025-01-26  5         32     4800.00
+`},
+		{"csv", `start,end,workdays,hours,subtotal
+2025-01-01,2025-01-05,3,24,3600.00
+2025-01-06,2025-01-12,5,40,6000.00
+2025-01-13,2025-01-19,5,40,6000.00
+2025-01-20,2025-01-26,5,32,4800.00
+`},
+		{"json", `[
+  {
+    "label": "Jan 1-5",
+    "start": "2025-01-01",
+    "end": "2025-01-05",
+    "workdays": 3,
+    "hours": 24,
+    "subtotal": 3600
+  },
+  {
+    "label": "Jan 6-12",
+    "start": "2025-01-06",
+    "end": "2025-01-12",
+    "workdays": 5,
+    "hours": 40,
+    "subtotal": 6000
+  },
+  {
+    "label": "Jan 13-19",
+    "start": "2025-01-13",
+    "end": "2025-01-19",
+    "workdays": 5,
+    "hours": 40,
+    "subtotal": 6000
+  },
+  {
+    "label": "Jan 20-26",
+    "start": "2025-01-20",
+    "end": "2025-01-26",
+    "workdays": 5,
+    "hours": 32,
+    "subtotal": 4800
+  }
+]
+`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			path := weeksFixture(t)
+			out := captureStdout(t, func() {
+				if err := runCLI(t, "weeks", "--config", path, "--no-local", "--format", tt.format, "january", "2025"); err != nil {
+					t.Errorf("weeks: %v", err)
+				}
+			})
+			if out != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", out, tt.want)
+			}
+		})
+	}
+}
+
+func TestWeeks_Language(t *testing.T) {
+	path := weeksFixture(t)
+	if err := os.WriteFile(path, []byte("vendor: Jane Doe\ncustomer: Acme Corp\nrate: 150\nhours: 40\nlanguage: de\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// A config file in ~/.invoicer makes it the directory translations.yaml
+	// is read from.
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	dir := filepath.Join(home, ".invoicer")
+	for name, content := range map[string]string{
+		"config.yaml":       "",
+		"translations.yaml": "fr:\n  week: \"du {start} au {end} {mon}\"\n",
+	} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{nil, "1.-5. Jan."},
+		{[]string{"--language", "fr"}, "du 1 au 5 janv."},
+		{[]string{"--language", "en"}, "Jan 1-5"},
+	}
+	for _, tt := range tests {
+		out := captureStdout(t, func() {
+			args := append([]string{"weeks", "--config", path, "--no-local", "january", "2025"}, tt.args...)
+			if err := runCLI(t, args...); err != nil {
+				t.Errorf("weeks: %v", err)
+			}
+		})
+		lines := strings.Split(out, "\n")
+		if len(lines) < 2 || !strings.HasPrefix(lines[1], tt.want+"  ") {
+			t.Errorf("%v: the first week should be labelled %q:\n%s", tt.args, tt.want, out)
+		}
+	}
+}
+
+func TestShowWorkdays(t *testing.T) {
+	path := weeksFixture(t)
+	if err := os.WriteFile(path, []byte("vendor: Jane Doe\ncustomer: Acme Corp\nrate: 150\nhours: 40\nshow_workdays: true\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	args := []string{"--config", path, "--no-local", "january", "2025"}
+
+	out := captureStdout(t, func() {
+		cmd := parseCLI(t, append(args, "--export-json=-")...)
+		if err := cmd.Generate.Run(&cmd.Globals, context.Background()); err != nil {
+			t.Errorf("--export-json: %v", err)
+		}
+	})
+	for _, want := range []string{`"label": "Jan 1-5"`, `"workdays": 3`, `"workdays": 5`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("the export does not contain %s:\n%s", want, out)
+		}
+	}
+
+	// weeks has a column for them instead.
+	out = captureStdout(t, func() {
+		if err := runCLI(t, append([]string{"weeks"}, args...)...); err != nil {
+			t.Errorf("weeks: %v", err)
+		}
+	})
+	if !strings.Contains(out, "\nJan 1-5    2025-01-01") {
+		t.Errorf("weeks should label the weeks without their workdays:\n%s", out)
+	}
+}
+
+func TestWeeks_MonthHours(t *testing.T) {
+	path := weeksFixture(t)
+	args := []string{"weeks", "--config", path, "--no-local", "january", "2025", "--month-hours", "120"}
+	hours := func(out string) []string {
+		var got []string
+		for _, line := range strings.Split(strings.TrimSpace(out), "\n")[1:] {
+			got = append(got, strings.Fields(line)[5])
+		}
+		return got
+	}
+
+	// January 2025's weeks have 3, 5, 5, 5 and 5 workdays, 23 in all.
+	out := captureStdout(t, func() {
+		if err := runCLI(t, append(args, "--no-overrides")...); err != nil {
+			t.Errorf("weeks: %v", err)
+		}
+	})
+	if got, want := hours(out), []string{"15.75", "26.25", "26", "26", "26"}; !slices.Equal(got, want) {
+		t.Errorf("hours = %v, want %v:\n%s", got, want, out)
+	}
+
+	// The overrides set 32 hours for the week of the 20th and leave out
+	// the week of the 27th, so the other 88 go to the first three weeks.
+	out = captureStdout(t, func() {
+		if err := runCLI(t, args...); err != nil {
+			t.Errorf("weeks: %v", err)
+		}
+	})
+	if got, want := hours(out), []string{"20.25", "34", "33.75", "32"}; !slices.Equal(got, want) {
+		t.Errorf("with overrides, hours = %v, want %v:\n%s", got, want, out)
+	}
+
+	// An overridden week taking more than the month leaves the rest none.
+	var warnings bytes.Buffer
+	old := config.Warnings
+	config.Warnings = &warnings
+	t.Cleanup(func() { config.Warnings = old })
+	out = captureStdout(t, func() {
+		if err := runCLI(t, append(args[:len(args)-1], "30")...); err != nil {
+			t.Errorf("weeks: %v", err)
+		}
+	})
+	if got, want := hours(out), []string{"0", "0", "0", "32"}; !slices.Equal(got, want) {
+		t.Errorf("with 30 hours, hours = %v, want %v:\n%s", got, want, out)
+	}
+	if !strings.Contains(warnings.String(), "the week overrides add up to 32.0 hours, more than the 30.0 of --month-hours") {
+		t.Errorf("warnings = %q", warnings.String())
+	}
+}
+
+func TestMonthHoursFlags(t *testing.T) {
+	for _, tt := range []struct {
+		args    []string
+		wantErr string
+	}{
+		{[]string{"--month-hours", "120", "--hours", "40"}, "--month-hours cannot be combined with --hours"},
+		{[]string{"--month-hours", "120", "--timesheet", "hours.csv"}, "--month-hours cannot be combined with --timesheet"},
+		{[]string{"--month-hours", "120.1"}, "--month-hours must be a positive number of quarter hours"},
+		{[]string{"--month-hours", "0"}, "--month-hours must be a positive number of quarter hours"},
+	} {
+		_, err := parseCLI(t, tt.args...).Generate.resolveOptions(&config.Config{}, nil)
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%v: expected error %q, got %v", tt.args, tt.wantErr, err)
+		}
+	}
+}
+
+func TestWeeks_DaysOff(t *testing.T) {
+	path := weeksFixture(t)
+	var warnings bytes.Buffer
+	old := config.Warnings
+	config.Warnings = &warnings
+	t.Cleanup(func() { config.Warnings = old })
+
+	// Two days off, one repeated and one with commas, a Saturday, and a
+	// day in February.
+	out := captureStdout(t, func() {
+		err := runCLI(t, "weeks", "--config", path, "--no-local", "--no-overrides", "january", "2025",
+			"--days-off", "2025-01-15", "--days-off", "2025-01-16,2025-01-18,2025-02-03")
+		if err != nil {
+			t.Errorf("weeks: %v", err)
+		}
+	})
+	if !strings.Contains(out, "\nJan 13-19  2025-01-13  2025-01-19  3         24     3600.00\n") {
+		t.Errorf("the week of the 13th should lose two days:\n%s", out)
+	}
+	if got, want := warnings.String(), "Warning: day off 2025-02-03 is in no week of January 2025; ignoring it\n"; got != want {
+		t.Errorf("warnings = %q, want %q", got, want)
+	}
+
+	err := runCLI(t, "weeks", "--config", path, "--no-local", "january", "2025", "--days-off", "15/01/2025")
+	if err == nil || !strings.Contains(err.Error(), `day off "15/01/2025" is not a date like 2025-01-31`) {
+		t.Errorf("got %v", err)
+	}
+}
+
+func TestWeeks_HolidayCountry(t *testing.T) {
+	path := writeTestConfig(t, "vendor: Jane Doe\ncustomer: Acme Corp\nrate: 150\nhours: 40\nholidays:\n  country: us\n")
+	oldNow := invoice.Now
+	invoice.Now = func() time.Time { return time.Date(2025, time.August, 5, 0, 0, 0, 0, time.UTC) }
+	t.Cleanup(func() { invoice.Now = oldNow })
+
+	weeks := func(args ...string) [][]string {
+		t.Helper()
+		out := captureStdout(t, func() {
+			if err := runCLI(t, append([]string{"weeks", "--config", path, "--no-local", "--no-overrides", "--format", "csv", "july"}, args...)...); err != nil {
+				t.Errorf("weeks: %v", err)
+			}
+		})
+		rows, err := csv.NewReader(strings.NewReader(out)).ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return rows[1:]
+	}
+
+	// July 4, 2025 is a Friday, off the first week of July.
+	rows := weeks()
+	if got := rows[0]; got[2] != "3" || got[3] != "24" {
+		t.Errorf("the week of July 4 should have 3 workdays and 24 hours: %v", got)
+	}
+
+	// The flag takes the place of the config: July 14 in France.
+	rows = weeks("--holiday-country", "FR")
+	if got := rows[0]; got[2] != "4" || got[3] != "32" {
+		t.Errorf("the first week should have all its workdays in France: %v", got)
+	}
+	if got := rows[2]; got[0] != "2025-07-14" || got[2] != "4" || got[3] != "32" {
+		t.Errorf("the week of July 14 should have 4 workdays and 32 hours: %v", got)
+	}
+
+	err := runCLI(t, "weeks", "--config", path, "--no-local", "july", "--holiday-country", "XX")
+	if err == nil || !strings.Contains(err.Error(), `no public holidays known for "XX"`) {
+		t.Errorf("got %v", err)
+	}
+}
@@ -1,37 +1,585 @@
-// Package config handles reading and writing of ~/.invoicer/config.yaml.
+// Package config handles reading and writing of the invoicer config file.
 package config
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"net/url"
 	"os"
 	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
 
+	"github.com/zon/invoicer/internal/fsutil"
+	"github.com/zon/invoicer/internal/paths"
 	"gopkg.in/yaml.v3"
 )
 
 // Config holds configuration values for invoicer.
-// Only pointer fields are written when explicitly set; nil means "not configured".
+// All fields are pointers so that an explicit zero or empty value can be
+// told apart from "not configured"; nil fields are omitted from the file.
+// Fields tagged `secret:""` are redacted when the config is displayed.
 type Config struct {
-	Vendor   string  `yaml:"vendor,omitempty"`
-	Customer string  `yaml:"customer,omitempty"`
-	Rate     float64 `yaml:"rate,omitempty"`
-	Hours    float64 `yaml:"hours,omitempty"`
-	PDF      *bool   `yaml:"pdf,omitempty"`
-	Model    string  `yaml:"model,omitempty"`
+	Vendor   *string  `yaml:"vendor,omitempty" json:"vendor,omitempty"`
+	Customer *string  `yaml:"customer,omitempty" json:"customer,omitempty"`
+	Rate     *float64 `yaml:"rate,omitempty" json:"rate,omitempty"`
+	Hours    *float64 `yaml:"hours,omitempty" json:"hours,omitempty"`
+	PDF      *bool    `yaml:"pdf,omitempty" json:"pdf,omitempty"`
+	Model    *string  `yaml:"model,omitempty" json:"model,omitempty"`
+	Backend  *string  `yaml:"backend,omitempty" json:"backend,omitempty"`
+
+	// Vendors are the contractors of an agency, each invoicing the
+	// customer on their own, with --vendor NAME or all in turn with
+	// --all-vendors.
+	Vendors []Vendor `yaml:"vendors,omitempty" json:"vendors,omitempty"`
+
+	// IBAN is the vendor's bank account, for the payment details and the
+	// EPC payment QR code.
+	IBAN *string `yaml:"iban,omitempty" json:"iban,omitempty"`
+
+	// BIC is the Business Identifier Code of the vendor's bank.
+	BIC *string `yaml:"bic,omitempty" json:"bic,omitempty"`
+
+	// RoutingNumber and AccountNumber are the vendor's US bank account,
+	// for customers who pay by ACH or wire transfer.
+	RoutingNumber *string `yaml:"routing_number,omitempty" json:"routing_number,omitempty"`
+	AccountNumber *string `yaml:"account_number,omitempty" json:"account_number,omitempty"`
+
+	// VendorCountry and CustomerCountry are the ISO 3166-1 alpha-2 codes
+	// of the parties' countries, e.g. "DE", for e-invoices.
+	VendorCountry   *string `yaml:"vendor_country,omitempty" json:"vendor_country,omitempty"`
+	CustomerCountry *string `yaml:"customer_country,omitempty" json:"customer_country,omitempty"`
+
+	// VendorVATID and CustomerVATID are the parties' VAT identifiers, e.g.
+	// "DE123456789", for e-invoices.
+	VendorVATID   *string `yaml:"vendor_vat_id,omitempty" json:"vendor_vat_id,omitempty"`
+	CustomerVATID *string `yaml:"customer_vat_id,omitempty" json:"customer_vat_id,omitempty"`
+
+	// QuickBooksIncomeAccount and QuickBooksItem are the income account
+	// and the product/service `invoicer export quickbooks` books lines to.
+	QuickBooksIncomeAccount *string `yaml:"quickbooks_income_account,omitempty" json:"quickbooks_income_account,omitempty"`
+	QuickBooksItem          *string `yaml:"quickbooks_item,omitempty" json:"quickbooks_item,omitempty"`
+
+	// XeroAccountCode, XeroTaxType and XeroRegion map `invoicer export
+	// xero` onto a Xero organisation: the revenue account and tax rate of
+	// the lines, and the region that decides the date format.
+	XeroAccountCode *string `yaml:"xero_account_code,omitempty" json:"xero_account_code,omitempty"`
+	XeroTaxType     *string `yaml:"xero_tax_type,omitempty" json:"xero_tax_type,omitempty"`
+	XeroRegion      *string `yaml:"xero_region,omitempty" json:"xero_region,omitempty"`
+
+	// StripeAPIKey is the secret key `invoicer --send-stripe` creates
+	// Stripe invoices with.
+	StripeAPIKey *string `yaml:"stripe_api_key,omitempty" json:"stripe_api_key,omitempty" secret:""`
+
+	// StripeCustomerID and StripeCustomerEmail pick the Stripe customer to
+	// bill: by ID, or by email, creating the customer if there is none.
+	StripeCustomerID    *string `yaml:"stripe_customer_id,omitempty" json:"stripe_customer_id,omitempty"`
+	StripeCustomerEmail *string `yaml:"stripe_customer_email,omitempty" json:"stripe_customer_email,omitempty"`
+
+	// StripeDaysUntilDue is how many days the customer has to pay a Stripe
+	// invoice.
+	StripeDaysUntilDue *int `yaml:"stripe_days_until_due,omitempty" json:"stripe_days_until_due,omitempty"`
+
+	// PayPalClientID and PayPalClientSecret are the credentials of the
+	// PayPal REST app `invoicer --send-paypal` creates invoices with, and
+	// PayPalSandbox says they are for the sandbox.
+	PayPalClientID     *string `yaml:"paypal_client_id,omitempty" json:"paypal_client_id,omitempty"`
+	PayPalClientSecret *string `yaml:"paypal_client_secret,omitempty" json:"paypal_client_secret,omitempty" secret:""`
+	PayPalSandbox      *bool   `yaml:"paypal_sandbox,omitempty" json:"paypal_sandbox,omitempty"`
+
+	// PayPalCustomerEmail is the email PayPal invoices are sent to.
+	PayPalCustomerEmail *string `yaml:"paypal_customer_email,omitempty" json:"paypal_customer_email,omitempty"`
+
+	// PayPalPaymentTerm is the payment term of PayPal invoices, e.g.
+	// "NET_30".
+	PayPalPaymentTerm *string `yaml:"paypal_payment_term,omitempty" json:"paypal_payment_term,omitempty"`
+
+	// FreshBooksToken and FreshBooksAccountID are the OAuth token and
+	// account `invoicer export freshbooks` creates invoices with.
+	FreshBooksToken     *string `yaml:"freshbooks_token,omitempty" json:"freshbooks_token,omitempty" secret:""`
+	FreshBooksAccountID *string `yaml:"freshbooks_account_id,omitempty" json:"freshbooks_account_id,omitempty"`
+
+	// FreshBooksClientID is the FreshBooks client the customer is, set in
+	// the project-local config of each customer.
+	FreshBooksClientID *string `yaml:"freshbooks_client_id,omitempty" json:"freshbooks_client_id,omitempty"`
+
+	// EmailProvider is how invoice emails are sent: smtp, gmail or
+	// sendgrid.
+	EmailProvider *string `yaml:"email_provider,omitempty" json:"email_provider,omitempty"`
+
+	// SMTPHost, SMTPPort, SMTPSecurity, SMTPUsername and SMTPPassword are
+	// the SMTP server `invoicer send` emails invoices through. SMTPSecurity
+	// is starttls, tls or none.
+	SMTPHost     *string `yaml:"smtp_host,omitempty" json:"smtp_host,omitempty"`
+	SMTPPort     *int    `yaml:"smtp_port,omitempty" json:"smtp_port,omitempty"`
+	SMTPSecurity *string `yaml:"smtp_security,omitempty" json:"smtp_security,omitempty"`
+	SMTPUsername *string `yaml:"smtp_username,omitempty" json:"smtp_username,omitempty"`
+	SMTPPassword *string `yaml:"smtp_password,omitempty" json:"smtp_password,omitempty" secret:""`
+
+	// GmailToken is the OAuth access token invoice emails are sent with
+	// through the Gmail API, and SendGridAPIKey the API key they are sent
+	// with through SendGrid.
+	GmailToken     *string `yaml:"gmail_token,omitempty" json:"gmail_token,omitempty" secret:""`
+	SendGridAPIKey *string `yaml:"sendgrid_api_key,omitempty" json:"sendgrid_api_key,omitempty" secret:""`
+
+	// EmailFrom is the sender of invoice emails, and EmailTo the
+	// comma-separated recipients, set in the project-local config of each
+	// customer.
+	EmailFrom *string `yaml:"email_from,omitempty" json:"email_from,omitempty"`
+	EmailTo   *string `yaml:"email_to,omitempty" json:"email_to,omitempty"`
+
+	// EmailSubject and EmailBody are text/template templates of invoice
+	// emails, filled in with the invoice's fields.
+	EmailSubject *string `yaml:"email_subject,omitempty" json:"email_subject,omitempty"`
+	EmailBody    *string `yaml:"email_body,omitempty" json:"email_body,omitempty"`
+
+	// NotifyWebhook is the Slack incoming webhook or Discord webhook a
+	// message is posted to when an invoice is generated, and NotifyMessage
+	// the text/template template of the message.
+	NotifyWebhook *string `yaml:"notify_webhook,omitempty" json:"notify_webhook,omitempty" secret:""`
+	NotifyMessage *string `yaml:"notify_message,omitempty" json:"notify_message,omitempty"`
+
+	// DateFormat is the style dates are shown in on invoices and in
+	// emails: long, iso, us or eu. ExportDateFormat is the style exports
+	// label weeks in, and defaults to DateFormat.
+	DateFormat       *string `yaml:"date_format,omitempty" json:"date_format,omitempty"`
+	ExportDateFormat *string `yaml:"export_date_format,omitempty" json:"export_date_format,omitempty"`
+
+	// Language is the code of the language invoices are labelled in, such
+	// as "de"; English if not set.
+	Language *string `yaml:"language,omitempty" json:"language,omitempty"`
+
+	// ShowWorkdays adds each week's workdays to its line item, e.g. "Jan
+	// 1-5 (3 workdays)".
+	ShowWorkdays *bool `yaml:"show_workdays,omitempty" json:"show_workdays,omitempty"`
+
+	// Holidays are the public holidays that are not workdays of their
+	// weeks.
+	Holidays *Holidays `yaml:"holidays,omitempty" json:"holidays,omitempty"`
+
+	// RatePrecision and HoursPrecision are the decimals rates and hours are
+	// shown with, 0 to 4.
+	RatePrecision  *int `yaml:"rate_precision,omitempty" json:"rate_precision,omitempty"`
+	HoursPrecision *int `yaml:"hours_precision,omitempty" json:"hours_precision,omitempty"`
+
+	// DecimalSeparator is "." or ",", the decimal separator of rates and
+	// hours written as text in this file, e.g. "," to read "1.250" as
+	// 1250. Without it, "1.250" could be either and is an error.
+	DecimalSeparator *string `yaml:"decimal_separator,omitempty" json:"decimal_separator,omitempty"`
+
+	// BillCurrency is the ISO 4217 currency invoices are billed in, if not
+	// dollars, e.g. "EUR". Its exchange rate is fetched from FXProvider,
+	// ecb or exchangerate.host, which takes the access key FXAPIKey.
+	BillCurrency *string `yaml:"bill_currency,omitempty" json:"bill_currency,omitempty"`
+	FXProvider   *string `yaml:"fx_provider,omitempty" json:"fx_provider,omitempty"`
+	FXAPIKey     *string `yaml:"fx_api_key,omitempty" json:"fx_api_key,omitempty" secret:""`
+
+	// Schedule is how often the customer is invoiced, for `invoicer due`:
+	// monthly, on ScheduleDay of the month for the previous month, with
+	// days counted in Timezone, an IANA time zone such as "Europe/Berlin".
+	Schedule    *string `yaml:"schedule,omitempty" json:"schedule,omitempty"`
+	ScheduleDay *int    `yaml:"schedule_day,omitempty" json:"schedule_day,omitempty"`
+	Timezone    *string `yaml:"timezone,omitempty" json:"timezone,omitempty"`
+
+	OllamaHost  *string `yaml:"ollama_host,omitempty" json:"ollama_host,omitempty"`
+	OllamaModel *string `yaml:"ollama_model,omitempty" json:"ollama_model,omitempty"`
+
+	// Agent is the opencode agent that writes the invoice.
+	Agent *string `yaml:"agent,omitempty" json:"agent,omitempty"`
+
+	// Session is the opencode session to continue.
+	Session *string `yaml:"session,omitempty" json:"session,omitempty"`
+
+	// RestrictTools switches off the opencode tools in DeniedTools.
+	RestrictTools *bool `yaml:"restrict_tools,omitempty" json:"restrict_tools,omitempty"`
+
+	// DeniedTools is a comma-separated list of opencode tools to switch off,
+	// e.g. "bash,webfetch".
+	DeniedTools *string `yaml:"denied_tools,omitempty" json:"denied_tools,omitempty"`
+
+	// Timeout bounds the generation step, e.g. "120s" or "5m".
+	Timeout *Duration `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+
+	// PDFOnly converts the invoice to PDF and removes the HTML afterwards.
+	PDFOnly *bool `yaml:"pdf_only,omitempty" json:"pdf_only,omitempty"`
+
+	// PDFEngine is how the PDF is produced: "exec", "chromedp" or "auto".
+	PDFEngine *string `yaml:"pdf_engine,omitempty" json:"pdf_engine,omitempty"`
+
+	// PDFTool is the PDF conversion tool to use, e.g. "chrome", or "auto".
+	PDFTool *string `yaml:"pdf_tool,omitempty" json:"pdf_tool,omitempty"`
+
+	// PDFToolPath is the PDF tool's executable, for one not on PATH.
+	PDFToolPath *string `yaml:"pdf_tool_path,omitempty" json:"pdf_tool_path,omitempty"`
+
+	// PDFToolArgs are extra arguments for the PDF tool, each passed as is.
+	PDFToolArgs []string `yaml:"pdf_tool_args,omitempty" json:"pdf_tool_args,omitempty"`
+
+	// PDFTimeout bounds the PDF conversion step, e.g. "60s".
+	PDFTimeout *Duration `yaml:"pdf_timeout,omitempty" json:"pdf_timeout,omitempty"`
+
+	// PDFTitle is the pattern for the PDF's title, e.g.
+	// "Invoice {number} for {customer}".
+	PDFTitle *string `yaml:"pdf_title,omitempty" json:"pdf_title,omitempty"`
+
+	// Thumbnail renders a PNG preview of the invoice beside it.
+	Thumbnail *bool `yaml:"thumbnail,omitempty" json:"thumbnail,omitempty"`
+
+	// ThumbnailWidth is the width of the thumbnail in pixels.
+	ThumbnailWidth *int `yaml:"thumbnail_width,omitempty" json:"thumbnail_width,omitempty"`
+
+	// Retries is how many times a failed generation is retried.
+	Retries *int `yaml:"retries,omitempty" json:"retries,omitempty"`
+
+	// MaxCost is the most a generation may cost in US dollars.
+	MaxCost *float64 `yaml:"max_cost,omitempty" json:"max_cost,omitempty"`
+
+	PostGenerateHook *string `yaml:"post_generate_hook,omitempty" json:"post_generate_hook,omitempty"`
+	HookStrict       *bool   `yaml:"hook_strict,omitempty" json:"hook_strict,omitempty"`
+
+	// ServeToken is the bearer token required by `invoicer serve`.
+	ServeToken *string `yaml:"serve_token,omitempty" json:"serve_token,omitempty" secret:""`
+
+	// Strict makes unknown keys in the file an error instead of a warning.
+	Strict *bool `yaml:"strict,omitempty" json:"strict,omitempty"`
+}
+
+// Vendor is one of an agency's contractors. Its rate, hours, IBAN and BIC
+// replace the top-level ones in its invoices; nil fields fall back to
+// them.
+type Vendor struct {
+	Name  string   `yaml:"name" json:"name"`
+	Rate  *float64 `yaml:"rate,omitempty" json:"rate,omitempty"`
+	Hours *float64 `yaml:"hours,omitempty" json:"hours,omitempty"`
+	IBAN  *string  `yaml:"iban,omitempty" json:"iban,omitempty"`
+	BIC   *string  `yaml:"bic,omitempty" json:"bic,omitempty"`
+}
+
+// String returns the vendor's name.
+func (v Vendor) String() string {
+	return v.Name
+}
+
+// FindVendor returns the entry of Vendors named name, ignoring case, or
+// nil if there is none.
+func (c *Config) FindVendor(name string) *Vendor {
+	for i := range c.Vendors {
+		if strings.EqualFold(c.Vendors[i].Name, name) {
+			return &c.Vendors[i]
+		}
+	}
+	return nil
+}
+
+// VendorNames returns the names of Vendors, in order.
+func (c *Config) VendorNames() []string {
+	var names []string
+	for _, v := range c.Vendors {
+		names = append(names, v.Name)
+	}
+	return names
+}
+
+// Holidays is the holidays: block of a config file.
+type Holidays struct {
+	// Country is the country, or region of one such as "DE-BY", whose
+	// public holidays are taken off.
+	Country *string `yaml:"country,omitempty" json:"country,omitempty"`
+}
+
+// HolidayCountry returns the country of c's holidays, or "" if none is
+// set.
+func (c *Config) HolidayCountry() string {
+	if c.Holidays == nil || c.Holidays.Country == nil {
+		return ""
+	}
+	return *c.Holidays.Country
+}
+
+// String returns the country, the block's only setting, for 'show config'.
+func (h Holidays) String() string {
+	if h.Country == nil {
+		return ""
+	}
+	return *h.Country
+}
+
+// UnmarshalText sets the country, so INVOICER_HOLIDAY_COUNTRY and the
+// answers of 'set config' fill in the block.
+func (h *Holidays) UnmarshalText(text []byte) error {
+	country := string(text)
+	h.Country = &country
+	return nil
+}
+
+// UnmarshalYAML decodes the block, which must be a mapping such as
+// country: US; yaml.v3 would otherwise read a lone country with
+// UnmarshalText.
+func (h *Holidays) UnmarshalYAML(n *yaml.Node) error {
+	if n.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: holidays must be a block such as country: US", n.Line)
+	}
+	type plain Holidays
+	return n.Decode((*plain)(h))
+}
+
+// Duration is a time.Duration written in config files as a string such as
+// "90s" or "5m".
+type Duration time.Duration
+
+// String formats the duration like time.Duration.
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+// MarshalText writes the duration as a string such as "1m30s".
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText parses a duration string such as "90s".
+func (d *Duration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return fmt.Errorf("invalid duration %q (use e.g. 90s or 5m)", text)
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// SplitModels splits a comma-separated model list, such as
+// "anthropic/claude-haiku-4-5,anthropic/claude-sonnet-4-6", into its models
+// in order of preference.
+func SplitModels(s string) []string {
+	models := strings.Split(s, ",")
+	for i, m := range models {
+		models[i] = strings.TrimSpace(m)
+	}
+	return models
+}
+
+// SplitTools splits a comma-separated tool list, such as "bash,webfetch",
+// leaving out empty entries.
+func SplitTools(s string) []string {
+	var tools []string
+	for _, tool := range strings.Split(s, ",") {
+		if tool = strings.TrimSpace(tool); tool != "" {
+			tools = append(tools, tool)
+		}
+	}
+	return tools
+}
+
+// modelPattern matches an opencode-formatted model stub (provider/model).
+var modelPattern = regexp.MustCompile(`^[^/\s]+/\S+$`)
+
+// ParseModels splits a comma-separated model list like SplitModels and
+// checks that every entry looks like provider/model.
+func ParseModels(s string) ([]string, error) {
+	models := SplitModels(s)
+	var errs []error
+	for _, model := range models {
+		switch {
+		case model == "":
+			errs = append(errs, fmt.Errorf("model list %q has an empty entry", s))
+		case !modelPattern.MatchString(model):
+			errs = append(errs, fmt.Errorf("model must look like provider/model (e.g. anthropic/claude-haiku-4-5), got %q", model))
+		}
+	}
+	return models, errors.Join(errs...)
 }
 
-// DefaultPath returns the default path to the config file (~/.invoicer/config.yaml).
+// KnownProviders are the model providers invoicer recognizes. A model from
+// another provider may still work, so it only draws a warning.
+var KnownProviders = []string{
+	"amazon-bedrock", "anthropic", "azure", "deepseek", "github-copilot",
+	"google", "google-vertex", "groq", "mistral", "ollama", "openai",
+	"opencode", "openrouter", "xai",
+}
+
+// KnownProvider reports whether model's provider is one of KnownProviders.
+func KnownProvider(model string) bool {
+	provider, _, _ := strings.Cut(model, "/")
+	return slices.Contains(KnownProviders, provider)
+}
+
+// maxHoursPerWeek is the number of hours in a week.
+const maxHoursPerWeek = 168
+
+// MaxRetries caps retries so a misconfiguration cannot loop for hours.
+const MaxRetries = 10
+
+// maxPrecision is the most decimals rates and hours are shown with.
+const maxPrecision = 4
+
+// Validate checks the values of the fields that are set.
+// It returns all problems found joined into a single error.
+func (c *Config) Validate() error {
+	var errs []error
+	if c.Rate != nil && *c.Rate <= 0 {
+		errs = append(errs, fmt.Errorf("rate must be positive, got %v", *c.Rate))
+	}
+	if c.Hours != nil && (*c.Hours <= 0 || *c.Hours > maxHoursPerWeek) {
+		errs = append(errs, fmt.Errorf("hours must be between 0 and %d, got %v", maxHoursPerWeek, *c.Hours))
+	}
+	seen := map[string]bool{}
+	for i, v := range c.Vendors {
+		key := strings.ToLower(v.Name)
+		switch {
+		case v.Name == "":
+			errs = append(errs, fmt.Errorf("vendors[%d] has no name", i))
+		case seen[key]:
+			errs = append(errs, fmt.Errorf("vendors: %q is listed twice", v.Name))
+		}
+		seen[key] = true
+		if v.Rate != nil && *v.Rate <= 0 {
+			errs = append(errs, fmt.Errorf("vendor %q: rate must be positive, got %v", v.Name, *v.Rate))
+		}
+		if v.Hours != nil && (*v.Hours <= 0 || *v.Hours > maxHoursPerWeek) {
+			errs = append(errs, fmt.Errorf("vendor %q: hours must be between 0 and %d, got %v", v.Name, maxHoursPerWeek, *v.Hours))
+		}
+	}
+	if c.Model != nil {
+		if _, err := ParseModels(*c.Model); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if c.Timeout != nil && *c.Timeout <= 0 {
+		errs = append(errs, fmt.Errorf("timeout must be positive, got %v", *c.Timeout))
+	}
+	if c.PDFTimeout != nil && *c.PDFTimeout <= 0 {
+		errs = append(errs, fmt.Errorf("pdf_timeout must be positive, got %v", *c.PDFTimeout))
+	}
+	if c.ThumbnailWidth != nil && *c.ThumbnailWidth <= 0 {
+		errs = append(errs, fmt.Errorf("thumbnail_width must be positive, got %d", *c.ThumbnailWidth))
+	}
+	if c.Retries != nil && (*c.Retries < 0 || *c.Retries > MaxRetries) {
+		errs = append(errs, fmt.Errorf("retries must be between 0 and %d, got %d", MaxRetries, *c.Retries))
+	}
+	if c.MaxCost != nil && *c.MaxCost <= 0 {
+		errs = append(errs, fmt.Errorf("max_cost must be positive, got %v", *c.MaxCost))
+	}
+	if c.StripeDaysUntilDue != nil && *c.StripeDaysUntilDue <= 0 {
+		errs = append(errs, fmt.Errorf("stripe_days_until_due must be positive, got %d", *c.StripeDaysUntilDue))
+	}
+	if c.SMTPPort != nil && (*c.SMTPPort <= 0 || *c.SMTPPort > 65535) {
+		errs = append(errs, fmt.Errorf("smtp_port must be between 1 and 65535, got %d", *c.SMTPPort))
+	}
+	if c.RatePrecision != nil && (*c.RatePrecision < 0 || *c.RatePrecision > maxPrecision) {
+		errs = append(errs, fmt.Errorf("rate_precision must be between 0 and %d, got %d", maxPrecision, *c.RatePrecision))
+	}
+	if c.HoursPrecision != nil && (*c.HoursPrecision < 0 || *c.HoursPrecision > maxPrecision) {
+		errs = append(errs, fmt.Errorf("hours_precision must be between 0 and %d, got %d", maxPrecision, *c.HoursPrecision))
+	}
+	if c.DecimalSeparator != nil {
+		if err := ValidateDecimalSeparator(*c.DecimalSeparator); err != nil {
+			errs = append(errs, fmt.Errorf("decimal_separator: %w", err))
+		}
+	}
+	if c.ScheduleDay != nil && (*c.ScheduleDay < 1 || *c.ScheduleDay > 31) {
+		errs = append(errs, fmt.Errorf("schedule_day must be between 1 and 31, got %d", *c.ScheduleDay))
+	}
+	if c.Timezone != nil {
+		if _, err := time.LoadLocation(*c.Timezone); err != nil {
+			errs = append(errs, fmt.Errorf("timezone must be an IANA time zone such as Europe/Berlin, got %q", *c.Timezone))
+		}
+	}
+	if c.OllamaHost != nil {
+		if u, err := url.Parse(*c.OllamaHost); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("ollama_host must be an http or https URL (e.g. http://localhost:11434), got %q", *c.OllamaHost))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// DefaultPath returns the default path to the config file.
+// This is ~/.invoicer/config.yaml if it already exists, otherwise
+// invoicer/config.yaml under the user config directory.
 func DefaultPath() (string, error) {
-	home, err := os.UserHomeDir()
+	return paths.ConfigFile()
+}
+
+// profileName matches the names a profile may have.
+var profileName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// ProfilePath returns the path of the named profile's config file,
+// config.yaml in the profile's directory (see paths.ProfileRoot). The file
+// may not exist yet.
+func ProfilePath(name string) (string, error) {
+	if !profileName.MatchString(name) {
+		return "", fmt.Errorf("invalid profile name %q: use letters, digits, '-', '_' and '.'", name)
+	}
+	dir, err := paths.ProfileRoot(name)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "config.yaml"), nil
+}
+
+// LookupProfile returns the path of the named profile's config file, or
+// an error listing the profiles there are if it does not exist.
+func LookupProfile(name string) (string, error) {
+	path, err := ProfilePath(name)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(path); err == nil {
+		return path, nil
+	}
+	names, err := Profiles()
+	if err != nil {
+		return "", err
+	}
+	if len(names) == 0 {
+		return "", fmt.Errorf("unknown profile %q: there are no profiles yet (create one with 'invoicer --profile %s set config ...')", name, name)
+	}
+	return "", fmt.Errorf("unknown profile %q (available: %s)", name, strings.Join(names, ", "))
+}
+
+// Profiles returns the names of the profiles that have a config file, in
+// order.
+func Profiles() ([]string, error) {
+	dir, err := paths.ProfileRoot("")
 	if err != nil {
-		return "", fmt.Errorf("could not determine home directory: %w", err)
+		return nil, err
 	}
-	return filepath.Join(home, ".invoicer", "config.yaml"), nil
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("listing profiles: %w", err)
+	}
+	var names []string
+	for _, entry := range entries {
+		if _, err := os.Stat(filepath.Join(dir, entry.Name(), "config.yaml")); entry.IsDir() && err == nil {
+			names = append(names, entry.Name())
+		}
+	}
+	return names, nil
 }
 
+// Warnings is where Load reports problems that are not errors, such as unknown
+// keys in a file without strict: true. It can be overridden in tests.
+var Warnings io.Writer = os.Stderr
+
 // Load reads the config file at the given path.
 // If the file does not exist, an empty Config is returned without error.
+// Unknown keys are reported to Warnings and ignored, unless the file sets
+// strict: true, in which case they are an error.
 func Load(path string) (*Config, error) {
+	return load(path, false)
+}
+
+// LoadStrict is like Load, but unknown keys are always an error.
+func LoadStrict(path string) (*Config, error) {
+	return load(path, true)
+}
+
+func load(path string, strict bool) (*Config, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -40,55 +588,261 @@ func Load(path string) (*Config, error) {
 		return nil, fmt.Errorf("reading config file %q: %w", path, err)
 	}
 
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parsing config file %q: %w", path, err)
+	}
+	if err := parseNumbers(&doc); err != nil {
+		return nil, fmt.Errorf("parsing config file %q: %w", path, err)
+	}
 	var cfg Config
-	if err := yaml.Unmarshal(data, &cfg); err != nil {
+	if err := doc.Decode(&cfg); err != nil {
 		return nil, fmt.Errorf("parsing config file %q: %w", path, err)
 	}
+
+	unknown := unknownKeys(path, &doc)
+	if len(unknown) == 0 {
+		return &cfg, nil
+	}
+	if strict || (cfg.Strict != nil && *cfg.Strict) {
+		return nil, errors.Join(unknown...)
+	}
+	for _, err := range unknown {
+		fmt.Fprintf(Warnings, "Warning: %v; ignoring it (set strict: true to make this an error)\n", err)
+	}
 	return &cfg, nil
 }
 
+// UnknownKeyError reports a key in a config file that invoicer does not recognize.
+type UnknownKeyError struct {
+	Path string
+	Line int
+	Key  string
+}
+
+func (e *UnknownKeyError) Error() string {
+	return fmt.Sprintf("%s:%d: %v", e.Path, e.Line, unknownKeyError(e.Key))
+}
+
+// unknownKeys returns an UnknownKeyError for each top-level key in doc that
+// does not match a Config field.
+func unknownKeys(path string, doc *yaml.Node) []error {
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil
+	}
+	var errs []error
+	m := doc.Content[0]
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if k := m.Content[i]; !isKey(k.Value) {
+			errs = append(errs, &UnknownKeyError{Path: path, Line: k.Line, Key: k.Value})
+		}
+	}
+	return errs
+}
+
 // Save writes the config to the given path, creating the directory and file if needed.
 // It merges the provided updates into any existing config, only overwriting fields
 // that are explicitly set in updates.
 func Save(path string, updates *Config) error {
-	// Load existing config (if any) so we only update specified fields.
-	existing, err := Load(path)
+	// Merge: fields set in updates take precedence over existing values.
+	return Edit(path, func(existing *Config) {
+		*existing = *Merge(existing, updates)
+	})
+}
+
+// Edit loads the config at path, lets edit change it, and writes it back,
+// with the file locked throughout so no other change is lost. The file
+// and its directory are created if needed.
+func Edit(path string, edit func(*Config)) error {
+	unlock, err := fsutil.Lock(path, fsutil.DefaultLockTimeout)
+	if err != nil {
+		return fmt.Errorf("locking config file: %w", err)
+	}
+	defer unlock()
+
+	cfg, err := Load(path)
 	if err != nil {
 		return err
 	}
+	edit(cfg)
+	return write(path, cfg)
+}
 
-	// Merge: updates take precedence over existing values.
-	if updates.Vendor != "" {
-		existing.Vendor = updates.Vendor
+// Merge returns a copy of base with the fields set in over replacing its values.
+// over may be nil.
+func Merge(base, over *Config) *Config {
+	merged := *base
+	if over == nil {
+		return &merged
 	}
-	if updates.Customer != "" {
-		existing.Customer = updates.Customer
+	mv := reflect.ValueOf(&merged).Elem()
+	ov := reflect.ValueOf(over).Elem()
+	for i := 0; i < ov.NumField(); i++ {
+		if f := ov.Field(i); !f.IsZero() {
+			mv.Field(i).Set(f)
+		}
 	}
-	if updates.Rate != 0 {
-		existing.Rate = updates.Rate
+	return &merged
+}
+
+// LocalFile is the name of a project-local config file that overrides the
+// global config for invoices generated in its directory tree.
+const LocalFile = ".invoicer.yaml"
+
+// FindLocal searches dir and its parents for a LocalFile and returns its path,
+// or "" if there is none. The search stops before stop (typically the user's
+// home directory, which holds the global config), after a directory containing
+// .git (the repository root), or at the filesystem root.
+func FindLocal(dir, stop string) string {
+	for {
+		if stop != "" && dir == stop {
+			return ""
+		}
+		path := filepath.Join(dir, LocalFile)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return ""
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
 	}
-	if updates.Hours != 0 {
-		existing.Hours = updates.Hours
+}
+
+// Unset removes the given keys from the config file at path.
+// It returns the keys that were set and have been removed; keys that were not set
+// are skipped. The file is only rewritten if at least one key was removed.
+// An unknown key is an error and leaves the file untouched.
+func Unset(path string, keys []string) ([]string, error) {
+	for _, key := range keys {
+		if !isKey(key) {
+			return nil, unknownKeyError(key)
+		}
 	}
-	if updates.PDF != nil {
-		existing.PDF = updates.PDF
+
+	unlock, err := fsutil.Lock(path, fsutil.DefaultLockTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("locking config file: %w", err)
 	}
-	if updates.Model != "" {
-		existing.Model = updates.Model
+	defer unlock()
+
+	cfg, err := Load(path)
+	if err != nil {
+		return nil, err
 	}
 
-	// Ensure the directory exists.
-	dir := filepath.Dir(path)
-	if err := os.MkdirAll(dir, 0o700); err != nil {
-		return fmt.Errorf("creating config directory %q: %w", dir, err)
+	v := reflect.ValueOf(cfg).Elem()
+	var removed []string
+	for _, key := range keys {
+		f := v.Field(fieldIndex(key))
+		if f.IsZero() {
+			continue
+		}
+		f.SetZero()
+		removed = append(removed, key)
+	}
+	if len(removed) == 0 {
+		return nil, nil
 	}
+	return removed, write(path, cfg)
+}
 
-	data, err := yaml.Marshal(existing)
+// Keys returns the config file keys in declaration order.
+func Keys() []string {
+	t := reflect.TypeOf(Config{})
+	keys := make([]string, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		if key := yamlKey(t.Field(i)); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// isKey reports whether key is a known config key.
+func isKey(key string) bool {
+	return fieldIndex(key) >= 0
+}
+
+// fieldIndex returns the index of the Config field with the given key, or -1.
+func fieldIndex(key string) int {
+	t := reflect.TypeOf(Config{})
+	for i := 0; i < t.NumField(); i++ {
+		if yamlKey(t.Field(i)) == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// yamlKey returns the YAML key of a struct field, or "" if it has none.
+func yamlKey(f reflect.StructField) string {
+	key, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+	if key == "-" {
+		return ""
+	}
+	return key
+}
+
+// unknownKeyError returns an error for an unrecognized config key,
+// suggesting the closest known key if there is a plausible one.
+func unknownKeyError(key string) error {
+	if s := Suggest(key); s != "" {
+		return fmt.Errorf("unknown config key %q (did you mean %q?)", key, s)
+	}
+	return fmt.Errorf("unknown config key %q (valid keys: %s)", key, strings.Join(Keys(), ", "))
+}
+
+// Suggest returns the known config key closest to key by edit distance,
+// or "" if none is close enough to be a likely typo.
+func Suggest(key string) string {
+	best, bestDist := "", -1
+	for _, k := range Keys() {
+		d := levenshtein(key, k)
+		if bestDist < 0 || d < bestDist {
+			best, bestDist = k, d
+		}
+	}
+	// Allow roughly one edit per three characters.
+	if bestDist < 0 || bestDist > max(2, len(key)/3) {
+		return ""
+	}
+	return best
+}
+
+// levenshtein returns the edit distance between a and b.
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+// write atomically writes cfg to path, creating the directory if needed.
+// Callers must hold the lock for path.
+func write(path string, cfg *Config) error {
+	data, err := yaml.Marshal(cfg)
 	if err != nil {
 		return fmt.Errorf("marshaling config: %w", err)
 	}
-
-	if err := os.WriteFile(path, data, 0o600); err != nil {
+	if err := fsutil.WriteFileAtomic(path, data, 0o600); err != nil {
 		return fmt.Errorf("writing config file %q: %w", path, err)
 	}
 	return nil
@@ -1,15 +1,41 @@
 package config_test
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/zon/invoicer/internal/config"
 )
 
 func boolPtr(b bool) *bool { return &b }
 
+func strPtr(s string) *string { return &s }
+
+func floatPtr(f float64) *float64 { return &f }
+
+// str and num format optional values for test failure messages.
+func str(p *string) string {
+	if p == nil {
+		return "<nil>"
+	}
+	return *p
+}
+
+func num(p *float64) string {
+	if p == nil {
+		return "<nil>"
+	}
+	return fmt.Sprint(*p)
+}
+
 func TestLoad_FileNotExist(t *testing.T) {
 	path := filepath.Join(t.TempDir(), "config.yaml")
 	cfg, err := config.Load(path)
@@ -20,7 +46,7 @@ func TestLoad_FileNotExist(t *testing.T) {
 		t.Fatal("expected non-nil config")
 	}
 	// All fields should be zero values.
-	if cfg.Vendor != "" || cfg.Customer != "" || cfg.Rate != 0 || cfg.Hours != 0 || cfg.PDF != nil || cfg.Model != "" {
+	if cfg.Vendor != nil || cfg.Customer != nil || cfg.Rate != nil || cfg.Hours != nil || cfg.PDF != nil || cfg.Model != nil {
 		t.Errorf("expected empty config, got: %+v", cfg)
 	}
 }
@@ -43,23 +69,23 @@ model: anthropic/claude-haiku-4-5
 	if err != nil {
 		t.Fatalf("Load: %v", err)
 	}
-	if cfg.Vendor != "Acme Corp" {
-		t.Errorf("Vendor: got %q, want %q", cfg.Vendor, "Acme Corp")
+	if cfg.Vendor == nil || *cfg.Vendor != "Acme Corp" {
+		t.Errorf("Vendor: got %q, want %q", str(cfg.Vendor), "Acme Corp")
 	}
-	if cfg.Customer != "Big Client" {
-		t.Errorf("Customer: got %q, want %q", cfg.Customer, "Big Client")
+	if cfg.Customer == nil || *cfg.Customer != "Big Client" {
+		t.Errorf("Customer: got %q, want %q", str(cfg.Customer), "Big Client")
 	}
-	if cfg.Rate != 150.5 {
-		t.Errorf("Rate: got %v, want 150.5", cfg.Rate)
+	if cfg.Rate == nil || *cfg.Rate != 150.5 {
+		t.Errorf("Rate: got %v, want 150.5", num(cfg.Rate))
 	}
-	if cfg.Hours != 40 {
-		t.Errorf("Hours: got %v, want 40", cfg.Hours)
+	if cfg.Hours == nil || *cfg.Hours != 40 {
+		t.Errorf("Hours: got %v, want 40", num(cfg.Hours))
 	}
 	if cfg.PDF == nil || !*cfg.PDF {
 		t.Errorf("PDF: got %v, want true", cfg.PDF)
 	}
-	if cfg.Model != "anthropic/claude-haiku-4-5" {
-		t.Errorf("Model: got %q, want %q", cfg.Model, "anthropic/claude-haiku-4-5")
+	if cfg.Model == nil || *cfg.Model != "anthropic/claude-haiku-4-5" {
+		t.Errorf("Model: got %q, want %q", str(cfg.Model), "anthropic/claude-haiku-4-5")
 	}
 }
 
@@ -80,12 +106,12 @@ func TestSave_CreatesFileAndDirectory(t *testing.T) {
 	path := filepath.Join(dir, "subdir", "config.yaml")
 
 	updates := &config.Config{
-		Vendor:   "My Company",
-		Customer: "Client Inc",
-		Rate:     100,
-		Hours:    35,
+		Vendor:   strPtr("My Company"),
+		Customer: strPtr("Client Inc"),
+		Rate:     floatPtr(100),
+		Hours:    floatPtr(35),
 		PDF:      boolPtr(false),
-		Model:    "anthropic/claude-haiku-4-5",
+		Model:    strPtr("anthropic/claude-haiku-4-5"),
 	}
 
 	if err := config.Save(path, updates); err != nil {
@@ -100,23 +126,23 @@ func TestSave_CreatesFileAndDirectory(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Load after Save: %v", err)
 	}
-	if cfg.Vendor != "My Company" {
-		t.Errorf("Vendor: got %q, want %q", cfg.Vendor, "My Company")
+	if cfg.Vendor == nil || *cfg.Vendor != "My Company" {
+		t.Errorf("Vendor: got %q, want %q", str(cfg.Vendor), "My Company")
 	}
-	if cfg.Customer != "Client Inc" {
-		t.Errorf("Customer: got %q, want %q", cfg.Customer, "Client Inc")
+	if cfg.Customer == nil || *cfg.Customer != "Client Inc" {
+		t.Errorf("Customer: got %q, want %q", str(cfg.Customer), "Client Inc")
 	}
-	if cfg.Rate != 100 {
-		t.Errorf("Rate: got %v, want 100", cfg.Rate)
+	if cfg.Rate == nil || *cfg.Rate != 100 {
+		t.Errorf("Rate: got %v, want 100", num(cfg.Rate))
 	}
-	if cfg.Hours != 35 {
-		t.Errorf("Hours: got %v, want 35", cfg.Hours)
+	if cfg.Hours == nil || *cfg.Hours != 35 {
+		t.Errorf("Hours: got %v, want 35", num(cfg.Hours))
 	}
 	if cfg.PDF == nil || *cfg.PDF != false {
 		t.Errorf("PDF: got %v, want false", cfg.PDF)
 	}
-	if cfg.Model != "anthropic/claude-haiku-4-5" {
-		t.Errorf("Model: got %q, want %q", cfg.Model, "anthropic/claude-haiku-4-5")
+	if cfg.Model == nil || *cfg.Model != "anthropic/claude-haiku-4-5" {
+		t.Errorf("Model: got %q, want %q", str(cfg.Model), "anthropic/claude-haiku-4-5")
 	}
 }
 
@@ -126,10 +152,10 @@ func TestSave_MergesWithExisting(t *testing.T) {
 
 	// Write initial config.
 	initial := &config.Config{
-		Vendor:   "Original Vendor",
-		Customer: "Original Client",
-		Rate:     80,
-		Hours:    40,
+		Vendor:   strPtr("Original Vendor"),
+		Customer: strPtr("Original Client"),
+		Rate:     floatPtr(80),
+		Hours:    floatPtr(40),
 	}
 	if err := config.Save(path, initial); err != nil {
 		t.Fatalf("initial Save: %v", err)
@@ -137,8 +163,8 @@ func TestSave_MergesWithExisting(t *testing.T) {
 
 	// Update only vendor and rate.
 	updates := &config.Config{
-		Vendor: "New Vendor",
-		Rate:   120,
+		Vendor: strPtr("New Vendor"),
+		Rate:   floatPtr(120),
 	}
 	if err := config.Save(path, updates); err != nil {
 		t.Fatalf("update Save: %v", err)
@@ -150,18 +176,18 @@ func TestSave_MergesWithExisting(t *testing.T) {
 	}
 
 	// Updated fields.
-	if cfg.Vendor != "New Vendor" {
-		t.Errorf("Vendor: got %q, want %q", cfg.Vendor, "New Vendor")
+	if cfg.Vendor == nil || *cfg.Vendor != "New Vendor" {
+		t.Errorf("Vendor: got %q, want %q", str(cfg.Vendor), "New Vendor")
 	}
-	if cfg.Rate != 120 {
-		t.Errorf("Rate: got %v, want 120", cfg.Rate)
+	if cfg.Rate == nil || *cfg.Rate != 120 {
+		t.Errorf("Rate: got %v, want 120", num(cfg.Rate))
 	}
 	// Unchanged fields.
-	if cfg.Customer != "Original Client" {
-		t.Errorf("Customer: got %q, want %q (should be unchanged)", cfg.Customer, "Original Client")
+	if cfg.Customer == nil || *cfg.Customer != "Original Client" {
+		t.Errorf("Customer: got %q, want %q (should be unchanged)", str(cfg.Customer), "Original Client")
 	}
-	if cfg.Hours != 40 {
-		t.Errorf("Hours: got %v, want 40 (should be unchanged)", cfg.Hours)
+	if cfg.Hours == nil || *cfg.Hours != 40 {
+		t.Errorf("Hours: got %v, want 40 (should be unchanged)", num(cfg.Hours))
 	}
 }
 
@@ -171,12 +197,12 @@ func TestSave_OnlyUpdatesSpecifiedFields(t *testing.T) {
 
 	// Write initial config with all fields.
 	initial := &config.Config{
-		Vendor:   "V1",
-		Customer: "C1",
-		Rate:     50,
-		Hours:    20,
+		Vendor:   strPtr("V1"),
+		Customer: strPtr("C1"),
+		Rate:     floatPtr(50),
+		Hours:    floatPtr(20),
 		PDF:      boolPtr(true),
-		Model:    "anthropic/claude-haiku-4-5",
+		Model:    strPtr("anthropic/claude-haiku-4-5"),
 	}
 	if err := config.Save(path, initial); err != nil {
 		t.Fatalf("initial Save: %v", err)
@@ -192,22 +218,554 @@ func TestSave_OnlyUpdatesSpecifiedFields(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Load: %v", err)
 	}
-	if cfg.Vendor != "V1" {
-		t.Errorf("Vendor should be unchanged: got %q", cfg.Vendor)
+	if cfg.Vendor == nil || *cfg.Vendor != "V1" {
+		t.Errorf("Vendor should be unchanged: got %q", str(cfg.Vendor))
 	}
-	if cfg.Customer != "C1" {
-		t.Errorf("Customer should be unchanged: got %q", cfg.Customer)
+	if cfg.Customer == nil || *cfg.Customer != "C1" {
+		t.Errorf("Customer should be unchanged: got %q", str(cfg.Customer))
 	}
-	if cfg.Rate != 50 {
-		t.Errorf("Rate should be unchanged: got %v", cfg.Rate)
+	if cfg.Rate == nil || *cfg.Rate != 50 {
+		t.Errorf("Rate should be unchanged: got %v", num(cfg.Rate))
 	}
-	if cfg.Hours != 20 {
-		t.Errorf("Hours should be unchanged: got %v", cfg.Hours)
+	if cfg.Hours == nil || *cfg.Hours != 20 {
+		t.Errorf("Hours should be unchanged: got %v", num(cfg.Hours))
 	}
 	if cfg.PDF == nil || *cfg.PDF != false {
 		t.Errorf("PDF should be updated to false: got %v", cfg.PDF)
 	}
-	if cfg.Model != "anthropic/claude-haiku-4-5" {
-		t.Errorf("Model should be unchanged: got %q", cfg.Model)
+	if cfg.Model == nil || *cfg.Model != "anthropic/claude-haiku-4-5" {
+		t.Errorf("Model should be unchanged: got %q", str(cfg.Model))
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"modle", "model"},
+		{"vendr", "vendor"},
+		{"post_generate_hok", "post_generate_hook"},
+		{"hookstrict", "hook_strict"},
+		{"Rate", "rate"},
+		{"stict", "strict"},
+		{"xyzzy", ""},
+		{"customer_email", ""},
+	}
+	for _, tt := range tests {
+		if got := config.Suggest(tt.key); got != tt.want {
+			t.Errorf("Suggest(%q) = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestSave_LeavesNoTempFiles(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	if err := config.Save(path, &config.Config{Vendor: strPtr("V")}); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("expected only config.yaml in %s, found %d entries", dir, len(entries))
+	}
+}
+
+func TestSave_ExplicitZeroRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := config.Save(path, &config.Config{Rate: floatPtr(150), Vendor: strPtr("V")}); err != nil {
+		t.Fatalf("initial Save: %v", err)
+	}
+	if err := config.Save(path, &config.Config{Rate: floatPtr(0), Vendor: strPtr("")}); err != nil {
+		t.Fatalf("update Save: %v", err)
+	}
+
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Rate == nil || *cfg.Rate != 0 {
+		t.Errorf("Rate: got %s, want explicit 0", num(cfg.Rate))
+	}
+	if cfg.Vendor == nil || *cfg.Vendor != "" {
+		t.Errorf("Vendor: got %s, want explicit empty string", str(cfg.Vendor))
+	}
+	if cfg.Hours != nil {
+		t.Errorf("Hours: got %s, want unset", num(cfg.Hours))
+	}
+}
+
+func TestSave_OmitsUnsetFields(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := config.Save(path, &config.Config{Rate: floatPtr(0)}); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(data); got != "rate: 0\n" {
+		t.Errorf("config file = %q, want only the explicit rate", got)
+	}
+}
+
+func TestSave_ConcurrentWritersDoNotLoseUpdates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+
+	// Each goroutine writes a different key, so every update must survive.
+	const writers = 8
+	var wg sync.WaitGroup
+	errs := make(chan error, writers)
+	for i := range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var updates config.Config
+			switch i % 4 {
+			case 0:
+				updates.Vendor = strPtr(fmt.Sprintf("Vendor %d", i))
+			case 1:
+				updates.Customer = strPtr(fmt.Sprintf("Customer %d", i))
+			case 2:
+				updates.Rate = floatPtr(float64(100 + i))
+			case 3:
+				updates.Hours = floatPtr(float64(i))
+			}
+			errs <- config.Save(path, &updates)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+	}
+
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("Load after concurrent saves: %v", err)
+	}
+	if cfg.Vendor == nil || cfg.Customer == nil || cfg.Rate == nil || cfg.Hours == nil {
+		t.Errorf("lost update: got vendor=%s customer=%s rate=%s hours=%s",
+			str(cfg.Vendor), str(cfg.Customer), num(cfg.Rate), num(cfg.Hours))
+	}
+	if _, err := os.Stat(path + ".lock"); !os.IsNotExist(err) {
+		t.Errorf("lock file should be removed after saving, stat err = %v", err)
+	}
+}
+
+func TestSave_ConcurrentWritersLeaveCoherentState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+
+	// Each goroutine writes a matching vendor and rate pair; the final file
+	// must hold a pair written by the same goroutine.
+	const writers = 8
+	var wg sync.WaitGroup
+	for i := range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			updates := config.Config{
+				Vendor: strPtr(fmt.Sprintf("Vendor %d", i)),
+				Rate:   floatPtr(float64(100 + i)),
+			}
+			if err := config.Save(path, &updates); err != nil {
+				t.Errorf("Save: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("final config is not valid YAML: %v", err)
+	}
+	var i int
+	if _, err := fmt.Sscanf(str(cfg.Vendor), "Vendor %d", &i); err != nil {
+		t.Fatalf("unexpected vendor %q", str(cfg.Vendor))
+	}
+	if cfg.Rate == nil || *cfg.Rate != float64(100+i) {
+		t.Errorf("incoherent state: vendor %q with rate %s", str(cfg.Vendor), num(cfg.Rate))
+	}
+}
+
+func TestMerge(t *testing.T) {
+	base := &config.Config{Vendor: strPtr("Global"), Rate: floatPtr(100), Hours: floatPtr(40)}
+	over := &config.Config{Rate: floatPtr(150), Customer: strPtr("Local Customer")}
+
+	got := config.Merge(base, over)
+	if str(got.Vendor) != "Global" || str(got.Customer) != "Local Customer" || *got.Rate != 150 || *got.Hours != 40 {
+		t.Errorf("unexpected merge result: vendor=%s customer=%s rate=%s hours=%s",
+			str(got.Vendor), str(got.Customer), num(got.Rate), num(got.Hours))
+	}
+	if *base.Rate != 100 {
+		t.Error("Merge must not modify base")
+	}
+	if got := config.Merge(base, nil); *got.Rate != 100 {
+		t.Errorf("Merge with nil over: rate = %s, want 100", num(got.Rate))
+	}
+}
+
+// mkdirs creates the directories under root and returns the last one.
+func mkdirs(t *testing.T, root string, elems ...string) string {
+	t.Helper()
+	dir := filepath.Join(append([]string{root}, elems...)...)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestFindLocal_WalksUp(t *testing.T) {
+	home := t.TempDir()
+	client := mkdirs(t, home, "clients", "acme")
+	work := mkdirs(t, client, "2025", "jan")
+	local := filepath.Join(client, config.LocalFile)
+	if err := os.WriteFile(local, []byte("customer: Acme\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := config.FindLocal(work, home); got != local {
+		t.Errorf("FindLocal from subdirectory = %q, want %q", got, local)
+	}
+	if got := config.FindLocal(client, home); got != local {
+		t.Errorf("FindLocal from same directory = %q, want %q", got, local)
+	}
+}
+
+func TestFindLocal_StopsAtHome(t *testing.T) {
+	root := t.TempDir()
+	home := mkdirs(t, root, "home")
+	work := mkdirs(t, home, "clients", "acme")
+	for _, dir := range []string{root, home} {
+		if err := os.WriteFile(filepath.Join(dir, config.LocalFile), []byte("customer: Wrong\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := config.FindLocal(work, home); got != "" {
+		t.Errorf("FindLocal should not search $HOME or above, got %q", got)
+	}
+}
+
+func TestFindLocal_StopsAtRepoRoot(t *testing.T) {
+	home := t.TempDir()
+	repo := mkdirs(t, home, "repo")
+	mkdirs(t, repo, ".git")
+	work := mkdirs(t, repo, "invoices")
+	if err := os.WriteFile(filepath.Join(home, config.LocalFile), []byte("customer: Wrong\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := config.FindLocal(work, ""); got != "" {
+		t.Errorf("FindLocal should stop at the repository root, got %q", got)
+	}
+}
+
+// captureWarnings redirects config.Warnings for the duration of the test.
+func captureWarnings(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	old := config.Warnings
+	config.Warnings = &buf
+	t.Cleanup(func() { config.Warnings = old })
+	return &buf
+}
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad_UnknownKeyWarns(t *testing.T) {
+	warnings := captureWarnings(t)
+	path := writeConfig(t, "vendor: Jane\nmodle: anthropic/claude-sonnet-4-6\n")
+
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if str(cfg.Vendor) != "Jane" || cfg.Model != nil {
+		t.Errorf("unexpected config: vendor=%s model=%s", str(cfg.Vendor), str(cfg.Model))
+	}
+	got := warnings.String()
+	for _, want := range []string{"Warning:", path + ":2:", `"modle"`, `did you mean "model"`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("warning %q does not contain %q", got, want)
+		}
+	}
+}
+
+func TestLoad_StrictInFileRejectsUnknownKeys(t *testing.T) {
+	warnings := captureWarnings(t)
+	path := writeConfig(t, "strict: true\nvendor: Jane\nmodle: x/y\n")
+
+	_, err := config.Load(path)
+	var unknown *config.UnknownKeyError
+	if !errors.As(err, &unknown) {
+		t.Fatalf("expected UnknownKeyError, got %v", err)
+	}
+	if unknown.Key != "modle" || unknown.Line != 3 || unknown.Path != path {
+		t.Errorf("unexpected error details: %+v", unknown)
+	}
+	if !strings.Contains(err.Error(), `did you mean "model"`) {
+		t.Errorf("expected suggestion in error, got %v", err)
+	}
+	if warnings.Len() != 0 {
+		t.Errorf("strict mode should not warn, got %q", warnings.String())
+	}
+}
+
+func TestLoadStrict_ReportsEveryUnknownKey(t *testing.T) {
+	captureWarnings(t)
+	path := writeConfig(t, "vendr: Jane\nrate: 100\nxyzzy: 1\n")
+
+	_, err := config.LoadStrict(path)
+	if err == nil {
+		t.Fatal("expected error for unknown keys, got nil")
+	}
+	for _, want := range []string{path + ":1:", `did you mean "vendor"`, path + ":3:", `"xyzzy" (valid keys:`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not contain %q", err, want)
+		}
+	}
+}
+
+func TestLoadStrict_AcceptsKnownKeys(t *testing.T) {
+	path := writeConfig(t, "vendor: Jane\nrate: 100\n")
+	if _, err := config.LoadStrict(path); err != nil {
+		t.Errorf("LoadStrict: %v", err)
+	}
+}
+
+func TestDuration_RoundTrip(t *testing.T) {
+	path := writeConfig(t, "timeout: 90s\n")
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Timeout == nil || *cfg.Timeout != config.Duration(90*time.Second) {
+		t.Fatalf("Timeout = %v, want 1m30s", cfg.Timeout)
+	}
+
+	d := config.Duration(2 * time.Minute)
+	if err := config.Save(path, &config.Config{Timeout: &d}); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "timeout: 2m0s") {
+		t.Errorf("expected the timeout saved as a duration string, got:\n%s", data)
+	}
+}
+
+func TestDuration_Invalid(t *testing.T) {
+	path := writeConfig(t, "timeout: soon\n")
+	if _, err := config.Load(path); err == nil || !strings.Contains(err.Error(), `invalid duration "soon"`) {
+		t.Errorf("expected an invalid duration error, got %v", err)
+	}
+}
+
+func TestHolidays_RoundTrip(t *testing.T) {
+	path := writeConfig(t, "holidays:\n  country: DE-BY\n")
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if got := cfg.HolidayCountry(); got != "DE-BY" {
+		t.Fatalf("HolidayCountry() = %q, want DE-BY", got)
+	}
+
+	us := "US"
+	if err := config.Save(path, &config.Config{Holidays: &config.Holidays{Country: &us}}); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "holidays:\n    country: US\n") {
+		t.Errorf("expected the country saved in a holidays: block, got:\n%s", data)
+	}
+
+	if got := (&config.Config{}).HolidayCountry(); got != "" {
+		t.Errorf("HolidayCountry() of an empty config = %q, want none", got)
+	}
+}
+
+func TestHolidays_NotABlock(t *testing.T) {
+	path := writeConfig(t, "holidays: US\n")
+	if _, err := config.Load(path); err == nil || !strings.Contains(err.Error(), "holidays must be a block such as country: US") {
+		t.Errorf("expected an error asking for a holidays: block, got %v", err)
+	}
+}
+
+func TestValidate_Timeout(t *testing.T) {
+	zero := config.Duration(0)
+	if err := (&config.Config{Timeout: &zero}).Validate(); err == nil || !strings.Contains(err.Error(), "timeout must be positive") {
+		t.Errorf("expected a timeout error, got %v", err)
+	}
+	ok := config.Duration(time.Second)
+	if err := (&config.Config{Timeout: &ok}).Validate(); err != nil {
+		t.Errorf("Validate: %v", err)
+	}
+}
+
+func TestValidate_Retries(t *testing.T) {
+	for _, n := range []int{-1, config.MaxRetries + 1} {
+		if err := (&config.Config{Retries: &n}).Validate(); err == nil || !strings.Contains(err.Error(), "retries must be between") {
+			t.Errorf("Validate(retries: %d) = %v, want a range error", n, err)
+		}
+	}
+	ok := 3
+	if err := (&config.Config{Retries: &ok}).Validate(); err != nil {
+		t.Errorf("Validate(retries: 3) = %v", err)
+	}
+}
+
+func TestValidate_MaxCost(t *testing.T) {
+	for _, c := range []float64{0, -0.5} {
+		if err := (&config.Config{MaxCost: &c}).Validate(); err == nil || !strings.Contains(err.Error(), "max_cost must be positive") {
+			t.Errorf("Validate(max_cost: %v) = %v, want an error", c, err)
+		}
+	}
+	ok := 0.5
+	if err := (&config.Config{MaxCost: &ok}).Validate(); err != nil {
+		t.Errorf("Validate(max_cost: 0.5) = %v", err)
+	}
+}
+
+func TestValidate_Vendors(t *testing.T) {
+	tests := []struct {
+		name    string
+		vendors []config.Vendor
+		wantErr string
+	}{
+		{"valid", []config.Vendor{{Name: "Jane Doe", Rate: floatPtr(150)}, {Name: "John Roe"}}, ""},
+		{"no name", []config.Vendor{{Rate: floatPtr(150)}}, "vendors[0] has no name"},
+		{"listed twice", []config.Vendor{{Name: "Jane Doe"}, {Name: "jane doe"}}, `vendors: "jane doe" is listed twice`},
+		{"bad rate", []config.Vendor{{Name: "Jane Doe", Rate: floatPtr(0)}}, `vendor "Jane Doe": rate must be positive`},
+		{"bad hours", []config.Vendor{{Name: "Jane Doe", Hours: floatPtr(200)}}, `vendor "Jane Doe": hours must be between 0 and 168`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := (&config.Config{Vendors: tt.vendors}).Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFindVendor(t *testing.T) {
+	cfg := &config.Config{Vendors: []config.Vendor{{Name: "Jane Doe"}, {Name: "John Roe"}}}
+	if v := cfg.FindVendor("john roe"); v == nil || v.Name != "John Roe" {
+		t.Errorf("FindVendor(john roe) = %v, want John Roe", v)
+	}
+	if v := cfg.FindVendor("Agency Ltd"); v != nil {
+		t.Errorf("FindVendor(Agency Ltd) = %v, want nil", v)
+	}
+}
+
+func TestValidate_Precision(t *testing.T) {
+	for _, n := range []int{-1, 5} {
+		if err := (&config.Config{RatePrecision: &n}).Validate(); err == nil || !strings.Contains(err.Error(), "rate_precision must be between 0 and 4") {
+			t.Errorf("Validate(rate_precision: %d) = %v, want a range error", n, err)
+		}
+		if err := (&config.Config{HoursPrecision: &n}).Validate(); err == nil || !strings.Contains(err.Error(), "hours_precision must be between 0 and 4") {
+			t.Errorf("Validate(hours_precision: %d) = %v, want a range error", n, err)
+		}
+	}
+	zero := 0
+	if err := (&config.Config{RatePrecision: &zero, HoursPrecision: &zero}).Validate(); err != nil {
+		t.Errorf("Validate(precision 0) = %v", err)
+	}
+}
+
+func TestValidate_DecimalSeparator(t *testing.T) {
+	for _, sep := range []string{".", ","} {
+		if err := (&config.Config{DecimalSeparator: &sep}).Validate(); err != nil {
+			t.Errorf("Validate(decimal_separator: %q) = %v", sep, err)
+		}
+	}
+	for _, sep := range []string{"'", ",.", "dot"} {
+		if err := (&config.Config{DecimalSeparator: &sep}).Validate(); err == nil || !strings.Contains(err.Error(), `decimal_separator: decimal separator must be "." or ","`) {
+			t.Errorf("Validate(decimal_separator: %q) = %v, want an error", sep, err)
+		}
+	}
+}
+
+func TestValidate_ModelList(t *testing.T) {
+	ok := "anthropic/claude-haiku-4-5,anthropic/claude-sonnet-4-6"
+	if err := (&config.Config{Model: &ok}).Validate(); err != nil {
+		t.Errorf("Validate(%q) = %v", ok, err)
+	}
+	bad := "anthropic/claude-haiku-4-5,sonnet"
+	if err := (&config.Config{Model: &bad}).Validate(); err == nil || !strings.Contains(err.Error(), `got "sonnet"`) {
+		t.Errorf("Validate(%q) = %v, want an error naming the bad model", bad, err)
+	}
+}
+
+func TestKnownProvider(t *testing.T) {
+	if !config.KnownProvider("anthropic/claude-haiku-4-5") {
+		t.Error("anthropic should be a known provider")
+	}
+	if config.KnownProvider("acme/llm-1") {
+		t.Error("acme should not be a known provider")
+	}
+}
+
+func TestProfiles(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	root := filepath.Join(home, ".invoicer")
+	for _, path := range []string{
+		filepath.Join(root, "config.yaml"),
+		filepath.Join(root, "profiles", "sideco", "config.yaml"),
+		filepath.Join(root, "profiles", "acme", "config.yaml"),
+		filepath.Join(root, "profiles", "empty", "notes.txt"),
+	} {
+		if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	names, err := config.Profiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(names, []string{"acme", "sideco"}) {
+		t.Errorf("Profiles = %q", names)
+	}
+	path, err := config.LookupProfile("sideco")
+	if err != nil || path != filepath.Join(root, "profiles", "sideco", "config.yaml") {
+		t.Errorf("LookupProfile = %q, %v", path, err)
+	}
+	if _, err := config.LookupProfile("empty"); err == nil || !strings.Contains(err.Error(), "(available: acme, sideco)") {
+		t.Errorf("expected an unknown-profile error listing the profiles, got %v", err)
+	}
+	for _, name := range []string{"", "../x", "a/b", ".hidden"} {
+		if _, err := config.ProfilePath(name); err == nil {
+			t.Errorf("ProfilePath(%q): expected an error", name)
+		}
 	}
 }
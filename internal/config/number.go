@@ -0,0 +1,261 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DecimalSeparatorEnv is the environment variable that sets the decimal
+// separator of numbers read outside a config file, such as --rate, which
+// is read before any config file is.
+const DecimalSeparatorEnv = "INVOICER_DECIMAL_SEPARATOR"
+
+// ParseNumber reads s, a rate or a number of hours written as it would be
+// in a contract, with the decimal separator set by DecimalSeparatorEnv,
+// if any. See ParseNumberAs.
+func ParseNumber(s string) (float64, error) {
+	decimal := os.Getenv(DecimalSeparatorEnv)
+	if err := ValidateDecimalSeparator(decimal); err != nil {
+		return 0, fmt.Errorf("%s: %w", DecimalSeparatorEnv, err)
+	}
+	return ParseNumberAs(s, decimal)
+}
+
+// ValidateDecimalSeparator returns an error unless decimal is "." or ","
+// or empty, for none.
+func ValidateDecimalSeparator(decimal string) error {
+	if decimal != "" && decimal != "." && decimal != "," {
+		return fmt.Errorf(`decimal separator must be "." or ",", got %q`, decimal)
+	}
+	return nil
+}
+
+// ParseNumberAs reads s, a rate or a number of hours written as it would
+// be in a contract: a plain number such as "150" or "37.5", or one with a
+// currency symbol or ISO code before or after it and grouped thousands,
+// such as "$150", "USD 1,250.00", "1.250,00 €" or "37,5".
+//
+// decimal is the decimal separator, "." or ",", the other one grouping
+// thousands; a number using them the other way round is an error. If
+// decimal is empty, the last of a dot and a comma is the decimal
+// separator, and a lone separator followed by exactly three digits, as in
+// "1.250", "1,250" or "€1.250", could group thousands or mark decimals,
+// and is an error rather than a guess; any other lone separator is a
+// decimal one.
+//
+// Infinities, NaN and hexadecimal numbers are errors.
+func ParseNumberAs(s, decimal string) (float64, error) {
+	t := strings.TrimSpace(s)
+	num, ok := stripCurrency(t)
+	if ok && decimal == "" {
+		if err := ambiguousSeparator(s, num); err != nil {
+			return 0, err
+		}
+	}
+	if decimal != "," && isPlainNumber(t) {
+		if f, err := strconv.ParseFloat(t, 64); err == nil {
+			return finite(s, f)
+		}
+	}
+	if !ok || num == "" || strings.Trim(num, "0123456789.,") != "" {
+		return 0, fmt.Errorf("%q is not a number", s)
+	}
+
+	// Find the decimal separator, if any, and the separator grouping the
+	// thousands before it.
+	var dec, group string
+	dots, commas := strings.Count(num, "."), strings.Count(num, ",")
+	switch {
+	case dots > 0 && commas > 0:
+		dec, group = ",", "."
+		if strings.LastIndex(num, ".") > strings.LastIndex(num, ",") {
+			dec, group = ".", ","
+		}
+		if strings.Count(num, dec) > 1 {
+			return 0, fmt.Errorf("%q has more than one decimal separator", s)
+		}
+	case dots > 1:
+		group = "."
+	case commas > 1:
+		group = ","
+	case dots == 1:
+		dec = "."
+	case commas == 1:
+		dec = ","
+	}
+	if decimal != "" {
+		switch {
+		case dec != "" && dec != decimal && group == "":
+			// A lone separator that is not the decimal one groups
+			// thousands.
+			dec, group = "", dec
+		case dec != "" && dec != decimal, group == decimal:
+			return 0, fmt.Errorf("%q does not use %q as its decimal separator", s, decimal)
+		}
+	}
+
+	whole, frac := num, ""
+	if dec != "" {
+		whole, frac, _ = strings.Cut(num, dec)
+		if frac == "" {
+			return 0, fmt.Errorf("%q has no digits after its decimal separator", s)
+		}
+	}
+	if group != "" {
+		groups := strings.Split(whole, group)
+		for i, g := range groups {
+			if (i == 0 && (len(g) == 0 || len(g) > 3)) || (i > 0 && len(g) != 3) {
+				return 0, fmt.Errorf("%q groups its digits unevenly; thousands are grouped in threes", s)
+			}
+		}
+		whole = strings.Join(groups, "")
+	}
+	if whole == "" {
+		whole = "0"
+	}
+	plain := whole
+	if frac != "" {
+		plain += "." + frac
+	}
+	f, err := strconv.ParseFloat(plain, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a number", s)
+	}
+	return finite(s, f)
+}
+
+// isPlainNumber reports whether s is made of decimal digits, a sign, a dot
+// and an exponent only, so strconv.ParseFloat does not read it as an
+// infinity, NaN or a hexadecimal number.
+func isPlainNumber(s string) bool {
+	return s != "" && strings.Trim(s, "0123456789+-.eE") == ""
+}
+
+// finite returns f, read from s, or an error if it is infinite or NaN.
+func finite(s string, f float64) (float64, error) {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return 0, fmt.Errorf("%q is not a finite number", s)
+	}
+	return f, nil
+}
+
+// ambiguousSeparator returns an error if num, s without its currency, has
+// a single separator followed by exactly three digits after one to three
+// others, as in "1,250", which could group thousands or mark decimals.
+// The error offers both readings written so they are not ambiguous, e.g.
+// 1250 and 1.25.
+func ambiguousSeparator(s, num string) error {
+	if strings.Count(num, ".")+strings.Count(num, ",") != 1 {
+		return nil
+	}
+	i := strings.IndexAny(num, ".,")
+	whole, frac := num[:i], num[i+1:]
+	if len(frac) == 3 && len(whole) >= 1 && len(whole) <= 3 && whole[0] != '0' {
+		decimal := whole
+		if f := strings.TrimRight(frac, "0"); f != "" {
+			decimal += "." + f
+		}
+		return fmt.Errorf("%q could be %s%s or %s; write it as one of those", s, whole, frac, decimal)
+	}
+	return nil
+}
+
+// stripCurrency returns s without a currency symbol, such as "$" or "€",
+// or a three-letter ISO 4217 code, such as "USD", at its start or its end.
+// It reports false if s has more than one.
+func stripCurrency(s string) (string, bool) {
+	isMark := func(r rune) bool { return unicode.Is(unicode.Sc, r) || unicode.IsLetter(r) }
+	start := strings.IndexFunc(s, func(r rune) bool { return !isMark(r) })
+	if start < 0 {
+		return "", false
+	}
+	end := strings.LastIndexFunc(s, func(r rune) bool { return !isMark(r) }) + 1
+	prefix, suffix := s[:start], s[end:]
+	if prefix != "" && suffix != "" {
+		return "", false
+	}
+	if !isCurrency(prefix + suffix) {
+		return "", false
+	}
+	return strings.TrimSpace(s[start:end]), true
+}
+
+// isCurrency reports whether s is empty, a single currency symbol or a
+// three-letter currency code.
+func isCurrency(s string) bool {
+	if r := []rune(s); len(r) == 1 {
+		return unicode.Is(unicode.Sc, r[0])
+	}
+	if len(s) == 0 {
+		return true
+	}
+	if len(s) != 3 {
+		return false
+	}
+	for _, r := range s {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// numberKeys are the keys of a config file, and of each of its vendors,
+// whose values are read with ParseNumber.
+var numberKeys = []string{"rate", "hours"}
+
+// parseNumbers rewrites the string values of doc's numberKeys, such as
+// rate: "$150", as the numbers ParseNumberAs reads them as, so they decode
+// into float fields. Numbers written as YAML numbers are left as they are.
+// The decimal separator is doc's decimal_separator, or DecimalSeparatorEnv
+// if that is not set.
+func parseNumbers(doc *yaml.Node) error {
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil
+	}
+	decimal, source := os.Getenv(DecimalSeparatorEnv), DecimalSeparatorEnv
+	if v := mappingValue(doc.Content[0], "decimal_separator"); v != nil && v.Kind == yaml.ScalarNode {
+		decimal, source = v.Value, fmt.Sprintf("line %d: decimal_separator", v.Line)
+	}
+	if err := ValidateDecimalSeparator(decimal); err != nil {
+		return fmt.Errorf("%s: %w", source, err)
+	}
+	maps := []*yaml.Node{doc.Content[0]}
+	if vendors := mappingValue(doc.Content[0], "vendors"); vendors != nil && vendors.Kind == yaml.SequenceNode {
+		for _, v := range vendors.Content {
+			if v.Kind == yaml.MappingNode {
+				maps = append(maps, v)
+			}
+		}
+	}
+	for _, m := range maps {
+		for _, key := range numberKeys {
+			v := mappingValue(m, key)
+			if v == nil || v.Kind != yaml.ScalarNode || v.ShortTag() != "!!str" {
+				continue
+			}
+			f, err := ParseNumberAs(v.Value, decimal)
+			if err != nil {
+				return fmt.Errorf("line %d: %s: %w", v.Line, key, err)
+			}
+			v.Value, v.Tag, v.Style = strconv.FormatFloat(f, 'f', -1, 64), "!!float", 0
+		}
+	}
+	return nil
+}
+
+// mappingValue returns the value of key in the mapping node m, or nil.
+func mappingValue(m *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i+1]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,220 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zon/invoicer/internal/config"
+)
+
+func TestParseNumber(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    float64
+		wantErr string
+	}{
+		// Plain numbers.
+		{"150", 150, ""},
+		{"37.5", 37.5, ""},
+		{"1.25", 1.25, ""},
+		{"0.125", 0.125, ""},
+		{".5", 0.5, ""},
+
+		// Currency symbols and codes, before or after.
+		{"$150", 150, ""},
+		{"$ 150", 150, ""},
+		{"€150", 150, ""},
+		{"150 €", 150, ""},
+		{"£95.50", 95.5, ""},
+		{"¥12,000", 0, "could be 12000 or 12"},
+		{"USD 150", 150, ""},
+		{"USD150", 150, ""},
+		{"150 EUR", 150, ""},
+		{"CHF 1'250", 0, "is not a number"},
+		{"US$150", 0, "is not a number"},
+		{"$150 USD", 0, "is not a number"},
+		{"usd 150", 0, "is not a number"},
+		{"$", 0, "is not a number"},
+
+		// Separators.
+		{"1,250.00", 1250, ""},
+		{"$1,250.00", 1250, ""},
+		{"1.250,00", 1250, ""},
+		{"1.250,00 €", 1250, ""},
+		{"1,234,567.89", 1234567.89, ""},
+		{"1.234.567,89", 1234567.89, ""},
+		{"1,250,000", 1250000, ""},
+		{"1.250.000", 1250000, ""},
+		{"37,5", 37.5, ""},
+		{"€87,50", 87.5, ""},
+		{"1250,00", 1250, ""},
+		{"0,125", 0.125, ""},
+		{"1250,000", 1250, ""},
+
+		// Ambiguous and malformed.
+		{"1.250", 0, `"1.250" could be 1250 or 1.25; write it as one of those`},
+		{" 1.250 ", 0, "could be 1250 or 1.25"},
+		{"1,250", 0, `"1,250" could be 1250 or 1.25; write it as one of those`},
+		{"$1.250", 0, "could be 1250 or 1.25"},
+		{"€1.250", 0, `"€1.250" could be 1250 or 1.25; write it as one of those`},
+		{"€150,000", 0, "could be 150000 or 150"},
+		{"1,25,000.00", 0, "groups its digits unevenly"},
+		{"12,50.00", 0, "groups its digits unevenly"},
+		{"1.250.00,5", 0, "groups its digits unevenly"},
+		{"1,250.000.5", 0, "more than one decimal separator"},
+		{"150,", 0, "no digits after its decimal separator"},
+		{"", 0, "is not a number"},
+		{"abc", 0, "is not a number"},
+		{"150/hr", 0, "is not a number"},
+
+		// Numbers strconv.ParseFloat reads but a rate or hours cannot be.
+		{"Inf", 0, "is not a number"},
+		{"-inf", 0, "is not a number"},
+		{"+Infinity", 0, "is not a number"},
+		{"NaN", 0, "is not a number"},
+		{"0x1p4", 0, "is not a number"},
+		{"0x10", 0, "is not a number"},
+		{"1e999", 0, "is not a number"},
+		{"1e3", 1000, ""},
+	}
+	for _, tt := range tests {
+		got, err := config.ParseNumber(tt.in)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ParseNumber(%q) = %v, %v; want error %q", tt.in, got, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("ParseNumber(%q) = %v, %v; want %v", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestParseNumberAs(t *testing.T) {
+	tests := []struct {
+		in, decimal string
+		want        float64
+		wantErr     string
+	}{
+		{"1.250", ".", 1.25, ""},
+		{"1.250", ",", 1250, ""},
+		{"1,250", ".", 1250, ""},
+		{"1,250", ",", 1.25, ""},
+		{"€1.250", ",", 1250, ""},
+		{"$1,250", ".", 1250, ""},
+		{"150", ",", 150, ""},
+		{"37,5", ",", 37.5, ""},
+		{"1.250,00 €", ",", 1250, ""},
+		{"1,250.00", ".", 1250, ""},
+		{"1.234.567", ",", 1234567, ""},
+		{"1,234,567", ".", 1234567, ""},
+		{"37.5", ",", 0, "groups its digits unevenly"},
+		{"1,250.00", ",", 0, `"1,250.00" does not use "," as its decimal separator`},
+		{"1.250,00", ".", 0, `does not use "." as its decimal separator`},
+		{"1.234.567", ".", 0, `does not use "." as its decimal separator`},
+		{"1,234,567", ",", 0, `does not use "," as its decimal separator`},
+		{"Inf", ",", 0, "is not a number"},
+		{"NaN", ".", 0, "is not a number"},
+	}
+	for _, tt := range tests {
+		got, err := config.ParseNumberAs(tt.in, tt.decimal)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ParseNumberAs(%q, %q) = %v, %v; want error %q", tt.in, tt.decimal, got, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("ParseNumberAs(%q, %q) = %v, %v; want %v", tt.in, tt.decimal, got, err, tt.want)
+		}
+	}
+}
+
+func TestParseNumber_DecimalSeparatorEnv(t *testing.T) {
+	t.Setenv(config.DecimalSeparatorEnv, ",")
+	if got, err := config.ParseNumber("1.250"); err != nil || got != 1250 {
+		t.Errorf(`ParseNumber("1.250") = %v, %v; want 1250`, got, err)
+	}
+	t.Setenv(config.DecimalSeparatorEnv, ";")
+	if _, err := config.ParseNumber("150"); err == nil || !strings.Contains(err.Error(), `INVOICER_DECIMAL_SEPARATOR: decimal separator must be "." or ","`) {
+		t.Errorf("ParseNumber = %v, want an error naming the bad separator", err)
+	}
+}
+
+func TestLoad_DecimalSeparator(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := `decimal_separator: ","
+rate: "1.250"
+hours: "37,5"
+vendors:
+  - name: Jane Doe
+    rate: €95,50
+`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if *cfg.Rate != 1250 || *cfg.Hours != 37.5 || *cfg.Vendors[0].Rate != 95.5 {
+		t.Errorf("got rate %v, hours %v, vendor rate %v; want 1250, 37.5, 95.5", *cfg.Rate, *cfg.Hours, *cfg.Vendors[0].Rate)
+	}
+	if cfg.DecimalSeparator == nil || *cfg.DecimalSeparator != "," {
+		t.Errorf("DecimalSeparator = %v, want \",\"", cfg.DecimalSeparator)
+	}
+
+	if err := os.WriteFile(path, []byte("decimal_separator: \"'\"\nrate: 150\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := config.Load(path); err == nil || !strings.Contains(err.Error(), "line 1: decimal_separator: decimal separator must be") {
+		t.Errorf("Load = %v, want an error naming the bad separator", err)
+	}
+}
+
+func TestLoad_FormattedNumbers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := `rate: "1.250,00 €"
+hours: "37,5"
+vendors:
+  - name: Jane Doe
+    rate: $150
+  - name: John Roe
+    rate: 120
+`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	for _, tt := range []struct {
+		name string
+		got  *float64
+		want float64
+	}{
+		{"rate", cfg.Rate, 1250},
+		{"hours", cfg.Hours, 37.5},
+		{"Jane Doe's rate", cfg.Vendors[0].Rate, 150},
+		{"John Roe's rate", cfg.Vendors[1].Rate, 120},
+	} {
+		if tt.got == nil || *tt.got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestLoad_AmbiguousNumber(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("vendor: Jane Doe\nrate: 1,250\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	_, err := config.Load(path)
+	if err == nil || !strings.Contains(err.Error(), `line 2: rate: "1,250" could be 1250 or 1.25`) {
+		t.Errorf("Load = %v, want an error naming the ambiguous rate", err)
+	}
+}
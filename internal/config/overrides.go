@@ -0,0 +1,218 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// OverridesFile is the name of the file in the current directory holding
+// overrides for the next invoice generated there.
+const OverridesFile = "invoice.yaml"
+
+// Overrides are one-off changes to the next invoice, read from an
+// OverridesFile. Every key is optional:
+//
+//	weeks:            # hours of single weeks, by any date in the week
+//	  2025-01-06: 20
+//	exclude:          # weeks left out, by any date in the week
+//	  - 2025-01-27
+//	notes: Thanks for your business.
+//	po_number: PO-1234
+//	adjustments:      # flat amounts; negative for a credit
+//	  - description: Travel expenses
+//	    amount: 120.50
+type Overrides struct {
+	Weeks       []WeekOverride
+	Exclude     []DateOverride
+	Notes       *string
+	PONumber    *string
+	Adjustments []AdjustmentOverride
+}
+
+// WeekOverride sets the hours of the week containing Date.
+type WeekOverride struct {
+	Date  time.Time
+	Hours float64
+	// Line is where the override is in the file.
+	Line int
+}
+
+// DateOverride is a date in the overrides file, such as a week to exclude.
+type DateOverride struct {
+	Date time.Time
+	// Line is where the date is in the file.
+	Line int
+}
+
+// AdjustmentOverride is a flat amount added to the invoice.
+type AdjustmentOverride struct {
+	Description string
+	Amount      float64
+}
+
+// overridesKeys are the keys of an overrides file, in documented order.
+var overridesKeys = []string{"weeks", "exclude", "notes", "po_number", "adjustments"}
+
+// OverridesError reports a problem at a line of an overrides file.
+type OverridesError struct {
+	Path string
+	Line int
+	Msg  string
+}
+
+func (e *OverridesError) Error() string {
+	return fmt.Sprintf("%s:%d: %s", e.Path, e.Line, e.Msg)
+}
+
+// LoadOverrides reads and checks the overrides file at path. It returns
+// an OverridesError for every problem found, joined into a single error.
+func LoadOverrides(path string) (*Overrides, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading overrides file %q: %w", path, err)
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parsing overrides file %q: %w", path, err)
+	}
+
+	o := &Overrides{}
+	if len(doc.Content) == 0 {
+		return o, nil
+	}
+	var errs []error
+	fail := func(n *yaml.Node, format string, args ...any) {
+		errs = append(errs, &OverridesError{Path: path, Line: n.Line, Msg: fmt.Sprintf(format, args...)})
+	}
+	m := doc.Content[0]
+	if m.Kind != yaml.MappingNode {
+		fail(m, "the file must be a mapping of %s", strings.Join(overridesKeys, ", "))
+		return nil, errors.Join(errs...)
+	}
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		k, v := m.Content[i], m.Content[i+1]
+		switch k.Value {
+		case "weeks":
+			if v.Kind != yaml.MappingNode {
+				fail(v, "weeks must map dates to hours, e.g. 2025-01-06: 20")
+				continue
+			}
+			for j := 0; j+1 < len(v.Content); j += 2 {
+				dk, dv := v.Content[j], v.Content[j+1]
+				date, err := parseOverrideDate(dk)
+				if err != nil {
+					fail(dk, "weeks: %v", err)
+					continue
+				}
+				var hours float64
+				if dv.Kind != yaml.ScalarNode || dv.Decode(&hours) != nil {
+					fail(dv, "weeks: hours of %s must be a number", dk.Value)
+					continue
+				}
+				if hours < 0 || hours > maxHoursPerWeek || math.IsNaN(hours) {
+					fail(dv, "weeks: hours of %s must be between 0 and %d, got %v", dk.Value, maxHoursPerWeek, hours)
+					continue
+				}
+				o.Weeks = append(o.Weeks, WeekOverride{Date: date, Hours: hours, Line: dk.Line})
+			}
+		case "exclude":
+			if v.Kind != yaml.SequenceNode {
+				fail(v, "exclude must be a list of dates, e.g. [2025-01-27]")
+				continue
+			}
+			for _, d := range v.Content {
+				date, err := parseOverrideDate(d)
+				if err != nil {
+					fail(d, "exclude: %v", err)
+					continue
+				}
+				o.Exclude = append(o.Exclude, DateOverride{Date: date, Line: d.Line})
+			}
+		case "notes", "po_number":
+			if v.Kind != yaml.ScalarNode || v.Tag == "!!null" {
+				fail(v, "%s must be text", k.Value)
+				continue
+			}
+			s := v.Value
+			if k.Value == "notes" {
+				o.Notes = &s
+			} else {
+				o.PONumber = &s
+			}
+		case "adjustments":
+			if v.Kind != yaml.SequenceNode {
+				fail(v, "adjustments must be a list of descriptions and amounts")
+				continue
+			}
+			for _, a := range v.Content {
+				if adj, ok := parseAdjustment(a, fail); ok {
+					o.Adjustments = append(o.Adjustments, adj)
+				}
+			}
+		default:
+			fail(k, "unknown overrides key %q (valid keys: %s)", k.Value, strings.Join(overridesKeys, ", "))
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return o, nil
+}
+
+// parseOverrideDate parses a date written as YYYY-MM-DD.
+func parseOverrideDate(n *yaml.Node) (time.Time, error) {
+	date, err := time.Parse(time.DateOnly, n.Value)
+	if n.Kind != yaml.ScalarNode || err != nil {
+		return time.Time{}, fmt.Errorf("%q is not a date like 2025-01-06", n.Value)
+	}
+	return date, nil
+}
+
+// parseAdjustment parses one entry of adjustments, reporting its problems
+// to fail.
+func parseAdjustment(n *yaml.Node, fail func(*yaml.Node, string, ...any)) (AdjustmentOverride, bool) {
+	var adj AdjustmentOverride
+	if n.Kind != yaml.MappingNode {
+		fail(n, "adjustments: each adjustment must have a description and an amount")
+		return adj, false
+	}
+	ok := true
+	var hasDescription, hasAmount bool
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		k, v := n.Content[i], n.Content[i+1]
+		switch k.Value {
+		case "description":
+			hasDescription = true
+			if v.Kind != yaml.ScalarNode || strings.TrimSpace(v.Value) == "" {
+				fail(v, "adjustments: description must be text")
+				ok = false
+				continue
+			}
+			adj.Description = v.Value
+		case "amount":
+			hasAmount = true
+			if v.Kind != yaml.ScalarNode || v.Decode(&adj.Amount) != nil || adj.Amount == 0 || math.IsNaN(adj.Amount) || math.IsInf(adj.Amount, 0) {
+				fail(v, "adjustments: amount must be a number other than 0, got %q", v.Value)
+				ok = false
+			}
+		default:
+			fail(k, "adjustments: unknown key %q (valid keys: description, amount)", k.Value)
+			ok = false
+		}
+	}
+	if !hasDescription {
+		fail(n, "adjustments: the adjustment has no description")
+		ok = false
+	}
+	if !hasAmount {
+		fail(n, "adjustments: the adjustment has no amount")
+		ok = false
+	}
+	return adj, ok
+}
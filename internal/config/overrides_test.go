@@ -0,0 +1,114 @@
+package config_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zon/invoicer/internal/config"
+)
+
+func writeOverrides(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), config.OverridesFile)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadOverrides(t *testing.T) {
+	path := writeOverrides(t, `weeks:
+  2025-01-06: 20
+exclude:
+  - 2025-01-27
+notes: Thanks!
+po_number: PO-1234
+adjustments:
+  - description: Travel expenses
+    amount: 120.50
+  - description: Credit
+    amount: -50
+`)
+	o, err := config.LoadOverrides(path)
+	if err != nil {
+		t.Fatalf("LoadOverrides: %v", err)
+	}
+	if len(o.Weeks) != 1 || !o.Weeks[0].Date.Equal(time.Date(2025, time.January, 6, 0, 0, 0, 0, time.UTC)) || o.Weeks[0].Hours != 20 || o.Weeks[0].Line != 2 {
+		t.Errorf("Weeks = %+v", o.Weeks)
+	}
+	if len(o.Exclude) != 1 || o.Exclude[0].Date.Day() != 27 || o.Exclude[0].Line != 4 {
+		t.Errorf("Exclude = %+v", o.Exclude)
+	}
+	if str(o.Notes) != "Thanks!" || str(o.PONumber) != "PO-1234" {
+		t.Errorf("Notes = %s, PONumber = %s", str(o.Notes), str(o.PONumber))
+	}
+	want := []config.AdjustmentOverride{{Description: "Travel expenses", Amount: 120.5}, {Description: "Credit", Amount: -50}}
+	if len(o.Adjustments) != len(want) || o.Adjustments[0] != want[0] || o.Adjustments[1] != want[1] {
+		t.Errorf("Adjustments = %+v, want %+v", o.Adjustments, want)
+	}
+}
+
+func TestLoadOverrides_OnlyNotes(t *testing.T) {
+	o, err := config.LoadOverrides(writeOverrides(t, "notes: |\n  Paid in two parts.\n  Thanks!\n"))
+	if err != nil {
+		t.Fatalf("LoadOverrides: %v", err)
+	}
+	if str(o.Notes) != "Paid in two parts.\nThanks!\n" {
+		t.Errorf("Notes = %q", str(o.Notes))
+	}
+	if o.PONumber != nil || o.Weeks != nil || o.Exclude != nil || o.Adjustments != nil {
+		t.Errorf("only notes should be set, got %+v", o)
+	}
+}
+
+func TestLoadOverrides_Empty(t *testing.T) {
+	o, err := config.LoadOverrides(writeOverrides(t, ""))
+	if err != nil || o == nil {
+		t.Fatalf("LoadOverrides = %+v, %v; want empty overrides", o, err)
+	}
+}
+
+func TestLoadOverrides_SchemaErrors(t *testing.T) {
+	path := writeOverrides(t, `weeks:
+  2025-01-06: lots
+  next monday: 8
+exclude: 2025-01-27
+notes: [a, b]
+adjustments:
+  - description: Travel
+  - amount: 0
+    description: Credit
+colour: blue
+`)
+	_, err := config.LoadOverrides(path)
+	if err == nil {
+		t.Fatal("expected schema errors, got nil")
+	}
+	for _, want := range []string{
+		path + `:2: weeks: hours of 2025-01-06 must be a number`,
+		path + `:3: weeks: "next monday" is not a date`,
+		path + `:4: exclude must be a list of dates`,
+		path + `:5: notes must be text`,
+		path + `:7: adjustments: the adjustment has no amount`,
+		path + `:8: adjustments: amount must be a number other than 0`,
+		path + `:10: unknown overrides key "colour"`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in:\n%v", want, err)
+		}
+	}
+	var oe *config.OverridesError
+	if !errors.As(err, &oe) {
+		t.Errorf("expected an OverridesError, got %T", err)
+	}
+}
+
+func TestLoadOverrides_Missing(t *testing.T) {
+	if _, err := config.LoadOverrides(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("expected error for a missing file, got nil")
+	}
+}
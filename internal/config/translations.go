@@ -0,0 +1,57 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/zon/invoicer/internal/paths"
+	"gopkg.in/yaml.v3"
+)
+
+// TranslationsPath returns the path of the translations file,
+// translations.yaml in invoicer's config directory.
+func TranslationsPath() (string, error) {
+	return paths.File("translations.yaml")
+}
+
+// LoadTranslations reads the translations file at path: labels by
+// language code and then by label key, e.g.
+//
+//	de:
+//	  total: Gesamtbetrag
+//
+// A missing file has no labels. Keys not in keys are reported to Warnings
+// and left out.
+func LoadTranslations(path string, keys []string) (map[string]map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading translations file %q: %w", path, err)
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parsing translations file %q: %w", path, err)
+	}
+	var labels map[string]map[string]string
+	if err := doc.Decode(&labels); err != nil {
+		return nil, fmt.Errorf("parsing translations file %q: %w", path, err)
+	}
+
+	if len(doc.Content) == 0 {
+		return labels, nil
+	}
+	langs := doc.Content[0]
+	for i := 0; i+1 < len(langs.Content); i += 2 {
+		lang, m := langs.Content[i].Value, langs.Content[i+1]
+		for j := 0; j+1 < len(m.Content); j += 2 {
+			if k := m.Content[j]; !slices.Contains(keys, k.Value) {
+				fmt.Fprintf(Warnings, "Warning: %s:%d: unknown label %q; ignoring it\n", path, k.Line, k.Value)
+				delete(labels[lang], k.Value)
+			}
+		}
+	}
+	return labels, nil
+}
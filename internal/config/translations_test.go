@@ -0,0 +1,42 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zon/invoicer/internal/config"
+)
+
+func TestLoadTranslations(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "translations.yaml")
+	if err := os.WriteFile(path, []byte("de:\n  total: Gesamtbetrag\n  totl: Summe\npt-BR:\n  invoice: FATURA\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	warnings := captureWarnings(t)
+
+	labels, err := config.LoadTranslations(path, []string{"invoice", "total"})
+	if err != nil {
+		t.Fatalf("LoadTranslations: %v", err)
+	}
+	if got := labels["de"]["total"]; got != "Gesamtbetrag" {
+		t.Errorf("de total = %q, want Gesamtbetrag", got)
+	}
+	if got := labels["pt-BR"]["invoice"]; got != "FATURA" {
+		t.Errorf("pt-BR invoice = %q, want FATURA", got)
+	}
+	if _, ok := labels["de"]["totl"]; ok {
+		t.Error("the unknown label totl should be left out")
+	}
+	if !strings.Contains(warnings.String(), `:3: unknown label "totl"`) {
+		t.Errorf("warnings = %q, want one for totl on line 3", warnings.String())
+	}
+}
+
+func TestLoadTranslations_Missing(t *testing.T) {
+	labels, err := config.LoadTranslations(filepath.Join(t.TempDir(), "translations.yaml"), nil)
+	if err != nil || labels != nil {
+		t.Errorf("LoadTranslations of a missing file = %v, %v; want no labels", labels, err)
+	}
+}
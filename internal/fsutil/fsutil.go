@@ -0,0 +1,80 @@
+// Package fsutil provides safe file writing for invoicer's config and state files.
+package fsutil
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DefaultLockTimeout is how long Lock waits for another process to release a lock.
+const DefaultLockTimeout = 10 * time.Second
+
+// lockPollInterval is how often Lock retries while waiting.
+const lockPollInterval = 20 * time.Millisecond
+
+// ErrLockTimeout is returned when a lock could not be acquired in time.
+var ErrLockTimeout = errors.New("timed out waiting for lock")
+
+// WriteFileAtomic writes data to path by writing a temporary file in the same
+// directory and renaming it into place, so readers never observe a partially
+// written file. The directory is created if needed.
+func WriteFileAtomic(path string, data []byte, perm fs.FileMode) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("creating directory %q: %w", dir, err)
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Lock acquires an advisory lock for path by exclusively creating path+".lock".
+// It waits up to timeout for another holder to release it, then fails with an
+// error wrapping ErrLockTimeout. The returned function releases the lock.
+func Lock(path string, timeout time.Duration) (func(), error) {
+	lockPath := path + ".lock"
+	if err := os.MkdirAll(filepath.Dir(lockPath), 0o700); err != nil {
+		return nil, fmt.Errorf("creating directory for %q: %w", lockPath, err)
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+		if err == nil {
+			fmt.Fprintf(f, "%d\n", os.Getpid())
+			f.Close()
+			return func() { os.Remove(lockPath) }, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, fmt.Errorf("creating lock file %q: %w", lockPath, err)
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("%w %q after %s (remove it if no other invoicer process is running)", ErrLockTimeout, lockPath, timeout)
+		}
+		time.Sleep(lockPollInterval)
+	}
+}
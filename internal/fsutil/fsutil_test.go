@@ -0,0 +1,70 @@
+package fsutil_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/zon/invoicer/internal/fsutil"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "sub", "state.yaml")
+	if err := fsutil.WriteFileAtomic(path, []byte("a: 1\n"), 0o600); err != nil {
+		t.Fatalf("WriteFileAtomic: %v", err)
+	}
+	if err := fsutil.WriteFileAtomic(path, []byte("a: 2\n"), 0o600); err != nil {
+		t.Fatalf("WriteFileAtomic overwrite: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "a: 2\n" {
+		t.Errorf("content = %q, want %q", data, "a: 2\n")
+	}
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Errorf("expected no leftover temp files, found %d entries", len(entries))
+	}
+}
+
+func TestLock_ExcludesAndReleases(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	unlock, err := fsutil.Lock(path, time.Second)
+	if err != nil {
+		t.Fatalf("Lock: %v", err)
+	}
+
+	_, err = fsutil.Lock(path, 50*time.Millisecond)
+	if !errors.Is(err, fsutil.ErrLockTimeout) {
+		t.Fatalf("second Lock: got %v, want ErrLockTimeout", err)
+	}
+
+	unlock()
+	unlock2, err := fsutil.Lock(path, time.Second)
+	if err != nil {
+		t.Fatalf("Lock after release: %v", err)
+	}
+	unlock2()
+}
+
+func TestLock_WaitsForRelease(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	unlock, err := fsutil.Lock(path, time.Second)
+	if err != nil {
+		t.Fatalf("Lock: %v", err)
+	}
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		unlock()
+	}()
+	unlock2, err := fsutil.Lock(path, 2*time.Second)
+	if err != nil {
+		t.Fatalf("Lock should succeed once released: %v", err)
+	}
+	unlock2()
+}
@@ -0,0 +1,367 @@
+// Package mcp implements a minimal Model Context Protocol server over stdio.
+//
+// Messages are JSON-RPC 2.0 objects, one per line. Only the tools capability
+// is supported: initialize, ping, tools/list and tools/call, plus the
+// notifications/cancelled notification and progress notifications for
+// tool calls that carry a progress token.
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// ProtocolVersion is the latest protocol revision the server speaks.
+const ProtocolVersion = "2025-06-18"
+
+// supportedVersions are the protocol revisions the server accepts from clients.
+var supportedVersions = []string{ProtocolVersion, "2025-03-26", "2024-11-05"}
+
+// maxMessageSize is the longest line the server will read.
+const maxMessageSize = 4 << 20
+
+// JSON-RPC error codes.
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+)
+
+// Error is a JSON-RPC error object.
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s (code %d)", e.Message, e.Code)
+}
+
+// ProgressFunc reports the progress of a tool call. total is 0 if unknown.
+// It does nothing if the client did not ask for progress.
+type ProgressFunc func(progress, total float64, message string)
+
+// Handler runs a tool with its already validated arguments. The returned
+// value is encoded as JSON in the tool result; an error is reported as a
+// tool result with isError set. ctx is cancelled if the client cancels the
+// request or the input closes.
+type Handler func(ctx context.Context, args json.RawMessage, progress ProgressFunc) (any, error)
+
+// Tool is a tool exposed to clients.
+type Tool struct {
+	Name        string
+	Description string
+	InputSchema *Schema
+	Handler     Handler
+}
+
+// Server dispatches MCP requests to registered tools.
+type Server struct {
+	name    string
+	version string
+	tools   []Tool
+
+	mu      sync.Mutex
+	w       io.Writer
+	cancels map[string]context.CancelFunc
+}
+
+// NewServer returns a server that identifies itself with name and version.
+func NewServer(name, version string) *Server {
+	return &Server{name: name, version: version}
+}
+
+// AddTool registers a tool. It panics if the name is already taken.
+func (s *Server) AddTool(t Tool) {
+	for _, existing := range s.tools {
+		if existing.Name == t.Name {
+			panic(fmt.Sprintf("mcp: tool %q registered twice", t.Name))
+		}
+	}
+	s.tools = append(s.tools, t)
+}
+
+// message is an incoming JSON-RPC request or notification.
+type message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// response is an outgoing JSON-RPC response.
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+// notification is an outgoing JSON-RPC notification.
+type notification struct {
+	JSONRPC string `json:"jsonrpc"`
+	Method  string `json:"method"`
+	Params  any    `json:"params,omitempty"`
+}
+
+// Serve reads requests from r and writes responses to w until r is
+// exhausted or ctx is done. Tool calls run concurrently; when Serve stops
+// it cancels the calls still running and waits for them to finish.
+func (s *Server) Serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	s.mu.Lock()
+	s.w = w
+	s.cancels = map[string]context.CancelFunc{}
+	s.mu.Unlock()
+
+	lines := make(chan []byte)
+	readErr := make(chan error, 1)
+	go func() {
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 64*1024), maxMessageSize)
+		for scanner.Scan() {
+			line := append([]byte(nil), scanner.Bytes()...)
+			select {
+			case lines <- line:
+			case <-ctx.Done():
+				return
+			}
+		}
+		readErr <- scanner.Err()
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-readErr:
+			if err != nil {
+				return fmt.Errorf("reading messages: %w", err)
+			}
+			return nil
+		case line := <-lines:
+			if len(line) == 0 {
+				continue
+			}
+			s.handle(ctx, line, &wg)
+		}
+	}
+}
+
+// handle dispatches one incoming message.
+func (s *Server) handle(ctx context.Context, line []byte, wg *sync.WaitGroup) {
+	var msg message
+	if err := json.Unmarshal(line, &msg); err != nil {
+		s.reply(nil, nil, &Error{Code: CodeParseError, Message: "parse error: " + err.Error()})
+		return
+	}
+	if msg.JSONRPC != "2.0" || msg.Method == "" {
+		if len(msg.ID) > 0 {
+			s.reply(msg.ID, nil, &Error{Code: CodeInvalidRequest, Message: "invalid request"})
+		}
+		return
+	}
+
+	// Notifications have no id and get no response.
+	if len(msg.ID) == 0 {
+		if msg.Method == "notifications/cancelled" {
+			s.cancel(msg.Params)
+		}
+		return
+	}
+
+	switch msg.Method {
+	case "initialize":
+		s.reply(msg.ID, s.initialize(msg.Params), nil)
+	case "ping":
+		s.reply(msg.ID, map[string]any{}, nil)
+	case "tools/list":
+		s.reply(msg.ID, s.listTools(), nil)
+	case "tools/call":
+		callCtx, cancel := context.WithCancel(ctx)
+		s.mu.Lock()
+		s.cancels[string(msg.ID)] = cancel
+		s.mu.Unlock()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() {
+				s.mu.Lock()
+				delete(s.cancels, string(msg.ID))
+				s.mu.Unlock()
+				cancel()
+			}()
+			result, err := s.callTool(callCtx, msg.Params)
+			// A request the client cancelled gets no response.
+			if callCtx.Err() != nil && ctx.Err() == nil {
+				return
+			}
+			s.reply(msg.ID, result, err)
+		}()
+	default:
+		s.reply(msg.ID, nil, &Error{Code: CodeMethodNotFound, Message: fmt.Sprintf("method %q not found", msg.Method)})
+	}
+}
+
+// initialize answers the client's handshake, agreeing on its protocol
+// version if supported and otherwise offering the latest.
+func (s *Server) initialize(params json.RawMessage) any {
+	var p struct {
+		ProtocolVersion string `json:"protocolVersion"`
+	}
+	_ = json.Unmarshal(params, &p)
+	version := ProtocolVersion
+	for _, v := range supportedVersions {
+		if v == p.ProtocolVersion {
+			version = v
+		}
+	}
+	return map[string]any{
+		"protocolVersion": version,
+		"capabilities":    map[string]any{"tools": map[string]any{}},
+		"serverInfo":      map[string]string{"name": s.name, "version": s.version},
+	}
+}
+
+// listTools describes the registered tools.
+func (s *Server) listTools() any {
+	tools := make([]map[string]any, 0, len(s.tools))
+	for _, t := range s.tools {
+		schema := t.InputSchema
+		if schema == nil {
+			schema = &Schema{Type: "object"}
+		}
+		tools = append(tools, map[string]any{
+			"name":        t.Name,
+			"description": t.Description,
+			"inputSchema": schema,
+		})
+	}
+	return map[string]any{"tools": tools}
+}
+
+// callTool validates the arguments and runs the named tool.
+func (s *Server) callTool(ctx context.Context, params json.RawMessage) (any, *Error) {
+	var p struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+		Meta      struct {
+			ProgressToken json.RawMessage `json:"progressToken"`
+		} `json:"_meta"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, &Error{Code: CodeInvalidParams, Message: "invalid params: " + err.Error()}
+	}
+	var tool *Tool
+	for i := range s.tools {
+		if s.tools[i].Name == p.Name {
+			tool = &s.tools[i]
+		}
+	}
+	if tool == nil {
+		return nil, &Error{Code: CodeInvalidParams, Message: fmt.Sprintf("unknown tool %q", p.Name)}
+	}
+
+	args := p.Arguments
+	if len(args) == 0 || string(args) == "null" {
+		args = json.RawMessage("{}")
+	}
+	if tool.InputSchema != nil {
+		var v any
+		if err := json.Unmarshal(args, &v); err != nil {
+			return nil, &Error{Code: CodeInvalidParams, Message: "invalid arguments: " + err.Error()}
+		}
+		if err := tool.InputSchema.Validate(v); err != nil {
+			return nil, &Error{Code: CodeInvalidParams, Message: fmt.Sprintf("invalid arguments for %s: %v", tool.Name, err)}
+		}
+	}
+
+	progress := func(float64, float64, string) {}
+	if len(p.Meta.ProgressToken) > 0 {
+		token := p.Meta.ProgressToken
+		progress = func(done, total float64, message string) {
+			params := map[string]any{"progressToken": token, "progress": done}
+			if total > 0 {
+				params["total"] = total
+			}
+			if message != "" {
+				params["message"] = message
+			}
+			s.notify("notifications/progress", params)
+		}
+	}
+
+	out, err := tool.Handler(ctx, args, progress)
+	if err != nil {
+		return toolResult(err.Error(), true), nil
+	}
+	text, err := json.Marshal(out)
+	if err != nil {
+		return nil, &Error{Code: CodeInternalError, Message: "encoding result: " + err.Error()}
+	}
+	return toolResult(string(text), false), nil
+}
+
+// toolResult builds a tools/call result with a single text item.
+func toolResult(text string, isError bool) map[string]any {
+	return map[string]any{
+		"content": []map[string]string{{"type": "text", "text": text}},
+		"isError": isError,
+	}
+}
+
+// cancel stops the in-flight request named by a notifications/cancelled message.
+func (s *Server) cancel(params json.RawMessage) {
+	var p struct {
+		RequestID json.RawMessage `json:"requestId"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return
+	}
+	s.mu.Lock()
+	cancel := s.cancels[string(p.RequestID)]
+	s.mu.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+}
+
+// reply writes a response. A nil id is written as null.
+func (s *Server) reply(id json.RawMessage, result any, rpcErr *Error) {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+	resp := response{JSONRPC: "2.0", ID: id, Error: rpcErr}
+	if rpcErr == nil {
+		resp.Result = result
+	}
+	s.write(resp)
+}
+
+// notify writes a notification.
+func (s *Server) notify(method string, params any) {
+	s.write(notification{JSONRPC: "2.0", Method: method, Params: params})
+}
+
+// write encodes v as a single line. Writes are serialized so concurrent
+// tool calls do not interleave.
+func (s *Server) write(v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, _ = s.w.Write(append(data, '\n'))
+}
@@ -0,0 +1,267 @@
+package mcp_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zon/invoicer/internal/mcp"
+)
+
+// client exchanges framed messages with a server over in-memory pipes.
+type client struct {
+	t    *testing.T
+	in   *io.PipeWriter
+	out  *bufio.Reader
+	done chan error
+}
+
+func startServer(t *testing.T, srv *mcp.Server) *client {
+	t.Helper()
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	c := &client{t: t, in: inW, out: bufio.NewReader(outR), done: make(chan error, 1)}
+	go func() {
+		c.done <- srv.Serve(context.Background(), inR, outW)
+		outW.Close()
+	}()
+	t.Cleanup(func() {
+		inW.Close()
+		go io.Copy(io.Discard, outR)
+		select {
+		case <-c.done:
+		case <-time.After(5 * time.Second):
+			t.Error("server did not stop after input closed")
+		}
+	})
+	return c
+}
+
+// send writes one message.
+func (c *client) send(msg string) {
+	c.t.Helper()
+	if _, err := io.WriteString(c.in, msg+"\n"); err != nil {
+		c.t.Fatalf("writing message: %v", err)
+	}
+}
+
+// recv reads one message.
+func (c *client) recv() map[string]any {
+	c.t.Helper()
+	line, err := c.out.ReadBytes('\n')
+	if err != nil {
+		c.t.Fatalf("reading message: %v", err)
+	}
+	var msg map[string]any
+	if err := json.Unmarshal(line, &msg); err != nil {
+		c.t.Fatalf("decoding %q: %v", line, err)
+	}
+	return msg
+}
+
+// call sends a request and returns its response.
+func (c *client) call(msg string) map[string]any {
+	c.t.Helper()
+	c.send(msg)
+	return c.recv()
+}
+
+func float(f float64) *float64 { return &f }
+func integer(i int) *int       { return &i }
+
+func testServer() *mcp.Server {
+	srv := mcp.NewServer("invoicer", "test")
+	srv.AddTool(mcp.Tool{
+		Name:        "echo",
+		Description: "Echoes its arguments.",
+		InputSchema: &mcp.Schema{
+			Type: "object",
+			Properties: map[string]*mcp.Schema{
+				"customer": {Type: "string", MinLength: integer(1)},
+				"year":     {Type: "integer", Minimum: float(1900)},
+			},
+			Required: []string{"customer"},
+		},
+		Handler: func(ctx context.Context, args json.RawMessage, progress mcp.ProgressFunc) (any, error) {
+			progress(1, 1, "echoed")
+			var v map[string]any
+			err := json.Unmarshal(args, &v)
+			return v, err
+		},
+	})
+	srv.AddTool(mcp.Tool{
+		Name: "fail",
+		Handler: func(ctx context.Context, args json.RawMessage, progress mcp.ProgressFunc) (any, error) {
+			return nil, errors.New("it broke")
+		},
+	})
+	srv.AddTool(mcp.Tool{
+		Name: "wait",
+		Handler: func(ctx context.Context, args json.RawMessage, progress mcp.ProgressFunc) (any, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+	})
+	return srv
+}
+
+func TestInitialize(t *testing.T) {
+	c := startServer(t, testServer())
+	resp := c.call(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05","capabilities":{},"clientInfo":{"name":"t","version":"1"}}}`)
+	result := resp["result"].(map[string]any)
+	if result["protocolVersion"] != "2024-11-05" {
+		t.Errorf("protocolVersion = %v, want the client's supported version", result["protocolVersion"])
+	}
+	if info := result["serverInfo"].(map[string]any); info["name"] != "invoicer" {
+		t.Errorf("serverInfo = %v", info)
+	}
+
+	resp = c.call(`{"jsonrpc":"2.0","id":2,"method":"initialize","params":{"protocolVersion":"1999-01-01"}}`)
+	if v := resp["result"].(map[string]any)["protocolVersion"]; v != mcp.ProtocolVersion {
+		t.Errorf("unsupported client version should get %s, got %v", mcp.ProtocolVersion, v)
+	}
+}
+
+func TestNotificationsGetNoResponse(t *testing.T) {
+	c := startServer(t, testServer())
+	c.send(`{"jsonrpc":"2.0","method":"notifications/initialized"}`)
+	resp := c.call(`{"jsonrpc":"2.0","id":"p","method":"ping"}`)
+	if resp["id"] != "p" {
+		t.Errorf("expected the ping response first, got %v", resp)
+	}
+}
+
+func TestToolsList(t *testing.T) {
+	c := startServer(t, testServer())
+	resp := c.call(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`)
+	tools := resp["result"].(map[string]any)["tools"].([]any)
+	if len(tools) != 3 {
+		t.Fatalf("expected 3 tools, got %d", len(tools))
+	}
+	echo := tools[0].(map[string]any)
+	schema := echo["inputSchema"].(map[string]any)
+	if schema["type"] != "object" || schema["required"].([]any)[0] != "customer" {
+		t.Errorf("unexpected schema: %v", schema)
+	}
+	if fail := tools[1].(map[string]any); fail["inputSchema"].(map[string]any)["type"] != "object" {
+		t.Errorf("tool without a schema should advertise an object schema: %v", fail)
+	}
+}
+
+func TestToolsCall(t *testing.T) {
+	c := startServer(t, testServer())
+	resp := c.call(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"echo","arguments":{"customer":"Acme","year":2025}}}`)
+	result := resp["result"].(map[string]any)
+	if result["isError"] != false {
+		t.Errorf("isError = %v", result["isError"])
+	}
+	text := result["content"].([]any)[0].(map[string]any)["text"].(string)
+	if text != `{"customer":"Acme","year":2025}` {
+		t.Errorf("text = %s", text)
+	}
+}
+
+func TestToolsCallProgress(t *testing.T) {
+	c := startServer(t, testServer())
+	c.send(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"echo","arguments":{"customer":"Acme"},"_meta":{"progressToken":"tok"}}}`)
+	note := c.recv()
+	if note["method"] != "notifications/progress" {
+		t.Fatalf("expected a progress notification, got %v", note)
+	}
+	params := note["params"].(map[string]any)
+	if params["progressToken"] != "tok" || params["message"] != "echoed" {
+		t.Errorf("unexpected progress params: %v", params)
+	}
+	if resp := c.recv(); resp["id"] != 1.0 {
+		t.Errorf("expected the response after progress, got %v", resp)
+	}
+}
+
+func TestToolsCallErrors(t *testing.T) {
+	c := startServer(t, testServer())
+	tests := []struct {
+		name string
+		msg  string
+		code float64
+		want string
+	}{
+		{"parse", `{"jsonrpc":`, mcp.CodeParseError, "parse error"},
+		{"method", `{"jsonrpc":"2.0","id":1,"method":"resources/list"}`, mcp.CodeMethodNotFound, "resources/list"},
+		{"unknown tool", `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"nope"}}`, mcp.CodeInvalidParams, `unknown tool "nope"`},
+		{"missing required", `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"echo","arguments":{}}}`, mcp.CodeInvalidParams, "customer is required"},
+		{"wrong type", `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"echo","arguments":{"customer":"A","year":"2025"}}}`, mcp.CodeInvalidParams, "year must be an integer"},
+		{"below minimum", `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"echo","arguments":{"customer":"A","year":25}}}`, mcp.CodeInvalidParams, "year must be at least 1900"},
+		{"empty string", `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"echo","arguments":{"customer":" "}}}`, mcp.CodeInvalidParams, "customer must not be empty"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := c.call(tt.msg)
+			rpcErr, ok := resp["error"].(map[string]any)
+			if !ok {
+				t.Fatalf("expected an error response, got %v", resp)
+			}
+			if rpcErr["code"] != tt.code {
+				t.Errorf("code = %v, want %v", rpcErr["code"], tt.code)
+			}
+			if msg := rpcErr["message"].(string); !strings.Contains(msg, tt.want) {
+				t.Errorf("message %q does not contain %q", msg, tt.want)
+			}
+		})
+	}
+}
+
+func TestToolsCallHandlerError(t *testing.T) {
+	c := startServer(t, testServer())
+	resp := c.call(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"fail"}}`)
+	result := resp["result"].(map[string]any)
+	if result["isError"] != true {
+		t.Errorf("isError = %v, want true", result["isError"])
+	}
+	if text := result["content"].([]any)[0].(map[string]any)["text"]; text != "it broke" {
+		t.Errorf("text = %v", text)
+	}
+}
+
+func TestToolsCallCancelled(t *testing.T) {
+	c := startServer(t, testServer())
+	c.send(`{"jsonrpc":"2.0","id":7,"method":"tools/call","params":{"name":"wait"}}`)
+	c.send(`{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":7}}`)
+
+	// The cancelled call gets no response, so the next one is the ping.
+	resp := c.call(`{"jsonrpc":"2.0","id":8,"method":"ping"}`)
+	if resp["id"] != 8.0 {
+		t.Errorf("expected only the ping response, got %v", resp)
+	}
+}
+
+func TestServeCancelsCallsWhenInputCloses(t *testing.T) {
+	c := startServer(t, testServer())
+	c.send(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"wait"}}`)
+	c.in.Close()
+	go io.Copy(io.Discard, c.out)
+	select {
+	case err := <-c.done:
+		if err != nil {
+			t.Errorf("Serve returned %v", err)
+		}
+		c.done <- err
+	case <-time.After(5 * time.Second):
+		t.Fatal("Serve did not return after the input closed")
+	}
+}
+
+func TestAddToolTwicePanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic")
+		}
+	}()
+	srv := mcp.NewServer("invoicer", "test")
+	srv.AddTool(mcp.Tool{Name: "x"})
+	srv.AddTool(mcp.Tool{Name: "x"})
+}
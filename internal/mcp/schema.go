@@ -0,0 +1,106 @@
+package mcp
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Schema is the subset of JSON Schema used to describe and validate tool
+// arguments: objects with typed properties, required keys, numeric bounds,
+// string lengths and enums.
+type Schema struct {
+	Type                 string             `json:"type"`
+	Description          string             `json:"description,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *bool              `json:"additionalProperties,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+}
+
+// Validate checks a decoded JSON value against the schema.
+func (s *Schema) Validate(v any) error {
+	return s.validate("", v)
+}
+
+func (s *Schema) validate(path string, v any) error {
+	name := path
+	if name == "" {
+		name = "arguments"
+	}
+	switch s.Type {
+	case "object":
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s must be an object", name)
+		}
+		for _, key := range s.Required {
+			if _, ok := obj[key]; !ok {
+				return fmt.Errorf("%s is required", join(path, key))
+			}
+		}
+		keys := make([]string, 0, len(obj))
+		for key := range obj {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			prop, ok := s.Properties[key]
+			if !ok {
+				if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+					return fmt.Errorf("%s is not a known property", join(path, key))
+				}
+				continue
+			}
+			if err := prop.validate(join(path, key), obj[key]); err != nil {
+				return err
+			}
+		}
+	case "string":
+		str, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("%s must be a string", name)
+		}
+		if s.MinLength != nil && len(strings.TrimSpace(str)) < *s.MinLength {
+			if *s.MinLength == 1 {
+				return fmt.Errorf("%s must not be empty", name)
+			}
+			return fmt.Errorf("%s must be at least %d characters", name, *s.MinLength)
+		}
+		if len(s.Enum) > 0 && !slices.Contains(s.Enum, str) {
+			return fmt.Errorf("%s must be one of: %s", name, strings.Join(s.Enum, ", "))
+		}
+	case "integer", "number":
+		num, ok := v.(float64)
+		if !ok || (s.Type == "integer" && num != math.Trunc(num)) {
+			if s.Type == "integer" {
+				return fmt.Errorf("%s must be an integer", name)
+			}
+			return fmt.Errorf("%s must be a number", name)
+		}
+		if s.Minimum != nil && num < *s.Minimum {
+			return fmt.Errorf("%s must be at least %v", name, *s.Minimum)
+		}
+		if s.Maximum != nil && num > *s.Maximum {
+			return fmt.Errorf("%s must be at most %v", name, *s.Maximum)
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return fmt.Errorf("%s must be a boolean", name)
+		}
+	}
+	return nil
+}
+
+// join appends key to a dotted property path.
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,50 @@
+// Package paths resolves where invoicer keeps its config and state files.
+package paths
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Root returns the directory holding invoicer's config and state files.
+// An existing legacy ~/.invoicer/config.yaml keeps ~/.invoicer as the root so
+// existing setups continue to work; otherwise the root is invoicer/ under
+// os.UserConfigDir (e.g. $XDG_CONFIG_HOME/invoicer on Linux).
+func Root() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("could not determine home directory: %w", err)
+	}
+	legacy := filepath.Join(home, ".invoicer")
+	if _, err := os.Stat(filepath.Join(legacy, "config.yaml")); err == nil {
+		return legacy, nil
+	}
+
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("could not determine config directory: %w", err)
+	}
+	return filepath.Join(dir, "invoicer"), nil
+}
+
+// File returns the path of the named file under Root.
+func File(name string) (string, error) {
+	root, err := Root()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(root, name), nil
+}
+
+// ConfigFile returns the path to the config file.
+func ConfigFile() (string, error) {
+	return File("config.yaml")
+}
+
+// ProfileRoot returns the directory holding the config and state files of
+// the named profile, profiles/<name> under Root. Each profile's files are
+// kept apart from the others' and from those directly under Root.
+func ProfileRoot(name string) (string, error) {
+	return File(filepath.Join("profiles", name))
+}
@@ -0,0 +1,79 @@
+package paths_test
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/zon/invoicer/internal/paths"
+)
+
+// setHome points the home and XDG config directories at fresh temp dirs.
+func setHome(t *testing.T) (home, xdg string) {
+	t.Helper()
+	if runtime.GOOS != "linux" {
+		t.Skip("XDG_CONFIG_HOME is only honored by os.UserConfigDir on Linux")
+	}
+	home = t.TempDir()
+	xdg = t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", xdg)
+	return home, xdg
+}
+
+func TestConfigFile_LegacyPresent(t *testing.T) {
+	home, _ := setHome(t)
+	legacy := filepath.Join(home, ".invoicer", "config.yaml")
+	if err := os.MkdirAll(filepath.Dir(legacy), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(legacy, []byte("vendor: x\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := paths.ConfigFile()
+	if err != nil {
+		t.Fatalf("ConfigFile: %v", err)
+	}
+	if got != legacy {
+		t.Errorf("ConfigFile() = %q, want legacy %q", got, legacy)
+	}
+}
+
+func TestConfigFile_FreshInstall(t *testing.T) {
+	_, xdg := setHome(t)
+	got, err := paths.ConfigFile()
+	if err != nil {
+		t.Fatalf("ConfigFile: %v", err)
+	}
+	want := filepath.Join(xdg, "invoicer", "config.yaml")
+	if got != want {
+		t.Errorf("ConfigFile() = %q, want %q", got, want)
+	}
+}
+
+func TestConfigFile_DefaultXDG(t *testing.T) {
+	home, _ := setHome(t)
+	t.Setenv("XDG_CONFIG_HOME", "")
+	got, err := paths.ConfigFile()
+	if err != nil {
+		t.Fatalf("ConfigFile: %v", err)
+	}
+	want := filepath.Join(home, ".config", "invoicer", "config.yaml")
+	if got != want {
+		t.Errorf("ConfigFile() = %q, want %q", got, want)
+	}
+}
+
+func TestFile_SharesRoot(t *testing.T) {
+	_, xdg := setHome(t)
+	got, err := paths.File("history.yaml")
+	if err != nil {
+		t.Fatalf("File: %v", err)
+	}
+	want := filepath.Join(xdg, "invoicer", "history.yaml")
+	if got != want {
+		t.Errorf("File() = %q, want %q", got, want)
+	}
+}
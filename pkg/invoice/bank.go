@@ -0,0 +1,140 @@
+package invoice
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ibanPattern matches the shape of an IBAN: a country code, two check
+// digits, and up to 30 letters and digits.
+var ibanPattern = regexp.MustCompile(`^[A-Z]{2}[0-9]{2}[A-Z0-9]{11,30}$`)
+
+// ibanLengths are the lengths of the IBANs of each country that has them,
+// from the SWIFT IBAN registry.
+var ibanLengths = map[string]int{
+	"AD": 24, "AE": 23, "AL": 28, "AT": 20, "AZ": 28, "BA": 20, "BE": 16,
+	"BG": 22, "BH": 22, "BI": 27, "BR": 29, "BY": 28, "CH": 21, "CR": 22,
+	"CY": 28, "CZ": 24, "DE": 22, "DJ": 27, "DK": 18, "DO": 28, "EE": 20,
+	"EG": 29, "ES": 24, "FI": 18, "FK": 18, "FO": 18, "FR": 27, "GB": 22,
+	"GE": 22, "GI": 23, "GL": 18, "GR": 27, "GT": 28, "HN": 28, "HR": 21,
+	"HU": 28, "IE": 22, "IL": 23, "IQ": 23, "IS": 26, "IT": 27, "JO": 30,
+	"KW": 30, "KZ": 20, "LB": 28, "LC": 32, "LI": 21, "LT": 20, "LU": 20,
+	"LV": 21, "LY": 25, "MC": 27, "MD": 24, "ME": 22, "MK": 19, "MN": 20,
+	"MR": 27, "MT": 31, "MU": 30, "NI": 28, "NL": 18, "NO": 15, "OM": 23,
+	"PK": 24, "PL": 28, "PS": 29, "PT": 25, "QA": 29, "RO": 24, "RS": 22,
+	"RU": 33, "SA": 24, "SC": 31, "SD": 18, "SE": 24, "SI": 19, "SK": 24,
+	"SM": 27, "SO": 23, "ST": 25, "SV": 28, "TL": 23, "TN": 24, "TR": 26,
+	"UA": 29, "VA": 22, "VG": 24, "XK": 20, "YE": 30,
+}
+
+// ValidateIBAN checks that iban is shaped like an IBAN, that it has the
+// length of its country's IBANs, and that its check digits are right
+// (ISO 13616: the number mod 97 is 1).
+func ValidateIBAN(iban string) error {
+	s := normalizeIBAN(iban)
+	if !ibanPattern.MatchString(s) {
+		return fmt.Errorf("IBAN %q is not valid: it should be a country code, two check digits and 11 to 30 letters and digits", iban)
+	}
+	want, ok := ibanLengths[s[:2]]
+	if !ok {
+		return fmt.Errorf("IBAN %q is not valid: %s is not a country with IBANs", iban, s[:2])
+	}
+	if len(s) != want {
+		return fmt.Errorf("IBAN %q is not valid: IBANs from %s have %d characters, not %d", iban, s[:2], want, len(s))
+	}
+	// Move the country code and check digits to the end, and turn letters
+	// into numbers, A=10 to Z=35.
+	var digits strings.Builder
+	for _, c := range s[4:] + s[:4] {
+		if c >= 'A' && c <= 'Z' {
+			digits.WriteString(strconv.Itoa(int(c-'A') + 10))
+		} else {
+			digits.WriteRune(c)
+		}
+	}
+	n, _ := new(big.Int).SetString(digits.String(), 10)
+	if new(big.Int).Mod(n, big.NewInt(97)).Int64() != 1 {
+		return fmt.Errorf("IBAN %q is not valid: its check digits do not match", iban)
+	}
+	return nil
+}
+
+// bicPattern matches a BIC: bank, country and location codes, and an
+// optional branch code.
+var bicPattern = regexp.MustCompile(`^[A-Z]{6}[A-Z0-9]{2}([A-Z0-9]{3})?$`)
+
+// ValidateBIC checks that bic is shaped like a BIC, e.g. COBADEFFXXX.
+func ValidateBIC(bic string) error {
+	if !bicPattern.MatchString(strings.ToUpper(strings.TrimSpace(bic))) {
+		return fmt.Errorf("BIC %q is not valid: it should be 8 or 11 letters and digits, e.g. COBADEFFXXX", bic)
+	}
+	return nil
+}
+
+// routingPattern matches an ABA routing number: nine digits.
+var routingPattern = regexp.MustCompile(`^[0-9]{9}$`)
+
+// ValidateRoutingNumber checks that routing is a US ABA routing number,
+// nine digits whose weighted sum (3, 7, 1, repeated) is a multiple of 10.
+func ValidateRoutingNumber(routing string) error {
+	s := strings.TrimSpace(routing)
+	if !routingPattern.MatchString(s) {
+		return fmt.Errorf("routing number %q is not valid: it should be 9 digits, e.g. 021000021", routing)
+	}
+	sum := 0
+	for i, c := range s {
+		sum += int(c-'0') * [3]int{3, 7, 1}[i%3]
+	}
+	if sum%10 != 0 {
+		return fmt.Errorf("routing number %q is not valid: its check digit does not match", routing)
+	}
+	return nil
+}
+
+// accountPattern matches a US bank account number: 4 to 17 digits.
+var accountPattern = regexp.MustCompile(`^[0-9]{4,17}$`)
+
+// ValidateAccountNumber checks that account is shaped like a US bank
+// account number. Account numbers have no check digit.
+func ValidateAccountNumber(account string) error {
+	if !accountPattern.MatchString(strings.TrimSpace(account)) {
+		return fmt.Errorf("account number %q is not valid: it should be 4 to 17 digits", account)
+	}
+	return nil
+}
+
+// hasAccount reports whether p has an account to pay into: an IBAN or a
+// US account.
+func (p *PaymentDetails) hasAccount() bool {
+	return p.IBAN != "" || p.RoutingNumber != "" || p.AccountNumber != ""
+}
+
+// Validate checks each of p's bank details that is set, and that a US
+// account has both its routing and its account number. It returns every
+// problem found, joined.
+func (p *PaymentDetails) Validate() error {
+	var errs []error
+	if p.IBAN != "" {
+		errs = append(errs, ValidateIBAN(p.IBAN))
+	}
+	if p.BIC != "" {
+		errs = append(errs, ValidateBIC(p.BIC))
+	}
+	if p.RoutingNumber != "" {
+		errs = append(errs, ValidateRoutingNumber(p.RoutingNumber))
+	}
+	if p.AccountNumber != "" {
+		errs = append(errs, ValidateAccountNumber(p.AccountNumber))
+	}
+	switch {
+	case p.RoutingNumber != "" && p.AccountNumber == "":
+		errs = append(errs, errors.New("a routing number needs an account number"))
+	case p.AccountNumber != "" && p.RoutingNumber == "":
+		errs = append(errs, errors.New("an account number needs a routing number"))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,170 @@
+package invoice_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+func TestValidateIBAN(t *testing.T) {
+	tests := []struct {
+		iban    string
+		wantErr string
+	}{
+		{testIBAN, ""},
+		{"GB82 WEST 1234 5698 7654 32", ""},
+		{"gb82west12345698765432", ""},
+		{"GB29NWBK60161331926819", ""},
+		{"NL91ABNA0417164300", ""},
+		{"FR1420041010050500013M02606", ""},
+		{"BE68539007547034", ""},
+		{"CH9300762011623852957", ""},
+		{"NO9386011117947", ""},
+		{"ES9121000418450200051332", ""},
+		{"IT60X0542811101000000123456", ""},
+		{"MT84MALT011000012345MTLCAST001S", ""},
+		{"PL61109010140000071219812874", ""},
+		{"GB82WEST12345698765433", "check digits do not match"},
+		{"DE89370400440532013001", "check digits do not match"},
+		{"FR1420041010050500013M02607", "check digits do not match"},
+		{"NL91ABNA0417164301", "check digits do not match"},
+		{"DE543704004405320130001", "IBANs from DE have 22 characters, not 23"},
+		{"NL91ABNA04171643001", "IBANs from NL have 18 characters, not 19"},
+		{"XX46370400440532013000", "XX is not a country with IBANs"},
+		{"GB82", "should be a country code"},
+		{"1282WEST12345698765432", "should be a country code"},
+		{"GB82WEST1234569876543!", "should be a country code"},
+		{"", "should be a country code"},
+	}
+	for _, tt := range tests {
+		err := invoice.ValidateIBAN(tt.iban)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("ValidateIBAN(%q) = %v, want valid", tt.iban, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("ValidateIBAN(%q) = %v, want %q", tt.iban, err, tt.wantErr)
+		}
+	}
+}
+
+func TestValidateBIC(t *testing.T) {
+	tests := []struct {
+		bic   string
+		valid bool
+	}{
+		{"COBADEFFXXX", true},
+		{"COBADEFF", true},
+		{"deutdeff500", true},
+		{"NWBKGB2L", true},
+		{"COBADEFFXX", false},
+		{"COBA1EFF", false},
+		{"DE00 1234", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if err := invoice.ValidateBIC(tt.bic); (err == nil) != tt.valid {
+			t.Errorf("ValidateBIC(%q) = %v, want valid %v", tt.bic, err, tt.valid)
+		}
+	}
+}
+
+func TestValidateRoutingNumber(t *testing.T) {
+	tests := []struct {
+		routing string
+		wantErr string
+	}{
+		{"021000021", ""},
+		{"011000015", ""},
+		{"121000358", ""},
+		{"026009593", ""},
+		{"322271627", ""},
+		{"021000022", "check digit does not match"},
+		{"120000358", "check digit does not match"},
+		{"02100002", "should be 9 digits"},
+		{"0210000210", "should be 9 digits"},
+		{"02100002A", "should be 9 digits"},
+		{"", "should be 9 digits"},
+	}
+	for _, tt := range tests {
+		err := invoice.ValidateRoutingNumber(tt.routing)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("ValidateRoutingNumber(%q) = %v, want valid", tt.routing, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("ValidateRoutingNumber(%q) = %v, want %q", tt.routing, err, tt.wantErr)
+		}
+	}
+}
+
+func TestValidateAccountNumber(t *testing.T) {
+	for _, account := range []string{"1234", "000123456789", "12345678901234567"} {
+		if err := invoice.ValidateAccountNumber(account); err != nil {
+			t.Errorf("ValidateAccountNumber(%q) = %v, want valid", account, err)
+		}
+	}
+	for _, account := range []string{"123", "123456789012345678", "1234-5678", ""} {
+		if err := invoice.ValidateAccountNumber(account); err == nil {
+			t.Errorf("ValidateAccountNumber(%q) should fail", account)
+		}
+	}
+}
+
+func TestPaymentDetailsValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		p       invoice.PaymentDetails
+		wantErr []string
+	}{
+		{"IBAN", invoice.PaymentDetails{IBAN: testIBAN, BIC: "COBADEFFXXX"}, nil},
+		{"US account", invoice.PaymentDetails{RoutingNumber: "021000021", AccountNumber: "000123456789"}, nil},
+		{"both", invoice.PaymentDetails{IBAN: testIBAN, RoutingNumber: "021000021", AccountNumber: "000123456789"}, nil},
+		{"every problem", invoice.PaymentDetails{IBAN: "DE89370400440532013001", BIC: "COBA", RoutingNumber: "021000022"}, []string{
+			"check digits do not match", `BIC "COBA"`, "check digit does not match", "a routing number needs an account number",
+		}},
+		{"account without routing", invoice.PaymentDetails{AccountNumber: "000123456789"}, []string{"an account number needs a routing number"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.p.Validate()
+			if len(tt.wantErr) == 0 {
+				if err != nil {
+					t.Errorf("Validate: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("Validate should fail with %q", tt.wantErr)
+			}
+			for _, want := range tt.wantErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Validate = %v, want it to contain %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestTextInvoice_USAccount(t *testing.T) {
+	inv := testInvoice()
+	inv.Payment = &invoice.PaymentDetails{RoutingNumber: "021000021", AccountNumber: "000123456789"}
+	var sb strings.Builder
+	if err := invoice.WriteText(&sb, inv); err != nil {
+		t.Fatalf("WriteText: %v", err)
+	}
+	got := sb.String()
+	for _, want := range []string{"Routing number: 021000021\n", "Account number: 000123456789\n"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("text invoice should show %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "IBAN") {
+		t.Errorf("text invoice shows an IBAN it does not have:\n%s", got)
+	}
+}
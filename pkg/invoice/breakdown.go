@@ -0,0 +1,46 @@
+package invoice
+
+// BreakdownLine is a line item with its unit price and amount rounded to
+// cents.
+type BreakdownLine struct {
+	LineItem
+	Price  Cents
+	Amount Cents
+}
+
+// InvoiceBreakdown is every figure of an invoice, computed in one place so
+// that each format shows the same amounts.
+type InvoiceBreakdown struct {
+	// Lines are the invoice's line items in order.
+	Lines []BreakdownLine
+	// Subtotal is the sum of the lines' amounts.
+	Subtotal Cents
+	// Discount is taken off the subtotal, and Tax added to it. Invoicer
+	// applies neither, so both are 0.
+	Discount Cents
+	Tax      Cents
+	// Withholding is tax the customer withholds from the payment. Invoicer
+	// applies none, so it is 0.
+	Withholding Cents
+	// AmountDue is what the customer pays: the subtotal less the discount,
+	// plus tax, less withholding.
+	AmountDue Cents
+}
+
+// Breakdown returns the figures of inv. Each line's amount is rounded to
+// cents on its own and every other figure is summed from those, so the
+// lines always add up to the total.
+func (inv *Invoice) Breakdown() InvoiceBreakdown {
+	var b InvoiceBreakdown
+	for _, item := range inv.LineItems() {
+		line := BreakdownLine{
+			LineItem: item,
+			Price:    ToCents(item.UnitPrice),
+			Amount:   ToCents(item.Amount()),
+		}
+		b.Lines = append(b.Lines, line)
+		b.Subtotal += line.Amount
+	}
+	b.AmountDue = b.Subtotal - b.Discount + b.Tax - b.Withholding
+	return b
+}
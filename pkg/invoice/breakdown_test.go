@@ -0,0 +1,110 @@
+package invoice_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+// trickyInvoice has an odd rate and a short first week, so each line's
+// amount has a half cent and the unrounded sum rounds differently from
+// the sum of the rounded lines.
+func trickyInvoice() *invoice.Invoice {
+	return &invoice.Invoice{
+		Month:    time.January,
+		Year:     2025,
+		Vendor:   "Jane Smith",
+		Customer: "Acme Corp",
+		Rate:     33.335,
+		Weeks:    invoice.WeeksForMonth(2025, time.January, 37.5),
+		Issued:   time.Date(2025, time.February, 1, 0, 0, 0, 0, time.UTC),
+		Payment:  &invoice.PaymentDetails{IBAN: "DE89370400440532013000"},
+	}
+}
+
+func TestInvoiceBreakdown(t *testing.T) {
+	b := trickyInvoice().Breakdown()
+	var amounts []invoice.Cents
+	for _, l := range b.Lines {
+		amounts = append(amounts, l.Amount)
+	}
+	if want := []invoice.Cents{75004, 125006, 125006, 125006, 125006}; !slices.Equal(amounts, want) {
+		t.Errorf("line amounts = %v, want %v", amounts, want)
+	}
+	if b.Lines[0].Price != 3334 {
+		t.Errorf("price = %v, want 33.34", b.Lines[0].Price)
+	}
+	if b.Subtotal != 575028 || b.AmountDue != 575028 {
+		t.Errorf("subtotal = %v, amount due = %v, want 5750.28", b.Subtotal, b.AmountDue)
+	}
+	if b.Discount != 0 || b.Tax != 0 || b.Withholding != 0 {
+		t.Errorf("discount, tax, withholding = %v, %v, %v, want 0", b.Discount, b.Tax, b.Withholding)
+	}
+	if got := trickyInvoice().Total(); got != 5750.28 {
+		t.Errorf("Total() = %v, want 5750.28", got)
+	}
+}
+
+// TestInvoiceBreakdown_Consumers checks that every format shows the
+// figures of the breakdown.
+func TestInvoiceBreakdown_Consumers(t *testing.T) {
+	inv := trickyInvoice()
+	lines := []string{"750.04", "1250.06"}
+	const total = "5750.28"
+
+	outputs := map[string]string{
+		"prompt": invoice.BuildPrompt(inv, "invoice.html"),
+		"email":  invoice.InvoiceEmailFields(inv).Total,
+		"hook":   strings.Join(invoice.HookEnv(inv, "a.html", "a.pdf"), "\n"),
+	}
+	var buf bytes.Buffer
+	if err := invoice.WriteText(&buf, inv); err != nil {
+		t.Fatal(err)
+	}
+	outputs["text"] = buf.String()
+	buf.Reset()
+	if err := invoice.WriteUBL(&buf, inv, invoice.UBLParty{Country: "US"}, invoice.UBLParty{Country: "US"}); err != nil {
+		t.Fatal(err)
+	}
+	outputs["ubl"] = buf.String()
+	buf.Reset()
+	if err := invoice.WriteQuickBooksIIF(&buf, inv, invoice.QuickBooksOptions{IncomeAccount: "Income", Item: "Consulting"}); err != nil {
+		t.Fatal(err)
+	}
+	outputs["iif"] = buf.String()
+	// The QR code is for euros; at a rate of 1 it shows the same total.
+	euro := *inv
+	euro.Exchange = &invoice.ExchangeRate{Currency: "EUR", Rate: 1}
+	epc, err := invoice.EPCPayload(&euro)
+	if err != nil {
+		t.Fatal(err)
+	}
+	outputs["epc"] = epc
+	data, err := json.Marshal(inv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	outputs["json"] = string(data)
+
+	for name, out := range outputs {
+		if !strings.Contains(out, total) {
+			t.Errorf("%s does not show the total %s:\n%s", name, total, out)
+		}
+		if strings.Contains(out, "5750.29") {
+			t.Errorf("%s shows the unrounded total 5750.29:\n%s", name, out)
+		}
+		if name == "email" || name == "hook" || name == "epc" {
+			continue
+		}
+		for _, l := range lines {
+			if !strings.Contains(out, l) {
+				t.Errorf("%s does not show the line amount %s:\n%s", name, l, out)
+			}
+		}
+	}
+}
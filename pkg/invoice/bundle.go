@@ -0,0 +1,246 @@
+package invoice
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// InvoiceFile is an invoice found on disk by FindInvoices.
+type InvoiceFile struct {
+	// CustomerSlug is the customer part of the file name, e.g. "acme-corp".
+	CustomerSlug string
+	// VendorSlug is the vendor part of a PerVendor invoice's file name,
+	// e.g. "jane-doe"; "" for any other invoice.
+	VendorSlug string
+	Year       int
+	Month      time.Month
+	// HTMLPath and PDFPath are the invoice's files, and JSONPath its JSON
+	// export; "" if there is none.
+	HTMLPath string
+	PDFPath  string
+	JSONPath string
+}
+
+// IsFor reports whether f is an invoice to customer.
+func (f InvoiceFile) IsFor(customer string) bool {
+	return f.CustomerSlug == customerSlug(customer)
+}
+
+// IsFrom reports whether f is vendor's PerVendor invoice.
+func (f InvoiceFile) IsFrom(vendor string) bool {
+	return f.VendorSlug != "" && f.VendorSlug == customerSlug(vendor)
+}
+
+// invoiceFileName matches the names OutputFilename gives invoice files.
+var invoiceFileName = regexp.MustCompile(`^invoice-(.+)-(\d{4})-(\d{2})\.(html|pdf|json)$`)
+
+// FindInvoices returns the invoices in dir for year, in order of month,
+// customer and vendor, with their JSON exports. A JSON export without an
+// HTML or PDF invoice is not an invoice. vendors are the vendors whose
+// PerVendor invoices name them after the customer: a file name that ends
+// in one of theirs is that vendor's invoice to the customer before it. If
+// customer is not empty, only that customer's invoices are returned.
+func FindInvoices(dir string, year int, customer string, vendors ...string) ([]InvoiceFile, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("listing invoices: %w", err)
+	}
+	// The longest vendor name is tried first, so "jane-doe" is not taken
+	// for "doe".
+	vendorSlugs := make([]string, 0, len(vendors))
+	for _, v := range vendors {
+		vendorSlugs = append(vendorSlugs, customerSlug(v))
+	}
+	sort.Slice(vendorSlugs, func(i, j int) bool { return len(vendorSlugs[i]) > len(vendorSlugs[j]) })
+	byKey := map[string]*InvoiceFile{}
+	for _, entry := range entries {
+		m := invoiceFileName.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || m == nil {
+			continue
+		}
+		y, _ := strconv.Atoi(m[2])
+		month, _ := strconv.Atoi(m[3])
+		if y != year || month < 1 || month > 12 {
+			continue
+		}
+		key := m[1] + "-" + m[3]
+		f := byKey[key]
+		if f == nil {
+			f = &InvoiceFile{CustomerSlug: m[1], Year: y, Month: time.Month(month)}
+			for _, v := range vendorSlugs {
+				if c, ok := strings.CutSuffix(m[1], "-"+v); ok && c != "" {
+					f.CustomerSlug, f.VendorSlug = c, v
+					break
+				}
+			}
+			if customer != "" && !f.IsFor(customer) {
+				continue
+			}
+			byKey[key] = f
+		}
+		path := filepath.Join(dir, entry.Name())
+		switch m[4] {
+		case "pdf":
+			f.PDFPath = path
+		case "json":
+			f.JSONPath = path
+		default:
+			f.HTMLPath = path
+		}
+	}
+	files := make([]InvoiceFile, 0, len(byKey))
+	for _, f := range byKey {
+		if f.HTMLPath != "" || f.PDFPath != "" {
+			files = append(files, *f)
+		}
+	}
+	sort.Slice(files, func(i, j int) bool {
+		if files[i].Month != files[j].Month {
+			return files[i].Month < files[j].Month
+		}
+		if files[i].CustomerSlug != files[j].CustomerSlug {
+			return files[i].CustomerSlug < files[j].CustomerSlug
+		}
+		return files[i].VendorSlug < files[j].VendorSlug
+	})
+	return files, nil
+}
+
+// pdfMerger describes an external program that can merge PDFs.
+type pdfMerger struct {
+	// Name identifies the program in messages.
+	Name string
+	// Args returns the arguments that merge inputs, in order, into outPath.
+	Args func(inputs []string, outPath string) []string
+}
+
+// pdfMergers are tried in order.
+var pdfMergers = []pdfMerger{
+	{Name: "pdfunite", Args: func(inputs []string, outPath string) []string {
+		return append(append([]string{}, inputs...), outPath)
+	}},
+	{Name: "qpdf", Args: func(inputs []string, outPath string) []string {
+		return append(append([]string{"--empty", "--pages"}, inputs...), "--", outPath)
+	}},
+	{Name: "gs", Args: func(inputs []string, outPath string) []string {
+		return append([]string{"-dBATCH", "-dNOPAUSE", "-dSAFER", "-q", "-sDEVICE=pdfwrite", "-sOutputFile=" + outPath}, inputs...)
+	}},
+}
+
+// MergePDFs merges inputs, in order, into one PDF at outPath with the first
+// of pdfunite, qpdf and Ghostscript that is installed. It returns the
+// program used. outPath is only replaced once the merged PDF is complete.
+func MergePDFs(ctx context.Context, inputs []string, outPath string) (string, error) {
+	if len(inputs) == 0 {
+		return "", fmt.Errorf("no PDFs to merge")
+	}
+	var merger pdfMerger
+	var bin string
+	for _, m := range pdfMergers {
+		if path, err := exec.LookPath(m.Name); err == nil {
+			merger, bin = m, path
+			break
+		}
+	}
+	if bin == "" {
+		return "", &NotInstalledError{
+			Tool:    "pdfunite",
+			Purpose: "invoicer merges the invoices into one PDF with pdfunite, qpdf or Ghostscript (gs).",
+			Install: []string{
+				"sudo apt install poppler-utils    (Debian, Ubuntu)",
+				"brew install poppler    (macOS)",
+			},
+		}
+	}
+	staged := stagingPath(outPath)
+	defer os.Remove(staged)
+	if err := run(command(ctx, bin, merger.Args(inputs, staged)...)); err != nil {
+		if ctx.Err() != nil {
+			return merger.Name, pdfToolStopped(ctx, merger.Name)
+		}
+		return merger.Name, fmt.Errorf("%s: %w", merger.Name, err)
+	}
+	if err := VerifyPDF(staged); err != nil {
+		return merger.Name, fmt.Errorf("%s exited cleanly but %w", merger.Name, err)
+	}
+	if err := os.Rename(staged, outPath); err != nil {
+		return merger.Name, fmt.Errorf("writing %s: %w", outPath, err)
+	}
+	return merger.Name, nil
+}
+
+// BundleMonth is one line of a bundle's cover page.
+type BundleMonth struct {
+	Month    time.Month
+	Customer string
+	// Number is the invoice number; "" for a month with no invoice.
+	Number string
+	// Total is the invoiced amount; nil if it is not known.
+	Total *float64
+}
+
+// coverTemplate lays out a bundle's cover page.
+var coverTemplate = template.Must(template.New("cover").Funcs(template.FuncMap{
+	"money": FormatMoney,
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 2cm; }
+table { border-collapse: collapse; width: 100%; }
+th, td { border-bottom: 1px solid #ccc; padding: 6px 8px; text-align: left; }
+td.amount, th.amount { text-align: right; }
+tfoot td { font-weight: bold; border-bottom: none; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<table>
+<thead><tr><th>Month</th><th>Customer</th><th>Invoice</th>{{if .Totals}}<th class="amount">Total</th>{{end}}</tr></thead>
+<tbody>
+{{- range .Months}}
+<tr><td>{{.Month}}</td><td>{{.Customer}}</td><td>{{if .Number}}{{.Number}}{{else}}No invoice{{end}}</td>{{if $.Totals}}<td class="amount">{{with .Total}}{{money .}}{{end}}</td>{{end}}</tr>
+{{- end}}
+</tbody>
+{{- if .Sum}}
+<tfoot><tr><td colspan="3">Total</td><td class="amount">{{money .Total}}</td></tr></tfoot>
+{{- end}}
+</table>
+</body>
+</html>
+`))
+
+// WriteBundleCover writes an HTML cover page titled title that lists months
+// with the totals that are known. The sum of the totals is only given if
+// every invoice's total is known, so it is never short of one.
+func WriteBundleCover(w io.Writer, title string, months []BundleMonth) error {
+	var total float64
+	totals, sum := false, true
+	for _, m := range months {
+		if m.Total != nil {
+			total += *m.Total
+			totals = true
+		} else if m.Number != "" {
+			sum = false
+		}
+	}
+	return coverTemplate.Execute(w, struct {
+		Title  string
+		Months []BundleMonth
+		Totals bool
+		Sum    bool
+		Total  float64
+	}{title, months, totals, totals && sum, total})
+}
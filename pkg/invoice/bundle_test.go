@@ -0,0 +1,239 @@
+package invoice_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+// fakeMergeScript records its arguments to args.txt beside itself and
+// writes a minimal PDF to $out, which findOut sets from the arguments.
+func fakeMergeScript(findOut string) string {
+	return `#!/bin/sh
+dir=${0%/*}
+printf '%s\n' "$@" > "$dir/args.txt"
+` + findOut + `
+printf '%%PDF-1.4\n%%%%EOF\n' > "$out"
+`
+}
+
+// fakeLastArgMergeScript writes to its last argument, as pdfunite and qpdf do.
+var fakeLastArgMergeScript = fakeMergeScript(`for out; do :; done`)
+
+func touch(t *testing.T, dir string, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		if err := os.WriteFile(filepath.Join(dir, name), minimalPDF(""), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestFindInvoices(t *testing.T) {
+	dir := t.TempDir()
+	touch(t, dir,
+		"invoice-acme-corp-2025-03.pdf",
+		"invoice-acme-corp-2025-03.html",
+		"invoice-acme-corp-2025-03.json",
+		"invoice-acme-corp-2025-04.json",
+		"invoice-acme-corp-2025-11.html",
+		"invoice-acme-corp-2025-01.pdf",
+		"invoice-side-client-2025-01.html",
+		"invoice-acme-corp-2024-12.pdf",
+		"invoice-acme-corp-2025-13.pdf",
+		"notes-2025-01.pdf",
+	)
+	if err := os.Mkdir(filepath.Join(dir, "invoice-acme-corp-2025-02.pdf"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := invoice.FindInvoices(dir, 2025, "")
+	if err != nil {
+		t.Fatalf("FindInvoices: %v", err)
+	}
+	var got []string
+	for _, f := range files {
+		got = append(got, strings.Join([]string{f.Month.String(), f.CustomerSlug, filepath.Base(f.HTMLPath), filepath.Base(f.PDFPath), filepath.Base(f.JSONPath)}, " "))
+	}
+	// April has a JSON export but no invoice.
+	want := []string{
+		"January acme-corp . invoice-acme-corp-2025-01.pdf .",
+		"January side-client invoice-side-client-2025-01.html . .",
+		"March acme-corp invoice-acme-corp-2025-03.html invoice-acme-corp-2025-03.pdf invoice-acme-corp-2025-03.json",
+		"November acme-corp invoice-acme-corp-2025-11.html . .",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("FindInvoices =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	files, err = invoice.FindInvoices(dir, 2025, "Side Client")
+	if err != nil {
+		t.Fatalf("FindInvoices: %v", err)
+	}
+	if len(files) != 1 || files[0].CustomerSlug != "side-client" || !files[0].IsFor("Side Client") {
+		t.Errorf("FindInvoices(Side Client) = %+v", files)
+	}
+}
+
+func TestMergePDFs_InputOrder(t *testing.T) {
+	tests := []struct {
+		tool   string
+		script string
+		want   string
+	}{
+		{"pdfunite", fakeLastArgMergeScript, "cover.pdf jan.pdf feb.pdf OUT"},
+		{"qpdf", fakeLastArgMergeScript, "--empty --pages cover.pdf jan.pdf feb.pdf -- OUT"},
+		{"gs", fakeMergeScript(`for arg; do case $arg in -sOutputFile=*) out=${arg#-sOutputFile=};; esac; done`),
+			"-dBATCH -dNOPAUSE -dSAFER -q -sDEVICE=pdfwrite -sOutputFile=OUT cover.pdf jan.pdf feb.pdf"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tool, func(t *testing.T) {
+			binDir := t.TempDir()
+			writeFakeTool(t, binDir, tt.tool, tt.script)
+			isolatePDFTools(t, binDir)
+			dir := t.TempDir()
+			var inputs []string
+			for _, name := range []string{"cover.pdf", "jan.pdf", "feb.pdf"} {
+				inputs = append(inputs, filepath.Join(dir, name))
+			}
+			out := filepath.Join(dir, "bundle.pdf")
+
+			tool, err := invoice.MergePDFs(context.Background(), inputs, out)
+			if err != nil {
+				t.Fatalf("MergePDFs: %v", err)
+			}
+			if tool != tt.tool {
+				t.Errorf("tool = %q, want %q", tool, tt.tool)
+			}
+			// The output is staged next to out under a random name.
+			args := strings.Join(readLines(t, filepath.Join(binDir, "args.txt")), " ")
+			args = regexp.MustCompile(regexp.QuoteMeta(out)+`\.tmp-[0-9a-f]+`).ReplaceAllString(args, "OUT")
+			args = strings.ReplaceAll(args, dir+string(filepath.Separator), "")
+			if args != tt.want {
+				t.Errorf("arguments = %q, want %q", args, tt.want)
+			}
+			if err := invoice.VerifyPDF(out); err != nil {
+				t.Errorf("the bundle should be written: %v", err)
+			}
+		})
+	}
+}
+
+func TestMergePDFs_Failure(t *testing.T) {
+	binDir := t.TempDir()
+	writeFakeTool(t, binDir, "pdfunite", "#!/bin/sh\necho 'Syntax Error: broken file' >&2\nexit 1\n")
+	isolatePDFTools(t, binDir)
+	dir := t.TempDir()
+	out := filepath.Join(dir, "bundle.pdf")
+
+	_, err := invoice.MergePDFs(context.Background(), []string{filepath.Join(dir, "a.pdf")}, out)
+	if err == nil || !strings.Contains(err.Error(), "pdfunite") || !strings.Contains(err.Error(), "broken file") {
+		t.Fatalf("expected the pdfunite failure, got %v", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) > 0 {
+		t.Errorf("nothing should be left behind, found %v", entries)
+	}
+}
+
+func TestMergePDFs_NotInstalled(t *testing.T) {
+	isolatePDFTools(t, t.TempDir())
+	var missing *invoice.NotInstalledError
+	_, err := invoice.MergePDFs(context.Background(), []string{"a.pdf"}, filepath.Join(t.TempDir(), "bundle.pdf"))
+	if !errors.As(err, &missing) || missing.Tool != "pdfunite" || !strings.Contains(err.Error(), "qpdf") {
+		t.Fatalf("expected pdfunite to be reported missing, got %v", err)
+	}
+}
+
+func TestFindInvoices_PerVendor(t *testing.T) {
+	dir := t.TempDir()
+	touch(t, dir,
+		"invoice-acme-corp-jane-doe-2025-01.pdf",
+		"invoice-acme-corp-doe-2025-01.pdf",
+		"invoice-acme-corp-2025-01.pdf",
+		"invoice-side-client-jane-doe-2025-02.html",
+	)
+
+	files, err := invoice.FindInvoices(dir, 2025, "Acme Corp", "Doe", "Jane Doe")
+	if err != nil {
+		t.Fatalf("FindInvoices: %v", err)
+	}
+	var got []string
+	for _, f := range files {
+		got = append(got, f.CustomerSlug+" "+f.VendorSlug)
+	}
+	// The agency's own invoice has no vendor, and Jane Doe's is not Doe's.
+	want := []string{"acme-corp ", "acme-corp doe", "acme-corp jane-doe"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("FindInvoices(Acme Corp) = %q, want %q", got, want)
+	}
+	if !files[2].IsFor("Acme Corp") || !files[2].IsFrom("Jane Doe") || files[1].IsFrom("Jane Doe") || files[0].IsFrom("Doe") {
+		t.Errorf("IsFor and IsFrom disagree with %+v", files)
+	}
+
+	// Without the vendors, the vendor is taken for part of the customer.
+	files, err = invoice.FindInvoices(dir, 2025, "Acme Corp")
+	if err != nil {
+		t.Fatalf("FindInvoices: %v", err)
+	}
+	if len(files) != 1 || files[0].VendorSlug != "" {
+		t.Errorf("FindInvoices(Acme Corp) without vendors = %+v, want the one invoice without a vendor", files)
+	}
+}
+
+func TestWriteBundleCover(t *testing.T) {
+	total := func(f float64) *float64 { return &f }
+	months := []invoice.BundleMonth{
+		{Month: time.January, Customer: "Acme Corp", Number: "INV-202501-acme-corp", Total: total(1200)},
+		{Month: time.February, Customer: "Acme Corp"},
+		{Month: time.March, Customer: "Acme <Corp>", Number: "INV-202503-acme-corp", Total: total(300.5)},
+	}
+
+	var b strings.Builder
+	if err := invoice.WriteBundleCover(&b, "Invoices to Acme Corp for 2025", months); err != nil {
+		t.Fatalf("WriteBundleCover: %v", err)
+	}
+	html := b.String()
+	for _, want := range []string{
+		"<h1>Invoices to Acme Corp for 2025</h1>",
+		"<td>January</td><td>Acme Corp</td><td>INV-202501-acme-corp</td><td class=\"amount\">$1200.00</td>",
+		"<td>February</td><td>Acme Corp</td><td>No invoice</td><td class=\"amount\"></td>",
+		"Acme &lt;Corp&gt;",
+		"<td colspan=\"3\">Total</td><td class=\"amount\">$1500.50</td>",
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("cover is missing %q:\n%s", want, html)
+		}
+	}
+	if strings.Index(html, "January") > strings.Index(html, "February") || strings.Index(html, "February") > strings.Index(html, "March") {
+		t.Errorf("months should be listed in the order given:\n%s", html)
+	}
+
+	// Without March's total, the sum would be short of it.
+	months[2].Total = nil
+	b.Reset()
+	if err := invoice.WriteBundleCover(&b, "Invoices for 2025", months); err != nil {
+		t.Fatalf("WriteBundleCover: %v", err)
+	}
+	html = b.String()
+	if !strings.Contains(html, "<td>March</td><td>Acme &lt;Corp&gt;</td><td>INV-202503-acme-corp</td><td class=\"amount\"></td>") || strings.Contains(html, "<tfoot>") {
+		t.Errorf("March's total and the sum should be left out:\n%s", html)
+	}
+
+	for i := range months {
+		months[i].Total = nil
+	}
+	b.Reset()
+	if err := invoice.WriteBundleCover(&b, "Invoices for 2025", months); err != nil {
+		t.Fatalf("WriteBundleCover: %v", err)
+	}
+	if strings.Contains(b.String(), "$") || strings.Contains(b.String(), "Total") {
+		t.Errorf("totals should be left out:\n%s", b.String())
+	}
+}
@@ -0,0 +1,184 @@
+//go:build unix
+
+package invoice_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+// outScript sets $out to the file the tool is asked to write: the one named
+// in the prompt for a backend, or the last argument for a PDF tool.
+const outScript = `for arg; do out=$arg; done
+case $out in *"to the file: "*) out=$(printf '%s\n' "$out" | sed -n 's/^.*to the file: //p') ;; esac
+`
+
+// slowScript writes a partial output file, starts a long sleep in the
+// background, records its pid in $FAKE_PID and waits for it.
+const slowScript = "#!/bin/sh\n" + outScript + `printf '<html><body>partial' > "$out"
+sleep 30 &
+echo $! > "$FAKE_PID"
+wait
+`
+
+// installSlowTool puts slowScript on PATH under name and returns the file
+// the background sleep's pid will be written to.
+func installSlowTool(t *testing.T, name string) string {
+	t.Helper()
+	bin := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bin, name), []byte(slowScript), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	pidFile := filepath.Join(bin, "pid")
+	t.Setenv("FAKE_PID", pidFile)
+	return pidFile
+}
+
+// cancelWhenStarted cancels once the pid file has been written.
+func cancelWhenStarted(t *testing.T, pidFile string) context.Context {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go func() {
+		for deadline := time.Now().Add(10 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+			if data, err := os.ReadFile(pidFile); err == nil && strings.HasSuffix(string(data), "\n") {
+				break
+			}
+		}
+		cancel()
+	}()
+	return ctx
+}
+
+// assertExited fails unless the process in pidFile is gone.
+func assertExited(t *testing.T, pidFile string) {
+	t.Helper()
+	data, err := os.ReadFile(pidFile)
+	if err != nil {
+		t.Fatalf("reading pid: %v", err)
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		t.Fatalf("parsing pid %q: %v", data, err)
+	}
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(20 * time.Millisecond) {
+		if syscall.Kill(pid, 0) != nil || isZombie(pid) {
+			return
+		}
+	}
+	t.Errorf("process %d started by the tool is still running", pid)
+}
+
+// isZombie reports whether pid has exited but not yet been reaped, which
+// can take a while when the orphan's new parent is slow to reap it.
+func isZombie(pid int) bool {
+	data, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "stat"))
+	return err == nil && strings.Contains(string(data), ") Z ")
+}
+
+func TestGenerate_CancelKillsOpencode(t *testing.T) {
+	outputPath := filepath.Join(t.TempDir(), "invoice.html")
+	pidFile := installSlowTool(t, "opencode")
+	ctx := cancelWhenStarted(t, pidFile)
+
+	start := time.Now()
+	_, err := invoice.Generate(ctx, "opencode", testInvoice(), outputPath, invoice.GenerateOptions{Model: "anthropic/claude-haiku-4-5"})
+	if err == nil || !strings.Contains(err.Error(), "canceled") {
+		t.Fatalf("expected a canceled error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("Generate took %s to return after cancellation", elapsed)
+	}
+	assertExited(t, pidFile)
+	assertNotWritten(t, outputPath)
+}
+
+func TestConvertToPDF_CancelKillsTool(t *testing.T) {
+	dir := t.TempDir()
+	htmlPath := filepath.Join(dir, "invoice.html")
+	pdfPath := filepath.Join(dir, "invoice.pdf")
+	if err := os.WriteFile(htmlPath, []byte("<html></html>"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	pidFile := installSlowTool(t, "wkhtmltopdf")
+	ctx := cancelWhenStarted(t, pidFile)
+
+	_, err := invoice.ConvertToPDF(ctx, htmlPath, pdfPath, invoice.PDFOptions{})
+	if err == nil || !strings.Contains(err.Error(), "canceled") {
+		t.Fatalf("expected a canceled error, got %v", err)
+	}
+	assertExited(t, pidFile)
+	if _, err := os.Stat(pdfPath); !os.IsNotExist(err) {
+		t.Errorf("partial PDF should be removed, stat err = %v", err)
+	}
+	if _, err := os.Stat(htmlPath); err != nil {
+		t.Errorf("the HTML input must be kept: %v", err)
+	}
+}
+
+func TestConvertToPDF_TimeoutKillsTool(t *testing.T) {
+	dir := t.TempDir()
+	htmlPath := filepath.Join(dir, "invoice.html")
+	pdfPath := filepath.Join(dir, "invoice.pdf")
+	if err := os.WriteFile(htmlPath, []byte("<html></html>"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	pidFile := installSlowTool(t, "wkhtmltopdf")
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	start := time.Now()
+	tool, err := invoice.ConvertToPDF(ctx, htmlPath, pdfPath, invoice.PDFOptions{})
+	if err == nil || !strings.Contains(err.Error(), "wkhtmltopdf timed out") || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected a timeout naming wkhtmltopdf, got %v", err)
+	}
+	if tool != "wkhtmltopdf" {
+		t.Errorf("tool = %q, want wkhtmltopdf", tool)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("ConvertToPDF took %s to return after the deadline", elapsed)
+	}
+	assertExited(t, pidFile)
+	if _, err := os.Stat(pdfPath); !os.IsNotExist(err) {
+		t.Errorf("partial PDF should be removed, stat err = %v", err)
+	}
+}
+
+// expensiveScript starts a long sleep, reports a step that costs a dollar,
+// then keeps running.
+const expensiveScript = "#!/bin/sh\n" + outScript + `printf '<html><body>partial' > "$out"
+sleep 30 &
+echo $! > "$FAKE_PID"
+echo '{"type":"step_finish","part":{"cost":1.0,"tokens":{"input":1000,"output":1000}}}'
+wait
+`
+
+func TestGenerate_BudgetKillsOpencode(t *testing.T) {
+	outputPath := filepath.Join(t.TempDir(), "invoice.html")
+	pidFile := installSlowTool(t, "opencode")
+	if err := os.WriteFile(filepath.Join(filepath.Dir(pidFile), "opencode"), []byte(expensiveScript), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	_, err := invoice.Generate(context.Background(), "opencode", testInvoice(), outputPath, invoice.GenerateOptions{Model: "anthropic/claude-haiku-4-5", MaxCost: 0.5})
+	var budget *invoice.BudgetError
+	if !errors.As(err, &budget) {
+		t.Fatalf("expected a BudgetError, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("Generate took %s to stop over budget", elapsed)
+	}
+	assertExited(t, pidFile)
+	assertNotWritten(t, outputPath)
+}
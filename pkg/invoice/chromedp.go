@@ -0,0 +1,134 @@
+package invoice
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+)
+
+// PDF engines: how ConvertToPDF drives the conversion.
+const (
+	// ExecPDFEngine runs a conversion tool as a separate program.
+	ExecPDFEngine = "exec"
+	// ChromedpPDFEngine prints the page from a headless Chrome driven over
+	// the DevTools protocol.
+	ChromedpPDFEngine = "chromedp"
+	// AutoPDFEngine tries ChromedpPDFEngine and falls back to ExecPDFEngine.
+	AutoPDFEngine = "auto"
+)
+
+// PDFEngines returns the names of the PDF engines.
+func PDFEngines() []string {
+	return []string{ExecPDFEngine, ChromedpPDFEngine, AutoPDFEngine}
+}
+
+// CheckPDFEngine returns an error if name is neither "" nor one of
+// PDFEngines.
+func CheckPDFEngine(name string) error {
+	if name == "" || slices.Contains(PDFEngines(), name) {
+		return nil
+	}
+	return fmt.Errorf("unknown PDF engine %q (use one of: %s)", name, strings.Join(PDFEngines(), ", "))
+}
+
+// browserPDFTools are the tools chromedp can drive.
+var browserPDFTools = []string{"chromium", "chrome", "edge"}
+
+// findBrowser returns the Chromium-based browser for chromedp to drive and
+// its executable, honoring opts.Tool and opts.ToolPath when they name one.
+func findBrowser(opts PDFOptions) (pdfTool, string, error) {
+	if opts.ToolPath != "" || (opts.Tool != "" && opts.Tool != AutoPDFTool) {
+		tool, path, err := selectPDFTool(opts)
+		if err != nil {
+			return pdfTool{}, "", err
+		}
+		if !slices.Contains(browserPDFTools, tool.Name) {
+			return pdfTool{}, "", fmt.Errorf("the chromedp engine drives Chromium, Chrome or Edge, not %s", tool.Name)
+		}
+		return tool, path, nil
+	}
+	for _, name := range browserPDFTools {
+		tool, _ := lookupPDFTool(name)
+		if path := tool.find(); path != "" {
+			return tool, path, nil
+		}
+	}
+	return pdfTool{}, "", &NotInstalledError{
+		Tool:    "Chrome",
+		Purpose: "The chromedp PDF engine prints the invoice with Chromium, Chrome or Edge in headless mode.",
+		Install: []string{
+			"sudo apt install chromium    (Debian, Ubuntu)",
+			"brew install --cask google-chrome    (macOS)",
+		},
+		Instead: "Or set pdf_engine: exec (or --pdf-engine exec) to use another conversion tool.",
+	}
+}
+
+// chromeFlag turns a command-line argument such as "--lang=de" into the
+// flag name and value chromedp expects.
+func chromeFlag(arg string) (string, any) {
+	name, value, ok := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+	if !ok {
+		return name, true
+	}
+	return name, value
+}
+
+// convertChromedp prints htmlPath to pdfPath from a headless browser driven
+// over the DevTools protocol. CSS @page rules set the paper size and margins.
+func convertChromedp(ctx context.Context, htmlPath, pdfPath string, opts PDFOptions) error {
+	tool, path, err := findBrowser(opts)
+	if err != nil {
+		return err
+	}
+	var pdf []byte
+	err = chromedpPage(ctx, tool, path, htmlPath, opts.Args, func(ctx context.Context) error {
+		var err error
+		pdf, _, err = page.PrintToPDF().WithPrintBackground(true).WithPreferCSSPageSize(true).Do(ctx)
+		if err != nil {
+			return fmt.Errorf("printing with %s: %w", tool.Name, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(pdfPath, pdf, 0o644)
+}
+
+// chromedpPage loads htmlPath in the headless browser at path, started with
+// args as flags, and runs render on it. It waits for the page to load and
+// its fonts to be ready first, so nothing is guessed with timers.
+func chromedpPage(ctx context.Context, tool pdfTool, path, htmlPath string, args []string, render chromedp.ActionFunc) error {
+	allocOpts := append(chromedp.DefaultExecAllocatorOptions[:], chromedp.ExecPath(path), chromedp.NoSandbox, chromedp.DisableGPU)
+	for _, arg := range args {
+		allocOpts = append(allocOpts, chromedp.Flag(chromeFlag(arg)))
+	}
+	allocCtx, cancelAlloc := chromedp.NewExecAllocator(ctx, allocOpts...)
+	defer cancelAlloc()
+	browserCtx, cancelBrowser := chromedp.NewContext(allocCtx)
+	defer cancelBrowser()
+
+	if err := chromedp.Run(browserCtx); err != nil {
+		return fmt.Errorf("starting %s: %w", tool.Name, err)
+	}
+	url := FileURL(htmlPath)
+	if err := chromedp.Run(browserCtx, chromedp.Navigate(url)); err != nil {
+		return fmt.Errorf("loading %s in %s: %w", url, tool.Name, err)
+	}
+	err := chromedp.Run(browserCtx,
+		chromedp.Evaluate(`document.fonts.ready.then(() => true)`, nil, func(p *runtime.EvaluateParams) *runtime.EvaluateParams {
+			return p.WithAwaitPromise(true)
+		}),
+	)
+	if err != nil {
+		return fmt.Errorf("waiting for fonts in %s: %w", tool.Name, err)
+	}
+	return chromedp.Run(browserCtx, render)
+}
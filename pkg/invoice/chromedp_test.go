@@ -0,0 +1,112 @@
+package invoice_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+func TestCheckPDFEngine(t *testing.T) {
+	for _, name := range append([]string{""}, invoice.PDFEngines()...) {
+		if err := invoice.CheckPDFEngine(name); err != nil {
+			t.Errorf("CheckPDFEngine(%q): %v", name, err)
+		}
+	}
+	if err := invoice.CheckPDFEngine("puppeteer"); err == nil || !strings.Contains(err.Error(), "exec, chromedp, auto") {
+		t.Errorf("expected the choices in the error, got %v", err)
+	}
+}
+
+func TestConvertToPDF_ChromedpWithoutBrowser(t *testing.T) {
+	isolatePDFTools(t, t.TempDir())
+	outDir := t.TempDir()
+	htmlPath := writeTestHTML(t, outDir)
+
+	_, err := invoice.ConvertToPDF(context.Background(), htmlPath, filepath.Join(outDir, "invoice.pdf"), invoice.PDFOptions{Engine: invoice.ChromedpPDFEngine})
+	var missing *invoice.NotInstalledError
+	if !errors.As(err, &missing) || missing.Tool != "Chrome" || !errors.Is(err, exec.ErrNotFound) {
+		t.Fatalf("expected Chrome to be reported missing, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "pdf_engine: exec") {
+		t.Errorf("message should suggest the exec engine:\n%v", err)
+	}
+}
+
+func TestConvertToPDF_ChromedpNeedsBrowser(t *testing.T) {
+	binDir := t.TempDir()
+	writeFakeTool(t, binDir, "wkhtmltopdf", fakeWkhtmltopdfScript)
+	isolatePDFTools(t, binDir)
+	outDir := t.TempDir()
+	htmlPath := writeTestHTML(t, outDir)
+
+	opts := invoice.PDFOptions{Engine: invoice.ChromedpPDFEngine, Tool: "wkhtmltopdf"}
+	_, err := invoice.ConvertToPDF(context.Background(), htmlPath, filepath.Join(outDir, "invoice.pdf"), opts)
+	if err == nil || !strings.Contains(err.Error(), "drives Chromium, Chrome or Edge, not wkhtmltopdf") {
+		t.Fatalf("expected a browser to be required, got %v", err)
+	}
+}
+
+func TestConvertToPDF_AutoEngineFallsBack(t *testing.T) {
+	binDir := t.TempDir()
+	writeFakeTool(t, binDir, "chromium", "#!/bin/sh\necho 'chromium: cannot open display' >&2\nexit 1\n")
+	writeFakeTool(t, binDir, "wkhtmltopdf", fakeWkhtmltopdfScript)
+	isolatePDFTools(t, binDir)
+	outDir := t.TempDir()
+	htmlPath := writeTestHTML(t, outDir)
+	pdfPath := filepath.Join(outDir, "invoice.pdf")
+
+	var fellBack error
+	opts := invoice.PDFOptions{Engine: invoice.AutoPDFEngine, OnFallback: func(err error) { fellBack = err }}
+	tool, err := invoice.ConvertToPDF(context.Background(), htmlPath, pdfPath, opts)
+	if err != nil {
+		t.Fatalf("ConvertToPDF() error: %v", err)
+	}
+	if tool != "wkhtmltopdf" {
+		t.Errorf("tool = %q, want the exec fallback", tool)
+	}
+	if fellBack == nil || !strings.Contains(fellBack.Error(), "starting chromium") {
+		t.Errorf("OnFallback got %v", fellBack)
+	}
+	if err := invoice.VerifyPDF(pdfPath); err != nil {
+		t.Error(err)
+	}
+}
+
+// TestConvertToPDF_Chromedp prints with a real browser, if one is installed.
+func TestConvertToPDF_Chromedp(t *testing.T) {
+	found := false
+	for _, bin := range []string{"chromium", "chromium-browser", "google-chrome", "google-chrome-stable", "msedge", "microsoft-edge"} {
+		if _, err := exec.LookPath(bin); err == nil {
+			found = true
+			break
+		}
+	}
+	if !found {
+		t.Skip("no Chromium-based browser installed")
+	}
+	outDir := filepath.Join(t.TempDir(), "Acme Corp")
+	if err := os.MkdirAll(outDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	htmlPath := filepath.Join(outDir, "invoice.html")
+	if err := os.WriteFile(htmlPath, []byte("<html><body><h1>Invoice</h1></body></html>"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	pdfPath := filepath.Join(outDir, "invoice.pdf")
+	tool, err := invoice.ConvertToPDF(context.Background(), htmlPath, pdfPath, invoice.PDFOptions{Engine: invoice.ChromedpPDFEngine, Args: []string{"--lang=en-US"}})
+	if err != nil {
+		t.Fatalf("ConvertToPDF() error: %v", err)
+	}
+	if tool != invoice.ChromedpPDFEngine {
+		t.Errorf("tool = %q", tool)
+	}
+	if err := invoice.VerifyPDF(pdfPath); err != nil {
+		t.Error(err)
+	}
+}
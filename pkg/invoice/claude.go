@@ -0,0 +1,139 @@
+package invoice
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+func init() {
+	Register("claude", claudeGenerator{})
+}
+
+// ClaudeExec is the function used to run the claude CLI subprocess.
+// It can be overridden in tests to use a fake binary.
+var ClaudeExec = func(ctx context.Context, model, dir, prompt string) ([]byte, error) {
+	cmd := command(ctx, "claude", "-p",
+		"--model", ClaudeModel(model),
+		"--output-format", "stream-json",
+		"--verbose",
+		"--allowedTools", "Write",
+		prompt,
+	)
+	cmd.Dir = dir
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	err := run(cmd)
+	return out.Bytes(), err
+}
+
+// ClaudeModel converts an opencode-formatted model stub to the name the claude
+// CLI expects by dropping the "anthropic/" provider prefix.
+func ClaudeModel(model string) string {
+	return strings.TrimPrefix(model, "anthropic/")
+}
+
+// claudeGenerator prompts the claude CLI to write the HTML invoice with its Write tool.
+type claudeGenerator struct{}
+
+func (claudeGenerator) Generate(ctx context.Context, inv *Invoice, outputPath string, opts GenerateOptions) (*Result, error) {
+	prompt := BuildPrompt(inv, outputPath)
+	opts.progress(ProgressEvent{Kind: ProgressPromptBuilt})
+
+	before := StatFile(outputPath)
+	opts.progress(ProgressEvent{Kind: ProgressStarted})
+	out, err := ClaudeExec(ctx, opts.Model, filepath.Dir(outputPath), prompt)
+	if errors.Is(err, exec.ErrNotFound) {
+		return nil, backendNotInstalled("claude",
+			"The claude CLI is Anthropic's coding agent, which writes the invoice HTML for the claude backend.",
+			"npm install -g @anthropic-ai/claude-code")
+	} else if err != nil {
+		return nil, fmt.Errorf("running claude: %w", err)
+	}
+
+	if err := checkClaudeOutput(out, outputPath, before); err != nil {
+		return nil, err
+	}
+	return &Result{Path: outputPath, Model: opts.Model}, nil
+}
+
+// claudeEvent represents a single JSON event line from claude --output-format stream-json.
+type claudeEvent struct {
+	Type    string        `json:"type"`
+	Message claudeMessage `json:"message"`
+}
+
+type claudeMessage struct {
+	Content []claudeContent `json:"content"`
+}
+
+// claudeContent is a content block of an assistant or user message.
+// Assistant messages carry tool_use blocks; user messages carry the tool_result blocks.
+type claudeContent struct {
+	Type      string          `json:"type"`
+	ID        string          `json:"id"`
+	Name      string          `json:"name"`
+	Input     json.RawMessage `json:"input"`
+	ToolUseID string          `json:"tool_use_id"`
+	IsError   bool            `json:"is_error"`
+}
+
+type claudeWriteInput struct {
+	FilePath string `json:"file_path"`
+}
+
+// CheckClaudeOutput parses the JSON lines from claude and verifies the file was written.
+// A Write tool use to expectedPath counts as successful once its tool result
+// arrives without an error.
+func CheckClaudeOutput(out []byte, expectedPath string) error {
+	return checkClaudeOutput(out, expectedPath, FileState{})
+}
+
+// checkClaudeOutput is CheckClaudeOutput for a file that was as before ahead
+// of the run; if it is unchanged, it does not count as written.
+func checkClaudeOutput(out []byte, expectedPath string, before FileState) error {
+	// IDs of Write tool uses targeting expectedPath.
+	writes := map[string]bool{}
+
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	for _, line := range lines {
+		if line == "" {
+			continue
+		}
+		var event claudeEvent
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			continue
+		}
+		for _, c := range event.Message.Content {
+			switch {
+			case event.Type == "assistant" && c.Type == "tool_use" && c.Name == "Write":
+				var input claudeWriteInput
+				if err := json.Unmarshal(c.Input, &input); err != nil {
+					continue
+				}
+				if input.FilePath == expectedPath {
+					writes[c.ID] = true
+				}
+			case event.Type == "user" && c.Type == "tool_result":
+				if writes[c.ToolUseID] && !c.IsError {
+					return nil
+				}
+			}
+		}
+	}
+
+	// Fallback: check if the file on disk changed during the run.
+	now := StatFile(expectedPath)
+	if now.Exists && !now.Same(before) {
+		return nil
+	}
+	if now.Exists {
+		return fmt.Errorf("claude did not write the HTML invoice to %s; the file already there predates this run", expectedPath)
+	}
+	return fmt.Errorf("claude did not write the HTML invoice to %s", expectedPath)
+}
@@ -0,0 +1,199 @@
+package invoice_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+// --- CheckClaudeOutput tests ---
+
+// makeClaudeToolUse creates a JSON line for a claude assistant message with a tool_use block.
+func makeClaudeToolUse(id, tool, filePath string) string {
+	input, _ := json.Marshal(map[string]string{"file_path": filePath, "content": "<html></html>"})
+	ev, _ := json.Marshal(map[string]any{
+		"type": "assistant",
+		"message": map[string]any{
+			"content": []map[string]any{
+				{"type": "tool_use", "id": id, "name": tool, "input": json.RawMessage(input)},
+			},
+		},
+	})
+	return string(ev)
+}
+
+// makeClaudeToolResult creates a JSON line for a claude user message with a tool_result block.
+func makeClaudeToolResult(id string, isError bool) string {
+	ev, _ := json.Marshal(map[string]any{
+		"type": "user",
+		"message": map[string]any{
+			"content": []map[string]any{
+				{"type": "tool_result", "tool_use_id": id, "content": "File created successfully", "is_error": isError},
+			},
+		},
+	})
+	return string(ev)
+}
+
+func claudeStream(lines ...string) []byte {
+	return []byte(strings.Join(lines, "\n"))
+}
+
+func TestCheckClaudeOutput_SuccessWithWriteEvent(t *testing.T) {
+	expectedPath := "/tmp/invoice.html"
+	out := claudeStream(
+		`{"type":"system","subtype":"init"}`,
+		makeClaudeToolUse("toolu_1", "Write", expectedPath),
+		makeClaudeToolResult("toolu_1", false),
+		`{"type":"result","subtype":"success","is_error":false}`,
+	)
+	if err := invoice.CheckClaudeOutput(out, expectedPath); err != nil {
+		t.Errorf("expected success, got error: %v", err)
+	}
+}
+
+func TestCheckClaudeOutput_FailsWhenWrongPath(t *testing.T) {
+	out := claudeStream(
+		makeClaudeToolUse("toolu_1", "Write", "/tmp/other.html"),
+		makeClaudeToolResult("toolu_1", false),
+	)
+	// No file on disk either, so should fail.
+	if err := invoice.CheckClaudeOutput(out, "/tmp/invoice.html"); err == nil {
+		t.Error("expected error for wrong path, got nil")
+	}
+}
+
+func TestCheckClaudeOutput_FailsWhenToolResultIsError(t *testing.T) {
+	expectedPath := "/tmp/invoice.html"
+	out := claudeStream(
+		makeClaudeToolUse("toolu_1", "Write", expectedPath),
+		makeClaudeToolResult("toolu_1", true),
+	)
+	// No file on disk, so should fail.
+	if err := invoice.CheckClaudeOutput(out, expectedPath); err == nil {
+		t.Error("expected error for failed tool result, got nil")
+	}
+}
+
+func TestCheckClaudeOutput_FailsWithoutToolResult(t *testing.T) {
+	expectedPath := "/tmp/invoice.html"
+	out := claudeStream(makeClaudeToolUse("toolu_1", "Write", expectedPath))
+	// The write was requested but never confirmed, and no file is on disk.
+	if err := invoice.CheckClaudeOutput(out, expectedPath); err == nil {
+		t.Error("expected error for unconfirmed write, got nil")
+	}
+}
+
+func TestCheckClaudeOutput_FallbackToFileExistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "invoice.html")
+	if err := os.WriteFile(path, []byte("<html></html>"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// No write event in output, but file exists on disk — should succeed.
+	if err := invoice.CheckClaudeOutput([]byte(""), path); err != nil {
+		t.Errorf("expected success via file existence fallback, got: %v", err)
+	}
+}
+
+func TestCheckClaudeOutput_FailsWhenNoEventAndNoFile(t *testing.T) {
+	err := invoice.CheckClaudeOutput([]byte(""), "/tmp/nonexistent-invoice-xyz.html")
+	if err == nil || !strings.Contains(err.Error(), "claude") {
+		t.Errorf("expected error naming claude, got %v", err)
+	}
+}
+
+func TestCheckClaudeOutput_IgnoresNonWriteTools(t *testing.T) {
+	expectedPath := "/tmp/invoice.html"
+	out := claudeStream(
+		makeClaudeToolUse("toolu_1", "Bash", expectedPath),
+		makeClaudeToolResult("toolu_1", false),
+	)
+	// No file on disk, so should fail.
+	if err := invoice.CheckClaudeOutput(out, expectedPath); err == nil {
+		t.Error("expected error when only non-write tool events present, got nil")
+	}
+}
+
+func TestCheckClaudeOutput_IgnoresInvalidJSON(t *testing.T) {
+	expectedPath := "/tmp/invoice.html"
+	out := []byte(fmt.Sprintf("not json\n%s\n{invalid}\n%s",
+		makeClaudeToolUse("toolu_1", "Write", expectedPath),
+		makeClaudeToolResult("toolu_1", false)))
+	if err := invoice.CheckClaudeOutput(out, expectedPath); err != nil {
+		t.Errorf("expected success despite invalid JSON lines, got: %v", err)
+	}
+}
+
+// --- claude generator tests ---
+
+func TestClaudeModel(t *testing.T) {
+	tests := map[string]string{
+		"anthropic/claude-haiku-4-5": "claude-haiku-4-5",
+		"claude-sonnet-4-6":          "claude-sonnet-4-6",
+		"sonnet":                     "sonnet",
+	}
+	for in, want := range tests {
+		if got := invoice.ClaudeModel(in); got != want {
+			t.Errorf("ClaudeModel(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestClaudeGenerator_CallsClaudeWithCorrectArgs(t *testing.T) {
+	inv := testInvoice()
+	tmpDir := t.TempDir()
+	outputPath := filepath.Join(tmpDir, "invoice-acme-corp-2025-01.html")
+
+	var capturedModel, capturedDir, capturedPrompt string
+
+	origExec := invoice.ClaudeExec
+	defer func() { invoice.ClaudeExec = origExec }()
+
+	invoice.ClaudeExec = func(ctx context.Context, model, dir, prompt string) ([]byte, error) {
+		capturedModel = model
+		capturedDir = dir
+		capturedPrompt = prompt
+		if err := os.WriteFile(promptedPath(prompt), []byte("<html>fake</html>"), 0o644); err != nil {
+			return nil, err
+		}
+		return claudeStream(
+			makeClaudeToolUse("toolu_1", "Write", promptedPath(prompt)),
+			makeClaudeToolResult("toolu_1", false),
+		), nil
+	}
+
+	opts := invoice.GenerateOptions{Model: "anthropic/claude-haiku-4-5"}
+	if _, err := invoice.Generate(context.Background(), "claude", inv, outputPath, opts); err != nil {
+		t.Fatalf("Generate() error: %v", err)
+	}
+	if capturedModel != "anthropic/claude-haiku-4-5" {
+		t.Errorf("model = %q, want %q", capturedModel, "anthropic/claude-haiku-4-5")
+	}
+	if capturedDir != tmpDir {
+		t.Errorf("dir = %q, want %q", capturedDir, tmpDir)
+	}
+	if !strings.Contains(capturedPrompt, "Acme Corp") {
+		t.Errorf("prompt does not contain customer, got: %s", capturedPrompt)
+	}
+}
+
+func TestClaudeGenerator_ReturnsErrorWhenClaudeFails(t *testing.T) {
+	origExec := invoice.ClaudeExec
+	defer func() { invoice.ClaudeExec = origExec }()
+
+	invoice.ClaudeExec = func(ctx context.Context, model, dir, prompt string) ([]byte, error) {
+		return nil, fmt.Errorf("exit status 1")
+	}
+
+	opts := invoice.GenerateOptions{Model: "anthropic/claude-haiku-4-5"}
+	_, err := invoice.Generate(context.Background(), "claude", testInvoice(), filepath.Join(t.TempDir(), "invoice.html"), opts)
+	if err == nil || !strings.Contains(err.Error(), "running claude") {
+		t.Errorf("expected error running claude, got %v", err)
+	}
+}
@@ -0,0 +1,84 @@
+package invoice
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Date formats, the styles an invoice's dates are shown in.
+const (
+	// DateLong shows dates as "January 6, 2025" and weeks as "Jan 6-12".
+	// It is the default.
+	DateLong = "long"
+	// DateISO shows dates as "2025-01-06".
+	DateISO = "iso"
+	// DateUS shows dates as "01/06/2025".
+	DateUS = "us"
+	// DateEU shows dates as "06.01.2025".
+	DateEU = "eu"
+)
+
+// DateFormats are the names of the date formats.
+var DateFormats = []string{DateLong, DateISO, DateUS, DateEU}
+
+// ValidateDateFormat returns an error if name is not one of DateFormats.
+func ValidateDateFormat(name string) error {
+	if !slices.Contains(DateFormats, name) {
+		return fmt.Errorf("unknown date format %q (valid: %s)", name, strings.Join(DateFormats, ", "))
+	}
+	return nil
+}
+
+// FormatDate returns t in the date format named format; "" is DateLong.
+// Every date an invoice shows is formatted here.
+func FormatDate(t time.Time, format string) string {
+	switch format {
+	case DateISO:
+		return t.Format(time.DateOnly)
+	case DateUS:
+		return t.Format("01/02/2006")
+	case DateEU:
+		return t.Format("02.01.2006")
+	}
+	return t.Format("January 2, 2006")
+}
+
+// WeekLabel returns the label of w in the date format named format; ""
+// is DateLong. The long label leaves out the year, and the month too if
+// the week is in one month, e.g. "Jan 6-12" or "Jan 27 - Feb 2"; the us
+// and eu labels give the week's first and last day, e.g. "06.01. -
+// 12.01.". A week across the new year shows both years, e.g. "Dec 29,
+// 2025 - Jan 4, 2026". The iso label is FormatWeekRange.
+func WeekLabel(w Week, format string) string {
+	crossYear := w.Start.Year() != w.End.Year()
+	switch format {
+	case DateISO:
+		return FormatWeekRange(w)
+	case DateUS, DateEU:
+		if crossYear {
+			return FormatDate(w.Start, format) + " - " + FormatDate(w.End, format)
+		}
+		layout := "01/02"
+		if format == DateEU {
+			layout = "02.01."
+		}
+		return w.Start.Format(layout) + " - " + w.End.Format(layout)
+	}
+	switch {
+	case crossYear:
+		return w.Start.Format("Jan 2, 2006") + " - " + w.End.Format("Jan 2, 2006")
+	case w.Start.Month() == w.End.Month():
+		return fmt.Sprintf("%s %d-%d", w.Start.Month().String()[:3], w.Start.Day(), w.End.Day())
+	}
+	return fmt.Sprintf("%s %d - %s %d",
+		w.Start.Month().String()[:3], w.Start.Day(),
+		w.End.Month().String()[:3], w.End.Day())
+}
+
+// FormatWeekRange returns w as an ISO 8601 interval of dates, e.g.
+// "2025-12-29/2026-01-04", for CSV and JSON.
+func FormatWeekRange(w Week) string {
+	return w.Start.Format(time.DateOnly) + "/" + w.End.Format(time.DateOnly)
+}
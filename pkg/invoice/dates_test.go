@@ -0,0 +1,106 @@
+package invoice_test
+
+import (
+	"cmp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+func TestDateFormats(t *testing.T) {
+	sameMonth := invoice.Week{Start: time.Date(2025, time.January, 6, 0, 0, 0, 0, time.UTC), End: time.Date(2025, time.January, 12, 0, 0, 0, 0, time.UTC)}
+	crossMonth := invoice.Week{Start: time.Date(2025, time.January, 27, 0, 0, 0, 0, time.UTC), End: time.Date(2025, time.February, 2, 0, 0, 0, 0, time.UTC)}
+	crossYear := invoice.Week{Start: time.Date(2025, time.December, 29, 0, 0, 0, 0, time.UTC), End: time.Date(2026, time.January, 4, 0, 0, 0, 0, time.UTC)}
+	tests := []struct {
+		format                        string
+		wantSame, wantCross, wantYear string
+		wantIssued, wantDue           string
+	}{
+		{"", "Jan 6-12", "Jan 27 - Feb 2", "Dec 29, 2025 - Jan 4, 2026", "February 1, 2025", "March 3, 2025"},
+		{invoice.DateLong, "Jan 6-12", "Jan 27 - Feb 2", "Dec 29, 2025 - Jan 4, 2026", "February 1, 2025", "March 3, 2025"},
+		{invoice.DateISO, "2025-01-06/2025-01-12", "2025-01-27/2025-02-02", "2025-12-29/2026-01-04", "2025-02-01", "2025-03-03"},
+		{invoice.DateUS, "01/06 - 01/12", "01/27 - 02/02", "12/29/2025 - 01/04/2026", "02/01/2025", "03/03/2025"},
+		{invoice.DateEU, "06.01. - 12.01.", "27.01. - 02.02.", "29.12.2025 - 04.01.2026", "01.02.2025", "03.03.2025"},
+	}
+	for _, tt := range tests {
+		t.Run(cmp.Or(tt.format, "default"), func(t *testing.T) {
+			if got := invoice.WeekLabel(sameMonth, tt.format); got != tt.wantSame {
+				t.Errorf("same-month label = %q, want %q", got, tt.wantSame)
+			}
+			if got := invoice.WeekLabel(crossMonth, tt.format); got != tt.wantCross {
+				t.Errorf("cross-month label = %q, want %q", got, tt.wantCross)
+			}
+			if got := invoice.WeekLabel(crossYear, tt.format); got != tt.wantYear {
+				t.Errorf("cross-year label = %q, want %q", got, tt.wantYear)
+			}
+
+			inv := jsonInvoice()
+			inv.DateFormat = tt.format
+			f := invoice.InvoiceEmailFields(inv)
+			if f.Issued != tt.wantIssued || f.Due != tt.wantDue {
+				t.Errorf("email dates = %q, %q, want %q, %q", f.Issued, f.Due, tt.wantIssued, tt.wantDue)
+			}
+			prompt := invoice.BuildPrompt(inv, "/tmp/invoice.html")
+			for _, want := range []string{"- Invoice Date: " + tt.wantIssued, "- Due Date: " + tt.wantDue, "  - " + tt.wantSame + ":"} {
+				if !strings.Contains(prompt, want) {
+					t.Errorf("prompt does not contain %q:\n%s", want, prompt)
+				}
+			}
+			var text strings.Builder
+			if err := invoice.WriteText(&text, inv); err != nil {
+				t.Fatalf("WriteText: %v", err)
+			}
+			if !strings.Contains(text.String(), "Date: "+tt.wantIssued) {
+				t.Errorf("text invoice does not show the issue date %q:\n%s", tt.wantIssued, text.String())
+			}
+		})
+	}
+}
+
+func TestFormatWeekLabelAndRange(t *testing.T) {
+	day := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 0, 0, 0, 0, time.UTC) }
+	tests := []struct {
+		name                 string
+		week                 invoice.Week
+		wantLabel, wantRange string
+	}{
+		{"same month", invoice.Week{Start: day(2025, time.March, 3), End: day(2025, time.March, 9)}, "Mar 3-9", "2025-03-03/2025-03-09"},
+		{"cross month", invoice.Week{Start: day(2025, time.March, 31), End: day(2025, time.April, 6)}, "Mar 31 - Apr 6", "2025-03-31/2025-04-06"},
+		{"cross year", invoice.Week{Start: day(2025, time.December, 29), End: day(2026, time.January, 4)}, "Dec 29, 2025 - Jan 4, 2026", "2025-12-29/2026-01-04"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := invoice.FormatWeekLabel(tt.week); got != tt.wantLabel {
+				t.Errorf("FormatWeekLabel = %q, want %q", got, tt.wantLabel)
+			}
+			if got := invoice.FormatWeekRange(tt.week); got != tt.wantRange {
+				t.Errorf("FormatWeekRange = %q, want %q", got, tt.wantRange)
+			}
+		})
+	}
+}
+
+func TestDateFormat_AmountsUnchanged(t *testing.T) {
+	inv := jsonInvoice()
+	want := inv.Breakdown()
+	for _, format := range invoice.DateFormats {
+		inv.DateFormat = format
+		got := inv.Breakdown()
+		if got.AmountDue != want.AmountDue || len(got.Lines) != len(want.Lines) {
+			t.Errorf("%s: breakdown = %+v, want %+v", format, got, want)
+		}
+	}
+}
+
+func TestValidateDateFormat(t *testing.T) {
+	for _, format := range invoice.DateFormats {
+		if err := invoice.ValidateDateFormat(format); err != nil {
+			t.Errorf("%s: %v", format, err)
+		}
+	}
+	if err := invoice.ValidateDateFormat("german"); err == nil || !strings.Contains(err.Error(), "valid: long, iso, us, eu") {
+		t.Errorf("expected an error listing the formats, got %v", err)
+	}
+}
@@ -0,0 +1,367 @@
+package invoice
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Security of the connection to an SMTP server.
+const (
+	// SMTPSecurityStartTLS upgrades a plain connection with STARTTLS, and
+	// fails if the server does not offer it.
+	SMTPSecurityStartTLS = "starttls"
+	// SMTPSecurityTLS connects with TLS from the start, usually on port 465.
+	SMTPSecurityTLS = "tls"
+	// SMTPSecurityNone sends everything in the clear. It is only meant for
+	// a relay on the local machine.
+	SMTPSecurityNone = "none"
+)
+
+// DefaultSMTPPort is the SMTP submission port, for STARTTLS.
+const DefaultSMTPPort = 587
+
+// SMTPTimeout bounds sending an email, connection included.
+const SMTPTimeout = time.Minute
+
+// SMTPSecurities returns the connection securities SMTPOptions can have.
+func SMTPSecurities() []string {
+	return []string{SMTPSecurityStartTLS, SMTPSecurityTLS, SMTPSecurityNone}
+}
+
+// ValidateSMTPSecurity checks that security is one of SMTPSecurities.
+func ValidateSMTPSecurity(security string) error {
+	if !slices.Contains(SMTPSecurities(), security) {
+		return fmt.Errorf("SMTP security %q is not valid (use %s)", security, strings.Join(SMTPSecurities(), ", "))
+	}
+	return nil
+}
+
+// ParseAddresses parses a comma-separated list of email addresses, e.g.
+// "ap@acme.example, Jane <jane@acme.example>", into the bare addresses.
+func ParseAddresses(list string) ([]string, error) {
+	parsed, err := mail.ParseAddressList(list)
+	if err != nil {
+		return nil, fmt.Errorf("email addresses %q: %w", list, err)
+	}
+	addrs := make([]string, len(parsed))
+	for i, a := range parsed {
+		addrs[i] = a.Address
+	}
+	return addrs, nil
+}
+
+// Steps of sending an email that can fail, named in an EmailError.
+const (
+	EmailStepConnection = "connection"
+	EmailStepAuth       = "authentication"
+	EmailStepSender     = "sender"
+	EmailStepRecipient  = "recipient"
+	EmailStepMessage    = "message"
+)
+
+// EmailError is a failed step of sending an email.
+type EmailError struct {
+	// Step is the step that failed, e.g. EmailStepRecipient.
+	Step string
+	// Recipient is the address the server rejected, for
+	// EmailStepRecipient.
+	Recipient string
+	// Err is what went wrong, e.g. the server's reply.
+	Err error
+}
+
+func (e *EmailError) Error() string {
+	switch e.Step {
+	case EmailStepConnection:
+		return "connecting to the SMTP server failed: " + e.Err.Error()
+	case EmailStepAuth:
+		return "SMTP authentication failed (check the username and password): " + e.Err.Error()
+	case EmailStepSender:
+		return "the SMTP server rejected the sender: " + e.Err.Error()
+	case EmailStepRecipient:
+		return fmt.Sprintf("the SMTP server rejected recipient %s: %v", e.Recipient, e.Err)
+	}
+	return "the SMTP server rejected the message: " + e.Err.Error()
+}
+
+func (e *EmailError) Unwrap() error { return e.Err }
+
+// SMTPOptions say how to reach an SMTP server.
+type SMTPOptions struct {
+	// Host and Port are the server's address; Port 0 means
+	// DefaultSMTPPort.
+	Host string
+	Port int
+	// Security is one of SMTPSecurities; "" means SMTPSecurityStartTLS.
+	Security string
+	// Username and Password sign in with PLAIN authentication. No
+	// authentication is done without a Username.
+	Username string
+	Password string
+	// TLSConfig configures TLS; nil means checking the certificate against
+	// Host. Tests set it to trust their server.
+	TLSConfig *tls.Config
+}
+
+// Attachment is a file attached to an email.
+type Attachment struct {
+	// Name is the file name the recipient sees.
+	Name string
+	// ContentType is the MIME type, e.g. "application/pdf".
+	ContentType string
+	// Data is the file's content.
+	Data []byte
+}
+
+// Email is a plain-text email with attachments.
+type Email struct {
+	From        string
+	To          []string
+	Subject     string
+	Body        string
+	Attachments []Attachment
+}
+
+// Message returns e as a MIME message: a multipart/mixed message with the
+// body as quoted-printable UTF-8 text, followed by the attachments in
+// base64.
+func (e *Email) Message() ([]byte, error) {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	header := []struct{ key, value string }{
+		{"From", e.From},
+		{"To", strings.Join(e.To, ", ")},
+		{"Subject", mime.QEncoding.Encode("utf-8", e.Subject)},
+		{"Date", Now().Format(time.RFC1123Z)},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", mime.FormatMediaType("multipart/mixed", map[string]string{"boundary": mw.Boundary()})},
+	}
+	for _, h := range header {
+		fmt.Fprintf(&buf, "%s: %s\r\n", h.key, h.value)
+	}
+	buf.WriteString("\r\n")
+
+	part, err := mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/plain; charset=utf-8"},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	qp := quotedprintable.NewWriter(part)
+	if _, err := qp.Write([]byte(strings.ReplaceAll(e.Body, "\n", "\r\n"))); err != nil {
+		return nil, err
+	}
+	if err := qp.Close(); err != nil {
+		return nil, err
+	}
+
+	for _, a := range e.Attachments {
+		part, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {mime.FormatMediaType(a.ContentType, map[string]string{"name": a.Name})},
+			"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": a.Name})},
+			"Content-Transfer-Encoding": {"base64"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		encoded := base64.StdEncoding.EncodeToString(a.Data)
+		for len(encoded) > 76 {
+			fmt.Fprintf(part, "%s\r\n", encoded[:76])
+			encoded = encoded[76:]
+		}
+		fmt.Fprintf(part, "%s\r\n", encoded)
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// SendEmail sends e through the SMTP server opts describe. A failure is an
+// EmailError that says whether connecting, authentication, the sender, a
+// recipient or the message was the problem.
+func SendEmail(ctx context.Context, opts SMTPOptions, e *Email) error {
+	if opts.Host == "" {
+		return errors.New("an SMTP host is required")
+	}
+	if len(e.To) == 0 {
+		return errors.New("an email needs at least one recipient")
+	}
+	security := opts.Security
+	if security == "" {
+		security = SMTPSecurityStartTLS
+	}
+	if err := ValidateSMTPSecurity(security); err != nil {
+		return err
+	}
+	msg, err := e.Message()
+	if err != nil {
+		return err
+	}
+	port := opts.Port
+	if port == 0 {
+		port = DefaultSMTPPort
+	}
+	tlsConfig := opts.TLSConfig
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{ServerName: opts.Host}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, SMTPTimeout)
+	defer cancel()
+	addr := net.JoinHostPort(opts.Host, strconv.Itoa(port))
+	var conn net.Conn
+	if security == SMTPSecurityTLS {
+		conn, err = (&tls.Dialer{Config: tlsConfig}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return &EmailError{Step: EmailStepConnection, Err: err}
+	}
+	deadline, _ := ctx.Deadline()
+	conn.SetDeadline(deadline)
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	c, err := smtp.NewClient(conn, opts.Host)
+	if err != nil {
+		conn.Close()
+		return &EmailError{Step: EmailStepConnection, Err: err}
+	}
+	defer c.Close()
+	if err := c.Hello("localhost"); err != nil {
+		return &EmailError{Step: EmailStepConnection, Err: err}
+	}
+	if security == SMTPSecurityStartTLS {
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			return &EmailError{Step: EmailStepConnection, Err: fmt.Errorf("%s does not offer STARTTLS (set the security to tls for implicit TLS)", opts.Host)}
+		}
+		if err := c.StartTLS(tlsConfig); err != nil {
+			return &EmailError{Step: EmailStepConnection, Err: err}
+		}
+	}
+	if opts.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", opts.Username, opts.Password, opts.Host)); err != nil {
+			return &EmailError{Step: EmailStepAuth, Err: err}
+		}
+	}
+	from, err := mail.ParseAddress(e.From)
+	if err != nil {
+		return &EmailError{Step: EmailStepSender, Err: err}
+	}
+	if err := c.Mail(from.Address); err != nil {
+		return &EmailError{Step: EmailStepSender, Err: err}
+	}
+	for _, to := range e.To {
+		if err := c.Rcpt(to); err != nil {
+			return &EmailError{Step: EmailStepRecipient, Recipient: to, Err: err}
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return &EmailError{Step: EmailStepMessage, Err: err}
+	}
+	if _, err := w.Write(msg); err != nil {
+		return &EmailError{Step: EmailStepMessage, Err: err}
+	}
+	if err := w.Close(); err != nil {
+		return &EmailError{Step: EmailStepMessage, Err: err}
+	}
+	return c.Quit()
+}
+
+// Default templates of an invoice email.
+const (
+	DefaultEmailSubject = "Invoice {{.Number}} — {{.Month}} {{.Year}}"
+	DefaultEmailBody    = `Hello,
+
+Please find attached invoice {{.Number}} for {{.Month}} {{.Year}}, for {{.Total}}{{with .Due}}, due {{.}}{{end}}.
+
+Thank you,
+{{.Vendor}}
+`
+)
+
+// EmailFields are the invoice fields the subject and body templates of an
+// invoice email can use, e.g. {{.Number}}.
+type EmailFields struct {
+	Number   string
+	Vendor   string
+	Customer string
+	Month    string
+	Year     int
+	Hours    string
+	Total    string
+	// Issued and Due are dates in the invoice's date format, such as
+	// "February 1, 2025"; Due is "" if the invoice has no due date.
+	Issued string
+	Due    string
+}
+
+// InvoiceEmailFields returns the fields of inv for an email template.
+func InvoiceEmailFields(inv *Invoice) EmailFields {
+	var hours float64
+	for _, l := range inv.LineItems() {
+		if l.Unit == UnitHour {
+			hours += l.Quantity
+		}
+	}
+	f := EmailFields{
+		Number:   InvoiceNumber(inv),
+		Vendor:   inv.Vendor,
+		Customer: inv.Customer,
+		Month:    inv.Month.String(),
+		Year:     inv.Year,
+		Hours:    inv.FormatHours(hours),
+		Total:    inv.FormatAmountDue(inv.Breakdown()),
+		Issued:   FormatDate(inv.IssueDate(), inv.DateFormat),
+	}
+	if !inv.Due.IsZero() {
+		f.Due = FormatDate(inv.Due, inv.DateFormat)
+	}
+	return f
+}
+
+// RenderEmail fills in the subject and body templates with inv's fields.
+// An empty template means the default.
+func RenderEmail(inv *Invoice, subject, body string) (string, string, error) {
+	if subject == "" {
+		subject = DefaultEmailSubject
+	}
+	if body == "" {
+		body = DefaultEmailBody
+	}
+	fields := InvoiceEmailFields(inv)
+	var out [2]string
+	for i, t := range []struct{ name, text string }{{"subject", subject}, {"body", body}} {
+		tmpl, err := template.New(t.name).Option("missingkey=error").Parse(t.text)
+		if err != nil {
+			return "", "", fmt.Errorf("email %s template: %w", t.name, err)
+		}
+		var buf strings.Builder
+		if err := tmpl.Execute(&buf, fields); err != nil {
+			return "", "", fmt.Errorf("email %s template: %w", t.name, err)
+		}
+		out[i] = buf.String()
+	}
+	// A subject is one header line, whatever the template left in it.
+	return strings.Join(strings.Fields(out[0]), " "), out[1], nil
+}
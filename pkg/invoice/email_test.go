@@ -0,0 +1,345 @@
+package invoice_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/http/httptest"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+// smtpServer is an in-memory SMTP server for tests. It accepts the user
+// "jane" with the password "secret", rejects recipients at reject.example,
+// and records the messages it receives.
+type smtpServer struct {
+	// Port is the port the server listens on 127.0.0.1.
+	Port int
+	// TLSConfig trusts the server's certificate.
+	TLSConfig *tls.Config
+	// Messages are the messages the server received.
+	Messages [][]byte
+	// Recipients are the recipients of the messages.
+	Recipients [][]string
+
+	cert     tls.Certificate
+	startTLS bool
+	done     chan struct{}
+}
+
+// newSMTPServer starts a test SMTP server. With security
+// invoice.SMTPSecurityTLS it speaks TLS from the start, and with
+// invoice.SMTPSecurityStartTLS it offers STARTTLS.
+func newSMTPServer(t *testing.T, security string) *smtpServer {
+	t.Helper()
+	https := httptest.NewUnstartedServer(nil)
+	https.StartTLS()
+	pool := x509.NewCertPool()
+	pool.AddCert(https.Certificate())
+	s := &smtpServer{
+		TLSConfig: &tls.Config{RootCAs: pool, ServerName: "127.0.0.1"},
+		cert:      https.TLS.Certificates[0],
+		startTLS:  security == invoice.SMTPSecurityStartTLS,
+		done:      make(chan struct{}),
+	}
+	https.Close()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if security == invoice.SMTPSecurityTLS {
+		ln = tls.NewListener(ln, &tls.Config{Certificates: []tls.Certificate{s.cert}})
+	}
+	s.Port = ln.Addr().(*net.TCPAddr).Port
+	go func() {
+		defer close(s.done)
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		s.serve(conn)
+	}()
+	t.Cleanup(func() {
+		ln.Close()
+		<-s.done
+	})
+	return s
+}
+
+// serve speaks SMTP on conn until the client quits.
+func (s *smtpServer) serve(conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+	r := bufio.NewReader(conn)
+	reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+	reply("220 localhost ESMTP test")
+	var to []string
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO":
+			if s.startTLS {
+				if _, ok := conn.(*tls.Conn); !ok {
+					reply("250-localhost")
+					reply("250 STARTTLS")
+					continue
+				}
+			}
+			reply("250-localhost")
+			reply("250 AUTH PLAIN")
+		case "STARTTLS":
+			reply("220 ready")
+			tc := tls.Server(conn, &tls.Config{Certificates: []tls.Certificate{s.cert}})
+			if err := tc.Handshake(); err != nil {
+				return
+			}
+			conn, r = tc, bufio.NewReader(tc)
+			conn.SetDeadline(time.Now().Add(10 * time.Second))
+		case "AUTH":
+			creds, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(arg, "PLAIN "))
+			if string(creds) == "\x00jane\x00secret" {
+				reply("235 2.7.0 Authentication successful")
+			} else {
+				reply("535 5.7.8 Authentication credentials invalid")
+			}
+		case "MAIL":
+			reply("250 OK")
+		case "RCPT":
+			if strings.Contains(arg, "@reject.example") {
+				reply("550 5.1.1 No such user")
+				continue
+			}
+			to = append(to, strings.Trim(strings.TrimPrefix(arg, "TO:"), "<>"))
+			reply("250 OK")
+		case "DATA":
+			reply("354 Go ahead")
+			var msg bytes.Buffer
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				msg.WriteString(strings.TrimPrefix(l, "."))
+			}
+			s.Messages = append(s.Messages, msg.Bytes())
+			s.Recipients = append(s.Recipients, to)
+			reply("250 OK")
+		case "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("502 Command not implemented")
+		}
+	}
+}
+
+// options returns SMTPOptions for the server, signing in as user.
+func (s *smtpServer) options(security, user, password string) invoice.SMTPOptions {
+	return invoice.SMTPOptions{Host: "127.0.0.1", Port: s.Port, Security: security, Username: user, Password: password, TLSConfig: s.TLSConfig}
+}
+
+// wait waits for the server to finish the connection.
+func (s *smtpServer) wait() { <-s.done }
+
+func testEmail() *invoice.Email {
+	return &invoice.Email{
+		From:    "Jane Smith <jane@smith.example>",
+		To:      []string{"ap@acme.example"},
+		Subject: "Invoice INV-202501-acme-corp — January 2025",
+		Body:    "Hello,\n\nPlease find the invoice attached.\n",
+		Attachments: []invoice.Attachment{
+			{Name: "invoice-acme-corp-2025-01.pdf", ContentType: "application/pdf", Data: bytes.Repeat([]byte("%PDF-1.7 \x00\xff"), 20)},
+			{Name: "invoice-acme-corp-2025-01.html", ContentType: "text/html", Data: []byte("<html></html>")},
+		},
+	}
+}
+
+func TestSendEmail(t *testing.T) {
+	for _, security := range invoice.SMTPSecurities() {
+		t.Run(security, func(t *testing.T) {
+			srv := newSMTPServer(t, security)
+			e := testEmail()
+			if err := invoice.SendEmail(context.Background(), srv.options(security, "jane", "secret"), e); err != nil {
+				t.Fatalf("SendEmail: %v", err)
+			}
+			srv.wait()
+			if len(srv.Messages) != 1 {
+				t.Fatalf("server received %d messages", len(srv.Messages))
+			}
+			if got := strings.Join(srv.Recipients[0], ","); got != "ap@acme.example" {
+				t.Errorf("recipients = %q", got)
+			}
+			checkEmailMessage(t, srv.Messages[0], e)
+		})
+	}
+}
+
+// checkEmailMessage checks that msg is e as a multipart/mixed message
+// with the body followed by the attachments.
+func checkEmailMessage(t *testing.T, msg []byte, e *invoice.Email) {
+	t.Helper()
+	m, err := mail.ReadMessage(bytes.NewReader(msg))
+	if err != nil {
+		t.Fatalf("reading message: %v", err)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(m.Header.Get("Subject"))
+	if err != nil || subject != e.Subject {
+		t.Errorf("Subject = %q (%v), want %q", subject, err, e.Subject)
+	}
+	if got := m.Header.Get("To"); got != "ap@acme.example" {
+		t.Errorf("To = %q", got)
+	}
+	mediaType, params, err := mime.ParseMediaType(m.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/mixed" {
+		t.Fatalf("Content-Type = %q", m.Header.Get("Content-Type"))
+	}
+	mr := multipart.NewReader(m.Body, params["boundary"])
+
+	body, err := mr.NextPart()
+	if err != nil {
+		t.Fatalf("body part: %v", err)
+	}
+	if ct := body.Header.Get("Content-Type"); ct != "text/plain; charset=utf-8" {
+		t.Errorf("body Content-Type = %q", ct)
+	}
+	text, _ := io.ReadAll(body) // NextPart decodes quoted-printable.
+	if got := strings.ReplaceAll(string(text), "\r\n", "\n"); got != e.Body {
+		t.Errorf("body = %q, want %q", got, e.Body)
+	}
+
+	for _, want := range e.Attachments {
+		part, err := mr.NextPart()
+		if err != nil {
+			t.Fatalf("attachment %s: %v", want.Name, err)
+		}
+		if part.FileName() != want.Name {
+			t.Errorf("attachment file name = %q, want %q", part.FileName(), want.Name)
+		}
+		if ct, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type")); ct != want.ContentType {
+			t.Errorf("attachment %s Content-Type = %q", want.Name, ct)
+		}
+		encoded, _ := io.ReadAll(part)
+		data, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(string(encoded), "\r\n", ""))
+		if err != nil || !bytes.Equal(data, want.Data) {
+			t.Errorf("attachment %s bytes differ (%v)", want.Name, err)
+		}
+	}
+	if _, err := mr.NextPart(); err != io.EOF {
+		t.Errorf("expected no more parts, got %v", err)
+	}
+}
+
+func TestSendEmail_Failures(t *testing.T) {
+	tests := []struct {
+		name      string
+		user      string
+		to        string
+		wantStep  string
+		wantError string
+	}{
+		{"bad password", "nobody", "ap@acme.example", invoice.EmailStepAuth, "SMTP authentication failed"},
+		{"rejected recipient", "jane", "ap@reject.example", invoice.EmailStepRecipient, "rejected recipient ap@reject.example: 550"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newSMTPServer(t, invoice.SMTPSecurityNone)
+			e := testEmail()
+			e.To = []string{tt.to}
+			err := invoice.SendEmail(context.Background(), srv.options(invoice.SMTPSecurityNone, tt.user, "secret"), e)
+			var eerr *invoice.EmailError
+			if !errors.As(err, &eerr) || eerr.Step != tt.wantStep {
+				t.Fatalf("expected a %s EmailError, got %v", tt.wantStep, err)
+			}
+			if !strings.Contains(err.Error(), tt.wantError) {
+				t.Errorf("error = %v, want %q", err, tt.wantError)
+			}
+			srv.wait()
+			if len(srv.Messages) != 0 {
+				t.Error("nothing should have been delivered")
+			}
+		})
+	}
+}
+
+func TestSendEmail_ConnectionFailures(t *testing.T) {
+	// A port nothing listens on.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+
+	// A server without STARTTLS, which starttls must not fall back from.
+	plain := newSMTPServer(t, invoice.SMTPSecurityNone)
+
+	for name, opts := range map[string]invoice.SMTPOptions{
+		"refused":     {Host: "127.0.0.1", Port: closed, Security: invoice.SMTPSecurityNone},
+		"no starttls": {Host: "127.0.0.1", Port: plain.Port, Security: invoice.SMTPSecurityStartTLS},
+	} {
+		t.Run(name, func(t *testing.T) {
+			err := invoice.SendEmail(context.Background(), opts, testEmail())
+			var eerr *invoice.EmailError
+			if !errors.As(err, &eerr) || eerr.Step != invoice.EmailStepConnection {
+				t.Errorf("expected a connection EmailError, got %v", err)
+			}
+		})
+	}
+}
+
+func TestRenderEmail(t *testing.T) {
+	inv := jsonInvoice()
+	subject, body, err := invoice.RenderEmail(inv, "", "")
+	if err != nil {
+		t.Fatalf("RenderEmail: %v", err)
+	}
+	if want := "Invoice INV-202501-acme-corp — January 2025"; subject != want {
+		t.Errorf("subject = %q, want %q", subject, want)
+	}
+	if want := "invoice INV-202501-acme-corp for January 2025, for $10800.00, due March 3, 2025."; !strings.Contains(body, want) {
+		t.Errorf("body does not contain %q:\n%s", want, body)
+	}
+
+	subject, body, err = invoice.RenderEmail(inv, "{{.Customer}}\n{{.Number}}", "{{.Hours}} hours, issued {{.Issued}}")
+	if err != nil {
+		t.Fatalf("RenderEmail: %v", err)
+	}
+	if subject != "Acme Corp INV-202501-acme-corp" || body != "72.0 hours, issued February 1, 2025" {
+		t.Errorf("got %q / %q", subject, body)
+	}
+
+	if _, _, err := invoice.RenderEmail(inv, "{{.Amount}}", ""); err == nil || !strings.Contains(err.Error(), "email subject template") {
+		t.Errorf("expected a template error, got %v", err)
+	}
+}
+
+func TestParseAddresses(t *testing.T) {
+	got, err := invoice.ParseAddresses("ap@acme.example, Jane Doe <jane@acme.example>")
+	if err != nil || strings.Join(got, ",") != "ap@acme.example,jane@acme.example" {
+		t.Errorf("ParseAddresses = %q, %v", got, err)
+	}
+	if _, err := invoice.ParseAddresses("not an address"); err == nil {
+		t.Error("expected an error")
+	}
+}
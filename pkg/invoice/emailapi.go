@@ -0,0 +1,288 @@
+package invoice
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/mail"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Providers invoice emails can be sent through.
+const (
+	EmailProviderSMTP     = "smtp"
+	EmailProviderGmail    = "gmail"
+	EmailProviderSendGrid = "sendgrid"
+)
+
+// DefaultEmailProvider is the provider of invoice emails if none is
+// configured.
+const DefaultEmailProvider = EmailProviderSMTP
+
+// EmailProviders returns the providers invoice emails can be sent through.
+func EmailProviders() []string {
+	return []string{EmailProviderSMTP, EmailProviderGmail, EmailProviderSendGrid}
+}
+
+// ValidateEmailProvider checks that provider is one of EmailProviders.
+func ValidateEmailProvider(provider string) error {
+	if !slices.Contains(EmailProviders(), provider) {
+		return fmt.Errorf("email provider %q is not valid (use %s)", provider, strings.Join(EmailProviders(), ", "))
+	}
+	return nil
+}
+
+// Base URLs of the email APIs.
+const (
+	DefaultGmailURL    = "https://gmail.googleapis.com"
+	DefaultSendGridURL = "https://api.sendgrid.com"
+)
+
+// EmailAPITimeout bounds each request to an email API.
+const EmailAPITimeout = time.Minute
+
+// EmailAPIError is an email an email API did not send.
+type EmailAPIError struct {
+	// Provider is the API, e.g. EmailProviderGmail.
+	Provider string
+	// StatusCode is the HTTP status of the API's reply, or 0 if there was
+	// none.
+	StatusCode int
+	// Message is what went wrong, with what to do about it where the
+	// problem is a known one.
+	Message string
+}
+
+func (e *EmailAPIError) Error() string {
+	name := "Gmail"
+	if e.Provider == EmailProviderSendGrid {
+		name = "SendGrid"
+	}
+	return fmt.Sprintf("sending the email with %s failed: %s", name, e.Message)
+}
+
+// GmailClient sends email with the Gmail API, as the user an OAuth access
+// token is for.
+type GmailClient struct {
+	// Token is an OAuth access token with the gmail.send scope.
+	Token string
+	// BaseURL is the API's base URL; "" means DefaultGmailURL. Tests point
+	// it at a fake server.
+	BaseURL string
+	// HTTP sends the requests; nil means a client with EmailAPITimeout.
+	HTTP *http.Client
+}
+
+// Send sends e, as the same MIME message the SMTP path sends, and returns
+// the ID Gmail gave it.
+func (c *GmailClient) Send(ctx context.Context, e *Email) (string, error) {
+	if c.Token == "" {
+		return "", &EmailAPIError{Provider: EmailProviderGmail, Message: "no OAuth token is configured"}
+	}
+	msg, err := e.Message()
+	if err != nil {
+		return "", err
+	}
+	body, err := json.Marshal(map[string]string{"raw": base64.URLEncoding.EncodeToString(msg)})
+	if err != nil {
+		return "", err
+	}
+	resp, data, err := emailAPIPost(ctx, c.HTTP, c.BaseURL, DefaultGmailURL, "/gmail/v1/users/me/messages/send", c.Token, body)
+	if err != nil {
+		return "", &EmailAPIError{Provider: EmailProviderGmail, Message: err.Error()}
+	}
+	if resp.StatusCode/100 != 2 {
+		return "", gmailError(resp, data)
+	}
+	var reply struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(data, &reply); err != nil || reply.ID == "" {
+		return "", &EmailAPIError{Provider: EmailProviderGmail, StatusCode: resp.StatusCode, Message: "the reply has no message ID"}
+	}
+	return reply.ID, nil
+}
+
+// gmailError translates a Gmail API error reply into an EmailAPIError
+// that says what to do about it.
+func gmailError(resp *http.Response, data []byte) error {
+	var reply struct {
+		Error struct {
+			Message string `json:"message"`
+			Status  string `json:"status"`
+			Details []struct {
+				Reason string `json:"reason"`
+			} `json:"details"`
+		} `json:"error"`
+	}
+	msg := strings.TrimSpace(string(data))
+	if json.Unmarshal(data, &reply) == nil && reply.Error.Message != "" {
+		msg = reply.Error.Message
+	}
+	reason := reply.Error.Status
+	for _, d := range reply.Error.Details {
+		if d.Reason != "" {
+			reason = d.Reason
+		}
+	}
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized:
+		msg = "the OAuth token has expired or is not valid; get a new access token with the gmail.send scope and set gmail_token (" + msg + ")"
+	case reason == "ACCESS_TOKEN_SCOPE_INSUFFICIENT":
+		msg = "the OAuth token does not have the gmail.send scope; get a new one that does (" + msg + ")"
+	}
+	return &EmailAPIError{Provider: EmailProviderGmail, StatusCode: resp.StatusCode, Message: fmt.Sprintf("%s: %s", resp.Status, msg)}
+}
+
+// SendGridClient sends email with the SendGrid v3 Mail Send API.
+type SendGridClient struct {
+	// Key is the API key, with the Mail Send permission.
+	Key string
+	// BaseURL is the API's base URL; "" means DefaultSendGridURL. Tests
+	// point it at a fake server.
+	BaseURL string
+	// HTTP sends the requests; nil means a client with EmailAPITimeout.
+	HTTP *http.Client
+}
+
+// Send sends e and returns the message ID SendGrid gave it. The Mail Send
+// API does not take a MIME message, so e's sender, recipients, subject,
+// body and attachments are sent as its JSON fields instead.
+func (c *SendGridClient) Send(ctx context.Context, e *Email) (string, error) {
+	if c.Key == "" {
+		return "", &EmailAPIError{Provider: EmailProviderSendGrid, Message: "no API key is configured"}
+	}
+	from, err := mail.ParseAddress(e.From)
+	if err != nil {
+		return "", &EmailAPIError{Provider: EmailProviderSendGrid, Message: fmt.Sprintf("sender %q: %v", e.From, err)}
+	}
+	var body sendGridRequest
+	body.From = sendGridAddress{Email: from.Address, Name: from.Name}
+	var to []sendGridAddress
+	for _, addr := range e.To {
+		to = append(to, sendGridAddress{Email: addr})
+	}
+	body.Personalizations = []sendGridPersonalization{{To: to}}
+	body.Subject = e.Subject
+	body.Content = []sendGridContent{{Type: "text/plain", Value: e.Body}}
+	for _, a := range e.Attachments {
+		body.Attachments = append(body.Attachments, sendGridAttachment{
+			Content:     base64.StdEncoding.EncodeToString(a.Data),
+			Type:        a.ContentType,
+			Filename:    a.Name,
+			Disposition: "attachment",
+		})
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return "", err
+	}
+	resp, reply, err := emailAPIPost(ctx, c.HTTP, c.BaseURL, DefaultSendGridURL, "/v3/mail/send", c.Key, data)
+	if err != nil {
+		return "", &EmailAPIError{Provider: EmailProviderSendGrid, Message: err.Error()}
+	}
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		// SendGrid answers 200 instead of 202 when it only validated the
+		// request in sandbox mode.
+		return "", &EmailAPIError{Provider: EmailProviderSendGrid, StatusCode: resp.StatusCode, Message: "the account is in sandbox mode, so the email was checked but not delivered; turn sandbox mode off in the SendGrid mail settings"}
+	case resp.StatusCode/100 != 2:
+		return "", sendGridError(resp, reply)
+	}
+	return resp.Header.Get("X-Message-Id"), nil
+}
+
+type sendGridRequest struct {
+	Personalizations []sendGridPersonalization `json:"personalizations"`
+	From             sendGridAddress           `json:"from"`
+	Subject          string                    `json:"subject"`
+	Content          []sendGridContent         `json:"content"`
+	Attachments      []sendGridAttachment      `json:"attachments,omitempty"`
+}
+
+type sendGridPersonalization struct {
+	To []sendGridAddress `json:"to"`
+}
+
+type sendGridAddress struct {
+	Email string `json:"email"`
+	Name  string `json:"name,omitempty"`
+}
+
+type sendGridContent struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type sendGridAttachment struct {
+	Content     string `json:"content"`
+	Type        string `json:"type"`
+	Filename    string `json:"filename"`
+	Disposition string `json:"disposition"`
+}
+
+// sendGridError translates a SendGrid error reply into an EmailAPIError
+// that says what to do about it.
+func sendGridError(resp *http.Response, data []byte) error {
+	var reply struct {
+		Errors []struct {
+			Message string `json:"message"`
+			Field   string `json:"field"`
+		} `json:"errors"`
+	}
+	msg := strings.TrimSpace(string(data))
+	if json.Unmarshal(data, &reply) == nil && len(reply.Errors) > 0 {
+		var msgs []string
+		for _, e := range reply.Errors {
+			if e.Field != "" {
+				msgs = append(msgs, e.Field+": "+e.Message)
+			} else {
+				msgs = append(msgs, e.Message)
+			}
+		}
+		msg = strings.Join(msgs, "; ")
+	}
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized:
+		msg = "the API key is not valid or was revoked; create a new one and set sendgrid_api_key (" + msg + ")"
+	case resp.StatusCode == http.StatusForbidden && strings.Contains(strings.ToLower(msg), "sender identity"):
+		msg = "the sender is not a verified sender identity; verify email_from's address or domain in SendGrid (" + msg + ")"
+	case resp.StatusCode == http.StatusForbidden:
+		msg = "the API key does not have the Mail Send permission (" + msg + ")"
+	}
+	return &EmailAPIError{Provider: EmailProviderSendGrid, StatusCode: resp.StatusCode, Message: fmt.Sprintf("%s: %s", resp.Status, msg)}
+}
+
+// emailAPIPost posts the JSON body to path of an email API at base, or
+// def if base is "", with the bearer token, and returns the reply and its
+// body.
+func emailAPIPost(ctx context.Context, client *http.Client, base, def, path, token string, body []byte) (*http.Response, []byte, error) {
+	if base == "" {
+		base = def
+	}
+	if client == nil {
+		client = &http.Client{Timeout: EmailAPITimeout}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(base, "/")+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp, data, nil
+}
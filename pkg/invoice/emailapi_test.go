@@ -0,0 +1,158 @@
+package invoice_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+// emailAPIServer starts a fake email API that answers path with reply,
+// and records the request's body.
+func emailAPIServer(t *testing.T, path string, reply apiReply, header http.Header, body *[]byte) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != path {
+			http.NotFound(w, r)
+			return
+		}
+		if h := r.Header.Get("Authorization"); h != "Bearer credential" {
+			t.Errorf("Authorization = %q", h)
+		}
+		if body != nil {
+			*body, _ = io.ReadAll(r.Body)
+		}
+		var data []byte
+		if reply.fixture != "" {
+			var err error
+			if data, err = os.ReadFile(filepath.Join("testdata", reply.fixture)); err != nil {
+				t.Error(err)
+			}
+		}
+		for k, v := range header {
+			w.Header()[k] = v
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(reply.status)
+		w.Write(data)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+const gmailSendPath = "/gmail/v1/users/me/messages/send"
+
+func TestGmailClient_Send(t *testing.T) {
+	var body []byte
+	srv := emailAPIServer(t, gmailSendPath, apiReply{http.StatusOK, "gmail-send.json"}, nil, &body)
+	client := &invoice.GmailClient{Token: "credential", BaseURL: srv.URL, HTTP: srv.Client()}
+	e := testEmail()
+	id, err := client.Send(context.Background(), e)
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if id != "18d4c2f7a9b3e601" {
+		t.Errorf("id = %q", id)
+	}
+	var req struct {
+		Raw string `json:"raw"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		t.Fatalf("request body: %v", err)
+	}
+	msg, err := base64.URLEncoding.DecodeString(req.Raw)
+	if err != nil {
+		t.Fatalf("raw message: %v", err)
+	}
+	checkEmailMessage(t, msg, e)
+}
+
+func TestSendGridClient_Send(t *testing.T) {
+	var body []byte
+	srv := emailAPIServer(t, "/v3/mail/send", apiReply{status: http.StatusAccepted}, http.Header{"X-Message-Id": {"W86EgYT6SQKk0lRflfLRsA"}}, &body)
+	client := &invoice.SendGridClient{Key: "credential", BaseURL: srv.URL, HTTP: srv.Client()}
+	e := testEmail()
+	id, err := client.Send(context.Background(), e)
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if id != "W86EgYT6SQKk0lRflfLRsA" {
+		t.Errorf("id = %q", id)
+	}
+	var req struct {
+		Personalizations []struct {
+			To []struct {
+				Email string `json:"email"`
+			} `json:"to"`
+		} `json:"personalizations"`
+		From struct {
+			Email string `json:"email"`
+			Name  string `json:"name"`
+		} `json:"from"`
+		Subject     string `json:"subject"`
+		Attachments []struct {
+			Content  string `json:"content"`
+			Filename string `json:"filename"`
+		} `json:"attachments"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		t.Fatalf("request body: %v", err)
+	}
+	if req.From.Email != "jane@smith.example" || req.From.Name != "Jane Smith" || req.Subject != e.Subject {
+		t.Errorf("from %+v, subject %q", req.From, req.Subject)
+	}
+	if len(req.Personalizations) != 1 || len(req.Personalizations[0].To) != 1 || req.Personalizations[0].To[0].Email != "ap@acme.example" {
+		t.Errorf("personalizations = %+v", req.Personalizations)
+	}
+	if len(req.Attachments) != len(e.Attachments) {
+		t.Fatalf("got %d attachments", len(req.Attachments))
+	}
+	for i, a := range req.Attachments {
+		data, err := base64.StdEncoding.DecodeString(a.Content)
+		if err != nil || a.Filename != e.Attachments[i].Name || string(data) != string(e.Attachments[i].Data) {
+			t.Errorf("attachment %d = %s (%v)", i, a.Filename, err)
+		}
+	}
+}
+
+func TestEmailAPI_Failures(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		reply   apiReply
+		wantErr string
+	}{
+		{"gmail expired token", gmailSendPath, apiReply{http.StatusUnauthorized, "gmail-unauthenticated.json"}, "sending the email with Gmail failed: 401 Unauthorized: the OAuth token has expired or is not valid; get a new access token with the gmail.send scope"},
+		{"gmail scope", gmailSendPath, apiReply{http.StatusForbidden, "gmail-insufficient-scope.json"}, "does not have the gmail.send scope"},
+		{"sendgrid bad key", "/v3/mail/send", apiReply{http.StatusUnauthorized, "sendgrid-unauthorized.json"}, "sending the email with SendGrid failed: 401 Unauthorized: the API key is not valid or was revoked"},
+		{"sendgrid sender identity", "/v3/mail/send", apiReply{http.StatusForbidden, "sendgrid-sender-identity.json"}, "the sender is not a verified sender identity"},
+		{"sendgrid sandbox", "/v3/mail/send", apiReply{status: http.StatusOK}, "sandbox mode"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := emailAPIServer(t, tt.path, tt.reply, nil, nil)
+			var err error
+			if strings.HasPrefix(tt.name, "gmail") {
+				_, err = (&invoice.GmailClient{Token: "credential", BaseURL: srv.URL}).Send(context.Background(), testEmail())
+			} else {
+				_, err = (&invoice.SendGridClient{Key: "credential", BaseURL: srv.URL}).Send(context.Background(), testEmail())
+			}
+			var aerr *invoice.EmailAPIError
+			if !errors.As(err, &aerr) {
+				t.Fatalf("expected an EmailAPIError, got %v", err)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package invoice
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// pdfEncrypter describes an external program that can encrypt a PDF.
+type pdfEncrypter struct {
+	// Name identifies the program in messages.
+	Name string
+	// Args returns the arguments that encrypt inPath to outPath.
+	Args func(user, owner, inPath, outPath string) []string
+	// ArgFile passes the arguments in a file named by a single "@path"
+	// argument, so the passwords do not show up in the process list.
+	ArgFile bool
+	// Stdin, if set, returns what is written to the program's standard
+	// input, for a program that reads the passwords there instead.
+	Stdin func(user, owner string) string
+}
+
+// pdfEncrypters are tried in order. pdftk is given PROMPT for each password
+// and reads them from its standard input, the owner password first.
+var pdfEncrypters = []pdfEncrypter{
+	{Name: "qpdf", ArgFile: true, Args: func(user, owner, inPath, outPath string) []string {
+		return []string{"--encrypt", user, owner, "256", "--modify=none", "--", inPath, outPath}
+	}},
+	{Name: "pdftk", Args: func(user, owner, inPath, outPath string) []string {
+		return []string{inPath, "output", outPath, "user_pw", "PROMPT", "owner_pw", "PROMPT", "allow", "Printing", "encrypt_128bit"}
+	}, Stdin: func(user, owner string) string {
+		return owner + "\n" + user + "\n"
+	}},
+}
+
+// findPDFEncrypter returns the first of pdfEncrypters that is installed and
+// its executable.
+func findPDFEncrypter() (pdfEncrypter, string, error) {
+	for _, enc := range pdfEncrypters {
+		if path, err := exec.LookPath(enc.Name); err == nil {
+			return enc, path, nil
+		}
+	}
+	return pdfEncrypter{}, "", &NotInstalledError{
+		Tool:    "qpdf",
+		Purpose: "invoicer encrypts the PDF for --pdf-password with qpdf, or else pdftk.",
+		Install: []string{
+			"sudo apt install qpdf    (Debian, Ubuntu)",
+			"brew install qpdf    (macOS)",
+			"winget install QPDF.QPDF    (Windows)",
+		},
+		Instead: "Or leave out --pdf-password to write the PDF unencrypted.",
+	}
+}
+
+// CheckPDFEncrypter returns a NotInstalledError if no program that
+// EncryptPDF can use is installed, so a missing one is found before an
+// invoice is generated.
+func CheckPDFEncrypter() error {
+	_, _, err := findPDFEncrypter()
+	return err
+}
+
+// EncryptPDF encrypts the PDF at path in place with the user password,
+// which is needed to open it. The owner password is random and thrown
+// away, so the permissions, which allow printing but not changes, cannot
+// be lifted. It returns the program used. The password never appears in
+// the returned error.
+func EncryptPDF(ctx context.Context, path, password string) (string, error) {
+	if password == "" {
+		return "", errors.New("the PDF password is empty")
+	}
+	if strings.ContainsAny(password, "\r\n") {
+		return "", errors.New("the PDF password cannot contain a line break")
+	}
+	enc, bin, err := findPDFEncrypter()
+	if err != nil {
+		return "", err
+	}
+	owner, err := randomPassword()
+	if err != nil {
+		return enc.Name, err
+	}
+	staged := stagingPath(path)
+	defer os.Remove(staged)
+	args := enc.Args(password, owner, path, staged)
+	if enc.ArgFile {
+		argPath, err := writeArgFile(args)
+		if err != nil {
+			return enc.Name, fmt.Errorf("writing the %s arguments: %w", enc.Name, err)
+		}
+		defer os.Remove(argPath)
+		args = []string{"@" + argPath}
+	}
+	cmd := command(ctx, bin, args...)
+	if enc.Stdin != nil {
+		cmd.Stdin = strings.NewReader(enc.Stdin(password, owner))
+	}
+	if tail, err := runTail(cmd); err != nil {
+		if ctx.Err() != nil {
+			return enc.Name, pdfToolStopped(ctx, enc.Name)
+		}
+		return enc.Name, fmt.Errorf("encrypting the PDF with %s: %w", enc.Name, redact(err, tail.String(), password, owner))
+	}
+	if err := VerifyPDF(staged); err != nil {
+		return enc.Name, fmt.Errorf("%s exited cleanly but %w", enc.Name, err)
+	}
+	if err := os.Rename(staged, path); err != nil {
+		return enc.Name, fmt.Errorf("replacing the PDF: %w", err)
+	}
+	return enc.Name, nil
+}
+
+// writeArgFile writes args, one per line, to a new file only the user can
+// read, and returns its path.
+func writeArgFile(args []string) (string, error) {
+	f, err := os.CreateTemp("", "invoicer-args-")
+	if err != nil {
+		return "", err
+	}
+	_, err = f.WriteString(strings.Join(args, "\n") + "\n")
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// randomPassword returns a password no one is meant to know.
+func randomPassword() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generating the owner password: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// redact returns err, which runTail ended with the tool's output, with
+// every occurrence of the secrets in the output hidden, for a tool that
+// echoes its arguments when it fails.
+func redact(err error, output string, secrets ...string) error {
+	hidden := output
+	for _, s := range secrets {
+		hidden = strings.ReplaceAll(hidden, s, "[redacted]")
+	}
+	if hidden == output {
+		return err
+	}
+	return fmt.Errorf("%w: %s", errors.Unwrap(err), hidden)
+}
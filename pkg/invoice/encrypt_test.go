@@ -0,0 +1,166 @@
+package invoice_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+// fakeQpdfScript records its arguments to args.txt beside itself and, for
+// an @file argument, the file's lines to argfile.txt. It writes a minimal
+// PDF to the last line of the file, using only shell builtins.
+const fakeQpdfScript = `#!/bin/sh
+dir=${0%/*}
+printf '%s\n' "$@" > "$dir/args.txt"
+case $1 in
+@*)
+	: > "$dir/argfile.txt"
+	while IFS= read -r line; do
+		printf '%s\n' "$line" >> "$dir/argfile.txt"
+		out=$line
+	done < "${1#@}"
+	;;
+esac
+printf '%%PDF-1.4\n%%%%EOF\n' > "$out"
+`
+
+// fakePdftkScript records its arguments to args.txt beside itself and its
+// standard input to stdin.txt, and writes a minimal PDF to the file after
+// "output".
+const fakePdftkScript = `#!/bin/sh
+dir=${0%/*}
+printf '%s\n' "$@" > "$dir/args.txt"
+while IFS= read -r line; do printf '%s\n' "$line"; done > "$dir/stdin.txt"
+printf '%%PDF-1.4\n%%%%EOF\n' > "$3"
+`
+
+const testPassword = "correct horse battery"
+
+func readLines(t *testing.T, path string) []string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+}
+
+func TestEncryptPDF_Qpdf(t *testing.T) {
+	binDir := t.TempDir()
+	writeFakeTool(t, binDir, "qpdf", fakeQpdfScript)
+	writeFakeTool(t, binDir, "pdftk", fakePdftkScript)
+	isolatePDFTools(t, binDir)
+	path := writePDF(t, minimalPDF(""))
+
+	tool, err := invoice.EncryptPDF(context.Background(), path, testPassword)
+	if err != nil {
+		t.Fatalf("EncryptPDF: %v", err)
+	}
+	if tool != "qpdf" {
+		t.Errorf("tool = %q, want qpdf", tool)
+	}
+	args := readLines(t, filepath.Join(binDir, "args.txt"))
+	if len(args) != 1 || !strings.HasPrefix(args[0], "@") {
+		t.Fatalf("qpdf should get its arguments from a file, got %q", args)
+	}
+	if _, err := os.Stat(args[0][1:]); !os.IsNotExist(err) {
+		t.Errorf("the argument file should be removed, stat err = %v", err)
+	}
+	got := readLines(t, filepath.Join(binDir, "argfile.txt"))
+	if len(got) != 8 {
+		t.Fatalf("arguments = %q", got)
+	}
+	if got[0] != "--encrypt" || got[1] != testPassword || got[3] != "256" || got[4] != "--modify=none" || got[5] != "--" || got[6] != path {
+		t.Errorf("arguments = %q", got)
+	}
+	if !regexp.MustCompile(`^[0-9a-f]{32}$`).MatchString(got[2]) {
+		t.Errorf("owner password = %q, want a random one", got[2])
+	}
+	if !strings.HasPrefix(got[7], path+".tmp-") {
+		t.Errorf("output = %q, want a staging file next to the PDF", got[7])
+	}
+	if data, err := os.ReadFile(path); err != nil || string(data) != "%PDF-1.4\n%%EOF\n" {
+		t.Errorf("the PDF should be replaced by the encrypted one, got %q, %v", data, err)
+	}
+}
+
+func TestEncryptPDF_Pdftk(t *testing.T) {
+	binDir := t.TempDir()
+	writeFakeTool(t, binDir, "pdftk", fakePdftkScript)
+	isolatePDFTools(t, binDir)
+	path := writePDF(t, minimalPDF(""))
+
+	tool, err := invoice.EncryptPDF(context.Background(), path, testPassword)
+	if err != nil {
+		t.Fatalf("EncryptPDF: %v", err)
+	}
+	if tool != "pdftk" {
+		t.Errorf("tool = %q, want pdftk", tool)
+	}
+	got := readLines(t, filepath.Join(binDir, "args.txt"))
+	if len(got) != 10 || got[0] != path || got[1] != "output" || strings.Join(got[3:7], " ") != "user_pw PROMPT owner_pw PROMPT" {
+		t.Fatalf("arguments = %q", got)
+	}
+	if strings.Join(got[7:], " ") != "allow Printing encrypt_128bit" {
+		t.Errorf("permissions = %q", got[7:])
+	}
+	stdin := readLines(t, filepath.Join(binDir, "stdin.txt"))
+	if len(stdin) != 2 || stdin[1] != testPassword || !regexp.MustCompile(`^[0-9a-f]{32}$`).MatchString(stdin[0]) {
+		t.Errorf("standard input = %q, want the random owner password and then the user password", stdin)
+	}
+}
+
+func TestEncryptPDF_NotInstalled(t *testing.T) {
+	isolatePDFTools(t, t.TempDir())
+	var missing *invoice.NotInstalledError
+	if err := invoice.CheckPDFEncrypter(); !errors.As(err, &missing) || missing.Tool != "qpdf" || !strings.Contains(err.Error(), "pdftk") {
+		t.Fatalf("expected qpdf to be reported missing, got %v", err)
+	}
+	path := writePDF(t, minimalPDF(""))
+	if _, err := invoice.EncryptPDF(context.Background(), path, testPassword); !errors.As(err, &missing) {
+		t.Errorf("expected a NotInstalledError, got %v", err)
+	}
+}
+
+func TestEncryptPDF_FailureHidesPassword(t *testing.T) {
+	binDir := t.TempDir()
+	// A qpdf that echoes its arguments as it fails.
+	writeFakeTool(t, binDir, "qpdf", "#!/bin/sh\nwhile IFS= read -r line; do echo \"bad argument: $line\" >&2; done < \"${1#@}\"\nexit 2\n")
+	isolatePDFTools(t, binDir)
+	original := minimalPDF("")
+	path := writePDF(t, original)
+
+	_, err := invoice.EncryptPDF(context.Background(), path, testPassword)
+	if err == nil || !strings.Contains(err.Error(), "encrypting the PDF with qpdf") {
+		t.Fatalf("expected the failure to be reported, got %v", err)
+	}
+	if strings.Contains(err.Error(), testPassword) || !strings.Contains(err.Error(), "bad argument: [redacted]") {
+		t.Errorf("the password should be redacted:\n%v", err)
+	}
+	var exit *exec.ExitError
+	if !errors.As(err, &exit) || exit.ExitCode() != 2 {
+		t.Errorf("the redacted error should wrap qpdf's exit status, got %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != string(original) {
+		t.Error("the PDF should be left as it was")
+	}
+	if staged, _ := filepath.Glob(path + ".tmp-*"); len(staged) > 0 {
+		t.Errorf("staging files left behind: %v", staged)
+	}
+}
+
+func TestEncryptPDF_BadPassword(t *testing.T) {
+	path := writePDF(t, minimalPDF(""))
+	for _, password := range []string{"", "two\nlines"} {
+		if _, err := invoice.EncryptPDF(context.Background(), path, password); err == nil {
+			t.Errorf("EncryptPDF(%q) should fail", password)
+		}
+	}
+}
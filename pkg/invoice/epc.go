@@ -0,0 +1,188 @@
+package invoice
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// PaymentDetails are the bank account an invoice is paid into.
+type PaymentDetails struct {
+	// Name is the account holder; "" means the invoice's vendor.
+	Name string `json:"name,omitempty"`
+	// IBAN is the account's International Bank Account Number. Spaces
+	// and lowercase letters are allowed.
+	IBAN string `json:"iban,omitempty"`
+	// BIC is the bank's Business Identifier Code; it may be "".
+	BIC string `json:"bic,omitempty"`
+	// RoutingNumber and AccountNumber are a US account, paid by ACH or
+	// wire transfer instead of or as well as the IBAN.
+	RoutingNumber string `json:"routing_number,omitempty"`
+	AccountNumber string `json:"account_number,omitempty"`
+}
+
+// EPC069-12 limits, in characters.
+const (
+	epcMaxName       = 70
+	epcMaxRemittance = 140
+	// epcMaxAmount is the largest amount a credit transfer may have.
+	epcMaxAmount Cents = 99999999999
+)
+
+// epcCurrency is the only currency a SEPA credit transfer is in.
+const epcCurrency = "EUR"
+
+// EPCQRPlaceholder is the src the generation prompt asks for on the image
+// that shows the payment QR code. Generate replaces it with the QR code.
+const EPCQRPlaceholder = "invoicer-epc-qr.png"
+
+// epcQRSize is the width and height of the QR code image in pixels.
+const epcQRSize = 256
+
+// normalizeIBAN returns iban without spaces, in upper case.
+func normalizeIBAN(iban string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(iban), ""))
+}
+
+// FormatIBAN returns iban in groups of four characters, as it is printed.
+func FormatIBAN(iban string) string {
+	iban = normalizeIBAN(iban)
+	var groups []string
+	for len(iban) > 4 {
+		groups = append(groups, iban[:4])
+		iban = iban[4:]
+	}
+	return strings.Join(append(groups, iban), " ")
+}
+
+// EPCPayload returns the EPC069-12 (version 002) text of a SEPA credit
+// transfer that pays inv: the account in inv.Payment, the billed amount
+// due in euros, and the invoice number as the remittance information.
+// Names that are too long are cut short. It fails if there are no payment
+// details, the IBAN or BIC is not valid, the invoice is not billed in
+// euros, or the total cannot be transferred.
+func EPCPayload(inv *Invoice) (string, error) {
+	p := inv.Payment
+	if p == nil || p.IBAN == "" {
+		return "", errors.New("no IBAN is configured")
+	}
+	if err := ValidateIBAN(p.IBAN); err != nil {
+		return "", err
+	}
+	if p.BIC != "" {
+		if err := ValidateBIC(p.BIC); err != nil {
+			return "", err
+		}
+	}
+	name := p.Name
+	if name == "" {
+		name = inv.Vendor
+	}
+	name = epcText(name, epcMaxName)
+	if name == "" {
+		return "", errors.New("the account holder's name is empty")
+	}
+	billed := Currency
+	if inv.Exchange != nil {
+		billed = inv.Exchange.Currency
+	}
+	if billed != epcCurrency {
+		return "", fmt.Errorf("the invoice is billed in %s, and a SEPA transfer is in %s", billed, epcCurrency)
+	}
+	amount, err := epcAmount(inv.BilledAmountDue(inv.Breakdown()))
+	if err != nil {
+		return "", err
+	}
+	lines := []string{
+		"BCD", // service tag
+		"002", // version
+		"1",   // character set: UTF-8
+		"SCT", // SEPA credit transfer
+		strings.ToUpper(strings.TrimSpace(p.BIC)),
+		name,
+		normalizeIBAN(p.IBAN),
+		amount,
+		"", // purpose
+		"", // structured reference, left out for the text below
+		epcText(InvoiceNumber(inv), epcMaxRemittance),
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// epcAmount formats amount as EPC069-12 requires, e.g. "EUR1234.50": euros
+// with a dot before the cents and no grouping, whatever the locale.
+func epcAmount(amount Cents) (string, error) {
+	if amount < 1 {
+		return "", fmt.Errorf("the amount %s is too small for a transfer", amount)
+	}
+	if amount > epcMaxAmount {
+		return "", fmt.Errorf("the amount %s is over the %s limit of a transfer", amount, epcMaxAmount)
+	}
+	return epcCurrency + amount.String(), nil
+}
+
+// epcText returns s on one line, trimmed, and cut to at most max characters.
+func epcText(s string, max int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	return strings.TrimSpace(string([]rune(s)[:max]))
+}
+
+// EPCQRCode returns a PNG of the QR code for EPCPayload(inv), with the
+// error correction level M the standard asks for.
+func EPCQRCode(inv *Invoice) ([]byte, error) {
+	payload, err := EPCPayload(inv)
+	if err != nil {
+		return nil, err
+	}
+	png, err := qrcode.Encode(payload, qrcode.Medium, epcQRSize)
+	if err != nil {
+		return nil, fmt.Errorf("encoding the payment QR code: %w", err)
+	}
+	return png, nil
+}
+
+// EPCQRDataURI returns EPCQRCode(inv) as a data: URI for an <img> src.
+func EPCQRDataURI(inv *Invoice) (string, error) {
+	png, err := EPCQRCode(inv)
+	if err != nil {
+		return "", err
+	}
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(png), nil
+}
+
+// EmbedEPCQR puts the QR code at dataURI into the HTML invoice at path, in
+// place of every EPCQRPlaceholder. An invoice without the placeholder gets
+// the code added at the end of its body, so it is never left out.
+func EmbedEPCQR(path, dataURI string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("embedding the payment QR code: %w", err)
+	}
+	if bytes.Contains(data, []byte(EPCQRPlaceholder)) {
+		data = bytes.ReplaceAll(data, []byte(EPCQRPlaceholder), []byte(dataURI))
+	} else {
+		figure := fmt.Sprintf(`<figure style="margin: 2em 0 0; text-align: center;"><img src="%s" alt="SEPA payment QR code" width="150" height="150"><figcaption>Scan to pay by bank transfer</figcaption></figure>`+"\n", dataURI)
+		lower := bytes.ToLower(data)
+		i := bytes.LastIndex(lower, []byte("</body>"))
+		if i < 0 {
+			i = bytes.LastIndex(lower, []byte("</html>"))
+		}
+		if i < 0 {
+			i = len(data)
+		}
+		data = append(data[:i:i], append([]byte(figure), data[i:]...)...)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("embedding the payment QR code: %w", err)
+	}
+	return nil
+}
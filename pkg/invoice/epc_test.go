@@ -0,0 +1,297 @@
+package invoice_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+// testIBAN is a well-known example IBAN with valid check digits.
+const testIBAN = "DE89370400440532013000"
+
+// epcInvoice returns testInvoice with payment details, billed in euros at
+// a rate of 1 so its amounts stay the same.
+func epcInvoice() *invoice.Invoice {
+	inv := testInvoice()
+	inv.Payment = &invoice.PaymentDetails{IBAN: "de89 3704 0044 0532 0130 00", BIC: "COBADEFFXXX"}
+	inv.Exchange = &invoice.ExchangeRate{Currency: "EUR", Rate: 1, Date: time.Date(2025, time.January, 31, 0, 0, 0, 0, time.UTC), Source: invoice.FXManual}
+	return inv
+}
+
+func TestEPCPayload(t *testing.T) {
+	got, err := invoice.EPCPayload(epcInvoice())
+	if err != nil {
+		t.Fatalf("EPCPayload: %v", err)
+	}
+	want := strings.Join([]string{
+		"BCD", "002", "1", "SCT",
+		"COBADEFFXXX",
+		"Jane Contractor",
+		testIBAN,
+		"EUR10800.00",
+		"", "",
+		"INV-202501-acme-corp",
+	}, "\n")
+	if got != want {
+		t.Errorf("EPCPayload =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestEPCPayload_BilledInEuros(t *testing.T) {
+	// The QR code asks for the euros the invoice shows, not its dollars.
+	inv := epcInvoice()
+	inv.Exchange = &invoice.ExchangeRate{Currency: "EUR", Rate: 0.93, Date: time.Date(2025, time.January, 31, 0, 0, 0, 0, time.UTC), Source: invoice.FXManual}
+	got, err := invoice.EPCPayload(inv)
+	if err != nil {
+		t.Fatalf("EPCPayload: %v", err)
+	}
+	if amount := strings.Split(got, "\n")[7]; amount != "EUR10044.00" {
+		t.Errorf("amount = %q, want EUR10044.00", amount)
+	}
+
+	// A transfer cannot be in pounds, nor in the dollars of an invoice
+	// without an exchange rate.
+	inv.Exchange.Currency = "GBP"
+	if _, err := invoice.EPCPayload(inv); err == nil || !strings.Contains(err.Error(), "billed in GBP") {
+		t.Errorf("expected an error for an invoice billed in GBP, got %v", err)
+	}
+	inv.Exchange = nil
+	if _, err := invoice.EPCPayload(inv); err == nil || !strings.Contains(err.Error(), "billed in USD") {
+		t.Errorf("expected an error for an invoice billed in USD, got %v", err)
+	}
+}
+
+func TestEPCPayload_OptionalFields(t *testing.T) {
+	inv := epcInvoice()
+	inv.Payment = &invoice.PaymentDetails{Name: "Jane's\nConsulting GmbH", IBAN: testIBAN}
+	got, err := invoice.EPCPayload(inv)
+	if err != nil {
+		t.Fatalf("EPCPayload: %v", err)
+	}
+	lines := strings.Split(got, "\n")
+	if len(lines) != 11 {
+		t.Fatalf("got %d lines, want 11:\n%s", len(lines), got)
+	}
+	if lines[4] != "" {
+		t.Errorf("BIC line = %q, want it empty", lines[4])
+	}
+	if lines[5] != "Jane's Consulting GmbH" {
+		t.Errorf("name = %q, want the account holder on one line", lines[5])
+	}
+}
+
+func TestEPCPayload_LongName(t *testing.T) {
+	inv := epcInvoice()
+	inv.Vendor = strings.Repeat("Müller ", 20)
+	got, err := invoice.EPCPayload(inv)
+	if err != nil {
+		t.Fatalf("EPCPayload: %v", err)
+	}
+	name := strings.Split(got, "\n")[5]
+	if n := len([]rune(name)); n > 70 {
+		t.Errorf("name has %d characters, want at most 70: %q", n, name)
+	}
+	if !strings.HasPrefix(name, "Müller Müller") || strings.HasSuffix(name, " ") {
+		t.Errorf("name = %q, want it cut short on whole characters and trimmed", name)
+	}
+}
+
+func TestEPCPayload_Amount(t *testing.T) {
+	tests := []struct {
+		name    string
+		rate    float64
+		hours   float64
+		want    string
+		wantErr string
+	}{
+		{"cents", 10.25, 2, "EUR20.50", ""},
+		{"one decimal", 123.45, 10, "EUR1234.50", ""},
+		{"no grouping", 1000000, 1.5, "EUR1500000.00", ""},
+		{"rounded", 33.333, 3, "EUR100.00", ""},
+		{"smallest", 0.01, 1, "EUR0.01", ""},
+		{"zero", 0, 40, "", "too small"},
+		{"rounds to zero", 0.001, 1, "", "too small"},
+		{"too large", 100000000, 10, "", "over the"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inv := epcInvoice()
+			inv.Rate = tt.rate
+			inv.Weeks = inv.Weeks[:1]
+			inv.Weeks[0].Hours = tt.hours
+			got, err := invoice.EPCPayload(inv)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("expected error %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("EPCPayload: %v", err)
+			}
+			if amount := strings.Split(got, "\n")[7]; amount != tt.want {
+				t.Errorf("amount = %q, want %q", amount, tt.want)
+			}
+		})
+	}
+}
+
+func TestEPCPayload_Incomplete(t *testing.T) {
+	tests := []struct {
+		name    string
+		payment *invoice.PaymentDetails
+		vendor  string
+		want    string
+	}{
+		{"no details", nil, "Jane", "no IBAN"},
+		{"BIC only", &invoice.PaymentDetails{BIC: "COBADEFFXXX"}, "Jane", "no IBAN"},
+		{"bad checksum", &invoice.PaymentDetails{IBAN: "DE89370400440532013001"}, "Jane", "check digits"},
+		{"bad BIC", &invoice.PaymentDetails{IBAN: testIBAN, BIC: "COBA"}, "Jane", `BIC "COBA"`},
+		{"no name", &invoice.PaymentDetails{IBAN: testIBAN}, " ", "name is empty"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inv := testInvoice()
+			inv.Vendor = tt.vendor
+			inv.Payment = tt.payment
+			if _, err := invoice.EPCPayload(inv); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestFormatIBAN(t *testing.T) {
+	if got, want := invoice.FormatIBAN("de89370400440532013000"), "DE89 3704 0044 0532 0130 00"; got != want {
+		t.Errorf("FormatIBAN = %q, want %q", got, want)
+	}
+}
+
+func TestEPCQRCode(t *testing.T) {
+	data, err := invoice.EPCQRCode(epcInvoice())
+	if err != nil {
+		t.Fatalf("EPCQRCode: %v", err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("the QR code is not a PNG: %v", err)
+	}
+	if b := img.Bounds(); b.Dx() != b.Dy() || b.Dx() < 100 {
+		t.Errorf("QR code is %dx%d, want a square of at least 100 pixels", b.Dx(), b.Dy())
+	}
+}
+
+func TestEmbedEPCQR(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{"placeholder", `<html><body><img src="` + invoice.EPCQRPlaceholder + `" alt="QR"></body></html>`, `<img src="data:image/png;base64,QR" alt="QR"></body>`},
+		{"no placeholder", "<html><BODY><p>Total</p></BODY></html>", `<p>Total</p><figure`},
+		{"no body", "<html><p>Total</p></html>", `<p>Total</p><figure`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "invoice.html")
+			if err := os.WriteFile(path, []byte(tt.html), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if err := invoice.EmbedEPCQR(path, "data:image/png;base64,QR"); err != nil {
+				t.Fatalf("EmbedEPCQR: %v", err)
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := string(data)
+			if !strings.Contains(got, tt.want) || strings.Count(got, "data:image/png;base64,QR") != 1 {
+				t.Errorf("embedded HTML = %q, want it to contain %q once", got, tt.want)
+			}
+			if !strings.HasSuffix(strings.ToLower(got), "</html>") {
+				t.Errorf("the QR code should go inside the document: %q", got)
+			}
+		})
+	}
+}
+
+func TestGenerate_EmbedsEPCQR(t *testing.T) {
+	outputPath := filepath.Join(t.TempDir(), "invoice.html")
+	var warnings []error
+	opts := invoice.GenerateOptions{OnWarning: func(err error) { warnings = append(warnings, err) }}
+	if _, err := invoice.Generate(context.Background(), "test-epc", epcInvoice(), outputPath, opts); err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if len(warnings) != 0 {
+		t.Errorf("unexpected warnings: %v", warnings)
+	}
+	data, err := os.ReadFile(outputPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const prefix = `<img src="data:image/png;base64,`
+	i := strings.Index(string(data), prefix)
+	if i < 0 {
+		t.Fatalf("the placeholder was not replaced: %s", data)
+	}
+	encoded := string(data[i+len(prefix):])
+	encoded = encoded[:strings.IndexByte(encoded, '"')]
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		t.Fatalf("the data URI is not base64: %v", err)
+	}
+	if _, err := png.Decode(bytes.NewReader(raw)); err != nil {
+		t.Errorf("the embedded QR code is not a PNG: %v", err)
+	}
+}
+
+func TestGenerate_IncompletePaymentDetails(t *testing.T) {
+	outputPath := filepath.Join(t.TempDir(), "invoice.html")
+	inv := testInvoice()
+	inv.Payment = &invoice.PaymentDetails{BIC: "COBADEFFXXX"}
+	var warnings []error
+	opts := invoice.GenerateOptions{OnWarning: func(err error) { warnings = append(warnings, err) }}
+	if _, err := invoice.Generate(context.Background(), "test-epc", inv, outputPath, opts); err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0].Error(), "leaving out the payment QR code: no IBAN") {
+		t.Errorf("warnings = %v, want one about the missing IBAN", warnings)
+	}
+	data, err := os.ReadFile(outputPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "data:image/png") {
+		t.Errorf("no QR code should be embedded: %s", data)
+	}
+}
+
+func TestBuildPrompt_PaymentDetails(t *testing.T) {
+	prompt := invoice.BuildPrompt(epcInvoice(), "invoice.html")
+	for _, want := range []string{
+		"- IBAN: DE89 3704 0044 0532 0130 00\n",
+		"- BIC: COBADEFFXXX\n",
+		`<img src="` + invoice.EPCQRPlaceholder + `"`,
+	} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt is missing %q:\n%s", want, prompt)
+		}
+	}
+
+	// Details that cannot be encoded are still shown, without the QR code.
+	inv := epcInvoice()
+	inv.Payment.BIC = ""
+	inv.Rate = 0
+	prompt = invoice.BuildPrompt(inv, "invoice.html")
+	if !strings.Contains(prompt, "- IBAN: ") || strings.Contains(prompt, invoice.EPCQRPlaceholder) {
+		t.Errorf("prompt should show the IBAN without the QR code:\n%s", prompt)
+	}
+}
@@ -0,0 +1,44 @@
+package invoice_test
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+func ExampleWeeksForMonth() {
+	for _, w := range invoice.WeeksForMonth(2025, time.January, 40) {
+		fmt.Println(invoice.FormatWeekLabel(w), w.Hours)
+	}
+	// Output:
+	// Jan 1-5 24
+	// Jan 6-12 40
+	// Jan 13-19 40
+	// Jan 20-26 40
+	// Jan 27-31 40
+}
+
+func ExampleInvoice_Total() {
+	inv := &invoice.Invoice{
+		Month:    time.January,
+		Year:     2025,
+		Vendor:   "Jane Smith",
+		Customer: "Acme Corp",
+		Rate:     150,
+		Weeks:    invoice.WeeksForMonth(2025, time.January, 40),
+	}
+	fmt.Printf("%s %.2f\n", invoice.InvoiceNumber(inv), inv.Total())
+	// Output: INV-202501-acme-corp 27600.00
+}
+
+func ExampleResolveMonthYear() {
+	now := time.Date(2025, time.January, 10, 0, 0, 0, 0, time.UTC)
+	month, year, err := invoice.ResolveMonthYear("december", 0, now)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println(month, year)
+	// Output: December 2024
+}
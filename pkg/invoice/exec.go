@@ -0,0 +1,145 @@
+package invoice
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// waitDelay bounds how long a cancelled subprocess's output pipes are
+// drained before Wait gives up on them.
+const waitDelay = 2 * time.Second
+
+// command returns an exec.Cmd that is killed, together with any processes
+// it started, when ctx is done.
+func command(ctx context.Context, name string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, name, args...)
+	killProcessGroup(cmd)
+	cmd.WaitDelay = waitDelay
+	return cmd
+}
+
+// ToolOutput, if set, receives the diagnostic output of the tools invoicer
+// runs as it is written. It is nil by default so the tools run quietly.
+var ToolOutput io.Writer
+
+// maxToolOutput is how much of a tool's diagnostic output is kept to
+// explain a failure.
+const maxToolOutput = 4 << 10
+
+// run runs cmd with its stderr, and its stdout unless that is already set,
+// captured. If cmd fails, the error ends with the last few kilobytes of what
+// it wrote there.
+func run(cmd *exec.Cmd) error {
+	_, err := runTail(cmd)
+	return err
+}
+
+// runTail is like run but also returns the captured output, for callers
+// that can find a failure the tool did not report.
+func runTail(cmd *exec.Cmd) (*tailBuffer, error) {
+	tail := &tailBuffer{max: maxToolOutput}
+	cmd.Stderr = tail
+	if ToolOutput != nil {
+		cmd.Stderr = io.MultiWriter(tail, ToolOutput)
+	}
+	if cmd.Stdout == nil {
+		cmd.Stdout = cmd.Stderr
+	}
+	err := cmd.Run()
+	if err != nil && tail.Len() > 0 {
+		return tail, fmt.Errorf("%w: %s", err, tail)
+	}
+	return tail, err
+}
+
+// tailBuffer is an io.Writer that keeps only the last max bytes written.
+type tailBuffer struct {
+	max  int
+	buf  []byte
+	full bool
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	n := len(p)
+	b.buf = append(b.buf, p...)
+	if len(b.buf) > b.max {
+		b.buf = append(b.buf[:0], b.buf[len(b.buf)-b.max:]...)
+		b.full = true
+	}
+	return n, nil
+}
+
+// Len returns the number of bytes kept.
+func (b *tailBuffer) Len() int { return len(b.buf) }
+
+// String returns the kept output, trimmed, marking where earlier output was dropped.
+func (b *tailBuffer) String() string {
+	s := strings.TrimSpace(string(b.buf))
+	if b.full {
+		s = "..." + s
+	}
+	return s
+}
+
+// removeIfCreated deletes path if it exists now but did not when existed was
+// recorded, so a failed run never leaves a partial file behind and never
+// deletes one it did not create.
+func removeIfCreated(path string, existed bool) {
+	if !existed {
+		_ = os.Remove(path)
+	}
+}
+
+// stagingPath returns a path next to path, unique to this run, to write to
+// before the result is renamed into place.
+func stagingPath(path string) string {
+	return fmt.Sprintf("%s.tmp-%08x", path, rand.Uint32())
+}
+
+// FileState identifies the contents of a file at some moment, so a later
+// FileState tells whether it has changed since.
+type FileState struct {
+	Exists  bool
+	ModTime time.Time
+	Size    int64
+	Sum     [sha256.Size]byte
+}
+
+// StatFile returns the current state of path. A file that cannot be read
+// counts as absent.
+func StatFile(path string) FileState {
+	f, err := os.Open(path)
+	if err != nil {
+		return FileState{}
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return FileState{}
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return FileState{}
+	}
+	state := FileState{Exists: true, ModTime: info.ModTime(), Size: info.Size()}
+	h.Sum(state.Sum[:0])
+	return state
+}
+
+// Same reports whether s and o describe the same file contents.
+func (s FileState) Same(o FileState) bool {
+	return s.Exists == o.Exists && s.ModTime.Equal(o.ModTime) && s.Size == o.Size && s.Sum == o.Sum
+}
+
+// exists reports whether path exists.
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package invoice
+
+import "os/exec"
+
+// killProcessGroup leaves cancellation to exec.CommandContext, which kills
+// only cmd itself on this platform.
+func killProcessGroup(cmd *exec.Cmd) {}
@@ -0,0 +1,236 @@
+package invoice
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultFreshBooksURL is the base URL of the FreshBooks API.
+const DefaultFreshBooksURL = "https://api.freshbooks.com"
+
+// FreshBooksTimeout bounds each request to the FreshBooks API.
+const FreshBooksTimeout = 30 * time.Second
+
+// FreshBooksRetries is how many times a request FreshBooks rate limited is
+// retried.
+const FreshBooksRetries = 3
+
+// FreshBooksClient calls the FreshBooks accounting API of one account with
+// an OAuth access token.
+type FreshBooksClient struct {
+	// Token is the OAuth bearer token.
+	Token string
+	// AccountID is the ID of the accounting account, e.g. "zDmNq".
+	AccountID string
+	// BaseURL is the API's base URL; "" means DefaultFreshBooksURL. Tests
+	// point it at a fake server.
+	BaseURL string
+	// HTTP sends the requests; nil means a client with FreshBooksTimeout.
+	HTTP *http.Client
+}
+
+// FreshBooksError is a request FreshBooks refused.
+type FreshBooksError struct {
+	// StatusCode is the HTTP status of FreshBooks' reply, or 0 if there was
+	// none.
+	StatusCode int
+	// Message is what FreshBooks, or the connection, said went wrong.
+	Message string
+}
+
+func (e *FreshBooksError) Error() string {
+	if e.StatusCode == http.StatusUnauthorized {
+		return "FreshBooks authentication failed (check the token): " + e.Message
+	}
+	return "FreshBooks invoice creation failed: " + e.Message
+}
+
+// FreshBooksOptions say who a FreshBooks invoice is for.
+type FreshBooksOptions struct {
+	// ClientID is the ID of the FreshBooks client the invoice is for.
+	ClientID string
+}
+
+// FreshBooksInvoice is the invoice SendFreshBooks created.
+type FreshBooksInvoice struct {
+	ID     int64  `json:"id"`
+	Number string `json:"invoice_number"`
+	Status string `json:"v3_status"`
+}
+
+// SendFreshBooks creates a draft FreshBooks invoice for inv with the
+// invoice number, issue date, due date and one line per week. A request
+// FreshBooks rate limits is retried after the delay it asks for, or with
+// backoff, up to FreshBooksRetries times.
+func SendFreshBooks(ctx context.Context, c *FreshBooksClient, inv *Invoice, opts FreshBooksOptions) (*FreshBooksInvoice, error) {
+	if c.Token == "" {
+		return nil, &FreshBooksError{StatusCode: http.StatusUnauthorized, Message: "no token is configured"}
+	}
+	if c.AccountID == "" {
+		return nil, errors.New("a FreshBooks account ID is required")
+	}
+	if opts.ClientID == "" {
+		return nil, errors.New("a FreshBooks client ID is required")
+	}
+	customer, err := strconv.ParseInt(opts.ClientID, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("FreshBooks client ID %q is not a number", opts.ClientID)
+	}
+	lines, _ := invoiceLines(inv)
+	if len(lines) == 0 {
+		return nil, errors.New("a FreshBooks invoice needs at least one line")
+	}
+
+	var body freshBooksInvoiceRequest
+	r := &body.Invoice
+	r.CustomerID = customer
+	r.Number = InvoiceNumber(inv)
+	r.CreateDate = formatJSONDate(inv.IssueDate())
+	if !inv.Due.IsZero() {
+		r.DueOffsetDays = int(inv.Due.Sub(inv.IssueDate()).Round(24*time.Hour) / (24 * time.Hour))
+	}
+	r.Currency = Currency
+	r.Notes = fmt.Sprintf("Services %s %d", inv.Month, inv.Year)
+	for _, l := range lines {
+		r.Lines = append(r.Lines, freshBooksLine{
+			Name:        l.Description,
+			Description: l.rateText(),
+			Quantity:    formatQuantity(l.Quantity),
+			UnitCost:    freshBooksAmount{Amount: l.Price.String(), Code: Currency},
+		})
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	var reply struct {
+		Response struct {
+			Result struct {
+				Invoice FreshBooksInvoice `json:"invoice"`
+			} `json:"result"`
+		} `json:"response"`
+	}
+	path := "/accounting/account/" + url.PathEscape(c.AccountID) + "/invoices/invoices"
+	if err := c.post(ctx, path, data, &reply); err != nil {
+		return nil, err
+	}
+	created := reply.Response.Result.Invoice
+	if created.ID == 0 {
+		return nil, &FreshBooksError{Message: "the reply has no invoice ID"}
+	}
+	return &created, nil
+}
+
+// freshBooksInvoiceRequest is the body of a request creating an invoice.
+type freshBooksInvoiceRequest struct {
+	Invoice struct {
+		CustomerID    int64            `json:"customerid"`
+		Number        string           `json:"invoice_number"`
+		CreateDate    string           `json:"create_date"`
+		DueOffsetDays int              `json:"due_offset_days,omitempty"`
+		Currency      string           `json:"currency_code"`
+		Notes         string           `json:"notes"`
+		Lines         []freshBooksLine `json:"lines"`
+	} `json:"invoice"`
+}
+
+type freshBooksLine struct {
+	Name        string           `json:"name"`
+	Description string           `json:"description"`
+	Quantity    string           `json:"qty"`
+	UnitCost    freshBooksAmount `json:"unit_cost"`
+}
+
+type freshBooksAmount struct {
+	Amount string `json:"amount"`
+	Code   string `json:"code"`
+}
+
+// post sends data to path, retrying while FreshBooks rate limits it, and
+// decodes the reply into out.
+func (c *FreshBooksClient) post(ctx context.Context, path string, data []byte, out any) error {
+	base := c.BaseURL
+	if base == "" {
+		base = DefaultFreshBooksURL
+	}
+	client := c.HTTP
+	if client == nil {
+		client = &http.Client{Timeout: FreshBooksTimeout}
+	}
+	for attempt := 1; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(base, "/")+path, bytes.NewReader(data))
+		if err != nil {
+			return &FreshBooksError{Message: err.Error()}
+		}
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := client.Do(req)
+		if err != nil {
+			return &FreshBooksError{Message: err.Error()}
+		}
+		reply, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return &FreshBooksError{StatusCode: resp.StatusCode, Message: err.Error()}
+		}
+		if resp.StatusCode == http.StatusTooManyRequests && attempt <= FreshBooksRetries {
+			delay := backoff(attempt)
+			if s, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && s >= 0 {
+				delay = time.Duration(s) * time.Second
+			}
+			if err := Sleep(ctx, delay); err != nil {
+				return err
+			}
+			continue
+		}
+		if resp.StatusCode/100 != 2 {
+			return &FreshBooksError{StatusCode: resp.StatusCode, Message: fmt.Sprintf("%s: %s", resp.Status, freshBooksReplyMessage(reply))}
+		}
+		if err := json.Unmarshal(reply, out); err != nil {
+			return &FreshBooksError{StatusCode: resp.StatusCode, Message: fmt.Sprintf("reading the reply: %v", err)}
+		}
+		return nil
+	}
+}
+
+// freshBooksReplyMessage returns what a FreshBooks error reply says went
+// wrong: the messages of its errors, with the fields they are about, or its
+// OAuth error description.
+func freshBooksReplyMessage(data []byte) string {
+	var reply struct {
+		Response struct {
+			Errors []struct {
+				Field   string `json:"field"`
+				Message string `json:"message"`
+			} `json:"errors"`
+		} `json:"response"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if json.Unmarshal(data, &reply) == nil {
+		var msgs []string
+		for _, e := range reply.Response.Errors {
+			if e.Field != "" {
+				msgs = append(msgs, e.Field+": "+e.Message)
+			} else {
+				msgs = append(msgs, e.Message)
+			}
+		}
+		if len(msgs) > 0 {
+			return strings.Join(msgs, "; ")
+		}
+		if reply.ErrorDescription != "" {
+			return reply.ErrorDescription
+		}
+	}
+	return strings.TrimSpace(string(data))
+}
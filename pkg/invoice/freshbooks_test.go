@@ -0,0 +1,173 @@
+package invoice_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+const freshBooksInvoicePath = "/accounting/account/zDmNq/invoices/invoices"
+
+// freshBooksServer starts a fake FreshBooks API that answers each request
+// with the next of replies, and records the requests' bodies.
+func freshBooksServer(t *testing.T, replies []apiReply, bodies *[][]byte) *invoice.FreshBooksClient {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != freshBooksInvoicePath || len(replies) == 0 {
+			http.NotFound(w, r)
+			return
+		}
+		if h := r.Header.Get("Authorization"); h != "Bearer fb-token" {
+			t.Errorf("Authorization = %q", h)
+		}
+		body, _ := io.ReadAll(r.Body)
+		if bodies != nil {
+			*bodies = append(*bodies, body)
+		}
+		reply := replies[0]
+		replies = replies[1:]
+		data, err := os.ReadFile(filepath.Join("testdata", reply.fixture))
+		if err != nil {
+			t.Error(err)
+		}
+		if reply.status == http.StatusTooManyRequests {
+			w.Header().Set("Retry-After", "7")
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(reply.status)
+		w.Write(data)
+	}))
+	t.Cleanup(srv.Close)
+	return &invoice.FreshBooksClient{Token: "fb-token", AccountID: "zDmNq", BaseURL: srv.URL, HTTP: srv.Client()}
+}
+
+func TestSendFreshBooks(t *testing.T) {
+	var bodies [][]byte
+	client := freshBooksServer(t, []apiReply{{http.StatusOK, "freshbooks-invoice.json"}}, &bodies)
+	inv := jsonInvoice()
+	created, err := invoice.SendFreshBooks(context.Background(), client, inv, invoice.FreshBooksOptions{ClientID: "81224"})
+	if err != nil {
+		t.Fatalf("SendFreshBooks: %v", err)
+	}
+	want := invoice.FreshBooksInvoice{ID: 730105, Number: "INV-202501-acme-corp", Status: "draft"}
+	if *created != want {
+		t.Errorf("created %+v, want %+v", *created, want)
+	}
+
+	var body struct {
+		Invoice struct {
+			CustomerID    int64  `json:"customerid"`
+			Number        string `json:"invoice_number"`
+			CreateDate    string `json:"create_date"`
+			DueOffsetDays int    `json:"due_offset_days"`
+			Currency      string `json:"currency_code"`
+			Lines         []struct {
+				Name     string `json:"name"`
+				Quantity string `json:"qty"`
+				UnitCost struct {
+					Amount string `json:"amount"`
+					Code   string `json:"code"`
+				} `json:"unit_cost"`
+			} `json:"lines"`
+		} `json:"invoice"`
+	}
+	if err := json.Unmarshal(bodies[0], &body); err != nil {
+		t.Fatalf("request body: %v\n%s", err, bodies[0])
+	}
+	b := body.Invoice
+	if b.CustomerID != 81224 || b.Number != "INV-202501-acme-corp" || b.CreateDate != "2025-02-01" || b.DueOffsetDays != 30 || b.Currency != "USD" {
+		t.Errorf("invoice = %+v", b)
+	}
+	if len(b.Lines) != len(inv.Weeks) {
+		t.Fatalf("got %d lines, want %d", len(b.Lines), len(inv.Weeks))
+	}
+	for i, l := range b.Lines {
+		if want := "Services " + invoice.FormatWeekLabel(inv.Weeks[i]); l.Name != want || l.UnitCost.Amount != "150.00" || l.UnitCost.Code != "USD" {
+			t.Errorf("line %d = %+v, want %q at 150.00 USD", i, l, want)
+		}
+	}
+	if b.Lines[0].Quantity != "32" {
+		t.Errorf("first line quantity = %q, want 32", b.Lines[0].Quantity)
+	}
+}
+
+func TestSendFreshBooks_RetriesRateLimit(t *testing.T) {
+	delays := fakeSleep(t)
+	var bodies [][]byte
+	client := freshBooksServer(t, []apiReply{
+		{http.StatusTooManyRequests, "freshbooks-rate-limited.json"},
+		{http.StatusOK, "freshbooks-invoice.json"},
+	}, &bodies)
+	created, err := invoice.SendFreshBooks(context.Background(), client, jsonInvoice(), invoice.FreshBooksOptions{ClientID: "81224"})
+	if err != nil {
+		t.Fatalf("SendFreshBooks: %v", err)
+	}
+	if created.ID != 730105 {
+		t.Errorf("created %+v", *created)
+	}
+	if len(bodies) != 2 || string(bodies[0]) != string(bodies[1]) {
+		t.Errorf("expected the same request twice, got %d", len(bodies))
+	}
+	if len(*delays) != 1 || (*delays)[0] != 7*time.Second {
+		t.Errorf("delays = %v, want the 7s Retry-After", *delays)
+	}
+}
+
+func TestSendFreshBooks_GivesUpOnRateLimit(t *testing.T) {
+	delays := fakeSleep(t)
+	replies := make([]apiReply, invoice.FreshBooksRetries+1)
+	for i := range replies {
+		replies[i] = apiReply{http.StatusTooManyRequests, "freshbooks-rate-limited.json"}
+	}
+	client := freshBooksServer(t, replies, nil)
+	_, err := invoice.SendFreshBooks(context.Background(), client, jsonInvoice(), invoice.FreshBooksOptions{ClientID: "81224"})
+	var ferr *invoice.FreshBooksError
+	if !errors.As(err, &ferr) || ferr.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("expected a rate limit FreshBooksError, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "Too many requests") {
+		t.Errorf("error = %v", err)
+	}
+	if len(*delays) != invoice.FreshBooksRetries {
+		t.Errorf("slept %d times, want %d", len(*delays), invoice.FreshBooksRetries)
+	}
+}
+
+func TestSendFreshBooks_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		reply   apiReply
+		wantErr string
+	}{
+		{"client not found", apiReply{http.StatusUnprocessableEntity, "freshbooks-client-not-found.json"}, "FreshBooks invoice creation failed: 422 Unprocessable Entity: customerid: Client not found."},
+		{"unauthenticated", apiReply{http.StatusUnauthorized, "freshbooks-unauthenticated.json"}, "FreshBooks authentication failed (check the token): 401 Unauthorized: This action requires authentication"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := freshBooksServer(t, []apiReply{tt.reply}, nil)
+			_, err := invoice.SendFreshBooks(context.Background(), client, jsonInvoice(), invoice.FreshBooksOptions{ClientID: "99999"})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestSendFreshBooks_BadClientID(t *testing.T) {
+	client := &invoice.FreshBooksClient{Token: "fb-token", AccountID: "zDmNq"}
+	for _, id := range []string{"", "acme"} {
+		if _, err := invoice.SendFreshBooks(context.Background(), client, jsonInvoice(), invoice.FreshBooksOptions{ClientID: id}); err == nil {
+			t.Errorf("client ID %q: expected an error", id)
+		}
+	}
+}
@@ -0,0 +1,339 @@
+package invoice
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Exchange rate providers an FXClient fetches rates from.
+const (
+	// FXProviderECB is the European Central Bank's daily reference rates,
+	// which need no account.
+	FXProviderECB = "ecb"
+	// FXProviderExchangeRateHost is exchangerate.host, which needs an
+	// access key.
+	FXProviderExchangeRateHost = "exchangerate.host"
+	// DefaultFXProvider is the provider used unless another is configured.
+	DefaultFXProvider = FXProviderECB
+)
+
+// FXManual is the Source of an exchange rate that was given rather than
+// fetched.
+const FXManual = "manual"
+
+// ECBRatesURL lists the ECB's reference rates of the last 90 days.
+const ECBRatesURL = "https://www.ecb.europa.eu/stats/eurofxref/eurofxref-hist-90d.xml"
+
+// ExchangeRateHostURL is the base URL of the exchangerate.host API.
+const ExchangeRateHostURL = "https://api.exchangerate.host"
+
+// FXTimeout bounds each request for an exchange rate.
+const FXTimeout = 30 * time.Second
+
+// FXProviders returns the exchange rate providers an FXClient knows.
+func FXProviders() []string {
+	return []string{FXProviderECB, FXProviderExchangeRateHost}
+}
+
+// ValidateFXProvider returns an error if provider is not one of
+// FXProviders.
+func ValidateFXProvider(provider string) error {
+	if !slices.Contains(FXProviders(), provider) {
+		return fmt.Errorf("exchange rate provider %q is not one of %s", provider, strings.Join(FXProviders(), ", "))
+	}
+	return nil
+}
+
+var currencyCode = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// ValidateCurrency returns an error if code is not shaped like an ISO 4217
+// currency code, such as "EUR".
+func ValidateCurrency(code string) error {
+	if !currencyCode.MatchString(code) {
+		return fmt.Errorf("currency %q is not a three-letter ISO 4217 code such as EUR", code)
+	}
+	return nil
+}
+
+// ExchangeRate is the rate an invoice's dollar amounts are billed at in
+// another currency. Every amount is converted at this one rate.
+type ExchangeRate struct {
+	// Currency is the ISO 4217 code of the currency billed in, e.g. "EUR".
+	Currency string
+	// Rate is how much of Currency one dollar buys, e.g. 0.93.
+	Rate float64
+	// Date is the day the rate is of.
+	Date time.Time
+	// Source is where the rate came from: one of FXProviders, or FXManual.
+	Source string
+}
+
+// Convert returns the dollar amount c in x's currency, rounded to cents.
+func (x *ExchangeRate) Convert(c Cents) Cents {
+	return ToCents(c.Dollars() * x.Rate)
+}
+
+// String describes the rate with its source and date, e.g.
+// "1 USD = 0.93 EUR (ecb, 2025-01-31)".
+func (x *ExchangeRate) String() string {
+	return fmt.Sprintf("1 %s = %s %s (%s, %s)", Currency, formatFXRate(x.Rate), x.Currency, x.Source, x.Date.Format(time.DateOnly))
+}
+
+// formatFXRate returns rate with at least two and at most six decimals,
+// e.g. "0.93" or "0.961234".
+func formatFXRate(rate float64) string {
+	s := strconv.FormatFloat(rate, 'f', 6, 64)
+	s = strings.TrimRight(s, "0")
+	if i := strings.IndexByte(s, '.'); len(s)-i-1 < 2 {
+		s += strings.Repeat("0", 2-(len(s)-i-1))
+	}
+	return s
+}
+
+// currencySymbols are the symbols amounts in a currency are shown with;
+// other currencies are shown with their code, e.g. "CHF 120.00".
+var currencySymbols = map[string]string{
+	"USD": "$",
+	"EUR": "€",
+	"GBP": "£",
+}
+
+// FormatMoneyIn returns amount in currency with cents, e.g. "€4800.00" or
+// "CHF 4800.00", as FormatMoney does for dollars.
+func FormatMoneyIn(amount float64, currency string) string {
+	return moneyPrefix(currency) + ToCents(amount).String()
+}
+
+// moneyPrefix returns what comes before an amount in currency.
+func moneyPrefix(currency string) string {
+	if s, ok := currencySymbols[currency]; ok {
+		return s
+	}
+	return currency + " "
+}
+
+// FXClient fetches exchange rates from a provider.
+type FXClient struct {
+	// Provider is one of FXProviders; "" means DefaultFXProvider.
+	Provider string
+	// APIKey is the access key of providers that need one.
+	APIKey string
+	// BaseURL is the provider's URL: ECBRatesURL or ExchangeRateHostURL
+	// if "". Tests point it at a fake server.
+	BaseURL string
+	// HTTP sends the requests; nil means a client with FXTimeout.
+	HTTP *http.Client
+}
+
+// Rate fetches the rate of dollars to currency on date, or on the last day
+// before it with a rate, as rates are not published on weekends and
+// holidays.
+func (c *FXClient) Rate(ctx context.Context, currency string, date time.Time) (*ExchangeRate, error) {
+	provider := c.Provider
+	if provider == "" {
+		provider = DefaultFXProvider
+	}
+	var (
+		x   *ExchangeRate
+		err error
+	)
+	switch provider {
+	case FXProviderECB:
+		x, err = c.ecbRate(ctx, currency, date)
+	case FXProviderExchangeRateHost:
+		x, err = c.exchangeRateHostRate(ctx, currency, date)
+	default:
+		err = ValidateFXProvider(provider)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("fetching the %s to %s rate from %s: %w", Currency, currency, provider, err)
+	}
+	x.Source = provider
+	return x, nil
+}
+
+// ecbEnvelope is the part of the ECB's rates XML Rate reads: the euro
+// rates of each day, newest first.
+type ecbEnvelope struct {
+	Days []struct {
+		Time  string `xml:"time,attr"`
+		Rates []struct {
+			Currency string  `xml:"currency,attr"`
+			Rate     float64 `xml:"rate,attr"`
+		} `xml:"Cube"`
+	} `xml:"Cube>Cube"`
+}
+
+// ecbRate returns the rate of the latest day on or before date in the
+// ECB's rates. The ECB quotes rates against the euro, so the dollar rate
+// is crossed through it.
+func (c *FXClient) ecbRate(ctx context.Context, currency string, date time.Time) (*ExchangeRate, error) {
+	base := c.BaseURL
+	if base == "" {
+		base = ECBRatesURL
+	}
+	data, err := c.get(ctx, base)
+	if err != nil {
+		return nil, err
+	}
+	var env ecbEnvelope
+	if err := xml.Unmarshal(data, &env); err != nil {
+		return nil, fmt.Errorf("reading the rates: %w", err)
+	}
+	want := date.Format(time.DateOnly)
+	for _, day := range env.Days {
+		if day.Time > want {
+			continue
+		}
+		perEuro := map[string]float64{"EUR": 1}
+		for _, r := range day.Rates {
+			perEuro[r.Currency] = r.Rate
+		}
+		dollars, ok := perEuro[Currency]
+		if !ok || dollars <= 0 {
+			return nil, fmt.Errorf("no %s rate on %s", Currency, day.Time)
+		}
+		rate, ok := perEuro[currency]
+		if !ok {
+			return nil, fmt.Errorf("the ECB publishes no rate for %s", currency)
+		}
+		d, err := time.Parse(time.DateOnly, day.Time)
+		if err != nil {
+			return nil, fmt.Errorf("reading the rates: %q is not a date", day.Time)
+		}
+		return &ExchangeRate{Currency: currency, Rate: rate / dollars, Date: d}, nil
+	}
+	return nil, fmt.Errorf("no rate on or before %s", want)
+}
+
+// exchangeRateHostReply is exchangerate.host's reply to a historical rate
+// request.
+type exchangeRateHostReply struct {
+	Success bool               `json:"success"`
+	Date    string             `json:"date"`
+	Quotes  map[string]float64 `json:"quotes"`
+	Error   struct {
+		Code int    `json:"code"`
+		Info string `json:"info"`
+	} `json:"error"`
+}
+
+// exchangeRateHostRate returns exchangerate.host's rate on date.
+func (c *FXClient) exchangeRateHostRate(ctx context.Context, currency string, date time.Time) (*ExchangeRate, error) {
+	if c.APIKey == "" {
+		return nil, errors.New("an access key is required (set fx_api_key in config)")
+	}
+	base := c.BaseURL
+	if base == "" {
+		base = ExchangeRateHostURL
+	}
+	query := url.Values{
+		"access_key": {c.APIKey},
+		"date":       {date.Format(time.DateOnly)},
+		"source":     {Currency},
+		"currencies": {currency},
+	}
+	data, err := c.get(ctx, strings.TrimRight(base, "/")+"/historical?"+query.Encode())
+	if err != nil {
+		return nil, err
+	}
+	var reply exchangeRateHostReply
+	if err := json.Unmarshal(data, &reply); err != nil {
+		return nil, fmt.Errorf("reading the reply: %w", err)
+	}
+	if !reply.Success {
+		return nil, fmt.Errorf("%s (code %d)", reply.Error.Info, reply.Error.Code)
+	}
+	rate, ok := reply.Quotes[Currency+currency]
+	if !ok || rate <= 0 {
+		return nil, fmt.Errorf("no rate for %s", currency)
+	}
+	d, err := time.Parse(time.DateOnly, reply.Date)
+	if err != nil {
+		d = date
+	}
+	return &ExchangeRate{Currency: currency, Rate: rate, Date: d}, nil
+}
+
+// get returns the body of a successful GET of rawURL.
+func (c *FXClient) get(ctx context.Context, rawURL string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	client := c.HTTP
+	if client == nil {
+		client = &http.Client{Timeout: FXTimeout}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s", resp.Status)
+	}
+	return data, nil
+}
+
+// formatAmount returns the dollar amount c as inv shows amounts: in
+// dollars, or converted into the currency inv is billed in.
+func (inv *Invoice) formatAmount(c Cents) string {
+	if x := inv.Exchange; x != nil {
+		return moneyPrefix(x.Currency) + x.Convert(c).String()
+	}
+	return FormatMoney(c.Dollars())
+}
+
+// BilledAmountDue returns the amount due of b in the currency inv is
+// billed in. Each line is converted and rounded on its own, and the total
+// summed from those, so the converted lines add up to it. Without an
+// Exchange it is b.AmountDue.
+func (inv *Invoice) BilledAmountDue(b InvoiceBreakdown) Cents {
+	x := inv.Exchange
+	if x == nil {
+		return b.AmountDue
+	}
+	var due Cents
+	for _, l := range b.Lines {
+		due += x.Convert(l.Amount)
+	}
+	return due - x.Convert(b.Discount) + x.Convert(b.Tax) - x.Convert(b.Withholding)
+}
+
+// FormatAmountDue returns the amount due of b as inv shows it: in dollars,
+// e.g. "$10800.00", or in the currency billed in followed by the dollar
+// amount and the rate, e.g. "€10044.00 (USD 10800.00 @ 0.93)".
+func (inv *Invoice) FormatAmountDue(b InvoiceBreakdown) string {
+	x := inv.Exchange
+	if x == nil {
+		return FormatMoney(b.AmountDue.Dollars())
+	}
+	return fmt.Sprintf("%s%s (%s %s @ %s)", moneyPrefix(x.Currency), inv.BilledAmountDue(b), Currency, b.AmountDue, formatFXRate(x.Rate))
+}
+
+// fxSourceName returns how an invoice names the source of a rate.
+func fxSourceName(source string) string {
+	switch source {
+	case FXProviderECB:
+		return "ECB"
+	case FXManual:
+		return "agreed"
+	}
+	return source
+}
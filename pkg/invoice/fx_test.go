@@ -0,0 +1,183 @@
+package invoice_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+// ecbRates is the ECB's rates XML for two days, newest first.
+const ecbRates = `<?xml version="1.0" encoding="UTF-8"?>
+<gesmes:Envelope xmlns:gesmes="http://www.gesmes.org/xml/2002-08-01" xmlns="http://www.ecb.int/vocabulary/2002-08-01/eurofxref">
+	<gesmes:subject>Reference rates</gesmes:subject>
+	<Cube>
+		<Cube time="2025-02-03">
+			<Cube currency="USD" rate="1.0250"/>
+			<Cube currency="GBP" rate="0.8300"/>
+		</Cube>
+		<Cube time="2025-01-31">
+			<Cube currency="USD" rate="1.0000"/>
+			<Cube currency="GBP" rate="0.8000"/>
+		</Cube>
+	</Cube>
+</gesmes:Envelope>`
+
+func euroInvoice() *invoice.Invoice {
+	inv := testInvoice()
+	inv.Exchange = &invoice.ExchangeRate{Currency: "EUR", Rate: 0.93, Date: time.Date(2025, time.January, 31, 0, 0, 0, 0, time.UTC), Source: invoice.FXManual}
+	return inv
+}
+
+func TestExchangeRate_Amounts(t *testing.T) {
+	inv := euroInvoice()
+	b := inv.Breakdown()
+	if got := inv.BilledAmountDue(b); got != 1004400 {
+		t.Errorf("BilledAmountDue = %v, want 10044.00", got)
+	}
+	if got, want := inv.FormatAmountDue(b), "€10044.00 (USD 10800.00 @ 0.93)"; got != want {
+		t.Errorf("FormatAmountDue = %q, want %q", got, want)
+	}
+	if got := inv.FormatRate(inv.Rate); got != "€139.50" {
+		t.Errorf("FormatRate = %q, want the converted rate €139.50", got)
+	}
+	// The amounts stay in dollars.
+	if inv.Total() != 10800 {
+		t.Errorf("Total() = %v, want 10800 dollars", inv.Total())
+	}
+
+	// Converted lines are rounded on their own, and the total adds them up.
+	inv.Exchange.Rate = 0.333
+	inv.Adjustments = []invoice.Adjustment{{Description: "Fee", Amount: 0.05}}
+	b = inv.Breakdown()
+	var sum invoice.Cents
+	for _, l := range b.Lines {
+		sum += inv.Exchange.Convert(l.Amount)
+	}
+	if got := inv.BilledAmountDue(b); got != sum {
+		t.Errorf("BilledAmountDue = %v, want the sum of the converted lines, %v", got, sum)
+	}
+
+	if got := invoice.FormatMoneyIn(120, "CHF"); got != "CHF 120.00" {
+		t.Errorf("FormatMoneyIn(CHF) = %q", got)
+	}
+}
+
+func TestExchangeRate_Rendering(t *testing.T) {
+	inv := euroInvoice()
+	var text bytes.Buffer
+	if err := invoice.WriteText(&text, inv); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"€4464.00", "€5580.00", "Total due: €10044.00 (USD 10800.00 @ 0.93)", "Exchange rate: 1 USD = 0.93 EUR (agreed, January 31, 2025)"} {
+		if !strings.Contains(text.String(), want) {
+			t.Errorf("text invoice does not have %q:\n%s", want, text.String())
+		}
+	}
+
+	prompt := invoice.BuildPrompt(inv, "invoice.html")
+	for _, want := range []string{"- Hourly Rate: €139.50", "= €4464.00", "Total Amount: €10044.00 (USD 10800.00 @ 0.93)", "Exchange Rate: 1 USD = 0.93 EUR"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt does not have %q:\n%s", want, prompt)
+		}
+	}
+
+	data, err := json.Marshal(inv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"exchange":{"currency":"EUR","rate":0.93,"date":"2025-01-31","source":"manual","total":10044}`) {
+		t.Errorf("JSON does not record the rate:\n%s", data)
+	}
+	var back invoice.Invoice
+	if err := json.Unmarshal(data, &back); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if back.Exchange == nil || *back.Exchange != *inv.Exchange {
+		t.Errorf("read back Exchange = %+v, want %+v", back.Exchange, inv.Exchange)
+	}
+}
+
+func TestFXClient_ECB(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(ecbRates))
+	}))
+	t.Cleanup(srv.Close)
+	client := &invoice.FXClient{BaseURL: srv.URL}
+
+	// February 2nd is a Sunday, so the rate is Friday's.
+	x, err := client.Rate(context.Background(), "EUR", time.Date(2025, time.February, 2, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Rate: %v", err)
+	}
+	if x.Rate != 1 || x.Date.Format(time.DateOnly) != "2025-01-31" || x.Source != invoice.FXProviderECB {
+		t.Errorf("Rate = %+v, want 1 on 2025-01-31 from the ECB", x)
+	}
+	x, err = client.Rate(context.Background(), "GBP", time.Date(2025, time.February, 3, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Rate: %v", err)
+	}
+	if math.Abs(x.Rate-0.83/1.025) > 1e-9 {
+		t.Errorf("GBP rate = %v, want the euro rates crossed, %v", x.Rate, 0.83/1.025)
+	}
+
+	if _, err := client.Rate(context.Background(), "JPY", time.Date(2025, time.February, 3, 0, 0, 0, 0, time.UTC)); err == nil || !strings.Contains(err.Error(), "no rate for JPY") {
+		t.Errorf("expected a missing currency error, got %v", err)
+	}
+	if _, err := client.Rate(context.Background(), "EUR", time.Date(2024, time.December, 31, 0, 0, 0, 0, time.UTC)); err == nil || !strings.Contains(err.Error(), "no rate on or before 2024-12-31") {
+		t.Errorf("expected a missing date error, got %v", err)
+	}
+}
+
+func TestFXClient_ExchangeRateHost(t *testing.T) {
+	var query string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.RawQuery
+		if r.URL.Query().Get("access_key") != "key" {
+			w.Write([]byte(`{"success":false,"error":{"code":101,"info":"You have not supplied a valid API Access Key."}}`))
+			return
+		}
+		w.Write([]byte(`{"success":true,"historical":true,"date":"2025-01-31","source":"USD","quotes":{"USDEUR":0.9612}}`))
+	}))
+	t.Cleanup(srv.Close)
+	end := time.Date(2025, time.January, 31, 0, 0, 0, 0, time.UTC)
+
+	client := &invoice.FXClient{Provider: invoice.FXProviderExchangeRateHost, APIKey: "key", BaseURL: srv.URL}
+	x, err := client.Rate(context.Background(), "EUR", end)
+	if err != nil {
+		t.Fatalf("Rate: %v", err)
+	}
+	if x.Rate != 0.9612 || !x.Date.Equal(end) || x.Source != invoice.FXProviderExchangeRateHost {
+		t.Errorf("Rate = %+v", x)
+	}
+	if !strings.Contains(query, "date=2025-01-31") || !strings.Contains(query, "currencies=EUR") {
+		t.Errorf("query = %q, want the date and currency", query)
+	}
+
+	client.APIKey = "wrong"
+	if _, err := client.Rate(context.Background(), "EUR", end); err == nil || !strings.Contains(err.Error(), "valid API Access Key. (code 101)") {
+		t.Errorf("expected the provider's error, got %v", err)
+	}
+	client.APIKey = ""
+	if _, err := client.Rate(context.Background(), "EUR", end); err == nil || !strings.Contains(err.Error(), "access key is required") {
+		t.Errorf("expected a missing key error, got %v", err)
+	}
+}
+
+func TestFXClient_Failure(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "down", http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(srv.Close)
+	_, err := (&invoice.FXClient{BaseURL: srv.URL}).Rate(context.Background(), "EUR", time.Now())
+	if err == nil || !strings.Contains(err.Error(), "fetching the USD to EUR rate from ecb: 503 Service Unavailable") {
+		t.Errorf("expected a fetch error, got %v", err)
+	}
+}
@@ -0,0 +1,267 @@
+package invoice
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// GenerateHTML prompts opencode to generate an HTML invoice and writes it to outputPath.
+// model is the opencode-formatted model stub (e.g. "anthropic/claude-haiku-4-5").
+// It is equivalent to Generate with the opencode backend.
+func GenerateHTML(inv *Invoice, model, outputPath string) error {
+	_, err := Generate(context.Background(), "opencode", inv, outputPath, GenerateOptions{Model: model})
+	return err
+}
+
+// promptIntro opens every generation prompt.
+const promptIntro = "Generate a professional HTML invoice for the following contract work. " +
+	"Use creative, unique styling with random color schemes and typography. " +
+	"Make it visually appealing and modern. "
+
+// styledPromptIntro opens the prompt for an invoice with a style reference,
+// in place of promptIntro.
+const styledPromptIntro = "Generate a professional HTML invoice for the following contract work. " +
+	"Style it like the previous invoice, whose stylesheet and layout are given below. "
+
+// intro returns the opening of the prompt for inv.
+func intro(inv *Invoice) string {
+	if inv.Style != nil {
+		return styledPromptIntro
+	}
+	return promptIntro
+}
+
+// BuildPrompt creates the opencode prompt for generating the HTML invoice.
+func BuildPrompt(inv *Invoice, outputPath string) string {
+	var sb strings.Builder
+
+	sb.WriteString(intro(inv))
+	sb.WriteString(fmt.Sprintf("Write the complete HTML (with embedded CSS) to the file: %s\n\n", outputPath))
+	writeInvoiceDetails(&sb, inv)
+	sb.WriteString("- Write the file using the write tool - do not output the HTML in text\n")
+
+	return sb.String()
+}
+
+// writeInvoiceDetails writes the invoice details and the design requirements
+// shared by all prompts.
+func writeInvoiceDetails(sb *strings.Builder, inv *Invoice) {
+	sb.WriteString(fmt.Sprintf("Invoice Details:\n"))
+	sb.WriteString(fmt.Sprintf("- Invoice Number: %s\n", InvoiceNumber(inv)))
+	sb.WriteString(fmt.Sprintf("- Vendor (Contractor): %s\n", inv.Vendor))
+	sb.WriteString(fmt.Sprintf("- Customer (Client): %s\n", inv.Customer))
+	sb.WriteString(fmt.Sprintf("- Month: %s %d\n", inv.Month.String(), inv.Year))
+	sb.WriteString(fmt.Sprintf("- Invoice Date: %s\n", inv.formatDate(inv.IssueDate())))
+	if !inv.Due.IsZero() {
+		sb.WriteString(fmt.Sprintf("- Due Date: %s\n", inv.formatDate(inv.Due)))
+	}
+	sb.WriteString(fmt.Sprintf("- Hourly Rate: %s\n", inv.FormatRate(inv.Rate)))
+	if inv.PONumber != "" {
+		sb.WriteString(fmt.Sprintf("- PO Number: %s\n", inv.PONumber))
+	}
+	if lang := NormalizeLanguage(inv.Language); lang != "" && lang != LanguageEnglish {
+		sb.WriteString(fmt.Sprintf("- Language: write the invoice in %s, with these labels: %s\n", lang, promptLabels(inv)))
+	}
+	sb.WriteString("\nWeekly Line Items:\n")
+
+	b := inv.Breakdown()
+	for _, l := range b.Lines {
+		if l.Unit == "" {
+			sb.WriteString(fmt.Sprintf("  - %s: %s\n", l.Description, inv.formatAmount(l.Amount)))
+			continue
+		}
+		unit := unitNameOf(l.Unit)
+		sb.WriteString(fmt.Sprintf("  - %s: %s %s @ %s/%s = %s\n",
+			l.Description, inv.FormatHours(l.Quantity), unit.Plural, inv.FormatRate(l.UnitPrice), unit.Per, inv.formatAmount(l.Amount)))
+	}
+
+	sb.WriteString(fmt.Sprintf("\nTotal Amount: %s\n", inv.FormatAmountDue(b)))
+	if x := inv.Exchange; x != nil {
+		sb.WriteString(fmt.Sprintf("Exchange Rate: 1 %s = %s %s, the %s rate of %s. Every amount is billed in %s, converted from %s at this rate.\n",
+			Currency, formatFXRate(x.Rate), x.Currency, fxSourceName(x.Source), inv.formatDate(x.Date), x.Currency, Currency))
+	}
+	if inv.Notes != "" {
+		sb.WriteString(fmt.Sprintf("\nNotes:\n%s\n", inv.Notes))
+	}
+	if p := inv.Payment; p != nil && p.hasAccount() {
+		sb.WriteString("\nPayment Details (bank transfer):\n")
+		if p.Name != "" {
+			sb.WriteString(fmt.Sprintf("- Account Holder: %s\n", p.Name))
+		}
+		if p.IBAN != "" {
+			sb.WriteString(fmt.Sprintf("- IBAN: %s\n", FormatIBAN(p.IBAN)))
+		}
+		if p.BIC != "" {
+			sb.WriteString(fmt.Sprintf("- BIC: %s\n", strings.ToUpper(p.BIC)))
+		}
+		if p.RoutingNumber != "" {
+			sb.WriteString(fmt.Sprintf("- Routing Number (ABA): %s\n", p.RoutingNumber))
+		}
+		if p.AccountNumber != "" {
+			sb.WriteString(fmt.Sprintf("- Account Number: %s\n", p.AccountNumber))
+		}
+	}
+	if inv.Style != nil {
+		writeStyleReference(sb, inv.Style)
+	}
+	sb.WriteString("\nRequirements:\n")
+	sb.WriteString("- Complete HTML5 document with embedded CSS styling\n")
+	if inv.Style != nil {
+		sb.WriteString("- The same visual identity as the style reference, not a new design\n")
+	} else {
+		sb.WriteString("- Unique, creative visual design with random color palette\n")
+	}
+	sb.WriteString("- Professional invoice layout with all line items shown in a table\n")
+	sb.WriteString("- Include the invoice date and the invoice number above, with every date written exactly as given\n")
+	sb.WriteString("- Show totals clearly\n")
+	if inv.PONumber != "" {
+		sb.WriteString("- Show the PO number with the invoice number\n")
+	}
+	if inv.Notes != "" {
+		sb.WriteString("- Show the notes below the totals, as written\n")
+	}
+	if inv.Payment != nil && inv.Payment.hasAccount() {
+		sb.WriteString("- Show the payment details near the total\n")
+		if _, err := EPCPayload(inv); err == nil {
+			sb.WriteString(fmt.Sprintf("- Next to the payment details, include exactly <img src=%q alt=\"Payment QR code\" width=\"150\" height=\"150\"> - keep its src as it is; it is replaced with a QR code\n", EPCQRPlaceholder))
+		}
+	}
+}
+
+// FormatMoney returns amount in dollars with cents, e.g. "$4800.00", as
+// every invoice format shows it.
+func FormatMoney(amount float64) string {
+	return "$" + ToCents(amount).String()
+}
+
+// FormatHours returns hours with one decimal place, e.g. "32.5".
+func FormatHours(hours float64) string {
+	return fmt.Sprintf("%.1f", hours)
+}
+
+// Precisions rates and hours are shown with unless an invoice sets its own.
+const (
+	DefaultRatePrecision  = 2
+	DefaultHoursPrecision = 1
+)
+
+// FormatRate returns rate in dollars as inv shows rates: with its
+// RatePrecision decimals, e.g. "$150" with 0 or "$150.00" by default, and
+// converted into the currency inv is billed in if it has an Exchange.
+func (inv *Invoice) FormatRate(rate float64) string {
+	prefix := "$"
+	if x := inv.Exchange; x != nil {
+		rate, prefix = rate*x.Rate, moneyPrefix(x.Currency)
+	}
+	if inv.RatePrecision == nil || *inv.RatePrecision == 2 {
+		return prefix + ToCents(rate).String()
+	}
+	return fmt.Sprintf("%s%.*f", prefix, *inv.RatePrecision, rate)
+}
+
+// FormatHours returns hours as inv shows hours: with its HoursPrecision
+// decimals, e.g. "32.5" by default or "32.50" with 2.
+func (inv *Invoice) FormatHours(hours float64) string {
+	if inv.HoursPrecision == nil {
+		return FormatHours(hours)
+	}
+	return fmt.Sprintf("%.*f", *inv.HoursPrecision, hours)
+}
+
+// FormatWeekLabel returns a human-readable label for a week range, in
+// the DateLong format. See WeekLabel.
+func FormatWeekLabel(w Week) string {
+	return WeekLabel(w, DateLong)
+}
+
+// OutputFilename returns the output filename for an invoice (without extension).
+func OutputFilename(inv *Invoice) string {
+	return fmt.Sprintf("invoice-%s-%d-%02d",
+		fileSlug(inv),
+		inv.Year,
+		int(inv.Month),
+	)
+}
+
+// InvoiceNumber returns the invoice number (e.g. "INV-202501-acme-corp",
+// or "INV-202501-acme-corp-jane-doe" for a PerVendor invoice).
+func InvoiceNumber(inv *Invoice) string {
+	return fmt.Sprintf("INV-%d%02d-%s", inv.Year, int(inv.Month), fileSlug(inv))
+}
+
+// fileSlug returns the part of inv's number and file names that names
+// its parties: the customer's slug, followed by the vendor's if inv is
+// PerVendor.
+func fileSlug(inv *Invoice) string {
+	if inv.PerVendor {
+		return customerSlug(inv.Customer) + "-" + customerSlug(inv.Vendor)
+	}
+	return customerSlug(inv.Customer)
+}
+
+// customerSlug returns the customer name lowercased with spaces replaced by dashes.
+func customerSlug(customer string) string {
+	return strings.ToLower(strings.ReplaceAll(customer, " ", "-"))
+}
+
+// InvoiceFilePath returns the full path for the HTML invoice file.
+func InvoiceFilePath(inv *Invoice, dir string) string {
+	return filepath.Join(dir, OutputFilename(inv)+".html")
+}
+
+// PDFFilePath returns the full path for the PDF invoice file.
+func PDFFilePath(inv *Invoice, dir string) string {
+	return filepath.Join(dir, OutputFilename(inv)+".pdf")
+}
+
+// ThumbnailFilePath returns the full path for the PNG thumbnail of the invoice.
+func ThumbnailFilePath(inv *Invoice, dir string) string {
+	return filepath.Join(dir, OutputFilename(inv)+".png")
+}
+
+// TextFilePath returns the full path for the plain-text invoice file.
+func TextFilePath(inv *Invoice, dir string) string {
+	return filepath.Join(dir, OutputFilename(inv)+".txt")
+}
+
+// JSONFilePath returns the full path for the invoice's data as JSON.
+func JSONFilePath(inv *Invoice, dir string) string {
+	return filepath.Join(dir, OutputFilename(inv)+".json")
+}
+
+// UBLFilePath returns the full path for the invoice as a UBL e-invoice.
+func UBLFilePath(inv *Invoice, dir string) string {
+	return filepath.Join(dir, OutputFilename(inv)+".xml")
+}
+
+// PromptFilePath returns the full path for a saved generation prompt.
+func PromptFilePath(inv *Invoice, dir string) string {
+	return filepath.Join(dir, OutputFilename(inv)+".prompt.txt")
+}
+
+// CurrentDir returns the working directory, falling back to temp dir.
+func CurrentDir() string {
+	if dir, err := os.Getwd(); err == nil {
+		return dir
+	}
+	return os.TempDir()
+}
+
+// Now returns the current time. Exposed as a variable for testing.
+var Now = func() time.Time {
+	return time.Now()
+}
+
+// promptLabels lists the invoice's labels for the prompt, e.g.
+// "Invoice: RECHNUNG, Date: Datum, ...".
+func promptLabels(inv *Invoice) string {
+	var labels []string
+	for _, key := range []string{"invoice", "date", "period", "bill_to", "description", "hours", "rate", "amount", "total", "notes"} {
+		labels = append(labels, Label(nil, LanguageEnglish, key)+": "+inv.label(key))
+	}
+	return strings.Join(labels, ", ")
+}
@@ -1,15 +1,16 @@
 package invoice_test
 
 import (
-	"encoding/json"
-	"fmt"
+	"context"
+	"errors"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
-	"github.com/zon/invoicer/internal/invoice"
+	"github.com/zon/invoicer/pkg/invoice"
 )
 
 // testInvoice returns a sample invoice for use in tests.
@@ -90,6 +91,32 @@ func TestBuildPrompt_ContainsWeekLineItems(t *testing.T) {
 	}
 }
 
+func TestBuildPrompt_ShowWorkdays(t *testing.T) {
+	// January 2025 starts on a Wednesday, so its first week is prorated to
+	// three workdays; the second is a full week.
+	inv := testInvoice()
+	inv.Weeks = invoice.WeeksForMonth(2025, time.January, 40)[:2]
+	if strings.Contains(invoice.BuildPrompt(inv, "/tmp/invoice.html"), "workdays") {
+		t.Error("the prompt shows workdays without ShowWorkdays")
+	}
+
+	inv.ShowWorkdays = true
+	prompt := invoice.BuildPrompt(inv, "/tmp/invoice.html")
+	for _, want := range []string{
+		"  - Jan 1-5 (3 workdays): 24.0 hours @ $150.00/hr = $3600.00\n",
+		"  - Jan 6-12 (5 workdays): 40.0 hours @ $150.00/hr = $6000.00\n",
+	} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt does not contain %q:\n%s", want, prompt)
+		}
+	}
+
+	inv.Language = "de"
+	if want := "1.-5. Jan. (3 Arbeitstage)"; inv.LineItems()[0].Description != want {
+		t.Errorf("the first week is %q in German, want %q", inv.LineItems()[0].Description, want)
+	}
+}
+
 func TestBuildPrompt_ContainsTotal(t *testing.T) {
 	inv := testInvoice()
 	// Total = (32 + 40) * 150 = 10800.00
@@ -123,113 +150,28 @@ func TestBuildPrompt_InstructsWriteToolNotText(t *testing.T) {
 	}
 }
 
-// --- CheckOpencodeOutput tests ---
-
-// makeToolUseEvent creates a JSON line representing an opencode tool_use event.
-func makeToolUseEvent(tool, filePath, status string) string {
-	type writeInput struct {
-		FilePath string `json:"filePath"`
-	}
-	input, _ := json.Marshal(writeInput{FilePath: filePath})
-	type toolState struct {
-		Status string          `json:"status"`
-		Input  json.RawMessage `json:"input"`
-		Output string          `json:"output"`
-	}
-	type toolPart struct {
-		Tool  string    `json:"tool"`
-		State toolState `json:"state"`
-	}
-	type event struct {
-		Type string          `json:"type"`
-		Part json.RawMessage `json:"part"`
-	}
-	partJSON, _ := json.Marshal(toolPart{
-		Tool: tool,
-		State: toolState{
-			Status: status,
-			Input:  input,
-		},
-	})
-	ev, _ := json.Marshal(event{Type: "tool_use", Part: partJSON})
-	return string(ev)
-}
-
-func TestCheckOpencodeOutput_SuccessWithWriteEvent(t *testing.T) {
-	expectedPath := "/tmp/invoice.html"
-	line := makeToolUseEvent("write", expectedPath, "completed")
-	err := invoice.CheckOpencodeOutput([]byte(line), expectedPath)
-	if err != nil {
-		t.Errorf("expected success, got error: %v", err)
-	}
-}
-
-func TestCheckOpencodeOutput_FailsWhenWrongPath(t *testing.T) {
-	line := makeToolUseEvent("write", "/tmp/other.html", "completed")
-	err := invoice.CheckOpencodeOutput([]byte(line), "/tmp/invoice.html")
-	// No file on disk either, so should fail.
-	if err == nil {
-		t.Error("expected error for wrong path, got nil")
-	}
-}
-
-func TestCheckOpencodeOutput_FailsWhenStatusNotCompleted(t *testing.T) {
-	expectedPath := "/tmp/invoice.html"
-	line := makeToolUseEvent("write", expectedPath, "error")
-	err := invoice.CheckOpencodeOutput([]byte(line), expectedPath)
-	// No file on disk, so should fail.
-	if err == nil {
-		t.Error("expected error for non-completed status, got nil")
-	}
-}
-
-func TestCheckOpencodeOutput_FallbackToFileExistence(t *testing.T) {
-	// Create a temp file to simulate the output file existing on disk.
-	tmpFile, err := os.CreateTemp("", "invoice-*.html")
-	if err != nil {
-		t.Fatal(err)
-	}
-	defer os.Remove(tmpFile.Name())
-	tmpFile.Close()
-
-	// No write event in output, but file exists on disk — should succeed.
-	err = invoice.CheckOpencodeOutput([]byte(""), tmpFile.Name())
-	if err != nil {
-		t.Errorf("expected success via file existence fallback, got: %v", err)
-	}
-}
-
-func TestCheckOpencodeOutput_FailsWhenNoEventAndNoFile(t *testing.T) {
-	err := invoice.CheckOpencodeOutput([]byte(""), "/tmp/nonexistent-invoice-xyz.html")
-	if err == nil {
-		t.Error("expected error when no event and no file, got nil")
-	}
-}
+// --- GenerateHTML tests ---
 
-func TestCheckOpencodeOutput_IgnoresNonWriteTools(t *testing.T) {
-	expectedPath := "/tmp/invoice.html"
-	// A tool_use event for a different tool (e.g. "bash") should be ignored.
-	line := makeToolUseEvent("bash", expectedPath, "completed")
-	err := invoice.CheckOpencodeOutput([]byte(line), expectedPath)
-	// No file on disk, so should fail.
-	if err == nil {
-		t.Error("expected error when only non-write tool events present, got nil")
-	}
+// promptedPath returns the file a generation prompt asks the model to write:
+// the staging file that Generate renames into place once it is verified.
+func promptedPath(prompt string) string {
+	_, rest, _ := strings.Cut(prompt, "to the file: ")
+	path, _, _ := strings.Cut(rest, "\n")
+	return path
 }
 
-func TestCheckOpencodeOutput_IgnoresInvalidJSON(t *testing.T) {
-	expectedPath := "/tmp/invoice.html"
-	// Mix of invalid JSON lines and a valid write event.
-	validLine := makeToolUseEvent("write", expectedPath, "completed")
-	output := fmt.Sprintf("not json\n%s\n{invalid}", validLine)
-	err := invoice.CheckOpencodeOutput([]byte(output), expectedPath)
-	if err != nil {
-		t.Errorf("expected success despite invalid JSON lines, got: %v", err)
-	}
+// TestBuildPrompt_Golden pins the prompts of an hourly invoice, so the
+// line items read the same to the model whatever produces them.
+func TestBuildPrompt_Golden(t *testing.T) {
+	inv := testInvoice()
+	inv.Weeks = invoice.WeeksForMonth(2025, time.January, 37.5)
+	inv.Rate = 187.5
+	inv.Issued = time.Date(2025, time.February, 1, 0, 0, 0, 0, time.UTC)
+	inv.Due = time.Date(2025, time.March, 3, 0, 0, 0, 0, time.UTC)
+	checkGolden(t, "prompt.txt", []byte(invoice.BuildPrompt(inv, "/tmp/invoice.html")))
+	checkGolden(t, "prompt-ollama.txt", []byte(invoice.BuildOllamaPrompt(inv)))
 }
 
-// --- GenerateHTML tests ---
-
 func TestGenerateHTML_CallsOpencodeWithCorrectArgs(t *testing.T) {
 	inv := testInvoice()
 	tmpDir := t.TempDir()
@@ -241,15 +183,15 @@ func TestGenerateHTML_CallsOpencodeWithCorrectArgs(t *testing.T) {
 	origExec := invoice.OpencodeExec
 	defer func() { invoice.OpencodeExec = origExec }()
 
-	invoice.OpencodeExec = func(model, dir, prompt string) ([]byte, error) {
-		capturedModel = model
-		capturedDir = dir
-		capturedPrompt = prompt
+	invoice.OpencodeExec = func(ctx context.Context, r invoice.OpencodeRun, stdout io.Writer) error {
+		capturedModel = r.Model
+		capturedDir = r.Dir
+		capturedPrompt = r.Prompt
 		// Write a fake HTML file so CheckOpencodeOutput succeeds via fallback.
-		if err := os.WriteFile(outputPath, []byte("<html>fake</html>"), 0644); err != nil {
-			return nil, err
+		if err := os.WriteFile(promptedPath(r.Prompt), []byte("<html>fake</html>"), 0644); err != nil {
+			return err
 		}
-		return []byte(""), nil
+		return nil
 	}
 
 	if err := invoice.GenerateHTML(inv, "anthropic/claude-haiku-4-5", outputPath); err != nil {
@@ -276,12 +218,12 @@ func TestGenerateHTML_WritesHTMLFile(t *testing.T) {
 	defer func() { invoice.OpencodeExec = origExec }()
 
 	// Fake opencode writes the expected HTML file.
-	invoice.OpencodeExec = func(model, dir, prompt string) ([]byte, error) {
+	invoice.OpencodeExec = func(ctx context.Context, r invoice.OpencodeRun, stdout io.Writer) error {
 		content := "<html><body>Invoice</body></html>"
-		if err := os.WriteFile(outputPath, []byte(content), 0644); err != nil {
-			return nil, err
+		if err := os.WriteFile(promptedPath(r.Prompt), []byte(content), 0644); err != nil {
+			return err
 		}
-		return []byte(""), nil
+		return nil
 	}
 
 	if err := invoice.GenerateHTML(inv, "anthropic/claude-haiku-4-5", outputPath); err != nil {
@@ -302,8 +244,8 @@ func TestGenerateHTML_ReturnsErrorWhenOpencodeFailsAndNoFile(t *testing.T) {
 	defer func() { invoice.OpencodeExec = origExec }()
 
 	// Fake opencode returns empty output without writing a file.
-	invoice.OpencodeExec = func(model, dir, prompt string) ([]byte, error) {
-		return []byte(""), nil
+	invoice.OpencodeExec = func(ctx context.Context, r invoice.OpencodeRun, stdout io.Writer) error {
+		return nil
 	}
 
 	err := invoice.GenerateHTML(inv, "anthropic/claude-haiku-4-5", outputPath)
@@ -321,12 +263,13 @@ func TestGenerateHTML_UsesWriteEventToConfirmSuccess(t *testing.T) {
 	defer func() { invoice.OpencodeExec = origExec }()
 
 	// Fake opencode that returns a valid write event AND writes the file.
-	invoice.OpencodeExec = func(model, dir, prompt string) ([]byte, error) {
-		if err := os.WriteFile(outputPath, []byte("<html>fake</html>"), 0644); err != nil {
-			return nil, err
+	invoice.OpencodeExec = func(ctx context.Context, r invoice.OpencodeRun, stdout io.Writer) error {
+		if err := os.WriteFile(promptedPath(r.Prompt), []byte("<html>fake</html>"), 0644); err != nil {
+			return err
 		}
-		event := makeToolUseEvent("write", outputPath, "completed")
-		return []byte(event), nil
+		event := makeToolUseEvent("write", promptedPath(r.Prompt), "completed")
+		_, err := io.WriteString(stdout, event)
+		return err
 	}
 
 	if err := invoice.GenerateHTML(inv, "anthropic/claude-haiku-4-5", outputPath); err != nil {
@@ -334,6 +277,61 @@ func TestGenerateHTML_UsesWriteEventToConfirmSuccess(t *testing.T) {
 	}
 }
 
+// failingOpencode fakes an opencode run that exits non-zero after
+// optionally writing a good invoice.
+func failingOpencode(t *testing.T, outputPath string, write bool) {
+	t.Helper()
+	origExec := invoice.OpencodeExec
+	t.Cleanup(func() { invoice.OpencodeExec = origExec })
+	invoice.OpencodeExec = func(ctx context.Context, r invoice.OpencodeRun, stdout io.Writer) error {
+		if write {
+			if err := os.WriteFile(promptedPath(r.Prompt), []byte("<html><body>Invoice</body></html>"), 0o644); err != nil {
+				return err
+			}
+			io.WriteString(stdout, makeToolUseEvent("write", promptedPath(r.Prompt), "completed")+"\n")
+		}
+		return errors.New("exit status 1: session cleanup failed")
+	}
+}
+
+func TestGenerate_OpencodeFailsAfterWriting(t *testing.T) {
+	outputPath := filepath.Join(t.TempDir(), "invoice.html")
+	failingOpencode(t, outputPath, true)
+
+	var warnings []error
+	opts := invoice.GenerateOptions{Model: "anthropic/claude-haiku-4-5", OnWarning: func(err error) { warnings = append(warnings, err) }}
+	if _, err := invoice.Generate(context.Background(), "opencode", testInvoice(), outputPath, opts); err != nil {
+		t.Fatalf("expected success with a warning, got %v", err)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0].Error(), "exit status 1: session cleanup failed") {
+		t.Errorf("warnings = %v, want one with the exit status and stderr", warnings)
+	}
+}
+
+func TestGenerate_OpencodeFailsWithoutWriting(t *testing.T) {
+	outputPath := filepath.Join(t.TempDir(), "invoice.html")
+	failingOpencode(t, outputPath, false)
+
+	_, err := invoice.Generate(context.Background(), "opencode", testInvoice(), outputPath, invoice.GenerateOptions{Model: "anthropic/claude-haiku-4-5"})
+	if err == nil || !strings.Contains(err.Error(), "running opencode: exit status 1") {
+		t.Errorf("expected the opencode failure, got %v", err)
+	}
+}
+
+func TestGenerate_OpencodeFailsAfterWritingStrictExit(t *testing.T) {
+	outputPath := filepath.Join(t.TempDir(), "invoice.html")
+	failingOpencode(t, outputPath, true)
+
+	opts := invoice.GenerateOptions{Model: "anthropic/claude-haiku-4-5", StrictExit: true}
+	_, err := invoice.Generate(context.Background(), "opencode", testInvoice(), outputPath, opts)
+	if err == nil || !strings.Contains(err.Error(), "running opencode: exit status 1") {
+		t.Errorf("expected strict mode to fail, got %v", err)
+	}
+	if _, err := os.Stat(outputPath); !os.IsNotExist(err) {
+		t.Errorf("the invoice of a failed run should be removed, stat err = %v", err)
+	}
+}
+
 // --- ConvertToPDF tests ---
 
 func TestConvertToPDF_UsesAvailableTool(t *testing.T) {
@@ -341,8 +339,8 @@ func TestConvertToPDF_UsesAvailableTool(t *testing.T) {
 	tmpDir := t.TempDir()
 
 	fakeWkhtmltopdf := filepath.Join(tmpDir, "wkhtmltopdf")
-	// Write a shell script that creates the output PDF file.
-	script := "#!/bin/sh\ntouch \"$2\"\n"
+	// Write a shell script that writes a minimal PDF to the output path.
+	script := "#!/bin/sh\nprintf '%%PDF-1.4\\n%%%%EOF\\n' > \"$2\"\n"
 	if err := os.WriteFile(fakeWkhtmltopdf, []byte(script), 0755); err != nil {
 		t.Fatal(err)
 	}
@@ -359,7 +357,7 @@ func TestConvertToPDF_UsesAvailableTool(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	if err := invoice.ConvertToPDF(htmlPath, pdfPath); err != nil {
+	if _, err := invoice.ConvertToPDF(context.Background(), htmlPath, pdfPath, invoice.PDFOptions{}); err != nil {
 		t.Fatalf("ConvertToPDF() error: %v", err)
 	}
 
@@ -374,7 +372,7 @@ func TestConvertToPDF_ReturnsErrorWhenNoToolFound(t *testing.T) {
 	os.Setenv("PATH", "")
 	defer os.Setenv("PATH", origPath)
 
-	err := invoice.ConvertToPDF("/tmp/invoice.html", "/tmp/invoice.pdf")
+	_, err := invoice.ConvertToPDF(context.Background(), "/tmp/invoice.html", "/tmp/invoice.pdf", invoice.PDFOptions{})
 	if err == nil {
 		t.Error("expected error when no PDF tool is available, got nil")
 	}
@@ -1,11 +1,12 @@
 package invoice_test
 
 import (
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
-	"github.com/zon/invoicer/internal/invoice"
+	"github.com/zon/invoicer/pkg/invoice"
 )
 
 func TestOutputFilename(t *testing.T) {
@@ -34,6 +35,22 @@ func TestOutputFilename_SimpleCustomer(t *testing.T) {
 	}
 }
 
+func TestOutputFilename_PerVendor(t *testing.T) {
+	inv := &invoice.Invoice{
+		Vendor:    "Jane Doe",
+		Customer:  "Acme Corp",
+		PerVendor: true,
+		Year:      2025,
+		Month:     time.January,
+	}
+	if got, want := invoice.OutputFilename(inv), "invoice-acme-corp-jane-doe-2025-01"; got != want {
+		t.Errorf("OutputFilename() = %q, want %q", got, want)
+	}
+	if got, want := invoice.InvoiceNumber(inv), "INV-202501-acme-corp-jane-doe"; got != want {
+		t.Errorf("InvoiceNumber() = %q, want %q", got, want)
+	}
+}
+
 func TestInvoiceFilePath(t *testing.T) {
 	inv := &invoice.Invoice{
 		Customer: "Stripe",
@@ -61,6 +78,18 @@ func TestPDFFilePath(t *testing.T) {
 	}
 }
 
+func TestThumbnailFilePath(t *testing.T) {
+	inv := &invoice.Invoice{
+		Customer: "Acme",
+		Year:     2025,
+		Month:    time.January,
+	}
+	got := invoice.ThumbnailFilePath(inv, "/tmp")
+	if want := filepath.Join("/tmp", "invoice-acme-2025-01.png"); got != want {
+		t.Errorf("ThumbnailFilePath() = %q, want %q", got, want)
+	}
+}
+
 func TestFormatWeekLabel_SameMonth(t *testing.T) {
 	w := invoice.Week{
 		Start: time.Date(2025, time.January, 6, 0, 0, 0, 0, time.UTC),
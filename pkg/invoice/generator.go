@@ -0,0 +1,327 @@
+package invoice
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Generator writes an HTML invoice using a particular backend.
+type Generator interface {
+	Generate(ctx context.Context, inv *Invoice, outputPath string, opts GenerateOptions) (*Result, error)
+}
+
+// GenerateOptions holds the backend settings for a single generation.
+// Each backend uses only the fields that apply to it.
+type GenerateOptions struct {
+	// Model is the opencode-formatted model stub used by the opencode and claude backends.
+	Model string
+
+	// OllamaHost is the URL of the ollama server.
+	OllamaHost string
+
+	// OllamaModel is the ollama model name.
+	OllamaModel string
+
+	// Agent, if set, is the opencode agent that writes the invoice.
+	Agent string
+
+	// Session, if set, is the opencode session to continue.
+	Session string
+
+	// DeniedTools are opencode tools the run may not use, e.g.
+	// DefaultDeniedTools. They are switched off, and a run that uses one
+	// anyway is stopped and fails without a retry.
+	DeniedTools []string
+
+	// FallbackModels are tried in order, in place of Model, once a model has
+	// used up its retries.
+	FallbackModels []string
+
+	// Retries is how many times a retryable failure is retried with each model.
+	Retries int
+
+	// OnRetry, if set, is called before waiting to retry after a failed attempt.
+	OnRetry func(attempt int, err error, delay time.Duration)
+
+	// OnFallback, if set, is called before switching to the next model after
+	// the previous one failed with err.
+	OnFallback func(model string, err error)
+
+	// OnUsage, if set, is called with what each step of the generation used
+	// as the backend reports it. Only the opencode backend reports usage.
+	OnUsage func(step Usage)
+
+	// MaxCost, if positive, is the most the generation may cost in US
+	// dollars over all attempts. Once the reported cost goes over it, the
+	// backend is stopped and no further attempts are made.
+	MaxCost float64
+
+	// SkipVerify accepts whatever the backend wrote without checking that
+	// it looks like an HTML document.
+	SkipVerify bool
+
+	// NoFormat keeps the HTML as the backend wrote it, instead of laying
+	// it out with FormatHTML.
+	NoFormat bool
+
+	// Minify strips the whitespace HTML ignores with MinifyHTML instead of
+	// laying the HTML out with FormatHTML.
+	Minify bool
+
+	// StrictExit makes a backend that exits with an error fail even if
+	// it wrote a good invoice first.
+	StrictExit bool
+
+	// OnWarning, if set, is called with a problem that did not stop the
+	// generation, such as opencode failing after it wrote the invoice.
+	OnWarning func(err error)
+
+	// Progress, if set, is called with each step of the generation as it
+	// happens, from building the prompt to verifying the invoice.
+	Progress func(event ProgressEvent)
+}
+
+// Result describes a generated invoice.
+type Result struct {
+	// Path is the HTML file that was written.
+	Path string
+
+	// Model is the model that wrote the invoice.
+	Model string
+
+	// Usage is what the generation used over all attempts, if the backend
+	// reports it.
+	Usage Usage
+
+	// SessionID is the opencode session the invoice was written in, which
+	// can be continued with GenerateOptions.Session.
+	SessionID string
+}
+
+// MaxAttempts caps the attempts Generate makes across all models.
+const MaxAttempts = 20
+
+// DefaultBackend is the backend used when none is configured.
+const DefaultBackend = "opencode"
+
+// generators holds the registered backends by name.
+var generators = map[string]Generator{}
+
+// Register makes a generator available under name.
+// It panics if name is already registered.
+func Register(name string, g Generator) {
+	if _, ok := generators[name]; ok {
+		panic(fmt.Sprintf("invoice: generator %q registered twice", name))
+	}
+	generators[name] = g
+}
+
+// Backends returns the names of the registered generators in sorted order.
+func Backends() []string {
+	names := make([]string, 0, len(generators))
+	for name := range generators {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Lookup returns the generator registered under name.
+func Lookup(name string) (Generator, error) {
+	if g, ok := generators[name]; ok {
+		return g, nil
+	}
+	return nil, fmt.Errorf("unknown backend %q (use one of: %s)", name, strings.Join(Backends(), ", "))
+}
+
+// Generate writes the invoice to outputPath with the named backend and
+// verifies the result, whichever backend produced it. A failed attempt is
+// retried up to opts.Retries times, with backoff, if IsRetryable allows;
+// after that each of opts.FallbackModels gets the same number of attempts,
+// up to MaxAttempts in all. If the cost goes over opts.MaxCost, Generate
+// stops and returns a *BudgetError.
+// The backend writes to a staging file next to outputPath, which is renamed
+// into place only once it has been verified, so a failed or cancelled run
+// never leaves a broken invoice behind nor touches an existing one. If the
+// file at outputPath changes while the backend runs, e.g. because another
+// run wrote it, Generate fails rather than replace it.
+// If inv has payment details, the invoice gets their EPC QR code; details
+// that cannot be encoded leave it out, with a warning to opts.OnWarning.
+func Generate(ctx context.Context, backend string, inv *Invoice, outputPath string, opts GenerateOptions) (*Result, error) {
+	g, err := Lookup(backend)
+	if err != nil {
+		return nil, err
+	}
+	before := StatFile(outputPath)
+	staged := stagingPath(outputPath)
+	defer os.Remove(staged)
+
+	warn := func(err error) {
+		if opts.OnWarning != nil {
+			opts.OnWarning(err)
+		}
+	}
+	var qrCode string
+	// The QR code pays an IBAN; a US account alone has none to leave out.
+	if p := inv.Payment; p != nil && (p.IBAN != "" || p.BIC != "") {
+		if qrCode, err = EPCQRDataURI(inv); err != nil {
+			warn(fmt.Errorf("leaving out the payment QR code: %w", err))
+		}
+	}
+
+	// Attempts run under runCtx so they can be stopped when over budget.
+	runCtx, stop := context.WithCancelCause(ctx)
+	defer stop(nil)
+	var total Usage
+	onUsage, maxCost := opts.OnUsage, opts.MaxCost
+	opts.OnUsage = func(step Usage) {
+		total.Add(step)
+		if onUsage != nil {
+			onUsage(step)
+		}
+		if maxCost > 0 && total.Cost > maxCost {
+			stop(&BudgetError{Limit: maxCost})
+		}
+	}
+
+	models := append([]string{opts.Model}, opts.FallbackModels...)
+	tried := 0
+	report := opts.Progress
+	opts.Progress = func(e ProgressEvent) {
+		if report == nil {
+			return
+		}
+		e.Backend, e.Attempt = backend, tried
+		if e.Model == "" {
+			e.Model = opts.Model
+		}
+		if e.Path == staged {
+			e.Path = outputPath
+		}
+		report(e)
+	}
+	for attempt := 1; ; attempt++ {
+		tried++
+		res, err := generateOnce(runCtx, g, backend, inv, staged, opts)
+		if err == nil {
+			if qrCode != "" {
+				if err := EmbedEPCQR(staged, qrCode); err != nil {
+					warn(err)
+				}
+			}
+			if !StatFile(outputPath).Same(before) {
+				return nil, fmt.Errorf("%s changed while the invoice was being generated; not replacing it", outputPath)
+			}
+			if err := os.Rename(staged, outputPath); err != nil {
+				return nil, fmt.Errorf("saving HTML invoice: %w", err)
+			}
+			res.Path = outputPath
+			res.Usage = total
+			return res, nil
+		}
+		_ = os.Remove(staged)
+		opts.progress(ProgressEvent{Kind: ProgressFailed, Err: err})
+		var budget *BudgetError
+		if errors.As(context.Cause(runCtx), &budget) {
+			return nil, &BudgetError{Limit: budget.Limit, Spent: total.Cost}
+		}
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("%s backend: %w", backend, ctx.Err())
+		}
+		if !IsRetryable(err) || tried >= MaxAttempts || (attempt > opts.Retries && len(models) == 1) {
+			if tried > 1 {
+				return nil, fmt.Errorf("giving up after %d attempts: %w", tried, err)
+			}
+			return nil, err
+		}
+		if attempt > opts.Retries {
+			models = models[1:]
+			opts.Model = models[0]
+			attempt = 0
+			if opts.OnFallback != nil {
+				opts.OnFallback(opts.Model, err)
+			}
+			continue
+		}
+		delay := backoff(attempt)
+		if opts.OnRetry != nil {
+			opts.OnRetry(attempt, err, delay)
+		}
+		if err := Sleep(ctx, delay); err != nil {
+			return nil, fmt.Errorf("%s backend: %w", backend, err)
+		}
+	}
+}
+
+// generateOnce makes a single generation attempt, formats its output
+// unless opts.NoFormat is set, and verifies it. Output that is not checked
+// is not formatted either, since it may not be HTML.
+func generateOnce(ctx context.Context, g Generator, backend string, inv *Invoice, outputPath string, opts GenerateOptions) (*Result, error) {
+	res, err := g.Generate(ctx, inv, outputPath, opts)
+	if err != nil {
+		return nil, err
+	}
+	if opts.SkipVerify {
+		return res, nil
+	}
+	if !opts.NoFormat {
+		if err := FormatHTMLFile(res.Path, opts.Minify); err != nil {
+			return nil, fmt.Errorf("%s backend: %w", backend, err)
+		}
+	}
+	if err := VerifyHTML(res.Path); err != nil {
+		return nil, fmt.Errorf("%s backend: %w", backend, err)
+	}
+	opts.progress(ProgressEvent{Kind: ProgressVerified, Path: res.Path})
+	return res, nil
+}
+
+// MaxHTMLSize is the largest generated invoice VerifyHTML accepts. A
+// one-page invoice is a few kilobytes even with inline styles.
+const MaxHTMLSize = 5 << 20
+
+// maxQuoted is how much of a rejected file VerifyHTML quotes.
+const maxQuoted = 200
+
+// VerifyHTML checks that a generated invoice exists, is not empty or
+// implausibly large, and looks like a whole HTML document: an <html> or
+// <!doctype html> opening and a closing </html> tag. The error for a file
+// that fails quotes the start of it.
+func VerifyHTML(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("HTML invoice missing: %w", err)
+	}
+	if info.Size() == 0 {
+		return fmt.Errorf("HTML invoice %s is empty", path)
+	}
+	if info.Size() > MaxHTMLSize {
+		return fmt.Errorf("HTML invoice %s is %d bytes, over the %d byte limit", path, info.Size(), MaxHTMLSize)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading HTML invoice: %w", err)
+	}
+	lower := bytes.ToLower(data)
+	switch {
+	case !bytes.Contains(lower, []byte("<html")) && !bytes.Contains(lower, []byte("<!doctype html")):
+		return fmt.Errorf("HTML invoice %s is not HTML; it starts with %q", path, quoteStart(data))
+	case !bytes.Contains(lower, []byte("</html>")):
+		return fmt.Errorf("HTML invoice %s has no closing </html> tag and may be truncated; it starts with %q", path, quoteStart(data))
+	}
+	return nil
+}
+
+// quoteStart returns the first maxQuoted bytes of data, marking the cut.
+func quoteStart(data []byte) string {
+	if len(data) <= maxQuoted {
+		return string(data)
+	}
+	return string(data[:maxQuoted]) + "..."
+}
@@ -0,0 +1,249 @@
+package invoice_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+// fileGenerator is a test backend that writes fixed content to the output path.
+type fileGenerator struct{ content string }
+
+func (g fileGenerator) Generate(ctx context.Context, inv *invoice.Invoice, outputPath string, opts invoice.GenerateOptions) (*invoice.Result, error) {
+	if err := os.WriteFile(outputPath, []byte(g.content), 0o644); err != nil {
+		return nil, err
+	}
+	return &invoice.Result{Path: outputPath}, nil
+}
+
+// partialGenerator is a test backend that writes part of the file and fails.
+type partialGenerator struct{}
+
+func (partialGenerator) Generate(ctx context.Context, inv *invoice.Invoice, outputPath string, opts invoice.GenerateOptions) (*invoice.Result, error) {
+	if err := os.WriteFile(outputPath, []byte("<html><body>"), 0o644); err != nil {
+		return nil, err
+	}
+	return nil, errors.New("connection reset")
+}
+
+// racingGenerator is a test backend that writes the invoice, and content
+// to the file it is to replace, as another run would.
+type racingGenerator struct{ content string }
+
+func (g racingGenerator) Generate(ctx context.Context, inv *invoice.Invoice, outputPath string, opts invoice.GenerateOptions) (*invoice.Result, error) {
+	target, _, _ := strings.Cut(outputPath, ".tmp-")
+	if err := os.WriteFile(target, []byte(g.content), 0o644); err != nil {
+		return nil, err
+	}
+	return fileGenerator{content: "<html></html>"}.Generate(ctx, inv, outputPath, opts)
+}
+
+func init() {
+	invoice.Register("test-html", fileGenerator{content: "<html></html>"})
+	invoice.Register("test-empty", fileGenerator{})
+	invoice.Register("test-truncated", fileGenerator{content: "<html><body>cut off"})
+	invoice.Register("test-partial", partialGenerator{})
+	invoice.Register("test-racing", racingGenerator{content: "<html>other run</html>"})
+	invoice.Register("test-epc", fileGenerator{content: `<html><body><img src="` + invoice.EPCQRPlaceholder + `"></body></html>`})
+}
+
+func TestBackends_IncludesBuiltins(t *testing.T) {
+	backends := invoice.Backends()
+	for _, name := range []string{"claude", "ollama", "opencode", invoice.DefaultBackend} {
+		if !slices.Contains(backends, name) {
+			t.Errorf("Backends() = %v, missing %q", backends, name)
+		}
+	}
+	if !slices.IsSorted(backends) {
+		t.Errorf("Backends() = %v, want sorted", backends)
+	}
+}
+
+func TestLookup_UnknownBackend(t *testing.T) {
+	_, err := invoice.Lookup("gpt")
+	if err == nil {
+		t.Fatal("expected error for unknown backend, got nil")
+	}
+	if !strings.Contains(err.Error(), `unknown backend "gpt"`) || !strings.Contains(err.Error(), "opencode") {
+		t.Errorf("expected error listing the backends, got %v", err)
+	}
+}
+
+func TestRegister_DuplicatePanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic registering a duplicate backend")
+		}
+	}()
+	invoice.Register("opencode", fileGenerator{})
+}
+
+func TestGenerate_UsesNamedBackend(t *testing.T) {
+	outputPath := filepath.Join(t.TempDir(), "invoice.html")
+	res, err := invoice.Generate(context.Background(), "test-html", testInvoice(), outputPath, invoice.GenerateOptions{NoFormat: true})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if res.Path != outputPath {
+		t.Errorf("Path = %q, want %q", res.Path, outputPath)
+	}
+}
+
+func TestGenerate_VerifiesOutputForEveryBackend(t *testing.T) {
+	outputPath := filepath.Join(t.TempDir(), "invoice.html")
+	_, err := invoice.Generate(context.Background(), "test-empty", testInvoice(), outputPath, invoice.GenerateOptions{})
+	if err == nil || !strings.Contains(err.Error(), "empty") {
+		t.Errorf("expected empty-file error from shared verification, got %v", err)
+	}
+}
+
+func TestVerifyHTML(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"empty", "", "is empty"},
+		{"apology", "I'm sorry, but I can't help with creating that invoice.", `is not HTML; it starts with "I'm sorry`},
+		{"truncated", "<!DOCTYPE html>\n<html><body><table><tr><td>Week 1", "no closing </html> tag"},
+		{"long garbage", strings.Repeat("x", 1000), `"` + strings.Repeat("x", 200) + `..."`},
+		{"good", "<!DOCTYPE html>\n<HTML><body>Invoice</body></HTML>\n", ""},
+		{"no doctype", "<html><body>Invoice</body></html>", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "invoice.html")
+			if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			err := invoice.VerifyHTML(path)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("VerifyHTML: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("VerifyHTML = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifyHTML_TooLarge(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "invoice.html")
+	if err := os.WriteFile(path, []byte("<html>"+strings.Repeat(" ", invoice.MaxHTMLSize)+"</html>"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := invoice.VerifyHTML(path); err == nil || !strings.Contains(err.Error(), "byte limit") {
+		t.Errorf("VerifyHTML = %v, want a size error", err)
+	}
+}
+
+func TestGenerate_SkipVerify(t *testing.T) {
+	outputPath := filepath.Join(t.TempDir(), "invoice.html")
+	if _, err := invoice.Generate(context.Background(), "test-truncated", testInvoice(), outputPath, invoice.GenerateOptions{}); err == nil {
+		t.Error("expected the truncated file to be rejected")
+	}
+	if _, err := invoice.Generate(context.Background(), "test-truncated", testInvoice(), outputPath, invoice.GenerateOptions{SkipVerify: true}); err != nil {
+		t.Errorf("expected SkipVerify to accept the file, got %v", err)
+	}
+}
+
+// assertNotWritten fails the test if path, or a staging file for it, exists.
+func assertNotWritten(t *testing.T, path string) {
+	t.Helper()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("%s should not exist, stat err = %v", path, err)
+	}
+	if staged, _ := filepath.Glob(path + ".tmp-*"); len(staged) > 0 {
+		t.Errorf("staging files left behind: %v", staged)
+	}
+}
+
+func TestGenerate_RemovesPartialFile(t *testing.T) {
+	outputPath := filepath.Join(t.TempDir(), "invoice.html")
+	_, err := invoice.Generate(context.Background(), "test-partial", testInvoice(), outputPath, invoice.GenerateOptions{})
+	if err == nil || !strings.Contains(err.Error(), "connection reset") {
+		t.Fatalf("expected the backend error, got %v", err)
+	}
+	assertNotWritten(t, outputPath)
+}
+
+func TestGenerate_RemovesEmptyFile(t *testing.T) {
+	outputPath := filepath.Join(t.TempDir(), "invoice.html")
+	if _, err := invoice.Generate(context.Background(), "test-empty", testInvoice(), outputPath, invoice.GenerateOptions{}); err == nil {
+		t.Fatal("expected error for empty output")
+	}
+	assertNotWritten(t, outputPath)
+}
+
+func TestGenerate_KeepsPreexistingFile(t *testing.T) {
+	outputPath := filepath.Join(t.TempDir(), "invoice.html")
+	if err := os.WriteFile(outputPath, []byte("<html>old</html>"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := invoice.Generate(context.Background(), "test-partial", testInvoice(), outputPath, invoice.GenerateOptions{}); err == nil {
+		t.Fatal("expected error")
+	}
+	if data, err := os.ReadFile(outputPath); err != nil || string(data) != "<html>old</html>" {
+		t.Errorf("a file that existed before the run must be left as it was, got %q, %v", data, err)
+	}
+	if staged, _ := filepath.Glob(outputPath + ".tmp-*"); len(staged) > 0 {
+		t.Errorf("staging files left behind: %v", staged)
+	}
+}
+
+func TestGenerate_RenamesStagedFile(t *testing.T) {
+	outputPath := filepath.Join(t.TempDir(), "invoice.html")
+	res, err := invoice.Generate(context.Background(), "test-html", testInvoice(), outputPath, invoice.GenerateOptions{NoFormat: true})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if res.Path != outputPath {
+		t.Errorf("Path = %q, want %q", res.Path, outputPath)
+	}
+	if data, err := os.ReadFile(outputPath); err != nil || string(data) != "<html></html>" {
+		t.Errorf("invoice = %q, %v", data, err)
+	}
+	if staged, _ := filepath.Glob(outputPath + ".tmp-*"); len(staged) > 0 {
+		t.Errorf("staging files left behind: %v", staged)
+	}
+}
+
+func TestGenerate_OutputChangedDuringRun(t *testing.T) {
+	for _, old := range []string{"", "<html>old</html>"} {
+		outputPath := filepath.Join(t.TempDir(), "invoice.html")
+		if old != "" {
+			if err := os.WriteFile(outputPath, []byte(old), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		_, err := invoice.Generate(context.Background(), "test-racing", testInvoice(), outputPath, invoice.GenerateOptions{NoFormat: true})
+		if err == nil || !strings.Contains(err.Error(), "changed while the invoice was being generated") {
+			t.Errorf("with old file %q: expected an error for a file changed during the run, got %v", old, err)
+		}
+		if data, err := os.ReadFile(outputPath); err != nil || string(data) != "<html>other run</html>" {
+			t.Errorf("with old file %q: the other run's file must be left as it was, got %q, %v", old, data, err)
+		}
+		if staged, _ := filepath.Glob(outputPath + ".tmp-*"); len(staged) > 0 {
+			t.Errorf("staging files left behind: %v", staged)
+		}
+	}
+}
+
+func TestGenerate_CancelledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	outputPath := filepath.Join(t.TempDir(), "invoice.html")
+	_, err := invoice.Generate(ctx, "test-partial", testInvoice(), outputPath, invoice.GenerateOptions{})
+	if !errors.Is(err, context.Canceled) || !strings.Contains(err.Error(), "canceled") {
+		t.Errorf("expected a canceled error, got %v", err)
+	}
+}
@@ -0,0 +1,302 @@
+package invoice
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Holiday is a public holiday: the day it is taken off work, at midnight
+// UTC, and its name, e.g. "Independence Day (observed)" when the holiday
+// falls on a weekend and is taken on a weekday instead.
+type Holiday struct {
+	Date time.Time
+	Name string
+}
+
+// holidayRule is a holiday of a calendar. date returns its date in a year,
+// and false if it is not kept that year.
+type holidayRule struct {
+	name string
+	date func(year int) (time.Time, bool)
+}
+
+// holidayCalendar is the public holidays of a country or of a region of
+// one.
+type holidayCalendar struct {
+	// parent is the code of the calendar this one adds holidays to, e.g.
+	// "DE" for "DE-BY", or "".
+	parent string
+	rules  []holidayRule
+	// observe is how a holiday on a weekend is taken on a weekday, or nil
+	// if it is not.
+	observe func(days []Holiday) []Holiday
+}
+
+// holidayCalendars are the calendars by region code: an ISO 3166-1
+// alpha-2 country code, or an ISO 3166-2 code for a region whose holidays
+// differ from its country's. Only holidays that are days off across the
+// region are kept, and one-off holidays, such as a coronation, are not.
+var holidayCalendars = map[string]holidayCalendar{
+	// US has the federal holidays.
+	"US": {
+		rules: []holidayRule{
+			{"New Year's Day", fixed(time.January, 1)},
+			{"Martin Luther King Jr. Day", nthWeekday(time.January, time.Monday, 3)},
+			{"Washington's Birthday", nthWeekday(time.February, time.Monday, 3)},
+			{"Memorial Day", nthWeekday(time.May, time.Monday, -1)},
+			{"Juneteenth", since(2021, fixed(time.June, 19))},
+			{"Independence Day", fixed(time.July, 4)},
+			{"Labor Day", nthWeekday(time.September, time.Monday, 1)},
+			{"Columbus Day", nthWeekday(time.October, time.Monday, 2)},
+			{"Veterans Day", fixed(time.November, 11)},
+			{"Thanksgiving Day", nthWeekday(time.November, time.Thursday, 4)},
+			{"Christmas Day", fixed(time.December, 25)},
+		},
+		observe: observeNearest,
+	},
+	// CA has the federal statutory holidays.
+	"CA": {
+		rules: []holidayRule{
+			{"New Year's Day", fixed(time.January, 1)},
+			{"Good Friday", easter(-2)},
+			{"Victoria Day", mondayBefore(time.May, 25)},
+			{"Canada Day", fixed(time.July, 1)},
+			{"Labour Day", nthWeekday(time.September, time.Monday, 1)},
+			{"National Day for Truth and Reconciliation", since(2021, fixed(time.September, 30))},
+			{"Thanksgiving", nthWeekday(time.October, time.Monday, 2)},
+			{"Remembrance Day", fixed(time.November, 11)},
+			{"Christmas Day", fixed(time.December, 25)},
+			{"Boxing Day", fixed(time.December, 26)},
+		},
+		observe: observeNext,
+	},
+	// GB has the bank holidays of England and Wales.
+	"GB": {
+		rules: []holidayRule{
+			{"New Year's Day", fixed(time.January, 1)},
+			{"Good Friday", easter(-2)},
+			{"Easter Monday", easter(1)},
+			{"Early May Bank Holiday", nthWeekday(time.May, time.Monday, 1)},
+			{"Spring Bank Holiday", nthWeekday(time.May, time.Monday, -1)},
+			{"Summer Bank Holiday", nthWeekday(time.August, time.Monday, -1)},
+			{"Christmas Day", fixed(time.December, 25)},
+			{"Boxing Day", fixed(time.December, 26)},
+		},
+		observe: observeNext,
+	},
+	"DE": {
+		rules: []holidayRule{
+			{"Neujahr", fixed(time.January, 1)},
+			{"Karfreitag", easter(-2)},
+			{"Ostermontag", easter(1)},
+			{"Tag der Arbeit", fixed(time.May, 1)},
+			{"Christi Himmelfahrt", easter(39)},
+			{"Pfingstmontag", easter(50)},
+			{"Tag der Deutschen Einheit", fixed(time.October, 3)},
+			{"1. Weihnachtstag", fixed(time.December, 25)},
+			{"2. Weihnachtstag", fixed(time.December, 26)},
+		},
+	},
+	"DE-BW": {
+		parent: "DE",
+		rules: []holidayRule{
+			{"Heilige Drei Könige", fixed(time.January, 6)},
+			{"Fronleichnam", easter(60)},
+			{"Allerheiligen", fixed(time.November, 1)},
+		},
+	},
+	// DE-BY has the holidays of the Catholic municipalities, most of
+	// Bavaria, with Mariä Himmelfahrt.
+	"DE-BY": {
+		parent: "DE",
+		rules: []holidayRule{
+			{"Heilige Drei Könige", fixed(time.January, 6)},
+			{"Fronleichnam", easter(60)},
+			{"Mariä Himmelfahrt", fixed(time.August, 15)},
+			{"Allerheiligen", fixed(time.November, 1)},
+		},
+	},
+	"DE-BE": {
+		parent: "DE",
+		rules: []holidayRule{
+			{"Internationaler Frauentag", since(2019, fixed(time.March, 8))},
+		},
+	},
+	"FR": {
+		rules: []holidayRule{
+			{"Jour de l'an", fixed(time.January, 1)},
+			{"Lundi de Pâques", easter(1)},
+			{"Fête du Travail", fixed(time.May, 1)},
+			{"Victoire 1945", fixed(time.May, 8)},
+			{"Ascension", easter(39)},
+			{"Lundi de Pentecôte", easter(50)},
+			{"Fête nationale", fixed(time.July, 14)},
+			{"Assomption", fixed(time.August, 15)},
+			{"Toussaint", fixed(time.November, 1)},
+			{"Armistice", fixed(time.November, 11)},
+			{"Noël", fixed(time.December, 25)},
+		},
+	},
+}
+
+// HolidayRegions returns the codes of the regions invoicer knows the
+// public holidays of, sorted.
+func HolidayRegions() []string {
+	return slices.Sorted(maps.Keys(holidayCalendars))
+}
+
+// NormalizeHolidayRegion returns region as it is looked up: in upper
+// case, with a hyphen before its subdivision, e.g. "DE-BY" for "de_by".
+func NormalizeHolidayRegion(region string) string {
+	return strings.ToUpper(strings.ReplaceAll(region, "_", "-"))
+}
+
+// ValidateHolidayRegion returns an error if invoicer does not know the
+// public holidays of region.
+func ValidateHolidayRegion(region string) error {
+	if _, ok := holidayCalendars[NormalizeHolidayRegion(region)]; !ok {
+		return fmt.Errorf("no public holidays known for %q (known: %s)", region, strings.Join(HolidayRegions(), ", "))
+	}
+	return nil
+}
+
+// Holidays returns the public holidays of region taken in year, sorted by
+// date. Where region takes a holiday on a weekend on a weekday instead,
+// as the US and the UK do, the weekday is returned, which may be in the
+// year before or after the holiday's; elsewhere the holiday stays on the
+// weekend, where it takes no workday off.
+func Holidays(region string, year int) ([]Holiday, error) {
+	if err := ValidateHolidayRegion(region); err != nil {
+		return nil, err
+	}
+	cal := holidayCalendars[NormalizeHolidayRegion(region)]
+	rules := cal.rules
+	for parent := cal.parent; parent != ""; parent = holidayCalendars[parent].parent {
+		rules = append(slices.Clip(holidayCalendars[parent].rules), rules...)
+	}
+
+	var days []Holiday
+	for y := year - 1; y <= year+1; y++ {
+		for _, r := range rules {
+			if d, ok := r.date(y); ok {
+				days = append(days, Holiday{Date: d, Name: r.name})
+			}
+		}
+	}
+	slices.SortStableFunc(days, func(a, b Holiday) int { return a.Date.Compare(b.Date) })
+	if cal.observe != nil {
+		days = cal.observe(days)
+	}
+	days = slices.DeleteFunc(days, func(h Holiday) bool { return h.Date.Year() != year })
+	slices.SortStableFunc(days, func(a, b Holiday) int { return a.Date.Compare(b.Date) })
+	return days, nil
+}
+
+// weekend reports whether d is a Saturday or a Sunday.
+func weekend(d time.Time) bool {
+	return d.Weekday() == time.Saturday || d.Weekday() == time.Sunday
+}
+
+// observeNearest takes a holiday on a Saturday on the Friday before, and
+// one on a Sunday on the Monday after, as US federal holidays are.
+func observeNearest(days []Holiday) []Holiday {
+	for i, h := range days {
+		switch h.Date.Weekday() {
+		case time.Saturday:
+			days[i] = Holiday{Date: h.Date.AddDate(0, 0, -1), Name: h.Name + " (observed)"}
+		case time.Sunday:
+			days[i] = Holiday{Date: h.Date.AddDate(0, 0, 1), Name: h.Name + " (observed)"}
+		}
+	}
+	return days
+}
+
+// observeNext takes a holiday on a weekend on the next weekday that is not
+// a holiday, as UK bank holidays are: Christmas on a Saturday is taken on
+// the Monday, and Boxing Day on the Tuesday. days are sorted by date.
+func observeNext(days []Holiday) []Holiday {
+	taken := make(map[time.Time]bool, len(days))
+	for _, h := range days {
+		if !weekend(h.Date) {
+			taken[h.Date] = true
+		}
+	}
+	for i, h := range days {
+		if !weekend(h.Date) {
+			continue
+		}
+		d := h.Date
+		for weekend(d) || taken[d] {
+			d = d.AddDate(0, 0, 1)
+		}
+		taken[d] = true
+		days[i] = Holiday{Date: d, Name: h.Name + " (observed)"}
+	}
+	return days
+}
+
+// fixed is a holiday on the same day every year.
+func fixed(month time.Month, day int) func(int) (time.Time, bool) {
+	return func(year int) (time.Time, bool) {
+		return time.Date(year, month, day, 0, 0, 0, 0, time.UTC), true
+	}
+}
+
+// nthWeekday is a holiday on the nth weekday of month, e.g. the third
+// Monday of January, or the last one of the month if n is -1.
+func nthWeekday(month time.Month, weekday time.Weekday, n int) func(int) (time.Time, bool) {
+	return func(year int) (time.Time, bool) {
+		if n < 0 {
+			last := time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC)
+			return last.AddDate(0, 0, -int((last.Weekday()-weekday+7)%7)), true
+		}
+		first := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+		return first.AddDate(0, 0, int((weekday-first.Weekday()+7)%7)+7*(n-1)), true
+	}
+}
+
+// mondayBefore is a holiday on the last Monday before day of month.
+func mondayBefore(month time.Month, day int) func(int) (time.Time, bool) {
+	return func(year int) (time.Time, bool) {
+		d := time.Date(year, month, day-1, 0, 0, 0, 0, time.UTC)
+		return d.AddDate(0, 0, -int((d.Weekday()-time.Monday+7)%7)), true
+	}
+}
+
+// easter is a holiday offset days from Easter Sunday, e.g. -2 for Good
+// Friday.
+func easter(offset int) func(int) (time.Time, bool) {
+	return func(year int) (time.Time, bool) {
+		return easterSunday(year).AddDate(0, 0, offset), true
+	}
+}
+
+// since is a holiday of date kept from first on.
+func since(first int, date func(int) (time.Time, bool)) func(int) (time.Time, bool) {
+	return func(year int) (time.Time, bool) {
+		if year < first {
+			return time.Time{}, false
+		}
+		return date(year)
+	}
+}
+
+// easterSunday returns the date of Easter Sunday in the Gregorian
+// calendar, by the anonymous Gregorian algorithm.
+func easterSunday(year int) time.Time {
+	a := year % 19
+	b, c := year/100, year%100
+	d, e := b/4, b%4
+	f := (b + 8) / 25
+	g := (b - f + 1) / 3
+	h := (19*a + b - d - g + 15) % 30
+	i, k := c/4, c%4
+	l := (32 + 2*e + 2*i - h - k) % 7
+	m := (a + 11*h + 22*l) / 451
+	month := (h + l - 7*m + 114) / 31
+	day := (h+l-7*m+114)%31 + 1
+	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,125 @@
+package invoice_test
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+func TestHolidays(t *testing.T) {
+	tests := []struct {
+		region string
+		year   int
+		date   string
+		name   string
+	}{
+		{"US", 2025, "2025-07-04", "Independence Day"},
+		{"US", 2025, "2025-11-27", "Thanksgiving Day"},
+		{"US", 2025, "2025-05-26", "Memorial Day"},
+		// July 4, 2026 is a Saturday, taken on the Friday.
+		{"US", 2026, "2026-07-03", "Independence Day (observed)"},
+		// January 1, 2022 is a Saturday, taken on December 31.
+		{"US", 2021, "2021-12-31", "New Year's Day (observed)"},
+		{"CA", 2025, "2025-05-19", "Victoria Day"},
+		{"GB", 2024, "2024-03-29", "Good Friday"},
+		// Christmas 2021 is a Saturday and Boxing Day a Sunday.
+		{"GB", 2021, "2021-12-27", "Christmas Day (observed)"},
+		{"GB", 2021, "2021-12-28", "Boxing Day (observed)"},
+		// Christmas 2022 is a Sunday, and Boxing Day keeps the Monday.
+		{"GB", 2022, "2022-12-26", "Boxing Day"},
+		{"GB", 2022, "2022-12-27", "Christmas Day (observed)"},
+		{"DE", 2025, "2025-06-09", "Pfingstmontag"},
+		{"DE-BY", 2025, "2025-06-19", "Fronleichnam"},
+		{"de_by", 2025, "2025-04-21", "Ostermontag"},
+		{"FR", 2025, "2025-05-29", "Ascension"},
+	}
+	for _, tt := range tests {
+		days, err := invoice.Holidays(tt.region, tt.year)
+		if err != nil {
+			t.Fatalf("Holidays(%q, %d): %v", tt.region, tt.year, err)
+		}
+		if !slices.ContainsFunc(days, func(h invoice.Holiday) bool {
+			return h.Date.Format(time.DateOnly) == tt.date && h.Name == tt.name
+		}) {
+			t.Errorf("Holidays(%q, %d) has no %s on %s: %v", tt.region, tt.year, tt.name, tt.date, days)
+		}
+	}
+}
+
+func TestHolidays_Year(t *testing.T) {
+	// January 1, 2022 is taken in 2021, and the holidays are sorted.
+	days, err := invoice.Holidays("US", 2022)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(days) != 10 {
+		t.Errorf("got %d holidays in 2022, want 10: %v", len(days), days)
+	}
+	for i, h := range days {
+		if h.Date.Year() != 2022 {
+			t.Errorf("holiday %s on %s is not in 2022", h.Name, h.Date.Format(time.DateOnly))
+		}
+		if i > 0 && h.Date.Before(days[i-1].Date) {
+			t.Errorf("%s is before %s", h.Name, days[i-1].Name)
+		}
+	}
+
+	// Regions add to their country's holidays.
+	de, _ := invoice.Holidays("DE", 2025)
+	by, _ := invoice.Holidays("DE-BY", 2025)
+	if len(by) != len(de)+4 {
+		t.Errorf("DE-BY has %d holidays, want the %d of DE and 4 more", len(by), len(de))
+	}
+}
+
+func TestHolidays_Unknown(t *testing.T) {
+	if _, err := invoice.Holidays("XX", 2025); err == nil {
+		t.Error("expected an error for a region without holidays")
+	}
+	if err := invoice.ValidateHolidayRegion("de-by"); err != nil {
+		t.Errorf("ValidateHolidayRegion(de-by): %v", err)
+	}
+}
+
+func TestWeeksForMonthExcept_Holidays(t *testing.T) {
+	holidayDates := func(region string, year int) []time.Time {
+		t.Helper()
+		days, err := invoice.Holidays(region, year)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var dates []time.Time
+		for _, h := range days {
+			dates = append(dates, h.Date)
+		}
+		return dates
+	}
+
+	// July 4, 2025 takes a day off the first week, Tuesday to Sunday, and
+	// taking it off again as a day off changes nothing.
+	dates := append(holidayDates("US", 2025), time.Date(2025, time.July, 4, 0, 0, 0, 0, time.UTC))
+	weeks := invoice.WeeksForMonthExcept(2025, time.July, 40, dates)
+	want := []float64{24, 40, 40, 40, 32}
+	for i, w := range weeks {
+		if w.Hours != want[i] {
+			t.Errorf("July 2025, week of %s: %v hours, want %v", w.Start.Format(time.DateOnly), w.Hours, want[i])
+		}
+	}
+
+	// Labor Day takes a day off a full week.
+	weeks = invoice.WeeksForMonthExcept(2025, time.September, 40, holidayDates("US", 2025))
+	if weeks[0].Workdays != 4 || weeks[0].Hours != 32 {
+		t.Errorf("September 2025, first week: %d workdays and %v hours, want 4 and 32", weeks[0].Workdays, weeks[0].Hours)
+	}
+
+	// Christmas 2021 in Germany is on a weekend and takes nothing off.
+	weeks = invoice.WeeksForMonthExcept(2021, time.December, 40, holidayDates("DE", 2021))
+	full := invoice.WeeksForMonth(2021, time.December, 40)
+	for i, w := range weeks {
+		if w.Workdays != full[i].Workdays {
+			t.Errorf("December 2021, week of %s: %d workdays, want %d", w.Start.Format(time.DateOnly), w.Workdays, full[i].Workdays)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package invoice
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+)
+
+// HookExec is the function used to run the post-generation hook command.
+// env holds the INVOICE_* variables to add to the current environment.
+// It can be overridden in tests to capture the command and environment.
+var HookExec = func(command string, env []string) error {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	} else {
+		cmd = exec.Command("sh", "-c", command)
+	}
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// HookEnv returns the environment variables describing a generated invoice.
+// pdfPath may be empty if no PDF was produced.
+func HookEnv(inv *Invoice, htmlPath, pdfPath string) []string {
+	return hookEnv(inv, htmlPath, pdfPath, "")
+}
+
+// TextHookEnv returns the environment variables describing a plain-text
+// invoice written to textPath, with INVOICE_HTML and INVOICE_PDF empty.
+func TextHookEnv(inv *Invoice, textPath string) []string {
+	return hookEnv(inv, "", "", textPath)
+}
+
+func hookEnv(inv *Invoice, htmlPath, pdfPath, textPath string) []string {
+	return []string{
+		"INVOICE_HTML=" + htmlPath,
+		"INVOICE_PDF=" + pdfPath,
+		"INVOICE_TEXT=" + textPath,
+		"INVOICE_TOTAL=" + inv.Breakdown().AmountDue.String(),
+		"INVOICE_CUSTOMER=" + inv.Customer,
+		fmt.Sprintf("INVOICE_PERIOD=%d-%02d", inv.Year, int(inv.Month)),
+		"INVOICE_NUMBER=" + InvoiceNumber(inv),
+	}
+}
+
+// RunHook executes command after a successful generation with the invoice
+// environment variables set.
+func RunHook(command string, inv *Invoice, htmlPath, pdfPath string) error {
+	return runHook(command, HookEnv(inv, htmlPath, pdfPath))
+}
+
+// RunTextHook is RunHook for a plain-text invoice written to textPath.
+func RunTextHook(command string, inv *Invoice, textPath string) error {
+	return runHook(command, TextHookEnv(inv, textPath))
+}
+
+func runHook(command string, env []string) error {
+	if err := HookExec(command, env); err != nil {
+		return fmt.Errorf("hook %q: %w", command, err)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package invoice_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+func TestInvoiceNumber(t *testing.T) {
+	got := invoice.InvoiceNumber(testInvoice())
+	want := "INV-202501-acme-corp"
+	if got != want {
+		t.Errorf("InvoiceNumber() = %q, want %q", got, want)
+	}
+}
+
+func TestRunHook_PassesEnvironment(t *testing.T) {
+	var capturedCommand string
+	var capturedEnv []string
+
+	origExec := invoice.HookExec
+	defer func() { invoice.HookExec = origExec }()
+
+	invoice.HookExec = func(command string, env []string) error {
+		capturedCommand = command
+		capturedEnv = env
+		return nil
+	}
+
+	inv := testInvoice()
+	if err := invoice.RunHook("./publish.sh", inv, "/tmp/invoice.html", "/tmp/invoice.pdf"); err != nil {
+		t.Fatalf("RunHook() error: %v", err)
+	}
+
+	if capturedCommand != "./publish.sh" {
+		t.Errorf("command = %q, want %q", capturedCommand, "./publish.sh")
+	}
+	want := map[string]bool{
+		"INVOICE_HTML=/tmp/invoice.html":      true,
+		"INVOICE_PDF=/tmp/invoice.pdf":        true,
+		"INVOICE_TOTAL=10800.00":              true,
+		"INVOICE_CUSTOMER=Acme Corp":          true,
+		"INVOICE_PERIOD=2025-01":              true,
+		"INVOICE_NUMBER=INV-202501-acme-corp": true,
+	}
+	for _, kv := range capturedEnv {
+		delete(want, kv)
+	}
+	for kv := range want {
+		t.Errorf("hook environment missing %q, got %v", kv, capturedEnv)
+	}
+}
+
+func TestRunHook_ReturnsError(t *testing.T) {
+	origExec := invoice.HookExec
+	defer func() { invoice.HookExec = origExec }()
+
+	invoice.HookExec = func(command string, env []string) error {
+		return errors.New("exit status 1")
+	}
+
+	if err := invoice.RunHook("false", testInvoice(), "/tmp/invoice.html", ""); err == nil {
+		t.Error("expected error from failing hook, got nil")
+	}
+}
+
+func TestTextHookEnv(t *testing.T) {
+	env := invoice.TextHookEnv(testInvoice(), "/tmp/invoice.txt")
+	for _, kv := range []string{"INVOICE_TEXT=/tmp/invoice.txt", "INVOICE_HTML=", "INVOICE_PDF=", "INVOICE_TOTAL=10800.00"} {
+		if !slices.Contains(env, kv) {
+			t.Errorf("hook environment missing %q, got %v", kv, env)
+		}
+	}
+	if env := invoice.HookEnv(testInvoice(), "/tmp/invoice.html", ""); !slices.Contains(env, "INVOICE_TEXT=") {
+		t.Errorf("an HTML invoice's hook environment should have an empty INVOICE_TEXT, got %v", env)
+	}
+}
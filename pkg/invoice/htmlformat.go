@@ -0,0 +1,330 @@
+package invoice
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// FormatHTML re-serializes the HTML document data with one block element,
+// such as a div, p, tr or td, per line, indented two spaces per level, and
+// the text and inline elements inside a block on the line of its start
+// tag. Only whitespace changes, and only where HTML ignores it: at the
+// edges of block elements, and runs of it inside text, which become one
+// space. Tags, attributes and entities are kept as they were written, and
+// so is the content of pre, textarea, script, style and title elements,
+// and of any element whose style sets white-space. Missing tags are not
+// added, so a truncated document stays truncated. Formatting the result
+// again leaves it as it is.
+//
+// The layout is assumed to be HTML's own: CSS that makes a block element
+// such as a li inline can make the whitespace between them show.
+func FormatHTML(data []byte) ([]byte, error) {
+	return layoutHTML(data, false)
+}
+
+// MinifyHTML is like FormatHTML, but leaves out the whitespace HTML
+// ignores, and comments, instead of laying the document out.
+func MinifyHTML(data []byte) ([]byte, error) {
+	return layoutHTML(data, true)
+}
+
+// FormatHTMLFile rewrites the HTML file at path with FormatHTML, or with
+// MinifyHTML if minify is set.
+func FormatHTMLFile(path string, minify bool) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("formatting HTML invoice: %w", err)
+	}
+	out, err := layoutHTML(data, minify)
+	if err != nil {
+		return fmt.Errorf("formatting HTML invoice: %w", err)
+	}
+	if err := os.WriteFile(path, out, 0o644); err != nil {
+		return fmt.Errorf("formatting HTML invoice: %w", err)
+	}
+	return nil
+}
+
+// blockElements are the elements whose edges HTML ignores whitespace at,
+// and that FormatHTML puts on lines of their own.
+var blockElements = setOf(
+	"address", "article", "aside", "base", "blockquote", "body", "caption",
+	"col", "colgroup", "dd", "details", "dialog", "div", "dl", "dt",
+	"fieldset", "figcaption", "figure", "footer", "form", "h1", "h2", "h3",
+	"h4", "h5", "h6", "head", "header", "hgroup", "hr", "html", "legend",
+	"li", "link", "main", "meta", "nav", "ol", "p", "pre", "script",
+	"section", "style", "summary", "table", "tbody", "td", "template",
+	"tfoot", "th", "thead", "title", "tr", "ul",
+)
+
+// voidElements are the elements that have no end tag.
+var voidElements = setOf(
+	"area", "base", "br", "col", "embed", "hr", "img", "input", "link",
+	"meta", "param", "source", "track", "wbr",
+)
+
+// verbatimElements are the elements whose content is kept as written,
+// since its whitespace shows or is code.
+var verbatimElements = setOf("pre", "textarea", "script", "style", "title")
+
+// closedBy lists, for an element, the open elements its start tag closes
+// when one of them is the innermost, as HTML's optional end tags allow.
+var closedBy = map[string][]string{
+	"li":    {"li"},
+	"p":     {"p"},
+	"dt":    {"dt", "dd"},
+	"dd":    {"dt", "dd"},
+	"tr":    {"tr", "td", "th"},
+	"td":    {"td", "th"},
+	"th":    {"td", "th"},
+	"thead": {"thead", "tbody", "tfoot", "tr", "td", "th"},
+	"tbody": {"thead", "tbody", "tfoot", "tr", "td", "th"},
+	"tfoot": {"thead", "tbody", "tfoot", "tr", "td", "th"},
+}
+
+func setOf(names ...string) map[string]bool {
+	set := make(map[string]bool, len(names))
+	for _, n := range names {
+		set[n] = true
+	}
+	return set
+}
+
+// htmlPiece is a piece of a run of inline content: a tag, a comment, or
+// text whose whitespace may be collapsed if collapse is set.
+type htmlPiece struct {
+	raw      []byte
+	collapse bool
+}
+
+// htmlLayout lays out a document for layoutHTML.
+type htmlLayout struct {
+	out    bytes.Buffer
+	minify bool
+	// open are the block elements open at this point.
+	open []string
+	// run is the inline content since the last block tag.
+	run []htmlPiece
+	// afterStart is set while the last block tag written is a start tag,
+	// so its inline content and end tag go on its line.
+	afterStart bool
+}
+
+func layoutHTML(data []byte, minify bool) ([]byte, error) {
+	l := &htmlLayout{minify: minify}
+	z := html.NewTokenizer(bytes.NewReader(data))
+	// verbatim is the element whose content is being copied as written,
+	// with depth its nesting, and kept what has been copied.
+	var verbatim string
+	var depth int
+	var kept []byte
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			if err := z.Err(); !errors.Is(err, io.EOF) {
+				return nil, err
+			}
+			break
+		}
+		// Raw and TagName are only good until the next token.
+		raw := append([]byte(nil), z.Raw()...)
+		var tag string
+		var hasAttr bool
+		if tt == html.StartTagToken || tt == html.EndTagToken || tt == html.SelfClosingTagToken {
+			var name []byte
+			name, hasAttr = z.TagName()
+			tag = string(name)
+		}
+
+		if verbatim != "" {
+			kept = append(kept, raw...)
+			switch {
+			case tt == html.StartTagToken && tag == verbatim:
+				depth++
+			case tt == html.EndTagToken && tag == verbatim:
+				depth--
+			}
+			if depth == 0 {
+				l.verbatim(verbatim, kept)
+				verbatim, kept = "", nil
+			}
+			continue
+		}
+
+		switch tt {
+		case html.DoctypeToken:
+			l.block(raw, false)
+		case html.CommentToken:
+			if !l.minify {
+				l.run = append(l.run, htmlPiece{raw: raw})
+			}
+		case html.TextToken:
+			l.run = append(l.run, htmlPiece{raw: raw, collapse: true})
+		case html.StartTagToken, html.SelfClosingTagToken:
+			if voidElements[tag] {
+				if blockElements[tag] {
+					l.block(raw, false)
+				} else {
+					l.run = append(l.run, htmlPiece{raw: raw})
+				}
+				continue
+			}
+			if tt == html.StartTagToken && (verbatimElements[tag] || hasAttr && setsWhiteSpace(z)) {
+				verbatim, depth, kept = tag, 1, raw
+				continue
+			}
+			if !blockElements[tag] {
+				l.run = append(l.run, htmlPiece{raw: raw})
+				continue
+			}
+			for len(l.open) > 0 && slices.Contains(closedBy[tag], l.open[len(l.open)-1]) {
+				l.open = l.open[:len(l.open)-1]
+			}
+			l.block(raw, true)
+			l.open = append(l.open, tag)
+		case html.EndTagToken:
+			if !blockElements[tag] {
+				l.run = append(l.run, htmlPiece{raw: raw})
+				continue
+			}
+			l.end(tag, raw)
+		}
+	}
+	if verbatim != "" {
+		l.run = append(l.run, htmlPiece{raw: kept})
+	}
+	l.flush()
+	if !l.minify && l.out.Len() > 0 {
+		l.out.WriteByte('\n')
+	}
+	return l.out.Bytes(), nil
+}
+
+// setsWhiteSpace reports whether the attributes of the tag z is at set
+// white-space in a style, which can make its whitespace show.
+func setsWhiteSpace(z *html.Tokenizer) bool {
+	for {
+		key, val, more := z.TagAttr()
+		if string(key) == "style" && strings.Contains(strings.ToLower(string(val)), "white-space") {
+			return true
+		}
+		if !more {
+			return false
+		}
+	}
+}
+
+// verbatim writes the element kept, copied as written: on a line of its
+// own if it is a block element, or in the run of inline content if not.
+func (l *htmlLayout) verbatim(tag string, kept []byte) {
+	if blockElements[tag] {
+		l.block(kept, false)
+		return
+	}
+	l.run = append(l.run, htmlPiece{raw: kept})
+}
+
+// block writes raw, a block tag or a doctype, on a line of its own, after
+// the inline content before it. start is set for a start tag whose
+// element is left open.
+func (l *htmlLayout) block(raw []byte, start bool) {
+	l.flush()
+	l.newline(len(l.open))
+	l.out.Write(raw)
+	l.afterStart = start
+}
+
+// end writes raw, the end tag of the block element tag, closing it and
+// any element left open inside it. The end tag goes on the line of the
+// start tag if the element holds only inline content.
+func (l *htmlLayout) end(tag string, raw []byte) {
+	l.flush()
+	sameLine := false
+	for i := len(l.open) - 1; i >= 0; i-- {
+		if l.open[i] == tag {
+			sameLine = l.afterStart && i == len(l.open)-1
+			l.open = l.open[:i]
+			break
+		}
+	}
+	if !sameLine {
+		l.newline(len(l.open))
+	}
+	l.out.Write(raw)
+	l.afterStart = false
+}
+
+// newline starts a line indented for depth open elements, unless the
+// document is minified or nothing has been written yet.
+func (l *htmlLayout) newline(depth int) {
+	if l.minify || l.out.Len() == 0 {
+		return
+	}
+	l.out.WriteByte('\n')
+	l.out.WriteString(strings.Repeat("  ", depth))
+}
+
+// flush writes the run of inline content, without the whitespace at its
+// edges and with each run of whitespace in its text made one space. It
+// goes on the line of the block start tag before it, if any, or on a new
+// line.
+func (l *htmlLayout) flush() {
+	run := l.run
+	l.run = nil
+	for i := range run {
+		if run[i].collapse {
+			run[i].raw = collapseSpace(run[i].raw)
+		}
+	}
+	for len(run) > 0 && run[0].collapse {
+		run[0].raw = bytes.TrimLeft(run[0].raw, " ")
+		if len(run[0].raw) > 0 {
+			break
+		}
+		run = run[1:]
+	}
+	for len(run) > 0 && run[len(run)-1].collapse {
+		last := &run[len(run)-1]
+		last.raw = bytes.TrimRight(last.raw, " ")
+		if len(last.raw) > 0 {
+			break
+		}
+		run = run[:len(run)-1]
+	}
+	if len(run) == 0 {
+		return
+	}
+	if !l.afterStart {
+		l.newline(len(l.open))
+	}
+	for _, p := range run {
+		l.out.Write(p.raw)
+	}
+}
+
+// collapseSpace returns text with each run of HTML whitespace made one
+// space.
+func collapseSpace(text []byte) []byte {
+	out := make([]byte, 0, len(text))
+	space := false
+	for _, c := range text {
+		switch c {
+		case ' ', '\t', '\n', '\f', '\r':
+			if !space {
+				out = append(out, ' ')
+			}
+			space = true
+		default:
+			out = append(out, c)
+			space = false
+		}
+	}
+	return out
+}
@@ -0,0 +1,107 @@
+package invoice_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+// messyPre is the pre block of testdata/invoice-messy.html, whose
+// whitespace must survive formatting.
+const messyPre = `<pre>
+Bank transfer to:
+    IBAN   DE89 3704 0044 0532 0130 00
+    BIC    COBADEFFXXX
+</pre>`
+
+func TestFormatHTML(t *testing.T) {
+	messy, err := os.ReadFile(filepath.Join("testdata", "invoice-messy.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		layout func([]byte) ([]byte, error)
+		golden string
+	}{
+		{"format", invoice.FormatHTML, "invoice-messy-formatted.html"},
+		{"minify", invoice.MinifyHTML, "invoice-messy-minified.html"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.layout(messy)
+			if err != nil {
+				t.Fatalf("%s: %v", tt.name, err)
+			}
+			checkGolden(t, tt.golden, got)
+
+			again, err := tt.layout(got)
+			if err != nil {
+				t.Fatalf("%s again: %v", tt.name, err)
+			}
+			if !bytes.Equal(again, got) {
+				t.Errorf("a second pass changed the output:\nfirst:\n%s\nsecond:\n%s", got, again)
+			}
+			for _, keep := range []string{
+				messyPre,
+				"<title>Invoice   INV-202501-acme-corp</title>",
+				"<p style=\"white-space: pre-line\">Thank you\n  for your business.</p>",
+				"January&nbsp;2025",
+				"<b>$9,600.00</b> <span>USD</span>",
+			} {
+				if !bytes.Contains(got, []byte(keep)) {
+					t.Errorf("output should keep %q as written:\n%s", keep, got)
+				}
+			}
+		})
+	}
+}
+
+func TestFormatHTML_KeepsTruncation(t *testing.T) {
+	got, err := invoice.FormatHTML([]byte("<html><body><table><tr><td>Jan 1-5</td>"))
+	if err != nil {
+		t.Fatalf("FormatHTML: %v", err)
+	}
+	if bytes.Contains(bytes.ToLower(got), []byte("</html>")) {
+		t.Errorf("formatting should not close a truncated document:\n%s", got)
+	}
+}
+
+func TestGenerate_FormatsHTML(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		opts invoice.GenerateOptions
+		want string
+	}{
+		{"format", invoice.GenerateOptions{}, "<html></html>\n"},
+		{"no format", invoice.GenerateOptions{NoFormat: true}, "<html></html>"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			outputPath := filepath.Join(t.TempDir(), "invoice.html")
+			if _, err := invoice.Generate(t.Context(), "test-html", testInvoice(), outputPath, tt.opts); err != nil {
+				t.Fatalf("Generate: %v", err)
+			}
+			data, err := os.ReadFile(outputPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tt.want {
+				t.Errorf("invoice = %q, want %q", data, tt.want)
+			}
+		})
+	}
+}
+
+func TestMinifyHTML_DropsComments(t *testing.T) {
+	got, err := invoice.MinifyHTML([]byte("<p>a <!-- note --> b</p>\n<div>\n  <p>c</p>\n</div>\n"))
+	if err != nil {
+		t.Fatalf("MinifyHTML: %v", err)
+	}
+	if want := "<p>a  b</p><div><p>c</p></div>"; strings.TrimSpace(string(got)) != want {
+		t.Errorf("MinifyHTML = %q, want %q", got, want)
+	}
+}
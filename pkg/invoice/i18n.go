@@ -0,0 +1,304 @@
+package invoice
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LanguageEnglish is the language invoices are shown in by default, and
+// the one a missing label falls back to.
+const LanguageEnglish = "en"
+
+// Translations are labels by language code, such as "de" or "pt-br", and
+// then by label key, such as "total". An invoice's Translations add to
+// and take precedence over the built-in ones.
+type Translations map[string]map[string]string
+
+// builtinTranslations are the labels invoicer knows. English has every
+// key; the others fall back to it for any they lack.
+//
+// Besides the labels, the keys are the month names, "january" to
+// "december"; their short forms, "jan" to "dec" with "may_short" for May;
+// the patterns of a week's workdays, "workdays_one" and "workdays", e.g.
+// "{n} workdays"; and the patterns of the long date format: "date_long",
+// e.g. "{month} {day}, {year}"; "date_short", used for a week across the
+// new year; "week", e.g. "{mon} {start}-{end}"; and "week_months", for a
+// week across two months.
+var builtinTranslations = Translations{
+	"en": {
+		"invoice":        "INVOICE",
+		"date":           "Date",
+		"period":         "Period",
+		"po_number":      "PO Number",
+		"from":           "From",
+		"bill_to":        "Bill To",
+		"description":    "Description",
+		"hours":          "Hours",
+		"rate":           "Rate",
+		"amount":         "Amount",
+		"total":          "Total",
+		"total_due":      "Total due",
+		"exchange_rate":  "Exchange rate",
+		"notes":          "Notes",
+		"bank_transfer":  "Payment by bank transfer",
+		"account_holder": "Account holder",
+		"routing_number": "Routing number",
+		"account_number": "Account number",
+		"reference":      "Reference",
+		"workdays_one":   "{n} workday",
+		"workdays":       "{n} workdays",
+
+		"january": "January", "february": "February", "march": "March",
+		"april": "April", "may": "May", "june": "June",
+		"july": "July", "august": "August", "september": "September",
+		"october": "October", "november": "November", "december": "December",
+		"jan": "Jan", "feb": "Feb", "mar": "Mar", "apr": "Apr", "may_short": "May", "jun": "Jun",
+		"jul": "Jul", "aug": "Aug", "sep": "Sep", "oct": "Oct", "nov": "Nov", "dec": "Dec",
+
+		"date_long":   "{month} {day}, {year}",
+		"date_short":  "{mon} {day}, {year}",
+		"week":        "{mon} {start}-{end}",
+		"week_months": "{start_mon} {start} - {end_mon} {end}",
+	},
+	"de": {
+		"invoice":        "RECHNUNG",
+		"date":           "Datum",
+		"period":         "Zeitraum",
+		"po_number":      "Bestellnummer",
+		"from":           "Von",
+		"bill_to":        "Rechnung an",
+		"description":    "Beschreibung",
+		"hours":          "Stunden",
+		"rate":           "Satz",
+		"amount":         "Betrag",
+		"total":          "Gesamt",
+		"total_due":      "Zu zahlen",
+		"exchange_rate":  "Wechselkurs",
+		"notes":          "Anmerkungen",
+		"bank_transfer":  "Zahlung per Überweisung",
+		"account_holder": "Kontoinhaber",
+		"routing_number": "ABA-Nummer",
+		"account_number": "Kontonummer",
+		"reference":      "Verwendungszweck",
+		"workdays_one":   "{n} Arbeitstag",
+		"workdays":       "{n} Arbeitstage",
+
+		"january": "Januar", "february": "Februar", "march": "März",
+		"april": "April", "may": "Mai", "june": "Juni",
+		"july": "Juli", "august": "August", "september": "September",
+		"october": "Oktober", "november": "November", "december": "Dezember",
+		"jan": "Jan.", "feb": "Feb.", "mar": "März", "apr": "Apr.", "may_short": "Mai", "jun": "Juni",
+		"jul": "Juli", "aug": "Aug.", "sep": "Sept.", "oct": "Okt.", "nov": "Nov.", "dec": "Dez.",
+
+		"date_long":   "{day}. {month} {year}",
+		"date_short":  "{day}. {mon} {year}",
+		"week":        "{start}.-{end}. {mon}",
+		"week_months": "{start}. {start_mon} - {end}. {end_mon}",
+	},
+	"fr": {
+		"invoice":        "FACTURE",
+		"date":           "Date",
+		"period":         "Période",
+		"po_number":      "N° de commande",
+		"from":           "De",
+		"bill_to":        "Facturé à",
+		"description":    "Description",
+		"hours":          "Heures",
+		"rate":           "Taux",
+		"amount":         "Montant",
+		"total":          "Total",
+		"total_due":      "Montant dû",
+		"exchange_rate":  "Taux de change",
+		"notes":          "Remarques",
+		"bank_transfer":  "Paiement par virement bancaire",
+		"account_holder": "Titulaire du compte",
+		"routing_number": "Code ABA",
+		"account_number": "Numéro de compte",
+		"reference":      "Référence",
+		"workdays_one":   "{n} jour ouvré",
+		"workdays":       "{n} jours ouvrés",
+
+		"january": "janvier", "february": "février", "march": "mars",
+		"april": "avril", "may": "mai", "june": "juin",
+		"july": "juillet", "august": "août", "september": "septembre",
+		"october": "octobre", "november": "novembre", "december": "décembre",
+		"jan": "janv.", "feb": "févr.", "mar": "mars", "apr": "avr.", "may_short": "mai", "jun": "juin",
+		"jul": "juil.", "aug": "août", "sep": "sept.", "oct": "oct.", "nov": "nov.", "dec": "déc.",
+
+		"date_long":   "{day} {month} {year}",
+		"date_short":  "{day} {mon} {year}",
+		"week":        "{start}-{end} {mon}",
+		"week_months": "{start} {start_mon} - {end} {end_mon}",
+	},
+	"es": {
+		"invoice":        "FACTURA",
+		"date":           "Fecha",
+		"period":         "Periodo",
+		"po_number":      "N.º de pedido",
+		"from":           "De",
+		"bill_to":        "Facturar a",
+		"description":    "Descripción",
+		"hours":          "Horas",
+		"rate":           "Tarifa",
+		"amount":         "Importe",
+		"total":          "Total",
+		"total_due":      "Importe a pagar",
+		"exchange_rate":  "Tipo de cambio",
+		"notes":          "Notas",
+		"bank_transfer":  "Pago por transferencia bancaria",
+		"account_holder": "Titular de la cuenta",
+		"routing_number": "Número ABA",
+		"account_number": "Número de cuenta",
+		"reference":      "Referencia",
+		"workdays_one":   "{n} día laborable",
+		"workdays":       "{n} días laborables",
+
+		"january": "enero", "february": "febrero", "march": "marzo",
+		"april": "abril", "may": "mayo", "june": "junio",
+		"july": "julio", "august": "agosto", "september": "septiembre",
+		"october": "octubre", "november": "noviembre", "december": "diciembre",
+		"jan": "ene.", "feb": "feb.", "mar": "mar.", "apr": "abr.", "may_short": "may.", "jun": "jun.",
+		"jul": "jul.", "aug": "ago.", "sep": "sept.", "oct": "oct.", "nov": "nov.", "dec": "dic.",
+
+		"date_long":   "{day} de {month} de {year}",
+		"date_short":  "{day} {mon} {year}",
+		"week":        "{start}-{end} {mon}",
+		"week_months": "{start} {start_mon} - {end} {end_mon}",
+	},
+}
+
+// monthKeys and monthShortKeys are the label keys of the months' names
+// and short names, in order.
+var (
+	monthKeys = []string{
+		"january", "february", "march", "april", "may", "june",
+		"july", "august", "september", "october", "november", "december",
+	}
+	monthShortKeys = []string{
+		"jan", "feb", "mar", "apr", "may_short", "jun",
+		"jul", "aug", "sep", "oct", "nov", "dec",
+	}
+)
+
+// Languages returns the codes of the languages invoicer has labels for,
+// sorted.
+func Languages() []string {
+	return slices.Sorted(maps.Keys(builtinTranslations))
+}
+
+// LabelKeys returns the keys of the labels invoicer shows, sorted.
+func LabelKeys() []string {
+	return slices.Sorted(maps.Keys(builtinTranslations[LanguageEnglish]))
+}
+
+// languageCode matches language codes such as "de", "pt-BR" or "zh-Hant".
+var languageCode = regexp.MustCompile(`^[A-Za-z]{2,3}([-_][A-Za-z0-9]{2,8})*$`)
+
+// ValidateLanguage returns an error if lang is not a language code such
+// as "de" or "pt-BR".
+func ValidateLanguage(lang string) error {
+	if !languageCode.MatchString(lang) {
+		return fmt.Errorf("invalid language %q: use a code such as de, fr or pt-BR", lang)
+	}
+	return nil
+}
+
+// NormalizeLanguage returns lang as it is looked up: in lower case, with
+// a hyphen before its region, e.g. "pt-br" for "pt_BR".
+func NormalizeLanguage(lang string) string {
+	return strings.ToLower(strings.ReplaceAll(lang, "_", "-"))
+}
+
+// Label returns the label key in the language lang, e.g. "de" or "de-AT",
+// from extra if it has it and from the built-in labels if not. If neither
+// has it in lang, the language lang is a dialect of, such as "de", is
+// tried, and then English. An unknown key is returned as it is.
+func Label(extra Translations, lang, key string) string {
+	lang = NormalizeLanguage(lang)
+	tried := []string{lang}
+	if base, _, ok := strings.Cut(lang, "-"); ok {
+		tried = append(tried, base)
+	}
+	tried = append(tried, LanguageEnglish)
+	for _, l := range tried {
+		if s := extra[l][key]; s != "" {
+			return s
+		}
+		if s := builtinTranslations[l][key]; s != "" {
+			return s
+		}
+	}
+	return key
+}
+
+// label returns the label key in the invoice's language.
+func (inv *Invoice) label(key string) string {
+	return Label(inv.Translations, inv.Language, key)
+}
+
+// formatDate returns t in the invoice's date format, with the long format
+// in the invoice's language.
+func (inv *Invoice) formatDate(t time.Time) string {
+	if inv.DateFormat != "" && inv.DateFormat != DateLong {
+		return FormatDate(t, inv.DateFormat)
+	}
+	return inv.fillDate("date_long", t)
+}
+
+// weekLabel returns the label of w in the invoice's date format, as
+// WeekLabel does, with the long format in the invoice's language.
+func (inv *Invoice) weekLabel(w Week) string {
+	if inv.DateFormat != "" && inv.DateFormat != DateLong {
+		return WeekLabel(w, inv.DateFormat)
+	}
+	switch {
+	case w.Start.Year() != w.End.Year():
+		return inv.fillDate("date_short", w.Start) + " - " + inv.fillDate("date_short", w.End)
+	case w.Start.Month() == w.End.Month():
+		return strings.NewReplacer(
+			"{mon}", inv.monthName(w.Start.Month(), true),
+			"{start}", strconv.Itoa(w.Start.Day()),
+			"{end}", strconv.Itoa(w.End.Day()),
+		).Replace(inv.label("week"))
+	}
+	return strings.NewReplacer(
+		"{start_mon}", inv.monthName(w.Start.Month(), true),
+		"{end_mon}", inv.monthName(w.End.Month(), true),
+		"{start}", strconv.Itoa(w.Start.Day()),
+		"{end}", strconv.Itoa(w.End.Day()),
+	).Replace(inv.label("week_months"))
+}
+
+// fillDate returns t in the date pattern labelled key.
+func (inv *Invoice) fillDate(key string, t time.Time) string {
+	return strings.NewReplacer(
+		"{month}", inv.monthName(t.Month(), false),
+		"{mon}", inv.monthName(t.Month(), true),
+		"{day}", strconv.Itoa(t.Day()),
+		"{year}", strconv.Itoa(t.Year()),
+	).Replace(inv.label(key))
+}
+
+// workdaysLabel returns n workdays in the invoice's language, e.g. "3
+// workdays".
+func (inv *Invoice) workdaysLabel(n int) string {
+	key := "workdays"
+	if n == 1 {
+		key = "workdays_one"
+	}
+	return strings.ReplaceAll(inv.label(key), "{n}", strconv.Itoa(n))
+}
+
+// monthName returns the name of m in the invoice's language, or its short
+// name if short is set.
+func (inv *Invoice) monthName(m time.Month, short bool) string {
+	if short {
+		return inv.label(monthShortKeys[m-1])
+	}
+	return inv.label(monthKeys[m-1])
+}
@@ -0,0 +1,61 @@
+package invoice_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+func TestLabel(t *testing.T) {
+	extra := invoice.Translations{
+		"de":    {"total": "Gesamtbetrag"},
+		"de-at": {"invoice": "HONORARNOTE"},
+		"en":    {"notes": "Remarks"},
+		"pt":    {"invoice": "FATURA"},
+	}
+	tests := []struct {
+		lang, key string
+		want      string
+	}{
+		{"de", "total", "Gesamtbetrag"},     // the override wins over the built-in label
+		{"de", "hours", "Stunden"},          // built in
+		{"de-AT", "invoice", "HONORARNOTE"}, // the dialect's own label
+		{"de_AT", "total", "Gesamtbetrag"},  // the language's, for one the dialect lacks
+		{"de-AT", "hours", "Stunden"},
+		{"fr", "total", "Total"},
+		{"en", "notes", "Remarks"},
+		{"pt", "invoice", "FATURA"},
+		{"pt", "hours", "Hours"}, // English for a label the language lacks
+		{"", "invoice", "INVOICE"},
+		{"de", "unknown", "unknown"},
+	}
+	for _, tt := range tests {
+		if got := invoice.Label(extra, tt.lang, tt.key); got != tt.want {
+			t.Errorf("Label(%q, %q) = %q, want %q", tt.lang, tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestLineItems_Language(t *testing.T) {
+	day := func(m time.Month, d int) time.Time { return time.Date(2025, m, d, 0, 0, 0, 0, time.UTC) }
+	inv := &invoice.Invoice{
+		Language: "de",
+		Weeks: []invoice.Week{
+			{Start: day(time.March, 3), End: day(time.March, 9)},
+			{Start: day(time.March, 31), End: day(time.April, 6)},
+			{Start: time.Date(2024, time.December, 30, 0, 0, 0, 0, time.UTC), End: day(time.January, 5)},
+		},
+	}
+	want := []string{"3.-9. März", "31. März - 6. Apr.", "30. Dez. 2024 - 5. Jan. 2025"}
+	for i, item := range inv.LineItems() {
+		if item.Description != want[i] {
+			t.Errorf("week %d is labelled %q, want %q", i, item.Description, want[i])
+		}
+	}
+
+	inv.DateFormat = invoice.DateISO
+	if got := inv.LineItems()[0].Description; got != "2025-03-03/2025-03-09" {
+		t.Errorf("an iso week is labelled %q in German, want it as it is in English", got)
+	}
+}
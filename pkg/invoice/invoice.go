@@ -0,0 +1,438 @@
+// Package invoice provides logic for generating invoices.
+//
+// It can be used as a library independently of the invoicer command:
+// build an Invoice from WeeksForMonth, then call Generate with one of
+// the registered Backends to produce the HTML and ConvertToPDF to turn
+// it into a PDF.
+package invoice
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+)
+
+// Week represents a single weekly line item in an invoice.
+type Week struct {
+	// Start is the Monday of the week (or first day of the month if month starts mid-week).
+	Start time.Time
+	// End is the Friday of the week (or last day of the month if month ends mid-week).
+	End time.Time
+	// Hours is the number of hours worked this week.
+	Hours float64
+	// Workdays is the number of days from Start to End that are billable
+	// workdays, Monday to Friday, which WeeksForMonth prorates the week's
+	// hours by. Zero if not known, as for a week built by hand.
+	Workdays int
+}
+
+// Units a line item's quantity is counted in.
+const (
+	// UnitHour is an hour of work, as billed by a Week.
+	UnitHour = "hour"
+	// UnitDay is a day, such as a day rate.
+	UnitDay = "day"
+	// UnitItem counts an Adjustment with a quantity but no unit.
+	UnitItem = "item"
+)
+
+// LineItem is one billed line of an invoice: a quantity of a unit at a
+// unit price. Every billable part of an invoice produces line items, so
+// the total, the prompts and the exports handle them all alike.
+type LineItem struct {
+	// Description says what is billed, e.g. "Jan 6-12".
+	Description string
+	// Quantity is how many units are billed, e.g. 40.
+	Quantity float64
+	// Unit is what the quantity counts, e.g. UnitHour. A line with no unit
+	// is a flat amount, such as an Adjustment: one at its unit price.
+	Unit string
+	// UnitPrice is the price of one unit in dollars.
+	UnitPrice float64
+	// Start and End are the dates the line covers; zero if it covers none.
+	Start, End time.Time
+}
+
+// Amount returns the line's amount in dollars, its quantity times its unit
+// price.
+func (l LineItem) Amount() float64 {
+	return l.Quantity * l.UnitPrice
+}
+
+// LineItem returns the week as a line item: its hours at rate.
+func (w Week) LineItem(rate float64) LineItem {
+	return w.lineItem(rate, WeekLabel(w, DateLong))
+}
+
+// lineItem returns the week as a line item with the description label.
+func (w Week) lineItem(rate float64, label string) LineItem {
+	return LineItem{
+		Description: label,
+		Quantity:    w.Hours,
+		Unit:        UnitHour,
+		UnitPrice:   rate,
+		Start:       w.Start,
+		End:         w.End,
+	}
+}
+
+// Adjustment is a flat amount added to an invoice, such as expenses, or
+// taken off it if negative, such as a credit. With a Quantity it is
+// instead that many units at Amount each, such as two conference days.
+type Adjustment struct {
+	// Description says what the amount is for, e.g. "Travel expenses".
+	Description string `json:"description"`
+	// Amount is in dollars: the whole amount, or the price of one unit if
+	// Quantity is set.
+	Amount float64 `json:"amount"`
+	// Quantity is how many units are billed; 0 for a flat amount.
+	Quantity float64 `json:"quantity,omitempty"`
+	// Unit is what Quantity counts, e.g. UnitDay; "" means UnitItem.
+	Unit string `json:"unit,omitempty"`
+}
+
+// LineItem returns the adjustment as a line item: flat, or its quantity
+// of its unit at its amount.
+func (a Adjustment) LineItem() LineItem {
+	if a.Quantity == 0 {
+		return LineItem{Description: a.Description, Quantity: 1, UnitPrice: a.Amount}
+	}
+	unit := a.Unit
+	if unit == "" {
+		unit = UnitItem
+	}
+	return LineItem{Description: a.Description, Quantity: a.Quantity, Unit: unit, UnitPrice: a.Amount}
+}
+
+// Invoice holds all data needed to generate an invoice for one calendar month.
+type Invoice struct {
+	// Month is the calendar month (1-12).
+	Month time.Month
+	// Year is the calendar year.
+	Year int
+	// Vendor is the name of the contractor sending the invoice.
+	Vendor string
+	// Customer is the name of the client receiving the invoice.
+	Customer string
+	// PerVendor says the invoice is one of several to the customer for
+	// the month, one per vendor of an agency, so its number and file names
+	// name the vendor as well as the customer.
+	PerVendor bool
+	// Rate is the hourly rate in dollars.
+	Rate float64
+	// Weeks is the list of weekly line items.
+	Weeks []Week
+	// Issued is the date the invoice is issued. Zero if not set.
+	Issued time.Time
+	// Due is the date payment is due. Zero if not set.
+	Due time.Time
+	// Payment is the vendor's bank account, shown with a payment QR code.
+	// Nil if none is configured.
+	Payment *PaymentDetails
+	// Adjustments are flat amounts and ad-hoc items billed after the weeks.
+	Adjustments []Adjustment
+	// Notes are shown on the invoice as written. Empty if none.
+	Notes string
+	// PONumber is the customer's purchase order number. Empty if none.
+	PONumber string
+	// Exchange is the rate the invoice is billed at in another currency,
+	// or nil to bill in dollars. Amounts stay in dollars; only how they are
+	// shown changes.
+	Exchange *ExchangeRate
+	// DateFormat names the format its dates and week labels are shown in,
+	// one of DateFormats; "" means DateLong. It is not part of the
+	// invoice's data, so each use of the invoice may set its own.
+	DateFormat string
+	// RatePrecision and HoursPrecision are the decimals rates and hours
+	// are shown with; nil means DefaultRatePrecision and
+	// DefaultHoursPrecision. Only how they are shown changes: amounts are
+	// computed at full precision and shown to the cent.
+	RatePrecision  *int
+	HoursPrecision *int
+	// Language is the code of the language its labels, month names and
+	// long dates are shown in, such as "de"; "" means English. Translations
+	// add to or change the built-in labels. Like DateFormat, neither is
+	// part of the invoice's data.
+	Language     string
+	Translations Translations
+	// ShowWorkdays adds each week's Workdays to its line item, e.g. "Jan
+	// 1-5 (3 workdays)", and to its JSON. Like DateFormat, it is not part
+	// of the invoice's data.
+	ShowWorkdays bool
+	// Style is an earlier invoice for the generated HTML to look like, or
+	// nil for a new design. Like DateFormat, it is not part of the
+	// invoice's data.
+	Style *StyleReference
+}
+
+// LineItems returns the invoice's line items in order: one per week, at
+// the invoice's rate and with its workdays if ShowWorkdays is set, then one
+// per adjustment.
+func (inv *Invoice) LineItems() []LineItem {
+	items := make([]LineItem, 0, len(inv.Weeks)+len(inv.Adjustments))
+	for _, w := range inv.Weeks {
+		label := inv.weekLabel(w)
+		if inv.ShowWorkdays && w.Workdays > 0 {
+			label += " (" + inv.workdaysLabel(w.Workdays) + ")"
+		}
+		items = append(items, w.lineItem(inv.Rate, label))
+	}
+	for _, a := range inv.Adjustments {
+		items = append(items, a.LineItem())
+	}
+	return items
+}
+
+// Total returns the amount due in dollars, as in the invoice's Breakdown.
+func (inv *Invoice) Total() float64 {
+	return inv.Breakdown().AmountDue.Dollars()
+}
+
+// IssueDate returns the date the invoice is issued: Issued, or today if
+// that is not set.
+func (inv *Invoice) IssueDate() time.Time {
+	if inv.Issued.IsZero() {
+		return Now()
+	}
+	return inv.Issued
+}
+
+// WeeksForMonth returns the weeks that belong to the given month.
+// A week belongs to a month if its Wednesday falls in that month.
+// Weeks run Monday through Sunday.
+func WeeksForMonth(year int, month time.Month, hoursPerWeek float64) []Week {
+	return WeeksForMonthExcept(year, month, hoursPerWeek, nil)
+}
+
+// WeeksForMonthExcept is like WeeksForMonth, but the days in daysOff are
+// not worked: each one in a week takes a day off its Workdays, and so a
+// fifth of hoursPerWeek off its hours. A day off on a weekend, or in no
+// week of the month, changes nothing. Only the dates of daysOff count.
+func WeeksForMonthExcept(year int, month time.Month, hoursPerWeek float64, daysOff []time.Time) []Week {
+	off := make(map[time.Time]bool, len(daysOff))
+	for _, d := range daysOff {
+		off[time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, time.UTC)] = true
+	}
+
+	// Find the first Wednesday in or after the 1st of the month.
+	// We iterate through the weeks whose Wednesday falls in the given month.
+	firstDay := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	lastDay := firstDay.AddDate(0, 1, -1)
+
+	var weeks []Week
+
+	// Find the first Wednesday >= firstDay of month.
+	// Then walk backwards to find the Monday of that week.
+	// Step through Wednesdays while they remain in the target month.
+
+	// Find day offset to first Wednesday.
+	wd := firstDay.Weekday()
+	var daysToFirstWed int
+	if wd <= time.Wednesday {
+		daysToFirstWed = int(time.Wednesday - wd)
+	} else {
+		daysToFirstWed = int(7 - wd + time.Wednesday)
+	}
+
+	firstWed := firstDay.AddDate(0, 0, daysToFirstWed)
+
+	for wed := firstWed; !wed.After(lastDay); wed = wed.AddDate(0, 0, 7) {
+		// Monday of this week
+		monday := wed.AddDate(0, 0, -2)
+		// Sunday of this week
+		sunday := wed.AddDate(0, 0, 4)
+
+		// Clamp to month boundaries for display purposes
+		weekStart := monday
+		if weekStart.Before(firstDay) {
+			weekStart = firstDay
+		}
+		weekEnd := sunday
+		if weekEnd.After(lastDay) {
+			weekEnd = lastDay
+		}
+
+		// Calculate prorated hours based on actual workdays (Mon-Fri) in the clamped range.
+		workdays := countWorkdays(weekStart, weekEnd, off)
+		hours := hoursPerWeek * float64(workdays) / 5.0
+
+		weeks = append(weeks, Week{
+			Start:    weekStart,
+			End:      weekEnd,
+			Hours:    hours,
+			Workdays: workdays,
+		})
+	}
+
+	return weeks
+}
+
+// HoursIncrement is the smallest part of an hour DistributeHours gives a
+// week: a quarter of an hour.
+const HoursIncrement = 0.25
+
+// DistributeHours sets the hours of weeks to add up to total, rounded to
+// a multiple of HoursIncrement, with each week's share in proportion to
+// its Workdays, so a partial week gets less. Shares are whole increments:
+// each week gets its share rounded down, and the increments left over go
+// one each to the weeks whose shares lost the most, earlier weeks first
+// on a tie. It returns an error if there are hours to give but the weeks
+// have no workdays.
+func DistributeHours(weeks []Week, total float64) error {
+	units := int(math.Round(total / HoursIncrement))
+	if units < 0 {
+		return fmt.Errorf("cannot distribute %s hours: hours must not be negative", FormatHours(total))
+	}
+	days := 0
+	for _, w := range weeks {
+		days += w.Workdays
+	}
+	if days == 0 {
+		if units > 0 {
+			return errors.New("cannot distribute hours over weeks without workdays")
+		}
+		for i := range weeks {
+			weeks[i].Hours = 0
+		}
+		return nil
+	}
+
+	shares := make([]int, len(weeks))
+	left := units
+	for i, w := range weeks {
+		shares[i] = units * w.Workdays / days
+		left -= shares[i]
+	}
+	// The remainders, units*Workdays%days, rank the weeks for the rest.
+	order := make([]int, len(weeks))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		return units*weeks[b].Workdays%days - units*weeks[a].Workdays%days
+	})
+	for _, i := range order[:left] {
+		shares[i]++
+	}
+	for i := range weeks {
+		weeks[i].Hours = float64(shares[i]) * HoursIncrement
+	}
+	return nil
+}
+
+// countWorkdays counts Monday-Friday days between start and end (inclusive),
+// other than the days in off, which are at midnight UTC.
+func countWorkdays(start, end time.Time, off map[time.Time]bool) int {
+	count := 0
+	for d := start; !d.After(end); d = d.AddDate(0, 0, 1) {
+		wd := d.Weekday()
+		if wd >= time.Monday && wd <= time.Friday && !off[d] {
+			count++
+		}
+	}
+	return count
+}
+
+// ParseMonth parses a month string (text or numeric) and returns the time.Month value.
+// Returns an error if the string is not a valid month.
+func ParseMonth(s string) (time.Month, error) {
+	// Try numeric first.
+	var n int
+	if _, err := fmt.Sscanf(s, "%d", &n); err == nil {
+		if n < 1 || n > 12 {
+			return 0, fmt.Errorf("month number %d out of range (1-12)", n)
+		}
+		return time.Month(n), nil
+	}
+
+	// Try text (full name or abbreviation).
+	for m := time.January; m <= time.December; m++ {
+		name := m.String()
+		// Full match (case-insensitive).
+		if equalFold(s, name) {
+			return m, nil
+		}
+		// 3-letter abbreviation match.
+		if len(s) == 3 && equalFold(s, name[:3]) {
+			return m, nil
+		}
+	}
+
+	return 0, fmt.Errorf("unrecognized month %q", s)
+}
+
+// equalFold compares two strings case-insensitively.
+func equalFold(a, b string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		ca := a[i]
+		cb := b[i]
+		if ca >= 'A' && ca <= 'Z' {
+			ca += 'a' - 'A'
+		}
+		if cb >= 'A' && cb <= 'Z' {
+			cb += 'a' - 'A'
+		}
+		if ca != cb {
+			return false
+		}
+	}
+	return true
+}
+
+// ResolveMonthYear resolves the month and year to use for an invoice.
+// If monthStr is empty, defaults to the previous month.
+// If year is 0, defaults to the year closest to the given month relative to today.
+func ResolveMonthYear(monthStr string, year int, now time.Time) (time.Month, int, error) {
+	var month time.Month
+	var err error
+
+	if monthStr == "" {
+		// Default to previous month.
+		prev := now.AddDate(0, -1, 0)
+		month = prev.Month()
+		if year == 0 {
+			year = prev.Year()
+		}
+	} else {
+		month, err = ParseMonth(monthStr)
+		if err != nil {
+			return 0, 0, err
+		}
+		if year == 0 {
+			// Choose the year closest to today for the given month.
+			year = closestYear(month, now)
+		}
+	}
+
+	return month, year, nil
+}
+
+// closestYear returns the year such that the given month is closest to now.
+// It considers the current year, previous year, and next year.
+func closestYear(month time.Month, now time.Time) int {
+	best := now.Year()
+	bestDiff := absDuration(now.Sub(time.Date(best, month, 1, 0, 0, 0, 0, time.UTC)))
+
+	for _, y := range []int{now.Year() - 1, now.Year() + 1} {
+		candidate := time.Date(y, month, 1, 0, 0, 0, 0, time.UTC)
+		diff := absDuration(now.Sub(candidate))
+		if diff < bestDiff {
+			bestDiff = diff
+			best = y
+		}
+	}
+
+	return best
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
@@ -1,10 +1,11 @@
 package invoice_test
 
 import (
+	"strings"
 	"testing"
 	"time"
 
-	"github.com/zon/invoicer/internal/invoice"
+	"github.com/zon/invoicer/pkg/invoice"
 )
 
 func TestWeeksForMonth_FullWeeks(t *testing.T) {
@@ -95,6 +96,102 @@ func TestWeeksForMonth_FullWeekHours(t *testing.T) {
 	// If no fully-unclamped week was found in January 2025, skip.
 }
 
+func TestWeekWorkdays(t *testing.T) {
+	// January 2025: Jan 1-5 has Wednesday to Friday, and Jan 27-31 ends the
+	// month on a Friday.
+	weeks := invoice.WeeksForMonth(2025, time.January, 40)
+	want := []int{3, 5, 5, 5, 5}
+	if len(weeks) != len(want) {
+		t.Fatalf("expected %d weeks, got %d", len(want), len(weeks))
+	}
+	for i, w := range weeks {
+		if got := w.Workdays; got != want[i] {
+			t.Errorf("week %d: expected %d workdays, got %d", i, want[i], got)
+		}
+	}
+}
+
+func TestWeeksForMonthExcept(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2025, time.January, d, 0, 0, 0, 0, time.UTC) }
+	// Two days off in the week of the 13th, a Saturday off in the same
+	// week, and a day in February.
+	weeks := invoice.WeeksForMonthExcept(2025, time.January, 40, []time.Time{
+		day(15), day(16), day(18), time.Date(2025, time.February, 3, 0, 0, 0, 0, time.UTC),
+	})
+	full := invoice.WeeksForMonth(2025, time.January, 40)
+	if len(weeks) != len(full) {
+		t.Fatalf("got %d weeks, want %d", len(weeks), len(full))
+	}
+	for i, w := range weeks {
+		want := full[i]
+		if w.Start.Equal(day(13)) {
+			want.Workdays, want.Hours = 3, 24
+		}
+		if w.Workdays != want.Workdays || w.Hours != want.Hours {
+			t.Errorf("week of %s: %d workdays and %v hours, want %d and %v", w.Start.Format(time.DateOnly), w.Workdays, w.Hours, want.Workdays, want.Hours)
+		}
+	}
+
+	// The prompt bills the week for the days worked.
+	inv := &invoice.Invoice{Month: time.January, Year: 2025, Vendor: "Jane Contractor", Customer: "Acme Corp", Rate: 150, Weeks: weeks}
+	if prompt := invoice.BuildPrompt(inv, "/tmp/invoice.html"); !strings.Contains(prompt, "Jan 13-19: 24.0 hours @ $150.00/hr = $3600.00") {
+		t.Errorf("the prompt should bill 24 hours for the week of the 13th:\n%s", prompt)
+	}
+}
+
+func TestDistributeHours(t *testing.T) {
+	// July 2025 has 23 workdays in its weeks: 4, 5, 5, 5 and 4. 120 hours
+	// are 480 quarter hours, 83.48 and 104.35 per week; the two left over
+	// go to the partial weeks, which lost the most to rounding.
+	weeks := invoice.WeeksForMonth(2025, time.July, 40)
+	if err := invoice.DistributeHours(weeks, 120); err != nil {
+		t.Fatal(err)
+	}
+	want := []float64{21, 26, 26, 26, 21}
+	var sum float64
+	for i, w := range weeks {
+		if w.Hours != want[i] {
+			t.Errorf("week %d: hours = %v, want %v", i, w.Hours, want[i])
+		}
+		sum += w.Hours
+	}
+	if sum != 120 {
+		t.Errorf("sum = %v, want 120", sum)
+	}
+
+	// 100 hours are 400 quarters: 69.57 and 86.96 per week.
+	if err := invoice.DistributeHours(weeks, 100); err != nil {
+		t.Fatal(err)
+	}
+	want = []float64{17.5, 21.75, 21.75, 21.75, 17.25}
+	sum = 0
+	for i, w := range weeks {
+		if w.Hours != want[i] {
+			t.Errorf("week %d: hours = %v, want %v", i, w.Hours, want[i])
+		}
+		if r := w.Hours / invoice.HoursIncrement; r != float64(int(r)) {
+			t.Errorf("week %d: hours %v are not a multiple of %v", i, w.Hours, invoice.HoursIncrement)
+		}
+		sum += w.Hours
+	}
+	if sum != 100 {
+		t.Errorf("sum = %v, want 100", sum)
+	}
+}
+
+func TestDistributeHours_NoWorkdays(t *testing.T) {
+	if err := invoice.DistributeHours(nil, 10); err == nil {
+		t.Error("expected an error for hours and no workdays")
+	}
+	if err := invoice.DistributeHours(nil, 0); err != nil {
+		t.Errorf("no hours over no weeks: %v", err)
+	}
+	weeks := invoice.WeeksForMonth(2025, time.July, 40)
+	if err := invoice.DistributeHours(weeks, -8); err == nil {
+		t.Error("expected an error for negative hours")
+	}
+}
+
 func TestParseMonth_Numeric(t *testing.T) {
 	tests := []struct {
 		input string
@@ -242,3 +339,47 @@ func TestInvoiceTotal(t *testing.T) {
 		t.Errorf("Total() = %.2f, want %.2f", got, want)
 	}
 }
+
+func TestWeekLineItem(t *testing.T) {
+	w := invoice.Week{
+		Start: time.Date(2025, time.January, 6, 0, 0, 0, 0, time.UTC),
+		End:   time.Date(2025, time.January, 12, 0, 0, 0, 0, time.UTC),
+		Hours: 37.5,
+	}
+	got := w.LineItem(150)
+	want := invoice.LineItem{
+		Description: "Jan 6-12",
+		Quantity:    37.5,
+		Unit:        invoice.UnitHour,
+		UnitPrice:   150,
+		Start:       w.Start,
+		End:         w.End,
+	}
+	if got != want {
+		t.Errorf("LineItem() = %+v, want %+v", got, want)
+	}
+	if got.Amount() != 5625 {
+		t.Errorf("Amount() = %v, want 5625", got.Amount())
+	}
+}
+
+func TestInvoiceLineItems(t *testing.T) {
+	inv := &invoice.Invoice{Year: 2025, Month: time.January, Rate: 120, Weeks: invoice.WeeksForMonth(2025, time.January, 40)}
+	items := inv.LineItems()
+	if len(items) != len(inv.Weeks) {
+		t.Fatalf("got %d line items, want one per week (%d)", len(items), len(inv.Weeks))
+	}
+	var total float64
+	for i, l := range items {
+		if l != inv.Weeks[i].LineItem(inv.Rate) {
+			t.Errorf("line item %d = %+v, want week %d's", i, l, i)
+		}
+		total += l.Amount()
+	}
+	if inv.Total() != total {
+		t.Errorf("Total() = %v, want the sum of the line items, %v", inv.Total(), total)
+	}
+	if len((&invoice.Invoice{Rate: 120}).LineItems()) != 0 {
+		t.Error("an invoice without weeks should have no line items")
+	}
+}
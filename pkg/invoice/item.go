@@ -0,0 +1,62 @@
+package invoice
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ParseItem parses an ad-hoc line item written as
+// "description:amount[:quantity[:unit]]", e.g. "Domain renewal:18.99" or
+// "Conference day:800:2:day". The fields are split off from the right, so
+// the description may itself hold colons. Without a quantity the item is
+// a flat amount; with one, amount is the price of each unit.
+func ParseItem(s string) (Adjustment, error) {
+	parts := strings.Split(s, ":")
+	n := len(parts)
+	var desc, amount, qty, unit string
+	switch {
+	case n >= 4 && isNumber(parts[n-3]) && isNumber(parts[n-2]) && !isNumber(parts[n-1]):
+		desc, amount, qty, unit = strings.Join(parts[:n-3], ":"), parts[n-3], parts[n-2], parts[n-1]
+	case n >= 3 && isNumber(parts[n-2]) && isNumber(parts[n-1]):
+		desc, amount, qty = strings.Join(parts[:n-2], ":"), parts[n-2], parts[n-1]
+	case n >= 2:
+		desc, amount = strings.Join(parts[:n-1], ":"), parts[n-1]
+	default:
+		return Adjustment{}, fmt.Errorf("item %q: want description:amount[:quantity[:unit]]", s)
+	}
+
+	item := Adjustment{Description: strings.TrimSpace(desc), Unit: strings.TrimSpace(unit)}
+	if item.Description == "" {
+		return Adjustment{}, fmt.Errorf("item %q: the description is empty", s)
+	}
+	var err error
+	if item.Amount, err = parseItemNumber(amount); err != nil || item.Amount == 0 {
+		return Adjustment{}, fmt.Errorf("item %q: amount %q is not a number other than 0", s, strings.TrimSpace(amount))
+	}
+	if qty != "" {
+		if item.Quantity, err = parseItemNumber(qty); err != nil || item.Quantity <= 0 {
+			return Adjustment{}, fmt.Errorf("item %q: quantity %q is not a positive number", s, strings.TrimSpace(qty))
+		}
+	}
+	return item, nil
+}
+
+// isNumber reports whether s is a finite number.
+func isNumber(s string) bool {
+	_, err := parseItemNumber(s)
+	return err == nil
+}
+
+// parseItemNumber parses a finite number, ignoring surrounding spaces.
+func parseItemNumber(s string) (float64, error) {
+	f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		return 0, err
+	}
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, fmt.Errorf("%q is not a finite number", s)
+	}
+	return f, nil
+}
@@ -0,0 +1,65 @@
+package invoice_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+func TestParseItem(t *testing.T) {
+	tests := []struct {
+		in   string
+		want invoice.Adjustment
+	}{
+		{"Domain renewal:18.99", invoice.Adjustment{Description: "Domain renewal", Amount: 18.99}},
+		{"Conference day (fixed):800", invoice.Adjustment{Description: "Conference day (fixed)", Amount: 800}},
+		{"Licenses:50:3", invoice.Adjustment{Description: "Licenses", Amount: 50, Quantity: 3}},
+		{"Conference:800:2:day", invoice.Adjustment{Description: "Conference", Amount: 800, Quantity: 2, Unit: "day"}},
+		{"Credit: overbilled March:-120", invoice.Adjustment{Description: "Credit: overbilled March", Amount: -120}},
+		{"Ticket ABC:123:40:1.5:hour", invoice.Adjustment{Description: "Ticket ABC:123", Amount: 40, Quantity: 1.5, Unit: "hour"}},
+	}
+	for _, tt := range tests {
+		got, err := invoice.ParseItem(tt.in)
+		if err != nil {
+			t.Errorf("ParseItem(%q): %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseItem(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseItem_Errors(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Domain renewal:lots", `amount "lots" is not a number`},
+		{"Domain renewal:$18.99", `amount "$18.99" is not a number`},
+		{"Domain renewal:0", `amount "0" is not a number other than 0`},
+		{"Domain renewal", "want description:amount"},
+		{":18.99", "the description is empty"},
+		{"Licenses:50:-3", `quantity "-3" is not a positive number`},
+	}
+	for _, tt := range tests {
+		_, err := invoice.ParseItem(tt.in)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("ParseItem(%q) error = %v, want it to mention %q", tt.in, err, tt.want)
+		}
+	}
+}
+
+func TestAdjustmentLineItem_Quantity(t *testing.T) {
+	l := invoice.Adjustment{Description: "Licenses", Amount: 50, Quantity: 3}.LineItem()
+	if l.Unit != invoice.UnitItem || l.Quantity != 3 || l.Amount() != 150 {
+		t.Errorf("LineItem() = %+v, want 3 items at 50", l)
+	}
+	inv := &invoice.Invoice{Year: 2025, Month: time.January, Rate: 100, Weeks: invoice.WeeksForMonth(2025, time.January, 40)}
+	before := inv.Total()
+	inv.Adjustments = []invoice.Adjustment{{Description: "Conference", Amount: 800, Quantity: 2, Unit: invoice.UnitDay}}
+	if got := inv.Total() - before; got != 1600 {
+		t.Errorf("the item added %v to the total, want 1600", got)
+	}
+}
@@ -0,0 +1,300 @@
+package invoice
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Currency is the currency of every amount on an invoice: rates are in
+// US dollars.
+const Currency = "USD"
+
+// jsonDate is the layout of dates in an invoice's JSON.
+const jsonDate = time.DateOnly
+
+// invoiceJSON is the JSON form of an Invoice. See Invoice.MarshalJSON.
+type invoiceJSON struct {
+	Number      string          `json:"number"`
+	Month       string          `json:"month"`
+	MonthNumber int             `json:"month_number"`
+	Year        int             `json:"year"`
+	IssueDate   string          `json:"issue_date,omitempty"`
+	DueDate     string          `json:"due_date,omitempty"`
+	Vendor      string          `json:"vendor"`
+	Customer    string          `json:"customer"`
+	PerVendor   bool            `json:"per_vendor,omitempty"`
+	Currency    string          `json:"currency"`
+	Rate        float64         `json:"rate"`
+	Weeks       []weekJSON      `json:"weeks"`
+	Adjustments []Adjustment    `json:"adjustments,omitempty"`
+	Subtotal    float64         `json:"subtotal"`
+	Tax         float64         `json:"tax"`
+	Discount    float64         `json:"discount"`
+	Total       float64         `json:"total"`
+	Payment     *PaymentDetails `json:"payment,omitempty"`
+	Notes       string          `json:"notes,omitempty"`
+	PONumber    string          `json:"po_number,omitempty"`
+	Exchange    *exchangeJSON   `json:"exchange,omitempty"`
+}
+
+// exchangeJSON is the JSON form of an ExchangeRate, with the total billed
+// in its currency.
+type exchangeJSON struct {
+	Currency string  `json:"currency"`
+	Rate     float64 `json:"rate"`
+	Date     string  `json:"date"`
+	Source   string  `json:"source,omitempty"`
+	Total    float64 `json:"total"`
+}
+
+// weekJSON is the JSON form of a Week. See Week.MarshalJSON.
+type weekJSON struct {
+	Label    string   `json:"label"`
+	Start    string   `json:"start"`
+	End      string   `json:"end"`
+	Workdays *int     `json:"workdays,omitempty"`
+	Hours    float64  `json:"hours"`
+	Subtotal *float64 `json:"subtotal,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler. An invoice is an object with:
+//
+//   - number: the invoice number, e.g. "INV-202501-acme-corp"
+//   - month, month_number, year: the month invoiced, e.g. "January", 1, 2025
+//   - issue_date, due_date: ISO dates, e.g. "2025-02-01"; left out if not set
+//   - vendor, customer: the names of the two parties
+//   - per_vendor: true for one of an agency's invoices, one per vendor,
+//     whose number names the vendor too; left out otherwise
+//   - currency: the currency of every amount, always "USD"
+//   - rate: the hourly rate
+//   - weeks: the line items, each with label, start, end, hours and
+//     subtotal, and workdays if ShowWorkdays is set
+//   - adjustments: flat amounts and ad-hoc items, each with description and
+//     amount, and quantity and unit if counted; left out if none
+//   - subtotal, tax, discount, total: the sum of the weeks and adjustments,
+//     the tax and discount (both 0, as invoicer adds neither), and the
+//     amount due
+//   - payment: the bank account, with name, iban and bic; left out if none
+//   - notes, po_number: shown on the invoice; left out if empty
+//   - exchange: for an invoice billed in another currency, the currency,
+//     the rate one dollar buys, the rate's date and source, and the total
+//     in that currency; left out otherwise
+func (inv *Invoice) MarshalJSON() ([]byte, error) {
+	out := invoiceJSON{
+		Number:      InvoiceNumber(inv),
+		Month:       inv.Month.String(),
+		MonthNumber: int(inv.Month),
+		Year:        inv.Year,
+		IssueDate:   formatJSONDate(inv.Issued),
+		DueDate:     formatJSONDate(inv.Due),
+		Vendor:      inv.Vendor,
+		Customer:    inv.Customer,
+		PerVendor:   inv.PerVendor,
+		Currency:    Currency,
+		Rate:        inv.Rate,
+		Adjustments: inv.Adjustments,
+		Payment:     inv.Payment,
+		Notes:       inv.Notes,
+		PONumber:    inv.PONumber,
+	}
+	b := inv.Breakdown()
+	out.Subtotal = b.Subtotal.Dollars()
+	out.Tax = b.Tax.Dollars()
+	out.Discount = b.Discount.Dollars()
+	out.Total = b.AmountDue.Dollars()
+	if x := inv.Exchange; x != nil {
+		out.Exchange = &exchangeJSON{
+			Currency: x.Currency,
+			Rate:     x.Rate,
+			Date:     formatJSONDate(x.Date),
+			Source:   x.Source,
+			Total:    inv.BilledAmountDue(b).Dollars(),
+		}
+	}
+	out.Weeks = inv.weeksJSON(b, inv.ShowWorkdays)
+	return json.Marshal(out)
+}
+
+// MarshalWeeks returns the JSON of the invoice's weeks, an array of them as
+// they are in the invoice's JSON, each with its workdays too.
+func (inv *Invoice) MarshalWeeks() ([]byte, error) {
+	return json.Marshal(inv.weeksJSON(inv.Breakdown(), true))
+}
+
+// weeksJSON returns the JSON form of the invoice's weeks, labeled and with
+// their subtotals from b, and with their workdays if workdays is set.
+func (inv *Invoice) weeksJSON(b InvoiceBreakdown, workdays bool) []weekJSON {
+	weeks := make([]weekJSON, 0, len(inv.Weeks))
+	for i, w := range inv.Weeks {
+		wj := w.toJSON()
+		wj.Label = inv.weekLabel(w)
+		if workdays {
+			wj.Workdays = &w.Workdays
+		}
+		subtotal := b.Lines[i].Amount.Dollars()
+		wj.Subtotal = &subtotal
+		weeks = append(weeks, wj)
+	}
+	return weeks
+}
+
+// UnmarshalJSON implements json.Unmarshaler for the object MarshalJSON
+// writes. The month may be given by name, by month_number, or both if they
+// agree. Computed values (number, label and the amounts other than the
+// rates) are ignored, since they follow from the rest.
+func (inv *Invoice) UnmarshalJSON(data []byte) error {
+	var in invoiceJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	if in.Currency != "" && in.Currency != Currency {
+		return fmt.Errorf("invoice currency %q is not supported (amounts are in %s)", in.Currency, Currency)
+	}
+	month, err := jsonMonth(in.Month, in.MonthNumber)
+	if err != nil {
+		return err
+	}
+	issued, err := parseJSONDate("issue_date", in.IssueDate)
+	if err != nil {
+		return err
+	}
+	due, err := parseJSONDate("due_date", in.DueDate)
+	if err != nil {
+		return err
+	}
+	*inv = Invoice{
+		Month:       month,
+		Year:        in.Year,
+		Vendor:      in.Vendor,
+		Customer:    in.Customer,
+		PerVendor:   in.PerVendor,
+		Rate:        in.Rate,
+		Issued:      issued,
+		Due:         due,
+		Payment:     in.Payment,
+		Adjustments: in.Adjustments,
+		Notes:       in.Notes,
+		PONumber:    in.PONumber,
+	}
+	if xj := in.Exchange; xj != nil {
+		if err := ValidateCurrency(xj.Currency); err != nil {
+			return fmt.Errorf("exchange: %w", err)
+		}
+		if xj.Rate <= 0 {
+			return fmt.Errorf("exchange: rate must be positive, got %v", xj.Rate)
+		}
+		date, err := parseJSONDate("exchange date", xj.Date)
+		if err != nil {
+			return err
+		}
+		inv.Exchange = &ExchangeRate{Currency: xj.Currency, Rate: xj.Rate, Date: date, Source: xj.Source}
+	}
+	for i, wj := range in.Weeks {
+		w, err := wj.toWeek()
+		if err != nil {
+			return fmt.Errorf("week %d: %w", i+1, err)
+		}
+		inv.Weeks = append(inv.Weeks, w)
+	}
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler. A week is an object with its
+// label, e.g. "Jan 6-12", its start and end as ISO dates, and its hours.
+// In an invoice it also has its subtotal.
+func (w Week) MarshalJSON() ([]byte, error) {
+	return json.Marshal(w.toJSON())
+}
+
+// UnmarshalJSON implements json.Unmarshaler for the object MarshalJSON
+// writes. The label and subtotal are ignored.
+func (w *Week) UnmarshalJSON(data []byte) error {
+	var in weekJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	week, err := in.toWeek()
+	if err != nil {
+		return err
+	}
+	*w = week
+	return nil
+}
+
+func (w Week) toJSON() weekJSON {
+	return weekJSON{
+		Label: FormatWeekLabel(w),
+		Start: formatJSONDate(w.Start),
+		End:   formatJSONDate(w.End),
+		Hours: w.Hours,
+	}
+}
+
+func (wj weekJSON) toWeek() (Week, error) {
+	start, err := parseJSONDate("start", wj.Start)
+	if err != nil {
+		return Week{}, err
+	}
+	end, err := parseJSONDate("end", wj.End)
+	if err != nil {
+		return Week{}, err
+	}
+	if start.IsZero() || end.IsZero() {
+		return Week{}, fmt.Errorf("start and end are required")
+	}
+	if end.Before(start) {
+		return Week{}, fmt.Errorf("end %s is before start %s", wj.End, wj.Start)
+	}
+	w := Week{Start: start, End: end, Hours: wj.Hours}
+	if wj.Workdays != nil {
+		if *wj.Workdays < 0 {
+			return Week{}, fmt.Errorf("workdays must not be negative, got %d", *wj.Workdays)
+		}
+		w.Workdays = *wj.Workdays
+	}
+	return w, nil
+}
+
+// jsonMonth returns the month named by name, number, or both.
+func jsonMonth(name string, number int) (time.Month, error) {
+	if number != 0 && (number < 1 || number > 12) {
+		return 0, fmt.Errorf("month_number must be between 1 and 12, got %d", number)
+	}
+	if name == "" {
+		if number == 0 {
+			return 0, fmt.Errorf("month is required")
+		}
+		return time.Month(number), nil
+	}
+	for m := time.January; m <= time.December; m++ {
+		if strings.EqualFold(name, m.String()) {
+			if number != 0 && number != int(m) {
+				return 0, fmt.Errorf("month %q does not match month_number %d", name, number)
+			}
+			return m, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown month %q", name)
+}
+
+// formatJSONDate returns t as an ISO date, or "" if t is zero.
+func formatJSONDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(jsonDate)
+}
+
+// parseJSONDate parses the ISO date in field; "" is the zero time.
+func parseJSONDate(field, s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(jsonDate, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s %q is not a date like 2025-01-31", field, s)
+	}
+	return t, nil
+}
@@ -0,0 +1,152 @@
+package invoice_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+// jsonInvoice returns testInvoice with every optional field set.
+func jsonInvoice() *invoice.Invoice {
+	inv := testInvoice()
+	inv.Issued = time.Date(2025, time.February, 1, 0, 0, 0, 0, time.UTC)
+	inv.Due = time.Date(2025, time.March, 3, 0, 0, 0, 0, time.UTC)
+	inv.Payment = &invoice.PaymentDetails{IBAN: testIBAN, BIC: "COBADEFFXXX"}
+	return inv
+}
+
+func TestInvoiceMarshalJSON(t *testing.T) {
+	data, err := json.MarshalIndent(jsonInvoice(), "", "  ")
+	if err != nil {
+		t.Fatalf("MarshalIndent: %v", err)
+	}
+	checkGolden(t, "invoice.json", append(data, '\n'))
+}
+
+func TestInvoiceMarshalJSON_OptionalFields(t *testing.T) {
+	data, err := json.Marshal(testInvoice())
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"issue_date", "due_date", "payment"} {
+		if _, ok := fields[key]; ok {
+			t.Errorf("%s should be left out when not set: %s", key, data)
+		}
+	}
+	if fields["month"] != "January" || fields["month_number"] != 1.0 || fields["total"] != 10800.0 {
+		t.Errorf("unexpected JSON: %s", data)
+	}
+}
+
+func TestInvoiceJSON_RoundTrip(t *testing.T) {
+	perVendor := testInvoice()
+	perVendor.PerVendor = true
+	for _, inv := range []*invoice.Invoice{testInvoice(), jsonInvoice(), perVendor} {
+		data, err := json.Marshal(inv)
+		if err != nil {
+			t.Fatalf("Marshal: %v", err)
+		}
+		var got invoice.Invoice
+		if err := json.Unmarshal(data, &got); err != nil {
+			t.Fatalf("Unmarshal: %v", err)
+		}
+		if !reflect.DeepEqual(&got, inv) {
+			t.Errorf("round trip changed the invoice:\ngot  %+v\nwant %+v", got, *inv)
+		}
+	}
+}
+
+func TestWeekJSON_RoundTrip(t *testing.T) {
+	week := testInvoice().Weeks[1]
+	data, err := json.Marshal(week)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	if want := `{"label":"Jan 6-12","start":"2025-01-06","end":"2025-01-12","hours":40}`; string(data) != want {
+		t.Errorf("Marshal = %s, want %s", data, want)
+	}
+	var got invoice.Week
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if got != week {
+		t.Errorf("round trip = %+v, want %+v", got, week)
+	}
+}
+
+func TestInvoiceMarshalJSON_ShowWorkdays(t *testing.T) {
+	inv := testInvoice()
+	inv.Weeks = invoice.WeeksForMonth(2025, time.January, 40)[:2]
+	data, err := json.Marshal(inv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "workdays") {
+		t.Errorf("the JSON has workdays without ShowWorkdays: %s", data)
+	}
+
+	inv.ShowWorkdays = true
+	if data, err = json.Marshal(inv); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`"label":"Jan 1-5","start":"2025-01-01","end":"2025-01-05","workdays":3`, `"workdays":5`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("the JSON does not contain %s: %s", want, data)
+		}
+	}
+	var back invoice.Invoice
+	if err := json.Unmarshal(data, &back); err != nil {
+		t.Fatal(err)
+	}
+	if back.Weeks[0].Workdays != 3 || back.Weeks[1].Workdays != 5 {
+		t.Errorf("read back workdays %d and %d, want 3 and 5", back.Weeks[0].Workdays, back.Weeks[1].Workdays)
+	}
+}
+
+func TestInvoiceUnmarshalJSON(t *testing.T) {
+	week := `{"start":"2025-01-06","end":"2025-01-12","hours":40}`
+	tests := []struct {
+		name    string
+		json    string
+		want    time.Month
+		wantErr string
+	}{
+		{"name only", `{"month":"march","year":2025}`, time.March, ""},
+		{"number only", `{"month_number":3,"year":2025}`, time.March, ""},
+		{"computed fields ignored", `{"number":"X","month":"March","total":1,"weeks":[` + strings.Replace(week, "{", `{"label":"X","subtotal":1,`, 1) + `]}`, time.March, ""},
+		{"no month", `{"year":2025}`, 0, "month is required"},
+		{"mismatched month", `{"month":"March","month_number":4}`, 0, "does not match"},
+		{"bad month number", `{"month_number":13}`, 0, "between 1 and 12"},
+		{"unknown month", `{"month":"Smarch"}`, 0, "unknown month"},
+		{"bad date", `{"month":"March","issue_date":"03/01/2025"}`, 0, "issue_date"},
+		{"week without dates", `{"month":"March","weeks":[{"hours":8}]}`, 0, "week 1: start and end are required"},
+		{"week ends before it starts", `{"month":"March","weeks":[{"start":"2025-01-12","end":"2025-01-06"}]}`, 0, "before start"},
+		{"other currency", `{"month":"March","currency":"EUR"}`, 0, "currency"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var inv invoice.Invoice
+			err := json.Unmarshal([]byte(tt.json), &inv)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("expected error %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unmarshal: %v", err)
+			}
+			if inv.Month != tt.want {
+				t.Errorf("Month = %v, want %v", inv.Month, tt.want)
+			}
+		})
+	}
+}
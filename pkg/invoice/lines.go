@@ -0,0 +1,90 @@
+package invoice
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+// unitName is how a unit is written.
+type unitName struct {
+	// Plural follows a quantity, e.g. "40 hours".
+	Plural string
+	// Per follows a unit price, e.g. "$150.00/hr".
+	Per string
+	// Symbol is the short form of exports, e.g. "32 h at $150.00/h".
+	Symbol string
+	// UNECE is the UN/ECE Recommendation 20 code of e-invoices.
+	UNECE string
+}
+
+// unitNames are the names of the units invoicer knows.
+var unitNames = map[string]unitName{
+	UnitHour: {Plural: "hours", Per: "hr", Symbol: "h", UNECE: "HUR"},
+	UnitDay:  {Plural: "days", Per: "day", Symbol: "d", UNECE: "DAY"},
+	UnitItem: {Plural: "items", Per: "item", Symbol: "item", UNECE: "C62"},
+}
+
+// unitNameOf returns the name of unit. A unit invoicer does not know is
+// written as is, and counted as units ("C62") in e-invoices.
+func unitNameOf(unit string) unitName {
+	if n, ok := unitNames[unit]; ok {
+		return n
+	}
+	return unitName{Plural: unit, Per: unit, Symbol: unit, UNECE: "C62"}
+}
+
+// invoiceLine is one billed line of an invoice as the exports see it: a
+// line item with its unit price and amount rounded to cents.
+type invoiceLine struct {
+	Description string
+	Quantity    float64
+	Unit        string
+	// UnitPrice is the unrounded unit price, for exports that take more
+	// than two decimals.
+	UnitPrice  float64
+	Price      Cents
+	Amount     Cents
+	Start, End time.Time
+}
+
+// invoiceLines returns inv's lines in order, as in its Breakdown, and the
+// amount due. Lines of hours are described as services.
+func invoiceLines(inv *Invoice) ([]invoiceLine, Cents) {
+	b := inv.Breakdown()
+	var lines []invoiceLine
+	for _, l := range b.Lines {
+		desc := l.Description
+		if l.Unit == UnitHour {
+			desc = "Services " + desc
+		}
+		lines = append(lines, invoiceLine{
+			Description: desc,
+			Quantity:    l.Quantity,
+			Unit:        l.Unit,
+			UnitPrice:   l.UnitPrice,
+			Price:       l.Price,
+			Amount:      l.Amount,
+			Start:       l.Start,
+			End:         l.End,
+		})
+	}
+	return lines, b.AmountDue
+}
+
+// rateText describes the line's quantity at its price in short, e.g.
+// "32 h at $150.00/h", or the price of a flat line.
+func (l invoiceLine) rateText() string {
+	if l.Unit == "" {
+		return FormatMoney(l.Price.Dollars())
+	}
+	sym := unitNameOf(l.Unit).Symbol
+	return fmt.Sprintf("%s %s at %s/%s", formatQuantity(l.Quantity), sym, FormatMoney(l.Price.Dollars()), sym)
+}
+
+// formatQuantity returns q with at most four decimals and no trailing
+// zeros, e.g. "32" or "7.5", as accounting imports expect quantities.
+func formatQuantity(q float64) string {
+	return strconv.FormatFloat(math.Round(q*10000)/10000, 'f', -1, 64)
+}
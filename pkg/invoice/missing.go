@@ -0,0 +1,56 @@
+package invoice
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// NotInstalledError reports that a program invoicer needs is not installed,
+// with what to do about it. It wraps exec.ErrNotFound.
+type NotInstalledError struct {
+	// Tool is the program that is missing.
+	Tool string
+	// Purpose says what invoicer uses the tool for.
+	Purpose string
+	// Install lists commands that install the tool.
+	Install []string
+	// Instead suggests how to do without the tool.
+	Instead string
+}
+
+func (e *NotInstalledError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s is not installed or not on your PATH.\n%s", e.Tool, e.Purpose)
+	if len(e.Install) > 0 {
+		b.WriteString(" Install it with:\n")
+		for _, cmd := range e.Install {
+			fmt.Fprintf(&b, "    %s\n", cmd)
+		}
+	} else {
+		b.WriteString("\n")
+	}
+	if e.Instead != "" {
+		b.WriteString(e.Instead)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+func (e *NotInstalledError) Unwrap() error { return exec.ErrNotFound }
+
+// backendNotInstalled returns a NotInstalledError for the generation backend
+// that runs tool, suggesting the other backends instead.
+func backendNotInstalled(tool, purpose string, install ...string) error {
+	var others []string
+	for _, name := range Backends() {
+		if name != tool {
+			others = append(others, "--backend "+name)
+		}
+	}
+	return &NotInstalledError{
+		Tool:    tool,
+		Purpose: purpose,
+		Install: install,
+		Instead: "Or generate with another backend: " + strings.Join(others, ", ") + ".",
+	}
+}
@@ -0,0 +1,60 @@
+package invoice_test
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+func TestGenerate_BackendNotInstalled(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	tests := []struct {
+		backend string
+		want    []string
+	}{
+		{"opencode", []string{"opencode is not installed", "curl -fsSL https://opencode.ai/install | bash", "--backend claude", "--backend ollama"}},
+		{"claude", []string{"claude is not installed", "npm install -g @anthropic-ai/claude-code", "--backend opencode"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.backend, func(t *testing.T) {
+			_, err := invoice.Generate(context.Background(), tt.backend, testInvoice(), filepath.Join(t.TempDir(), "invoice.html"), invoice.GenerateOptions{Model: "anthropic/claude-haiku-4-5", Retries: 3})
+			var missing *invoice.NotInstalledError
+			if !errors.As(err, &missing) {
+				t.Fatalf("expected a NotInstalledError, got %v", err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("message does not contain %q:\n%s", want, err)
+				}
+			}
+			if strings.Contains(err.Error(), "--backend "+tt.backend) {
+				t.Errorf("message suggests the backend that is missing:\n%s", err)
+			}
+			if !errors.Is(err, exec.ErrNotFound) {
+				t.Errorf("error should wrap exec.ErrNotFound")
+			}
+		})
+	}
+}
+
+func TestConvertToPDF_NotInstalledMessage(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	t.Setenv("ProgramFiles", "")
+	t.Setenv("ProgramFiles(x86)", "")
+	t.Setenv("LocalAppData", "")
+	_, err := invoice.ConvertToPDF(context.Background(), "/tmp/invoice.html", "/tmp/invoice.pdf", invoice.PDFOptions{})
+	var missing *invoice.NotInstalledError
+	if !errors.As(err, &missing) {
+		t.Fatalf("expected a NotInstalledError, got %v", err)
+	}
+	for _, want := range []string{"wkhtmltopdf", "weasyprint, pandoc.", "It looked for wkhtmltopdf, chromium, chromium-browser, ", "brew install wkhtmltopdf", "leave out --pdf"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("message does not contain %q:\n%s", want, err)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package invoice
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"slices"
+	"strings"
+)
+
+// OpencodeModelsExec is the function used to run `opencode models`.
+// It can be overridden in tests to use a fake binary.
+var OpencodeModelsExec = func(ctx context.Context) ([]byte, error) {
+	cmd := command(ctx, "opencode", "models")
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	err := run(cmd)
+	return out.Bytes(), err
+}
+
+// OpencodeModels returns the model stubs opencode can use, in sorted order.
+func OpencodeModels(ctx context.Context) ([]string, error) {
+	out, err := OpencodeModelsExec(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return ParseModelList(out), nil
+}
+
+// ParseModelList extracts provider/model stubs from the output of
+// `opencode models`. Versions of opencode print either one stub per line or
+// JSON: a list of stubs, a list of model objects with provider and model IDs,
+// or providers with their models. Anything else in the output is ignored.
+// The stubs are returned sorted, without duplicates.
+func ParseModelList(out []byte) []string {
+	var models []string
+	trimmed := bytes.TrimSpace(out)
+	var v any
+	if len(trimmed) > 0 && (trimmed[0] == '[' || trimmed[0] == '{') && json.Unmarshal(trimmed, &v) == nil {
+		models = slices.DeleteFunc(jsonModels(v, ""), func(m string) bool { return !strings.Contains(m, "/") })
+	} else {
+		for _, line := range strings.Split(string(trimmed), "\n") {
+			line = strings.TrimSpace(line)
+			if strings.Contains(line, "/") && !strings.ContainsAny(line, " \t") {
+				models = append(models, line)
+			}
+		}
+	}
+	slices.Sort(models)
+	return slices.Compact(models)
+}
+
+// jsonModels collects the model stubs in a decoded JSON value. provider is
+// the provider the value belongs to, if known from an enclosing object.
+func jsonModels(v any, provider string) []string {
+	switch v := v.(type) {
+	case string:
+		return []string{stub(provider, v)}
+	case []any:
+		var models []string
+		for _, e := range v {
+			models = append(models, jsonModels(e, provider)...)
+		}
+		return models
+	case map[string]any:
+		id := firstString(v, "id", "modelID", "model_id")
+		if p := firstString(v, "providerID", "provider_id", "provider"); p != "" {
+			provider = p
+		}
+		if models, ok := v["models"]; ok {
+			// A provider with its models, which may be keyed by model ID.
+			if id != "" {
+				provider = id
+			}
+			if byID, ok := models.(map[string]any); ok {
+				var stubs []string
+				for modelID := range byID {
+					stubs = append(stubs, stub(provider, modelID))
+				}
+				return stubs
+			}
+			return jsonModels(models, provider)
+		}
+		if id != "" {
+			return []string{stub(provider, id)}
+		}
+		// Otherwise the keys are providers.
+		var models []string
+		for p, e := range v {
+			models = append(models, jsonModels(e, p)...)
+		}
+		return models
+	}
+	return nil
+}
+
+// stub joins provider and model unless model already names its provider.
+func stub(provider, model string) string {
+	if strings.Contains(model, "/") || provider == "" {
+		return model
+	}
+	return provider + "/" + model
+}
+
+// firstString returns the first of keys in m that holds a non-empty string.
+func firstString(m map[string]any, keys ...string) string {
+	for _, k := range keys {
+		if s, ok := m[k].(string); ok && s != "" {
+			return s
+		}
+	}
+	return ""
+}
@@ -0,0 +1,55 @@
+package invoice_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+func TestParseModelList(t *testing.T) {
+	tests := []struct {
+		fixture string
+		want    []string
+	}{
+		{"opencode-models.txt", []string{"anthropic/claude-haiku-4-5", "anthropic/claude-opus-4-1", "anthropic/claude-sonnet-4-6", "openai/gpt-5", "openai/gpt-5-mini", "opencode/grok-code"}},
+		{"opencode-models-log.txt", []string{"anthropic/claude-haiku-4-5", "openai/gpt-5"}},
+		{"opencode-models-list.json", []string{"anthropic/claude-haiku-4-5", "anthropic/claude-sonnet-4-6", "openai/gpt-5", "opencode/grok-code"}},
+		{"opencode-models-providers.json", []string{"anthropic/claude-haiku-4-5", "anthropic/claude-sonnet-4-6", "openai/gpt-5", "openai/gpt-5-mini"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			out, err := os.ReadFile(filepath.Join("testdata", tt.fixture))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := invoice.ParseModelList(out); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseModelList = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseModelList_Empty(t *testing.T) {
+	if got := invoice.ParseModelList(nil); len(got) != 0 {
+		t.Errorf("ParseModelList(nil) = %v", got)
+	}
+}
+
+func TestOpencodeModels_UsesExec(t *testing.T) {
+	orig := invoice.OpencodeModelsExec
+	t.Cleanup(func() { invoice.OpencodeModelsExec = orig })
+	invoice.OpencodeModelsExec = func(ctx context.Context) ([]byte, error) {
+		return []byte("openai/gpt-5\nanthropic/claude-haiku-4-5\nopenai/gpt-5\n"), nil
+	}
+	got, err := invoice.OpencodeModels(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"anthropic/claude-haiku-4-5", "openai/gpt-5"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("OpencodeModels = %v, want %v", got, want)
+	}
+}
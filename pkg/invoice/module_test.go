@@ -0,0 +1,132 @@
+package invoice_test
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+// The stable surface of the package, which the CLI and other modules build
+// on. A change to any of these signatures fails to compile here.
+var (
+	_ func(int, time.Month, float64) []invoice.Week                                                             = invoice.WeeksForMonth
+	_ func(string) (time.Month, error)                                                                          = invoice.ParseMonth
+	_ func(string, int, time.Time) (time.Month, int, error)                                                     = invoice.ResolveMonthYear
+	_ func(float64) string                                                                                      = invoice.FormatMoney
+	_ func(float64) string                                                                                      = invoice.FormatHours
+	_ func(invoice.Week) string                                                                                 = invoice.FormatWeekLabel
+	_ func(*invoice.Invoice) string                                                                             = invoice.InvoiceNumber
+	_ func(*invoice.Invoice) float64                                                                            = (*invoice.Invoice).Total
+	_ func(string) (invoice.Generator, error)                                                                   = invoice.Lookup
+	_ func(context.Context, string, *invoice.Invoice, string, invoice.GenerateOptions) (*invoice.Result, error) = invoice.Generate
+	_ func(context.Context, string, string, invoice.PDFOptions) (string, error)                                 = invoice.ConvertToPDF
+)
+
+// TestExternalModule builds a program in a module of its own that gets
+// pkg/invoice, as another Go service would, with the invoicer module
+// replaced by this tree.
+func TestExternalModule(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds a module")
+	}
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go is not installed")
+	}
+	root, err := filepath.Abs(filepath.Join("..", ".."))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	src, err := os.ReadFile(filepath.Join("testdata", "external", "main.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum, err := os.ReadFile(filepath.Join(root, "go.sum"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mod := "module example.com/external\n\ngo 1.25.0\n\nrequire github.com/zon/invoicer v0.0.0\n\nreplace github.com/zon/invoicer => " + root + "\n"
+	for name, data := range map[string][]byte{"main.go": src, "go.sum": sum, "go.mod": []byte(mod)} {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	run := func(args ...string) string {
+		t.Helper()
+		cmd := exec.Command(goBin, args...)
+		cmd.Dir = dir
+		// The dependencies are in the module cache already: this test's
+		// own build needed them.
+		cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod", "GOPROXY=off", "GOWORK=off")
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("go %s: %v\n%s", strings.Join(args, " "), err, out)
+		}
+		return string(out)
+	}
+	run("get", "github.com/zon/invoicer/pkg/invoice")
+	if got, want := run("run", "."), "INV-202501-acme-corp $27600.00\n"; got != want {
+		t.Errorf("the external program printed %q, want %q", got, want)
+	}
+}
+
+// cliSupport are the internal packages the CLI may import besides
+// pkg/invoice. None of them invoices anything: every invoice the CLI
+// makes goes through the package's exported API.
+var cliSupport = []string{
+	"github.com/zon/invoicer/internal/config",
+	"github.com/zon/invoicer/internal/fsutil",
+	"github.com/zon/invoicer/internal/mcp",
+	"github.com/zon/invoicer/internal/paths",
+}
+
+// TestCLIDependsOnPublicSurface lists the packages the CLI is built from
+// and fails on any of this module's packages that is neither pkg/invoice,
+// whose unexported code Go keeps out of reach, nor one of cliSupport, so
+// invoicing code cannot move back under internal/ for the CLI alone. It
+// also checks that pkg/invoice itself imports nothing internal, which
+// other modules could not see.
+func TestCLIDependsOnPublicSurface(t *testing.T) {
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go is not installed")
+	}
+	deps := func(pkgs ...string) []string {
+		t.Helper()
+		cmd := exec.Command(goBin, append([]string{"list", "-deps", "-f", "{{.ImportPath}}"}, pkgs...)...)
+		cmd.Dir = filepath.Join("..", "..")
+		out, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("go list %s: %v", strings.Join(pkgs, " "), err)
+		}
+		var own []string
+		for _, p := range strings.Fields(string(out)) {
+			if strings.HasPrefix(p, "github.com/zon/invoicer/") {
+				own = append(own, p)
+			}
+		}
+		return own
+	}
+
+	const public = "github.com/zon/invoicer/pkg/invoice"
+	allowed := append([]string{public, "github.com/zon/invoicer/cmd/invoicer", "github.com/zon/invoicer/internal/cli"}, cliSupport...)
+	for _, p := range deps("./cmd/invoicer", "./internal/cli") {
+		if !slices.Contains(allowed, p) {
+			t.Errorf("the CLI depends on %s rather than on the exported API of %s", p, public)
+		}
+	}
+	for _, p := range deps("./pkg/invoice") {
+		if p != public {
+			t.Errorf("%s depends on %s, which other modules cannot import", public, p)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package invoice
+
+import (
+	"fmt"
+	"math"
+)
+
+// Cents is an amount of money in whole cents. Amounts that must add up
+// exactly, such as the lines and totals of an e-invoice, are rounded to
+// Cents once and summed as Cents, so no float error creeps in.
+type Cents int64
+
+// ToCents rounds amount, in dollars, to the nearest cent; halves round
+// away from zero. amount is first taken to a millionth of a dollar, so a
+// half cent such as 1.005, which a float64 holds as 1.00499..., rounds up
+// as written.
+func ToCents(amount float64) Cents {
+	return Cents(math.Round(math.Round(amount*1e6) / 1e4))
+}
+
+// String returns c in dollars with a dot before the two cent digits and
+// no grouping, e.g. "1234.50" or "-0.05".
+func (c Cents) String() string {
+	sign := ""
+	if c < 0 {
+		sign, c = "-", -c
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, c/100, c%100)
+}
+
+// Dollars returns c as an amount in dollars.
+func (c Cents) Dollars() float64 {
+	return float64(c) / 100
+}
@@ -0,0 +1,89 @@
+package invoice_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+func TestCents(t *testing.T) {
+	tests := []struct {
+		amount float64
+		want   string
+	}{
+		{0, "0.00"},
+		{1234.5, "1234.50"},
+		{0.005, "0.01"},
+		{1.005, "1.01"},
+		{99.999, "100.00"},
+		{-0.05, "-0.05"},
+		{-12.345, "-12.35"},
+		{1000000, "1000000.00"},
+	}
+	for _, tt := range tests {
+		if got := invoice.ToCents(tt.amount).String(); got != tt.want {
+			t.Errorf("ToCents(%v) = %s, want %s", tt.amount, got, tt.want)
+		}
+	}
+}
+
+func TestFormatMoney(t *testing.T) {
+	if got := invoice.FormatMoney(10800); got != "$10800.00" {
+		t.Errorf("FormatMoney(10800) = %q", got)
+	}
+}
+
+func TestInvoiceFormatRateAndHours(t *testing.T) {
+	precision := func(n int) *int { return &n }
+	tests := []struct {
+		name           string
+		rate, hours    *int
+		wantRate       string
+		wantHours      string
+		wantOddRate    string
+		wantRoundHours string
+	}{
+		{"defaults", nil, nil, "$150.00", "32.0", "$187.13", "7.5"},
+		{"whole rate", precision(0), nil, "$150", "32.0", "$187", "7.5"},
+		{"two-decimal hours", nil, precision(2), "$150.00", "32.00", "$187.13", "7.46"},
+		{"more decimals", precision(3), precision(0), "$150.000", "32", "$187.125", "7"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inv := testInvoice()
+			inv.RatePrecision, inv.HoursPrecision = tt.rate, tt.hours
+			if got := inv.FormatRate(150); got != tt.wantRate {
+				t.Errorf("FormatRate(150) = %q, want %q", got, tt.wantRate)
+			}
+			if got := inv.FormatRate(187.125); got != tt.wantOddRate {
+				t.Errorf("FormatRate(187.125) = %q, want %q", got, tt.wantOddRate)
+			}
+			if got := inv.FormatHours(32); got != tt.wantHours {
+				t.Errorf("FormatHours(32) = %q, want %q", got, tt.wantHours)
+			}
+			if got := inv.FormatHours(7.46); got != tt.wantRoundHours {
+				t.Errorf("FormatHours(7.46) = %q, want %q", got, tt.wantRoundHours)
+			}
+		})
+	}
+}
+
+func TestPrecision_ShownNotComputed(t *testing.T) {
+	inv := testInvoice()
+	inv.Rate = 150.25
+	inv.Weeks = inv.Weeks[:1]
+	inv.Weeks[0].Hours = 7.46
+	want := inv.Breakdown()
+	zero := 0
+	inv.RatePrecision, inv.HoursPrecision = &zero, &zero
+	if got := inv.Breakdown(); got.AmountDue != want.AmountDue {
+		t.Errorf("amount due = %s, want %s", got.AmountDue, want.AmountDue)
+	}
+	prompt := invoice.BuildPrompt(inv, "/tmp/invoice.html")
+	for _, s := range []string{"- Hourly Rate: $150\n", "7 hours @ $150/hr = $1120.87", "Total Amount: $1120.87"} {
+		if !strings.Contains(prompt, s) {
+			t.Errorf("prompt does not contain %q:\n%s", s, prompt)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package invoice
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Chat services a notification can be posted to.
+const (
+	NotifySlack   = "slack"
+	NotifyDiscord = "discord"
+)
+
+// NotifyTimeout bounds posting a notification.
+const NotifyTimeout = 10 * time.Second
+
+// DefaultNotifyMessage is the template of a notification if none is
+// configured.
+const DefaultNotifyMessage = "Invoice {{.Number}} for {{.Total}} generated{{with .Due}} (due {{.}}){{end}}: {{.Path}}"
+
+// NotifyProvider returns the chat service a webhook URL posts to: a Slack
+// incoming webhook (https://hooks.slack.com/...) or a Discord webhook
+// (https://discord.com/api/webhooks/...). The URL is a credential, so
+// errors do not repeat it.
+func NotifyProvider(webhook string) (string, error) {
+	u, err := url.Parse(webhook)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return "", errors.New("a notification webhook must be an https URL")
+	}
+	host := strings.ToLower(u.Hostname())
+	switch {
+	case host == "hooks.slack.com":
+		return NotifySlack, nil
+	case (host == "discord.com" || host == "discordapp.com" || strings.HasSuffix(host, ".discord.com")) &&
+		strings.HasPrefix(u.Path, "/api/webhooks/"):
+		return NotifyDiscord, nil
+	}
+	return "", fmt.Errorf("the notification webhook on %s is neither a Slack incoming webhook nor a Discord webhook", u.Host)
+}
+
+// NotifyFields are the fields a notification template can use: those of
+// an invoice email, and Path, the generated file.
+type NotifyFields struct {
+	EmailFields
+	Path string
+}
+
+// RenderNotification fills in the template tmpl, or DefaultNotifyMessage
+// if it is "", with inv's fields and the path of the generated file. The
+// fields are escaped for provider, so a customer named "Smith & <Sons>"
+// is shown as is instead of as Slack markup or Discord markdown.
+func RenderNotification(provider, tmpl string, inv *Invoice, path string) (string, error) {
+	if tmpl == "" {
+		tmpl = DefaultNotifyMessage
+	}
+	t, err := template.New("notification").Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return "", fmt.Errorf("notification template: %w", err)
+	}
+	esc := notifyEscaper(provider)
+	f := InvoiceEmailFields(inv)
+	fields := NotifyFields{
+		EmailFields: EmailFields{
+			Number:   esc(f.Number),
+			Vendor:   esc(f.Vendor),
+			Customer: esc(f.Customer),
+			Month:    f.Month,
+			Year:     f.Year,
+			Hours:    f.Hours,
+			Total:    f.Total,
+			Issued:   f.Issued,
+			Due:      f.Due,
+		},
+		Path: esc(path),
+	}
+	var buf strings.Builder
+	if err := t.Execute(&buf, fields); err != nil {
+		return "", fmt.Errorf("notification template: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// notifyEscaper returns the function that escapes a field's text for
+// provider.
+func notifyEscaper(provider string) func(string) string {
+	switch provider {
+	case NotifySlack:
+		// Slack only treats &, < and > as markup in message text.
+		return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace
+	case NotifyDiscord:
+		return strings.NewReplacer(`\`, `\\`, "*", `\*`, "_", `\_`, "~", `\~`, "`", "\\`", "|", `\|`, ">", `\>`, "#", `\#`).Replace
+	}
+	return func(s string) string { return s }
+}
+
+// NotificationPayload returns the JSON body that posts text to a webhook
+// of provider. Discord is told not to turn any mention in the text into a
+// ping.
+func NotificationPayload(provider, text string) ([]byte, error) {
+	switch provider {
+	case NotifySlack:
+		return json.Marshal(map[string]string{"text": text})
+	case NotifyDiscord:
+		return json.Marshal(map[string]any{
+			"content":          text,
+			"allowed_mentions": map[string][]string{"parse": {}},
+		})
+	}
+	return nil, fmt.Errorf("unknown notification provider %q", provider)
+}
+
+// PostNotification posts payload to the webhook. client nil means a
+// client with NotifyTimeout.
+func PostNotification(ctx context.Context, client *http.Client, webhook string, payload []byte) error {
+	if client == nil {
+		client = &http.Client{Timeout: NotifyTimeout}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		// The URL is the webhook's credential, so it is kept out of the
+		// error.
+		var uerr *url.Error
+		if errors.As(err, &uerr) {
+			err = uerr.Err
+		}
+		return fmt.Errorf("posting the notification: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		if msg := strings.TrimSpace(string(body)); msg != "" {
+			return fmt.Errorf("posting the notification: %s: %s", resp.Status, msg)
+		}
+		return fmt.Errorf("posting the notification: %s", resp.Status)
+	}
+	return nil
+}
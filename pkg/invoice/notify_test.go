@@ -0,0 +1,191 @@
+package invoice_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+func TestNotifyProvider(t *testing.T) {
+	tests := []struct {
+		webhook string
+		want    string
+		wantErr string
+	}{
+		{"https://hooks.slack.com/services/T000/B000/XXXX", invoice.NotifySlack, ""},
+		{"https://discord.com/api/webhooks/123/abc", invoice.NotifyDiscord, ""},
+		{"https://discordapp.com/api/webhooks/123/abc", invoice.NotifyDiscord, ""},
+		{"https://ptb.discord.com/api/webhooks/123/abc", invoice.NotifyDiscord, ""},
+		{"http://hooks.slack.com/services/T000/B000/XXXX", "", "must be an https URL"},
+		{"https://discord.com/channels/123", "", "neither a Slack incoming webhook nor a Discord webhook"},
+		{"https://example.com/hook", "", "on example.com"},
+	}
+	for _, tt := range tests {
+		got, err := invoice.NotifyProvider(tt.webhook)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("NotifyProvider(%q) error = %v, want it to contain %q", tt.webhook, err, tt.wantErr)
+			}
+			if err != nil && strings.Contains(err.Error(), "/hook") {
+				t.Errorf("NotifyProvider(%q) error repeats the URL: %v", tt.webhook, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("NotifyProvider(%q) = %q, %v, want %q", tt.webhook, got, err, tt.want)
+		}
+	}
+}
+
+func notifyInvoice() *invoice.Invoice {
+	return &invoice.Invoice{
+		Month:    time.January,
+		Year:     2025,
+		Vendor:   "Jane Smith",
+		Customer: "Smith & <Sons> *Ltd*",
+		Rate:     100,
+		Weeks:    []invoice.Week{{Hours: 40}},
+		Issued:   time.Date(2025, time.February, 1, 0, 0, 0, 0, time.UTC),
+		Due:      time.Date(2025, time.March, 2, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestRenderNotification_Default(t *testing.T) {
+	inv := notifyInvoice()
+	got, err := invoice.RenderNotification(invoice.NotifySlack, "", inv, "invoices/a.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "Invoice INV-202501-smith-&amp;-&lt;sons&gt;-*ltd* for $4000.00 generated (due March 2, 2025): invoices/a.pdf"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestRenderNotification_EscapesFields(t *testing.T) {
+	tmpl := "{{.Customer}} owes {{.Total}}"
+	tests := []struct {
+		provider string
+		want     string
+	}{
+		{invoice.NotifySlack, "Smith &amp; &lt;Sons&gt; *Ltd* owes $4000.00"},
+		{invoice.NotifyDiscord, `Smith & <Sons\> \*Ltd\* owes $4000.00`},
+	}
+	for _, tt := range tests {
+		got, err := invoice.RenderNotification(tt.provider, tmpl, notifyInvoice(), "")
+		if err != nil {
+			t.Fatalf("%s: %v", tt.provider, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.provider, got, tt.want)
+		}
+	}
+}
+
+func TestRenderNotification_NoDueDate(t *testing.T) {
+	inv := notifyInvoice()
+	inv.Due = time.Time{}
+	got, err := invoice.RenderNotification(invoice.NotifySlack, "", inv, "a.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(got, "due") {
+		t.Errorf("got %q, want no due date", got)
+	}
+}
+
+func TestRenderNotification_BadTemplate(t *testing.T) {
+	for _, tmpl := range []string{"{{.Number", "{{.Amount}}"} {
+		if _, err := invoice.RenderNotification(invoice.NotifySlack, tmpl, notifyInvoice(), ""); err == nil || !strings.Contains(err.Error(), "notification template") {
+			t.Errorf("RenderNotification(%q) error = %v, want a template error", tmpl, err)
+		}
+	}
+}
+
+func TestNotificationPayload(t *testing.T) {
+	text := "Invoice INV-1 for @everyone"
+
+	data, err := invoice.NotificationPayload(invoice.NotifySlack, text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var slack map[string]any
+	if err := json.Unmarshal(data, &slack); err != nil {
+		t.Fatal(err)
+	}
+	if len(slack) != 1 || slack["text"] != text {
+		t.Errorf("Slack payload = %s", data)
+	}
+
+	data, err = invoice.NotificationPayload(invoice.NotifyDiscord, text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var discord struct {
+		Content         string `json:"content"`
+		AllowedMentions struct {
+			Parse []string `json:"parse"`
+		} `json:"allowed_mentions"`
+	}
+	if err := json.Unmarshal(data, &discord); err != nil {
+		t.Fatal(err)
+	}
+	if discord.Content != text {
+		t.Errorf("Discord content = %q, want %q", discord.Content, text)
+	}
+	if !strings.Contains(string(data), `"parse":[]`) || len(discord.AllowedMentions.Parse) != 0 {
+		t.Errorf("Discord payload = %s, want no parsed mentions", data)
+	}
+
+	if _, err := invoice.NotificationPayload("teams", text); err == nil {
+		t.Error("expected an error for an unknown provider")
+	}
+}
+
+func TestPostNotification(t *testing.T) {
+	var got []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("Content-Type = %q", ct)
+		}
+		got, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	payload := []byte(`{"text":"hi"}`)
+	if err := invoice.PostNotification(context.Background(), srv.Client(), srv.URL+"/services/secret", payload); err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(payload) {
+		t.Errorf("body = %s, want %s", got, payload)
+	}
+}
+
+func TestPostNotification_Errors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "invalid_token", http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	err := invoice.PostNotification(context.Background(), srv.Client(), srv.URL+"/services/secret", []byte("{}"))
+	if err == nil || !strings.Contains(err.Error(), "403 Forbidden: invalid_token") {
+		t.Errorf("error = %v, want the status and the reply", err)
+	}
+
+	srv.Close()
+	err = invoice.PostNotification(context.Background(), srv.Client(), srv.URL+"/services/secret", []byte("{}"))
+	if err == nil {
+		t.Fatal("expected an error from a closed server")
+	}
+	if strings.Contains(err.Error(), "secret") {
+		t.Errorf("error repeats the webhook URL: %v", err)
+	}
+}
@@ -0,0 +1,175 @@
+package invoice
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
+)
+
+func init() {
+	Register("ollama", ollamaGenerator{})
+}
+
+// Defaults for the ollama backend.
+const (
+	DefaultOllamaHost  = "http://localhost:11434"
+	DefaultOllamaModel = "llama3.2"
+)
+
+// OllamaTimeout bounds a whole ollama generation request, including streaming.
+const OllamaTimeout = 10 * time.Minute
+
+// OllamaClient is the HTTP client used to call the ollama API.
+// It can be overridden in tests.
+var OllamaClient = &http.Client{Timeout: OllamaTimeout}
+
+// ollamaRequest is the body of a POST to /api/generate.
+type ollamaRequest struct {
+	Model  string `json:"model"`
+	Prompt string `json:"prompt"`
+	Stream bool   `json:"stream"`
+}
+
+// ollamaChunk is one streamed JSON object from /api/generate.
+type ollamaChunk struct {
+	Response string `json:"response"`
+	Done     bool   `json:"done"`
+	Error    string `json:"error"`
+}
+
+// ollamaGenerator asks an ollama server for the HTML invoice, extracts the HTML
+// from the streamed response, and writes the file itself.
+type ollamaGenerator struct{}
+
+func (ollamaGenerator) Generate(ctx context.Context, inv *Invoice, outputPath string, opts GenerateOptions) (*Result, error) {
+	host, model := opts.OllamaHost, opts.OllamaModel
+	if host == "" {
+		host = DefaultOllamaHost
+	}
+	if model == "" {
+		model = DefaultOllamaModel
+	}
+	prompt := BuildOllamaPrompt(inv)
+	opts.progress(ProgressEvent{Kind: ProgressPromptBuilt, Model: model})
+	opts.progress(ProgressEvent{Kind: ProgressStarted, Model: model})
+	if err := generateOllama(ctx, prompt, host, model, outputPath); err != nil {
+		return nil, err
+	}
+	return &Result{Path: outputPath, Model: model}, nil
+}
+
+// generateOllama streams the invoice prompt asks for from the ollama server
+// at host and writes it to outputPath.
+func generateOllama(ctx context.Context, prompt, host, model, outputPath string) error {
+	body, err := json.Marshal(ollamaRequest{Model: model, Prompt: prompt, Stream: true})
+	if err != nil {
+		return err
+	}
+	url := strings.TrimRight(host, "/") + "/api/generate"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("invalid ollama host %q: %w", host, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := OllamaClient.Do(req)
+	if err != nil {
+		return ollamaError(host, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var chunk ollamaChunk
+		data, _ := io.ReadAll(resp.Body)
+		if json.Unmarshal(data, &chunk) != nil || chunk.Error == "" {
+			chunk.Error = strings.TrimSpace(string(data))
+		}
+		if resp.StatusCode == http.StatusNotFound {
+			return Permanent(fmt.Errorf("ollama model %q not found at %s (run: ollama pull %s)", model, host, model))
+		}
+		return fmt.Errorf("ollama returned %s: %s", resp.Status, chunk.Error)
+	}
+
+	var text strings.Builder
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var chunk ollamaChunk
+		if err := dec.Decode(&chunk); err == io.EOF {
+			break
+		} else if err != nil {
+			return ollamaError(host, err)
+		}
+		if chunk.Error != "" {
+			return fmt.Errorf("ollama: %s", chunk.Error)
+		}
+		text.WriteString(chunk.Response)
+		if chunk.Done {
+			break
+		}
+	}
+
+	html := ExtractHTML(text.String())
+	if html == "" {
+		return fmt.Errorf("ollama response did not contain an HTML document")
+	}
+	if err := os.WriteFile(outputPath, []byte(html+"\n"), 0o644); err != nil {
+		return fmt.Errorf("writing HTML invoice: %w", err)
+	}
+	return nil
+}
+
+// ollamaError describes a failed request to the ollama server at host.
+func ollamaError(host string, err error) error {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return fmt.Errorf("cannot connect to ollama at %s (is `ollama serve` running?): %w", host, err)
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return fmt.Errorf("ollama at %s timed out: %w", host, err)
+	}
+	return fmt.Errorf("calling ollama at %s: %w", host, err)
+}
+
+// BuildOllamaPrompt creates the prompt for backends that return the HTML in
+// their response instead of writing a file.
+func BuildOllamaPrompt(inv *Invoice) string {
+	var sb strings.Builder
+
+	sb.WriteString(intro(inv))
+	sb.WriteString("Respond with only the complete HTML document (with embedded CSS), starting with <!DOCTYPE html>.\n\n")
+	writeInvoiceDetails(&sb, inv)
+	sb.WriteString("- Do not include any explanation before or after the HTML\n")
+
+	return sb.String()
+}
+
+// codeFence matches a fenced code block, capturing its contents.
+var codeFence = regexp.MustCompile("(?s)```[a-zA-Z]*\\s*\\n(.*?)```")
+
+// ExtractHTML returns the HTML document in a model response, unwrapping a
+// Markdown code fence and dropping text around the document.
+// It returns "" if the response contains no HTML document.
+func ExtractHTML(response string) string {
+	if m := codeFence.FindStringSubmatch(response); m != nil {
+		response = m[1]
+	}
+	lower := strings.ToLower(response)
+	start := strings.Index(lower, "<!doctype html")
+	if start < 0 {
+		start = strings.Index(lower, "<html")
+	}
+	end := strings.LastIndex(lower, "</html>")
+	if start < 0 || end < start {
+		return ""
+	}
+	return response[start : end+len("</html>")]
+}
@@ -0,0 +1,170 @@
+package invoice_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+// generateOllama runs the ollama generator against host.
+func generateOllama(host, model, outputPath string) error {
+	// Left unformatted, the invoice is what the model wrote.
+	opts := invoice.GenerateOptions{OllamaHost: host, OllamaModel: model, NoFormat: true}
+	_, err := invoice.Generate(context.Background(), "ollama", testInvoice(), outputPath, opts)
+	return err
+}
+
+const testHTML = "<!DOCTYPE html>\n<html><body>Invoice</body></html>"
+
+// ollamaServer starts a server that streams the given response text from
+// /api/generate in chunks of a few characters, recording the request.
+func ollamaServer(t *testing.T, response string, got *map[string]any) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/generate" {
+			http.NotFound(w, r)
+			return
+		}
+		if got != nil {
+			json.NewDecoder(r.Body).Decode(got)
+		}
+		enc := json.NewEncoder(w)
+		for len(response) > 0 {
+			n := min(7, len(response))
+			enc.Encode(map[string]any{"response": response[:n], "done": false})
+			w.(http.Flusher).Flush()
+			response = response[n:]
+		}
+		enc.Encode(map[string]any{"response": "", "done": true})
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestOllamaGenerator_WritesStreamedHTML(t *testing.T) {
+	var req map[string]any
+	srv := ollamaServer(t, testHTML, &req)
+	outputPath := filepath.Join(t.TempDir(), "invoice.html")
+
+	if err := generateOllama(srv.URL, "llama3.2", outputPath); err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	data, err := os.ReadFile(outputPath)
+	if err != nil {
+		t.Fatalf("reading output: %v", err)
+	}
+	if strings.TrimSpace(string(data)) != testHTML {
+		t.Errorf("output = %q, want %q", data, testHTML)
+	}
+	if req["model"] != "llama3.2" || req["stream"] != true {
+		t.Errorf("unexpected request: %v", req)
+	}
+	if prompt, _ := req["prompt"].(string); !strings.Contains(prompt, "Acme Corp") {
+		t.Errorf("prompt does not contain customer, got: %s", prompt)
+	}
+}
+
+func TestOllamaGenerator_UnwrapsCodeFence(t *testing.T) {
+	srv := ollamaServer(t, "Here is your invoice:\n\n```html\n"+testHTML+"\n```\n\nLet me know!", nil)
+	outputPath := filepath.Join(t.TempDir(), "invoice.html")
+
+	if err := generateOllama(srv.URL, "llama3.2", outputPath); err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	data, _ := os.ReadFile(outputPath)
+	if strings.TrimSpace(string(data)) != testHTML {
+		t.Errorf("output = %q, want %q", data, testHTML)
+	}
+}
+
+func TestOllamaGenerator_NoHTML(t *testing.T) {
+	srv := ollamaServer(t, "Sorry, I can't help with that.", nil)
+	outputPath := filepath.Join(t.TempDir(), "invoice.html")
+
+	err := generateOllama(srv.URL, "llama3.2", outputPath)
+	if err == nil || !strings.Contains(err.Error(), "did not contain an HTML document") {
+		t.Errorf("expected missing HTML error, got %v", err)
+	}
+	if _, err := os.Stat(outputPath); err == nil {
+		t.Error("no file should be written without HTML")
+	}
+}
+
+func TestOllamaGenerator_ModelNotFound(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error":"model \"nope\" not found, try pulling it first"}`))
+	}))
+	defer srv.Close()
+
+	err := generateOllama(srv.URL, "nope", filepath.Join(t.TempDir(), "invoice.html"))
+	if err == nil || !strings.Contains(err.Error(), "ollama pull nope") {
+		t.Errorf("expected model-not-found error suggesting a pull, got %v", err)
+	}
+}
+
+func TestOllamaGenerator_ConnectionRefused(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	host := srv.URL
+	srv.Close()
+
+	err := generateOllama(host, "llama3.2", filepath.Join(t.TempDir(), "invoice.html"))
+	if err == nil || !strings.Contains(err.Error(), "cannot connect to ollama") {
+		t.Errorf("expected connection error, got %v", err)
+	}
+}
+
+func TestOllamaGenerator_Timeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer srv.Close()
+
+	origClient := invoice.OllamaClient
+	defer func() { invoice.OllamaClient = origClient }()
+	invoice.OllamaClient = &http.Client{Timeout: 20 * time.Millisecond}
+
+	err := generateOllama(srv.URL, "llama3.2", filepath.Join(t.TempDir(), "invoice.html"))
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("expected timeout error, got %v", err)
+	}
+}
+
+func TestExtractHTML(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"bare", testHTML, testHTML},
+		{"surrounding text", "Sure!\n" + testHTML + "\nEnjoy.", testHTML},
+		{"fenced", "```html\n" + testHTML + "\n```", testHTML},
+		{"unlabelled fence", "```\n" + testHTML + "\n```", testHTML},
+		{"no doctype", "<html><body>x</body></html>", "<html><body>x</body></html>"},
+		{"no html", "nothing here", ""},
+	}
+	for _, tt := range tests {
+		if got := invoice.ExtractHTML(tt.in); got != tt.want {
+			t.Errorf("%s: ExtractHTML = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestBuildOllamaPrompt_AsksForHTMLInResponse(t *testing.T) {
+	prompt := invoice.BuildOllamaPrompt(testInvoice())
+	if strings.Contains(strings.ToLower(prompt), "write tool") {
+		t.Errorf("ollama prompt should not mention a write tool, got: %s", prompt)
+	}
+	if !strings.Contains(prompt, "Respond with only the complete HTML document") {
+		t.Errorf("ollama prompt should ask for the HTML in the response, got: %s", prompt)
+	}
+	if !strings.Contains(prompt, "Jane Contractor") {
+		t.Errorf("ollama prompt should include invoice details, got: %s", prompt)
+	}
+}
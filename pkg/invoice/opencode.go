@@ -0,0 +1,552 @@
+package invoice
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+func init() {
+	Register("opencode", opencodeGenerator{})
+}
+
+// OpencodeRun describes a single opencode run.
+type OpencodeRun struct {
+	Model  string
+	Dir    string
+	Prompt string
+
+	// Agent, if set, is the opencode agent to use instead of the default.
+	Agent string
+
+	// Session, if set, is the opencode session to continue.
+	Session string
+
+	// DeniedTools are opencode tools that are switched off for the run.
+	DeniedTools []string
+}
+
+// Args returns the opencode command-line arguments for the run.
+func (r OpencodeRun) Args() []string {
+	args := []string{"run",
+		"--model", r.Model,
+		"--format", "json",
+		"--dir", r.Dir,
+	}
+	if r.Agent != "" {
+		args = append(args, "--agent", r.Agent)
+	}
+	if r.Session != "" {
+		args = append(args, "--session", r.Session)
+	}
+	return append(args, r.Prompt)
+}
+
+// DefaultDeniedTools are the tools invoicer switches off unless told
+// otherwise: the invoice only needs the file tools, not a shell or the network.
+var DefaultDeniedTools = []string{"bash", "webfetch", "websearch"}
+
+// allowedPermissions are the opencode permissions a restricted run keeps:
+// the file tools that write the invoice. Every other permission is denied,
+// so a tool missing from DeniedTools, or added to opencode later, is not
+// let through.
+var allowedPermissions = []string{"edit", "write"}
+
+// Env returns the environment variables to add for the run. opencode reads
+// extra config from OPENCODE_CONFIG_CONTENT, which is used to switch off
+// DeniedTools and to deny every permission but allowedPermissions.
+func (r OpencodeRun) Env() []string {
+	if len(r.DeniedTools) == 0 {
+		return nil
+	}
+	tools := make(map[string]bool, len(r.DeniedTools))
+	for _, tool := range r.DeniedTools {
+		tools[tool] = false
+	}
+	permission := map[string]string{"*": "deny"}
+	for _, p := range allowedPermissions {
+		permission[p] = "allow"
+	}
+	content, _ := json.Marshal(map[string]any{"permission": permission, "tools": tools})
+	return []string{"OPENCODE_CONFIG_CONTENT=" + string(content)}
+}
+
+// OpencodeExec is the function used to run the opencode subprocess. It
+// writes opencode's JSON event stream to stdout as the events arrive.
+// It can be overridden in tests to use a fake binary.
+var OpencodeExec = func(ctx context.Context, r OpencodeRun, stdout io.Writer) error {
+	cmd := command(ctx, "opencode", r.Args()...)
+	if env := r.Env(); env != nil {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	cmd.Stdout = stdout
+	return run(cmd)
+}
+
+// opencodeGenerator prompts opencode to write the HTML invoice with its write tool.
+type opencodeGenerator struct{}
+
+func (opencodeGenerator) Generate(ctx context.Context, inv *Invoice, outputPath string, opts GenerateOptions) (*Result, error) {
+	prompt := BuildPrompt(inv, outputPath)
+	opts.progress(ProgressEvent{Kind: ProgressPromptBuilt})
+
+	// Events are checked as opencode emits them rather than after it exits,
+	// so a run that uses a denied tool is stopped straight away. The check
+	// is made first so it records the file before opencode can touch it.
+	check := NewOpencodeCheck(outputPath, opts.DeniedTools...)
+	runCtx, stop := context.WithCancel(ctx)
+	defer stop()
+	pr, pw := io.Pipe()
+	done := make(chan error, 1)
+	opts.progress(ProgressEvent{Kind: ProgressStarted})
+	go func() {
+		err := OpencodeExec(runCtx, OpencodeRun{
+			Model:       opts.Model,
+			Dir:         filepath.Dir(outputPath),
+			Prompt:      prompt,
+			Agent:       opts.Agent,
+			Session:     opts.Session,
+			DeniedTools: opts.DeniedTools,
+		}, pw)
+		pw.Close()
+		done <- err
+	}()
+
+	session := opts.Session
+	events, scanErr := ScanOpencodeEvents(pr)
+	for event := range events {
+		check.Event(event)
+		if check.Denied() != nil || check.Fatal() != nil {
+			stop()
+		}
+		if session == "" {
+			session = event.SessionID
+		}
+		if usage, ok := event.Usage(); ok && opts.OnUsage != nil {
+			opts.OnUsage(usage)
+		}
+		if tool, path, ok := toolUsed(event, filepath.Dir(outputPath)); ok {
+			opts.progress(ProgressEvent{Kind: ProgressToolUse, Tool: tool, Path: path})
+		}
+	}
+	err := <-done
+	if denied := check.Denied(); denied != nil {
+		return nil, Permanent(denied)
+	}
+	if fatal := check.Fatal(); fatal != nil && ctx.Err() == nil {
+		return nil, fatal
+	}
+	if err := scanErr(); err != nil {
+		return nil, fmt.Errorf("reading opencode's events: %w", err)
+	}
+	if errors.Is(err, exec.ErrNotFound) {
+		return nil, backendNotInstalled("opencode",
+			"opencode is the coding agent that writes the invoice HTML (https://opencode.ai).",
+			"curl -fsSL https://opencode.ai/install | bash",
+			"npm install -g opencode-ai")
+	} else if err != nil {
+		err = fmt.Errorf("running opencode: %w", err)
+		// opencode sometimes fails after the invoice is written; unless
+		// StrictExit is set, a good invoice counts with a warning.
+		if opts.StrictExit || ctx.Err() != nil || check.Err() != nil {
+			return nil, err
+		}
+		if !opts.SkipVerify && VerifyHTML(outputPath) != nil {
+			return nil, err
+		}
+		if opts.OnWarning != nil {
+			opts.OnWarning(fmt.Errorf("opencode wrote the invoice but then failed: %w", err))
+		}
+	}
+
+	if err := check.Err(); err != nil {
+		return nil, err
+	}
+	if opts.OnWarning != nil {
+		for _, w := range check.Warnings() {
+			opts.OnWarning(w)
+		}
+	}
+
+	return &Result{Path: outputPath, Model: opts.Model, SessionID: session}, nil
+}
+
+// OpencodeEvent is a single JSON event line from opencode --format json.
+type OpencodeEvent struct {
+	Type      string          `json:"type"`
+	SessionID string          `json:"sessionID"`
+	Part      json.RawMessage `json:"part"`
+	Error     json.RawMessage `json:"error"`
+}
+
+// textPart is the part of a text event, some of what the model said.
+type textPart struct {
+	Text string `json:"text"`
+}
+
+// opencodeError is the error of an error event. Provider errors put the
+// message under data; others carry it directly.
+type opencodeError struct {
+	Name    string `json:"name"`
+	Message string `json:"message"`
+	Data    struct {
+		Message string `json:"message"`
+	} `json:"data"`
+}
+
+// toolPart is the part of a tool_use event.
+type toolPart struct {
+	Tool  string    `json:"tool"`
+	State toolState `json:"state"`
+}
+
+type toolState struct {
+	Status string          `json:"status"`
+	Input  json.RawMessage `json:"input"`
+	Output string          `json:"output"`
+	Error  string          `json:"error"`
+}
+
+// fileToolInput is the input of the tools that change files. write, edit and
+// multiedit name the file in filePath; patch names its files in the patch text;
+// bash may change any file its command names.
+type fileToolInput struct {
+	FilePath  string `json:"filePath"`
+	PatchText string `json:"patchText"`
+	Command   string `json:"command"`
+}
+
+// changedFiles returns the files a tool_use event's tool writes to, or nil
+// for tools that do not change files. Relative paths in a patch are resolved
+// against dir, the directory opencode runs in.
+func changedFiles(part toolPart, dir string) []string {
+	var input fileToolInput
+	if err := json.Unmarshal(part.State.Input, &input); err != nil {
+		return nil
+	}
+	switch part.Tool {
+	case "write", "edit", "multiedit":
+		return []string{input.FilePath}
+	case "patch":
+		var files []string
+		for _, line := range strings.Split(input.PatchText, "\n") {
+			for _, prefix := range []string{"*** Add File: ", "*** Update File: ", "*** Move to: "} {
+				if name, ok := strings.CutPrefix(strings.TrimSpace(line), prefix); ok {
+					if !filepath.IsAbs(name) {
+						name = filepath.Join(dir, name)
+					}
+					files = append(files, name)
+				}
+			}
+		}
+		return files
+	}
+	return nil
+}
+
+// shellNames reports whether command, run by the bash tool in dir, names
+// path: as it is, or as a word that is path relative to dir, such as the
+// target of "cat <<EOF > invoice.html".
+func shellNames(command, path, dir string) bool {
+	if strings.Contains(command, path) {
+		return true
+	}
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	words := strings.FieldsFunc(command, func(r rune) bool {
+		return unicode.IsSpace(r) || strings.ContainsRune("<>|;&()'\"`", r)
+	})
+	for _, w := range words {
+		if filepath.Clean(w) == rel {
+			return true
+		}
+	}
+	return false
+}
+
+// toolUsed returns the tool a completed tool_use event used and the file
+// it changed or read, if it names one, with relative paths resolved
+// against dir.
+func toolUsed(event OpencodeEvent, dir string) (tool, path string, ok bool) {
+	if event.Type != "tool_use" {
+		return "", "", false
+	}
+	var part toolPart
+	if err := json.Unmarshal(event.Part, &part); err != nil || part.State.Status != "completed" {
+		return "", "", false
+	}
+	if files := changedFiles(part, dir); len(files) > 0 {
+		return part.Tool, files[0], true
+	}
+	var input fileToolInput
+	if json.Unmarshal(part.State.Input, &input) == nil && input.FilePath != "" {
+		path = input.FilePath
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+	}
+	return part.Tool, path, true
+}
+
+// MaxEventSize is the longest opencode event line that is parsed. A write
+// event carries the whole HTML document, so this is generous; a longer line
+// ends the scan, the rest of the stream is discarded, and the scan fails
+// with bufio.ErrTooLong.
+const MaxEventSize = 16 << 20
+
+// ScanOpencodeEvents parses opencode's JSON event stream from r one line at a
+// time and sends each event on the returned channel as soon as it is read.
+// Lines that are not JSON are skipped. The channel is closed once r is
+// exhausted, and r is always read to the end so the writer never blocks.
+// Once the channel is closed, the returned function reports the error that
+// ended the scan early, e.g. a line longer than MaxEventSize, or nil.
+func ScanOpencodeEvents(r io.Reader) (<-chan OpencodeEvent, func() error) {
+	events := make(chan OpencodeEvent)
+	var scanErr error
+	go func() {
+		defer close(events)
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 0, 64<<10), MaxEventSize)
+		for scanner.Scan() {
+			var event OpencodeEvent
+			if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+				continue
+			}
+			events <- event
+		}
+		scanErr = scanner.Err()
+		_, _ = io.Copy(io.Discard, r)
+	}()
+	return events, func() error { return scanErr }
+}
+
+// OpencodeCheck follows an opencode event stream, one event at a time, to
+// tell whether opencode wrote the invoice and, if not, why.
+type OpencodeCheck struct {
+	path     string
+	before   FileState
+	deny     []string
+	denied   []string
+	warnings []error
+	wrote    bool
+	errors   []string
+	failed   bool
+	text     string
+}
+
+// NewOpencodeCheck returns an OpencodeCheck for an invoice written to
+// expectedPath. It records the file as it is now, so it must be made
+// before opencode runs. Any use of one of deniedTools fails the check.
+func NewOpencodeCheck(expectedPath string, deniedTools ...string) *OpencodeCheck {
+	return &OpencodeCheck{path: expectedPath, before: StatFile(expectedPath), deny: deniedTools}
+}
+
+// Event processes one event and reports whether a completed write, edit or
+// patch of the invoice has been seen, which is known from that event on. A
+// completed bash command that names the invoice counts too if the file has
+// changed since the check was made, as when the model writes it with
+// "cat <<EOF > invoice.html"; with bash denied, it still counts, and is
+// named in Warnings rather than Denied.
+func (c *OpencodeCheck) Event(event OpencodeEvent) bool {
+	if event.Type == "tool_use" && len(c.deny) > 0 {
+		var part toolPart
+		if err := json.Unmarshal(event.Part, &part); err == nil && slices.Contains(c.deny, part.Tool) {
+			use := describeToolUse(part)
+			if c.shellWrote(part) {
+				c.warnings = append(c.warnings, fmt.Errorf("opencode wrote the invoice with a denied tool instead of the write tool: %s", use))
+				c.wrote = true
+			} else {
+				c.denied = append(c.denied, use)
+			}
+		}
+	}
+	if c.wrote {
+		return true
+	}
+	switch event.Type {
+	case "error":
+		c.failed = true
+		if msg := opencodeErrorMessage(event.Error); msg != "" {
+			c.errors = append(c.errors, msg)
+		}
+	case "text":
+		var part textPart
+		if err := json.Unmarshal(event.Part, &part); err == nil && strings.TrimSpace(part.Text) != "" {
+			c.text = strings.TrimSpace(part.Text)
+		}
+	case "tool_use":
+		var part toolPart
+		if err := json.Unmarshal(event.Part, &part); err != nil {
+			return false
+		}
+		if part.State.Status == "error" && part.State.Error != "" {
+			c.errors = append(c.errors, fmt.Sprintf("%s tool failed: %s", part.Tool, part.State.Error))
+		}
+		if part.State.Status != "completed" {
+			return false
+		}
+		// Check if this write, edit or patch was to our expected output path.
+		for _, file := range changedFiles(part, filepath.Dir(c.path)) {
+			c.wrote = c.wrote || file == c.path
+		}
+		c.wrote = c.wrote || c.shellWrote(part)
+	}
+	return c.wrote
+}
+
+// shellWrote reports whether part is a completed bash command that names
+// the invoice, which has changed since the check was made: a file that was
+// only read, or written before the run, is not attributed to the command.
+func (c *OpencodeCheck) shellWrote(part toolPart) bool {
+	if part.Tool != "bash" || part.State.Status != "completed" {
+		return false
+	}
+	var input fileToolInput
+	if err := json.Unmarshal(part.State.Input, &input); err != nil || !shellNames(input.Command, c.path, filepath.Dir(c.path)) {
+		return false
+	}
+	now := StatFile(c.path)
+	return now.Exists && !now.Same(c.before)
+}
+
+// opencodeErrorMessage returns the message of an error event's error, which
+// may be an object or a plain string.
+func opencodeErrorMessage(raw json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	var e opencodeError
+	if err := json.Unmarshal(raw, &e); err != nil {
+		return ""
+	}
+	switch {
+	case e.Data.Message != "":
+		return e.Data.Message
+	case e.Message != "":
+		return e.Message
+	}
+	return e.Name
+}
+
+// describeToolUse names a tool and what it was asked to do, e.g.
+// `bash: curl https://example.com`.
+func describeToolUse(part toolPart) string {
+	var input map[string]any
+	if err := json.Unmarshal(part.State.Input, &input); err == nil {
+		for _, key := range []string{"command", "url", "query", "filePath", "pattern"} {
+			if v, ok := input[key].(string); ok && v != "" {
+				return part.Tool + ": " + v
+			}
+		}
+	}
+	if len(part.State.Input) == 0 {
+		return part.Tool
+	}
+	return part.Tool + ": " + truncate(string(part.State.Input), maxReplyLen)
+}
+
+// Denied returns an error describing the uses of denied tools seen so far,
+// or nil if there were none.
+func (c *OpencodeCheck) Denied() error {
+	if len(c.denied) == 0 {
+		return nil
+	}
+	return fmt.Errorf("opencode used a denied tool: %s", strings.Join(c.denied, "; "))
+}
+
+// Warnings returns the policy problems seen so far that did not fail the
+// check: an invoice written by a denied shell command.
+func (c *OpencodeCheck) Warnings() []error {
+	return c.warnings
+}
+
+// maxReplyLen bounds how much of the model's reply is quoted in an error.
+const maxReplyLen = 500
+
+// Fatal returns an error with what opencode reported if an error event has
+// been seen before the invoice was written, or nil. opencode gives up on
+// the session after one, so there is no need to wait for it to exit.
+func (c *OpencodeCheck) Fatal() error {
+	if !c.failed || c.wrote {
+		return nil
+	}
+	return c.notWritten()
+}
+
+// Err returns the Denied error if a denied tool was used. Otherwise it
+// returns nil if the invoice write was seen or, failing that, the file has
+// changed since the check was made, and if not, an error that includes what
+// opencode reported or, if it reported nothing, the last thing the model
+// said. A file that was already there and is unchanged does not count.
+func (c *OpencodeCheck) Err() error {
+	if err := c.Denied(); err != nil {
+		return err
+	}
+	if c.wrote {
+		return nil
+	}
+	now := StatFile(c.path)
+	if now.Exists && !now.Same(c.before) {
+		return nil
+	}
+	err := c.notWritten()
+	if now.Exists {
+		return fmt.Errorf("%w; the file already there predates this run", err)
+	}
+	return err
+}
+
+// notWritten explains why the invoice was not written.
+func (c *OpencodeCheck) notWritten() error {
+	if len(c.errors) > 0 {
+		return fmt.Errorf("opencode reported: %s", strings.Join(c.errors, "; "))
+	}
+	if c.text != "" {
+		return fmt.Errorf("opencode did not write the HTML invoice to %s; the model replied: %s", c.path, truncate(c.text, maxReplyLen))
+	}
+	return fmt.Errorf("opencode did not write the HTML invoice to %s", c.path)
+}
+
+// truncate shortens s to at most n runes, marking the cut.
+func truncate(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n]) + "..."
+	}
+	return s
+}
+
+// CheckOpencodeOutput parses the JSON lines from opencode and verifies the
+// file was written. Without a record of the file from before the run, a
+// file at expectedPath counts as written; use CheckOpencodeOutputSince when
+// one may predate the run.
+func CheckOpencodeOutput(out []byte, expectedPath string) error {
+	return CheckOpencodeOutputSince(out, expectedPath, FileState{})
+}
+
+// CheckOpencodeOutputSince is like CheckOpencodeOutput, but a file that is
+// still as before, taken with StatFile ahead of the run, does not count.
+func CheckOpencodeOutputSince(out []byte, expectedPath string, before FileState) error {
+	check := NewOpencodeCheck(expectedPath)
+	check.before = before
+	events, scanErr := ScanOpencodeEvents(bytes.NewReader(out))
+	for event := range events {
+		check.Event(event)
+	}
+	if err := scanErr(); err != nil {
+		return fmt.Errorf("reading opencode's events: %w", err)
+	}
+	return check.Err()
+}
@@ -0,0 +1,627 @@
+package invoice_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+// --- CheckOpencodeOutput tests ---
+
+// makeToolUseEvent creates a JSON line representing an opencode tool_use event.
+func makeToolUseEvent(tool, filePath, status string) string {
+	type writeInput struct {
+		FilePath string `json:"filePath"`
+	}
+	input, _ := json.Marshal(writeInput{FilePath: filePath})
+	type toolState struct {
+		Status string          `json:"status"`
+		Input  json.RawMessage `json:"input"`
+		Output string          `json:"output"`
+	}
+	type toolPart struct {
+		Tool  string    `json:"tool"`
+		State toolState `json:"state"`
+	}
+	type event struct {
+		Type string          `json:"type"`
+		Part json.RawMessage `json:"part"`
+	}
+	partJSON, _ := json.Marshal(toolPart{
+		Tool: tool,
+		State: toolState{
+			Status: status,
+			Input:  input,
+		},
+	})
+	ev, _ := json.Marshal(event{Type: "tool_use", Part: partJSON})
+	return string(ev)
+}
+
+func TestCheckOpencodeOutput_SuccessWithWriteEvent(t *testing.T) {
+	expectedPath := "/tmp/invoice.html"
+	line := makeToolUseEvent("write", expectedPath, "completed")
+	err := invoice.CheckOpencodeOutput([]byte(line), expectedPath)
+	if err != nil {
+		t.Errorf("expected success, got error: %v", err)
+	}
+}
+
+func TestCheckOpencodeOutput_FailsWhenWrongPath(t *testing.T) {
+	line := makeToolUseEvent("write", "/tmp/other.html", "completed")
+	err := invoice.CheckOpencodeOutput([]byte(line), "/tmp/invoice.html")
+	// No file on disk either, so should fail.
+	if err == nil {
+		t.Error("expected error for wrong path, got nil")
+	}
+}
+
+func TestCheckOpencodeOutput_FailsWhenStatusNotCompleted(t *testing.T) {
+	expectedPath := "/tmp/invoice.html"
+	line := makeToolUseEvent("write", expectedPath, "error")
+	err := invoice.CheckOpencodeOutput([]byte(line), expectedPath)
+	// No file on disk, so should fail.
+	if err == nil {
+		t.Error("expected error for non-completed status, got nil")
+	}
+}
+
+func TestCheckOpencodeOutput_FallbackToFileExistence(t *testing.T) {
+	// Create a temp file to simulate the output file existing on disk.
+	tmpFile, err := os.CreateTemp("", "invoice-*.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmpFile.Name())
+	tmpFile.Close()
+
+	// No write event in output, but file exists on disk — should succeed.
+	err = invoice.CheckOpencodeOutput([]byte(""), tmpFile.Name())
+	if err != nil {
+		t.Errorf("expected success via file existence fallback, got: %v", err)
+	}
+}
+
+func TestCheckOpencodeOutput_FailsWhenNoEventAndNoFile(t *testing.T) {
+	err := invoice.CheckOpencodeOutput([]byte(""), "/tmp/nonexistent-invoice-xyz.html")
+	if err == nil {
+		t.Error("expected error when no event and no file, got nil")
+	}
+}
+
+func TestCheckOpencodeOutput_IgnoresNonWriteTools(t *testing.T) {
+	expectedPath := "/tmp/invoice.html"
+	// A tool_use event for a different tool (e.g. "bash") should be ignored.
+	line := makeToolUseEvent("bash", expectedPath, "completed")
+	err := invoice.CheckOpencodeOutput([]byte(line), expectedPath)
+	// No file on disk, so should fail.
+	if err == nil {
+		t.Error("expected error when only non-write tool events present, got nil")
+	}
+}
+
+func TestCheckOpencodeOutput_IgnoresInvalidJSON(t *testing.T) {
+	expectedPath := "/tmp/invoice.html"
+	// Mix of invalid JSON lines and a valid write event.
+	validLine := makeToolUseEvent("write", expectedPath, "completed")
+	output := fmt.Sprintf("not json\n%s\n{invalid}", validLine)
+	err := invoice.CheckOpencodeOutput([]byte(output), expectedPath)
+	if err != nil {
+		t.Errorf("expected success despite invalid JSON lines, got: %v", err)
+	}
+}
+
+// --- ScanOpencodeEvents tests ---
+
+func TestScanOpencodeEvents_DetectsWriteBeforeStreamEnds(t *testing.T) {
+	expectedPath := "/tmp/nonexistent-invoice-xyz.html"
+	pr, pw := io.Pipe()
+	defer pw.Close()
+	events, _ := invoice.ScanOpencodeEvents(pr)
+	check := invoice.NewOpencodeCheck(expectedPath)
+
+	// A slow writer: the stream stays open after the write event.
+	go io.WriteString(pw, `{"type":"step_start","part":{}}`+"\n"+makeToolUseEvent("write", expectedPath, "completed")+"\n")
+	for i := 0; i < 2; i++ {
+		select {
+		case event := <-events:
+			if check.Event(event) != (i == 1) {
+				t.Fatalf("event %d (%s): unexpected write detection", i, event.Type)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("event was not delivered while the stream was still open")
+		}
+	}
+	if err := check.Err(); err != nil {
+		t.Errorf("Err() = %v after the write event", err)
+	}
+}
+
+// repeatReader yields line n times without holding the stream in memory.
+type repeatReader struct {
+	line []byte
+	n    int
+	off  int
+}
+
+func (r *repeatReader) Read(p []byte) (int, error) {
+	if r.n == 0 {
+		return 0, io.EOF
+	}
+	n := copy(p, r.line[r.off:])
+	r.off += n
+	if r.off == len(r.line) {
+		r.off = 0
+		r.n--
+	}
+	return n, nil
+}
+
+func TestScanOpencodeEvents_LongStream(t *testing.T) {
+	expectedPath := "/tmp/nonexistent-invoice-xyz.html"
+	filler := &repeatReader{line: []byte(`{"type":"text","part":{"text":"` + strings.Repeat("x", 1000) + `"}}` + "\n"), n: 50000}
+	r := io.MultiReader(filler, strings.NewReader(makeToolUseEvent("write", expectedPath, "completed")+"\n"))
+
+	check := invoice.NewOpencodeCheck(expectedPath)
+	count := 0
+	events, scanErr := invoice.ScanOpencodeEvents(r)
+	for event := range events {
+		count++
+		check.Event(event)
+	}
+	if err := scanErr(); err != nil {
+		t.Errorf("scan: %v", err)
+	}
+	if count != 50001 {
+		t.Errorf("got %d events, want 50001", count)
+	}
+	if err := check.Err(); err != nil {
+		t.Errorf("write at the end of a long stream was missed: %v", err)
+	}
+}
+
+func TestScanOpencodeEvents_DrainsOversizedLine(t *testing.T) {
+	huge := &repeatReader{line: []byte(strings.Repeat("x", 64<<10)), n: invoice.MaxEventSize/(64<<10) + 1}
+	r := io.MultiReader(strings.NewReader(`{"type":"step_start"}`+"\n"), huge, strings.NewReader("\n"+`{"type":"step_finish"}`+"\n"))
+
+	var types []string
+	events, scanErr := invoice.ScanOpencodeEvents(r)
+	for event := range events {
+		types = append(types, event.Type)
+	}
+	if err := scanErr(); !errors.Is(err, bufio.ErrTooLong) {
+		t.Errorf("scan error = %v, want %v", err, bufio.ErrTooLong)
+	}
+	if len(types) != 1 || types[0] != "step_start" {
+		t.Errorf("events = %v, want only the one before the oversized line", types)
+	}
+	if huge.n != 0 {
+		t.Errorf("the stream was not drained, %d chunks left", huge.n)
+	}
+}
+
+// --- error and reply reporting tests ---
+
+// fixturePath is the invoice path the testdata streams refer to; it never exists.
+const fixturePath = "/tmp/invoicer-fixture/invoice.html"
+
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestCheckOpencodeOutput_ReportsErrorEvent(t *testing.T) {
+	err := invoice.CheckOpencodeOutput(readFixture(t, "opencode-error.jsonl"), fixturePath)
+	if err == nil || err.Error() != "opencode reported: rate limit exceeded for anthropic/claude-haiku-4-5" {
+		t.Errorf("got %v", err)
+	}
+}
+
+func TestCheckOpencodeOutput_ReportsModelReply(t *testing.T) {
+	err := invoice.CheckOpencodeOutput(readFixture(t, "opencode-refusal.jsonl"), fixturePath)
+	if err == nil || !strings.Contains(err.Error(), "the model replied: I'm sorry, but I can't write files outside the project directory.") {
+		t.Errorf("got %v", err)
+	}
+}
+
+func TestCheckOpencodeOutput_MixedStream(t *testing.T) {
+	out := readFixture(t, "opencode-mixed.jsonl")
+
+	// The failed tool is reported ahead of what the model said.
+	err := invoice.CheckOpencodeOutput(out, fixturePath)
+	if err == nil || !strings.Contains(err.Error(), "opencode reported: read tool failed: File not found") {
+		t.Errorf("got %v", err)
+	}
+
+	// Earlier errors do not matter once the invoice itself is written.
+	out = append(out, makeToolUseEvent("write", fixturePath, "completed")+"\n"...)
+	if err := invoice.CheckOpencodeOutput(out, fixturePath); err != nil {
+		t.Errorf("expected success after the write, got %v", err)
+	}
+}
+
+func TestCheckOpencodeOutput_TruncatesLongReply(t *testing.T) {
+	line := `{"type":"text","part":{"text":"` + strings.Repeat("é", 2000) + `"}}`
+	err := invoice.CheckOpencodeOutput([]byte(line), fixturePath)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if msg := err.Error(); !strings.HasSuffix(msg, "...") || len([]rune(msg)) > 700 {
+		t.Errorf("reply was not truncated: %d runes", len([]rune(msg)))
+	}
+}
+
+// --- edit and patch tool tests ---
+
+func TestCheckOpencodeOutput_AcceptsFileTools(t *testing.T) {
+	for _, name := range []string{"opencode-edit.jsonl", "opencode-multiedit.jsonl", "opencode-patch.jsonl"} {
+		t.Run(name, func(t *testing.T) {
+			if err := invoice.CheckOpencodeOutput(readFixture(t, name), fixturePath); err != nil {
+				t.Errorf("expected success, got %v", err)
+			}
+		})
+	}
+}
+
+func TestCheckOpencodeOutput_IgnoresEditsElsewhere(t *testing.T) {
+	// An edit to another file, a patch to a file with the same name in
+	// another directory, and a patch that deletes the invoice do not count.
+	err := invoice.CheckOpencodeOutput(readFixture(t, "opencode-edit-elsewhere.jsonl"), fixturePath)
+	if err == nil {
+		t.Error("expected error, got nil")
+	}
+}
+
+func TestCheckOpencodeOutput_IgnoresFailedEdit(t *testing.T) {
+	line := makeToolUseEvent("edit", fixturePath, "error")
+	if err := invoice.CheckOpencodeOutput([]byte(line), fixturePath); err == nil {
+		t.Error("expected error for a failed edit, got nil")
+	}
+}
+
+// --- agent and session tests ---
+
+func TestOpencodeRun_Args(t *testing.T) {
+	r := invoice.OpencodeRun{Model: "anthropic/claude-haiku-4-5", Dir: "/tmp/out", Prompt: "write it"}
+	want := []string{"run", "--model", "anthropic/claude-haiku-4-5", "--format", "json", "--dir", "/tmp/out", "write it"}
+	if got := r.Args(); !slices.Equal(got, want) {
+		t.Errorf("Args() = %q, want %q", got, want)
+	}
+
+	r.Agent, r.Session = "invoice-writer", "ses_01"
+	want = []string{"run", "--model", "anthropic/claude-haiku-4-5", "--format", "json", "--dir", "/tmp/out",
+		"--agent", "invoice-writer", "--session", "ses_01", "write it"}
+	if got := r.Args(); !slices.Equal(got, want) {
+		t.Errorf("Args() = %q, want %q", got, want)
+	}
+}
+
+func TestGenerate_PassesAgentAndSession(t *testing.T) {
+	var args []string
+	orig := invoice.OpencodeExec
+	t.Cleanup(func() { invoice.OpencodeExec = orig })
+	invoice.OpencodeExec = func(ctx context.Context, r invoice.OpencodeRun, stdout io.Writer) error {
+		args = r.Args()
+		return os.WriteFile(promptedPath(r.Prompt), []byte("<html>ok</html>"), 0o644)
+	}
+
+	opts := invoice.GenerateOptions{Model: "anthropic/claude-haiku-4-5", Agent: "invoice-writer", Session: "ses_01"}
+	res, err := invoice.Generate(context.Background(), "opencode", testInvoice(), filepath.Join(t.TempDir(), "invoice.html"), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	joined := strings.Join(args, " ")
+	if !strings.Contains(joined, "--agent invoice-writer --session ses_01") {
+		t.Errorf("opencode args %q are missing the agent and session", args)
+	}
+	if res.SessionID != "ses_01" {
+		t.Errorf("SessionID = %q, want the continued session", res.SessionID)
+	}
+}
+
+func TestGenerate_RecordsSessionID(t *testing.T) {
+	streamFixture(t, "opencode-usage.jsonl")
+	res, err := invoice.Generate(context.Background(), "opencode", testInvoice(), filepath.Join(t.TempDir(), "invoice.html"), invoice.GenerateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.SessionID != "ses_04" {
+		t.Errorf("SessionID = %q, want ses_04 from the events", res.SessionID)
+	}
+}
+
+// --- denied tool tests ---
+
+func TestOpencodeRun_Env(t *testing.T) {
+	if env := (invoice.OpencodeRun{}).Env(); env != nil {
+		t.Errorf("Env() = %q, want nothing without denied tools", env)
+	}
+	r := invoice.OpencodeRun{DeniedTools: invoice.DefaultDeniedTools}
+	env := r.Env()
+	content, ok := strings.CutPrefix(strings.Join(env, "\n"), "OPENCODE_CONFIG_CONTENT=")
+	if len(env) != 1 || !ok {
+		t.Fatalf("Env() = %q, want OPENCODE_CONFIG_CONTENT alone", env)
+	}
+	var cfg struct {
+		Permission map[string]string
+		Tools      map[string]bool
+	}
+	if err := json.Unmarshal([]byte(content), &cfg); err != nil {
+		t.Fatalf("OPENCODE_CONFIG_CONTENT: %v", err)
+	}
+	// Everything is denied but the tools that write the invoice.
+	wantPermission := map[string]string{"*": "deny", "edit": "allow", "write": "allow"}
+	if !maps.Equal(cfg.Permission, wantPermission) {
+		t.Errorf("permission = %v, want %v", cfg.Permission, wantPermission)
+	}
+	wantTools := map[string]bool{"bash": false, "webfetch": false, "websearch": false}
+	if !maps.Equal(cfg.Tools, wantTools) {
+		t.Errorf("tools = %v, want %v", cfg.Tools, wantTools)
+	}
+}
+
+func TestOpencodeCheck_DeniedTool(t *testing.T) {
+	check := invoice.NewOpencodeCheck(fixturePath, invoice.DefaultDeniedTools...)
+	events, _ := invoice.ScanOpencodeEvents(bytes.NewReader(readFixture(t, "opencode-bash.jsonl")))
+	for event := range events {
+		check.Event(event)
+	}
+	// The invoice was written, but the bash call still fails the run.
+	err := check.Err()
+	if err == nil || err.Error() != "opencode used a denied tool: bash: ls -la ~/invoices" {
+		t.Errorf("got %v", err)
+	}
+}
+
+func TestOpencodeCheck_WriteOnlyPasses(t *testing.T) {
+	check := invoice.NewOpencodeCheck(fixturePath, invoice.DefaultDeniedTools...)
+	events, _ := invoice.ScanOpencodeEvents(bytes.NewReader(readFixture(t, "opencode-write.jsonl")))
+	for event := range events {
+		check.Event(event)
+	}
+	if err := check.Err(); err != nil {
+		t.Errorf("expected success, got %v", err)
+	}
+}
+
+func TestGenerate_ErrorEventStopsRun(t *testing.T) {
+	orig := invoice.OpencodeExec
+	t.Cleanup(func() { invoice.OpencodeExec = orig })
+	invoice.OpencodeExec = func(ctx context.Context, r invoice.OpencodeRun, stdout io.Writer) error {
+		stdout.Write(readFixture(t, "opencode-error.jsonl"))
+		// Hang, as opencode might, until stopped.
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(5 * time.Second):
+			t.Error("opencode was not stopped after the error event")
+			return nil
+		}
+	}
+
+	opts := invoice.GenerateOptions{Model: "anthropic/claude-haiku-4-5"}
+	_, err := invoice.Generate(context.Background(), "opencode", testInvoice(), filepath.Join(t.TempDir(), "invoice.html"), opts)
+	if err == nil || err.Error() != "opencode reported: rate limit exceeded for anthropic/claude-haiku-4-5" {
+		t.Errorf("got %v", err)
+	}
+}
+
+func TestGenerate_OversizedEventFails(t *testing.T) {
+	orig := invoice.OpencodeExec
+	t.Cleanup(func() { invoice.OpencodeExec = orig })
+	invoice.OpencodeExec = func(ctx context.Context, r invoice.OpencodeRun, stdout io.Writer) error {
+		huge := &repeatReader{line: []byte(strings.Repeat("x", 64<<10)), n: invoice.MaxEventSize/(64<<10) + 1}
+		_, err := io.Copy(stdout, huge)
+		return err
+	}
+
+	opts := invoice.GenerateOptions{Model: "anthropic/claude-haiku-4-5"}
+	_, err := invoice.Generate(context.Background(), "opencode", testInvoice(), filepath.Join(t.TempDir(), "invoice.html"), opts)
+	if !errors.Is(err, bufio.ErrTooLong) || !strings.Contains(err.Error(), "reading opencode's events") {
+		t.Errorf("got %v, want the scan error", err)
+	}
+}
+
+func TestGenerate_DeniedToolStopsRun(t *testing.T) {
+	calls := 0
+	orig := invoice.OpencodeExec
+	t.Cleanup(func() { invoice.OpencodeExec = orig })
+	invoice.OpencodeExec = func(ctx context.Context, r invoice.OpencodeRun, stdout io.Writer) error {
+		calls++
+		if !slices.Equal(r.DeniedTools, []string{"bash"}) {
+			t.Errorf("DeniedTools = %q", r.DeniedTools)
+		}
+		io.WriteString(stdout, `{"type":"tool_use","part":{"tool":"bash","state":{"status":"running","input":{"command":"curl https://example.com"}}}}`+"\n")
+		// Keep running, as opencode would, until stopped.
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	opts := invoice.GenerateOptions{Model: "anthropic/claude-haiku-4-5", DeniedTools: []string{"bash"}, Retries: 2}
+	_, err := invoice.Generate(context.Background(), "opencode", testInvoice(), filepath.Join(t.TempDir(), "invoice.html"), opts)
+	if err == nil || err.Error() != "opencode used a denied tool: bash: curl https://example.com" {
+		t.Errorf("got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("opencode ran %d times; a denied tool should not be retried", calls)
+	}
+}
+
+// --- stale file tests ---
+
+func TestCheckOpencodeOutputSince_StaleFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "invoice.html")
+	if err := os.WriteFile(path, []byte("<html>last month</html>"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	before := invoice.StatFile(path)
+
+	err := invoice.CheckOpencodeOutputSince(readFixture(t, "opencode-refusal.jsonl"), path, before)
+	if err == nil || !strings.Contains(err.Error(), "predates this run") || !strings.Contains(err.Error(), "the model replied") {
+		t.Errorf("expected a stale-file error with the reply, got %v", err)
+	}
+}
+
+func TestOpencodeCheck_FileUpdatedDuringRun(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "invoice.html")
+	if err := os.WriteFile(path, []byte("<html>last month</html>"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	check := invoice.NewOpencodeCheck(path)
+
+	// The run rewrites the file without a write event being seen.
+	if err := os.WriteFile(path, []byte("<html>this month</html>"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := check.Err(); err != nil {
+		t.Errorf("expected the updated file to count, got %v", err)
+	}
+}
+
+func TestOpencodeCheck_FileAbsentBeforeAndAfter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "invoice.html")
+	check := invoice.NewOpencodeCheck(path)
+	err := check.Err()
+	if err == nil || strings.Contains(err.Error(), "predates") {
+		t.Errorf("expected a plain not-written error, got %v", err)
+	}
+}
+
+// --- bash write tests ---
+
+// scanBashFixture follows the events of the fixture name for an invoice
+// at path, writing content to it first, as a bash command would, unless
+// content is empty, and reports whether a write of the invoice was seen.
+func scanBashFixture(t *testing.T, check *invoice.OpencodeCheck, name, path, content string) bool {
+	t.Helper()
+	if content != "" {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	seen := false
+	events, _ := invoice.ScanOpencodeEvents(bytes.NewReader(readFixture(t, name)))
+	for event := range events {
+		seen = check.Event(event) || seen
+	}
+	return seen
+}
+
+func TestOpencodeCheck_BashHeredoc(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "invoice.html")
+	check := invoice.NewOpencodeCheck(path)
+	if !scanBashFixture(t, check, "opencode-bash-heredoc.jsonl", path, "<html>this month</html>") {
+		t.Error("a bash command that wrote the invoice should count as its write")
+	}
+	if err := check.Err(); err != nil {
+		t.Errorf("expected success, got %v", err)
+	}
+}
+
+func TestOpencodeCheck_BashHeredocElsewhere(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "invoice.html")
+	check := invoice.NewOpencodeCheck(path)
+	// The invoice changed, but the command wrote another file, so only the
+	// fallback accepts it.
+	if scanBashFixture(t, check, "opencode-bash-elsewhere.jsonl", path, "<html>this month</html>") {
+		t.Error("a bash command writing another file should not count as the invoice's write")
+	}
+	if err := check.Err(); err != nil {
+		t.Errorf("expected the changed file to count, got %v", err)
+	}
+
+	check = invoice.NewOpencodeCheck(filepath.Join(t.TempDir(), "invoice.html"))
+	scanBashFixture(t, check, "opencode-bash-elsewhere.jsonl", "", "")
+	if err := check.Err(); err == nil || !strings.Contains(err.Error(), "did not write the HTML invoice") {
+		t.Errorf("expected a not-written error, got %v", err)
+	}
+}
+
+func TestOpencodeCheck_BashUnchangedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "invoice.html")
+	if err := os.WriteFile(path, []byte("<html>last month</html>"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	check := invoice.NewOpencodeCheck(path)
+	// The command names the invoice, but the file is as it was before.
+	if scanBashFixture(t, check, "opencode-bash-heredoc.jsonl", path, "") {
+		t.Error("a bash command should not count when the invoice did not change")
+	}
+	if err := check.Err(); err == nil || !strings.Contains(err.Error(), "predates this run") {
+		t.Errorf("expected a stale-file error, got %v", err)
+	}
+}
+
+func TestOpencodeCheck_NoBashOrWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "invoice.html")
+	check := invoice.NewOpencodeCheck(path)
+	for _, name := range []string{"opencode-refusal.jsonl", "opencode-edit-elsewhere.jsonl"} {
+		if scanBashFixture(t, check, name, path, "") {
+			t.Errorf("%s: no write of the invoice should be seen", name)
+		}
+	}
+	if err := check.Err(); err == nil {
+		t.Error("expected an error without a write or a file")
+	}
+}
+
+func TestOpencodeCheck_BashHeredocDenied(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "invoice.html")
+	check := invoice.NewOpencodeCheck(path, invoice.DefaultDeniedTools...)
+	if !scanBashFixture(t, check, "opencode-bash-heredoc.jsonl", path, "<html>this month</html>") {
+		t.Error("a denied bash command that wrote the invoice should still count as its write")
+	}
+	if err := check.Err(); err != nil {
+		t.Errorf("expected a warning rather than a failure, got %v", err)
+	}
+	warnings := check.Warnings()
+	if len(warnings) != 1 || !strings.Contains(warnings[0].Error(), "wrote the invoice with a denied tool instead of the write tool: bash: cat <<'EOF' > invoice.html") {
+		t.Errorf("warnings = %v, want one naming the bash command", warnings)
+	}
+}
+
+func TestGenerate_BashWriteDeniedWarns(t *testing.T) {
+	outputPath := filepath.Join(t.TempDir(), "invoice.html")
+	origExec := invoice.OpencodeExec
+	t.Cleanup(func() { invoice.OpencodeExec = origExec })
+	invoice.OpencodeExec = func(ctx context.Context, r invoice.OpencodeRun, stdout io.Writer) error {
+		staged := promptedPath(r.Prompt)
+		if err := os.WriteFile(staged, []byte("<html><body>Invoice</body></html>"), 0o644); err != nil {
+			return err
+		}
+		// The command names the staging file the prompt gave.
+		events := bytes.ReplaceAll(readFixture(t, "opencode-bash-heredoc.jsonl"), []byte("> invoice.html"), []byte("> "+filepath.Base(staged)))
+		_, err := stdout.Write(events)
+		return err
+	}
+
+	var warnings []error
+	opts := invoice.GenerateOptions{
+		Model:       "anthropic/claude-haiku-4-5",
+		DeniedTools: invoice.DefaultDeniedTools,
+		OnWarning:   func(err error) { warnings = append(warnings, err) },
+	}
+	if _, err := invoice.Generate(context.Background(), "opencode", testInvoice(), outputPath, opts); err != nil {
+		t.Fatalf("expected success with a warning, got %v", err)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0].Error(), "denied tool instead of the write tool: bash:") {
+		t.Errorf("warnings = %v, want one about the bash command", warnings)
+	}
+}
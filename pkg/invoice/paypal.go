@@ -0,0 +1,361 @@
+package invoice
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Base URLs of the PayPal REST API.
+const (
+	PayPalURL        = "https://api-m.paypal.com"
+	PayPalSandboxURL = "https://api-m.sandbox.paypal.com"
+)
+
+// PayPalTimeout bounds each request to the PayPal API.
+const PayPalTimeout = 30 * time.Second
+
+// DefaultPayPalPaymentTerm is the payment term of a PayPal invoice without
+// a due date.
+const DefaultPayPalPaymentTerm = "NET_30"
+
+// PayPalPaymentTerms returns the payment terms PayPal invoices can have.
+func PayPalPaymentTerms() []string {
+	return []string{"DUE_ON_RECEIPT", "NET_10", "NET_15", "NET_30", "NET_45", "NET_60", "NET_90"}
+}
+
+// ValidatePayPalPaymentTerm checks that term is one of PayPalPaymentTerms.
+func ValidatePayPalPaymentTerm(term string) error {
+	if !slices.Contains(PayPalPaymentTerms(), term) {
+		return fmt.Errorf("PayPal payment term %q is not valid (use %s)", term, strings.Join(PayPalPaymentTerms(), ", "))
+	}
+	return nil
+}
+
+// Steps of sending an invoice to PayPal, named in a PayPalError.
+const (
+	PayPalStepAuth   = "authentication"
+	PayPalStepCreate = "invoice creation"
+	PayPalStepSend   = "sending"
+)
+
+// PayPalClient calls the PayPal Invoicing v2 API with a REST app's
+// client credentials.
+type PayPalClient struct {
+	// ClientID and Secret are the REST app's credentials.
+	ClientID string
+	Secret   string
+	// BaseURL is the API's base URL; "" means PayPalURL. It is
+	// PayPalSandboxURL for sandbox credentials, and tests point it at a
+	// fake server.
+	BaseURL string
+	// HTTP sends the requests; nil means a client with PayPalTimeout.
+	HTTP *http.Client
+}
+
+// PayPalError is a failed step of sending an invoice to PayPal.
+type PayPalError struct {
+	// Step is the step that failed, e.g. PayPalStepCreate. A request the
+	// credentials are not good for is always PayPalStepAuth.
+	Step string
+	// StatusCode is the HTTP status of PayPal's reply, or 0 if there was
+	// none.
+	StatusCode int
+	// Message is what PayPal, or the connection, said went wrong.
+	Message string
+	// Details are the problems PayPal found with the request, e.g.
+	// "/detail/currency_code: CURRENCY_NOT_SUPPORTED".
+	Details []string
+}
+
+func (e *PayPalError) Error() string {
+	msg := e.Message
+	if len(e.Details) > 0 {
+		msg += " (" + strings.Join(e.Details, "; ") + ")"
+	}
+	if e.Step == PayPalStepAuth {
+		return "PayPal authentication failed (check the client ID and secret): " + msg
+	}
+	return fmt.Sprintf("PayPal %s failed: %s", e.Step, msg)
+}
+
+// PayPalOptions say who a PayPal invoice is for and when it is due.
+type PayPalOptions struct {
+	// CustomerEmail is the email the invoice is sent to. Required.
+	CustomerEmail string
+	// PaymentTerm is one of PayPalPaymentTerms, used if the invoice has no
+	// due date; "" means DefaultPayPalPaymentTerm.
+	PaymentTerm string
+	// Send sends the invoice to the customer, instead of leaving it as a
+	// draft.
+	Send bool
+}
+
+// PayPalInvoice is the invoice SendPayPal created.
+type PayPalInvoice struct {
+	// ID is PayPal's ID of the invoice, e.g. "INV2-ABCD-EFGH-IJKL-MNOP".
+	ID string
+	// Sent is whether the invoice was sent to the customer.
+	Sent bool
+	// PayerURL is the page the customer pays on, once the invoice is sent.
+	PayerURL string
+}
+
+// SendPayPal creates a PayPal invoice for inv, and sends it if opts.Send
+// is set: it gets an access token, creates the invoice, and sends it.
+func SendPayPal(ctx context.Context, c *PayPalClient, inv *Invoice, opts PayPalOptions) (*PayPalInvoice, error) {
+	token, err := c.Token(ctx)
+	if err != nil {
+		return nil, err
+	}
+	id, err := c.CreateInvoice(ctx, token, inv, opts)
+	if err != nil {
+		return nil, err
+	}
+	created := &PayPalInvoice{ID: id}
+	if opts.Send {
+		if created.PayerURL, err = c.SendInvoice(ctx, token, id); err != nil {
+			return created, err
+		}
+		created.Sent = true
+	}
+	return created, nil
+}
+
+// Token gets an access token for the client credentials.
+func (c *PayPalClient) Token(ctx context.Context) (string, error) {
+	if c.ClientID == "" || c.Secret == "" {
+		return "", &PayPalError{Step: PayPalStepAuth, Message: "no client ID and secret are configured"}
+	}
+	body := strings.NewReader(url.Values{"grant_type": {"client_credentials"}}.Encode())
+	req, err := c.request(ctx, http.MethodPost, "/v1/oauth2/token", body)
+	if err != nil {
+		return "", &PayPalError{Step: PayPalStepAuth, Message: err.Error()}
+	}
+	req.SetBasicAuth(c.ClientID, c.Secret)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	var reply struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := c.do(req, PayPalStepAuth, &reply); err != nil {
+		return "", err
+	}
+	if reply.AccessToken == "" {
+		return "", &PayPalError{Step: PayPalStepAuth, Message: "the reply has no access token"}
+	}
+	return reply.AccessToken, nil
+}
+
+// payPalAmount is an amount of money in a PayPal request.
+type payPalAmount struct {
+	Currency string `json:"currency_code"`
+	Value    string `json:"value"`
+}
+
+// payPalInvoiceRequest is the body of a request to create an invoice.
+type payPalInvoiceRequest struct {
+	Detail struct {
+		Number      string `json:"invoice_number"`
+		Currency    string `json:"currency_code"`
+		Date        string `json:"invoice_date"`
+		Memo        string `json:"memo,omitempty"`
+		PaymentTerm struct {
+			TermType string `json:"term_type,omitempty"`
+			DueDate  string `json:"due_date,omitempty"`
+		} `json:"payment_term"`
+	} `json:"detail"`
+	Invoicer struct {
+		BusinessName string `json:"business_name"`
+	} `json:"invoicer"`
+	Recipients []payPalRecipient `json:"primary_recipients"`
+	Items      []payPalItem      `json:"items"`
+}
+
+type payPalRecipient struct {
+	BillingInfo struct {
+		BusinessName string `json:"business_name,omitempty"`
+		Email        string `json:"email_address"`
+	} `json:"billing_info"`
+}
+
+type payPalItem struct {
+	Name        string       `json:"name"`
+	Description string       `json:"description,omitempty"`
+	Quantity    string       `json:"quantity"`
+	UnitAmount  payPalAmount `json:"unit_amount"`
+	Unit        string       `json:"unit_of_measure"`
+}
+
+// CreateInvoice creates a draft PayPal invoice for inv with the access
+// token, and returns its ID. It has the invoice number, one item per week
+// in hours, and the due date or opts.PaymentTerm. The invoice number is
+// also the request ID, so a retried request does not create a second
+// invoice.
+func (c *PayPalClient) CreateInvoice(ctx context.Context, token string, inv *Invoice, opts PayPalOptions) (string, error) {
+	if opts.CustomerEmail == "" {
+		return "", &PayPalError{Step: PayPalStepCreate, Message: "the customer's email is required"}
+	}
+	term := opts.PaymentTerm
+	if term == "" {
+		term = DefaultPayPalPaymentTerm
+	}
+	if err := ValidatePayPalPaymentTerm(term); err != nil {
+		return "", &PayPalError{Step: PayPalStepCreate, Message: err.Error()}
+	}
+	lines, _ := invoiceLines(inv)
+	if len(lines) == 0 {
+		return "", errors.New("a PayPal invoice needs at least one line")
+	}
+
+	var body payPalInvoiceRequest
+	number := InvoiceNumber(inv)
+	body.Detail.Number = number
+	body.Detail.Currency = Currency
+	body.Detail.Date = formatJSONDate(inv.IssueDate())
+	body.Detail.Memo = fmt.Sprintf("Services %s %d", inv.Month, inv.Year)
+	if inv.Due.IsZero() {
+		body.Detail.PaymentTerm.TermType = term
+	} else {
+		body.Detail.PaymentTerm.DueDate = formatJSONDate(inv.Due)
+	}
+	body.Invoicer.BusinessName = inv.Vendor
+	var recipient payPalRecipient
+	recipient.BillingInfo.BusinessName = inv.Customer
+	recipient.BillingInfo.Email = opts.CustomerEmail
+	body.Recipients = []payPalRecipient{recipient}
+	for _, l := range lines {
+		body.Items = append(body.Items, payPalItem{
+			Name:       l.Description,
+			Quantity:   formatQuantity(l.Quantity),
+			UnitAmount: payPalAmount{Currency: Currency, Value: l.Price.String()},
+			Unit:       "HOURS",
+		})
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := c.request(ctx, http.MethodPost, "/v2/invoicing/invoices", bytes.NewReader(data))
+	if err != nil {
+		return "", &PayPalError{Step: PayPalStepCreate, Message: err.Error()}
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Prefer", "return=representation")
+	req.Header.Set("PayPal-Request-Id", "invoicer-"+number)
+	var reply struct {
+		ID string `json:"id"`
+	}
+	if err := c.do(req, PayPalStepCreate, &reply); err != nil {
+		return "", err
+	}
+	if reply.ID == "" {
+		return "", &PayPalError{Step: PayPalStepCreate, Message: "the reply has no invoice ID"}
+	}
+	return reply.ID, nil
+}
+
+// SendInvoice sends the PayPal invoice id to its recipient with the access
+// token, and returns the URL of the page the customer pays on.
+func (c *PayPalClient) SendInvoice(ctx context.Context, token, id string) (string, error) {
+	body := strings.NewReader(`{"send_to_recipient":true,"send_to_invoicer":false}`)
+	req, err := c.request(ctx, http.MethodPost, "/v2/invoicing/invoices/"+url.PathEscape(id)+"/send", body)
+	if err != nil {
+		return "", &PayPalError{Step: PayPalStepSend, Message: err.Error()}
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("PayPal-Request-Id", "invoicer-send-"+id)
+	var reply struct {
+		Href string `json:"href"`
+	}
+	if err := c.do(req, PayPalStepSend, &reply); err != nil {
+		return "", err
+	}
+	return reply.Href, nil
+}
+
+// request returns a request to path on the API.
+func (c *PayPalClient) request(ctx context.Context, method, path string, body io.Reader) (*http.Request, error) {
+	base := c.BaseURL
+	if base == "" {
+		base = PayPalURL
+	}
+	return http.NewRequestWithContext(ctx, method, strings.TrimRight(base, "/")+path, body)
+}
+
+// do sends req for step and decodes the reply into out, if it has a body.
+func (c *PayPalClient) do(req *http.Request, step string, out any) error {
+	client := c.HTTP
+	if client == nil {
+		client = &http.Client{Timeout: PayPalTimeout}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return &PayPalError{Step: step, Message: err.Error()}
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return &PayPalError{Step: step, StatusCode: resp.StatusCode, Message: err.Error()}
+	}
+	if resp.StatusCode/100 != 2 {
+		return payPalReplyError(step, resp, data)
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return &PayPalError{Step: step, StatusCode: resp.StatusCode, Message: fmt.Sprintf("reading the reply: %v", err)}
+	}
+	return nil
+}
+
+// payPalReplyError describes PayPal's error reply to a request for step.
+// PayPal's API errors have a message and details; OAuth errors have an
+// error_description.
+func payPalReplyError(step string, resp *http.Response, data []byte) error {
+	var reply struct {
+		Message     string `json:"message"`
+		Description string `json:"error_description"`
+		Details     []struct {
+			Field       string `json:"field"`
+			Issue       string `json:"issue"`
+			Description string `json:"description"`
+		} `json:"details"`
+	}
+	e := &PayPalError{Step: step, StatusCode: resp.StatusCode}
+	if resp.StatusCode == http.StatusUnauthorized {
+		e.Step = PayPalStepAuth
+	}
+	msg := strings.TrimSpace(string(data))
+	if json.Unmarshal(data, &reply) == nil {
+		switch {
+		case reply.Message != "":
+			msg = reply.Message
+		case reply.Description != "":
+			msg = reply.Description
+		}
+		for _, d := range reply.Details {
+			detail := d.Issue
+			if d.Description != "" {
+				detail += ": " + d.Description
+			}
+			if d.Field != "" {
+				detail = d.Field + " " + detail
+			}
+			e.Details = append(e.Details, detail)
+		}
+	}
+	e.Message = fmt.Sprintf("%s: %s", resp.Status, msg)
+	return e
+}
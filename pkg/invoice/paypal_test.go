@@ -0,0 +1,234 @@
+package invoice_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+// apiReply is a canned reply of a fake HTTP API: a status and a fixture
+// from testdata.
+type apiReply struct {
+	status  int
+	fixture string
+}
+
+// paypalServer starts a fake PayPal API that answers each path with a
+// fixture from testdata, and records the requests' bodies by path.
+func paypalServer(t *testing.T, replies map[string]apiReply, got map[string]*http.Request, bodies map[string][]byte) *invoice.PayPalClient {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reply, ok := replies[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		if got != nil {
+			got[r.URL.Path] = r
+		}
+		if bodies != nil {
+			bodies[r.URL.Path], _ = io.ReadAll(r.Body)
+		}
+		data, err := os.ReadFile(filepath.Join("testdata", reply.fixture))
+		if err != nil {
+			t.Error(err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(reply.status)
+		w.Write(data)
+	}))
+	t.Cleanup(srv.Close)
+	return &invoice.PayPalClient{ClientID: "client-id", Secret: "client-secret", BaseURL: srv.URL, HTTP: srv.Client()}
+}
+
+const (
+	paypalTokenPath   = "/v1/oauth2/token"
+	paypalInvoicePath = "/v2/invoicing/invoices"
+	paypalSendPath    = "/v2/invoicing/invoices/INV2-Z56S-5LLA-Q52L-CPZ5/send"
+)
+
+// paypalHappyPath are the replies of an API that takes everything.
+var paypalHappyPath = map[string]apiReply{
+	paypalTokenPath:   {http.StatusOK, "paypal-token.json"},
+	paypalInvoicePath: {http.StatusCreated, "paypal-invoice.json"},
+	paypalSendPath:    {http.StatusOK, "paypal-send.json"},
+}
+
+func TestSendPayPal(t *testing.T) {
+	got := map[string]*http.Request{}
+	bodies := map[string][]byte{}
+	client := paypalServer(t, paypalHappyPath, got, bodies)
+	inv := jsonInvoice()
+	created, err := invoice.SendPayPal(context.Background(), client, inv, invoice.PayPalOptions{CustomerEmail: "ap@acme.example", Send: true})
+	if err != nil {
+		t.Fatalf("SendPayPal: %v", err)
+	}
+	want := invoice.PayPalInvoice{ID: "INV2-Z56S-5LLA-Q52L-CPZ5", Sent: true, PayerURL: "https://www.paypal.com/invoice/p/#Z56S5LLAQ52LCPZ5"}
+	if *created != want {
+		t.Errorf("created %+v, want %+v", *created, want)
+	}
+
+	if user, pass, _ := got[paypalTokenPath].BasicAuth(); user != "client-id" || pass != "client-secret" {
+		t.Errorf("token request authenticated as %q:%q", user, pass)
+	}
+	if string(bodies[paypalTokenPath]) != "grant_type=client_credentials" {
+		t.Errorf("token request body = %q", bodies[paypalTokenPath])
+	}
+	create := got[paypalInvoicePath]
+	if h := create.Header.Get("Authorization"); h != "Bearer A21AAFakeAccessToken" {
+		t.Errorf("create Authorization = %q", h)
+	}
+	if h := create.Header.Get("PayPal-Request-Id"); h != "invoicer-INV-202501-acme-corp" {
+		t.Errorf("create PayPal-Request-Id = %q", h)
+	}
+	var body struct {
+		Detail struct {
+			Number      string `json:"invoice_number"`
+			Currency    string `json:"currency_code"`
+			Date        string `json:"invoice_date"`
+			PaymentTerm struct {
+				DueDate string `json:"due_date"`
+			} `json:"payment_term"`
+		} `json:"detail"`
+		Recipients []struct {
+			BillingInfo struct {
+				Email string `json:"email_address"`
+			} `json:"billing_info"`
+		} `json:"primary_recipients"`
+		Items []struct {
+			Name       string `json:"name"`
+			Quantity   string `json:"quantity"`
+			UnitAmount struct {
+				Value string `json:"value"`
+			} `json:"unit_amount"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(bodies[paypalInvoicePath], &body); err != nil {
+		t.Fatalf("create body: %v\n%s", err, bodies[paypalInvoicePath])
+	}
+	if d := body.Detail; d.Number != "INV-202501-acme-corp" || d.Currency != "USD" || d.Date != "2025-02-01" || d.PaymentTerm.DueDate != "2025-03-03" {
+		t.Errorf("detail = %+v", d)
+	}
+	if len(body.Recipients) != 1 || body.Recipients[0].BillingInfo.Email != "ap@acme.example" {
+		t.Errorf("recipients = %+v", body.Recipients)
+	}
+	if len(body.Items) != len(inv.Weeks) {
+		t.Fatalf("got %d items, want %d", len(body.Items), len(inv.Weeks))
+	}
+	for i, item := range body.Items {
+		if want := "Services " + invoice.FormatWeekLabel(inv.Weeks[i]); item.Name != want || item.UnitAmount.Value != "150.00" {
+			t.Errorf("item %d = %+v, want %q at 150.00", i, item, want)
+		}
+	}
+	if body.Items[0].Quantity != "32" {
+		t.Errorf("first item quantity = %q, want 32", body.Items[0].Quantity)
+	}
+}
+
+func TestSendPayPal_Draft(t *testing.T) {
+	got := map[string]*http.Request{}
+	client := paypalServer(t, paypalHappyPath, got, nil)
+	created, err := invoice.SendPayPal(context.Background(), client, jsonInvoice(), invoice.PayPalOptions{CustomerEmail: "ap@acme.example"})
+	if err != nil {
+		t.Fatalf("SendPayPal: %v", err)
+	}
+	if created.Sent || created.PayerURL != "" {
+		t.Errorf("created %+v, want an unsent draft", *created)
+	}
+	if _, ok := got[paypalSendPath]; ok {
+		t.Error("the draft should not be sent")
+	}
+}
+
+func TestPayPalClient_Token_AuthFailure(t *testing.T) {
+	client := paypalServer(t, map[string]apiReply{
+		paypalTokenPath: {http.StatusUnauthorized, "paypal-token-invalid-client.json"},
+	}, nil, nil)
+	_, err := client.Token(context.Background())
+	var perr *invoice.PayPalError
+	if !errors.As(err, &perr) || perr.Step != invoice.PayPalStepAuth {
+		t.Fatalf("expected an authentication PayPalError, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "Client Authentication failed") {
+		t.Errorf("error %q does not say why", err)
+	}
+}
+
+func TestPayPalClient_Token_NoCredentials(t *testing.T) {
+	_, err := (&invoice.PayPalClient{ClientID: "client-id"}).Token(context.Background())
+	var perr *invoice.PayPalError
+	if !errors.As(err, &perr) || perr.Step != invoice.PayPalStepAuth {
+		t.Errorf("expected an authentication PayPalError, got %v", err)
+	}
+}
+
+func TestPayPalClient_CreateInvoice_ValidationError(t *testing.T) {
+	client := paypalServer(t, map[string]apiReply{
+		paypalInvoicePath: {http.StatusBadRequest, "paypal-validation-error.json"},
+	}, nil, nil)
+	_, err := client.CreateInvoice(context.Background(), "token", jsonInvoice(), invoice.PayPalOptions{CustomerEmail: "not-an-email"})
+	var perr *invoice.PayPalError
+	if !errors.As(err, &perr) || perr.Step != invoice.PayPalStepCreate || perr.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected a creation PayPalError, got %v", err)
+	}
+	want := "/primary_recipients/0/billing_info/email_address INVALID_PARAMETER_SYNTAX"
+	if len(perr.Details) != 1 || !strings.HasPrefix(perr.Details[0], want) {
+		t.Errorf("details = %q, want one starting with %q", perr.Details, want)
+	}
+	if !strings.Contains(err.Error(), "PayPal invoice creation failed: 400 Bad Request: Request is not well-formed") {
+		t.Errorf("error = %v", err)
+	}
+}
+
+func TestPayPalClient_CreateInvoice_PaymentTerm(t *testing.T) {
+	bodies := map[string][]byte{}
+	client := paypalServer(t, paypalHappyPath, nil, bodies)
+	if _, err := client.CreateInvoice(context.Background(), "token", testInvoice(), invoice.PayPalOptions{CustomerEmail: "ap@acme.example", PaymentTerm: "NET_15"}); err != nil {
+		t.Fatalf("CreateInvoice: %v", err)
+	}
+	if !strings.Contains(string(bodies[paypalInvoicePath]), `"payment_term":{"term_type":"NET_15"}`) {
+		t.Errorf("an invoice without a due date should have the payment term:\n%s", bodies[paypalInvoicePath])
+	}
+
+	// The term runs from the issue date, so a backdated invoice is due
+	// that much earlier.
+	backdated := testInvoice()
+	backdated.Issued = time.Date(2025, time.January, 31, 0, 0, 0, 0, time.UTC)
+	if _, err := client.CreateInvoice(context.Background(), "token", backdated, invoice.PayPalOptions{CustomerEmail: "ap@acme.example", PaymentTerm: "NET_15"}); err != nil {
+		t.Fatalf("CreateInvoice: %v", err)
+	}
+	if !strings.Contains(string(bodies[paypalInvoicePath]), `"invoice_date":"2025-01-31"`) {
+		t.Errorf("the payment term should run from the issue date:\n%s", bodies[paypalInvoicePath])
+	}
+
+	_, err := client.CreateInvoice(context.Background(), "token", testInvoice(), invoice.PayPalOptions{CustomerEmail: "ap@acme.example", PaymentTerm: "NET_7"})
+	if err == nil || !strings.Contains(err.Error(), `payment term "NET_7"`) {
+		t.Errorf("expected a payment term error, got %v", err)
+	}
+}
+
+func TestPayPalClient_SendInvoice_Failure(t *testing.T) {
+	client := paypalServer(t, map[string]apiReply{
+		paypalTokenPath:   {http.StatusOK, "paypal-token.json"},
+		paypalInvoicePath: {http.StatusCreated, "paypal-invoice.json"},
+		paypalSendPath:    {http.StatusUnprocessableEntity, "paypal-validation-error.json"},
+	}, nil, nil)
+	created, err := invoice.SendPayPal(context.Background(), client, jsonInvoice(), invoice.PayPalOptions{CustomerEmail: "ap@acme.example", Send: true})
+	var perr *invoice.PayPalError
+	if !errors.As(err, &perr) || perr.Step != invoice.PayPalStepSend {
+		t.Fatalf("expected a sending PayPalError, got %v", err)
+	}
+	if created == nil || created.ID != "INV2-Z56S-5LLA-Q52L-CPZ5" || created.Sent {
+		t.Errorf("created = %+v, want the unsent draft's ID", created)
+	}
+}
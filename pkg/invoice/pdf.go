@@ -0,0 +1,417 @@
+package invoice
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// pdfTool describes an external program that can convert HTML to PDF.
+type pdfTool struct {
+	// Name identifies the tool in messages.
+	Name string
+	// Bins are executable names searched for on PATH, in order.
+	Bins []string
+	// Installs are standard install locations checked when none of Bins is on PATH.
+	// They may reference environment variables as ${VAR}; entries whose variables
+	// are unset are skipped.
+	Installs []string
+	// Args returns the arguments to convert htmlPath to pdfPath, with the
+	// caller's extra arguments placed before the input and output.
+	Args func(htmlPath, pdfPath string, extra []string) []string
+	// Install lists commands that install the tool, if there are any.
+	Install []string
+}
+
+// headlessArgs returns the arguments for printing to PDF with a Chromium-based browser.
+func headlessArgs(htmlPath, pdfPath string, extra []string) []string {
+	args := []string{"--headless", "--disable-gpu", "--no-sandbox"}
+	args = append(args, extra...)
+	return append(args, "--print-to-pdf="+pdfPath, FileURL(htmlPath))
+}
+
+// pdfTools lists the supported conversion tools in order of preference.
+var pdfTools = []pdfTool{
+	{
+		Name: "wkhtmltopdf",
+		Bins: []string{"wkhtmltopdf"},
+		Args: func(htmlPath, pdfPath string, extra []string) []string {
+			return append(slices.Clone(extra), htmlPath, pdfPath)
+		},
+		Install: []string{
+			"sudo apt install wkhtmltopdf    (Debian, Ubuntu)",
+			"brew install wkhtmltopdf        (macOS)",
+			"winget install wkhtmltopdf.wkhtmltox    (Windows)",
+		},
+	},
+	{
+		Name: "chromium",
+		Bins: []string{"chromium", "chromium-browser"},
+		Args: headlessArgs,
+		Install: []string{
+			"sudo apt install chromium    (Debian, Ubuntu)",
+			"brew install --cask chromium    (macOS)",
+		},
+	},
+	{
+		Name: "chrome",
+		Bins: []string{"google-chrome", "google-chrome-stable", "chrome.exe"},
+		Installs: []string{
+			"${ProgramFiles}/Google/Chrome/Application/chrome.exe",
+			"${ProgramFiles(x86)}/Google/Chrome/Application/chrome.exe",
+			"${LocalAppData}/Google/Chrome/Application/chrome.exe",
+		},
+		Args: headlessArgs,
+	},
+	{
+		Name: "edge",
+		Bins: []string{"msedge", "msedge.exe", "microsoft-edge"},
+		Installs: []string{
+			"${ProgramFiles(x86)}/Microsoft/Edge/Application/msedge.exe",
+			"${ProgramFiles}/Microsoft/Edge/Application/msedge.exe",
+		},
+		Args: headlessArgs,
+	},
+	{
+		Name: "weasyprint",
+		Bins: []string{"weasyprint"},
+		Args: func(htmlPath, pdfPath string, extra []string) []string {
+			return append(slices.Clone(extra), htmlPath, pdfPath)
+		},
+		Install: []string{
+			"sudo apt install weasyprint    (Debian, Ubuntu)",
+			"brew install weasyprint        (macOS)",
+			"pip install weasyprint",
+		},
+	},
+	{
+		Name: "pandoc",
+		Bins: []string{"pandoc"},
+		Args: func(htmlPath, pdfPath string, extra []string) []string {
+			return append(slices.Clone(extra), htmlPath, "-o", pdfPath)
+		},
+		Install: []string{
+			"sudo apt install pandoc    (Debian, Ubuntu)",
+			"brew install pandoc        (macOS)",
+			"winget install JohnMacFarlane.Pandoc    (Windows)",
+		},
+	},
+}
+
+// find returns the path to the tool's executable, or "" if it is not installed.
+func (t pdfTool) find() string {
+	for _, bin := range t.Bins {
+		if path, err := exec.LookPath(bin); err == nil {
+			return path
+		}
+	}
+	for _, install := range t.Installs {
+		missing := false
+		path := os.Expand(install, func(name string) string {
+			v := os.Getenv(name)
+			if v == "" {
+				missing = true
+			}
+			return v
+		})
+		if missing {
+			continue
+		}
+		path = filepath.FromSlash(path)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+	}
+	return ""
+}
+
+// AutoPDFTool selects the first PDF tool installed, in the order of PDFTools.
+const AutoPDFTool = "auto"
+
+// PDFOptions configures ConvertToPDF. The zero value uses the first tool
+// installed.
+type PDFOptions struct {
+	// Tool is the conversion tool to use, one of PDFTools, or "" or
+	// AutoPDFTool for the first one installed.
+	Tool string
+	// ToolPath is the tool's executable, for one that is neither on PATH
+	// nor in a standard install location. Without Tool, which tool it is
+	// is told from the executable's name.
+	ToolPath string
+	// Args are extra arguments for the tool, e.g. "--enable-local-file-access".
+	// Each is passed as a single argument, before the input and output.
+	// The chromedp engine passes them to the browser as flags.
+	Args []string
+	// Engine is one of PDFEngines; "" means ExecPDFEngine.
+	Engine string
+	// OnFallback, if set, is called when the auto engine gives up on
+	// chromedp and falls back to a conversion tool.
+	OnFallback func(err error)
+	// Progress, if set, is called as the conversion starts and finishes.
+	Progress func(event ProgressEvent)
+}
+
+// PDFTools returns the names of the supported conversion tools in order of
+// preference.
+func PDFTools() []string {
+	names := make([]string, len(pdfTools))
+	for i, tool := range pdfTools {
+		names[i] = tool.Name
+	}
+	return names
+}
+
+// CheckPDFTool returns an error if name is neither AutoPDFTool nor one of
+// PDFTools.
+func CheckPDFTool(name string) error {
+	if name == "" || name == AutoPDFTool || slices.Contains(PDFTools(), name) {
+		return nil
+	}
+	return fmt.Errorf("unknown PDF tool %q (use one of: %s, %s)", name, AutoPDFTool, strings.Join(PDFTools(), ", "))
+}
+
+// ConvertToPDF converts an HTML file to PDF and returns the name of the tool
+// used. Unless opts names a tool, it tries wkhtmltopdf, then falls back to
+// Chromium, Chrome, or Edge headless, then WeasyPrint, then pandoc. A tool
+// named in opts that is not installed is an error rather than a reason to
+// fall back.
+// Arguments are passed to the tool directly rather than through a shell, so
+// paths containing spaces need no quoting. The tool and any processes it
+// started are killed if ctx is done, e.g. when a deadline set by the caller
+// expires.
+// With the chromedp engine the browser is driven over the DevTools protocol
+// instead; the auto engine falls back to running a tool if that fails.
+// A tool that exits cleanly must still leave a PDF that passes VerifyPDF.
+// A PDF the failed run created is removed.
+func ConvertToPDF(ctx context.Context, htmlPath, pdfPath string, opts PDFOptions) (string, error) {
+	opts.progress(ProgressEvent{Kind: ProgressPDFStarted, Path: pdfPath})
+	tool, err := convertToPDF(ctx, htmlPath, pdfPath, opts)
+	opts.progress(ProgressEvent{Kind: ProgressPDFFinished, Tool: tool, Path: pdfPath, Err: err})
+	return tool, err
+}
+
+func convertToPDF(ctx context.Context, htmlPath, pdfPath string, opts PDFOptions) (string, error) {
+	if err := CheckPDFEngine(opts.Engine); err != nil {
+		return "", err
+	}
+	if opts.Engine == ChromedpPDFEngine || opts.Engine == AutoPDFEngine {
+		existed := exists(pdfPath)
+		err := convertChromedp(ctx, htmlPath, pdfPath, opts)
+		if err == nil {
+			err = VerifyPDF(pdfPath)
+		}
+		if err == nil {
+			return ChromedpPDFEngine, nil
+		}
+		removeIfCreated(pdfPath, existed)
+		if ctx.Err() != nil {
+			return ChromedpPDFEngine, pdfToolStopped(ctx, ChromedpPDFEngine)
+		}
+		if opts.Engine == ChromedpPDFEngine {
+			return ChromedpPDFEngine, fmt.Errorf("%s: %w", ChromedpPDFEngine, err)
+		}
+		if opts.OnFallback != nil {
+			opts.OnFallback(err)
+		}
+	}
+
+	tool, path, err := selectPDFTool(opts)
+	if err != nil {
+		return "", err
+	}
+	existed := exists(pdfPath)
+	cmd := command(ctx, path, tool.Args(htmlPath, pdfPath, opts.Args)...)
+	tail, err := runTail(cmd)
+	if err != nil {
+		removeIfCreated(pdfPath, existed)
+		if ctx.Err() != nil {
+			return tool.Name, pdfToolStopped(ctx, tool.Name)
+		}
+		return tool.Name, fmt.Errorf("%s: %w", tool.Name, err)
+	}
+	if err := VerifyPDF(pdfPath); err != nil {
+		removeIfCreated(pdfPath, existed)
+		if tail.Len() > 0 {
+			return tool.Name, fmt.Errorf("%s exited cleanly but %w: %s", tool.Name, err, tail)
+		}
+		return tool.Name, fmt.Errorf("%s exited cleanly but %w", tool.Name, err)
+	}
+	return tool.Name, nil
+}
+
+// pdfToolStopped returns the error for a conversion by name that was
+// stopped because ctx is done.
+func pdfToolStopped(ctx context.Context, name string) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%s timed out: %w", name, ctx.Err())
+	}
+	return fmt.Errorf("%s: %w", name, ctx.Err())
+}
+
+// selectPDFTool returns the tool opts asks for and the path to its
+// executable.
+func selectPDFTool(opts PDFOptions) (pdfTool, string, error) {
+	if err := CheckPDFTool(opts.Tool); err != nil {
+		return pdfTool{}, "", err
+	}
+	auto := opts.Tool == "" || opts.Tool == AutoPDFTool
+
+	if opts.ToolPath != "" {
+		var tool pdfTool
+		var ok bool
+		if auto {
+			if tool, ok = pdfToolForPath(opts.ToolPath); !ok {
+				return pdfTool{}, "", fmt.Errorf("cannot tell which PDF tool %s is; set the PDF tool to one of: %s", opts.ToolPath, strings.Join(PDFTools(), ", "))
+			}
+		} else {
+			tool, _ = lookupPDFTool(opts.Tool)
+		}
+		if info, err := os.Stat(opts.ToolPath); err != nil {
+			return pdfTool{}, "", fmt.Errorf("%s: %w", tool.Name, err)
+		} else if info.IsDir() {
+			return pdfTool{}, "", fmt.Errorf("%s: %s is a directory, not the executable", tool.Name, opts.ToolPath)
+		}
+		return tool, opts.ToolPath, nil
+	}
+
+	if !auto {
+		tool, _ := lookupPDFTool(opts.Tool)
+		if path := tool.find(); path != "" {
+			return tool, path, nil
+		}
+		return pdfTool{}, "", &NotInstalledError{
+			Tool:    tool.Name,
+			Purpose: fmt.Sprintf("The PDF tool is set to %s, so invoicer looked for %s and nothing else.", tool.Name, strings.Join(tool.Bins, ", ")),
+			Install: tool.Install,
+			Instead: "Or set pdf_tool: auto (or --pdf-tool auto) to use whichever tool is installed, or point pdf_tool_path: (--pdf-tool-path) at the executable.",
+		}
+	}
+
+	for _, tool := range pdfTools {
+		if path := tool.find(); path != "" {
+			return tool, path, nil
+		}
+	}
+	var bins []string
+	for _, tool := range pdfTools {
+		bins = append(bins, tool.Bins...)
+	}
+	return pdfTool{}, "", &NotInstalledError{
+		Tool:    "A PDF conversion tool",
+		Purpose: fmt.Sprintf("invoicer converts the HTML invoice to PDF with the first of these it finds: %s. It looked for %s.", strings.Join(PDFTools(), ", "), strings.Join(bins, ", ")),
+		Install: pdfTools[0].Install,
+		Instead: "Or leave out --pdf (set pdf: false in the config) to keep just the HTML invoice.",
+	}
+}
+
+// lookupPDFTool returns the tool called name.
+func lookupPDFTool(name string) (pdfTool, bool) {
+	for _, tool := range pdfTools {
+		if tool.Name == name {
+			return tool, true
+		}
+	}
+	return pdfTool{}, false
+}
+
+// pdfToolForPath returns the tool whose executable is at path, told from
+// its name.
+func pdfToolForPath(path string) (pdfTool, bool) {
+	base := strings.ToLower(filepath.Base(filepath.FromSlash(path)))
+	if i := strings.LastIndexByte(base, '\\'); i >= 0 {
+		base = base[i+1:]
+	}
+	base = strings.TrimSuffix(base, ".exe")
+	for _, tool := range pdfTools {
+		if base == tool.Name {
+			return tool, true
+		}
+		for _, bin := range tool.Bins {
+			if base == strings.TrimSuffix(bin, ".exe") {
+				return tool, true
+			}
+		}
+	}
+	return pdfTool{}, false
+}
+
+// pdfTrailerWindow is how far from the end of a PDF VerifyPDF looks for the
+// %%EOF marker; writers may follow it with a little whitespace.
+const pdfTrailerWindow = 1024
+
+// VerifyPDF checks that path is a non-empty file that starts with the %PDF-
+// header and ends with the %%EOF trailer.
+func VerifyPDF(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("the PDF is missing: %w", err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("reading the PDF: %w", err)
+	}
+	if info.Size() == 0 {
+		return fmt.Errorf("the PDF %s is empty", path)
+	}
+	head := make([]byte, min(info.Size(), maxQuoted))
+	if _, err := io.ReadFull(f, head); err != nil {
+		return fmt.Errorf("reading the PDF: %w", err)
+	}
+	if !bytes.HasPrefix(head, []byte("%PDF-")) {
+		return fmt.Errorf("%s is not a PDF; it starts with %q", path, head)
+	}
+	tail := make([]byte, min(info.Size(), pdfTrailerWindow))
+	if _, err := f.ReadAt(tail, info.Size()-int64(len(tail))); err != nil {
+		return fmt.Errorf("reading the PDF: %w", err)
+	}
+	if !bytes.Contains(tail, []byte("%%EOF")) {
+		return fmt.Errorf("the PDF %s has no %%%%EOF trailer and may be truncated", path)
+	}
+	return nil
+}
+
+// FileURL returns a file:// URL for path, made absolute first, with
+// spaces, '#', '%' and non-ASCII characters percent-encoded. Windows paths
+// with a drive letter become file:///C:/... URLs, and UNC paths
+// (\\server\share\...) become file://server/share/... URLs.
+func FileURL(path string) string {
+	if !hasDriveLetter(path) && !isUNC(path) {
+		if abs, err := filepath.Abs(path); err == nil {
+			path = abs
+		}
+	}
+	u := url.URL{Scheme: "file"}
+	switch {
+	case hasDriveLetter(path):
+		u.Path = "/" + strings.ReplaceAll(path, `\`, "/")
+	case isUNC(path):
+		host, rest, _ := strings.Cut(strings.ReplaceAll(path, `\`, "/")[2:], "/")
+		u.Host, u.Path = host, "/"+rest
+	default:
+		u.Path = filepath.ToSlash(path)
+	}
+	return u.String()
+}
+
+// isUNC reports whether path is a Windows UNC path such as \\server\share.
+func isUNC(path string) bool {
+	return len(path) > 2 && strings.HasPrefix(path, `\\`) && path[2] != '\\' && path[2] != '?'
+}
+
+// hasDriveLetter reports whether path starts with a Windows drive letter (e.g. "C:").
+func hasDriveLetter(path string) bool {
+	if len(path) < 2 || path[1] != ':' {
+		return false
+	}
+	c := path[0]
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
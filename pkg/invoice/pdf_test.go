@@ -0,0 +1,384 @@
+package invoice_test
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+// fakeBrowserScript records its arguments to args.txt beside itself, one per
+// line, and writes a minimal PDF to the file named by --print-to-pdf, or
+// else to its last argument, using only shell builtins.
+const fakeBrowserScript = `#!/bin/sh
+dir=${0%/*}
+printf '%s\n' "$@" > "$dir/args.txt"
+for a in "$@"; do
+	case "$a" in
+	--print-to-pdf=*) out=${a#--print-to-pdf=} ;;
+	*) last=$a ;;
+	esac
+done
+printf '%%PDF-1.4\n%%%%EOF\n' > "${out:-$last}"
+`
+
+// fakeWkhtmltopdfScript writes a minimal PDF to its second argument.
+const fakeWkhtmltopdfScript = "#!/bin/sh\nprintf '%%PDF-1.4\\n%%%%EOF\\n' > \"$2\"\n"
+
+// writeFakeTool writes an executable script named name into dir.
+func writeFakeTool(t *testing.T, dir, name, script string) string {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// isolatePDFTools clears PATH and the Windows install location variables so only
+// fake tools are found.
+func isolatePDFTools(t *testing.T, path string) {
+	t.Helper()
+	t.Setenv("PATH", path)
+	for _, v := range []string{"ProgramFiles", "ProgramFiles(x86)", "LocalAppData"} {
+		t.Setenv(v, "")
+	}
+}
+
+func writeTestHTML(t *testing.T, dir string) string {
+	t.Helper()
+	htmlPath := filepath.Join(dir, "invoice.html")
+	if err := os.WriteFile(htmlPath, []byte("<html></html>"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return htmlPath
+}
+
+func TestConvertToPDF_WindowsBrowserNamesOnPath(t *testing.T) {
+	for _, name := range []string{"msedge", "msedge.exe", "chrome.exe"} {
+		t.Run(name, func(t *testing.T) {
+			binDir := t.TempDir()
+			writeFakeTool(t, binDir, name, fakeBrowserScript)
+			isolatePDFTools(t, binDir)
+
+			outDir := t.TempDir()
+			htmlPath := writeTestHTML(t, outDir)
+			pdfPath := filepath.Join(outDir, "invoice.pdf")
+			if _, err := invoice.ConvertToPDF(context.Background(), htmlPath, pdfPath, invoice.PDFOptions{}); err != nil {
+				t.Fatalf("ConvertToPDF() error: %v", err)
+			}
+			if _, err := os.Stat(pdfPath); err != nil {
+				t.Errorf("expected PDF to be created: %v", err)
+			}
+			args, err := os.ReadFile(filepath.Join(binDir, "args.txt"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(args), "--headless") {
+				t.Errorf("expected headless args, got %q", args)
+			}
+		})
+	}
+}
+
+func TestConvertToPDF_EdgeInstallLocation(t *testing.T) {
+	isolatePDFTools(t, t.TempDir())
+	programFiles := t.TempDir()
+	t.Setenv("ProgramFiles", programFiles)
+	binDir := filepath.Join(programFiles, "Microsoft", "Edge", "Application")
+	writeFakeTool(t, binDir, "msedge.exe", fakeBrowserScript)
+
+	outDir := t.TempDir()
+	htmlPath := writeTestHTML(t, outDir)
+	pdfPath := filepath.Join(outDir, "invoice.pdf")
+	if _, err := invoice.ConvertToPDF(context.Background(), htmlPath, pdfPath, invoice.PDFOptions{}); err != nil {
+		t.Fatalf("ConvertToPDF() error: %v", err)
+	}
+	if _, err := os.Stat(pdfPath); err != nil {
+		t.Errorf("expected PDF to be created: %v", err)
+	}
+}
+
+func TestConvertToPDF_PathWithSpaces(t *testing.T) {
+	binDir := t.TempDir()
+	writeFakeTool(t, binDir, "chromium", fakeBrowserScript)
+	isolatePDFTools(t, binDir)
+
+	outDir := filepath.Join(t.TempDir(), "Acme Corp")
+	if err := os.MkdirAll(outDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	htmlPath := writeTestHTML(t, outDir)
+	pdfPath := filepath.Join(outDir, "invoice.pdf")
+	if _, err := invoice.ConvertToPDF(context.Background(), htmlPath, pdfPath, invoice.PDFOptions{}); err != nil {
+		t.Fatalf("ConvertToPDF() error: %v", err)
+	}
+	if _, err := os.Stat(pdfPath); err != nil {
+		t.Errorf("expected PDF in directory with spaces: %v", err)
+	}
+}
+
+func TestConvertToPDF_VerifiesOutput(t *testing.T) {
+	tests := []struct {
+		name    string
+		script  string
+		wantErr string
+	}{
+		{"writes nothing", "echo 'Done' >&2\n", "wkhtmltopdf exited cleanly but the PDF is missing"},
+		{"writes empty file", ": > \"$2\"\n", "is empty"},
+		{"writes garbage", "echo 'Warning: blocked access to file' >&2\necho '<html>oops</html>' > \"$2\"\n", `is not a PDF; it starts with "<html>oops</html>\n"`},
+		{"writes truncated PDF", "printf '%%PDF-1.4\\n1 0 obj' > \"$2\"\n", "has no %%EOF trailer"},
+		{"writes valid PDF", "printf '%%PDF-1.4\\n%%%%EOF\\n' > \"$2\"\n", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			binDir := t.TempDir()
+			writeFakeTool(t, binDir, "wkhtmltopdf", "#!/bin/sh\n"+tt.script)
+			isolatePDFTools(t, binDir)
+
+			outDir := t.TempDir()
+			htmlPath := writeTestHTML(t, outDir)
+			pdfPath := filepath.Join(outDir, "invoice.pdf")
+			tool, err := invoice.ConvertToPDF(context.Background(), htmlPath, pdfPath, invoice.PDFOptions{})
+			if tool != "wkhtmltopdf" {
+				t.Errorf("tool = %q, want wkhtmltopdf", tool)
+			}
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("ConvertToPDF() error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error = %v, want it to contain %q", err, tt.wantErr)
+			}
+			if strings.Contains(tt.script, ">&2") && !strings.Contains(err.Error(), "Warning: blocked access") && !strings.Contains(err.Error(), ": Done") {
+				t.Errorf("expected the tool output in the error, got %v", err)
+			}
+			if _, err := os.Stat(pdfPath); !os.IsNotExist(err) {
+				t.Errorf("invalid PDF should be removed, stat err = %v", err)
+			}
+		})
+	}
+}
+
+func TestConvertToPDF_PreferredTool(t *testing.T) {
+	binDir := t.TempDir()
+	writeFakeTool(t, binDir, "wkhtmltopdf", "#!/bin/sh\nexit 1\n")
+	writeFakeTool(t, binDir, "google-chrome", fakeBrowserScript)
+	isolatePDFTools(t, binDir)
+
+	outDir := t.TempDir()
+	htmlPath := writeTestHTML(t, outDir)
+	tool, err := invoice.ConvertToPDF(context.Background(), htmlPath, filepath.Join(outDir, "invoice.pdf"), invoice.PDFOptions{Tool: "chrome"})
+	if err != nil {
+		t.Fatalf("ConvertToPDF() error: %v", err)
+	}
+	if tool != "chrome" {
+		t.Errorf("tool = %q, want chrome", tool)
+	}
+}
+
+func TestConvertToPDF_FallsBackToWeasyPrintAndPandoc(t *testing.T) {
+	for _, bins := range [][]string{{"weasyprint", "pandoc"}, {"pandoc"}} {
+		t.Run(bins[0], func(t *testing.T) {
+			binDir := t.TempDir()
+			for _, bin := range bins {
+				writeFakeTool(t, binDir, bin, fakeBrowserScript)
+			}
+			isolatePDFTools(t, binDir)
+
+			outDir := t.TempDir()
+			htmlPath := writeTestHTML(t, outDir)
+			pdfPath := filepath.Join(outDir, "invoice.pdf")
+			tool, err := invoice.ConvertToPDF(context.Background(), htmlPath, pdfPath, invoice.PDFOptions{})
+			if err != nil {
+				t.Fatalf("ConvertToPDF() error: %v", err)
+			}
+			if tool != bins[0] {
+				t.Errorf("tool = %q, want %q", tool, bins[0])
+			}
+			if err := invoice.VerifyPDF(pdfPath); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestConvertToPDF_PreferredToolMissing(t *testing.T) {
+	binDir := t.TempDir()
+	writeFakeTool(t, binDir, "wkhtmltopdf", fakeWkhtmltopdfScript)
+	isolatePDFTools(t, binDir)
+
+	outDir := t.TempDir()
+	htmlPath := writeTestHTML(t, outDir)
+	pdfPath := filepath.Join(outDir, "invoice.pdf")
+	_, err := invoice.ConvertToPDF(context.Background(), htmlPath, pdfPath, invoice.PDFOptions{Tool: "chromium"})
+	var missing *invoice.NotInstalledError
+	if !errors.As(err, &missing) || missing.Tool != "chromium" {
+		t.Fatalf("expected chromium to be reported missing, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "looked for chromium, chromium-browser") || !strings.Contains(err.Error(), "pdf_tool: auto") {
+		t.Errorf("unexpected message:\n%v", err)
+	}
+	if _, err := os.Stat(pdfPath); !os.IsNotExist(err) {
+		t.Errorf("wkhtmltopdf should not have been used as a fallback, stat err = %v", err)
+	}
+}
+
+func TestConvertToPDF_ToolPath(t *testing.T) {
+	isolatePDFTools(t, t.TempDir())
+	elsewhere := t.TempDir()
+	chrome := writeFakeTool(t, elsewhere, "google-chrome", fakeBrowserScript)
+	browser := writeFakeTool(t, elsewhere, "browser", fakeBrowserScript)
+	tests := []struct {
+		name     string
+		opts     invoice.PDFOptions
+		wantTool string
+		wantErr  string
+	}{
+		{"named by its executable", invoice.PDFOptions{ToolPath: chrome}, "chrome", ""},
+		{"explicit tool", invoice.PDFOptions{Tool: "edge", ToolPath: browser}, "edge", ""},
+		{"unrecognized executable", invoice.PDFOptions{ToolPath: browser}, "", "cannot tell which PDF tool"},
+		{"missing executable", invoice.PDFOptions{Tool: "chrome", ToolPath: filepath.Join(elsewhere, "nope")}, "", "chrome: stat"},
+		{"directory", invoice.PDFOptions{Tool: "chrome", ToolPath: elsewhere}, "", "is a directory"},
+		{"unknown tool", invoice.PDFOptions{Tool: "prince"}, "", `unknown PDF tool "prince"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outDir := t.TempDir()
+			htmlPath := writeTestHTML(t, outDir)
+			tool, err := invoice.ConvertToPDF(context.Background(), htmlPath, filepath.Join(outDir, "invoice.pdf"), tt.opts)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ConvertToPDF() error: %v", err)
+			}
+			if tool != tt.wantTool {
+				t.Errorf("tool = %q, want %q", tool, tt.wantTool)
+			}
+		})
+	}
+}
+
+func TestConvertToPDF_ExtraArgs(t *testing.T) {
+	extra := []string{"--enable-local-file-access", "--footer-center=Page [page] of [topage]"}
+	tests := []struct {
+		tool string
+		bin  string
+		want func(htmlPath, pdfPath string) []string
+	}{
+		{"wkhtmltopdf", "wkhtmltopdf", func(htmlPath, pdfPath string) []string {
+			return append(slices.Clone(extra), htmlPath, pdfPath)
+		}},
+		{"chromium", "chromium", headlessWant(extra)},
+		{"chrome", "google-chrome", headlessWant(extra)},
+		{"edge", "msedge", headlessWant(extra)},
+		{"weasyprint", "weasyprint", func(htmlPath, pdfPath string) []string {
+			return append(slices.Clone(extra), htmlPath, pdfPath)
+		}},
+		{"pandoc", "pandoc", func(htmlPath, pdfPath string) []string {
+			return append(slices.Clone(extra), htmlPath, "-o", pdfPath)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tool, func(t *testing.T) {
+			binDir := t.TempDir()
+			writeFakeTool(t, binDir, tt.bin, fakeBrowserScript)
+			isolatePDFTools(t, binDir)
+
+			outDir := filepath.Join(t.TempDir(), "Acme Corp")
+			if err := os.MkdirAll(outDir, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			htmlPath := writeTestHTML(t, outDir)
+			pdfPath := filepath.Join(outDir, "invoice.pdf")
+			if _, err := invoice.ConvertToPDF(context.Background(), htmlPath, pdfPath, invoice.PDFOptions{Tool: tt.tool, Args: extra}); err != nil {
+				t.Fatalf("ConvertToPDF() error: %v", err)
+			}
+			data, err := os.ReadFile(filepath.Join(binDir, "args.txt"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+			if want := tt.want(htmlPath, pdfPath); !slices.Equal(got, want) {
+				t.Errorf("argv = %q\nwant %q", got, want)
+			}
+		})
+	}
+}
+
+// headlessWant returns the argv expected of a headless browser given extra.
+func headlessWant(extra []string) func(htmlPath, pdfPath string) []string {
+	return func(htmlPath, pdfPath string) []string {
+		args := append([]string{"--headless", "--disable-gpu", "--no-sandbox"}, extra...)
+		return append(args, "--print-to-pdf="+pdfPath, invoice.FileURL(htmlPath))
+	}
+}
+
+func TestCheckPDFTool(t *testing.T) {
+	for _, name := range append([]string{"", invoice.AutoPDFTool}, invoice.PDFTools()...) {
+		if err := invoice.CheckPDFTool(name); err != nil {
+			t.Errorf("CheckPDFTool(%q): %v", name, err)
+		}
+	}
+	if err := invoice.CheckPDFTool("Chrome"); err == nil || !strings.Contains(err.Error(), "auto, wkhtmltopdf, chromium, chrome, edge, weasyprint, pandoc") {
+		t.Errorf("expected the choices in the error, got %v", err)
+	}
+}
+
+func TestFileURL(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/tmp/invoice.html", "file:///tmp/invoice.html"},
+		{"/home/jane/Invoices/Acme Corp/2025/invoice.html", "file:///home/jane/Invoices/Acme%20Corp/2025/invoice.html"},
+		{"/home/jane/Invoices/Client #2/invoice.html", "file:///home/jane/Invoices/Client%20%232/invoice.html"},
+		{"/home/jane/Invoices/100% Pure/invoice.html", "file:///home/jane/Invoices/100%25%20Pure/invoice.html"},
+		{"/home/jane/Rechnungen/Müller GmbH/invoice.html", "file:///home/jane/Rechnungen/M%C3%BCller%20GmbH/invoice.html"},
+		{`C:\Users\jane\invoice.html`, "file:///C:/Users/jane/invoice.html"},
+		{"D:/invoices/invoice.html", "file:///D:/invoices/invoice.html"},
+		{`C:\Users\jane\Acme Corp #2\invoice.html`, "file:///C:/Users/jane/Acme%20Corp%20%232/invoice.html"},
+		{`\\fileserver\invoices\Acme Corp\invoice.html`, "file://fileserver/invoices/Acme%20Corp/invoice.html"},
+	}
+	for _, tt := range tests {
+		got := invoice.FileURL(tt.path)
+		if got != tt.want {
+			t.Errorf("FileURL(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+		if u, err := url.Parse(got); err != nil {
+			t.Errorf("url.Parse(%q): %v", got, err)
+		} else if !strings.HasSuffix(u.Path, "invoice.html") {
+			t.Errorf("FileURL(%q) parses back to path %q", tt.path, u.Path)
+		}
+	}
+}
+
+func TestFileURL_Relative(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "Acme Corp")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(dir)
+	got := invoice.FileURL("invoice.html")
+	if want := invoice.FileURL(filepath.Join(dir, "invoice.html")); got != want {
+		t.Errorf("FileURL = %q, want %q", got, want)
+	}
+	if !strings.Contains(got, "/Acme%20Corp/invoice.html") {
+		t.Errorf("FileURL = %q, want the absolute, escaped path", got)
+	}
+}
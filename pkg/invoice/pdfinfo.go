@@ -0,0 +1,490 @@
+package invoice
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// PDFInfo is the document information a PDF viewer shows as the file's
+// properties.
+type PDFInfo struct {
+	Title        string
+	Author       string
+	Subject      string
+	Creator      string
+	CreationDate time.Time
+}
+
+// DefaultPDFTitle is the PDF title pattern used when none is configured.
+const DefaultPDFTitle = "Invoice {number} — {customer} — {month} {year}"
+
+// pdfTitlePlaceholder matches a placeholder in a PDF title pattern.
+var pdfTitlePlaceholder = regexp.MustCompile(`\{[^{}]*\}`)
+
+// pdfTitleFields lists the placeholders a PDF title pattern may use.
+var pdfTitleFields = []string{"{number}", "{vendor}", "{customer}", "{month}", "{year}"}
+
+// CheckPDFTitle returns an error if pattern uses an unknown placeholder.
+func CheckPDFTitle(pattern string) error {
+	for _, p := range pdfTitlePlaceholder.FindAllString(pattern, -1) {
+		if !slices.Contains(pdfTitleFields, p) {
+			return fmt.Errorf("unknown placeholder %s in the PDF title (use %s)", p, strings.Join(pdfTitleFields, ", "))
+		}
+	}
+	return nil
+}
+
+// PDFTitle expands the placeholders in pattern for inv, e.g.
+// "Invoice {number} — {customer} — {month} {year}" becomes
+// "Invoice INV-202501-acme-corp — Acme Corp — January 2025".
+func PDFTitle(pattern string, inv *Invoice) string {
+	return strings.NewReplacer(
+		"{number}", InvoiceNumber(inv),
+		"{vendor}", inv.Vendor,
+		"{customer}", inv.Customer,
+		"{month}", inv.Month.String(),
+		"{year}", strconv.Itoa(inv.Year),
+	).Replace(pattern)
+}
+
+// NewPDFInfo returns the document information for inv's PDF, titled by
+// expanding pattern and created at created.
+func NewPDFInfo(inv *Invoice, pattern string, created time.Time) PDFInfo {
+	return PDFInfo{
+		Title:        PDFTitle(pattern, inv),
+		Author:       inv.Vendor,
+		Subject:      fmt.Sprintf("Invoice from %s to %s for %s %d", inv.Vendor, inv.Customer, inv.Month, inv.Year),
+		Creator:      "invoicer",
+		CreationDate: created,
+	}
+}
+
+// SetPDFInfo replaces the document information of the PDF at path.
+// It appends an incremental update, so the pages the tool wrote are left
+// byte for byte as they were, then reads the information back to check it.
+// The file is only replaced once the check passes. PDFs that are encrypted
+// or use a cross-reference stream are not supported.
+func SetPDFInfo(path string, info PDFInfo) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	update, err := pdfInfoUpdate(data, info)
+	if err != nil {
+		return err
+	}
+	stat, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	staged := stagingPath(path)
+	defer os.Remove(staged)
+	if err := os.WriteFile(staged, append(data, update...), stat.Mode().Perm()); err != nil {
+		return err
+	}
+	got, err := ReadPDFInfo(staged)
+	if err != nil {
+		return fmt.Errorf("reading back the metadata: %w", err)
+	}
+	if !got.equal(info) {
+		return fmt.Errorf("the metadata read back as %+v, not %+v", got, info)
+	}
+	return os.Rename(staged, path)
+}
+
+// equal reports whether i and o match, comparing dates to the second as
+// PDF dates store them.
+func (i PDFInfo) equal(o PDFInfo) bool {
+	return i.Title == o.Title && i.Author == o.Author && i.Subject == o.Subject && i.Creator == o.Creator &&
+		i.CreationDate.Truncate(time.Second).Equal(o.CreationDate.Truncate(time.Second))
+}
+
+// pdfInfoUpdate returns an incremental update to append to data that adds
+// an Info dictionary for info and points a new trailer at it.
+func pdfInfoUpdate(data []byte, info PDFInfo) ([]byte, error) {
+	xref, err := pdfStartXref(data)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(data[xref:], []byte("xref")) {
+		return nil, errors.New("the PDF uses a cross-reference stream, which is not supported")
+	}
+	trailer, err := pdfTrailer(data, xref)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := trailer["Encrypt"]; ok {
+		return nil, errors.New("the PDF is encrypted")
+	}
+	size, err := strconv.Atoi(trailer["Size"])
+	if err != nil {
+		return nil, fmt.Errorf("the PDF trailer has no valid /Size: %q", trailer["Size"])
+	}
+	root, ok := trailer["Root"]
+	if !ok {
+		return nil, errors.New("the PDF trailer has no /Root")
+	}
+
+	var b bytes.Buffer
+	if !bytes.HasSuffix(data, []byte("\n")) {
+		b.WriteByte('\n')
+	}
+	obj := len(data) + b.Len()
+	fmt.Fprintf(&b, "%d 0 obj\n<< /Title %s /Author %s /Subject %s /Creator %s /CreationDate %s /ModDate %[6]s >>\nendobj\n",
+		size, pdfString(info.Title), pdfString(info.Author), pdfString(info.Subject), pdfString(info.Creator), pdfString(pdfDate(info.CreationDate)))
+	newXref := len(data) + b.Len()
+	fmt.Fprintf(&b, "xref\n%d 1\n%010d 00000 n\r\ntrailer\n<< /Size %d /Root %s /Info %d 0 R /Prev %d", size, obj, size+1, root, size, xref)
+	if id, ok := trailer["ID"]; ok {
+		fmt.Fprintf(&b, " /ID %s", id)
+	}
+	fmt.Fprintf(&b, " >>\nstartxref\n%d\n%%%%EOF\n", newXref)
+	return b.Bytes(), nil
+}
+
+// ReadPDFInfo returns the document information of the PDF at path, or a
+// zero PDFInfo if it has none.
+func ReadPDFInfo(path string) (PDFInfo, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return PDFInfo{}, err
+	}
+	xref, err := pdfStartXref(data)
+	if err != nil {
+		return PDFInfo{}, err
+	}
+	trailer, err := pdfTrailer(data, xref)
+	if err != nil {
+		return PDFInfo{}, err
+	}
+	ref, ok := trailer["Info"]
+	if !ok {
+		return PDFInfo{}, nil
+	}
+	fields := strings.Fields(ref)
+	if len(fields) != 3 || fields[2] != "R" {
+		return PDFInfo{}, fmt.Errorf("the PDF trailer has an invalid /Info %q", ref)
+	}
+	start := regexp.MustCompile(`(?:^|\s)` + regexp.QuoteMeta(fields[0]) + `\s+` + regexp.QuoteMeta(fields[1]) + `\s+obj\b`)
+	found := start.FindAllIndex(data, -1)
+	if found == nil {
+		return PDFInfo{}, fmt.Errorf("the PDF has no object %s %s", fields[0], fields[1])
+	}
+	dict, err := pdfDict(data, found[len(found)-1][1])
+	if err != nil {
+		return PDFInfo{}, fmt.Errorf("parsing the Info dictionary: %w", err)
+	}
+
+	var info PDFInfo
+	for key, dst := range map[string]*string{"Title": &info.Title, "Author": &info.Author, "Subject": &info.Subject, "Creator": &info.Creator} {
+		if raw, ok := dict[key]; ok {
+			if *dst, err = pdfDecodeString(raw); err != nil {
+				return PDFInfo{}, fmt.Errorf("/%s: %w", key, err)
+			}
+		}
+	}
+	if raw, ok := dict["CreationDate"]; ok {
+		s, err := pdfDecodeString(raw)
+		if err != nil {
+			return PDFInfo{}, fmt.Errorf("/CreationDate: %w", err)
+		}
+		if info.CreationDate, err = parsePDFDate(s); err != nil {
+			return PDFInfo{}, fmt.Errorf("/CreationDate: %w", err)
+		}
+	}
+	return info, nil
+}
+
+// pdfStartXref returns the offset of the last cross-reference section.
+func pdfStartXref(data []byte) (int, error) {
+	i := bytes.LastIndex(data, []byte("startxref"))
+	if i < 0 {
+		return 0, errors.New("the PDF has no startxref")
+	}
+	fields := strings.Fields(string(data[i+len("startxref"):]))
+	if len(fields) == 0 {
+		return 0, errors.New("the PDF has no startxref offset")
+	}
+	off, err := strconv.Atoi(fields[0])
+	if err != nil || off < 0 || off >= len(data) {
+		return 0, fmt.Errorf("the PDF has an invalid startxref offset %q", fields[0])
+	}
+	return off, nil
+}
+
+// pdfTrailer returns the trailer dictionary of the cross-reference section
+// at xref: the one after the trailer keyword of a table, or the stream
+// dictionary of a cross-reference stream.
+func pdfTrailer(data []byte, xref int) (map[string]string, error) {
+	var start int
+	if bytes.HasPrefix(data[xref:], []byte("xref")) {
+		i := bytes.Index(data[xref:], []byte("trailer"))
+		if i < 0 {
+			return nil, errors.New("the PDF has no trailer")
+		}
+		start = xref + i + len("trailer")
+	} else {
+		i := bytes.Index(data[xref:], []byte("<<"))
+		if i < 0 {
+			return nil, errors.New("the PDF cross-reference stream has no dictionary")
+		}
+		start = xref + i
+	}
+	dict, err := pdfDict(data, start)
+	if err != nil {
+		return nil, fmt.Errorf("parsing the trailer: %w", err)
+	}
+	return dict, nil
+}
+
+// pdfDict parses the dictionary at data[i:], after any white space, and
+// returns the raw text of each value by key.
+func pdfDict(data []byte, i int) (map[string]string, error) {
+	i = pdfSkipSpace(data, i)
+	if !bytes.HasPrefix(data[i:], []byte("<<")) {
+		return nil, fmt.Errorf("expected a dictionary at offset %d", i)
+	}
+	dict := make(map[string]string)
+	i += 2
+	for {
+		i = pdfSkipSpace(data, i)
+		if i >= len(data) {
+			return nil, errors.New("unterminated dictionary")
+		}
+		if bytes.HasPrefix(data[i:], []byte(">>")) {
+			return dict, nil
+		}
+		if data[i] != '/' {
+			return nil, fmt.Errorf("expected a name at offset %d", i)
+		}
+		end := pdfValueEnd(data, i)
+		key := string(data[i+1 : end])
+		i = pdfSkipSpace(data, end)
+		end = pdfValueEnd(data, i)
+		if end <= i {
+			return nil, fmt.Errorf("missing value for /%s", key)
+		}
+		dict[key] = string(data[i:end])
+		i = end
+	}
+}
+
+// pdfRef matches the generation and R that follow an object number in an
+// indirect reference.
+var pdfRef = regexp.MustCompile(`^\s+\d+\s+R\b`)
+
+// pdfValueEnd returns the offset just past the value that starts at data[i].
+func pdfValueEnd(data []byte, i int) int {
+	if i >= len(data) {
+		return i
+	}
+	switch {
+	case data[i] == '(':
+		depth := 0
+		for ; i < len(data); i++ {
+			switch data[i] {
+			case '\\':
+				i++
+			case '(':
+				depth++
+			case ')':
+				if depth--; depth == 0 {
+					return i + 1
+				}
+			}
+		}
+		return i
+	case bytes.HasPrefix(data[i:], []byte("<<")):
+		for i = pdfSkipSpace(data, i+2); i < len(data) && !bytes.HasPrefix(data[i:], []byte(">>")); i = pdfSkipSpace(data, i) {
+			i = pdfValueEnd(data, i)
+		}
+		return min(i+2, len(data))
+	case data[i] == '<':
+		if end := bytes.IndexByte(data[i:], '>'); end >= 0 {
+			return i + end + 1
+		}
+		return len(data)
+	case data[i] == '[':
+		for i = pdfSkipSpace(data, i+1); i < len(data) && data[i] != ']'; i = pdfSkipSpace(data, i) {
+			i = pdfValueEnd(data, i)
+		}
+		return min(i+1, len(data))
+	}
+	end := i + 1
+	for end < len(data) && !pdfDelimiter(data[end]) {
+		end++
+	}
+	if _, err := strconv.Atoi(string(data[i:end])); err == nil {
+		if m := pdfRef.Find(data[end:]); m != nil {
+			end += len(m)
+		}
+	}
+	return end
+}
+
+// pdfDelimiter reports whether c ends a name, number or keyword.
+func pdfDelimiter(c byte) bool {
+	return strings.IndexByte(" \t\r\n\f\x00()<>[]{}/%", c) >= 0
+}
+
+// pdfSkipSpace returns the offset of the first byte at or after i that is
+// not white space or part of a comment.
+func pdfSkipSpace(data []byte, i int) int {
+	for i < len(data) {
+		switch data[i] {
+		case ' ', '\t', '\r', '\n', '\f', 0:
+			i++
+		case '%':
+			for i < len(data) && data[i] != '\n' && data[i] != '\r' {
+				i++
+			}
+		default:
+			return i
+		}
+	}
+	return i
+}
+
+// pdfString encodes s as a PDF string: a literal string if it is plain
+// ASCII, or UTF-16 with a byte order mark otherwise.
+func pdfString(s string) string {
+	ascii := true
+	for _, r := range s {
+		if r < 0x20 || r > 0x7e {
+			ascii = false
+			break
+		}
+	}
+	if ascii {
+		return "(" + strings.NewReplacer(`\`, `\\`, "(", `\(`, ")", `\)`).Replace(s) + ")"
+	}
+	var b strings.Builder
+	b.WriteString("<FEFF")
+	for _, u := range utf16.Encode([]rune(s)) {
+		fmt.Fprintf(&b, "%04X", u)
+	}
+	b.WriteString(">")
+	return b.String()
+}
+
+// pdfDecodeString decodes a literal or hexadecimal PDF string.
+func pdfDecodeString(raw string) (string, error) {
+	var data []byte
+	switch {
+	case strings.HasPrefix(raw, "(") && strings.HasSuffix(raw, ")"):
+		data = pdfUnescape(raw[1 : len(raw)-1])
+	case strings.HasPrefix(raw, "<") && strings.HasSuffix(raw, ">"):
+		digits := strings.Join(strings.Fields(raw[1:len(raw)-1]), "")
+		if len(digits)%2 == 1 {
+			digits += "0"
+		}
+		var err error
+		if data, err = hex.DecodeString(digits); err != nil {
+			return "", err
+		}
+	default:
+		return "", fmt.Errorf("%q is not a string", raw)
+	}
+	if len(data) >= 2 && data[0] == 0xFE && data[1] == 0xFF {
+		units := make([]uint16, 0, len(data)/2)
+		for i := 2; i+1 < len(data); i += 2 {
+			units = append(units, uint16(data[i])<<8|uint16(data[i+1]))
+		}
+		return string(utf16.Decode(units)), nil
+	}
+	runes := make([]rune, len(data))
+	for i, c := range data {
+		runes[i] = rune(c)
+	}
+	return string(runes), nil
+}
+
+// pdfUnescape resolves the escape sequences of a literal string's contents.
+func pdfUnescape(s string) []byte {
+	var out []byte
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			out = append(out, s[i])
+			continue
+		}
+		i++
+		switch c := s[i]; c {
+		case 'n':
+			out = append(out, '\n')
+		case 'r':
+			out = append(out, '\r')
+		case 't':
+			out = append(out, '\t')
+		case 'b':
+			out = append(out, '\b')
+		case 'f':
+			out = append(out, '\f')
+		case '\r':
+			if i+1 < len(s) && s[i+1] == '\n' {
+				i++
+			}
+		case '\n':
+		default:
+			if c >= '0' && c <= '7' {
+				n := 0
+				j := i
+				for ; j < len(s) && j < i+3 && s[j] >= '0' && s[j] <= '7'; j++ {
+					n = n*8 + int(s[j]-'0')
+				}
+				out = append(out, byte(n))
+				i = j - 1
+				continue
+			}
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// pdfDate formats t as a PDF date, e.g. "D:20250131120000+01'00'".
+func pdfDate(t time.Time) string {
+	_, offset := t.Zone()
+	sign := '+'
+	if offset < 0 {
+		sign, offset = '-', -offset
+	}
+	return fmt.Sprintf("D:%s%c%02d'%02d'", t.Format("20060102150405"), sign, offset/3600, offset/60%60)
+}
+
+// parsePDFDate parses a PDF date with a full date and time, as pdfDate
+// writes it. A missing time zone is taken as UTC.
+func parsePDFDate(s string) (time.Time, error) {
+	s = strings.TrimPrefix(s, "D:")
+	if len(s) < 14 {
+		return time.Time{}, fmt.Errorf("invalid PDF date %q", s)
+	}
+	t, err := time.Parse("20060102150405", s[:14])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid PDF date %q", s)
+	}
+	zone := strings.ReplaceAll(s[14:], "'", "")
+	switch {
+	case zone == "" || zone == "Z" || strings.HasPrefix(zone, "Z"):
+		return t, nil
+	case len(zone) == 5 && (zone[0] == '+' || zone[0] == '-'):
+		hours, err1 := strconv.Atoi(zone[1:3])
+		minutes, err2 := strconv.Atoi(zone[3:5])
+		if err1 != nil || err2 != nil {
+			return time.Time{}, fmt.Errorf("invalid time zone in PDF date %q", s)
+		}
+		offset := hours*3600 + minutes*60
+		if zone[0] == '-' {
+			offset = -offset
+		}
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.FixedZone("", offset)), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time zone in PDF date %q", s)
+}
@@ -0,0 +1,163 @@
+package invoice_test
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+// minimalPDF returns a one-page PDF with a valid cross-reference table.
+// trailerExtra is added to its trailer dictionary, and extra objects are
+// numbered from 4.
+func minimalPDF(trailerExtra string, extra ...string) []byte {
+	objects := append([]string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] >>",
+	}, extra...)
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = b.Len()
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f\r\n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n\r\n", off)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root 1 0 R%s >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, trailerExtra, xref)
+	return b.Bytes()
+}
+
+func writePDF(t *testing.T, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "invoice.pdf")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestSetPDFInfo(t *testing.T) {
+	original := minimalPDF(" /ID [<0123> <4567>]")
+	path := writePDF(t, original)
+	created := time.Date(2025, time.February, 1, 9, 30, 0, 0, time.FixedZone("", -5*3600))
+	want := invoice.NewPDFInfo(testInvoice(), invoice.DefaultPDFTitle, created)
+
+	if err := invoice.SetPDFInfo(path, want); err != nil {
+		t.Fatalf("SetPDFInfo: %v", err)
+	}
+	got, err := invoice.ReadPDFInfo(path)
+	if err != nil {
+		t.Fatalf("ReadPDFInfo: %v", err)
+	}
+	if got.Title != "Invoice INV-202501-acme-corp — Acme Corp — January 2025" {
+		t.Errorf("Title = %q", got.Title)
+	}
+	if got.Author != "Jane Contractor" || got.Creator != "invoicer" {
+		t.Errorf("Author = %q, Creator = %q", got.Author, got.Creator)
+	}
+	if got.Subject != "Invoice from Jane Contractor to Acme Corp for January 2025" {
+		t.Errorf("Subject = %q", got.Subject)
+	}
+	if !got.CreationDate.Equal(created) {
+		t.Errorf("CreationDate = %s, want %s", got.CreationDate, created)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(data, original) {
+		t.Error("the original bytes should be kept, with the metadata appended")
+	}
+	update := string(data[len(original):])
+	for _, s := range []string{"/Info 4 0 R", "/Size 5", "/ID [<0123> <4567>]", "/CreationDate (D:20250201093000-05'00')"} {
+		if !strings.Contains(update, s) {
+			t.Errorf("update lacks %q:\n%s", s, update)
+		}
+	}
+	if err := invoice.VerifyPDF(path); err != nil {
+		t.Errorf("VerifyPDF after SetPDFInfo: %v", err)
+	}
+}
+
+func TestSetPDFInfo_Twice(t *testing.T) {
+	path := writePDF(t, minimalPDF(""))
+	created := time.Date(2025, time.February, 1, 0, 0, 0, 0, time.UTC)
+	for _, title := range []string{"First (draft)", `Second \ final`} {
+		if err := invoice.SetPDFInfo(path, invoice.PDFInfo{Title: title, CreationDate: created}); err != nil {
+			t.Fatalf("SetPDFInfo(%q): %v", title, err)
+		}
+	}
+	got, err := invoice.ReadPDFInfo(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Title != `Second \ final` {
+		t.Errorf("Title = %q, want the latest", got.Title)
+	}
+}
+
+func TestSetPDFInfo_Unsupported(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr string
+	}{
+		{"encrypted", minimalPDF(" /Encrypt 9 0 R"), "encrypted"},
+		{"no startxref", []byte("%PDF-1.4\n%%EOF\n"), "no startxref"},
+		{"xref stream", []byte("%PDF-1.5\n1 0 obj\n<< /Type /XRef /Size 2 /Root 2 0 R >>\nstream\nendstream\nendobj\nstartxref\n9\n%%EOF\n"), "cross-reference stream"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writePDF(t, tt.data)
+			err := invoice.SetPDFInfo(path, invoice.PDFInfo{Title: "x"})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error = %v, want %q", err, tt.wantErr)
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(data, tt.data) {
+				t.Error("the PDF should be left untouched")
+			}
+			if staged, _ := filepath.Glob(path + ".tmp-*"); len(staged) > 0 {
+				t.Errorf("staging files left behind: %v", staged)
+			}
+		})
+	}
+}
+
+func TestReadPDFInfo_ToolMetadata(t *testing.T) {
+	pdf := minimalPDF(" /Info 4 0 R", `<< /Title (Acme \(draft\)\051) /Producer (Qt 4.8.7) /Nested << /A [1 (]) <41>] >> /Author <FEFF004A0061006E0065> >>`)
+	got, err := invoice.ReadPDFInfo(writePDF(t, pdf))
+	if err != nil {
+		t.Fatalf("ReadPDFInfo: %v", err)
+	}
+	if got.Title != "Acme (draft))" || got.Author != "Jane" {
+		t.Errorf("got %+v", got)
+	}
+}
+
+func TestPDFTitle(t *testing.T) {
+	got := invoice.PDFTitle("{vendor}: {number} ({month} {year}) for {customer}", testInvoice())
+	if want := "Jane Contractor: INV-202501-acme-corp (January 2025) for Acme Corp"; got != want {
+		t.Errorf("PDFTitle = %q, want %q", got, want)
+	}
+	if err := invoice.CheckPDFTitle(invoice.DefaultPDFTitle); err != nil {
+		t.Errorf("CheckPDFTitle(default): %v", err)
+	}
+	if err := invoice.CheckPDFTitle("Invoice {invoice_number}"); err == nil || !strings.Contains(err.Error(), "{invoice_number}") {
+		t.Errorf("expected an unknown placeholder error, got %v", err)
+	}
+}
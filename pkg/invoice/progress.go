@@ -0,0 +1,61 @@
+package invoice
+
+// ProgressKind is what a ProgressEvent reports.
+type ProgressKind string
+
+// The kinds of progress events, in the order a generation reports them.
+const (
+	// ProgressPromptBuilt: the backend has built its prompt.
+	ProgressPromptBuilt ProgressKind = "prompt_built"
+	// ProgressStarted: the backend's subprocess, or its request to the
+	// server, has started.
+	ProgressStarted ProgressKind = "started"
+	// ProgressToolUse: the backend's agent used a tool, named in Tool, on
+	// the file Path if it names one. Only the opencode backend reports
+	// tool use, as it happens.
+	ProgressToolUse ProgressKind = "tool_use"
+	// ProgressVerified: the invoice written to Path passed VerifyHTML.
+	ProgressVerified ProgressKind = "verified"
+	// ProgressFailed: the attempt failed with Err. Another attempt may
+	// follow.
+	ProgressFailed ProgressKind = "failed"
+	// ProgressPDFStarted: ConvertToPDF has started converting to Path.
+	ProgressPDFStarted ProgressKind = "pdf_started"
+	// ProgressPDFFinished: ConvertToPDF has finished with Tool, writing
+	// Path, or failed with Err.
+	ProgressPDFFinished ProgressKind = "pdf_finished"
+)
+
+// ProgressEvent is a step of a generation or a PDF conversion, for
+// GenerateOptions.Progress and PDFOptions.Progress.
+type ProgressEvent struct {
+	Kind ProgressKind
+	// Backend, Model and Attempt identify the generation attempt, which
+	// counts from 1 across every model. They are unset for PDF events.
+	Backend string
+	Model   string
+	Attempt int
+	// Tool is the tool used, for ProgressToolUse, or the PDF tool, for
+	// ProgressPDFFinished.
+	Tool string
+	// Path is the file the event is about, if any: the invoice being
+	// written, never the staging file Generate writes it to first.
+	Path string
+	// Err is the error of ProgressFailed and of a failed
+	// ProgressPDFFinished.
+	Err error
+}
+
+// progress reports e to opts.Progress, if set.
+func (opts GenerateOptions) progress(e ProgressEvent) {
+	if opts.Progress != nil {
+		opts.Progress(e)
+	}
+}
+
+// progress reports e to opts.Progress, if set.
+func (opts PDFOptions) progress(e ProgressEvent) {
+	if opts.Progress != nil {
+		opts.Progress(e)
+	}
+}
@@ -0,0 +1,113 @@
+package invoice_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+// recordProgress returns a Progress callback and the events it records,
+// each written as kind, attempt, tool and path.
+func recordProgress() (func(invoice.ProgressEvent), *[]string) {
+	var events []string
+	return func(e invoice.ProgressEvent) {
+		s := fmt.Sprintf("%s %d", e.Kind, e.Attempt)
+		if e.Tool != "" {
+			s += " " + e.Tool
+		}
+		if e.Path != "" {
+			s += " " + filepath.Base(e.Path)
+		}
+		if e.Err != nil {
+			s += " error"
+		}
+		events = append(events, s)
+	}, &events
+}
+
+func TestGenerate_ProgressSuccess(t *testing.T) {
+	orig := invoice.OpencodeExec
+	t.Cleanup(func() { invoice.OpencodeExec = orig })
+	invoice.OpencodeExec = func(ctx context.Context, r invoice.OpencodeRun, stdout io.Writer) error {
+		path := promptedPath(r.Prompt)
+		io.WriteString(stdout, `{"type":"tool_use","part":{"tool":"read","state":{"status":"completed","input":{"filePath":"notes.md"}}}}`+"\n")
+		io.WriteString(stdout, `{"type":"tool_use","part":{"tool":"write","state":{"status":"running","input":{"filePath":"`+path+`"}}}}`+"\n")
+		if err := os.WriteFile(path, []byte("<html>ok</html>"), 0o644); err != nil {
+			return err
+		}
+		io.WriteString(stdout, `{"type":"tool_use","part":{"tool":"write","state":{"status":"completed","input":{"filePath":"`+path+`"}}}}`+"\n")
+		return nil
+	}
+
+	progress, events := recordProgress()
+	opts := invoice.GenerateOptions{Model: "anthropic/claude-haiku-4-5", Progress: progress}
+	if _, err := invoice.Generate(context.Background(), "opencode", testInvoice(), filepath.Join(t.TempDir(), "invoice.html"), opts); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"prompt_built 1",
+		"started 1",
+		"tool_use 1 read notes.md",
+		"tool_use 1 write invoice.html",
+		"verified 1 invoice.html",
+	}
+	if !slices.Equal(*events, want) {
+		t.Errorf("events = %q, want %q", *events, want)
+	}
+}
+
+func TestGenerate_ProgressFailure(t *testing.T) {
+	orig := invoice.OpencodeExec
+	t.Cleanup(func() { invoice.OpencodeExec = orig })
+	invoice.OpencodeExec = func(ctx context.Context, r invoice.OpencodeRun, stdout io.Writer) error {
+		io.WriteString(stdout, `{"type":"text","part":{"text":"I cannot do that."}}`+"\n")
+		return nil
+	}
+	fakeSleep(t)
+
+	progress, events := recordProgress()
+	var models []string
+	opts := invoice.GenerateOptions{Model: "anthropic/claude-haiku-4-5", Retries: 1, Progress: func(e invoice.ProgressEvent) {
+		models = append(models, e.Model)
+		progress(e)
+	}}
+	if _, err := invoice.Generate(context.Background(), "opencode", testInvoice(), filepath.Join(t.TempDir(), "invoice.html"), opts); err == nil {
+		t.Fatal("expected an error")
+	}
+	want := []string{
+		"prompt_built 1",
+		"started 1",
+		"failed 1 error",
+		"prompt_built 2",
+		"started 2",
+		"failed 2 error",
+	}
+	if !slices.Equal(*events, want) {
+		t.Errorf("events = %q, want %q", *events, want)
+	}
+	for _, m := range models {
+		if m != "anthropic/claude-haiku-4-5" {
+			t.Errorf("event model = %q", m)
+		}
+	}
+}
+
+func TestConvertToPDF_Progress(t *testing.T) {
+	progress, events := recordProgress()
+	dir := t.TempDir()
+	_, err := invoice.ConvertToPDF(context.Background(), filepath.Join(dir, "invoice.html"), filepath.Join(dir, "invoice.pdf"),
+		invoice.PDFOptions{Engine: "nope", Progress: progress})
+	if err == nil {
+		t.Fatal("expected an error for an unknown engine")
+	}
+	want := []string{"pdf_started 0 invoice.pdf", "pdf_finished 0 invoice.pdf error"}
+	if !slices.Equal(*events, want) {
+		t.Errorf("events = %q, want %q", *events, want)
+	}
+}
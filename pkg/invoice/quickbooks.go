@@ -0,0 +1,128 @@
+package invoice
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// QuickBooks defaults for the accounts an exported invoice is booked to.
+const (
+	// DefaultQuickBooksIncomeAccount is the income account the lines are
+	// booked to.
+	DefaultQuickBooksIncomeAccount = "Services"
+	// DefaultQuickBooksItem is the product/service the lines are for.
+	DefaultQuickBooksItem = "Services"
+	// quickBooksReceivable is the account an IIF invoice is owed on.
+	quickBooksReceivable = "Accounts Receivable"
+)
+
+// QuickBooksOptions names what an exported invoice is booked to. The names
+// must match an income account and an item that exist in the company file.
+type QuickBooksOptions struct {
+	// IncomeAccount is the income account the lines are booked to; ""
+	// means DefaultQuickBooksIncomeAccount.
+	IncomeAccount string
+	// Item is the product/service the lines are for; "" means
+	// DefaultQuickBooksItem.
+	Item string
+}
+
+// withDefaults returns o with the empty names set to their defaults.
+func (o QuickBooksOptions) withDefaults() QuickBooksOptions {
+	if o.IncomeAccount == "" {
+		o.IncomeAccount = DefaultQuickBooksIncomeAccount
+	}
+	if o.Item == "" {
+		o.Item = DefaultQuickBooksItem
+	}
+	return o
+}
+
+// quickBooksDate formats t as QuickBooks imports dates, e.g. "01/31/2025".
+// The zero time is "".
+func quickBooksDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format("01/02/2006")
+}
+
+// iifField returns s on one line, without the tabs and quotes that would
+// break an IIF row.
+func iifField(s string) string {
+	return strings.Join(strings.Fields(strings.ReplaceAll(s, `"`, " ")), " ")
+}
+
+// WriteQuickBooksIIF writes inv as a QuickBooks Desktop IIF transaction: an
+// INVOICE on Accounts Receivable for the total, and one split per week on
+// the income account, in order. As IIF requires, the splits' amounts and
+// quantities are negative, and they add up to the total exactly.
+func WriteQuickBooksIIF(w io.Writer, inv *Invoice, opts QuickBooksOptions) error {
+	opts = opts.withDefaults()
+	lines, total := invoiceLines(inv)
+	if len(lines) == 0 {
+		return errors.New("a QuickBooks invoice needs at least one line")
+	}
+	date := quickBooksDate(inv.IssueDate())
+	number := iifField(InvoiceNumber(inv))
+	customer := iifField(inv.Customer)
+	memo := iifField(fmt.Sprintf("Services %s %d", inv.Month, inv.Year))
+
+	bw := bufio.NewWriter(w)
+	row := func(fields ...string) {
+		bw.WriteString(strings.Join(fields, "\t"))
+		bw.WriteString("\r\n")
+	}
+	row("!TRNS", "TRNSID", "TRNSTYPE", "DATE", "ACCNT", "NAME", "AMOUNT", "DOCNUM", "MEMO", "DUEDATE")
+	row("!SPL", "SPLID", "TRNSTYPE", "DATE", "ACCNT", "NAME", "AMOUNT", "DOCNUM", "MEMO", "QNTY", "PRICE", "INVITEM")
+	row("!ENDTRNS")
+	row("TRNS", "", "INVOICE", date, quickBooksReceivable, customer, total.String(), number, memo, quickBooksDate(inv.Due))
+	for _, l := range lines {
+		row("SPL", "", "INVOICE", date, iifField(opts.IncomeAccount), customer, (-l.Amount).String(), number,
+			iifField(l.Description), formatQuantity(-l.Quantity), l.Price.String(), iifField(opts.Item))
+	}
+	row("ENDTRNS")
+	return bw.Flush()
+}
+
+// quickBooksCSVHeader is the header of a QuickBooks Online invoice import.
+var quickBooksCSVHeader = []string{
+	"InvoiceNo", "Customer", "InvoiceDate", "DueDate", "Item(Product/Service)",
+	"ItemDescription", "ItemQuantity", "ItemRate", "ItemAmount", "ServiceDate",
+}
+
+// WriteQuickBooksCSV writes inv as a QuickBooks Online invoice import: a
+// header and one row per week, in order, each repeating the invoice number
+// so QuickBooks puts them on one invoice. The item amounts add up to the
+// total exactly. The income account comes from the item in QuickBooks
+// Online, so opts.IncomeAccount is not used.
+func WriteQuickBooksCSV(w io.Writer, inv *Invoice, opts QuickBooksOptions) error {
+	opts = opts.withDefaults()
+	lines, _ := invoiceLines(inv)
+	if len(lines) == 0 {
+		return errors.New("a QuickBooks invoice needs at least one line")
+	}
+	cw := csv.NewWriter(w)
+	cw.Write(quickBooksCSVHeader)
+	for _, l := range lines {
+		cw.Write([]string{
+			InvoiceNumber(inv),
+			inv.Customer,
+			quickBooksDate(inv.IssueDate()),
+			quickBooksDate(inv.Due),
+			opts.Item,
+			l.Description,
+			formatQuantity(l.Quantity),
+			l.Price.String(),
+			l.Amount.String(),
+			quickBooksDate(l.Start),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
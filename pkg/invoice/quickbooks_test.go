@@ -0,0 +1,172 @@
+package invoice_test
+
+import (
+	"bytes"
+	"encoding/csv"
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+func TestWriteQuickBooksIIF(t *testing.T) {
+	var buf bytes.Buffer
+	opts := invoice.QuickBooksOptions{IncomeAccount: "Consulting Income", Item: "Consulting"}
+	if err := invoice.WriteQuickBooksIIF(&buf, jsonInvoice(), opts); err != nil {
+		t.Fatalf("WriteQuickBooksIIF: %v", err)
+	}
+	checkGolden(t, "invoice.iif", buf.Bytes())
+}
+
+func TestWriteQuickBooksCSV(t *testing.T) {
+	var buf bytes.Buffer
+	opts := invoice.QuickBooksOptions{IncomeAccount: "Consulting Income", Item: "Consulting"}
+	if err := invoice.WriteQuickBooksCSV(&buf, jsonInvoice(), opts); err != nil {
+		t.Fatalf("WriteQuickBooksCSV: %v", err)
+	}
+	checkGolden(t, "invoice-quickbooks.csv", buf.Bytes())
+}
+
+// adjustedInvoice is jsonInvoice with a flat expense and a credit after
+// the weeks.
+func adjustedInvoice() *invoice.Invoice {
+	inv := jsonInvoice()
+	inv.Adjustments = []invoice.Adjustment{
+		{Description: "Travel expenses", Amount: 412.5},
+		{Description: "Credit for December", Amount: -150},
+	}
+	return inv
+}
+
+func TestWriteQuickBooks_Adjustment(t *testing.T) {
+	opts := invoice.QuickBooksOptions{IncomeAccount: "Consulting Income", Item: "Consulting"}
+	var buf bytes.Buffer
+	if err := invoice.WriteQuickBooksIIF(&buf, adjustedInvoice(), opts); err != nil {
+		t.Fatalf("WriteQuickBooksIIF: %v", err)
+	}
+	checkGolden(t, "invoice-adjustment.iif", buf.Bytes())
+
+	buf.Reset()
+	if err := invoice.WriteQuickBooksCSV(&buf, adjustedInvoice(), opts); err != nil {
+		t.Fatalf("WriteQuickBooksCSV: %v", err)
+	}
+	checkGolden(t, "invoice-quickbooks-adjustment.csv", buf.Bytes())
+}
+
+// parseCents parses an amount such as "-1234.50" into cents.
+func parseCents(t *testing.T, s string) int64 {
+	t.Helper()
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		t.Fatalf("amount %q: %v", s, err)
+	}
+	return int64(math.Round(f * 100))
+}
+
+// oddInvoice has many lines whose amounts each round to cents.
+func oddInvoice() *invoice.Invoice {
+	inv := testInvoice()
+	inv.Rate = 33.333
+	inv.Weeks = invoice.WeeksForMonth(2025, time.January, 7.5)
+	return inv
+}
+
+func TestWriteQuickBooksIIF_Reconciles(t *testing.T) {
+	inv := oddInvoice()
+	var buf bytes.Buffer
+	if err := invoice.WriteQuickBooksIIF(&buf, inv, invoice.QuickBooksOptions{}); err != nil {
+		t.Fatalf("WriteQuickBooksIIF: %v", err)
+	}
+	var total, splits int64
+	var descriptions []string
+	for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\r\n"), "\r\n") {
+		fields := strings.Split(line, "\t")
+		switch fields[0] {
+		case "TRNS":
+			total = parseCents(t, fields[6])
+		case "SPL":
+			if len(fields) != 12 {
+				t.Fatalf("SPL row has %d fields, want 12: %q", len(fields), line)
+			}
+			if fields[4] != "Services" || fields[11] != "Services" {
+				t.Errorf("SPL account and item = %q, %q, want the defaults", fields[4], fields[11])
+			}
+			splits += parseCents(t, fields[6])
+			descriptions = append(descriptions, fields[8])
+		}
+	}
+	if total+splits != 0 {
+		t.Errorf("splits add up to %d cents, want -%d", splits, total)
+	}
+	if len(descriptions) != len(inv.Weeks) {
+		t.Fatalf("got %d splits, want %d", len(descriptions), len(inv.Weeks))
+	}
+	for i, wk := range inv.Weeks {
+		if want := "Services " + invoice.FormatWeekLabel(wk); descriptions[i] != want {
+			t.Errorf("split %d = %q, want %q", i, descriptions[i], want)
+		}
+	}
+}
+
+func TestWriteQuickBooksCSV_Reconciles(t *testing.T) {
+	inv := oddInvoice()
+	var buf bytes.Buffer
+	if err := invoice.WriteQuickBooksCSV(&buf, inv, invoice.QuickBooksOptions{}); err != nil {
+		t.Fatalf("WriteQuickBooksCSV: %v", err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("reading CSV: %v", err)
+	}
+	if len(rows) != len(inv.Weeks)+1 {
+		t.Fatalf("got %d rows, want a header and %d lines", len(rows), len(inv.Weeks))
+	}
+	var sum int64
+	for i, row := range rows[1:] {
+		if row[0] != invoice.InvoiceNumber(inv) {
+			t.Errorf("row %d invoice number = %q", i, row[0])
+		}
+		if want := "Services " + invoice.FormatWeekLabel(inv.Weeks[i]); row[5] != want {
+			t.Errorf("row %d description = %q, want %q", i, row[5], want)
+		}
+		sum += parseCents(t, row[8])
+	}
+	var want int64
+	for _, wk := range inv.Weeks {
+		want += int64(invoice.ToCents(wk.Hours * inv.Rate))
+	}
+	if sum != want {
+		t.Errorf("item amounts add up to %d cents, want %d", sum, want)
+	}
+}
+
+func TestWriteQuickBooks_NoLines(t *testing.T) {
+	inv := testInvoice()
+	inv.Weeks = nil
+	var buf bytes.Buffer
+	if err := invoice.WriteQuickBooksIIF(&buf, inv, invoice.QuickBooksOptions{}); err == nil {
+		t.Error("WriteQuickBooksIIF: expected an error for an invoice without lines")
+	}
+	if err := invoice.WriteQuickBooksCSV(&buf, inv, invoice.QuickBooksOptions{}); err == nil {
+		t.Error("WriteQuickBooksCSV: expected an error for an invoice without lines")
+	}
+}
+
+func TestWriteQuickBooksIIF_SanitizesFields(t *testing.T) {
+	inv := testInvoice()
+	inv.Customer = "Acme\tCorp \"East\""
+	var buf bytes.Buffer
+	if err := invoice.WriteQuickBooksIIF(&buf, inv, invoice.QuickBooksOptions{}); err != nil {
+		t.Fatalf("WriteQuickBooksIIF: %v", err)
+	}
+	for _, line := range strings.Split(buf.String(), "\r\n") {
+		if strings.HasPrefix(line, "TRNS\t") {
+			if fields := strings.Split(line, "\t"); fields[5] != "Acme Corp East" {
+				t.Errorf("NAME = %q, want %q", fields[5], "Acme Corp East")
+			}
+		}
+	}
+}
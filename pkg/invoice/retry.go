@@ -0,0 +1,72 @@
+package invoice
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"os/exec"
+	"time"
+)
+
+// Backoff between retries starts at RetryBaseDelay and doubles after each
+// failed attempt, up to RetryMaxDelay.
+const (
+	RetryBaseDelay = 2 * time.Second
+	RetryMaxDelay  = 30 * time.Second
+)
+
+// Sleep waits for d or until ctx is done. It can be overridden in tests so
+// retries do not wait for real.
+var Sleep = func(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// permanentError marks a failure that retrying cannot fix.
+type permanentError struct{ err error }
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as not worth retrying.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsRetryable reports whether a failed generation might succeed if tried
+// again. Cancellation, timeouts, a missing executable and errors marked
+// Permanent are not retryable.
+func IsRetryable(err error) bool {
+	var permanent *permanentError
+	switch {
+	case err == nil:
+		return false
+	case errors.As(err, &permanent),
+		errors.Is(err, exec.ErrNotFound),
+		errors.Is(err, context.Canceled),
+		errors.Is(err, context.DeadlineExceeded):
+		return false
+	}
+	return true
+}
+
+// backoff returns the delay before retrying after the given failed attempt
+// (starting at 1): exponential, capped at RetryMaxDelay, with jitter that
+// picks a point in the upper half of the interval.
+func backoff(attempt int) time.Duration {
+	d := RetryBaseDelay
+	for i := 1; i < attempt && d < RetryMaxDelay; i++ {
+		d *= 2
+	}
+	d = min(d, RetryMaxDelay)
+	return d/2 + rand.N(d/2+1)
+}
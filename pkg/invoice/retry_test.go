@@ -0,0 +1,234 @@
+package invoice_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+// fakeSleep replaces invoice.Sleep for the test and records the delays.
+func fakeSleep(t *testing.T) *[]time.Duration {
+	t.Helper()
+	var delays []time.Duration
+	orig := invoice.Sleep
+	t.Cleanup(func() { invoice.Sleep = orig })
+	invoice.Sleep = func(ctx context.Context, d time.Duration) error {
+		delays = append(delays, d)
+		return ctx.Err()
+	}
+	return &delays
+}
+
+// flakyOpencode replaces OpencodeExec with one that fails with err for the
+// first failures calls and then writes the invoice. It returns the call count.
+func flakyOpencode(t *testing.T, failures int, err error) *int {
+	t.Helper()
+	calls := 0
+	orig := invoice.OpencodeExec
+	t.Cleanup(func() { invoice.OpencodeExec = orig })
+	invoice.OpencodeExec = func(ctx context.Context, r invoice.OpencodeRun, stdout io.Writer) error {
+		calls++
+		if calls <= failures {
+			// Leave a partial file behind, as an interrupted write would.
+			_ = os.WriteFile(promptedPath(r.Prompt), []byte("<html>"), 0o644)
+			return err
+		}
+		return os.WriteFile(promptedPath(r.Prompt), []byte("<html>ok</html>"), 0o644)
+	}
+	return &calls
+}
+
+func TestGenerate_RetriesUntilSuccess(t *testing.T) {
+	delays := fakeSleep(t)
+	calls := flakyOpencode(t, 1, errors.New("rate limited"))
+	outputPath := filepath.Join(t.TempDir(), "invoice.html")
+
+	var retried []int
+	opts := invoice.GenerateOptions{Retries: 2, OnRetry: func(attempt int, err error, delay time.Duration) {
+		retried = append(retried, attempt)
+	}}
+	if _, err := invoice.Generate(context.Background(), "opencode", testInvoice(), outputPath, opts); err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if *calls != 2 {
+		t.Errorf("calls = %d, want 2", *calls)
+	}
+	if len(retried) != 1 || retried[0] != 1 {
+		t.Errorf("OnRetry attempts = %v, want [1]", retried)
+	}
+	if len(*delays) != 1 || (*delays)[0] < invoice.RetryBaseDelay/2 || (*delays)[0] > invoice.RetryBaseDelay {
+		t.Errorf("delays = %v, want one in [%s, %s]", *delays, invoice.RetryBaseDelay/2, invoice.RetryBaseDelay)
+	}
+}
+
+func TestGenerate_RetriesExhausted(t *testing.T) {
+	delays := fakeSleep(t)
+	calls := flakyOpencode(t, 10, errors.New("rate limited"))
+	outputPath := filepath.Join(t.TempDir(), "invoice.html")
+
+	_, err := invoice.Generate(context.Background(), "opencode", testInvoice(), outputPath, invoice.GenerateOptions{Retries: 2})
+	if err == nil || !strings.Contains(err.Error(), "giving up after 3 attempts") || !strings.Contains(err.Error(), "rate limited") {
+		t.Fatalf("expected an exhaustion error, got %v", err)
+	}
+	if *calls != 3 {
+		t.Errorf("calls = %d, want 3", *calls)
+	}
+	// Backoff doubles: the second delay comes from a window twice as large.
+	if len(*delays) != 2 || (*delays)[1] < invoice.RetryBaseDelay || (*delays)[1] > 2*invoice.RetryBaseDelay {
+		t.Errorf("delays = %v, want the second in [%s, %s]", *delays, invoice.RetryBaseDelay, 2*invoice.RetryBaseDelay)
+	}
+	if _, err := os.Stat(outputPath); !os.IsNotExist(err) {
+		t.Errorf("partial file should be removed, stat err = %v", err)
+	}
+}
+
+func TestGenerate_NoRetriesByDefault(t *testing.T) {
+	fakeSleep(t)
+	calls := flakyOpencode(t, 1, errors.New("rate limited"))
+	_, err := invoice.Generate(context.Background(), "opencode", testInvoice(), filepath.Join(t.TempDir(), "invoice.html"), invoice.GenerateOptions{})
+	if err == nil || strings.Contains(err.Error(), "giving up") {
+		t.Errorf("expected the plain error, got %v", err)
+	}
+	if *calls != 1 {
+		t.Errorf("calls = %d, want 1", *calls)
+	}
+}
+
+func TestGenerate_NonRetryableShortCircuits(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{"missing binary", &exec.Error{Name: "opencode", Err: exec.ErrNotFound}},
+		{"permanent", invoice.Permanent(errors.New("model not found"))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delays := fakeSleep(t)
+			calls := flakyOpencode(t, 10, tt.err)
+			_, err := invoice.Generate(context.Background(), "opencode", testInvoice(), filepath.Join(t.TempDir(), "invoice.html"), invoice.GenerateOptions{Retries: 3})
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if *calls != 1 || len(*delays) != 0 {
+				t.Errorf("calls = %d, delays = %v; want a single attempt", *calls, *delays)
+			}
+		})
+	}
+}
+
+func TestGenerate_CancelDuringBackoff(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	orig := invoice.Sleep
+	t.Cleanup(func() { invoice.Sleep = orig })
+	invoice.Sleep = func(context.Context, time.Duration) error {
+		cancel()
+		return ctx.Err()
+	}
+	calls := flakyOpencode(t, 10, errors.New("rate limited"))
+
+	_, err := invoice.Generate(ctx, "opencode", testInvoice(), filepath.Join(t.TempDir(), "invoice.html"), invoice.GenerateOptions{Retries: 3})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected a canceled error, got %v", err)
+	}
+	if *calls != 1 {
+		t.Errorf("calls = %d, want 1", *calls)
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{errors.New("connection reset"), true},
+		{fmt.Errorf("running opencode: %w", &exec.Error{Name: "opencode", Err: exec.ErrNotFound}), false},
+		{fmt.Errorf("wrapped: %w", invoice.Permanent(errors.New("bad model"))), false},
+		{fmt.Errorf("opencode backend: %w", context.DeadlineExceeded), false},
+		{context.Canceled, false},
+	}
+	for _, tt := range tests {
+		if got := invoice.IsRetryable(tt.err); got != tt.want {
+			t.Errorf("IsRetryable(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestGenerate_FallsBackToNextModel(t *testing.T) {
+	delays := fakeSleep(t)
+	var models []string
+	orig := invoice.OpencodeExec
+	t.Cleanup(func() { invoice.OpencodeExec = orig })
+	invoice.OpencodeExec = func(ctx context.Context, r invoice.OpencodeRun, stdout io.Writer) error {
+		models = append(models, r.Model)
+		if r.Model == "anthropic/claude-haiku-4-5" {
+			return errors.New("overloaded")
+		}
+		return os.WriteFile(promptedPath(r.Prompt), []byte("<html>ok</html>"), 0o644)
+	}
+
+	var fellBack []string
+	opts := invoice.GenerateOptions{
+		Model:          "anthropic/claude-haiku-4-5",
+		FallbackModels: []string{"anthropic/claude-sonnet-4-6"},
+		Retries:        1,
+		OnFallback:     func(model string, err error) { fellBack = append(fellBack, model) },
+	}
+	res, err := invoice.Generate(context.Background(), "opencode", testInvoice(), filepath.Join(t.TempDir(), "invoice.html"), opts)
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if res.Model != "anthropic/claude-sonnet-4-6" {
+		t.Errorf("Result.Model = %q, want the fallback", res.Model)
+	}
+	want := []string{"anthropic/claude-haiku-4-5", "anthropic/claude-haiku-4-5", "anthropic/claude-sonnet-4-6"}
+	if strings.Join(models, " ") != strings.Join(want, " ") {
+		t.Errorf("models tried = %v, want %v", models, want)
+	}
+	if len(fellBack) != 1 || fellBack[0] != "anthropic/claude-sonnet-4-6" {
+		t.Errorf("OnFallback models = %v", fellBack)
+	}
+	// Only the retry of the first model waits; switching models does not.
+	if len(*delays) != 1 {
+		t.Errorf("delays = %v, want 1", *delays)
+	}
+}
+
+func TestGenerate_FallbackAttemptsAreCapped(t *testing.T) {
+	fakeSleep(t)
+	calls := flakyOpencode(t, 100, errors.New("overloaded"))
+	opts := invoice.GenerateOptions{
+		Model:          "a/one",
+		FallbackModels: []string{"a/two", "a/three"},
+		Retries:        10,
+	}
+	_, err := invoice.Generate(context.Background(), "opencode", testInvoice(), filepath.Join(t.TempDir(), "invoice.html"), opts)
+	if err == nil || !strings.Contains(err.Error(), fmt.Sprintf("giving up after %d attempts", invoice.MaxAttempts)) {
+		t.Fatalf("expected the combined cap to stop retries, got %v", err)
+	}
+	if *calls != invoice.MaxAttempts {
+		t.Errorf("calls = %d, want %d", *calls, invoice.MaxAttempts)
+	}
+}
+
+func TestGenerate_NoFallbackOnPermanentError(t *testing.T) {
+	fakeSleep(t)
+	calls := flakyOpencode(t, 100, invoice.Permanent(errors.New("unknown model")))
+	opts := invoice.GenerateOptions{Model: "a/one", FallbackModels: []string{"a/two"}}
+	if _, err := invoice.Generate(context.Background(), "opencode", testInvoice(), filepath.Join(t.TempDir(), "invoice.html"), opts); err == nil {
+		t.Fatal("expected error")
+	}
+	if *calls != 1 {
+		t.Errorf("calls = %d, want 1", *calls)
+	}
+}
@@ -0,0 +1,46 @@
+package invoice
+
+import (
+	"fmt"
+	"time"
+)
+
+// ScheduleMonthly generates each month's invoice early in the next month.
+// It is the only schedule there is so far.
+const ScheduleMonthly = "monthly"
+
+// ValidateSchedule returns an error if name is not a known schedule.
+func ValidateSchedule(name string) error {
+	if name != ScheduleMonthly {
+		return fmt.Errorf("unknown schedule %q (valid: %s)", name, ScheduleMonthly)
+	}
+	return nil
+}
+
+// Schedule says when invoices are generated: each month, on Day, for the
+// previous month. In a month with fewer days the last day is used.
+type Schedule struct {
+	// Day is the day of the month, 1 to 31.
+	Day int
+	// Location is the time zone days are counted in.
+	Location *time.Location
+}
+
+// Period returns the month whose invoice was last due at now, and the
+// start of the day it became due.
+func (s Schedule) Period(now time.Time) (time.Month, int, time.Time) {
+	now = now.In(s.Location)
+	due := s.dueDate(now.Year(), now.Month())
+	if now.Before(due) {
+		prev := time.Date(now.Year(), now.Month()-1, 1, 0, 0, 0, 0, s.Location)
+		due = s.dueDate(prev.Year(), prev.Month())
+	}
+	period := time.Date(due.Year(), due.Month()-1, 1, 0, 0, 0, 0, s.Location)
+	return period.Month(), period.Year(), due
+}
+
+// dueDate returns the start of the day in month the schedule falls on.
+func (s Schedule) dueDate(year int, month time.Month) time.Time {
+	last := time.Date(year, month+1, 0, 0, 0, 0, 0, s.Location).Day()
+	return time.Date(year, month, min(s.Day, last), 0, 0, 0, 0, s.Location)
+}
@@ -0,0 +1,55 @@
+package invoice_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+func TestSchedulePeriod(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("no time zone data: %v", err)
+	}
+	tests := []struct {
+		name      string
+		day       int
+		loc       *time.Location
+		now       time.Time
+		wantMonth time.Month
+		wantYear  int
+		wantDue   string
+	}{
+		{"on the day", 5, time.UTC, time.Date(2025, time.March, 5, 9, 0, 0, 0, time.UTC), time.February, 2025, "2025-03-05"},
+		{"before the day", 5, time.UTC, time.Date(2025, time.March, 4, 23, 59, 0, 0, time.UTC), time.January, 2025, "2025-02-05"},
+		{"across the year", 1, time.UTC, time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC), time.December, 2024, "2025-01-01"},
+		{"day 31 in February", 31, time.UTC, time.Date(2025, time.February, 28, 12, 0, 0, 0, time.UTC), time.January, 2025, "2025-02-28"},
+		{"day 31 before the end of February", 31, time.UTC, time.Date(2025, time.February, 27, 12, 0, 0, 0, time.UTC), time.December, 2024, "2025-01-31"},
+		{"day 30 in a leap February", 30, time.UTC, time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC), time.January, 2024, "2024-02-29"},
+		// 23:30 UTC on February 28 is already March 1 in Berlin.
+		{"time zone ahead of UTC", 1, berlin, time.Date(2025, time.February, 28, 23, 30, 0, 0, time.UTC), time.February, 2025, "2025-03-01"},
+		{"UTC behind the time zone", 1, time.UTC, time.Date(2025, time.February, 28, 23, 30, 0, 0, time.UTC), time.January, 2025, "2025-02-01"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := invoice.Schedule{Day: tt.day, Location: tt.loc}
+			month, year, due := s.Period(tt.now)
+			if month != tt.wantMonth || year != tt.wantYear {
+				t.Errorf("period = %s %d, want %s %d", month, year, tt.wantMonth, tt.wantYear)
+			}
+			if got := due.Format(time.DateOnly); got != tt.wantDue || due.Location() != tt.loc {
+				t.Errorf("due = %s in %s, want %s in %s", got, due.Location(), tt.wantDue, tt.loc)
+			}
+		})
+	}
+}
+
+func TestValidateSchedule(t *testing.T) {
+	if err := invoice.ValidateSchedule(invoice.ScheduleMonthly); err != nil {
+		t.Errorf("monthly: %v", err)
+	}
+	if err := invoice.ValidateSchedule("weekly"); err == nil {
+		t.Error("weekly: expected an error")
+	}
+}
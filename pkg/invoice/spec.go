@@ -0,0 +1,156 @@
+package invoice
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// specJSON holds the keys of a spec that an invoice's JSON does not have.
+type specJSON struct {
+	// Hours is the hours of every week, for a spec without weeks.
+	Hours *float64 `json:"hours"`
+}
+
+// ReadSpec reads a complete invoice specification, in YAML or JSON, and
+// returns the invoice it describes. A spec has the keys of the JSON export
+// (see Invoice.MarshalJSON), whose computed values are ignored. It may
+// leave out weeks and give hours instead, the hours of each week; the
+// weeks are then those of WeeksForMonth. The invoice must pass Validate.
+// Keys a spec cannot have are ignored, and returned by their paths, e.g.
+// "weeks[1].hourz", for the caller to warn about or reject.
+func ReadSpec(data []byte) (*Invoice, []string, error) {
+	var doc any
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, nil, fmt.Errorf("parsing the spec: %w", err)
+	}
+	if doc == nil {
+		return nil, nil, errors.New("the spec is empty")
+	}
+	unknown, err := unknownSpecKeys(doc, reflect.TypeFor[invoiceJSON](), "")
+	if err != nil {
+		return nil, nil, err
+	}
+	// YAML is read as JSON values, so the invoice's JSON methods apply.
+	js, err := json.Marshal(specDates(doc))
+	if err != nil {
+		return nil, nil, fmt.Errorf("parsing the spec: %w", err)
+	}
+	inv := new(Invoice)
+	if err := json.Unmarshal(js, inv); err != nil {
+		return nil, nil, fmt.Errorf("parsing the spec: %w", err)
+	}
+	var extra specJSON
+	if err := json.Unmarshal(js, &extra); err != nil {
+		return nil, nil, fmt.Errorf("parsing the spec: hours: %w", err)
+	}
+	if extra.Hours != nil {
+		if len(inv.Weeks) > 0 {
+			return nil, nil, errors.New("the spec gives both weeks and hours; give one of them")
+		}
+		inv.Weeks = WeeksForMonth(inv.Year, inv.Month, *extra.Hours)
+	}
+	if err := inv.Validate(); err != nil {
+		return nil, nil, err
+	}
+	return inv, unknown, nil
+}
+
+// unknownSpecKeys returns the paths of the keys in v, which is at path in
+// a spec, that t has no JSON field for.
+func unknownSpecKeys(v any, t reflect.Type, path string) ([]string, error) {
+	for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	switch v := v.(type) {
+	case map[string]any:
+		if t.Kind() != reflect.Struct {
+			return nil, nil
+		}
+		fields := jsonFields(t)
+		if t == reflect.TypeFor[invoiceJSON]() {
+			maps.Copy(fields, jsonFields(reflect.TypeFor[specJSON]()))
+		}
+		var unknown []string
+		for _, key := range slices.Sorted(maps.Keys(v)) {
+			field, ok := fields[key]
+			if !ok {
+				unknown = append(unknown, joinSpecPath(path, key))
+				continue
+			}
+			more, err := unknownSpecKeys(v[key], field, joinSpecPath(path, key))
+			if err != nil {
+				return nil, err
+			}
+			unknown = append(unknown, more...)
+		}
+		return unknown, nil
+	case map[any]any:
+		return nil, fmt.Errorf("%s: the keys of the spec must be strings", specPath(path))
+	case []any:
+		var unknown []string
+		for i, elem := range v {
+			more, err := unknownSpecKeys(elem, t, fmt.Sprintf("%s[%d]", path, i))
+			if err != nil {
+				return nil, err
+			}
+			unknown = append(unknown, more...)
+		}
+		return unknown, nil
+	}
+	return nil, nil
+}
+
+// specDates returns v with the dates YAML read as times written back as
+// ISO dates, as they are in JSON.
+func specDates(v any) any {
+	switch v := v.(type) {
+	case time.Time:
+		return v.Format(jsonDate)
+	case map[string]any:
+		for key, elem := range v {
+			v[key] = specDates(elem)
+		}
+	case []any:
+		for i, elem := range v {
+			v[i] = specDates(elem)
+		}
+	}
+	return v
+}
+
+// jsonFields returns the types of the fields of the struct type t, by
+// their JSON keys.
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := map[string]reflect.Type{}
+	for i := range t.NumField() {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			fields[name] = f.Type
+		}
+	}
+	return fields
+}
+
+func joinSpecPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// specPath returns path, or "spec" for the top of the spec.
+func specPath(path string) string {
+	if path == "" {
+		return "spec"
+	}
+	return path
+}
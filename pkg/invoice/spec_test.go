@@ -0,0 +1,166 @@
+package invoice_test
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+func TestReadSpec_ExplicitWeeks(t *testing.T) {
+	spec := `
+month: January
+year: 2025
+vendor: Jane Doe
+customer: Acme Corp
+rate: 100
+issue_date: 2025-02-01
+weeks:
+  - start: 2025-01-01
+    end: 2025-01-15
+    hours: 70
+  - start: 2025-01-16
+    end: 2025-01-31
+    hours: 82.5
+adjustments:
+  - description: Travel expenses
+    amount: 120.5
+po_number: PO-1234
+`
+	inv, unknown, err := invoice.ReadSpec([]byte(spec))
+	if err != nil {
+		t.Fatalf("ReadSpec: %v", err)
+	}
+	if len(unknown) != 0 {
+		t.Errorf("unknown = %q, want none", unknown)
+	}
+	want := []invoice.Week{
+		{Start: time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC), End: time.Date(2025, time.January, 15, 0, 0, 0, 0, time.UTC), Hours: 70},
+		{Start: time.Date(2025, time.January, 16, 0, 0, 0, 0, time.UTC), End: time.Date(2025, time.January, 31, 0, 0, 0, 0, time.UTC), Hours: 82.5},
+	}
+	if !slices.Equal(inv.Weeks, want) {
+		t.Errorf("weeks = %v, want %v", inv.Weeks, want)
+	}
+	if inv.Vendor != "Jane Doe" || inv.Customer != "Acme Corp" || inv.Rate != 100 || inv.PONumber != "PO-1234" {
+		t.Errorf("invoice = %+v", inv)
+	}
+	if len(inv.Adjustments) != 1 || inv.Adjustments[0].Amount != 120.5 {
+		t.Errorf("adjustments = %v", inv.Adjustments)
+	}
+	if got := inv.Issued.Format(time.DateOnly); got != "2025-02-01" {
+		t.Errorf("issued = %s, want 2025-02-01", got)
+	}
+}
+
+func TestReadSpec_ComputedWeeks(t *testing.T) {
+	spec := `{"month_number": 1, "year": 2025, "vendor": "Jane Doe", "customer": "Acme Corp", "rate": 100, "hours": 32}`
+	inv, _, err := invoice.ReadSpec([]byte(spec))
+	if err != nil {
+		t.Fatalf("ReadSpec: %v", err)
+	}
+	want := invoice.WeeksForMonth(2025, time.January, 32)
+	if !slices.Equal(inv.Weeks, want) {
+		t.Errorf("weeks = %v, want %v", inv.Weeks, want)
+	}
+}
+
+func TestReadSpec_Export(t *testing.T) {
+	// The JSON export reads back as a spec, computed values and all.
+	data, err := jsonInvoice().MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	inv, unknown, err := invoice.ReadSpec(data)
+	if err != nil {
+		t.Fatalf("ReadSpec: %v", err)
+	}
+	if len(unknown) != 0 {
+		t.Errorf("unknown = %q, want none", unknown)
+	}
+	if !slices.Equal(inv.Weeks, jsonInvoice().Weeks) {
+		t.Errorf("weeks = %v, want %v", inv.Weeks, jsonInvoice().Weeks)
+	}
+}
+
+func TestReadSpec_UnknownKeys(t *testing.T) {
+	spec := `
+month: January
+year: 2025
+vendor: Jane Doe
+customer: Acme Corp
+rate: 100
+tax_rate: 0.2
+weeks:
+  - start: 2025-01-06
+    end: 2025-01-12
+    hours: 40
+  - start: 2025-01-13
+    end: 2025-01-19
+    hourz: 40
+payment:
+  iban: DE89370400440532013000
+  swift: COBADEFFXXX
+`
+	_, unknown, err := invoice.ReadSpec([]byte(spec))
+	if err != nil {
+		t.Fatalf("ReadSpec: %v", err)
+	}
+	want := []string{"payment.swift", "tax_rate", "weeks[1].hourz"}
+	if !slices.Equal(unknown, want) {
+		t.Errorf("unknown = %q, want %q", unknown, want)
+	}
+}
+
+func TestReadSpec_Invalid(t *testing.T) {
+	spec := `
+month: January
+year: 2025
+vendor: Jane Doe
+rate: 0
+weeks:
+  - start: 2025-01-06
+    end: 2025-01-12
+    hours: -4
+  - start: 2025-02-03
+    end: 2025-02-09
+    hours: 40
+`
+	_, _, err := invoice.ReadSpec([]byte(spec))
+	var problems invoice.ValidationErrors
+	if !errors.As(err, &problems) {
+		t.Fatalf("expected ValidationErrors, got %v", err)
+	}
+	var fields []string
+	for _, p := range problems {
+		fields = append(fields, p.Field)
+	}
+	want := []string{"customer", "rate", "weeks[0].hours", "weeks[1]"}
+	if !slices.Equal(fields, want) {
+		t.Errorf("fields = %q, want %q", fields, want)
+	}
+}
+
+func TestReadSpec_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		wantErr string
+	}{
+		{"empty", "", "the spec is empty"},
+		{"not yaml", "month: [", "parsing the spec"},
+		{"no month", "year: 2025\nhours: 40\n", "month is required"},
+		{"weeks and hours", "month: January\nyear: 2025\nhours: 40\nweeks:\n  - start: 2025-01-06\n    end: 2025-01-12\n", "both weeks and hours"},
+		{"bad date", "month: January\nyear: 2025\nweeks:\n  - start: soon\n    end: 2025-01-12\n", `start "soon" is not a date`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := invoice.ReadSpec([]byte(tt.spec))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+//go:build unix
+
+package invoice_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+// installTool puts a shell script on PATH under name.
+func installTool(t *testing.T, name, script string) {
+	t.Helper()
+	bin := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bin, name), []byte("#!/bin/sh\n"+script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+// captureStderr returns what fn writes to os.Stderr.
+func captureStderr(t *testing.T, fn func()) string {
+	t.Helper()
+	f, err := os.CreateTemp(t.TempDir(), "stderr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	orig := os.Stderr
+	os.Stderr = f
+	defer func() { os.Stderr = orig }()
+	fn()
+	data, err := os.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestOpencodeExec_StderrInError(t *testing.T) {
+	installTool(t, "opencode", "echo 'Error: provider anthropic is not configured' >&2\nexit 1\n")
+	var err error
+	leaked := captureStderr(t, func() {
+		err = invoice.OpencodeExec(context.Background(), invoice.OpencodeRun{Model: "anthropic/claude-haiku-4-5", Dir: t.TempDir(), Prompt: "prompt"}, &bytes.Buffer{})
+	})
+	if err == nil || !strings.Contains(err.Error(), "exit status 1: Error: provider anthropic is not configured") {
+		t.Errorf("expected the stderr text in the error, got %v", err)
+	}
+	if leaked != "" {
+		t.Errorf("stderr leaked to the terminal: %q", leaked)
+	}
+}
+
+func TestOpencodeExec_QuietOnSuccess(t *testing.T) {
+	installTool(t, "opencode", "echo 'INFO loading config' >&2\necho '{\"type\":\"step_start\"}'\n")
+	var out bytes.Buffer
+	leaked := captureStderr(t, func() {
+		if err := invoice.OpencodeExec(context.Background(), invoice.OpencodeRun{Model: "anthropic/claude-haiku-4-5", Dir: t.TempDir(), Prompt: "prompt"}, &out); err != nil {
+			t.Errorf("OpencodeExec: %v", err)
+		}
+	})
+	if leaked != "" {
+		t.Errorf("stderr leaked to the terminal: %q", leaked)
+	}
+	if strings.TrimSpace(out.String()) != `{"type":"step_start"}` {
+		t.Errorf("stdout = %q", out.String())
+	}
+}
+
+func TestOpencodeExec_ToolOutputEchoes(t *testing.T) {
+	installTool(t, "opencode", "echo 'INFO loading config' >&2\n")
+	var echoed bytes.Buffer
+	invoice.ToolOutput = &echoed
+	t.Cleanup(func() { invoice.ToolOutput = nil })
+	if err := invoice.OpencodeExec(context.Background(), invoice.OpencodeRun{Model: "anthropic/claude-haiku-4-5", Dir: t.TempDir(), Prompt: "prompt"}, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+	if echoed.String() != "INFO loading config\n" {
+		t.Errorf("ToolOutput got %q", echoed.String())
+	}
+}
+
+func TestConvertToPDF_StderrInError(t *testing.T) {
+	installTool(t, "wkhtmltopdf", "echo 'Loading pages (1/6)'\necho 'Exit with code 1 due to network error: HostNotFoundError' >&2\nexit 1\n")
+	dir := t.TempDir()
+	htmlPath := filepath.Join(dir, "invoice.html")
+	if err := os.WriteFile(htmlPath, []byte("<html></html>"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var err error
+	leaked := captureStderr(t, func() {
+		_, err = invoice.ConvertToPDF(context.Background(), htmlPath, filepath.Join(dir, "invoice.pdf"), invoice.PDFOptions{})
+	})
+	if err == nil || !strings.Contains(err.Error(), "HostNotFoundError") || !strings.Contains(err.Error(), "Loading pages") {
+		t.Errorf("expected the tool output in the error, got %v", err)
+	}
+	if leaked != "" {
+		t.Errorf("output leaked to the terminal: %q", leaked)
+	}
+}
+
+func TestOpencodeExec_KeepsStderrTail(t *testing.T) {
+	installTool(t, "opencode", "head -c 100000 /dev/zero | tr '\\0' x >&2\necho ' last line' >&2\nexit 1\n")
+	err := invoice.OpencodeExec(context.Background(), invoice.OpencodeRun{Model: "anthropic/claude-haiku-4-5", Dir: t.TempDir(), Prompt: "prompt"}, &bytes.Buffer{})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if msg := err.Error(); !strings.HasSuffix(msg, "x last line") || !strings.Contains(msg, ": ...x") || len(msg) > 5000 {
+		t.Errorf("expected a bounded tail of stderr, got %d bytes ending %q", len(msg), msg[max(0, len(msg)-40):])
+	}
+}
+
+func TestGenerate_InstalledOpencodeSkipsMissingMessage(t *testing.T) {
+	outputPath := filepath.Join(t.TempDir(), "invoice.html")
+	installTool(t, "opencode", outScript+"printf '<html>ok</html>' > \"$out\"\n")
+	if _, err := invoice.Generate(context.Background(), "opencode", testInvoice(), outputPath, invoice.GenerateOptions{Model: "anthropic/claude-haiku-4-5"}); err != nil {
+		t.Fatalf("Generate with opencode installed: %v", err)
+	}
+}
+
+func TestOpencodeExec_DeniedToolsConfig(t *testing.T) {
+	installTool(t, "opencode", `printf '%s\n' "$*" >&2; printf '%s' "$OPENCODE_CONFIG_CONTENT" >&2; exit 1`+"\n")
+	r := invoice.OpencodeRun{Model: "anthropic/claude-haiku-4-5", Dir: t.TempDir(), Prompt: "prompt", DeniedTools: []string{"bash", "webfetch"}}
+	err := invoice.OpencodeExec(context.Background(), r, &bytes.Buffer{})
+	if err == nil {
+		t.Fatal("expected the fake to fail")
+	}
+	if !strings.Contains(err.Error(), "run --model anthropic/claude-haiku-4-5 --format json --dir ") {
+		t.Errorf("unexpected argv in %v", err)
+	}
+	if !strings.Contains(err.Error(), `"tools":{"bash":false,"webfetch":false}}`) {
+		t.Errorf("denied tools not passed in OPENCODE_CONFIG_CONTENT: %v", err)
+	}
+}
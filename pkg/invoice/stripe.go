@@ -0,0 +1,258 @@
+package invoice
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultStripeURL is the base URL of the Stripe API.
+const DefaultStripeURL = "https://api.stripe.com"
+
+// DefaultStripeDaysUntilDue is how many days a Stripe invoice without a due
+// date gives the customer to pay.
+const DefaultStripeDaysUntilDue = 30
+
+// StripeTimeout bounds each request to the Stripe API.
+const StripeTimeout = 30 * time.Second
+
+// Steps of sending an invoice to Stripe, named in a StripeError.
+const (
+	StripeStepAuth     = "authentication"
+	StripeStepCustomer = "customer lookup"
+	StripeStepInvoice  = "invoice creation"
+	StripeStepItem     = "item creation"
+	StripeStepFinalize = "finalization"
+)
+
+// StripeClient calls the Stripe API with a secret key.
+type StripeClient struct {
+	// Key is the secret API key, e.g. "sk_live_...".
+	Key string
+	// BaseURL is the API's base URL; "" means DefaultStripeURL. Tests
+	// point it at a fake server.
+	BaseURL string
+	// HTTP sends the requests; nil means a client with StripeTimeout.
+	HTTP *http.Client
+}
+
+// StripeError is a failed step of sending an invoice to Stripe.
+type StripeError struct {
+	// Step is the step that failed, e.g. StripeStepCustomer. A request
+	// the key is not good for is always StripeStepAuth.
+	Step string
+	// StatusCode is the HTTP status of Stripe's reply, or 0 if there was
+	// none.
+	StatusCode int
+	// Message is what Stripe, or the connection, said went wrong.
+	Message string
+}
+
+func (e *StripeError) Error() string {
+	if e.Step == StripeStepAuth {
+		return "Stripe authentication failed (check the API key): " + e.Message
+	}
+	return fmt.Sprintf("Stripe %s failed: %s", e.Step, e.Message)
+}
+
+// StripeOptions say who a Stripe invoice is for and when it is due.
+type StripeOptions struct {
+	// CustomerID is the Stripe customer to bill, e.g. "cus_123". If it is
+	// "", the customer is looked up by CustomerEmail, and created with the
+	// invoice's customer name if there is none.
+	CustomerID string
+	// CustomerEmail is the billing email of the customer.
+	CustomerEmail string
+	// DaysUntilDue is how long the customer has to pay if the invoice has
+	// no due date; 0 means DefaultStripeDaysUntilDue.
+	DaysUntilDue int
+	// Finalize finalizes the invoice so it can be sent, instead of leaving
+	// it as a draft.
+	Finalize bool
+}
+
+// StripeInvoice is the invoice SendStripe created.
+type StripeInvoice struct {
+	ID       string `json:"id"`
+	Customer string `json:"customer"`
+	Status   string `json:"status"`
+	// HostedURL is the page the customer pays on. Stripe only sets it once
+	// the invoice is finalized.
+	HostedURL string `json:"hosted_invoice_url"`
+}
+
+// SendStripe creates a Stripe invoice for inv: it finds or creates the
+// customer, creates a draft invoice with the invoice number as metadata,
+// adds one item per week with the amount in cents, and finalizes it if
+// opts.Finalize is set. Every request that creates something carries an
+// idempotency key made from the invoice number, so retrying after a
+// failure, or running again within Stripe's 24 hours, does not bill twice.
+func SendStripe(ctx context.Context, c *StripeClient, inv *Invoice, opts StripeOptions) (*StripeInvoice, error) {
+	if c.Key == "" {
+		return nil, &StripeError{Step: StripeStepAuth, Message: "no API key is configured"}
+	}
+	lines, _ := invoiceLines(inv)
+	if len(lines) == 0 {
+		return nil, errors.New("a Stripe invoice needs at least one line")
+	}
+	number := InvoiceNumber(inv)
+	key := "invoicer-" + number
+	currency := strings.ToLower(Currency)
+
+	customer, err := c.stripeCustomer(ctx, inv, opts, key)
+	if err != nil {
+		return nil, err
+	}
+
+	params := url.Values{
+		"customer":                       {customer},
+		"currency":                       {currency},
+		"collection_method":              {"send_invoice"},
+		"auto_advance":                   {"false"},
+		"pending_invoice_items_behavior": {"exclude"},
+		"description":                    {fmt.Sprintf("Services %s %d", inv.Month, inv.Year)},
+		"metadata[invoice_number]":       {number},
+	}
+	if inv.Due.IsZero() {
+		days := opts.DaysUntilDue
+		if days == 0 {
+			days = DefaultStripeDaysUntilDue
+		}
+		params.Set("days_until_due", strconv.Itoa(days))
+	} else {
+		params.Set("due_date", strconv.FormatInt(inv.Due.Unix(), 10))
+	}
+	var created StripeInvoice
+	if err := c.do(ctx, StripeStepInvoice, http.MethodPost, "/v1/invoices", params, key+"-invoice", &created); err != nil {
+		return nil, err
+	}
+
+	for i, l := range lines {
+		params := url.Values{
+			"customer":      {customer},
+			"invoice":       {created.ID},
+			"currency":      {currency},
+			"amount":        {strconv.FormatInt(int64(l.Amount), 10)},
+			"description":   {fmt.Sprintf("%s (%s)", l.Description, l.rateText())},
+			"period[start]": {strconv.FormatInt(l.Start.Unix(), 10)},
+			"period[end]":   {strconv.FormatInt(l.End.Unix(), 10)},
+		}
+		idem := fmt.Sprintf("%s-item-%d", key, i+1)
+		if err := c.do(ctx, StripeStepItem, http.MethodPost, "/v1/invoiceitems", params, idem, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.Finalize {
+		path := "/v1/invoices/" + url.PathEscape(created.ID) + "/finalize"
+		if err := c.do(ctx, StripeStepFinalize, http.MethodPost, path, url.Values{}, key+"-finalize", &created); err != nil {
+			return nil, err
+		}
+	}
+	return &created, nil
+}
+
+// stripeCustomer returns the ID of the customer to bill: opts.CustomerID
+// once Stripe knows it, or the customer with opts.CustomerEmail, created if
+// there is none.
+func (c *StripeClient) stripeCustomer(ctx context.Context, inv *Invoice, opts StripeOptions, key string) (string, error) {
+	var customer struct {
+		ID      string `json:"id"`
+		Deleted bool   `json:"deleted"`
+	}
+	if opts.CustomerID != "" {
+		if err := c.do(ctx, StripeStepCustomer, http.MethodGet, "/v1/customers/"+url.PathEscape(opts.CustomerID), nil, "", &customer); err != nil {
+			return "", err
+		}
+		if customer.Deleted {
+			return "", &StripeError{Step: StripeStepCustomer, Message: fmt.Sprintf("customer %s has been deleted", opts.CustomerID)}
+		}
+		return customer.ID, nil
+	}
+	if opts.CustomerEmail == "" {
+		return "", &StripeError{Step: StripeStepCustomer, Message: "a Stripe customer ID or email is required"}
+	}
+	var list struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	query := url.Values{"email": {opts.CustomerEmail}, "limit": {"1"}}
+	if err := c.do(ctx, StripeStepCustomer, http.MethodGet, "/v1/customers?"+query.Encode(), nil, "", &list); err != nil {
+		return "", err
+	}
+	if len(list.Data) > 0 {
+		return list.Data[0].ID, nil
+	}
+	params := url.Values{"email": {opts.CustomerEmail}, "name": {inv.Customer}}
+	if err := c.do(ctx, StripeStepCustomer, http.MethodPost, "/v1/customers", params, key+"-customer", &customer); err != nil {
+		return "", err
+	}
+	return customer.ID, nil
+}
+
+// do sends a request for step to the Stripe API and decodes the reply into
+// out, if it is not nil. params are form-encoded as the body of a POST, and
+// idem, if it is not "", is sent as the Idempotency-Key.
+func (c *StripeClient) do(ctx context.Context, step, method, path string, params url.Values, idem string, out any) error {
+	base := c.BaseURL
+	if base == "" {
+		base = DefaultStripeURL
+	}
+	var body io.Reader
+	if params != nil {
+		body = strings.NewReader(params.Encode())
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimRight(base, "/")+path, body)
+	if err != nil {
+		return &StripeError{Step: step, Message: err.Error()}
+	}
+	req.SetBasicAuth(c.Key, "")
+	if params != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	if idem != "" {
+		req.Header.Set("Idempotency-Key", idem)
+	}
+	client := c.HTTP
+	if client == nil {
+		client = &http.Client{Timeout: StripeTimeout}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return &StripeError{Step: step, Message: err.Error()}
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return &StripeError{Step: step, StatusCode: resp.StatusCode, Message: err.Error()}
+	}
+	if resp.StatusCode/100 != 2 {
+		var reply struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		msg := strings.TrimSpace(string(data))
+		if json.Unmarshal(data, &reply) == nil && reply.Error.Message != "" {
+			msg = reply.Error.Message
+		}
+		if resp.StatusCode == http.StatusUnauthorized {
+			step = StripeStepAuth
+		}
+		return &StripeError{Step: step, StatusCode: resp.StatusCode, Message: fmt.Sprintf("%s: %s", resp.Status, msg)}
+	}
+	if out != nil {
+		if err := json.Unmarshal(data, out); err != nil {
+			return &StripeError{Step: step, StatusCode: resp.StatusCode, Message: fmt.Sprintf("reading the reply: %v", err)}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,290 @@
+package invoice_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+// stripeRequest is a request the fake Stripe API received.
+type stripeRequest struct {
+	Method, Path string
+	Form         url.Values
+	Idempotency  string
+}
+
+// fakeStripe is a Stripe API that records requests and replays creations
+// with the same idempotency key, as Stripe does.
+type fakeStripe struct {
+	mu        sync.Mutex
+	requests  []stripeRequest
+	customers map[string]string // email to ID
+	replies   map[string]string // idempotency key to reply
+	fail      map[string]int    // path prefix to status
+	created   int
+}
+
+func newFakeStripe(t *testing.T) (*fakeStripe, *invoice.StripeClient) {
+	t.Helper()
+	f := &fakeStripe{customers: map[string]string{}, replies: map[string]string{}, fail: map[string]int{}}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	return f, &invoice.StripeClient{Key: "sk_test_123", BaseURL: srv.URL, HTTP: srv.Client()}
+}
+
+func (f *fakeStripe) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	r.ParseForm()
+	form := r.PostForm
+	if r.Method == http.MethodGet {
+		form = r.URL.Query()
+	}
+	f.requests = append(f.requests, stripeRequest{r.Method, r.URL.Path, form, r.Header.Get("Idempotency-Key")})
+	w.Header().Set("Content-Type", "application/json")
+	if user, _, _ := r.BasicAuth(); user != "sk_test_123" {
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, `{"error":{"message":"Invalid API Key provided","type":"invalid_request_error"}}`)
+		return
+	}
+	for prefix, status := range f.fail {
+		if strings.HasPrefix(r.URL.Path, prefix) {
+			w.WriteHeader(status)
+			fmt.Fprintf(w, `{"error":{"message":"No such thing: %s"}}`, r.URL.Path)
+			return
+		}
+	}
+	key := r.Header.Get("Idempotency-Key")
+	if reply, ok := f.replies[key]; ok && key != "" {
+		fmt.Fprint(w, reply)
+		return
+	}
+	var reply string
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/v1/customers":
+		if id, ok := f.customers[form.Get("email")]; ok {
+			reply = fmt.Sprintf(`{"data":[{"id":%q}]}`, id)
+		} else {
+			reply = `{"data":[]}`
+		}
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/v1/customers/"):
+		reply = fmt.Sprintf(`{"id":%q}`, strings.TrimPrefix(r.URL.Path, "/v1/customers/"))
+	case r.URL.Path == "/v1/customers":
+		f.created++
+		id := fmt.Sprintf("cus_new%d", f.created)
+		f.customers[form.Get("email")] = id
+		reply = fmt.Sprintf(`{"id":%q}`, id)
+	case r.URL.Path == "/v1/invoices":
+		f.created++
+		reply = fmt.Sprintf(`{"id":"in_%d","customer":%q,"status":"draft"}`, f.created, form.Get("customer"))
+	case r.URL.Path == "/v1/invoiceitems":
+		f.created++
+		reply = fmt.Sprintf(`{"id":"ii_%d"}`, f.created)
+	case strings.HasSuffix(r.URL.Path, "/finalize"):
+		id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/v1/invoices/"), "/finalize")
+		reply = fmt.Sprintf(`{"id":%q,"status":"open","hosted_invoice_url":"https://invoice.stripe.com/i/%s"}`, id, id)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		reply = `{"error":{"message":"Unrecognized request URL"}}`
+	}
+	if key != "" {
+		f.replies[key] = reply
+	}
+	fmt.Fprint(w, reply)
+}
+
+// posts returns the POSTs to path.
+func (f *fakeStripe) posts(path string) []stripeRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var got []stripeRequest
+	for _, r := range f.requests {
+		if r.Method == http.MethodPost && r.Path == path {
+			got = append(got, r)
+		}
+	}
+	return got
+}
+
+func TestSendStripe(t *testing.T) {
+	f, client := newFakeStripe(t)
+	inv := jsonInvoice()
+	got, err := invoice.SendStripe(context.Background(), client, inv, invoice.StripeOptions{CustomerID: "cus_acme"})
+	if err != nil {
+		t.Fatalf("SendStripe: %v", err)
+	}
+	if got.ID == "" || got.Status != "draft" || got.Customer != "cus_acme" {
+		t.Errorf("created %+v, want a draft for cus_acme", got)
+	}
+
+	invoices := f.posts("/v1/invoices")
+	if len(invoices) != 1 {
+		t.Fatalf("created %d invoices, want 1", len(invoices))
+	}
+	form := invoices[0].Form
+	for field, want := range map[string]string{
+		"customer":                 "cus_acme",
+		"currency":                 "usd",
+		"collection_method":        "send_invoice",
+		"auto_advance":             "false",
+		"metadata[invoice_number]": "INV-202501-acme-corp",
+		"due_date":                 fmt.Sprint(inv.Due.Unix()),
+	} {
+		if form.Get(field) != want {
+			t.Errorf("invoice %s = %q, want %q", field, form.Get(field), want)
+		}
+	}
+	if invoices[0].Idempotency != "invoicer-INV-202501-acme-corp-invoice" {
+		t.Errorf("invoice idempotency key = %q", invoices[0].Idempotency)
+	}
+
+	items := f.posts("/v1/invoiceitems")
+	if len(items) != len(inv.Weeks) {
+		t.Fatalf("created %d items, want %d", len(items), len(inv.Weeks))
+	}
+	var total int
+	for i, item := range items {
+		wk := inv.Weeks[i]
+		if want := "Services " + invoice.FormatWeekLabel(wk); !strings.HasPrefix(item.Form.Get("description"), want) {
+			t.Errorf("item %d description = %q, want it to start with %q", i, item.Form.Get("description"), want)
+		}
+		if item.Form.Get("invoice") != got.ID {
+			t.Errorf("item %d is on invoice %q, want %q", i, item.Form.Get("invoice"), got.ID)
+		}
+		if want := fmt.Sprintf("invoicer-INV-202501-acme-corp-item-%d", i+1); item.Idempotency != want {
+			t.Errorf("item %d idempotency key = %q, want %q", i, item.Idempotency, want)
+		}
+		var cents int
+		fmt.Sscan(item.Form.Get("amount"), &cents)
+		total += cents
+	}
+	if total != 1080000 {
+		t.Errorf("items add up to %d cents, want 1080000", total)
+	}
+	if len(f.posts("/v1/invoices/"+got.ID+"/finalize")) != 0 {
+		t.Error("the invoice should be left as a draft")
+	}
+}
+
+func TestSendStripe_RetryDoesNotDoubleBill(t *testing.T) {
+	f, client := newFakeStripe(t)
+	opts := invoice.StripeOptions{CustomerEmail: "ap@acme.example"}
+	first, err := invoice.SendStripe(context.Background(), client, jsonInvoice(), opts)
+	if err != nil {
+		t.Fatalf("SendStripe: %v", err)
+	}
+	// The customer is found by email the second time, and the invoice and
+	// items are replayed from their idempotency keys.
+	delete(f.customers, "ap@acme.example")
+	second, err := invoice.SendStripe(context.Background(), client, jsonInvoice(), opts)
+	if err != nil {
+		t.Fatalf("SendStripe again: %v", err)
+	}
+	if first.ID != second.ID || first.Customer != second.Customer {
+		t.Errorf("the retry created %+v, want %+v again", second, first)
+	}
+	if f.created != 1+1+2 {
+		t.Errorf("created %d objects, want one customer, one invoice and two items", f.created)
+	}
+}
+
+func TestSendStripe_FindsCustomerByEmail(t *testing.T) {
+	f, client := newFakeStripe(t)
+	f.customers["ap@acme.example"] = "cus_existing"
+	got, err := invoice.SendStripe(context.Background(), client, jsonInvoice(), invoice.StripeOptions{CustomerEmail: "ap@acme.example"})
+	if err != nil {
+		t.Fatalf("SendStripe: %v", err)
+	}
+	if got.Customer != "cus_existing" {
+		t.Errorf("billed %q, want cus_existing", got.Customer)
+	}
+	if n := len(f.posts("/v1/customers")); n != 0 {
+		t.Errorf("created %d customers, want none", n)
+	}
+}
+
+func TestSendStripe_CreatesCustomer(t *testing.T) {
+	f, client := newFakeStripe(t)
+	got, err := invoice.SendStripe(context.Background(), client, jsonInvoice(), invoice.StripeOptions{CustomerEmail: "ap@acme.example"})
+	if err != nil {
+		t.Fatalf("SendStripe: %v", err)
+	}
+	created := f.posts("/v1/customers")
+	if len(created) != 1 {
+		t.Fatalf("created %d customers, want 1", len(created))
+	}
+	if form := created[0].Form; form.Get("email") != "ap@acme.example" || form.Get("name") != "Acme Corp" {
+		t.Errorf("created customer %v", form)
+	}
+	if got.Customer != "cus_new1" {
+		t.Errorf("billed %q, want the new customer", got.Customer)
+	}
+}
+
+func TestSendStripe_Finalize(t *testing.T) {
+	f, client := newFakeStripe(t)
+	got, err := invoice.SendStripe(context.Background(), client, jsonInvoice(), invoice.StripeOptions{CustomerID: "cus_acme", Finalize: true})
+	if err != nil {
+		t.Fatalf("SendStripe: %v", err)
+	}
+	if got.Status != "open" || got.HostedURL == "" {
+		t.Errorf("got %+v, want an open invoice with a payment page", got)
+	}
+	if finalize := f.posts("/v1/invoices/" + got.ID + "/finalize"); len(finalize) != 1 || finalize[0].Idempotency != "invoicer-INV-202501-acme-corp-finalize" {
+		t.Errorf("finalize requests = %+v", finalize)
+	}
+}
+
+func TestSendStripe_DaysUntilDue(t *testing.T) {
+	f, client := newFakeStripe(t)
+	if _, err := invoice.SendStripe(context.Background(), client, testInvoice(), invoice.StripeOptions{CustomerID: "cus_acme"}); err != nil {
+		t.Fatalf("SendStripe: %v", err)
+	}
+	form := f.posts("/v1/invoices")[0].Form
+	if form.Get("days_until_due") != "30" || form.Has("due_date") {
+		t.Errorf("an invoice without a due date should be due in 30 days, got %v", form)
+	}
+}
+
+func TestSendStripe_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		key      string
+		opts     invoice.StripeOptions
+		fail     string
+		wantStep string
+		want     string
+	}{
+		{"no key", "", invoice.StripeOptions{CustomerID: "cus_acme"}, "", invoice.StripeStepAuth, "no API key"},
+		{"bad key", "sk_test_bad", invoice.StripeOptions{CustomerID: "cus_acme"}, "", invoice.StripeStepAuth, "Invalid API Key"},
+		{"no customer", "sk_test_123", invoice.StripeOptions{}, "", invoice.StripeStepCustomer, "customer ID or email is required"},
+		{"unknown customer", "sk_test_123", invoice.StripeOptions{CustomerID: "cus_gone"}, "/v1/customers/", invoice.StripeStepCustomer, "No such thing"},
+		{"invoice rejected", "sk_test_123", invoice.StripeOptions{CustomerID: "cus_acme"}, "/v1/invoices", invoice.StripeStepInvoice, "400 Bad Request"},
+		{"item rejected", "sk_test_123", invoice.StripeOptions{CustomerID: "cus_acme"}, "/v1/invoiceitems", invoice.StripeStepItem, "No such thing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, client := newFakeStripe(t)
+			client.Key = tt.key
+			if tt.fail != "" {
+				f.fail[tt.fail] = http.StatusBadRequest
+			}
+			_, err := invoice.SendStripe(context.Background(), client, jsonInvoice(), tt.opts)
+			var serr *invoice.StripeError
+			if !errors.As(err, &serr) {
+				t.Fatalf("expected a StripeError, got %v", err)
+			}
+			if serr.Step != tt.wantStep || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %v (step %q), want step %q mentioning %q", err, serr.Step, tt.wantStep, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package invoice
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxStyleSize is the most of a stylesheet a StyleReference keeps, in
+// bytes, so an earlier invoice cannot blow up the prompt.
+const MaxStyleSize = 8 << 10
+
+// maxLayoutElements is the most elements a StyleReference's layout lists.
+const maxLayoutElements = 40
+
+// StyleReference is the look of an earlier invoice, for a new invoice to
+// resemble.
+type StyleReference struct {
+	// CSS is the invoice's stylesheet: the contents of its <style>
+	// elements, cut to MaxStyleSize at the end of a rule.
+	CSS string
+	// Layout lists the invoice's main elements in order, with their
+	// classes, e.g. "header.top, table.items, footer".
+	Layout string
+	// Truncated is set if CSS was cut.
+	Truncated bool
+}
+
+var (
+	styleElement = regexp.MustCompile(`(?is)<style[^>]*>(.*?)</style>`)
+	bodyElement  = regexp.MustCompile(`(?is)<body[^>]*>(.*)`)
+	layoutTag    = regexp.MustCompile(`(?is)<(header|footer|main|section|article|aside|nav|table|h1|h2|h3|address|div)\b([^>]*)>`)
+	classAttr    = regexp.MustCompile(`(?is)\bclass\s*=\s*["']([^"']*)["']`)
+)
+
+// ExtractStyle returns the stylesheet and layout of the HTML invoice
+// html, or nil if it has no stylesheet.
+func ExtractStyle(html []byte) *StyleReference {
+	var css []string
+	for _, m := range styleElement.FindAllSubmatch(html, -1) {
+		if s := strings.TrimSpace(string(m[1])); s != "" {
+			css = append(css, s)
+		}
+	}
+	if len(css) == 0 {
+		return nil
+	}
+	ref := &StyleReference{CSS: strings.Join(css, "\n")}
+	if len(ref.CSS) > MaxStyleSize {
+		cut := ref.CSS[:MaxStyleSize]
+		// Keep whole rules, so the reference still reads as CSS.
+		if end := strings.LastIndex(cut, "}"); end > 0 {
+			cut = cut[:end+1]
+		}
+		ref.CSS, ref.Truncated = cut, true
+	}
+
+	body := html
+	if m := bodyElement.FindSubmatch(html); m != nil {
+		body = m[1]
+	}
+	var layout []string
+	for _, m := range layoutTag.FindAllSubmatch(body, -1) {
+		el := strings.ToLower(string(m[1]))
+		if c := classAttr.FindSubmatch(m[2]); c != nil {
+			for _, class := range strings.Fields(string(c[1])) {
+				el += "." + class
+			}
+		}
+		// A run of the same element, such as a grid of divs, is listed once.
+		if len(layout) > 0 && layout[len(layout)-1] == el {
+			continue
+		}
+		layout = append(layout, el)
+		if len(layout) == maxLayoutElements {
+			break
+		}
+	}
+	ref.Layout = strings.Join(layout, ", ")
+	return ref
+}
+
+// LastInvoice returns the HTML file of the latest invoice in dir to
+// inv's customer for a month before inv's, or "" if there is none. For a
+// PerVendor invoice, only the vendor's own invoices count.
+func LastInvoice(dir string, inv *Invoice) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", fmt.Errorf("listing invoices: %w", err)
+	}
+	before := time.Date(inv.Year, inv.Month, 1, 0, 0, 0, 0, time.UTC)
+	var last time.Time
+	var path string
+	for _, entry := range entries {
+		m := invoiceFileName.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || m == nil || m[4] != "html" || m[1] != fileSlug(inv) {
+			continue
+		}
+		year, _ := strconv.Atoi(m[2])
+		month, _ := strconv.Atoi(m[3])
+		if month < 1 || month > 12 {
+			continue
+		}
+		t := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
+		if t.Before(before) && t.After(last) {
+			last, path = t, filepath.Join(dir, entry.Name())
+		}
+	}
+	return path, nil
+}
+
+// writeStyleReference asks, in a prompt, for the invoice to keep the look
+// of ref.
+func writeStyleReference(sb *strings.Builder, ref *StyleReference) {
+	sb.WriteString("\nStyle Reference (from the previous invoice):\n")
+	sb.WriteString("Keep the visual identity of the previous invoice - its colors, fonts and layout - ")
+	sb.WriteString("but take every name, date, number and amount from the invoice details above, none from the reference.\n")
+	if ref.Layout != "" {
+		sb.WriteString(fmt.Sprintf("- Layout: %s\n", ref.Layout))
+	}
+	sb.WriteString("- Stylesheet")
+	if ref.Truncated {
+		sb.WriteString(" (cut short)")
+	}
+	sb.WriteString(":\n```css\n" + ref.CSS + "\n```\n")
+}
@@ -0,0 +1,115 @@
+package invoice_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+const styledHTML = `<!DOCTYPE html>
+<html><head><style>
+body { font-family: Georgia; color: #223; }
+.total { background: #fd4; }
+</style></head>
+<body>
+<header class="top brand"><h1>Invoice</h1></header>
+<table class="items"><tr><td>Jan 6-12</td></tr></table>
+<div class="row"></div><div class="row"></div>
+<footer></footer>
+</body></html>`
+
+func TestExtractStyle(t *testing.T) {
+	ref := invoice.ExtractStyle([]byte(styledHTML))
+	if ref == nil {
+		t.Fatal("ExtractStyle returned nil")
+	}
+	if !strings.Contains(ref.CSS, "font-family: Georgia") || !strings.Contains(ref.CSS, ".total { background: #fd4; }") {
+		t.Errorf("CSS = %q", ref.CSS)
+	}
+	if want := "header.top.brand, h1, table.items, div.row, footer"; ref.Layout != want {
+		t.Errorf("Layout = %q, want %q", ref.Layout, want)
+	}
+	if ref.Truncated {
+		t.Error("Truncated is set for a small stylesheet")
+	}
+}
+
+func TestExtractStyle_NoStylesheet(t *testing.T) {
+	if ref := invoice.ExtractStyle([]byte("<html><body><table></table></body></html>")); ref != nil {
+		t.Errorf("ExtractStyle = %+v, want nil", ref)
+	}
+}
+
+func TestExtractStyle_Truncated(t *testing.T) {
+	var css strings.Builder
+	for css.Len() <= 2*invoice.MaxStyleSize {
+		css.WriteString(".rule-with-a-long-name { color: #123456; margin: 0 auto; }\n")
+	}
+	ref := invoice.ExtractStyle([]byte("<style>" + css.String() + "</style>"))
+	if !ref.Truncated {
+		t.Error("Truncated is not set")
+	}
+	if len(ref.CSS) > invoice.MaxStyleSize {
+		t.Errorf("CSS is %d bytes, over %d", len(ref.CSS), invoice.MaxStyleSize)
+	}
+	if !strings.HasSuffix(ref.CSS, "}") {
+		t.Errorf("CSS is cut inside a rule: ...%q", ref.CSS[len(ref.CSS)-20:])
+	}
+}
+
+func TestLastInvoice(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{
+		"invoice-acme-corp-2024-11.html",
+		"invoice-acme-corp-2024-12.html",
+		"invoice-acme-corp-2024-12.pdf",
+		"invoice-acme-corp-2025-01.html", // the invoice's own month
+		"invoice-acme-corp-2025-02.html",
+		"invoice-globex-2024-12.html",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	path, err := invoice.LastInvoice(dir, testInvoice())
+	if err != nil {
+		t.Fatalf("LastInvoice: %v", err)
+	}
+	if want := filepath.Join(dir, "invoice-acme-corp-2024-12.html"); path != want {
+		t.Errorf("LastInvoice = %q, want %q", path, want)
+	}
+}
+
+func TestLastInvoice_None(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "invoice-globex-2024-12.html"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	path, err := invoice.LastInvoice(dir, testInvoice())
+	if err != nil || path != "" {
+		t.Errorf("LastInvoice = %q, %v; want none", path, err)
+	}
+}
+
+func TestBuildPrompt_StyleReference(t *testing.T) {
+	inv := testInvoice()
+	inv.Style = invoice.ExtractStyle([]byte(styledHTML))
+	prompt := invoice.BuildPrompt(inv, "/tmp/invoice.html")
+	for _, want := range []string{
+		"Style it like the previous invoice",
+		"Style Reference (from the previous invoice):",
+		"- Layout: header.top.brand, h1, table.items, div.row, footer\n",
+		"```css\nbody { font-family: Georgia; color: #223; }",
+		"- The same visual identity as the style reference, not a new design\n",
+	} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt is missing %q", want)
+		}
+	}
+	if strings.Contains(prompt, "random color") {
+		t.Error("prompt still asks for a random color palette")
+	}
+}
@@ -0,0 +1,22 @@
+// Command external is a program outside the invoicer module that uses
+// pkg/invoice, built by TestExternalModule.
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+func main() {
+	inv := &invoice.Invoice{
+		Month:    time.January,
+		Year:     2025,
+		Vendor:   "Jane Smith",
+		Customer: "Acme Corp",
+		Rate:     150,
+		Weeks:    invoice.WeeksForMonth(2025, time.January, 40),
+	}
+	fmt.Println(invoice.InvoiceNumber(inv), invoice.FormatMoney(inv.Total()))
+}
@@ -0,0 +1,169 @@
+package invoice
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// TextWidth is the most columns a line of a plain-text invoice takes.
+const TextWidth = 80
+
+// Column widths of the plain-text line-item table. They add up to TextWidth.
+const (
+	textDescWidth   = 38
+	textHoursWidth  = 10
+	textRateWidth   = 15
+	textAmountWidth = 17
+)
+
+// WriteText writes inv as a fixed-width plain-text invoice, for accounts
+// payable systems that only take text: the invoice number and dates, the
+// vendor and customer side by side, a table of the weeks and adjustments,
+// the total, and any notes.
+// Money and labels are formatted as in the other formats, so the amounts
+// match, and the labels are in the invoice's Language. Lines are at most TextWidth columns; long names wrap.
+func WriteText(w io.Writer, inv *Invoice) error {
+	var sb strings.Builder
+	sb.WriteString(textJustify(inv.label("invoice"), InvoiceNumber(inv)))
+	sb.WriteString(textJustify(inv.label("date")+": "+inv.formatDate(inv.IssueDate()), fmt.Sprintf("%s: %s %d", inv.label("period"), inv.monthName(inv.Month, false), inv.Year)))
+	if inv.PONumber != "" {
+		for _, line := range textWrap(inv.label("po_number")+": "+inv.PONumber, TextWidth) {
+			sb.WriteString(line + "\n")
+		}
+	}
+	sb.WriteString("\n")
+
+	half := TextWidth / 2
+	from := append([]string{inv.label("from") + ":"}, textWrap(inv.Vendor, half-2)...)
+	to := append([]string{inv.label("bill_to") + ":"}, textWrap(inv.Customer, half-2)...)
+	for i := 0; i < max(len(from), len(to)); i++ {
+		var left, right string
+		if i < len(from) {
+			left = from[i]
+		}
+		if i < len(to) {
+			right = to[i]
+		}
+		sb.WriteString(strings.TrimRight(textPad(left, half)+right, " ") + "\n")
+	}
+	sb.WriteString("\n")
+
+	rule := strings.Repeat("-", TextWidth) + "\n"
+	sb.WriteString(textRow(inv.label("description"), inv.label("hours"), inv.label("rate"), inv.label("amount")))
+	sb.WriteString(rule)
+	b := inv.Breakdown()
+	for _, l := range b.Lines {
+		lines := textWrap(l.Description, textDescWidth-2)
+		hours, rate := inv.FormatHours(l.Quantity), inv.FormatRate(l.UnitPrice)
+		if l.Unit == "" {
+			hours, rate = "", ""
+		}
+		sb.WriteString(textRow(lines[0], hours, rate, inv.formatAmount(l.Amount)))
+		for _, line := range lines[1:] {
+			sb.WriteString(line + "\n")
+		}
+	}
+	sb.WriteString(rule)
+	sb.WriteString(textRow("", "", inv.label("total"), inv.formatAmount(b.AmountDue)))
+	if x := inv.Exchange; x != nil {
+		sb.WriteString(fmt.Sprintf("\n%s: %s\n", inv.label("total_due"), inv.FormatAmountDue(b)))
+		sb.WriteString(fmt.Sprintf("%s: 1 %s = %s %s (%s, %s)\n", inv.label("exchange_rate"), Currency, formatFXRate(x.Rate), x.Currency, fxSourceName(x.Source), inv.formatDate(x.Date)))
+	}
+
+	if inv.Notes != "" {
+		sb.WriteString("\n" + inv.label("notes") + ":\n")
+		for _, para := range strings.Split(inv.Notes, "\n") {
+			for _, line := range textWrap(para, TextWidth) {
+				sb.WriteString(line + "\n")
+			}
+		}
+	}
+
+	if p := inv.Payment; p != nil && p.hasAccount() {
+		sb.WriteString("\n" + inv.label("bank_transfer") + ":\n")
+		if p.Name != "" {
+			for _, line := range textWrap(inv.label("account_holder")+": "+p.Name, TextWidth) {
+				sb.WriteString(line + "\n")
+			}
+		}
+		if p.IBAN != "" {
+			sb.WriteString("IBAN: " + FormatIBAN(p.IBAN) + "\n")
+		}
+		if p.BIC != "" {
+			sb.WriteString("BIC: " + strings.ToUpper(p.BIC) + "\n")
+		}
+		if p.RoutingNumber != "" {
+			sb.WriteString(inv.label("routing_number") + ": " + p.RoutingNumber + "\n")
+		}
+		if p.AccountNumber != "" {
+			sb.WriteString(inv.label("account_number") + ": " + p.AccountNumber + "\n")
+		}
+		sb.WriteString(inv.label("reference") + ": " + InvoiceNumber(inv) + "\n")
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// textRow returns a line of the line-item table, with the description on
+// the left and the numbers right-aligned in their columns.
+func textRow(desc, hours, rate, amount string) string {
+	row := textPad(desc, textDescWidth) +
+		textPadLeft(hours, textHoursWidth) +
+		textPadLeft(rate, textRateWidth) +
+		textPadLeft(amount, textAmountWidth)
+	return strings.TrimRight(row, " ") + "\n"
+}
+
+// textJustify returns left and right on one line TextWidth wide, or on two
+// lines, right-aligned, if they do not fit on one.
+func textJustify(left, right string) string {
+	if utf8.RuneCountInString(left)+1+utf8.RuneCountInString(right) > TextWidth {
+		var sb strings.Builder
+		for _, line := range textWrap(left, TextWidth) {
+			sb.WriteString(line + "\n")
+		}
+		for _, line := range textWrap(right, TextWidth) {
+			sb.WriteString(textPadLeft(line, TextWidth) + "\n")
+		}
+		return sb.String()
+	}
+	return textPad(left, TextWidth-utf8.RuneCountInString(right)) + right + "\n"
+}
+
+// textWrap breaks s into lines of at most width characters, between words
+// where it can. A word longer than width is split. It always returns at
+// least one line.
+func textWrap(s string, width int) []string {
+	var lines []string
+	var line []rune
+	for _, word := range strings.Fields(s) {
+		w := []rune(word)
+		if len(line) > 0 && len(line)+1+len(w) > width {
+			lines = append(lines, string(line))
+			line = nil
+		}
+		if len(line) > 0 {
+			line = append(line, ' ')
+		}
+		for len(line)+len(w) > width {
+			n := width - len(line)
+			lines = append(lines, string(append(line, w[:n]...)))
+			line, w = nil, w[n:]
+		}
+		line = append(line, w...)
+	}
+	return append(lines, string(line))
+}
+
+// textPad returns s padded with spaces on the right to width characters.
+func textPad(s string, width int) string {
+	return s + strings.Repeat(" ", max(0, width-utf8.RuneCountInString(s)))
+}
+
+// textPadLeft returns s padded with spaces on the left to width characters.
+func textPadLeft(s string, width int) string {
+	return strings.Repeat(" ", max(0, width-utf8.RuneCountInString(s))) + s
+}
@@ -0,0 +1,134 @@
+package invoice_test
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// checkGolden compares got with testdata/name, or rewrites it with -update.
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("output differs from %s (run go test -update to rewrite it):\ngot:\n%s\nwant:\n%s", path, got, want)
+	}
+}
+
+func TestWriteText(t *testing.T) {
+	oldNow := invoice.Now
+	invoice.Now = func() time.Time { return time.Date(2025, time.February, 3, 9, 0, 0, 0, time.UTC) }
+	t.Cleanup(func() { invoice.Now = oldNow })
+
+	long := testInvoice()
+	long.Vendor = "Jane Contractor Consulting and Software Engineering Services Limited Liability Company"
+	long.Customer = "Acme Corporation International Holdings Worldwide"
+	long.Rate = 187.5
+	long.Weeks = invoice.WeeksForMonth(2025, time.January, 40)
+	long.Payment = &invoice.PaymentDetails{IBAN: testIBAN, BIC: "COBADEFFXXX"}
+
+	tests := []struct {
+		name   string
+		inv    *invoice.Invoice
+		golden string
+	}{
+		{"basic", testInvoice(), "invoice-basic.txt"},
+		{"long names and five-figure total", long, "invoice-long.txt"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := invoice.WriteText(&buf, tt.inv); err != nil {
+				t.Fatalf("WriteText: %v", err)
+			}
+			for i, line := range strings.Split(buf.String(), "\n") {
+				if n := utf8.RuneCountInString(line); n > invoice.TextWidth {
+					t.Errorf("line %d is %d columns: %q", i+1, n, line)
+				}
+			}
+			if !strings.Contains(buf.String(), invoice.FormatMoney(tt.inv.Total())+"\n") {
+				t.Errorf("the total should match the other formats, %s:\n%s", invoice.FormatMoney(tt.inv.Total()), buf.String())
+			}
+			checkGolden(t, tt.golden, buf.Bytes())
+		})
+	}
+}
+
+func TestWriteText_Wraps(t *testing.T) {
+	inv := testInvoice()
+	inv.Customer = strings.Repeat("Verylongcustomername", 5) + " Inc"
+	var buf bytes.Buffer
+	if err := invoice.WriteText(&buf, inv); err != nil {
+		t.Fatalf("WriteText: %v", err)
+	}
+	out := buf.String()
+	// The name and the invoice number made from it are split, not cut off.
+	if got := strings.Join(strings.Fields(strings.ReplaceAll(out, "\n", "")), ""); !strings.Contains(got, strings.Repeat("Verylongcustomername", 5)+"Inc") {
+		t.Errorf("the customer name was lost:\n%s", out)
+	}
+	for i, line := range strings.Split(out, "\n") {
+		if n := utf8.RuneCountInString(line); n > invoice.TextWidth {
+			t.Errorf("line %d is %d columns: %q", i+1, n, line)
+		}
+	}
+}
+
+func TestWriteText_Languages(t *testing.T) {
+	oldNow := invoice.Now
+	invoice.Now = func() time.Time { return time.Date(2025, time.February, 3, 9, 0, 0, 0, time.UTC) }
+	t.Cleanup(func() { invoice.Now = oldNow })
+
+	// English labels that are not also words of the language.
+	english := []string{
+		"INVOICE", "Date:", "Period", "PO Number", "From:", "Bill To", "Hours",
+		"Rate", "Amount", "Total", "Notes", "Payment", "Account", "Routing",
+		"Reference", "January", "February", "Jan ",
+	}
+	tests := []struct {
+		lang   string
+		golden string
+		shared []string
+	}{
+		{"de", "invoice-de.txt", nil},
+		{"fr", "invoice-fr.txt", []string{"Date:", "Total"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.lang, func(t *testing.T) {
+			inv := testInvoice()
+			inv.Language = tt.lang
+			inv.PONumber = "PO-4471"
+			inv.Notes = "Thank you for your business."
+			inv.Payment = &invoice.PaymentDetails{Name: "Jane Contractor", IBAN: testIBAN, BIC: "COBADEFFXXX", RoutingNumber: "011000015", AccountNumber: "123456789"}
+			var buf bytes.Buffer
+			if err := invoice.WriteText(&buf, inv); err != nil {
+				t.Fatalf("WriteText: %v", err)
+			}
+			text := strings.ReplaceAll(buf.String(), inv.Notes, "")
+			for _, label := range english {
+				if !slices.Contains(tt.shared, label) && strings.Contains(text, label) {
+					t.Errorf("the English label %q shows in the %s invoice:\n%s", label, tt.lang, text)
+				}
+			}
+			checkGolden(t, tt.golden, buf.Bytes())
+		})
+	}
+}
@@ -0,0 +1,189 @@
+package invoice
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+)
+
+// DefaultThumbnailWidth is the width of a thumbnail in pixels when none is
+// configured.
+const DefaultThumbnailWidth = 300
+
+// The invoice is laid out for a thumbnail on a US Letter page at 96 pixels
+// per inch, which is then scaled down to the thumbnail's width.
+const (
+	thumbnailPageWidth  = 816
+	thumbnailPageHeight = 1056
+)
+
+// thumbnailScale returns the device scale factor that makes a page
+// thumbnailPageWidth wide width pixels wide.
+func thumbnailScale(width int) float64 {
+	return float64(width) / thumbnailPageWidth
+}
+
+// screenshotArgs returns the arguments for taking a thumbnail with a
+// Chromium-based browser.
+func screenshotArgs(htmlPath, pngPath string, width int, extra []string) []string {
+	args := []string{
+		"--headless", "--disable-gpu", "--no-sandbox", "--hide-scrollbars",
+		fmt.Sprintf("--window-size=%d,%d", thumbnailPageWidth, thumbnailPageHeight),
+		"--force-device-scale-factor=" + strconv.FormatFloat(thumbnailScale(width), 'f', -1, 64),
+	}
+	args = append(args, extra...)
+	return append(args, "--screenshot="+pngPath, FileURL(htmlPath))
+}
+
+// RenderThumbnail renders htmlPath to a PNG at pngPath, width pixels wide,
+// and returns the name of the browser or engine used. The thumbnail shows
+// the first page of the invoice as it would be printed on US Letter paper.
+// opts chooses the engine and browser as for ConvertToPDF, except that a
+// PDF tool that is not Chromium, Chrome or Edge is passed over for the
+// first of those installed; its extra arguments are then not used either.
+// A browser that exits cleanly must still leave a file that passes
+// VerifyPNG. The browser writes to a staging file next to pngPath, which
+// is renamed into place once it is verified, so a failed run leaves no
+// partial thumbnail behind and an existing one as it was.
+func RenderThumbnail(ctx context.Context, htmlPath, pngPath string, width int, opts PDFOptions) (string, error) {
+	if width <= 0 {
+		return "", fmt.Errorf("thumbnail width must be positive, got %d", width)
+	}
+	if err := CheckPDFEngine(opts.Engine); err != nil {
+		return "", err
+	}
+	tool, path, args, err := thumbnailBrowser(opts)
+	if err != nil {
+		return "", err
+	}
+	// The staging file keeps the .png extension, which a browser may take
+	// the image format from.
+	staged := stagingPath(pngPath) + ".png"
+	defer os.Remove(staged)
+	name, err := screenshot(ctx, tool, path, args, htmlPath, staged, width, opts)
+	if err != nil {
+		return name, err
+	}
+	if err := os.Rename(staged, pngPath); err != nil {
+		return name, fmt.Errorf("saving the thumbnail: %w", err)
+	}
+	return name, nil
+}
+
+// screenshot takes the thumbnail of htmlPath to pngPath with the browser
+// thumbnailBrowser chose, over the DevTools protocol or by running it, as
+// opts.Engine says, and returns the browser or engine used.
+func screenshot(ctx context.Context, tool pdfTool, path string, args []string, htmlPath, pngPath string, width int, opts PDFOptions) (string, error) {
+	if opts.Engine == ChromedpPDFEngine || opts.Engine == AutoPDFEngine {
+		err := screenshotChromedp(ctx, tool, path, args, htmlPath, pngPath, width)
+		if err == nil {
+			err = VerifyPNG(pngPath)
+		}
+		if err == nil {
+			return ChromedpPDFEngine, nil
+		}
+		_ = os.Remove(pngPath)
+		if ctx.Err() != nil {
+			return ChromedpPDFEngine, pdfToolStopped(ctx, ChromedpPDFEngine)
+		}
+		if opts.Engine == ChromedpPDFEngine {
+			return ChromedpPDFEngine, fmt.Errorf("%s: %w", ChromedpPDFEngine, err)
+		}
+		if opts.OnFallback != nil {
+			opts.OnFallback(err)
+		}
+	}
+
+	tail, err := runTail(command(ctx, path, screenshotArgs(htmlPath, pngPath, width, args)...))
+	if err != nil {
+		if ctx.Err() != nil {
+			return tool.Name, pdfToolStopped(ctx, tool.Name)
+		}
+		return tool.Name, fmt.Errorf("%s: %w", tool.Name, err)
+	}
+	if err := VerifyPNG(pngPath); err != nil {
+		if tail.Len() > 0 {
+			return tool.Name, fmt.Errorf("%s exited cleanly but %w: %s", tool.Name, err, tail)
+		}
+		return tool.Name, fmt.Errorf("%s exited cleanly but %w", tool.Name, err)
+	}
+	return tool.Name, nil
+}
+
+// thumbnailBrowser returns the browser to take a thumbnail with, its
+// executable, and the extra arguments to start it with: the PDF tool in
+// opts if that is a browser, or else the first browser installed.
+func thumbnailBrowser(opts PDFOptions) (pdfTool, string, []string, error) {
+	if opts.ToolPath != "" || (opts.Tool != "" && opts.Tool != AutoPDFTool) {
+		if tool, path, err := selectPDFTool(opts); err == nil && slices.Contains(browserPDFTools, tool.Name) {
+			return tool, path, opts.Args, nil
+		}
+	}
+	tool, path, err := findBrowser(PDFOptions{})
+	var missing *NotInstalledError
+	if errors.As(err, &missing) {
+		return pdfTool{}, "", nil, &NotInstalledError{
+			Tool:    missing.Tool,
+			Purpose: "invoicer renders thumbnails with Chromium, Chrome or Edge in headless mode.",
+			Install: missing.Install,
+			Instead: "Or leave out --thumbnail (set thumbnail: false in the config).",
+		}
+	}
+	return tool, path, nil, err
+}
+
+// screenshotChromedp takes a thumbnail of htmlPath with a headless browser
+// driven over the DevTools protocol.
+func screenshotChromedp(ctx context.Context, tool pdfTool, path string, args []string, htmlPath, pngPath string, width int) error {
+	var png []byte
+	err := chromedpPage(ctx, tool, path, htmlPath, args, func(ctx context.Context) error {
+		err := chromedp.EmulateViewport(thumbnailPageWidth, thumbnailPageHeight, chromedp.EmulateScale(thumbnailScale(width))).Do(ctx)
+		if err == nil {
+			png, err = page.CaptureScreenshot().WithFormat(page.CaptureScreenshotFormatPng).Do(ctx)
+		}
+		if err != nil {
+			return fmt.Errorf("taking a screenshot with %s: %w", tool.Name, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(pngPath, png, 0o644)
+}
+
+// pngSignature is the first eight bytes of every PNG file.
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// VerifyPNG checks that path is a file that starts with the PNG signature
+// and has an image after it.
+func VerifyPNG(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("the thumbnail is missing: %w", err)
+	}
+	defer f.Close()
+	head := make([]byte, maxQuoted)
+	n, err := io.ReadFull(f, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("reading the thumbnail: %w", err)
+	}
+	head = head[:n]
+	switch {
+	case n == 0:
+		return fmt.Errorf("the thumbnail %s is empty", path)
+	case !bytes.HasPrefix(head, pngSignature):
+		return fmt.Errorf("%s is not a PNG; it starts with %q", path, head)
+	case n == len(pngSignature):
+		return fmt.Errorf("the thumbnail %s has no image after the PNG signature", path)
+	}
+	return nil
+}
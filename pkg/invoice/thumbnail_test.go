@@ -0,0 +1,182 @@
+package invoice_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+// fakeScreenshotScript records its arguments to args.txt beside itself and
+// writes a PNG signature followed by a little data to the --screenshot
+// path, using only shell builtins.
+const fakeScreenshotScript = `#!/bin/sh
+dir=${0%/*}
+printf '%s\n' "$@" > "$dir/args.txt"
+for arg; do
+	case $arg in --screenshot=*) out=${arg#--screenshot=};; esac
+done
+printf '\211PNG\r\n\032\nIHDR' > "$out"
+`
+
+func TestRenderThumbnail_Args(t *testing.T) {
+	binDir := t.TempDir()
+	writeFakeTool(t, binDir, "chromium", fakeScreenshotScript)
+	writeFakeTool(t, binDir, "wkhtmltopdf", fakeWkhtmltopdfScript)
+	isolatePDFTools(t, binDir)
+	outDir := t.TempDir()
+	htmlPath := writeTestHTML(t, outDir)
+	pngPath := filepath.Join(outDir, "invoice.png")
+
+	// The configured PDF tool is not a browser, so it and its arguments
+	// are passed over.
+	opts := invoice.PDFOptions{Tool: "wkhtmltopdf", Args: []string{"--enable-local-file-access"}}
+	tool, err := invoice.RenderThumbnail(context.Background(), htmlPath, pngPath, 408, opts)
+	if err != nil {
+		t.Fatalf("RenderThumbnail: %v", err)
+	}
+	if tool != "chromium" {
+		t.Errorf("tool = %q, want chromium", tool)
+	}
+	got := readLines(t, filepath.Join(binDir, "args.txt"))
+	want := []string{
+		"--headless", "--disable-gpu", "--no-sandbox", "--hide-scrollbars",
+		"--window-size=816,1056", "--force-device-scale-factor=0.5",
+		"--screenshot=", invoice.FileURL(htmlPath),
+	}
+	// The browser writes to a staging file, which is renamed into place.
+	if len(got) == len(want) {
+		if staged := got[6]; strings.HasPrefix(staged, "--screenshot="+pngPath+".tmp-") && strings.HasSuffix(staged, ".png") {
+			got[6] = "--screenshot="
+		}
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("arguments = %q, want %q with a staging file next to %s", got, want, pngPath)
+	}
+	if err := invoice.VerifyPNG(pngPath); err != nil {
+		t.Error(err)
+	}
+	if staged, _ := filepath.Glob(pngPath + ".tmp-*"); len(staged) > 0 {
+		t.Errorf("staging files left behind: %v", staged)
+	}
+}
+
+func TestRenderThumbnail_BrowserArgs(t *testing.T) {
+	binDir := t.TempDir()
+	writeFakeTool(t, binDir, "google-chrome", fakeScreenshotScript)
+	writeFakeTool(t, binDir, "chromium", "#!/bin/sh\nexit 1\n")
+	isolatePDFTools(t, binDir)
+	outDir := t.TempDir()
+	htmlPath := writeTestHTML(t, outDir)
+
+	opts := invoice.PDFOptions{Tool: "chrome", Args: []string{"--lang=de"}}
+	tool, err := invoice.RenderThumbnail(context.Background(), htmlPath, filepath.Join(outDir, "invoice.png"), 300, opts)
+	if err != nil {
+		t.Fatalf("RenderThumbnail: %v", err)
+	}
+	if tool != "chrome" {
+		t.Errorf("tool = %q, want the configured browser", tool)
+	}
+	got := readLines(t, filepath.Join(binDir, "args.txt"))
+	if len(got) != 9 || got[6] != "--lang=de" {
+		t.Errorf("the browser's extra arguments should come before the output: %q", got)
+	}
+}
+
+func TestRenderThumbnail_NotAPNG(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   string
+	}{
+		{"nothing written", "#!/bin/sh\necho 'GPU process crashed' >&2\n", "the thumbnail is missing"},
+		{"empty", "#!/bin/sh\nfor arg; do case $arg in --screenshot=*) : > \"${arg#--screenshot=}\";; esac; done\n", "is empty"},
+		{"not a PNG", "#!/bin/sh\nfor arg; do case $arg in --screenshot=*) echo '<html>' > \"${arg#--screenshot=}\";; esac; done\n", "is not a PNG"},
+		{"signature only", "#!/bin/sh\nfor arg; do case $arg in --screenshot=*) printf '\\211PNG\\r\\n\\032\\n' > \"${arg#--screenshot=}\";; esac; done\n", "no image after the PNG signature"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			binDir := t.TempDir()
+			writeFakeTool(t, binDir, "chromium", tt.script)
+			isolatePDFTools(t, binDir)
+			outDir := t.TempDir()
+			htmlPath := writeTestHTML(t, outDir)
+			pngPath := filepath.Join(outDir, "invoice.png")
+
+			_, err := invoice.RenderThumbnail(context.Background(), htmlPath, pngPath, 300, invoice.PDFOptions{})
+			if err == nil || !strings.Contains(err.Error(), "chromium exited cleanly but") || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("expected %q, got %v", tt.want, err)
+			}
+			if _, err := os.Stat(pngPath); !os.IsNotExist(err) {
+				t.Errorf("a bad thumbnail should be removed, stat err = %v", err)
+			}
+		})
+	}
+}
+
+func TestRenderThumbnail_KeepsExistingThumbnail(t *testing.T) {
+	binDir := t.TempDir()
+	writeFakeTool(t, binDir, "chromium", "#!/bin/sh\nfor arg; do case $arg in --screenshot=*) echo '<html>' > \"${arg#--screenshot=}\";; esac; done\n")
+	isolatePDFTools(t, binDir)
+	outDir := t.TempDir()
+	htmlPath := writeTestHTML(t, outDir)
+	pngPath := filepath.Join(outDir, "invoice.png")
+	old := "\x89PNG\r\n\x1a\nlast month"
+	if err := os.WriteFile(pngPath, []byte(old), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := invoice.RenderThumbnail(context.Background(), htmlPath, pngPath, 300, invoice.PDFOptions{}); err == nil {
+		t.Fatal("expected an error for a browser that writes no PNG")
+	}
+	if data, err := os.ReadFile(pngPath); err != nil || string(data) != old {
+		t.Errorf("the existing thumbnail should be left as it was, got %q, %v", data, err)
+	}
+	if staged, _ := filepath.Glob(pngPath + ".tmp-*"); len(staged) > 0 {
+		t.Errorf("staging files left behind: %v", staged)
+	}
+}
+
+func TestRenderThumbnail_NoBrowser(t *testing.T) {
+	binDir := t.TempDir()
+	writeFakeTool(t, binDir, "wkhtmltopdf", fakeWkhtmltopdfScript)
+	isolatePDFTools(t, binDir)
+	outDir := t.TempDir()
+	htmlPath := writeTestHTML(t, outDir)
+
+	_, err := invoice.RenderThumbnail(context.Background(), htmlPath, filepath.Join(outDir, "invoice.png"), 300, invoice.PDFOptions{})
+	var missing *invoice.NotInstalledError
+	if !errors.As(err, &missing) || !strings.Contains(err.Error(), "renders thumbnails") || !strings.Contains(err.Error(), "--thumbnail") {
+		t.Fatalf("expected a browser to be reported missing, got %v", err)
+	}
+}
+
+func TestRenderThumbnail_BadWidth(t *testing.T) {
+	if _, err := invoice.RenderThumbnail(context.Background(), "invoice.html", "invoice.png", 0, invoice.PDFOptions{}); err == nil {
+		t.Error("a zero width should be rejected")
+	}
+}
+
+// TestRenderThumbnail_Chromedp takes a thumbnail with a real browser, if
+// one is installed.
+func TestRenderThumbnail_Chromedp(t *testing.T) {
+	outDir := t.TempDir()
+	htmlPath := writeTestHTML(t, outDir)
+	pngPath := filepath.Join(outDir, "invoice.png")
+
+	_, err := invoice.RenderThumbnail(context.Background(), htmlPath, pngPath, 200, invoice.PDFOptions{Engine: invoice.ChromedpPDFEngine})
+	var missing *invoice.NotInstalledError
+	if errors.As(err, &missing) {
+		t.Skip("no Chromium-based browser installed")
+	}
+	if err != nil {
+		t.Fatalf("RenderThumbnail: %v", err)
+	}
+	if err := invoice.VerifyPNG(pngPath); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,307 @@
+package invoice
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// TimesheetEntry is the hours worked on one day.
+type TimesheetEntry struct {
+	Date  time.Time
+	Hours float64
+}
+
+// timesheetYAML is the YAML form of a timesheet:
+//
+//	entries:
+//	  - date: 2025-01-06
+//	    hours: 8
+type timesheetYAML struct {
+	Entries []struct {
+		Date  string  `yaml:"date"`
+		Hours float64 `yaml:"hours"`
+	} `yaml:"entries"`
+}
+
+// ErrEmptyTimesheet is returned by ReadTimesheet for a timesheet with no
+// entries, so an empty input is not taken for a month without work.
+var ErrEmptyTimesheet = errors.New("the timesheet has no entries")
+
+// Timesheet formats ReadTimesheetFormat names, besides those of
+// timesheetFormats.
+const (
+	TimesheetYAML = "YAML"
+	TimesheetCSV  = "CSV"
+)
+
+// timesheetFormat is the CSV export of a time tracker, told apart by the
+// columns of its header.
+type timesheetFormat struct {
+	// Name is the tracker, e.g. "Toggl".
+	Name string
+	// Date and Hours name the columns of each entry's date and hours.
+	Date, Hours string
+	// Also names further columns the header must have, to tell the format
+	// from others with the same date and hours columns.
+	Also []string
+	// Layouts are the layouts the dates may be in; the first is shown in
+	// errors.
+	Layouts []string
+}
+
+// timesheetFormats are the CSV timesheets ReadTimesheet knows, in the
+// order their headers are tried. Columns are matched ignoring case, and
+// hours may be decimal, e.g. "1.5", or a duration, e.g. "1:30:00".
+var timesheetFormats = []timesheetFormat{
+	// Toggl Track's detailed report export.
+	{Name: "Toggl", Date: "Start date", Hours: "Duration", Also: []string{"Start time"}, Layouts: []string{time.DateOnly}},
+	// Clockify's detailed report export, with its dates month first.
+	{Name: "Clockify", Date: "Start Date", Hours: "Duration (decimal)", Layouts: []string{"01/02/2006", time.DateOnly}},
+	// Harvest's time report export.
+	{Name: "Harvest", Date: "Date", Hours: "Hours", Also: []string{"Project", "Task"}, Layouts: []string{time.DateOnly}},
+	// A plain timesheet, which may also have no header.
+	{Name: TimesheetCSV, Date: "date", Hours: "hours", Layouts: []string{time.DateOnly}},
+}
+
+// ReadTimesheet reads a timesheet from r, in CSV or in YAML. The format is
+// told from the first line that is not empty or a # comment: YAML if it
+// starts with "entries:", CSV otherwise. A CSV timesheet has a date and
+// hours on each line, e.g. "2025-01-06,8", with an optional "date,hours"
+// header and any columns after those ignored; the exports of Toggl,
+// Clockify and Harvest are read too, told apart by their headers. Dates
+// are YYYY-MM-DD, and a day may have several entries, but no entry may
+// have negative hours and no day more than 24.
+func ReadTimesheet(r io.Reader) ([]TimesheetEntry, error) {
+	entries, _, err := ReadTimesheetFormat(r)
+	return entries, err
+}
+
+// ReadTimesheetFormat is ReadTimesheet that also returns the name of the
+// format it read: TimesheetYAML, TimesheetCSV or a time tracker, such as
+// "Toggl".
+func ReadTimesheetFormat(r io.Reader) ([]TimesheetEntry, string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, "", fmt.Errorf("reading the timesheet: %w", err)
+	}
+	// Trackers' exports often start with a byte order mark.
+	data = bytes.TrimPrefix(data, []byte("\uFEFF"))
+	var entries []TimesheetEntry
+	format := TimesheetYAML
+	if timesheetIsYAML(data) {
+		entries, err = readTimesheetYAML(data)
+	} else {
+		entries, format, err = readTimesheetCSV(data)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	if len(entries) == 0 {
+		return nil, "", ErrEmptyTimesheet
+	}
+	days := map[time.Time]float64{}
+	for _, e := range entries {
+		days[e.Date] += e.Hours
+		if days[e.Date] > 24 {
+			return nil, "", fmt.Errorf("timesheet: %s has more than 24 hours", e.Date.Format(time.DateOnly))
+		}
+	}
+	return entries, format, nil
+}
+
+// timesheetIsYAML reports whether the first line of data that is not
+// empty or a comment starts a YAML timesheet.
+func timesheetIsYAML(data []byte) bool {
+	s := bufio.NewScanner(bytes.NewReader(data))
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		return strings.HasPrefix(line, "entries:")
+	}
+	return false
+}
+
+func readTimesheetYAML(data []byte) ([]TimesheetEntry, error) {
+	var ts timesheetYAML
+	if err := yaml.Unmarshal(data, &ts); err != nil {
+		return nil, fmt.Errorf("parsing the timesheet: %w", err)
+	}
+	entries := make([]TimesheetEntry, 0, len(ts.Entries))
+	for i, e := range ts.Entries {
+		entry, err := timesheetEntry(e.Date, e.Hours, time.DateOnly)
+		if err != nil {
+			return nil, fmt.Errorf("timesheet: entries[%d]: %w", i, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// readTimesheetCSV reads a CSV timesheet in the format its first line
+// tells, and returns the format's name. A first line that is an entry
+// starts a plain timesheet without a header.
+func readTimesheetCSV(data []byte) ([]TimesheetEntry, string, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+	r.Comment = '#'
+	r.TrimLeadingSpace = true
+	var (
+		entries []TimesheetEntry
+		format  timesheetFormat
+		// date and hours are the columns of the format's date and hours.
+		date, hours int
+		first       = true
+	)
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, "", fmt.Errorf("parsing the timesheet: %w", err)
+		}
+		line, _ := r.FieldPos(0)
+		if first {
+			first = false
+			if timesheetIsEntry(record) {
+				format, date, hours = timesheetFormats[len(timesheetFormats)-1], 0, 1
+			} else {
+				if format, date, hours, err = timesheetHeader(record); err != nil {
+					return nil, "", fmt.Errorf("timesheet: line %d: %w", line, err)
+				}
+				continue
+			}
+		}
+		if len(record) <= max(date, hours) {
+			return nil, "", fmt.Errorf("timesheet: line %d: want a date and hours, got %q", line, strings.Join(record, ","))
+		}
+		h, err := parseTimesheetHours(record[hours])
+		if err != nil {
+			return nil, "", fmt.Errorf("timesheet: line %d: hours must be a number, got %q", line, record[hours])
+		}
+		entry, err := timesheetEntry(record[date], h, format.Layouts...)
+		if err != nil {
+			return nil, "", fmt.Errorf("timesheet: line %d: %w", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, format.Name, nil
+}
+
+// timesheetIsEntry reports whether record, the first of a CSV timesheet,
+// is an entry rather than a header: a header has neither a date first nor
+// hours second.
+func timesheetIsEntry(record []string) bool {
+	if _, err := time.Parse(time.DateOnly, strings.TrimSpace(record[0])); err == nil {
+		return true
+	}
+	if len(record) < 2 {
+		return false
+	}
+	_, err := parseTimesheetHours(record[1])
+	return err == nil
+}
+
+// timesheetHeader returns the format whose columns header has, and the
+// columns of its date and hours.
+func timesheetHeader(header []string) (timesheetFormat, int, int, error) {
+	column := func(name string) int {
+		for i, h := range header {
+			if strings.EqualFold(strings.TrimSpace(h), name) {
+				return i
+			}
+		}
+		return -1
+	}
+	var known []string
+	for _, f := range timesheetFormats {
+		date, hours := column(f.Date), column(f.Hours)
+		matches := date >= 0 && hours >= 0
+		for _, c := range f.Also {
+			matches = matches && column(c) >= 0
+		}
+		if matches {
+			return f, date, hours, nil
+		}
+		known = append(known, fmt.Sprintf("%s (%s)", f.Name, strings.Join(append([]string{f.Date, f.Hours}, f.Also...), ", ")))
+	}
+	if len(header) < 2 {
+		return timesheetFormat{}, 0, 0, fmt.Errorf("want a date and hours, got %q", strings.Join(header, ","))
+	}
+	return timesheetFormat{}, 0, 0, fmt.Errorf("unknown timesheet header %q; known headers have the columns of %s",
+		strings.Join(header, ","), strings.Join(known, "; "))
+}
+
+// parseTimesheetHours parses hours written as a number, e.g. "1.5", or as
+// a duration, e.g. "1:30" or "1:30:00".
+func parseTimesheetHours(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	if !strings.Contains(s, ":") {
+		return strconv.ParseFloat(s, 64)
+	}
+	parts := strings.Split(s, ":")
+	if len(parts) > 3 {
+		return 0, fmt.Errorf("%q is not a duration", s)
+	}
+	var hours float64
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 || (i > 0 && (n >= 60 || len(p) != 2)) {
+			return 0, fmt.Errorf("%q is not a duration", s)
+		}
+		hours += float64(n) / math.Pow(60, float64(i))
+	}
+	return hours, nil
+}
+
+// timesheetEntry checks and returns the entry for date, in the first of
+// layouts it is in, and hours.
+func timesheetEntry(date string, hours float64, layouts ...string) (TimesheetEntry, error) {
+	date = strings.TrimSpace(date)
+	for _, layout := range layouts {
+		d, err := time.Parse(layout, date)
+		if err != nil {
+			continue
+		}
+		if hours < 0 || hours > 24 || math.IsNaN(hours) {
+			return TimesheetEntry{}, fmt.Errorf("hours of %s must be between 0 and 24, got %v", date, hours)
+		}
+		return TimesheetEntry{Date: d, Hours: hours}, nil
+	}
+	example := time.Date(2025, time.January, 6, 0, 0, 0, 0, time.UTC).Format(layouts[0])
+	return TimesheetEntry{}, fmt.Errorf("%q is not a date like %s", date, example)
+}
+
+// ApplyTimesheet sets the hours of each of weeks to the sum of the
+// entries in it. An entry in none of the weeks is an error.
+func ApplyTimesheet(weeks []Week, entries []TimesheetEntry) error {
+	for i := range weeks {
+		weeks[i].Hours = 0
+	}
+	for _, e := range entries {
+		found := false
+		for i, w := range weeks {
+			if !e.Date.Before(w.Start) && !e.Date.After(w.End) {
+				weeks[i].Hours += e.Hours
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("timesheet: %s is outside the invoiced weeks", e.Date.Format(time.DateOnly))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package invoice_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+func TestReadTimesheet(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"csv", "date,hours\n2025-01-06,8\n2025-01-07,7.5\n2025-01-07,0.5\n2025-01-13,4\n"},
+		{"csv without header", "\n# January\n2025-01-06, 8, planning\n2025-01-07,7.5\n2025-01-07,0.5\n2025-01-13,4\n"},
+		{"yaml", "# January\nentries:\n  - date: 2025-01-06\n    hours: 8\n  - date: 2025-01-07\n    hours: 7.5\n  - date: 2025-01-07\n    hours: 0.5\n  - date: 2025-01-13\n    hours: 4\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := invoice.ReadTimesheet(strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatalf("ReadTimesheet: %v", err)
+			}
+			weeks := invoice.WeeksForMonth(2025, time.January, 40)
+			if err := invoice.ApplyTimesheet(weeks, entries); err != nil {
+				t.Fatalf("ApplyTimesheet: %v", err)
+			}
+			want := []float64{0, 16, 4, 0, 0}
+			for i, w := range weeks {
+				if w.Hours != want[i] {
+					t.Errorf("week %d hours = %v, want %v", i, w.Hours, want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestReadTimesheetFormat_Trackers(t *testing.T) {
+	tests := []struct {
+		file, format string
+	}{
+		{"timesheet-toggl.csv", "Toggl"},
+		{"timesheet-clockify.csv", "Clockify"},
+		{"timesheet-harvest.csv", "Harvest"},
+		{"timesheet-generic.csv", invoice.TimesheetCSV},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			f, err := os.Open(filepath.Join("testdata", tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			entries, format, err := invoice.ReadTimesheetFormat(f)
+			if err != nil {
+				t.Fatalf("ReadTimesheetFormat: %v", err)
+			}
+			if format != tt.format {
+				t.Errorf("format = %q, want %q", format, tt.format)
+			}
+			weeks := invoice.WeeksForMonth(2025, time.January, 40)
+			if err := invoice.ApplyTimesheet(weeks, entries); err != nil {
+				t.Fatalf("ApplyTimesheet: %v", err)
+			}
+			want := []float64{0, 16, 4, 0, 0}
+			for i, w := range weeks {
+				if w.Hours != want[i] {
+					t.Errorf("week %d hours = %v, want %v", i, w.Hours, want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestReadTimesheet_UnknownFormat(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "timesheet-unknown.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	_, err = invoice.ReadTimesheet(f)
+	if err == nil {
+		t.Fatal("expected an unknown format error")
+	}
+	for _, want := range []string{`unknown timesheet header "Day,Worked,Comment"`, "Toggl (Start date, Duration", "Clockify (Start Date, Duration (decimal))", "Harvest (Date, Hours", "CSV (date, hours)"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %q", err, want)
+		}
+	}
+}
+
+func TestReadTimesheet_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr string
+	}{
+		{"bad date", "2025-13-01,8\n", `"2025-13-01" is not a date`},
+		{"bad hours", "date,hours\n2025-01-06,eight\n", "line 2: hours must be a number"},
+		{"negative hours", "2025-01-06,-1\n", "must be between 0 and 24"},
+		{"one column", "2025-01-06\n", "line 1: want a date and hours"},
+		{"over a day", "2025-01-06,16\n2025-01-06,9\n", "2025-01-06 has more than 24 hours"},
+		{"bad duration", "Start date,Start time,Duration\n2025-01-06,09:00:00,8:75:00\n", "line 2: hours must be a number"},
+		{"bad clockify date", "Start Date,Duration (decimal)\n2025/01/06,8\n", `"2025/01/06" is not a date like 01/06/2025`},
+		{"bad yaml entry", "entries:\n  - date: soon\n    hours: 8\n", "entries[0]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := invoice.ReadTimesheet(strings.NewReader(tt.input))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestReadTimesheet_Empty(t *testing.T) {
+	for _, input := range []string{"", "\n\n", "date,hours\n", "entries:\n"} {
+		if _, err := invoice.ReadTimesheet(strings.NewReader(input)); !errors.Is(err, invoice.ErrEmptyTimesheet) {
+			t.Errorf("%q: expected ErrEmptyTimesheet, got %v", input, err)
+		}
+	}
+}
+
+func TestApplyTimesheet_OutsideWeeks(t *testing.T) {
+	weeks := invoice.WeeksForMonth(2025, time.January, 40)
+	entries := []invoice.TimesheetEntry{{Date: time.Date(2025, time.March, 3, 0, 0, 0, 0, time.UTC), Hours: 8}}
+	err := invoice.ApplyTimesheet(weeks, entries)
+	if err == nil || !strings.Contains(err.Error(), "2025-03-03 is outside the invoiced weeks") {
+		t.Errorf("expected an outside-weeks error, got %v", err)
+	}
+}
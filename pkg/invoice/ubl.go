@@ -0,0 +1,307 @@
+package invoice
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// UBLParty is what an e-invoice needs to know about the seller or the
+// buyer beyond their name.
+type UBLParty struct {
+	// Country is the ISO 3166-1 alpha-2 code of the country the party is
+	// in, e.g. "DE". Required.
+	Country string
+	// VATID is the party's VAT identifier with its country prefix, e.g.
+	// "DE123456789". Optional.
+	VATID string
+}
+
+// UBL tax categories (UNCL5305) invoicer can use. invoicer does not charge
+// VAT, so an invoice is either outside its scope or reverse charged.
+const (
+	ublNotSubjectToVAT = "O"
+	ublReverseCharge   = "AE"
+)
+
+// countryCodes lists the ISO 3166-1 alpha-2 country codes.
+var countryCodes = strings.Fields(`
+AD AE AF AG AI AL AM AO AQ AR AS AT AU AW AX AZ BA BB BD BE BF BG BH BI BJ
+BL BM BN BO BQ BR BS BT BV BW BY BZ CA CC CD CF CG CH CI CK CL CM CN CO CR
+CU CV CW CX CY CZ DE DJ DK DM DO DZ EC EE EG EH ER ES ET FI FJ FK FM FO FR
+GA GB GD GE GF GG GH GI GL GM GN GP GQ GR GS GT GU GW GY HK HM HN HR HT HU
+ID IE IL IM IN IO IQ IR IS IT JE JM JO JP KE KG KH KI KM KN KP KR KW KY KZ
+LA LB LC LI LK LR LS LT LU LV LY MA MC MD ME MF MG MH MK ML MM MN MO MP MQ
+MR MS MT MU MV MW MX MY MZ NA NC NE NF NG NI NL NO NP NR NU NZ OM PA PE PF
+PG PH PK PL PM PN PR PS PT PW PY QA RE RO RS RU RW SA SB SC SD SE SG SH SI
+SJ SK SL SM SN SO SR SS ST SV SX SY SZ TC TD TF TG TH TJ TK TL TM TN TO TR
+TT TV TW TZ UA UG UM US UY UZ VA VC VE VG VI VN VU WF WS YE YT ZA ZM ZW
+`)
+
+// ValidateCountryCode checks that code is an ISO 3166-1 alpha-2 country
+// code in upper case, e.g. "DE".
+func ValidateCountryCode(code string) error {
+	for _, c := range countryCodes {
+		if code == c {
+			return nil
+		}
+	}
+	return fmt.Errorf("country %q is not an ISO 3166-1 alpha-2 code such as DE, FR or US", code)
+}
+
+// vatIDPattern matches the shape of a VAT identifier: a two-letter country
+// prefix (EL for Greece) and 2 to 13 letters, digits and a few symbols.
+var vatIDPattern = regexp.MustCompile(`^[A-Z]{2}[0-9A-Z+*.]{2,13}$`)
+
+// ValidateVATID checks that id is shaped like a VAT identifier with its
+// country prefix, e.g. "DE123456789". It does not ask the tax office.
+func ValidateVATID(id string) error {
+	if !vatIDPattern.MatchString(id) {
+		return fmt.Errorf("VAT ID %q is not valid: it should be a country prefix and 2 to 13 letters and digits, e.g. DE123456789", id)
+	}
+	return nil
+}
+
+// ublLine is one InvoiceLine of a UBL invoice.
+type ublLine struct {
+	ID       int
+	Quantity string
+	UnitCode string
+	Amount   Cents
+	Price    Cents
+	Start    string
+	End      string
+	Name     string
+}
+
+// ublTemplate lays out a UBL 2.1 invoice in the order the schema requires.
+var ublTemplate = template.Must(template.New("ubl").Funcs(template.FuncMap{
+	"x": func(s string) (string, error) {
+		var b bytes.Buffer
+		err := xml.EscapeText(&b, []byte(s))
+		return b.String(), err
+	},
+}).Parse(`<?xml version="1.0" encoding="UTF-8"?>
+<Invoice xmlns="urn:oasis:names:specification:ubl:schema:xsd:Invoice-2" xmlns:cac="urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2" xmlns:cbc="urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2">
+  <cbc:CustomizationID>urn:cen.eu:en16931:2017</cbc:CustomizationID>
+  <cbc:ID>{{x .Number}}</cbc:ID>
+  <cbc:IssueDate>{{.IssueDate}}</cbc:IssueDate>
+{{- if .DueDate}}
+  <cbc:DueDate>{{.DueDate}}</cbc:DueDate>
+{{- end}}
+  <cbc:InvoiceTypeCode>380</cbc:InvoiceTypeCode>
+{{- if .Notes}}
+  <cbc:Note>{{x .Notes}}</cbc:Note>
+{{- end}}
+  <cbc:DocumentCurrencyCode>{{.Currency}}</cbc:DocumentCurrencyCode>
+  <cac:InvoicePeriod>
+    <cbc:StartDate>{{.Start}}</cbc:StartDate>
+    <cbc:EndDate>{{.End}}</cbc:EndDate>
+  </cac:InvoicePeriod>
+{{- if .PONumber}}
+  <cac:OrderReference>
+    <cbc:ID>{{x .PONumber}}</cbc:ID>
+  </cac:OrderReference>
+{{- end}}
+{{- range .Parties}}
+  <cac:{{.Role}}>
+    <cac:Party>
+      <cac:PostalAddress>
+        <cac:Country>
+          <cbc:IdentificationCode>{{.Country}}</cbc:IdentificationCode>
+        </cac:Country>
+      </cac:PostalAddress>
+{{- if .VATID}}
+      <cac:PartyTaxScheme>
+        <cbc:CompanyID>{{x .VATID}}</cbc:CompanyID>
+        <cac:TaxScheme>
+          <cbc:ID>VAT</cbc:ID>
+        </cac:TaxScheme>
+      </cac:PartyTaxScheme>
+{{- end}}
+      <cac:PartyLegalEntity>
+        <cbc:RegistrationName>{{x .Name}}</cbc:RegistrationName>
+      </cac:PartyLegalEntity>
+    </cac:Party>
+  </cac:{{.Role}}>
+{{- end}}
+{{- with .Payment}}
+  <cac:PaymentMeans>
+    <cbc:PaymentMeansCode>58</cbc:PaymentMeansCode>
+    <cbc:PaymentID>{{x $.Number}}</cbc:PaymentID>
+    <cac:PayeeFinancialAccount>
+      <cbc:ID>{{.IBAN}}</cbc:ID>
+{{- if .BIC}}
+      <cac:FinancialInstitutionBranch>
+        <cbc:ID>{{.BIC}}</cbc:ID>
+      </cac:FinancialInstitutionBranch>
+{{- end}}
+    </cac:PayeeFinancialAccount>
+  </cac:PaymentMeans>
+{{- end}}
+  <cac:TaxTotal>
+    <cbc:TaxAmount currencyID="{{.Currency}}">0.00</cbc:TaxAmount>
+    <cac:TaxSubtotal>
+      <cbc:TaxableAmount currencyID="{{.Currency}}">{{.Total}}</cbc:TaxableAmount>
+      <cbc:TaxAmount currencyID="{{.Currency}}">0.00</cbc:TaxAmount>
+      <cac:TaxCategory>
+        <cbc:ID>{{.TaxCategory}}</cbc:ID>
+{{- if .TaxPercent}}
+        <cbc:Percent>0</cbc:Percent>
+{{- end}}
+        <cbc:TaxExemptionReason>{{.TaxReason}}</cbc:TaxExemptionReason>
+        <cac:TaxScheme>
+          <cbc:ID>VAT</cbc:ID>
+        </cac:TaxScheme>
+      </cac:TaxCategory>
+    </cac:TaxSubtotal>
+  </cac:TaxTotal>
+  <cac:LegalMonetaryTotal>
+    <cbc:LineExtensionAmount currencyID="{{.Currency}}">{{.Total}}</cbc:LineExtensionAmount>
+    <cbc:TaxExclusiveAmount currencyID="{{.Currency}}">{{.Total}}</cbc:TaxExclusiveAmount>
+    <cbc:TaxInclusiveAmount currencyID="{{.Currency}}">{{.Total}}</cbc:TaxInclusiveAmount>
+    <cbc:PayableAmount currencyID="{{.Currency}}">{{.Total}}</cbc:PayableAmount>
+  </cac:LegalMonetaryTotal>
+{{- range .Lines}}
+  <cac:InvoiceLine>
+    <cbc:ID>{{.ID}}</cbc:ID>
+    <cbc:InvoicedQuantity unitCode="{{.UnitCode}}">{{.Quantity}}</cbc:InvoicedQuantity>
+    <cbc:LineExtensionAmount currencyID="{{$.Currency}}">{{.Amount}}</cbc:LineExtensionAmount>
+{{- if .Start}}
+    <cac:InvoicePeriod>
+      <cbc:StartDate>{{.Start}}</cbc:StartDate>
+      <cbc:EndDate>{{.End}}</cbc:EndDate>
+    </cac:InvoicePeriod>
+{{- end}}
+    <cac:Item>
+      <cbc:Name>{{x .Name}}</cbc:Name>
+      <cac:ClassifiedTaxCategory>
+        <cbc:ID>{{$.TaxCategory}}</cbc:ID>
+{{- if $.TaxPercent}}
+        <cbc:Percent>0</cbc:Percent>
+{{- end}}
+        <cac:TaxScheme>
+          <cbc:ID>VAT</cbc:ID>
+        </cac:TaxScheme>
+      </cac:ClassifiedTaxCategory>
+    </cac:Item>
+    <cac:Price>
+      <cbc:PriceAmount currencyID="{{$.Currency}}">{{.Price}}</cbc:PriceAmount>
+    </cac:Price>
+  </cac:InvoiceLine>
+{{- end}}
+</Invoice>
+`))
+
+// WriteUBL writes inv as a UBL 2.1 invoice that follows the EN 16931 core
+// rules, for clients that only take structured e-invoices: one line per
+// week, in hours, one per adjustment, and the totals, with the notes and
+// the PO number as the order reference. Amounts are rounded to cents line by
+// line, and the totals are the sum of the lines.
+//
+// invoicer charges no VAT. If both parties have a VAT ID the invoice is
+// reverse charged (category AE), so the buyer accounts for the VAT;
+// otherwise it is outside the scope of VAT (category O), which leaves a
+// buyer's VAT ID out. A seller with a VAT ID billing a buyer without one
+// is an error, as is a party with no valid country.
+func WriteUBL(w io.Writer, inv *Invoice, seller, buyer UBLParty) error {
+	for _, p := range []struct {
+		role  string
+		party UBLParty
+	}{{"seller", seller}, {"buyer", buyer}} {
+		if p.party.Country == "" {
+			return fmt.Errorf("the %s's country is required", p.role)
+		}
+		if err := ValidateCountryCode(p.party.Country); err != nil {
+			return fmt.Errorf("the %s's %w", p.role, err)
+		}
+		if p.party.VATID != "" {
+			if err := ValidateVATID(p.party.VATID); err != nil {
+				return fmt.Errorf("the %s's %w", p.role, err)
+			}
+		}
+	}
+	category, reason, percent := ublNotSubjectToVAT, "Not subject to VAT", false
+	switch {
+	case seller.VATID != "" && buyer.VATID != "":
+		category, reason, percent = ublReverseCharge, "Reverse charge", true
+	case seller.VATID != "":
+		return errors.New("a seller VAT ID needs the buyer's VAT ID too: invoicer charges no VAT, so the invoice must be reverse charged")
+	default:
+		buyer.VATID = ""
+	}
+
+	items, total := invoiceLines(inv)
+	var lines []ublLine
+	for i, l := range items {
+		// A price may not be negative, so a credit is a negative quantity.
+		quantity, price := l.Quantity, l.Price
+		if price < 0 {
+			quantity, price = -quantity, -price
+		}
+		lines = append(lines, ublLine{
+			ID:       i + 1,
+			Quantity: formatQuantity(quantity),
+			UnitCode: unitNameOf(l.Unit).UNECE,
+			Amount:   l.Amount,
+			Price:    price,
+			Start:    formatJSONDate(l.Start),
+			End:      formatJSONDate(l.End),
+			Name:     l.Description,
+		})
+	}
+	if len(lines) == 0 {
+		return errors.New("an e-invoice needs at least one line")
+	}
+
+	first := time.Date(inv.Year, inv.Month, 1, 0, 0, 0, 0, time.UTC)
+	var payment *PaymentDetails
+	if p := inv.Payment; p != nil && p.IBAN != "" {
+		payment = &PaymentDetails{IBAN: normalizeIBAN(p.IBAN), BIC: strings.ToUpper(p.BIC)}
+	}
+	type party struct {
+		Role, Name string
+		UBLParty
+	}
+	return ublTemplate.Execute(w, struct {
+		Number      string
+		PONumber    string
+		Notes       string
+		IssueDate   string
+		DueDate     string
+		Currency    string
+		Start, End  string
+		Parties     []party
+		Payment     *PaymentDetails
+		TaxCategory string
+		TaxReason   string
+		TaxPercent  bool
+		Total       Cents
+		Lines       []ublLine
+	}{
+		Number:    InvoiceNumber(inv),
+		PONumber:  inv.PONumber,
+		Notes:     inv.Notes,
+		IssueDate: formatJSONDate(inv.IssueDate()),
+		DueDate:   formatJSONDate(inv.Due),
+		Currency:  Currency,
+		Start:     formatJSONDate(first),
+		End:       formatJSONDate(first.AddDate(0, 1, -1)),
+		Parties: []party{
+			{"AccountingSupplierParty", inv.Vendor, seller},
+			{"AccountingCustomerParty", inv.Customer, buyer},
+		},
+		Payment:     payment,
+		TaxCategory: category,
+		TaxReason:   reason,
+		TaxPercent:  percent,
+		Total:       total,
+		Lines:       lines,
+	})
+}
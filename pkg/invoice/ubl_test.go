@@ -0,0 +1,228 @@
+package invoice_test
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+var (
+	ublSeller = invoice.UBLParty{Country: "DE", VATID: "DE123456789"}
+	ublBuyer  = invoice.UBLParty{Country: "FR", VATID: "FR12345678901"}
+)
+
+func TestWriteUBL(t *testing.T) {
+	var buf bytes.Buffer
+	if err := invoice.WriteUBL(&buf, jsonInvoice(), ublSeller, ublBuyer); err != nil {
+		t.Fatalf("WriteUBL: %v", err)
+	}
+	validateUBL(t, buf.Bytes())
+	checkGolden(t, "invoice.xml", buf.Bytes())
+}
+
+func TestWriteUBL_RoundsLinesToCents(t *testing.T) {
+	inv := testInvoice()
+	inv.Rate = 33.333
+	inv.Weeks = invoice.WeeksForMonth(2025, time.January, 7.5)
+	var buf bytes.Buffer
+	if err := invoice.WriteUBL(&buf, inv, invoice.UBLParty{Country: "US"}, invoice.UBLParty{Country: "US"}); err != nil {
+		t.Fatalf("WriteUBL: %v", err)
+	}
+	validateUBL(t, buf.Bytes())
+}
+
+func TestWriteUBL_TaxCategory(t *testing.T) {
+	tests := []struct {
+		name          string
+		seller, buyer invoice.UBLParty
+		want          string
+		wantVATIDs    int
+		wantErr       string
+	}{
+		{"reverse charge", ublSeller, ublBuyer, "AE", 2, ""},
+		{"no VAT IDs", invoice.UBLParty{Country: "US"}, invoice.UBLParty{Country: "DE"}, "O", 0, ""},
+		{"buyer VAT ID only", invoice.UBLParty{Country: "US"}, ublBuyer, "O", 0, ""},
+		{"seller VAT ID only", ublSeller, invoice.UBLParty{Country: "FR"}, "", 0, "buyer's VAT ID"},
+		{"no country", ublSeller, invoice.UBLParty{VATID: "FR12345678901"}, "", 0, "buyer's country is required"},
+		{"bad country", invoice.UBLParty{Country: "de"}, ublBuyer, "", 0, `seller's country "de"`},
+		{"bad VAT ID", invoice.UBLParty{Country: "DE", VATID: "123"}, ublBuyer, "", 0, `seller's VAT ID "123"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := invoice.WriteUBL(&buf, testInvoice(), tt.seller, tt.buyer)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("expected error %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("WriteUBL: %v", err)
+			}
+			validateUBL(t, buf.Bytes())
+			out := buf.String()
+			if !strings.Contains(out, "<cbc:ID>"+tt.want+"</cbc:ID>") {
+				t.Errorf("expected tax category %s:\n%s", tt.want, out)
+			}
+			if n := strings.Count(out, "<cac:PartyTaxScheme>"); n != tt.wantVATIDs {
+				t.Errorf("got %d VAT IDs, want %d", n, tt.wantVATIDs)
+			}
+		})
+	}
+}
+
+func TestValidateCountryCode(t *testing.T) {
+	for _, code := range []string{"DE", "US", "GB", "ZW"} {
+		if err := invoice.ValidateCountryCode(code); err != nil {
+			t.Errorf("ValidateCountryCode(%q) = %v", code, err)
+		}
+	}
+	for _, code := range []string{"", "de", "XX", "DEU", "UK"} {
+		if err := invoice.ValidateCountryCode(code); err == nil {
+			t.Errorf("ValidateCountryCode(%q) should fail", code)
+		}
+	}
+}
+
+// ublNode is an element of a parsed UBL document.
+type ublNode struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr `xml:",any,attr"`
+	Text     string     `xml:",chardata"`
+	Children []ublNode  `xml:",any"`
+}
+
+func (n *ublNode) child(name string) *ublNode {
+	for i := range n.Children {
+		if n.Children[i].XMLName.Local == name {
+			return &n.Children[i]
+		}
+	}
+	return nil
+}
+
+func (n *ublNode) all(name string) []*ublNode {
+	var out []*ublNode
+	for i := range n.Children {
+		if n.Children[i].XMLName.Local == name {
+			out = append(out, &n.Children[i])
+		}
+	}
+	return out
+}
+
+// ublOrder is the order of the top-level elements a UBL 2.1 Invoice may
+// use here, from the schema's sequence.
+var ublOrder = []string{
+	"CustomizationID", "ID", "IssueDate", "DueDate", "InvoiceTypeCode",
+	"DocumentCurrencyCode", "InvoicePeriod", "AccountingSupplierParty",
+	"AccountingCustomerParty", "PaymentMeans", "TaxTotal",
+	"LegalMonetaryTotal", "InvoiceLine",
+}
+
+var ublDecimal = regexp.MustCompile(`^-?[0-9]+(\.[0-9]{1,2})?$`)
+
+// validateUBL checks the structure of a UBL invoice: its namespaces, the
+// order and presence of the required elements, the decimal rules for
+// amounts, and that the lines add up to the totals (EN 16931 BR-CO-10,
+// BR-CO-13 and BR-CO-15).
+func validateUBL(t *testing.T, data []byte) {
+	t.Helper()
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		if _, err := dec.Token(); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("not well-formed XML: %v", err)
+		}
+	}
+	var root ublNode
+	if err := xml.Unmarshal(data, &root); err != nil {
+		t.Fatalf("parsing UBL: %v", err)
+	}
+	if root.XMLName.Space != "urn:oasis:names:specification:ubl:schema:xsd:Invoice-2" || root.XMLName.Local != "Invoice" {
+		t.Fatalf("root element is %v", root.XMLName)
+	}
+
+	last := -1
+	for _, c := range root.Children {
+		i := slices.Index(ublOrder, c.XMLName.Local)
+		if i < 0 {
+			t.Errorf("unexpected element %s", c.XMLName.Local)
+			continue
+		}
+		if i < last {
+			t.Errorf("element %s is out of order", c.XMLName.Local)
+		}
+		last = i
+	}
+	for _, name := range []string{"CustomizationID", "ID", "IssueDate", "InvoiceTypeCode", "DocumentCurrencyCode", "AccountingSupplierParty", "AccountingCustomerParty", "TaxTotal", "LegalMonetaryTotal", "InvoiceLine"} {
+		if root.child(name) == nil {
+			t.Errorf("required element %s is missing", name)
+		}
+	}
+	for _, role := range []string{"AccountingSupplierParty", "AccountingCustomerParty"} {
+		party := root.child(role).child("Party")
+		if party.child("PostalAddress").child("Country").child("IdentificationCode") == nil || party.child("PartyLegalEntity").child("RegistrationName") == nil {
+			t.Errorf("%s needs a country and a name", role)
+		}
+	}
+
+	var amounts func(n *ublNode)
+	amounts = func(n *ublNode) {
+		for _, a := range n.Attrs {
+			if a.Name.Local == "currencyID" {
+				if a.Value != invoice.Currency {
+					t.Errorf("%s has currency %q", n.XMLName.Local, a.Value)
+				}
+				if !ublDecimal.MatchString(n.Text) {
+					t.Errorf("%s %q is not a decimal with at most two places", n.XMLName.Local, n.Text)
+				}
+			}
+		}
+		for i := range n.Children {
+			amounts(&n.Children[i])
+		}
+	}
+	amounts(&root)
+
+	cents := func(s string) int64 {
+		var whole, frac int64
+		parts := strings.SplitN(s, ".", 2)
+		for _, c := range parts[0] {
+			whole = whole*10 + int64(c-'0')
+		}
+		if len(parts) == 2 {
+			f := (parts[1] + "0")[:2]
+			frac = int64(f[0]-'0')*10 + int64(f[1]-'0')
+		}
+		return whole*100 + frac
+	}
+	var sum int64
+	for _, line := range root.all("InvoiceLine") {
+		sum += cents(line.child("LineExtensionAmount").Text)
+	}
+	totals := root.child("LegalMonetaryTotal")
+	tax := cents(root.child("TaxTotal").child("TaxAmount").Text)
+	lineTotal := cents(totals.child("LineExtensionAmount").Text)
+	if sum != lineTotal {
+		t.Errorf("lines add up to %d cents, LineExtensionAmount is %d", sum, lineTotal)
+	}
+	if cents(totals.child("TaxExclusiveAmount").Text) != lineTotal {
+		t.Errorf("TaxExclusiveAmount should equal the line total")
+	}
+	if cents(totals.child("TaxInclusiveAmount").Text) != lineTotal+tax {
+		t.Errorf("TaxInclusiveAmount should be the line total plus tax")
+	}
+	if cents(totals.child("PayableAmount").Text) != lineTotal+tax {
+		t.Errorf("PayableAmount should be the amount due")
+	}
+}
@@ -0,0 +1,112 @@
+package invoice
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Usage is the tokens and cost a generation used, as reported by the backend.
+type Usage struct {
+	// InputTokens counts prompt tokens, including cached ones.
+	InputTokens int `json:"input_tokens"`
+	// OutputTokens counts generated tokens, including reasoning.
+	OutputTokens int `json:"output_tokens"`
+	// Cost is in US dollars.
+	Cost float64 `json:"cost"`
+}
+
+// Add adds o to u.
+func (u *Usage) Add(o Usage) {
+	u.InputTokens += o.InputTokens
+	u.OutputTokens += o.OutputTokens
+	u.Cost += o.Cost
+}
+
+// Tokens returns the total number of tokens used.
+func (u Usage) Tokens() int {
+	return u.InputTokens + u.OutputTokens
+}
+
+// IsZero reports whether no usage was reported.
+func (u Usage) IsZero() bool {
+	return u == Usage{}
+}
+
+// String formats u briefly, e.g. "12.3k tokens, $0.04".
+func (u Usage) String() string {
+	var parts []string
+	if n := u.Tokens(); n > 0 {
+		parts = append(parts, formatCount(n)+" tokens")
+	}
+	if u.Cost > 0 {
+		parts = append(parts, FormatCost(u.Cost))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// FormatCost formats a dollar cost, keeping a cost under a cent visible.
+func FormatCost(cost float64) string {
+	if cost > 0 && cost < 0.01 {
+		return fmt.Sprintf("$%.4f", cost)
+	}
+	return fmt.Sprintf("$%.2f", cost)
+}
+
+// formatCount abbreviates n, e.g. 950, 12.3k or 1.2M.
+func formatCount(n int) string {
+	switch {
+	case n >= 1_000_000:
+		return fmt.Sprintf("%.1fM", float64(n)/1_000_000)
+	case n >= 1_000:
+		return fmt.Sprintf("%.1fk", float64(n)/1_000)
+	}
+	return fmt.Sprint(n)
+}
+
+// stepFinishPart is the part of an opencode step_finish event, which
+// reports what the step used.
+type stepFinishPart struct {
+	Cost   float64 `json:"cost"`
+	Tokens struct {
+		Input     int `json:"input"`
+		Output    int `json:"output"`
+		Reasoning int `json:"reasoning"`
+		Cache     struct {
+			Read  int `json:"read"`
+			Write int `json:"write"`
+		} `json:"cache"`
+	} `json:"tokens"`
+}
+
+// Usage returns what the step ending with event used. ok is false for
+// events that do not report any usage.
+func (e OpencodeEvent) Usage() (u Usage, ok bool) {
+	if e.Type != "step_finish" {
+		return Usage{}, false
+	}
+	var part stepFinishPart
+	if err := json.Unmarshal(e.Part, &part); err != nil {
+		return Usage{}, false
+	}
+	t := part.Tokens
+	u = Usage{
+		InputTokens:  t.Input + t.Cache.Read + t.Cache.Write,
+		OutputTokens: t.Output + t.Reasoning,
+		Cost:         part.Cost,
+	}
+	return u, !u.IsZero()
+}
+
+// BudgetError reports that a generation was stopped because its cost went
+// over the limit set by GenerateOptions.MaxCost.
+type BudgetError struct {
+	// Limit is the cost limit in US dollars.
+	Limit float64
+	// Spent is what the generation cost before it was stopped.
+	Spent float64
+}
+
+func (e *BudgetError) Error() string {
+	return fmt.Sprintf("generation cost %s, over the %s limit", FormatCost(e.Spent), FormatCost(e.Limit))
+}
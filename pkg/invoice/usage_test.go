@@ -0,0 +1,168 @@
+package invoice_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+// streamFixture replaces OpencodeExec with one that streams the named
+// testdata file for the first len(errs) calls, failing with errs[i], and
+// then streams it and writes the invoice.
+func streamFixture(t *testing.T, name string, errs ...error) {
+	t.Helper()
+	data := readFixture(t, name)
+	calls := 0
+	orig := invoice.OpencodeExec
+	t.Cleanup(func() { invoice.OpencodeExec = orig })
+	invoice.OpencodeExec = func(ctx context.Context, r invoice.OpencodeRun, stdout io.Writer) error {
+		calls++
+		if _, err := stdout.Write(data); err != nil {
+			return err
+		}
+		if calls <= len(errs) {
+			return errs[calls-1]
+		}
+		return os.WriteFile(promptedPath(r.Prompt), []byte("<html>ok</html>"), 0o644)
+	}
+}
+
+func TestGenerate_AccumulatesUsage(t *testing.T) {
+	streamFixture(t, "opencode-usage.jsonl")
+	var steps []invoice.Usage
+	opts := invoice.GenerateOptions{OnUsage: func(u invoice.Usage) { steps = append(steps, u) }}
+	res, err := invoice.Generate(context.Background(), "opencode", testInvoice(), filepath.Join(t.TempDir(), "invoice.html"), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(steps) != 2 {
+		t.Fatalf("OnUsage called %d times, want once per step", len(steps))
+	}
+	if steps[0] != (invoice.Usage{InputTokens: 9000, OutputTokens: 3500, Cost: 0.0125}) {
+		t.Errorf("first step = %+v", steps[0])
+	}
+	u := res.Usage
+	if u.InputTokens != 9200 || u.OutputTokens != 3600 || math.Abs(u.Cost-0.04) > 1e-9 {
+		t.Errorf("Usage = %+v, want 9200 in, 3600 out, $0.04", u)
+	}
+	if got := u.String(); got != "12.8k tokens, $0.04" {
+		t.Errorf("String() = %q", got)
+	}
+}
+
+func TestGenerate_UsageIncludesFailedAttempts(t *testing.T) {
+	fakeSleep(t)
+	streamFixture(t, "opencode-usage.jsonl", errors.New("connection reset"))
+	res, err := invoice.Generate(context.Background(), "opencode", testInvoice(), filepath.Join(t.TempDir(), "invoice.html"), invoice.GenerateOptions{Retries: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(res.Usage.Cost-0.08) > 1e-9 {
+		t.Errorf("Cost = %v, want both attempts' $0.08", res.Usage.Cost)
+	}
+}
+
+func TestGenerate_NoUsageEvents(t *testing.T) {
+	streamFixture(t, "opencode-refusal.jsonl")
+	called := false
+	opts := invoice.GenerateOptions{OnUsage: func(invoice.Usage) { called = true }}
+	res, err := invoice.Generate(context.Background(), "opencode", testInvoice(), filepath.Join(t.TempDir(), "invoice.html"), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Usage.IsZero() || res.Usage.String() != "" || called {
+		t.Errorf("expected no usage, got %+v (OnUsage called: %v)", res.Usage, called)
+	}
+}
+
+func TestUsageString(t *testing.T) {
+	tests := []struct {
+		u    invoice.Usage
+		want string
+	}{
+		{invoice.Usage{InputTokens: 900, OutputTokens: 50}, "950 tokens"},
+		{invoice.Usage{InputTokens: 1_500_000, Cost: 2.5}, "1.5M tokens, $2.50"},
+		{invoice.Usage{OutputTokens: 10, Cost: 0.0012}, "10 tokens, $0.0012"},
+	}
+	for _, tt := range tests {
+		if got := tt.u.String(); got != tt.want {
+			t.Errorf("%+v.String() = %q, want %q", tt.u, got, tt.want)
+		}
+	}
+}
+
+// stepFinish returns a step_finish event line reporting cost.
+func stepFinish(cost float64) string {
+	return fmt.Sprintf(`{"type":"step_finish","part":{"type":"step-finish","cost":%g,"tokens":{"input":1000,"output":500}}}`+"\n", cost)
+}
+
+func TestGenerate_StopsOverBudget(t *testing.T) {
+	outputPath := filepath.Join(t.TempDir(), "invoice.html")
+	killed := false
+	orig := invoice.OpencodeExec
+	t.Cleanup(func() { invoice.OpencodeExec = orig })
+	invoice.OpencodeExec = func(ctx context.Context, r invoice.OpencodeRun, stdout io.Writer) error {
+		_ = os.WriteFile(promptedPath(r.Prompt), []byte("<html><body>partial"), 0o644)
+		io.WriteString(stdout, stepFinish(0.30))
+		io.WriteString(stdout, stepFinish(0.30))
+		// Keep running, as opencode would, until stopped.
+		<-ctx.Done()
+		killed = true
+		return ctx.Err()
+	}
+
+	_, err := invoice.Generate(context.Background(), "opencode", testInvoice(), outputPath, invoice.GenerateOptions{MaxCost: 0.50, Retries: 3})
+	var budget *invoice.BudgetError
+	if !errors.As(err, &budget) {
+		t.Fatalf("expected a BudgetError, got %v", err)
+	}
+	if budget.Limit != 0.50 || math.Abs(budget.Spent-0.60) > 1e-9 {
+		t.Errorf("BudgetError = %+v", budget)
+	}
+	if err.Error() != "generation cost $0.60, over the $0.50 limit" {
+		t.Errorf("Error() = %q", err)
+	}
+	if !killed {
+		t.Error("the backend was not stopped")
+	}
+	if _, err := os.Stat(outputPath); !os.IsNotExist(err) {
+		t.Errorf("partial file should be removed, stat err = %v", err)
+	}
+}
+
+func TestGenerate_BudgetStopsRetries(t *testing.T) {
+	fakeSleep(t)
+	calls := 0
+	orig := invoice.OpencodeExec
+	t.Cleanup(func() { invoice.OpencodeExec = orig })
+	invoice.OpencodeExec = func(ctx context.Context, r invoice.OpencodeRun, stdout io.Writer) error {
+		calls++
+		io.WriteString(stdout, stepFinish(0.20))
+		return errors.New("overloaded")
+	}
+
+	// Two failed attempts at $0.20 each stay under $0.50; the third goes over.
+	opts := invoice.GenerateOptions{Model: "a/one", FallbackModels: []string{"a/two"}, Retries: 5, MaxCost: 0.50}
+	_, err := invoice.Generate(context.Background(), "opencode", testInvoice(), filepath.Join(t.TempDir(), "invoice.html"), opts)
+	var budget *invoice.BudgetError
+	if !errors.As(err, &budget) {
+		t.Fatalf("expected a BudgetError, got %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("calls = %d, want no attempts after going over budget", calls)
+	}
+}
+
+func TestGenerate_UnderBudget(t *testing.T) {
+	streamFixture(t, "opencode-usage.jsonl")
+	if _, err := invoice.Generate(context.Background(), "opencode", testInvoice(), filepath.Join(t.TempDir(), "invoice.html"), invoice.GenerateOptions{MaxCost: 0.05}); err != nil {
+		t.Errorf("a $0.04 run under a $0.05 limit failed: %v", err)
+	}
+}
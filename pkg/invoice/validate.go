@@ -0,0 +1,153 @@
+package invoice
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Codes of the problems Invoice.Validate finds, named in a
+// ValidationError.
+const (
+	ValidationMissingVendor     = "missing_vendor"
+	ValidationMissingCustomer   = "missing_customer"
+	ValidationInvalidRate       = "invalid_rate"
+	ValidationInvalidPeriod     = "invalid_period"
+	ValidationNoWeeks           = "no_weeks"
+	ValidationInvalidWeek       = "invalid_week"
+	ValidationWeekOutsidePeriod = "week_outside_period"
+	ValidationOverlappingWeeks  = "overlapping_weeks"
+	ValidationNegativeHours     = "negative_hours"
+	ValidationInvalidAdjustment = "invalid_adjustment"
+)
+
+// ValidationError is one way an Invoice is inconsistent.
+type ValidationError struct {
+	// Code says what is wrong, e.g. ValidationMissingVendor.
+	Code string `json:"code"`
+	// Field is the invoice's field, named as in its JSON, e.g. "vendor" or
+	// "weeks[2].hours".
+	Field string `json:"field"`
+	// Message describes the problem.
+	Message string `json:"message"`
+}
+
+func (e *ValidationError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// ValidationErrors are all the problems Invoice.Validate found.
+type ValidationErrors []*ValidationError
+
+func (errs ValidationErrors) Error() string {
+	msgs := make([]string, len(errs))
+	for i, e := range errs {
+		msgs[i] = e.Error()
+	}
+	return "invalid invoice: " + strings.Join(msgs, "; ")
+}
+
+// Unwrap returns each ValidationError, so errors.As finds them.
+func (errs ValidationErrors) Unwrap() []error {
+	out := make([]error, len(errs))
+	for i, e := range errs {
+		out[i] = e
+	}
+	return out
+}
+
+// Validate checks that inv is internally consistent: it has a vendor,
+// a customer, a positive rate, a month and year, and at least one week;
+// every week lies in the month, has no negative hours, and overlaps no
+// other; and every adjustment has a description and an amount. It returns
+// ValidationErrors with every problem found, or nil.
+func (inv *Invoice) Validate() error {
+	var errs ValidationErrors
+	add := func(code, field, format string, args ...any) {
+		errs = append(errs, &ValidationError{Code: code, Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if strings.TrimSpace(inv.Vendor) == "" {
+		add(ValidationMissingVendor, "vendor", "the vendor is empty")
+	}
+	if strings.TrimSpace(inv.Customer) == "" {
+		add(ValidationMissingCustomer, "customer", "the customer is empty")
+	}
+	if !(inv.Rate > 0) {
+		add(ValidationInvalidRate, "rate", "the rate must be positive, got %v", inv.Rate)
+	}
+	period := inv.Month >= time.January && inv.Month <= time.December && inv.Year > 0
+	if inv.Month < time.January || inv.Month > time.December {
+		add(ValidationInvalidPeriod, "month", "the month must be between 1 and 12, got %d", inv.Month)
+	}
+	if inv.Year <= 0 {
+		add(ValidationInvalidPeriod, "year", "the year is not set")
+	}
+	if len(inv.Weeks) == 0 {
+		add(ValidationNoWeeks, "weeks", "the invoice has no weeks")
+	}
+
+	first := time.Date(inv.Year, inv.Month, 1, 0, 0, 0, 0, time.UTC)
+	last := first.AddDate(0, 1, -1)
+	var ordered []int
+	for i, w := range inv.Weeks {
+		field := fmt.Sprintf("weeks[%d]", i)
+		if w.Hours < 0 {
+			add(ValidationNegativeHours, field+".hours", "the hours must not be negative, got %v", w.Hours)
+		}
+		switch {
+		case w.Start.IsZero() || w.End.IsZero():
+			add(ValidationInvalidWeek, field, "the week has no start or end date")
+			continue
+		case w.End.Before(w.Start):
+			add(ValidationInvalidWeek, field, "the week ends on %s, before it starts on %s", w.End.Format(time.DateOnly), w.Start.Format(time.DateOnly))
+			continue
+		case period && (day(w.Start).Before(first) || day(w.End).After(last)):
+			add(ValidationWeekOutsidePeriod, field, "%s to %s is not within %s %d", w.Start.Format(time.DateOnly), w.End.Format(time.DateOnly), inv.Month, inv.Year)
+		}
+		ordered = append(ordered, i)
+	}
+
+	slices.SortStableFunc(ordered, func(a, b int) int {
+		return inv.Weeks[a].Start.Compare(inv.Weeks[b].Start)
+	})
+	// Each week is compared with the earlier week that ends last.
+	for k, latest := 1, 0; k < len(ordered); k++ {
+		prev, next := ordered[latest], ordered[k]
+		if !day(inv.Weeks[next].Start).After(day(inv.Weeks[prev].End)) {
+			add(ValidationOverlappingWeeks, fmt.Sprintf("weeks[%d]", next), "the week overlaps weeks[%d]", prev)
+		}
+		if inv.Weeks[next].End.After(inv.Weeks[prev].End) {
+			latest = k
+		}
+	}
+
+	for i, a := range inv.Adjustments {
+		field := fmt.Sprintf("adjustments[%d]", i)
+		if strings.TrimSpace(a.Description) == "" {
+			add(ValidationInvalidAdjustment, field+".description", "the adjustment has no description")
+		}
+		if a.Amount == 0 || math.IsNaN(a.Amount) || math.IsInf(a.Amount, 0) {
+			add(ValidationInvalidAdjustment, field+".amount", "the amount must be a number other than 0, got %v", a.Amount)
+		}
+		if a.Quantity < 0 || math.IsNaN(a.Quantity) || math.IsInf(a.Quantity, 0) {
+			add(ValidationInvalidAdjustment, field+".quantity", "the quantity must be a positive number, got %v", a.Quantity)
+		}
+		if a.Unit != "" && a.Quantity == 0 {
+			add(ValidationInvalidAdjustment, field+".unit", "the unit needs a quantity")
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// day returns the date of t, at midnight UTC.
+func day(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,138 @@
+package invoice_test
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+// validInvoice returns an invoice that passes Validate.
+func validInvoice() *invoice.Invoice {
+	return &invoice.Invoice{
+		Month:    time.January,
+		Year:     2025,
+		Vendor:   "Jane Smith",
+		Customer: "Acme Corp",
+		Rate:     150,
+		Weeks:    invoice.WeeksForMonth(2025, time.January, 40),
+	}
+}
+
+func day2025(month time.Month, d int) time.Time {
+	return time.Date(2025, month, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestInvoiceValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(inv *invoice.Invoice)
+		code   string
+		field  string
+	}{
+		{"valid", func(inv *invoice.Invoice) {}, "", ""},
+		{"zero hours", func(inv *invoice.Invoice) { inv.Weeks[1].Hours = 0 }, "", ""},
+		{"missing vendor", func(inv *invoice.Invoice) { inv.Vendor = " " }, invoice.ValidationMissingVendor, "vendor"},
+		{"missing customer", func(inv *invoice.Invoice) { inv.Customer = "" }, invoice.ValidationMissingCustomer, "customer"},
+		{"zero rate", func(inv *invoice.Invoice) { inv.Rate = 0 }, invoice.ValidationInvalidRate, "rate"},
+		{"negative rate", func(inv *invoice.Invoice) { inv.Rate = -10 }, invoice.ValidationInvalidRate, "rate"},
+		{"no month", func(inv *invoice.Invoice) { inv.Month = 0 }, invoice.ValidationInvalidPeriod, "month"},
+		{"month 13", func(inv *invoice.Invoice) { inv.Month = 13 }, invoice.ValidationInvalidPeriod, "month"},
+		{"no year", func(inv *invoice.Invoice) { inv.Year = 0 }, invoice.ValidationInvalidPeriod, "year"},
+		{"no weeks", func(inv *invoice.Invoice) { inv.Weeks = nil }, invoice.ValidationNoWeeks, "weeks"},
+		{"negative hours", func(inv *invoice.Invoice) { inv.Weeks[2].Hours = -1 }, invoice.ValidationNegativeHours, "weeks[2].hours"},
+		{"no dates", func(inv *invoice.Invoice) { inv.Weeks[0] = invoice.Week{Hours: 40} }, invoice.ValidationInvalidWeek, "weeks[0]"},
+		{"reversed", func(inv *invoice.Invoice) {
+			inv.Weeks[0].Start, inv.Weeks[0].End = inv.Weeks[0].End, inv.Weeks[0].Start
+		}, invoice.ValidationInvalidWeek, "weeks[0]"},
+		{"before the month", func(inv *invoice.Invoice) {
+			inv.Weeks[0].Start = time.Date(2024, time.December, 30, 0, 0, 0, 0, time.UTC)
+		}, invoice.ValidationWeekOutsidePeriod, "weeks[0]"},
+		{"after the month", func(inv *invoice.Invoice) {
+			inv.Weeks[len(inv.Weeks)-1].End = day2025(time.February, 2)
+		}, invoice.ValidationWeekOutsidePeriod, "weeks[4]"},
+		{"overlapping", func(inv *invoice.Invoice) {
+			inv.Weeks[2].Start = inv.Weeks[1].End
+		}, invoice.ValidationOverlappingWeeks, "weeks[2]"},
+		{"overlapping out of order", func(inv *invoice.Invoice) {
+			inv.Weeks = append(inv.Weeks, invoice.Week{Start: day2025(time.January, 10), End: day2025(time.January, 10), Hours: 8})
+		}, invoice.ValidationOverlappingWeeks, "weeks[5]"},
+		{"adjustment", func(inv *invoice.Invoice) {
+			inv.Adjustments = []invoice.Adjustment{{Description: "Credit", Amount: -50}}
+		}, "", ""},
+		{"adjustment without description", func(inv *invoice.Invoice) {
+			inv.Adjustments = []invoice.Adjustment{{Amount: 50}}
+		}, invoice.ValidationInvalidAdjustment, "adjustments[0].description"},
+		{"adjustment of 0", func(inv *invoice.Invoice) {
+			inv.Adjustments = []invoice.Adjustment{{Description: "Expenses"}}
+		}, invoice.ValidationInvalidAdjustment, "adjustments[0].amount"},
+		{"adjustment with a negative quantity", func(inv *invoice.Invoice) {
+			inv.Adjustments = []invoice.Adjustment{{Description: "Licenses", Amount: 50, Quantity: -1}}
+		}, invoice.ValidationInvalidAdjustment, "adjustments[0].quantity"},
+		{"adjustment with a unit but no quantity", func(inv *invoice.Invoice) {
+			inv.Adjustments = []invoice.Adjustment{{Description: "Conference", Amount: 800, Unit: "day"}}
+		}, invoice.ValidationInvalidAdjustment, "adjustments[0].unit"},
+		{"inside a longer week", func(inv *invoice.Invoice) {
+			inv.Weeks = []invoice.Week{
+				{Start: day2025(time.January, 1), End: day2025(time.January, 31), Hours: 160},
+				{Start: day2025(time.January, 6), End: day2025(time.January, 10), Hours: 40},
+				{Start: day2025(time.January, 13), End: day2025(time.January, 17), Hours: 40},
+			}
+		}, invoice.ValidationOverlappingWeeks, "weeks[2]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inv := validInvoice()
+			tt.modify(inv)
+			err := inv.Validate()
+			if tt.code == "" {
+				if err != nil {
+					t.Fatalf("Validate() = %v, want nil", err)
+				}
+				return
+			}
+			var problems invoice.ValidationErrors
+			if !errors.As(err, &problems) {
+				t.Fatalf("Validate() = %v, want ValidationErrors", err)
+			}
+			if !slices.ContainsFunc(problems, func(e *invoice.ValidationError) bool {
+				return e.Code == tt.code && e.Field == tt.field
+			}) {
+				t.Errorf("Validate() = %v, want %s on %s", err, tt.code, tt.field)
+			}
+		})
+	}
+}
+
+func TestInvoiceValidate_ReportsEveryProblem(t *testing.T) {
+	err := (&invoice.Invoice{}).Validate()
+	var problems invoice.ValidationErrors
+	if !errors.As(err, &problems) {
+		t.Fatalf("Validate() = %v, want ValidationErrors", err)
+	}
+	var codes []string
+	for _, p := range problems {
+		codes = append(codes, p.Code)
+	}
+	want := []string{
+		invoice.ValidationMissingVendor,
+		invoice.ValidationMissingCustomer,
+		invoice.ValidationInvalidRate,
+		invoice.ValidationInvalidPeriod,
+		invoice.ValidationInvalidPeriod,
+		invoice.ValidationNoWeeks,
+	}
+	if !slices.Equal(codes, want) {
+		t.Errorf("codes = %v, want %v", codes, want)
+	}
+	if msg := err.Error(); !strings.HasPrefix(msg, "invalid invoice: vendor: the vendor is empty; customer: ") {
+		t.Errorf("Error() = %q", msg)
+	}
+	var one *invoice.ValidationError
+	if !errors.As(err, &one) || one.Code != invoice.ValidationMissingVendor {
+		t.Errorf("errors.As found %v, want the first problem", one)
+	}
+}
@@ -0,0 +1,131 @@
+package invoice
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strings"
+)
+
+// Xero regions. The region of a Xero organisation decides how its imports
+// read dates and which tax types it has.
+const (
+	XeroRegionUS     = "US"
+	XeroRegionUK     = "UK"
+	XeroRegionAU     = "AU"
+	XeroRegionNZ     = "NZ"
+	XeroRegionGlobal = "global"
+)
+
+// XeroRegions returns the Xero regions in the order they are documented.
+func XeroRegions() []string {
+	return []string{XeroRegionUS, XeroRegionUK, XeroRegionAU, XeroRegionNZ, XeroRegionGlobal}
+}
+
+// DefaultXeroAccountCode is the code of the sales account in Xero's
+// default chart of accounts.
+const DefaultXeroAccountCode = "200"
+
+// xeroNoTax is the tax type for sales without tax in each region's default
+// tax rates.
+var xeroNoTax = map[string]string{
+	XeroRegionUS:     "Tax Exempt",
+	XeroRegionUK:     "No VAT",
+	XeroRegionAU:     "BAS Excluded",
+	XeroRegionNZ:     "No GST",
+	XeroRegionGlobal: "Tax Exempt",
+}
+
+// ValidateXeroRegion checks that region is one of XeroRegions.
+func ValidateXeroRegion(region string) error {
+	if !slices.Contains(XeroRegions(), region) {
+		return fmt.Errorf("Xero region %q is not valid (use %s)", region, strings.Join(XeroRegions(), ", "))
+	}
+	return nil
+}
+
+// XeroOptions map an exported invoice onto a Xero organisation. The account
+// code and tax type must exist in the organisation.
+type XeroOptions struct {
+	// AccountCode is the revenue account the lines are coded to; "" means
+	// DefaultXeroAccountCode.
+	AccountCode string
+	// TaxType is the tax rate of the lines; "" means the region's rate for
+	// sales without tax, e.g. "Tax Exempt" in the US.
+	TaxType string
+	// Region is the organisation's region, one of XeroRegions; "" means
+	// XeroRegionUS.
+	Region string
+}
+
+// xeroCSVHeader is the header of Xero's sales invoice import, with the
+// columns Xero requires starred as in its template.
+var xeroCSVHeader = []string{
+	"*ContactName", "*InvoiceNumber", "*InvoiceDate", "*DueDate", "*Description",
+	"*Quantity", "*UnitAmount", "*AccountCode", "*TaxType",
+}
+
+// WriteXeroCSV writes inv as a Xero sales invoice import: a header and one
+// row per week, in order, with the hours as the quantity and the rate as
+// the unit amount. Each row repeats the invoice number so Xero puts them on
+// one invoice. Xero works the line amounts out itself, so a rate in
+// fractions of a cent keeps up to four decimals and the lines add up to
+// the total exactly. Dates are
+// month first in the US region and day first elsewhere. An invoice without
+// a due date is due on its issue date, as Xero needs one.
+func WriteXeroCSV(w io.Writer, inv *Invoice, opts XeroOptions) error {
+	if opts.Region == "" {
+		opts.Region = XeroRegionUS
+	}
+	if err := ValidateXeroRegion(opts.Region); err != nil {
+		return err
+	}
+	if opts.AccountCode == "" {
+		opts.AccountCode = DefaultXeroAccountCode
+	}
+	if opts.TaxType == "" {
+		opts.TaxType = xeroNoTax[opts.Region]
+	}
+	lines, _ := invoiceLines(inv)
+	if len(lines) == 0 {
+		return errors.New("a Xero invoice needs at least one line")
+	}
+	layout := "02/01/2006"
+	if opts.Region == XeroRegionUS {
+		layout = "01/02/2006"
+	}
+	issued := inv.IssueDate()
+	due := inv.Due
+	if due.IsZero() {
+		due = issued
+	}
+	cw := csv.NewWriter(w)
+	cw.Write(xeroCSVHeader)
+	for _, l := range lines {
+		cw.Write([]string{
+			inv.Customer,
+			InvoiceNumber(inv),
+			issued.Format(layout),
+			due.Format(layout),
+			l.Description,
+			formatQuantity(l.Quantity),
+			xeroUnitAmount(l.UnitPrice),
+			opts.AccountCode,
+			opts.TaxType,
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// xeroUnitAmount returns rate with two decimals, or up to the four Xero
+// allows if it is not a whole number of cents, e.g. "150.00" or "33.333".
+func xeroUnitAmount(rate float64) string {
+	if c := ToCents(rate); float64(c) == math.Round(rate*1e6)/1e4 {
+		return c.String()
+	}
+	return formatQuantity(rate)
+}
@@ -0,0 +1,124 @@
+package invoice_test
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zon/invoicer/pkg/invoice"
+)
+
+func TestWriteXeroCSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := invoice.WriteXeroCSV(&buf, jsonInvoice(), invoice.XeroOptions{}); err != nil {
+		t.Fatalf("WriteXeroCSV: %v", err)
+	}
+	checkGolden(t, "invoice-xero.csv", buf.Bytes())
+}
+
+func TestWriteXeroCSV_Adjustment(t *testing.T) {
+	var buf bytes.Buffer
+	if err := invoice.WriteXeroCSV(&buf, adjustedInvoice(), invoice.XeroOptions{}); err != nil {
+		t.Fatalf("WriteXeroCSV: %v", err)
+	}
+	checkGolden(t, "invoice-xero-adjustment.csv", buf.Bytes())
+}
+
+func TestWriteXeroCSV_Region(t *testing.T) {
+	tests := []struct {
+		opts    invoice.XeroOptions
+		date    string
+		tax     string
+		account string
+	}{
+		{invoice.XeroOptions{}, "02/01/2025", "Tax Exempt", "200"},
+		{invoice.XeroOptions{Region: "UK"}, "01/02/2025", "No VAT", "200"},
+		{invoice.XeroOptions{Region: "AU", AccountCode: "4000"}, "01/02/2025", "BAS Excluded", "4000"},
+		{invoice.XeroOptions{Region: "NZ", TaxType: "Zero Rated"}, "01/02/2025", "Zero Rated", "200"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.opts.Region, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := invoice.WriteXeroCSV(&buf, jsonInvoice(), tt.opts); err != nil {
+				t.Fatalf("WriteXeroCSV: %v", err)
+			}
+			rows, err := csv.NewReader(&buf).ReadAll()
+			if err != nil {
+				t.Fatalf("reading CSV: %v", err)
+			}
+			row := rows[1]
+			if row[2] != tt.date || row[7] != tt.account || row[8] != tt.tax {
+				t.Errorf("date, account, tax type = %q, %q, %q, want %q, %q, %q", row[2], row[7], row[8], tt.date, tt.account, tt.tax)
+			}
+		})
+	}
+}
+
+func TestWriteXeroCSV_BadRegion(t *testing.T) {
+	var buf bytes.Buffer
+	err := invoice.WriteXeroCSV(&buf, jsonInvoice(), invoice.XeroOptions{Region: "us"})
+	if err == nil || !strings.Contains(err.Error(), `Xero region "us"`) {
+		t.Errorf("expected a region error, got %v", err)
+	}
+}
+
+func TestWriteXeroCSV_DueDefaultsToIssueDate(t *testing.T) {
+	inv := jsonInvoice()
+	inv.Due = time.Time{}
+	var buf bytes.Buffer
+	if err := invoice.WriteXeroCSV(&buf, inv, invoice.XeroOptions{}); err != nil {
+		t.Fatalf("WriteXeroCSV: %v", err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("reading CSV: %v", err)
+	}
+	if got := rows[1][3]; got != "02/01/2025" {
+		t.Errorf("DueDate = %q, want the issue date 02/01/2025", got)
+	}
+}
+
+// xeroTotal works out the invoice total as Xero does: each line's quantity
+// times its unit amount, rounded to cents.
+func xeroTotal(t *testing.T, rows [][]string) invoice.Cents {
+	t.Helper()
+	var total invoice.Cents
+	for _, row := range rows[1:] {
+		q, err := strconv.ParseFloat(row[5], 64)
+		if err != nil {
+			t.Fatalf("quantity %q: %v", row[5], err)
+		}
+		u, err := strconv.ParseFloat(row[6], 64)
+		if err != nil {
+			t.Fatalf("unit amount %q: %v", row[6], err)
+		}
+		total += invoice.ToCents(q * u)
+	}
+	return total
+}
+
+func TestWriteXeroCSV_Reconciles(t *testing.T) {
+	for _, inv := range []*invoice.Invoice{jsonInvoice(), oddInvoice()} {
+		var buf bytes.Buffer
+		if err := invoice.WriteXeroCSV(&buf, inv, invoice.XeroOptions{}); err != nil {
+			t.Fatalf("WriteXeroCSV: %v", err)
+		}
+		rows, err := csv.NewReader(&buf).ReadAll()
+		if err != nil {
+			t.Fatalf("reading CSV: %v", err)
+		}
+		if len(rows) != len(inv.Weeks)+1 {
+			t.Fatalf("got %d rows, want a header and %d lines", len(rows), len(inv.Weeks))
+		}
+		var want invoice.Cents
+		for _, wk := range inv.Weeks {
+			want += invoice.ToCents(wk.Hours * inv.Rate)
+		}
+		if got := xeroTotal(t, rows); got != want {
+			t.Errorf("rate %v: lines add up to %s, want %s", inv.Rate, got, want)
+		}
+	}
+}